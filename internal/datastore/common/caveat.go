@@ -0,0 +1,50 @@
+package common
+
+import (
+	"fmt"
+
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+const errUnableToSerializeCaveat = "unable to serialize caveat context: %w"
+
+// CaveatColumnValues returns the values stored in the caveat name and context columns for the
+// caveat of a tuple. Both are nil for a tuple without a caveat, and the context is nil for a
+// caveat without one.
+func CaveatColumnValues(caveat *core.ContextualizedCaveat) (name any, context any, err error) {
+	if caveat == nil {
+		return nil, nil, nil
+	}
+
+	if caveat.Context == nil {
+		return caveat.CaveatName, nil, nil
+	}
+
+	serialized, err := protojson.Marshal(caveat.Context)
+	if err != nil {
+		return nil, nil, fmt.Errorf(errUnableToSerializeCaveat, err)
+	}
+
+	return caveat.CaveatName, string(serialized), nil
+}
+
+// CaveatFromColumns returns the caveat of a tuple from the values scanned from its caveat name
+// and context columns, or nil if the tuple has no caveat.
+func CaveatFromColumns(name *string, context []byte) (*core.ContextualizedCaveat, error) {
+	if name == nil {
+		return nil, nil
+	}
+
+	caveat := &core.ContextualizedCaveat{CaveatName: *name}
+	if context != nil {
+		caveat.Context = &structpb.Struct{}
+		if err := protojson.Unmarshal(context, caveat.Context); err != nil {
+			return nil, fmt.Errorf(errUnableToSerializeCaveat, err)
+		}
+	}
+
+	return caveat, nil
+}
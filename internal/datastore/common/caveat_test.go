@@ -0,0 +1,59 @@
+package common
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+func TestCaveatColumnsRoundTrip(t *testing.T) {
+	caveatContext, err := structpb.NewStruct(map[string]any{
+		"ip":      "10.0.0.1",
+		"allowed": []any{"read", "write"},
+		"nested":  map[string]any{"limit": 42.0},
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name   string
+		caveat *core.ContextualizedCaveat
+	}{
+		{"no caveat", nil},
+		{"caveat without context", &core.ContextualizedCaveat{CaveatName: "somecaveat"}},
+		{"caveat with empty context", &core.ContextualizedCaveat{CaveatName: "somecaveat", Context: &structpb.Struct{}}},
+		{"caveat with context", &core.ContextualizedCaveat{CaveatName: "somecaveat", Context: caveatContext}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			name, context, err := CaveatColumnValues(tc.caveat)
+			require.NoError(err)
+			if tc.caveat == nil {
+				require.Nil(name)
+				require.Nil(context)
+			}
+
+			// Simulate scanning the values back out of the columns
+			var scannedName *string
+			if name != nil {
+				nameStr := name.(string)
+				scannedName = &nameStr
+			}
+
+			var scannedContext []byte
+			if context != nil {
+				scannedContext = []byte(context.(string))
+			}
+
+			caveat, err := CaveatFromColumns(scannedName, scannedContext)
+			require.NoError(err)
+			require.True(proto.Equal(tc.caveat, caveat), "expected %v, found %v", tc.caveat, caveat)
+		})
+	}
+}
@@ -0,0 +1,31 @@
+package common
+
+import (
+	"context"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// CountRelationships counts the relationships matching a filter by iterating over all of them.
+// Datastores without a more efficient native implementation can use this to implement
+// Reader.CountRelationships.
+func CountRelationships(ctx context.Context, reader datastore.Reader, filter *v1.RelationshipFilter) (uint64, error) {
+	iter, err := reader.QueryRelationships(ctx, filter)
+	if err != nil {
+		return 0, err
+	}
+	defer iter.Close()
+
+	var count uint64
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		count++
+	}
+
+	if err := iter.Err(); err != nil {
+		return 0, err
+	}
+
+	return count, nil
+}
@@ -0,0 +1,72 @@
+package common
+
+import (
+	"sort"
+
+	sq "github.com/Masterminds/squirrel"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+func tupleSortKey(tpl *core.RelationTuple) [6]string {
+	return [6]string{
+		tpl.ResourceAndRelation.Namespace,
+		tpl.ResourceAndRelation.ObjectId,
+		tpl.ResourceAndRelation.Relation,
+		tpl.Subject.Namespace,
+		tpl.Subject.ObjectId,
+		tpl.Subject.Relation,
+	}
+}
+
+// TupleLess returns whether the first tuple sorts before the second in the order used by
+// sorted queries: by resource namespace, object ID and relation, then by subject namespace,
+// object ID and relation.
+func TupleLess(first, second *core.RelationTuple) bool {
+	firstKey, secondKey := tupleSortKey(first), tupleSortKey(second)
+	for i := range firstKey {
+		if firstKey[i] != secondKey[i] {
+			return firstKey[i] < secondKey[i]
+		}
+	}
+	return false
+}
+
+// SortTuples sorts tuples in the order used by sorted queries.
+func SortTuples(tuples []*core.RelationTuple) {
+	sort.Slice(tuples, func(i, j int) bool {
+		return TupleLess(tuples[i], tuples[j])
+	})
+}
+
+// SortedAfter returns a new SchemaQueryFilterer which returns tuples in the order used by sorted
+// queries, starting after the cursor tuple if it is non-nil.
+func (sqf SchemaQueryFilterer) SortedAfter(cursor *core.RelationTuple) SchemaQueryFilterer {
+	columns := []string{
+		sqf.schema.ColNamespace,
+		sqf.schema.ColObjectID,
+		sqf.schema.ColRelation,
+		sqf.schema.ColUsersetNamespace,
+		sqf.schema.ColUsersetObjectID,
+		sqf.schema.ColUsersetRelation,
+	}
+
+	if cursor != nil {
+		// Row value comparisons are not supported by all datastores, so the comparison is
+		// expanded to: (c0 > v0) OR (c0 = v0 AND c1 > v1) OR ...
+		values := tupleSortKey(cursor)
+		orClause := sq.Or{}
+		for i := range columns {
+			andClause := sq.And{}
+			for j := 0; j < i; j++ {
+				andClause = append(andClause, sq.Eq{columns[j]: values[j]})
+			}
+			andClause = append(andClause, sq.Gt{columns[i]: values[i]})
+			orClause = append(orClause, andClause)
+		}
+		sqf.queryBuilder = sqf.queryBuilder.Where(orClause)
+	}
+
+	sqf.queryBuilder = sqf.queryBuilder.OrderBy(columns...)
+	return sqf
+}
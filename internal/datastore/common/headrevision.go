@@ -0,0 +1,119 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// LoadHeadRevisionFunc loads the head revision of a datastore.
+type LoadHeadRevisionFunc func(ctx context.Context) (uint64, error)
+
+// HeadRevisionCache shares the loading of a datastore's head revision between the watches of the
+// datastore, so that they issue a single query per interval between them rather than one each: a
+// head revision loaded less than maxAge ago is reused, and concurrent loads are coalesced into one.
+type HeadRevisionCache struct {
+	load   LoadHeadRevisionFunc
+	maxAge time.Duration
+	clock  clock.Clock
+
+	mu       sync.Mutex
+	revision uint64
+	loadedAt time.Time
+	loaded   bool
+	inflight *headRevisionLoad
+}
+
+type headRevisionLoad struct {
+	done     chan struct{}
+	revision uint64
+	err      error
+}
+
+// NewHeadRevisionCache creates a new HeadRevisionCache which loads the head revision with the
+// given function, reusing it for up to maxAge.
+func NewHeadRevisionCache(load LoadHeadRevisionFunc, maxAge time.Duration, clock clock.Clock) *HeadRevisionCache {
+	return &HeadRevisionCache{
+		load:   load,
+		maxAge: maxAge,
+		clock:  clock,
+	}
+}
+
+// HeadRevision returns the head revision, which may have been loaded up to maxAge ago. If
+// requireFresh is set, as it should be once a new revision is known to have been committed, the
+// head revision is always loaded.
+func (hrc *HeadRevisionCache) HeadRevision(ctx context.Context, requireFresh bool) (uint64, error) {
+	if requireFresh {
+		startedAt := hrc.clock.Now()
+		revision, err := hrc.load(ctx)
+		if err != nil {
+			return 0, err
+		}
+
+		hrc.mu.Lock()
+		hrc.storeUnsafe(revision, startedAt)
+		hrc.mu.Unlock()
+		return revision, nil
+	}
+
+	for {
+		hrc.mu.Lock()
+		if hrc.loaded && hrc.clock.Since(hrc.loadedAt) < hrc.maxAge {
+			revision := hrc.revision
+			hrc.mu.Unlock()
+			return revision, nil
+		}
+
+		if inflight := hrc.inflight; inflight != nil {
+			hrc.mu.Unlock()
+
+			select {
+			case <-inflight.done:
+			case <-ctx.Done():
+				return 0, ctx.Err()
+			}
+
+			// A load which failed because the context of the watch which started it was done
+			// is retried by the others.
+			if inflight.err != nil && ctx.Err() == nil &&
+				(errors.Is(inflight.err, context.Canceled) || errors.Is(inflight.err, context.DeadlineExceeded)) {
+				continue
+			}
+			return inflight.revision, inflight.err
+		}
+
+		inflight := &headRevisionLoad{done: make(chan struct{})}
+		hrc.inflight = inflight
+		hrc.mu.Unlock()
+
+		startedAt := hrc.clock.Now()
+		inflight.revision, inflight.err = hrc.load(ctx)
+
+		hrc.mu.Lock()
+		hrc.inflight = nil
+		if inflight.err == nil {
+			hrc.storeUnsafe(inflight.revision, startedAt)
+		}
+		hrc.mu.Unlock()
+		close(inflight.done)
+
+		return inflight.revision, inflight.err
+	}
+}
+
+// storeUnsafe records a loaded head revision, unless a later one is already known. The
+// revision is considered to have been loaded when the load started, since it may have been
+// committed to by then.
+func (hrc *HeadRevisionCache) storeUnsafe(revision uint64, loadedAt time.Time) {
+	if hrc.loaded && revision < hrc.revision {
+		return
+	}
+
+	hrc.revision = revision
+	hrc.loadedAt = loadedAt
+	hrc.loaded = true
+}
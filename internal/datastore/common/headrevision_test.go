@@ -0,0 +1,143 @@
+package common
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadRevisionCacheSharesLoads(t *testing.T) {
+	require := require.New(t)
+
+	mockTime := clock.NewMock()
+	var loads, head uint64
+	release := make(chan struct{})
+	cache := NewHeadRevisionCache(func(ctx context.Context) (uint64, error) {
+		atomic.AddUint64(&loads, 1)
+		<-release
+		return atomic.LoadUint64(&head), nil
+	}, 100*time.Millisecond, mockTime)
+
+	atomic.StoreUint64(&head, 1)
+
+	// Concurrent loads by many watchers are coalesced into one
+	const watchers = 50
+	var wg sync.WaitGroup
+	revisions := make([]uint64, watchers)
+	for i := 0; i < watchers; i++ {
+		i := i
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			revision, err := cache.HeadRevision(context.Background(), false)
+			require.NoError(err)
+			revisions[i] = revision
+		}()
+	}
+
+	// Wait for the first load to start before releasing it
+	require.Eventually(func() bool { return atomic.LoadUint64(&loads) == 1 }, time.Second, time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	require.Equal(uint64(1), atomic.LoadUint64(&loads))
+	for _, revision := range revisions {
+		require.Equal(uint64(1), revision)
+	}
+
+	// The loaded revision is reused until it is older than the max age
+	atomic.StoreUint64(&head, 2)
+	for i := 0; i < watchers; i++ {
+		revision, err := cache.HeadRevision(context.Background(), false)
+		require.NoError(err)
+		require.Equal(uint64(1), revision)
+	}
+	require.Equal(uint64(1), atomic.LoadUint64(&loads))
+
+	mockTime.Add(100 * time.Millisecond)
+	for i := 0; i < watchers; i++ {
+		revision, err := cache.HeadRevision(context.Background(), false)
+		require.NoError(err)
+		require.Equal(uint64(2), revision)
+	}
+	require.Equal(uint64(2), atomic.LoadUint64(&loads))
+
+	// A fresh revision is always loaded, and replaces the shared one
+	atomic.StoreUint64(&head, 3)
+	revision, err := cache.HeadRevision(context.Background(), true)
+	require.NoError(err)
+	require.Equal(uint64(3), revision)
+	require.Equal(uint64(3), atomic.LoadUint64(&loads))
+
+	revision, err = cache.HeadRevision(context.Background(), false)
+	require.NoError(err)
+	require.Equal(uint64(3), revision)
+	require.Equal(uint64(3), atomic.LoadUint64(&loads))
+}
+
+func TestHeadRevisionCacheRetriesCanceledLoads(t *testing.T) {
+	require := require.New(t)
+
+	started := make(chan struct{})
+	var loads uint64
+	cache := NewHeadRevisionCache(func(ctx context.Context) (uint64, error) {
+		if atomic.AddUint64(&loads, 1) == 1 {
+			close(started)
+			<-ctx.Done()
+			return 0, ctx.Err()
+		}
+		return 5, nil
+	}, 100*time.Millisecond, clock.NewMock())
+
+	// The first watcher's load is canceled while a second is waiting on it
+	ctx, cancel := context.WithCancel(context.Background())
+	firstErr := make(chan error, 1)
+	go func() {
+		_, err := cache.HeadRevision(ctx, false)
+		firstErr <- err
+	}()
+	<-started
+
+	secondRevision := make(chan uint64, 1)
+	go func() {
+		revision, err := cache.HeadRevision(context.Background(), false)
+		require.NoError(err)
+		secondRevision <- revision
+	}()
+
+	cancel()
+	require.ErrorIs(<-firstErr, context.Canceled)
+	require.Equal(uint64(5), <-secondRevision)
+
+	// A failed load is not shared
+	require.Equal(uint64(2), atomic.LoadUint64(&loads))
+}
+
+func TestHeadRevisionCacheKeepsLatest(t *testing.T) {
+	require := require.New(t)
+
+	mockTime := clock.NewMock()
+	head := uint64(10)
+	cache := NewHeadRevisionCache(func(ctx context.Context) (uint64, error) {
+		return head, nil
+	}, 100*time.Millisecond, mockTime)
+
+	revision, err := cache.HeadRevision(context.Background(), true)
+	require.NoError(err)
+	require.Equal(uint64(10), revision)
+
+	// An older revision loaded by a slower query does not replace a newer one
+	head = 9
+	revision, err = cache.HeadRevision(context.Background(), true)
+	require.NoError(err)
+	require.Equal(uint64(9), revision)
+
+	revision, err = cache.HeadRevision(context.Background(), false)
+	require.NoError(err)
+	require.Equal(uint64(10), revision)
+}
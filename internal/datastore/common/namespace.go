@@ -0,0 +1,192 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RenameNamespace renames a namespace by rewriting its definition, the subject types of every
+// definition which reference it, and every relationship that references it, on either the
+// resource or subject side, using only the generic transaction operations. The schema source
+// recorded for the rewritten definitions is dropped, since it still names the old namespace. Datastores without a more efficient native implementation can
+// use this to implement ReadWriteTransaction.RenameNamespace.
+func RenameNamespace(ctx context.Context, rwt datastore.ReadWriteTransaction, oldName, newName string) error {
+	if oldName == newName {
+		return fmt.Errorf("cannot rename namespace `%s` to itself", oldName)
+	}
+
+	existing, _, err := rwt.ReadNamespace(ctx, oldName)
+	if err != nil {
+		return err
+	}
+
+	if err := ensureNamespaceMissing(ctx, rwt, newName); err != nil {
+		return fmt.Errorf("cannot rename namespace `%s`: %w", oldName, err)
+	}
+
+	resourceIter, err := rwt.QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: oldName})
+	if err != nil {
+		return err
+	}
+
+	renamed := make(map[string]*core.RelationTuple)
+	if err := collectTuples(resourceIter, renamed); err != nil {
+		return err
+	}
+
+	subjectIter, err := rwt.ReverseQueryRelationships(ctx, &v1.SubjectFilter{SubjectType: oldName})
+	if err != nil {
+		return err
+	}
+
+	if err := collectTuples(subjectIter, renamed); err != nil {
+		return err
+	}
+
+	mutations := make([]*v1.RelationshipUpdate, 0, len(renamed)*2)
+	for _, tpl := range renamed {
+		mutations = append(mutations, tuple.UpdateToRelationshipUpdate(tuple.Delete(tpl)))
+
+		updated := proto.Clone(tpl).(*core.RelationTuple)
+		if updated.ResourceAndRelation.Namespace == oldName {
+			updated.ResourceAndRelation.Namespace = newName
+		}
+		if updated.Subject.Namespace == oldName {
+			updated.Subject.Namespace = newName
+		}
+		mutations = append(mutations, tuple.UpdateToRelationshipUpdate(tuple.Create(updated)))
+	}
+
+	renamedDef := proto.Clone(existing).(*core.NamespaceDefinition)
+	renamedDef.Name = newName
+	namespace.RenameTypeReferences(renamedDef, oldName, newName)
+	namespace.ClearDefinitionSource(renamedDef)
+
+	if err := rwt.WriteNamespaces(renamedDef); err != nil {
+		return err
+	}
+
+	if err := RenameNamespaceReferences(ctx, rwt, oldName, newName); err != nil {
+		return err
+	}
+
+	if err := rwt.WriteRelationships(mutations); err != nil {
+		return err
+	}
+
+	return rwt.DeleteNamespace(oldName)
+}
+
+// RenameNamespaceReferences rewrites the definition of every namespace whose relations allow
+// subjects of the namespace oldName to allow subjects of newName instead, so that the schema
+// remains valid after a rename.
+func RenameNamespaceReferences(ctx context.Context, rwt datastore.ReadWriteTransaction, oldName, newName string) error {
+	existing, err := rwt.ListNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	var updated []*core.NamespaceDefinition
+	for _, nsdef := range existing {
+		if nsdef.Name == oldName {
+			continue
+		}
+
+		rewritten := proto.Clone(nsdef).(*core.NamespaceDefinition)
+		if namespace.RenameTypeReferences(rewritten, oldName, newName) {
+			updated = append(updated, rewritten)
+		}
+	}
+
+	if len(updated) == 0 {
+		return nil
+	}
+
+	return rwt.WriteNamespaces(updated...)
+}
+
+// CopyNamespace writes a copy of a namespace definition under a new name and, if copyTuples
+// is set, copies every tuple whose resource is in the source namespace into the new
+// namespace, returning the number of tuples copied. The copy does not keep the schema source
+// recorded for the original definition. Datastores without a more efficient
+// native implementation can use this to implement ReadWriteTransaction.CopyNamespace.
+func CopyNamespace(ctx context.Context, rwt datastore.ReadWriteTransaction, sourceName, destName string, copyTuples bool) (uint64, error) {
+	existing, _, err := rwt.ReadNamespace(ctx, sourceName)
+	if err != nil {
+		return 0, err
+	}
+
+	if err := ensureNamespaceMissing(ctx, rwt, destName); err != nil {
+		return 0, fmt.Errorf("cannot copy namespace `%s`: %w", sourceName, err)
+	}
+
+	copiedDef := proto.Clone(existing).(*core.NamespaceDefinition)
+	copiedDef.Name = destName
+	namespace.ClearDefinitionSource(copiedDef)
+
+	if err := rwt.WriteNamespaces(copiedDef); err != nil {
+		return 0, err
+	}
+
+	if !copyTuples {
+		return 0, nil
+	}
+
+	iter, err := rwt.QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: sourceName})
+	if err != nil {
+		return 0, err
+	}
+
+	found := make(map[string]*core.RelationTuple)
+	if err := collectTuples(iter, found); err != nil {
+		return 0, err
+	}
+
+	mutations := make([]*v1.RelationshipUpdate, 0, len(found))
+	for _, tpl := range found {
+		copied := proto.Clone(tpl).(*core.RelationTuple)
+		copied.ResourceAndRelation.Namespace = destName
+		mutations = append(mutations, tuple.UpdateToRelationshipUpdate(tuple.Create(copied)))
+	}
+
+	if len(mutations) == 0 {
+		return 0, nil
+	}
+
+	if err := rwt.WriteRelationships(mutations); err != nil {
+		return 0, err
+	}
+
+	return uint64(len(mutations)), nil
+}
+
+func ensureNamespaceMissing(ctx context.Context, reader datastore.Reader, nsName string) error {
+	_, _, err := reader.ReadNamespace(ctx, nsName)
+	switch {
+	case errors.As(err, &datastore.ErrNamespaceNotFound{}):
+		return nil
+	case err == nil:
+		return datastore.NewNamespaceAlreadyExistsErr(nsName)
+	default:
+		return err
+	}
+}
+
+func collectTuples(iter datastore.RelationshipIterator, into map[string]*core.RelationTuple) error {
+	defer iter.Close()
+
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		into[tuple.String(tpl)] = tpl
+	}
+
+	return iter.Err()
+}
@@ -0,0 +1,61 @@
+package common
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rs/zerolog/log"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// NullSubjectRelation determines how a tuple whose subject relation column is NULL is read. SpiceDB
+// never writes such tuples, but a migration or another writer of the table may leave them behind.
+type NullSubjectRelation int
+
+const (
+	// NullSubjectRelationAsEllipsis reads a NULL subject relation as the ellipsis, making the
+	// subject of the tuple an object.
+	NullSubjectRelationAsEllipsis NullSubjectRelation = iota
+
+	// NullSubjectRelationAsEmpty reads a NULL subject relation as the empty string.
+	NullSubjectRelationAsEmpty
+
+	// NullSubjectRelationAsError fails the read of a tuple whose subject relation is NULL.
+	NullSubjectRelationAsError
+)
+
+// SubjectRelationFromColumn returns the subject relation of a tuple from the value scanned from
+// its subject relation column, which is nil if the column is NULL. Unless NULLs are handled as
+// errors, a warning is logged for each tuple with a NULL subject relation, so that one malformed
+// row does not fail a whole query or watch.
+func (handling NullSubjectRelation) SubjectRelationFromColumn(ctx context.Context, tpl *core.RelationTuple, relation *string) (string, error) {
+	if relation != nil {
+		return *relation, nil
+	}
+
+	var substitute string
+	switch handling {
+	case NullSubjectRelationAsError:
+		return "", fmt.Errorf(
+			"subject relation of relationship %s@%s:%s is NULL",
+			tuple.StringONR(tpl.ResourceAndRelation),
+			tpl.Subject.Namespace,
+			tpl.Subject.ObjectId,
+		)
+	case NullSubjectRelationAsEmpty:
+		substitute = ""
+	default:
+		substitute = tuple.Ellipsis
+	}
+
+	log.Ctx(ctx).Warn().
+		Str("resource", tuple.StringONR(tpl.ResourceAndRelation)).
+		Str("subjectType", tpl.Subject.Namespace).
+		Str("subjectID", tpl.Subject.ObjectId).
+		Str("substitute", substitute).
+		Msg("read relationship with a NULL subject relation")
+
+	return substitute, nil
+}
@@ -0,0 +1,46 @@
+package common
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestSubjectRelationFromColumn(t *testing.T) {
+	viewer := "viewer"
+
+	testCases := []struct {
+		name             string
+		handling         NullSubjectRelation
+		scanned          *string
+		expectedRelation string
+		expectedError    string
+	}{
+		{"relation as ellipsis", NullSubjectRelationAsEllipsis, &viewer, "viewer", ""},
+		{"relation as empty", NullSubjectRelationAsEmpty, &viewer, "viewer", ""},
+		{"relation as error", NullSubjectRelationAsError, &viewer, "viewer", ""},
+		{"null as ellipsis", NullSubjectRelationAsEllipsis, nil, tuple.Ellipsis, ""},
+		{"null as empty", NullSubjectRelationAsEmpty, nil, "", ""},
+		{"null as error", NullSubjectRelationAsError, nil, "", "subject relation of relationship document:foo#viewer@user:tom is NULL"},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			tpl := tuple.Parse("document:foo#viewer@user:tom")
+			relation, err := tc.handling.SubjectRelationFromColumn(context.Background(), tpl, tc.scanned)
+			if tc.expectedError != "" {
+				require.EqualError(err, tc.expectedError)
+				return
+			}
+
+			require.NoError(err)
+			require.Equal(tc.expectedRelation, relation)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// RetryPolicy configures the retrying of queries which fail with transient errors, such as
+// connection resets or a failover of the database.
+type RetryPolicy struct {
+	// MaxRetries is the maximum number of times a query is retried. Zero disables retries.
+	MaxRetries uint8
+
+	// InitialBackoff is the delay before the first retry, which doubles with each further retry.
+	InitialBackoff time.Duration
+
+	// MaxBackoff is the maximum delay between retries.
+	MaxBackoff time.Duration
+
+	// IsRetryable returns whether an error is transient, and so worth retrying. Errors for which
+	// it returns false are returned unchanged.
+	IsRetryable func(err error) bool
+}
+
+// backoff returns the delay before the given retry, starting at zero.
+func (rp RetryPolicy) backoff(retry uint8) time.Duration {
+	backoff := rp.InitialBackoff
+	for i := uint8(0); i < retry && backoff < rp.MaxBackoff; i++ {
+		backoff *= 2
+	}
+
+	if backoff > rp.MaxBackoff {
+		return rp.MaxBackoff
+	}
+	return backoff
+}
+
+// Retry runs fn, retrying it with exponential backoff for as long as it fails with a retryable
+// error, up to the maximum number of retries. Waiting for a retry stops when the context is done.
+func (rp RetryPolicy) Retry(ctx context.Context, fn func(ctx context.Context) error) error {
+	var err error
+	for retry := uint8(0); ; retry++ {
+		err = fn(ctx)
+		if err == nil || rp.IsRetryable == nil || !rp.IsRetryable(err) {
+			return err
+		}
+
+		if retry >= rp.MaxRetries {
+			if rp.MaxRetries == 0 {
+				return err
+			}
+			return fmt.Errorf("max retries exceeded: %w", err)
+		}
+
+		backoff := WithJitter(0.2, rp.backoff(retry))
+		log.Ctx(ctx).Debug().Err(err).Dur("backoff", backoff).Uint8("retry", retry+1).Msg("retrying query after transient error")
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%w: stopped retrying after: %s", ctx.Err(), err)
+		case <-timer.C:
+		}
+	}
+}
+
+// NewRetryingExecutor wraps an executor so that queries failing with transient errors are
+// retried according to the policy. As the executor loads all of the results of a query, a
+// retried query never returns partial results.
+func NewRetryingExecutor(executor ExecuteQueryFunc, policy RetryPolicy) ExecuteQueryFunc {
+	return func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error) {
+		var tuples []*core.RelationTuple
+		err := policy.Retry(ctx, func(ctx context.Context) error {
+			var err error
+			tuples, err = executor(ctx, sql, args)
+			return err
+		})
+		return tuples, err
+	}
+}
+
+// NewRetryingRevisionsExecutor wraps a revisions executor so that queries failing with transient
+// errors are retried according to the policy, like NewRetryingExecutor.
+func NewRetryingRevisionsExecutor(executor ExecuteRevisionsQueryFunc, policy RetryPolicy) ExecuteRevisionsQueryFunc {
+	return func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, map[*core.RelationTuple]datastore.RevisionMetadata, error) {
+		var tuples []*core.RelationTuple
+		var revisions map[*core.RelationTuple]datastore.RevisionMetadata
+		err := policy.Retry(ctx, func(ctx context.Context) error {
+			var err error
+			tuples, revisions, err = executor(ctx, sql, args)
+			return err
+		})
+		return tuples, revisions, err
+	}
+}
+
+// NewRetryingStreamingExecutor wraps a streaming executor so that queries failing with transient
+// errors before returning their iterator are retried according to the policy. Errors reading the
+// rows once iteration has started are not retried, as tuples may have been returned already.
+func NewRetryingStreamingExecutor(executor StreamQueryFunc, policy RetryPolicy) StreamQueryFunc {
+	return func(ctx context.Context, sql string, args []any) (datastore.RelationshipIterator, error) {
+		var iter datastore.RelationshipIterator
+		err := policy.Retry(ctx, func(ctx context.Context) error {
+			var err error
+			iter, err = executor(ctx, sql, args)
+			return err
+		})
+		return iter, err
+	}
+}
@@ -0,0 +1,126 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+var (
+	errTransient = errors.New("connection reset by peer")
+	errPermanent = errors.New("syntax error")
+)
+
+// fakeConnection fails the given number of queries with an error, and then succeeds.
+type fakeConnection struct {
+	failures int
+	err      error
+	queries  int
+}
+
+func (fc *fakeConnection) execute(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error) {
+	fc.queries++
+	if fc.queries <= fc.failures {
+		return nil, fc.err
+	}
+	return []*core.RelationTuple{tuple.MustParse("document:doc1#viewer@user:tom")}, nil
+}
+
+func testRetryPolicy(maxRetries uint8) RetryPolicy {
+	return RetryPolicy{
+		MaxRetries:     maxRetries,
+		InitialBackoff: time.Millisecond,
+		MaxBackoff:     4 * time.Millisecond,
+		IsRetryable: func(err error) bool {
+			return errors.Is(err, errTransient)
+		},
+	}
+}
+
+func TestRetryingExecutor(t *testing.T) {
+	testCases := []struct {
+		name            string
+		maxRetries      uint8
+		failures        int
+		err             error
+		expectedQueries int
+		expectedErr     error
+	}{
+		{"succeeds immediately", 3, 0, errTransient, 1, nil},
+		{"succeeds after transient failures", 3, 2, errTransient, 3, nil},
+		{"succeeds on last retry", 3, 3, errTransient, 4, nil},
+		{"exceeds max retries", 3, 4, errTransient, 4, errTransient},
+		{"retries disabled", 0, 1, errTransient, 1, errTransient},
+		{"permanent error not retried", 3, 1, errPermanent, 1, errPermanent},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			conn := &fakeConnection{failures: tc.failures, err: tc.err}
+			executor := NewRetryingExecutor(conn.execute, testRetryPolicy(tc.maxRetries))
+
+			tuples, err := executor(context.Background(), "SELECT", nil)
+			require.Equal(tc.expectedQueries, conn.queries)
+			if tc.expectedErr != nil {
+				require.ErrorIs(err, tc.expectedErr)
+				require.Nil(tuples)
+				return
+			}
+
+			require.NoError(err)
+			require.Len(tuples, 1)
+		})
+	}
+}
+
+func TestRetryPermanentErrorUnchanged(t *testing.T) {
+	err := testRetryPolicy(3).Retry(context.Background(), func(ctx context.Context) error {
+		return errPermanent
+	})
+	require.Same(t, errPermanent, err)
+}
+
+func TestRetryRespectsCancellation(t *testing.T) {
+	require := require.New(t)
+
+	policy := testRetryPolicy(10)
+	policy.InitialBackoff = time.Hour
+	policy.MaxBackoff = time.Hour
+
+	ctx, cancel := context.WithCancel(context.Background())
+	conn := &fakeConnection{failures: 10, err: errTransient}
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := NewRetryingExecutor(conn.execute, policy)(ctx, "SELECT", nil)
+		done <- err
+	}()
+
+	cancel()
+	select {
+	case err := <-done:
+		require.ErrorIs(err, context.Canceled)
+	case <-time.After(5 * time.Second):
+		require.Fail("retry did not stop when the context was canceled")
+	}
+	require.Equal(1, conn.queries)
+}
+
+func TestRetryBackoff(t *testing.T) {
+	policy := RetryPolicy{InitialBackoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+
+	require.Equal(t, 10*time.Millisecond, policy.backoff(0))
+	require.Equal(t, 20*time.Millisecond, policy.backoff(1))
+	require.Equal(t, 40*time.Millisecond, policy.backoff(2))
+	require.Equal(t, 50*time.Millisecond, policy.backoff(3))
+	require.Equal(t, 50*time.Millisecond, policy.backoff(200))
+}
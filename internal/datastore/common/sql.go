@@ -2,6 +2,7 @@ package common
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"math"
 	"runtime"
@@ -18,12 +19,15 @@ import (
 
 	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 const (
 	errUnableToQueryTuples = "unable to query tuples: %w"
 )
 
+var errClosedIterator = errors.New("unable to iterate: iterator closed")
+
 var (
 	// ObjNamespaceNameKey is a tracing attribute representing the resource
 	// object type.
@@ -50,6 +54,14 @@ var (
 
 	limitKey = attribute.Key("authzed.com/spicedb/sql/limit")
 
+	// filterValueCountKey is a tracing attribute representing the number of usersets or tuples
+	// a query filters to, which determines how many times it is split.
+	filterValueCountKey = attribute.Key("authzed.com/spicedb/sql/filterValueCount")
+
+	// splitCountKey is a tracing attribute representing the number of queries executed after
+	// splitting a query.
+	splitCountKey = attribute.Key("authzed.com/spicedb/sql/splitCount")
+
 	tracer = otel.Tracer("spicedb/internal/datastore/common")
 )
 
@@ -67,9 +79,10 @@ type SchemaInformation struct {
 // SchemaQueryFilterer wraps a SchemaInformation and SelectBuilder to give an opinionated
 // way to build query objects.
 type SchemaQueryFilterer struct {
-	schema           SchemaInformation
-	queryBuilder     sq.SelectBuilder
-	tracerAttributes []attribute.KeyValue
+	schema             SchemaInformation
+	queryBuilder       sq.SelectBuilder
+	tracerAttributes   []attribute.KeyValue
+	ignoreObjectIDCase bool
 }
 
 // NewSchemaQueryFilterer creates a new SchemaQueryFilterer object.
@@ -80,6 +93,14 @@ func NewSchemaQueryFilterer(schema SchemaInformation, initialQuery sq.SelectBuil
 	}
 }
 
+// FilterWith returns a new SchemaQueryFilterer with the filter applied to its query, after the
+// filters applied so far. Filters which are applied later add their predicates later in the
+// generated SQL.
+func (sqf SchemaQueryFilterer) FilterWith(filter func(sq.SelectBuilder) sq.SelectBuilder) SchemaQueryFilterer {
+	sqf.queryBuilder = filter(sqf.queryBuilder)
+	return sqf
+}
+
 // FilterToResourceType returns a new SchemaQueryFilterer that is limited to resources of the
 // specified type.
 func (sqf SchemaQueryFilterer) FilterToResourceType(resourceType string) SchemaQueryFilterer {
@@ -88,10 +109,53 @@ func (sqf SchemaQueryFilterer) FilterToResourceType(resourceType string) SchemaQ
 	return sqf
 }
 
+// FilterToResourceTypes returns a new SchemaQueryFilterer that is limited to resources of any of
+// the specified types.
+func (sqf SchemaQueryFilterer) FilterToResourceTypes(resourceTypes ...string) SchemaQueryFilterer {
+	if len(resourceTypes) == 1 {
+		return sqf.FilterToResourceType(resourceTypes[0])
+	}
+
+	sqf.queryBuilder = sqf.queryBuilder.Where(sq.Eq{sqf.schema.ColNamespace: resourceTypes})
+	sqf.tracerAttributes = append(sqf.tracerAttributes, ObjNamespaceNameKey.StringSlice(resourceTypes))
+	return sqf
+}
+
+// FilterToResourceRelation returns a new SchemaQueryFilterer that is limited to resources of the
+// specified type and with the specified relation. Either may be empty, in which case the query is
+// not filtered on it.
+func (sqf SchemaQueryFilterer) FilterToResourceRelation(resourceType, relation string) SchemaQueryFilterer {
+	if resourceType != "" {
+		sqf = sqf.FilterToResourceType(resourceType)
+	}
+
+	if relation != "" {
+		sqf = sqf.FilterToRelation(relation)
+	}
+
+	return sqf
+}
+
+// IgnoringObjectIDCase returns a new SchemaQueryFilterer whose later resource and subject ID
+// filters match IDs regardless of case, by comparing the lowercased columns.
+func (sqf SchemaQueryFilterer) IgnoringObjectIDCase() SchemaQueryFilterer {
+	sqf.ignoreObjectIDCase = true
+	return sqf
+}
+
+// objectIDEq returns a predicate matching the object ID column against the ID, regardless of
+// case if the filterer ignores object ID case.
+func (sqf SchemaQueryFilterer) objectIDEq(column, objectID string) sq.Sqlizer {
+	if sqf.ignoreObjectIDCase {
+		return sq.Expr("LOWER("+column+") = LOWER(?)", objectID)
+	}
+	return sq.Eq{column: objectID}
+}
+
 // FilterToResourceID returns a new SchemaQueryFilterer that is limited to resources with the
 // specified ID.
 func (sqf SchemaQueryFilterer) FilterToResourceID(objectID string) SchemaQueryFilterer {
-	sqf.queryBuilder = sqf.queryBuilder.Where(sq.Eq{sqf.schema.ColObjectID: objectID})
+	sqf.queryBuilder = sqf.queryBuilder.Where(sqf.objectIDEq(sqf.schema.ColObjectID, objectID))
 	sqf.tracerAttributes = append(sqf.tracerAttributes, ObjIDKey.String(objectID))
 	return sqf
 }
@@ -107,21 +171,45 @@ func (sqf SchemaQueryFilterer) FilterToRelation(relation string) SchemaQueryFilt
 // FilterToSubjectFilter returns a new SchemaQueryFilterer that is limited to resources with
 // subjects that match the specified filter.
 func (sqf SchemaQueryFilterer) FilterToSubjectFilter(filter *v1.SubjectFilter) SchemaQueryFilterer {
+	return sqf.FilterToReverseQuerySubject(filter, &options.ReverseQueryOptions{})
+}
+
+// FilterToReverseQuerySubject returns a new SchemaQueryFilterer that is limited to resources with
+// subjects that match the specified filter, broadened as requested by the IncludeEllipsisRelation
+// and IncludeWildcardSubject reverse query options.
+func (sqf SchemaQueryFilterer) FilterToReverseQuerySubject(filter *v1.SubjectFilter, queryOpts *options.ReverseQueryOptions) SchemaQueryFilterer {
 	sqf.queryBuilder = sqf.queryBuilder.Where(sq.Eq{sqf.schema.ColUsersetNamespace: filter.SubjectType})
 	sqf.tracerAttributes = append(sqf.tracerAttributes, SubNamespaceNameKey.String(filter.SubjectType))
 
+	var subjectClauses sq.And
 	if filter.OptionalSubjectId != "" {
-		sqf.queryBuilder = sqf.queryBuilder.Where(sq.Eq{sqf.schema.ColUsersetObjectID: filter.OptionalSubjectId})
+		subjectClauses = append(subjectClauses, sqf.objectIDEq(sqf.schema.ColUsersetObjectID, filter.OptionalSubjectId))
 		sqf.tracerAttributes = append(sqf.tracerAttributes, SubObjectIDKey.String(filter.OptionalSubjectId))
 	}
 
 	if filter.OptionalRelation != nil {
 		dsRelationName := stringz.DefaultEmpty(filter.OptionalRelation.Relation, datastore.Ellipsis)
 
-		sqf.queryBuilder = sqf.queryBuilder.Where(sq.Eq{sqf.schema.ColUsersetRelation: dsRelationName})
+		if queryOpts.IncludeEllipsisRelation && dsRelationName != datastore.Ellipsis {
+			subjectClauses = append(subjectClauses, sq.Eq{sqf.schema.ColUsersetRelation: []string{dsRelationName, datastore.Ellipsis}})
+		} else {
+			subjectClauses = append(subjectClauses, sq.Eq{sqf.schema.ColUsersetRelation: dsRelationName})
+		}
 		sqf.tracerAttributes = append(sqf.tracerAttributes, SubRelationNameKey.String(dsRelationName))
 	}
 
+	// The wildcard subject always has the ellipsis relation, so it is matched by object ID alone.
+	if queryOpts.IncludeWildcardSubject && filter.OptionalSubjectId != "" && filter.OptionalSubjectId != tuple.PublicWildcard {
+		sqf.queryBuilder = sqf.queryBuilder.Where(sq.Or{
+			subjectClauses,
+			sq.Eq{sqf.schema.ColUsersetObjectID: tuple.PublicWildcard},
+		})
+		return sqf
+	}
+
+	for _, clause := range subjectClauses {
+		sqf.queryBuilder = sqf.queryBuilder.Where(clause)
+	}
 	return sqf
 }
 
@@ -147,6 +235,48 @@ func (sqf SchemaQueryFilterer) filterToUsersets(usersets []*core.ObjectAndRelati
 	return sqf
 }
 
+// FilterToExcludedSubjects returns a new SchemaQueryFilterer that excludes the tuples whose
+// subjects are any of the specified usersets. Nil or empty usersets parameter does not affect the
+// underlying query.
+func (sqf SchemaQueryFilterer) FilterToExcludedSubjects(usersets []*core.ObjectAndRelation) SchemaQueryFilterer {
+	if len(usersets) == 0 {
+		return sqf
+	}
+
+	// Row value comparisons are not supported by all datastores, so the NOT IN is expanded to:
+	// (c0 <> v0 OR c1 <> v1 OR c2 <> v2) AND ...
+	andClause := sq.And{}
+	for _, userset := range usersets {
+		andClause = append(andClause, sq.Or{
+			sq.NotEq{sqf.schema.ColUsersetNamespace: userset.Namespace},
+			sq.NotEq{sqf.schema.ColUsersetObjectID: userset.ObjectId},
+			sq.NotEq{sqf.schema.ColUsersetRelation: userset.Relation},
+		})
+	}
+
+	sqf.queryBuilder = sqf.queryBuilder.Where(andClause)
+
+	return sqf
+}
+
+// filterToTuples returns a new SchemaQueryFilterer that is limited to the given tuples.
+func (sqf SchemaQueryFilterer) filterToTuples(tuples []*core.RelationTuple) SchemaQueryFilterer {
+	orClause := sq.Or{}
+	for _, tpl := range tuples {
+		orClause = append(orClause, sq.Eq{
+			sqf.schema.ColNamespace:        tpl.ResourceAndRelation.Namespace,
+			sqf.schema.ColObjectID:         tpl.ResourceAndRelation.ObjectId,
+			sqf.schema.ColRelation:         tpl.ResourceAndRelation.Relation,
+			sqf.schema.ColUsersetNamespace: tpl.Subject.Namespace,
+			sqf.schema.ColUsersetObjectID:  tpl.Subject.ObjectId,
+			sqf.schema.ColUsersetRelation:  tpl.Subject.Relation,
+		})
+	}
+
+	sqf.queryBuilder = sqf.queryBuilder.Where(orClause)
+	return sqf
+}
+
 // Limit returns a new SchemaQueryFilterer which is limited to the specified number of results.
 func (sqf SchemaQueryFilterer) limit(limit uint64) SchemaQueryFilterer {
 	sqf.queryBuilder = sqf.queryBuilder.Limit(limit)
@@ -154,10 +284,23 @@ func (sqf SchemaQueryFilterer) limit(limit uint64) SchemaQueryFilterer {
 	return sqf
 }
 
+// ToSql renders the filtered query to SQL.
+func (sqf SchemaQueryFilterer) ToSql() (string, []any, error) {
+	return sqf.queryBuilder.ToSql()
+}
+
 // TupleQuerySplitter is a tuple query runner shared by SQL implementations of the datastore.
 type TupleQuerySplitter struct {
 	Executor         ExecuteQueryFunc
 	UsersetBatchSize uint16
+
+	// RevisionsExecutor, if set, executes the queries made with options.WithRevisionMetadata,
+	// which must then select the created and deleted transaction IDs of each tuple.
+	RevisionsExecutor ExecuteRevisionsQueryFunc
+
+	// StreamingExecutor, if set, executes the queries made with options.WithStreamResults
+	// which do not need to be split, returning an iterator which reads the tuples lazily.
+	StreamingExecutor StreamQueryFunc
 }
 
 // SplitAndExecuteQuery is used to split up the usersets in a very large query and execute
@@ -171,14 +314,51 @@ func (tqs TupleQuerySplitter) SplitAndExecuteQuery(
 	defer span.End()
 	queryOpts := options.NewQueryOptionsWithOptions(opts...)
 
+	span.SetAttributes(query.tracerAttributes...)
+	span.SetAttributes(filterValueCountKey.Int(len(queryOpts.Usersets)))
+
 	var tuples []*core.RelationTuple
+	var revisions map[*core.RelationTuple]datastore.RevisionMetadata
+	withRevisions := queryOpts.IncludeRevisions && tqs.RevisionsExecutor != nil
+	if withRevisions {
+		revisions = make(map[*core.RelationTuple]datastore.RevisionMetadata)
+	}
+
 	remainingLimit := math.MaxInt
 	if queryOpts.Limit != nil {
 		remainingLimit = int(*queryOpts.Limit)
 	}
 
+	// Only a single tuple past MaxResults is needed to tell that the results exceed it
+	if queryOpts.MaxResults > 0 && uint64(remainingLimit) > queryOpts.MaxResults {
+		remainingLimit = int(queryOpts.MaxResults + 1)
+	}
+
+	if queryOpts.Sorted {
+		query = query.SortedAfter(queryOpts.After)
+	}
+
 	remainingUsersets := queryOpts.Usersets
+	splitQuery := len(remainingUsersets) > int(tqs.UsersetBatchSize)
+	if queryOpts.StreamResults && tqs.StreamingExecutor != nil && !splitQuery && !withRevisions {
+		span.SetAttributes(splitCountKey.Int(1))
+
+		sql, args, err := query.limit(uint64(remainingLimit)).filterToUsersets(remainingUsersets).queryBuilder.ToSql()
+		if err != nil {
+			return nil, err
+		}
+
+		iter, err := tqs.StreamingExecutor(ctx, sql, args)
+		if err != nil {
+			return nil, err
+		}
+		return datastore.NewMaxResultsIterator(iter, queryOpts.MaxResults), nil
+	}
+
+	splitCount := 0
 	for remaining := 1; remaining > 0; remaining = len(remainingUsersets) {
+		splitCount++
+
 		upperBound := uint16(len(remainingUsersets))
 		if upperBound > tqs.UsersetBatchSize {
 			upperBound = tqs.UsersetBatchSize
@@ -192,7 +372,16 @@ func (tqs TupleQuerySplitter) SplitAndExecuteQuery(
 			return nil, err
 		}
 
-		queryTuples, err := tqs.Executor(ctx, sql, args)
+		var queryTuples []*core.RelationTuple
+		if withRevisions {
+			var queryRevisions map[*core.RelationTuple]datastore.RevisionMetadata
+			queryTuples, queryRevisions, err = tqs.RevisionsExecutor(ctx, sql, args)
+			for tpl, metadata := range queryRevisions {
+				revisions[tpl] = metadata
+			}
+		} else {
+			queryTuples, err = tqs.Executor(ctx, sql, args)
+		}
 		if err != nil {
 			return nil, err
 		}
@@ -204,15 +393,85 @@ func (tqs TupleQuerySplitter) SplitAndExecuteQuery(
 		tuples = append(tuples, queryTuples...)
 		remainingUsersets = remainingUsersets[upperBound:]
 	}
+	span.SetAttributes(splitCountKey.Int(splitCount))
+
+	// Each batch of a split sorted query is sorted separately, so the results must be merged
+	if queryOpts.Sorted && splitQuery {
+		SortTuples(tuples)
+		if len(tuples) > remainingLimit {
+			tuples = tuples[:remainingLimit]
+		}
+	}
 
-	iter := datastore.NewSliceRelationshipIterator(tuples)
+	var iter datastore.RelationshipIterator
+	if withRevisions {
+		iter = datastore.NewSliceRelationshipIteratorWithRevisions(tuples, revisions)
+	} else {
+		iter = datastore.NewSliceRelationshipIterator(tuples)
+	}
 	runtime.SetFinalizer(iter, datastore.BuildFinalizerFunction())
-	return iter, nil
+	return datastore.NewMaxResultsIterator(iter, queryOpts.MaxResults), nil
+}
+
+// SplitAndCheckTuplesExist checks which of the tuples exist, keyed by tuple.String, querying
+// for them in batches of at most UsersetBatchSize tuples.
+func (tqs TupleQuerySplitter) SplitAndCheckTuplesExist(
+	ctx context.Context,
+	query SchemaQueryFilterer,
+	tuples []*core.RelationTuple,
+) (map[string]bool, error) {
+	ctx, span := tracer.Start(ctx, "SplitAndCheckTuplesExist")
+	defer span.End()
+
+	span.SetAttributes(query.tracerAttributes...)
+	span.SetAttributes(filterValueCountKey.Int(len(tuples)))
+
+	exists := make(map[string]bool, len(tuples))
+	for _, tpl := range tuples {
+		exists[tuple.String(tpl)] = false
+	}
+
+	remainingTuples := tuples
+	splitCount := 0
+	for len(remainingTuples) > 0 {
+		splitCount++
+		upperBound := uint16(len(remainingTuples))
+		if upperBound > tqs.UsersetBatchSize {
+			upperBound = tqs.UsersetBatchSize
+		}
+
+		sql, args, err := query.filterToTuples(remainingTuples[:upperBound]).queryBuilder.ToSql()
+		if err != nil {
+			return nil, err
+		}
+
+		found, err := tqs.Executor(ctx, sql, args)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, tpl := range found {
+			exists[tuple.String(tpl)] = true
+		}
+
+		remainingTuples = remainingTuples[upperBound:]
+	}
+	span.SetAttributes(splitCountKey.Int(splitCount))
+
+	return exists, nil
 }
 
 // ExecuteQueryFunc is a function that can be used to execute a single rendered SQL query.
 type ExecuteQueryFunc func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error)
 
+// ExecuteRevisionsQueryFunc is a function that can be used to execute a single rendered SQL query
+// which selects the revisions of each tuple, returning them along with the tuples.
+type ExecuteRevisionsQueryFunc func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, map[*core.RelationTuple]datastore.RevisionMetadata, error)
+
+// StreamQueryFunc is a function that can be used to execute a single rendered SQL query,
+// returning an iterator which reads the resulting tuples as it is advanced.
+type StreamQueryFunc func(ctx context.Context, sql string, args []any) (datastore.RelationshipIterator, error)
+
 // TxCleanupFunc is a function that should be executed when the caller of
 // TransactionFactory is done with the transaction.
 type TxCleanupFunc func(context.Context)
@@ -222,53 +481,281 @@ type TxCleanupFunc func(context.Context)
 type TxFactory func(context.Context) (pgx.Tx, TxCleanupFunc, error)
 
 // NewPGXExecutor creates an executor that uses the pgx library to make the specified queries.
-func NewPGXExecutor(txSource TxFactory) ExecuteQueryFunc {
+// Tuples with a NULL subject relation are read according to nullSubjectRelation.
+func NewPGXExecutor(txSource TxFactory, nullSubjectRelation NullSubjectRelation) ExecuteQueryFunc {
 	return func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error) {
-		ctx = datastore.SeparateContextWithTracing(ctx)
+		tuples, _, err := queryPGXTuples(ctx, txSource, nullSubjectRelation, sql, args, nil)
+		return tuples, err
+	}
+}
+
+// NewPGXRevisionsExecutor creates an executor that uses the pgx library to make the specified
+// queries, which select the created and deleted transaction IDs of each tuple after its other
+// columns. The transaction IDs are converted to revisions with toRevision, and tuples with a NULL
+// subject relation are read according to nullSubjectRelation.
+func NewPGXRevisionsExecutor(txSource TxFactory, nullSubjectRelation NullSubjectRelation, toRevision func(txID uint64) datastore.Revision) ExecuteRevisionsQueryFunc {
+	return func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, map[*core.RelationTuple]datastore.RevisionMetadata, error) {
+		return queryPGXTuples(ctx, txSource, nullSubjectRelation, sql, args, toRevision)
+	}
+}
+
+func queryPGXTuples(
+	ctx context.Context,
+	txSource TxFactory,
+	nullSubjectRelation NullSubjectRelation,
+	sql string,
+	args []any,
+	toRevision func(txID uint64) datastore.Revision,
+) ([]*core.RelationTuple, map[*core.RelationTuple]datastore.RevisionMetadata, error) {
+	ctx = datastore.SeparateContextWithTracing(ctx)
+
+	span := trace.SpanFromContext(ctx)
+
+	tx, txCleanup, err := txSource(ctx)
+	if err != nil {
+		return nil, nil, fmt.Errorf(errUnableToQueryTuples, err)
+	}
+	defer txCleanup(ctx)
+
+	span.AddEvent("DB transaction established")
+
+	rows, err := tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, nil, fmt.Errorf(errUnableToQueryTuples, err)
+	}
+	defer rows.Close()
+
+	span.AddEvent("Query issued to database")
+
+	withCaveats := hasCaveatColumns(rows, toRevision != nil)
+
+	var tuples []*core.RelationTuple
+	var revisions map[*core.RelationTuple]datastore.RevisionMetadata
+	if toRevision != nil {
+		revisions = make(map[*core.RelationTuple]datastore.RevisionMetadata)
+	}
+	for rows.Next() {
+		nextTuple, metadata, err := scanPGXTuple(ctx, rows, nullSubjectRelation, withCaveats, toRevision)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		if toRevision != nil {
+			revisions[nextTuple] = metadata
+		}
+
+		tuples = append(tuples, nextTuple)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, nil, fmt.Errorf(errUnableToQueryTuples, err)
+	}
+
+	span.AddEvent("Tuples loaded", trace.WithAttributes(attribute.Int("tupleCount", len(tuples))))
+	return tuples, revisions, nil
+}
+
+// hasCaveatColumns returns whether the rows select the caveat name and context columns. Queries
+// of datastores which store caveats select them after the six core columns, and queries for
+// revisions select the created and deleted transaction columns last.
+func hasCaveatColumns(rows pgx.Rows, withRevisions bool) bool {
+	fieldCount := len(rows.FieldDescriptions())
+	if withRevisions {
+		fieldCount -= 2
+	}
+	return fieldCount > 6
+}
 
-		span := trace.SpanFromContext(ctx)
+// scanPGXTuple scans the current row into a tuple, along with its revisions if toRevision is set.
+func scanPGXTuple(
+	ctx context.Context,
+	rows pgx.Rows,
+	nullSubjectRelation NullSubjectRelation,
+	withCaveats bool,
+	toRevision func(txID uint64) datastore.Revision,
+) (*core.RelationTuple, datastore.RevisionMetadata, error) {
+	nextTuple := &core.RelationTuple{
+		ResourceAndRelation: &core.ObjectAndRelation{},
+		Subject:             &core.ObjectAndRelation{},
+	}
+	var subjectRelation *string
+	dest := []any{
+		&nextTuple.ResourceAndRelation.Namespace,
+		&nextTuple.ResourceAndRelation.ObjectId,
+		&nextTuple.ResourceAndRelation.Relation,
+		&nextTuple.Subject.Namespace,
+		&nextTuple.Subject.ObjectId,
+		&subjectRelation,
+	}
+
+	var caveatName *string
+	var caveatContext []byte
+	if withCaveats {
+		dest = append(dest, &caveatName, &caveatContext)
+	}
+
+	var createdTxn uint64
+	var deletedTxn uint64
+	if toRevision != nil {
+		dest = append(dest, &createdTxn, &deletedTxn)
+	}
+
+	if err := rows.Scan(dest...); err != nil {
+		return nil, datastore.RevisionMetadata{}, fmt.Errorf(errUnableToQueryTuples, err)
+	}
 
-		tx, txCleanup, err := txSource(ctx)
+	var err error
+	nextTuple.Subject.Relation, err = nullSubjectRelation.SubjectRelationFromColumn(ctx, nextTuple, subjectRelation)
+	if err != nil {
+		return nil, datastore.RevisionMetadata{}, fmt.Errorf(errUnableToQueryTuples, err)
+	}
+
+	nextTuple.Caveat, err = CaveatFromColumns(caveatName, caveatContext)
+	if err != nil {
+		return nil, datastore.RevisionMetadata{}, fmt.Errorf(errUnableToQueryTuples, err)
+	}
+
+	if toRevision == nil {
+		return nextTuple, datastore.RevisionMetadata{}, nil
+	}
+
+	return nextTuple, datastore.RevisionMetadata{
+		CreatedRevision: toRevision(createdTxn),
+		DeletedRevision: toRevision(deletedTxn),
+	}, nil
+}
+
+// NewPGXStreamingExecutor creates an executor that uses the pgx library to make the specified
+// queries, returning iterators which read the rows as they are advanced. Each iterator holds the
+// transaction of its query until it is closed. Canceling the context of the query aborts it, which
+// closes its connection. Tuples with a NULL subject relation are read according to
+// nullSubjectRelation.
+func NewPGXStreamingExecutor(txSource TxFactory, nullSubjectRelation NullSubjectRelation) StreamQueryFunc {
+	return func(ctx context.Context, sql string, args []any) (datastore.RelationshipIterator, error) {
+		// The query runs under its own context, which is only canceled with the caller's context
+		// while the iterator is open, so that a canceled caller cannot interrupt the cleanup.
+		queryCtx, cancelQuery := context.WithCancel(datastore.SeparateContextWithTracing(ctx))
+
+		span := trace.SpanFromContext(queryCtx)
+
+		tx, txCleanup, err := txSource(queryCtx)
 		if err != nil {
+			cancelQuery()
 			return nil, fmt.Errorf(errUnableToQueryTuples, err)
 		}
-		defer txCleanup(ctx)
 
 		span.AddEvent("DB transaction established")
 
-		rows, err := tx.Query(ctx, sql, args...)
+		rows, err := tx.Query(queryCtx, sql, args...)
 		if err != nil {
+			txCleanup(queryCtx)
+			cancelQuery()
 			return nil, fmt.Errorf(errUnableToQueryTuples, err)
 		}
-		defer rows.Close()
 
 		span.AddEvent("Query issued to database")
 
-		var tuples []*core.RelationTuple
-		for rows.Next() {
-			nextTuple := &core.RelationTuple{
-				ResourceAndRelation: &core.ObjectAndRelation{},
-				Subject:             &core.ObjectAndRelation{},
+		go func() {
+			select {
+			case <-ctx.Done():
+				cancelQuery()
+			case <-queryCtx.Done():
 			}
-			err := rows.Scan(
-				&nextTuple.ResourceAndRelation.Namespace,
-				&nextTuple.ResourceAndRelation.ObjectId,
-				&nextTuple.ResourceAndRelation.Relation,
-				&nextTuple.Subject.Namespace,
-				&nextTuple.Subject.ObjectId,
-				&nextTuple.Subject.Relation,
-			)
-			if err != nil {
-				return nil, fmt.Errorf(errUnableToQueryTuples, err)
+		}()
+
+		iter := &pgxTupleIterator{
+			ctx:                 ctx,
+			queryCtx:            queryCtx,
+			cancelQuery:         cancelQuery,
+			txCleanup:           txCleanup,
+			rows:                rows,
+			nullSubjectRelation: nullSubjectRelation,
+			withCaveats:         hasCaveatColumns(rows, false),
+		}
+		runtime.SetFinalizer(iter, func(iter *pgxTupleIterator) {
+			if !iter.closed {
+				panic("Tuple iterator garbage collected before Close() was called")
 			}
+		})
+		return iter, nil
+	}
+}
 
-			tuples = append(tuples, nextTuple)
-		}
-		if err := rows.Err(); err != nil {
-			return nil, fmt.Errorf(errUnableToQueryTuples, err)
+// pgxTupleIterator is a datastore.RelationshipIterator which scans the tuples from the rows of a
+// query as it is advanced.
+type pgxTupleIterator struct {
+	ctx         context.Context
+	queryCtx    context.Context
+	cancelQuery context.CancelFunc
+	txCleanup   TxCleanupFunc
+	rows        pgx.Rows
+
+	nullSubjectRelation NullSubjectRelation
+	withCaveats         bool
+
+	last   *core.RelationTuple
+	done   bool
+	closed bool
+	err    error
+}
+
+// Next implements TupleIterator
+func (pti *pgxTupleIterator) Next() *core.RelationTuple {
+	if pti.closed {
+		pti.err = errClosedIterator
+		return nil
+	}
+
+	if pti.done {
+		return nil
+	}
+
+	if err := pti.ctx.Err(); err != nil {
+		return pti.fail(err)
+	}
+
+	if !pti.rows.Next() {
+		pti.done = true
+		if err := pti.rows.Err(); err != nil {
+			pti.err = fmt.Errorf(errUnableToQueryTuples, err)
 		}
+		return nil
+	}
 
-		span.AddEvent("Tuples loaded", trace.WithAttributes(attribute.Int("tupleCount", len(tuples))))
-		return tuples, nil
+	tpl, _, err := scanPGXTuple(pti.queryCtx, pti.rows, pti.nullSubjectRelation, pti.withCaveats, nil)
+	if err != nil {
+		return pti.fail(err)
 	}
+
+	pti.last = tpl
+	return tpl
+}
+
+// fail stops the iteration with the error, aborting the query.
+func (pti *pgxTupleIterator) fail(err error) *core.RelationTuple {
+	pti.done = true
+	pti.err = err
+	pti.cancelQuery()
+	return nil
+}
+
+// Err implements TupleIterator
+func (pti *pgxTupleIterator) Err() error {
+	return pti.err
+}
+
+// Cursor implements TupleIterator
+func (pti *pgxTupleIterator) Cursor() *core.RelationTuple {
+	return pti.last
+}
+
+// Close implements TupleIterator
+func (pti *pgxTupleIterator) Close() {
+	if pti.closed {
+		panic("tuple iterator double closed")
+	}
+	pti.closed = true
+
+	pti.rows.Close()
+	pti.txCleanup(pti.queryCtx)
+	pti.cancelQuery()
 }
@@ -0,0 +1,491 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	sq "github.com/Masterminds/squirrel"
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/jackc/pgproto3/v2"
+	"github.com/jackc/pgx/v4"
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+var testSchema = SchemaInformation{
+	TableTuple:          "relation_tuple",
+	ColNamespace:        "namespace",
+	ColObjectID:         "object_id",
+	ColRelation:         "relation",
+	ColUsersetNamespace: "userset_namespace",
+	ColUsersetObjectID:  "userset_object_id",
+	ColUsersetRelation:  "userset_relation",
+}
+
+func TestFilterToResourceRelation(t *testing.T) {
+	testCases := []struct {
+		name         string
+		resourceType string
+		relation     string
+		expectedSQL  string
+		expectedArgs []interface{}
+	}{
+		{
+			"unfiltered",
+			"",
+			"",
+			"SELECT * FROM relation_tuple WHERE userset_namespace = ?",
+			[]interface{}{"user"},
+		},
+		{
+			"resource type only",
+			"document",
+			"",
+			"SELECT * FROM relation_tuple WHERE userset_namespace = ? AND namespace = ?",
+			[]interface{}{"user", "document"},
+		},
+		{
+			"relation only",
+			"",
+			"viewer",
+			"SELECT * FROM relation_tuple WHERE userset_namespace = ? AND relation = ?",
+			[]interface{}{"user", "viewer"},
+		},
+		{
+			"resource type and relation",
+			"document",
+			"viewer",
+			"SELECT * FROM relation_tuple WHERE userset_namespace = ? AND namespace = ? AND relation = ?",
+			[]interface{}{"user", "document", "viewer"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			filterer := NewSchemaQueryFilterer(testSchema, sq.Select("*").From(testSchema.TableTuple)).
+				FilterToSubjectFilter(&v1.SubjectFilter{SubjectType: "user"}).
+				FilterToResourceRelation(tc.resourceType, tc.relation)
+
+			sql, args, err := filterer.queryBuilder.ToSql()
+			require.NoError(err)
+			require.Equal(tc.expectedSQL, sql)
+			require.Equal(tc.expectedArgs, args)
+		})
+	}
+}
+
+func TestFilterToReverseQuerySubject(t *testing.T) {
+	testCases := []struct {
+		name         string
+		relation     string
+		queryOpts    *options.ReverseQueryOptions
+		expectedSQL  string
+		expectedArgs []interface{}
+	}{
+		{
+			"relation only",
+			"member",
+			&options.ReverseQueryOptions{},
+			"SELECT * FROM relation_tuple WHERE userset_namespace = ? AND userset_object_id = ? AND userset_relation = ?",
+			[]interface{}{"user", "tom", "member"},
+		},
+		{
+			"relation including ellipsis",
+			"member",
+			&options.ReverseQueryOptions{IncludeEllipsisRelation: true},
+			"SELECT * FROM relation_tuple WHERE userset_namespace = ? AND userset_object_id = ? AND userset_relation IN (?,?)",
+			[]interface{}{"user", "tom", "member", "..."},
+		},
+		{
+			"ellipsis including ellipsis",
+			"",
+			&options.ReverseQueryOptions{IncludeEllipsisRelation: true},
+			"SELECT * FROM relation_tuple WHERE userset_namespace = ? AND userset_object_id = ? AND userset_relation = ?",
+			[]interface{}{"user", "tom", "..."},
+		},
+		{
+			"ellipsis including wildcard",
+			"",
+			&options.ReverseQueryOptions{IncludeWildcardSubject: true},
+			"SELECT * FROM relation_tuple WHERE userset_namespace = ? AND ((userset_object_id = ? AND userset_relation = ?) OR userset_object_id = ?)",
+			[]interface{}{"user", "tom", "...", "*"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			filterer := NewSchemaQueryFilterer(testSchema, sq.Select("*").From(testSchema.TableTuple)).
+				FilterToReverseQuerySubject(&v1.SubjectFilter{
+					SubjectType:       "user",
+					OptionalSubjectId: "tom",
+					OptionalRelation:  &v1.SubjectFilter_RelationFilter{Relation: tc.relation},
+				}, tc.queryOpts)
+
+			sql, args, err := filterer.queryBuilder.ToSql()
+			require.NoError(err)
+			require.Equal(tc.expectedSQL, sql)
+			require.Equal(tc.expectedArgs, args)
+		})
+	}
+}
+
+func TestSplitAndCheckTuplesExist(t *testing.T) {
+	require := require.New(t)
+
+	candidates := []*core.RelationTuple{
+		tuple.MustParse("document:first#viewer@user:tom"),
+		tuple.MustParse("document:second#viewer@user:tom"),
+		tuple.MustParse("document:third#viewer@user:tom"),
+	}
+
+	var queries []string
+	splitter := TupleQuerySplitter{
+		Executor: func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error) {
+			queries = append(queries, sql)
+
+			// Pretend that only the second tuple exists
+			for i := 0; i < len(args); i += 6 {
+				if args[i+1] == "second" {
+					return []*core.RelationTuple{candidates[1]}, nil
+				}
+			}
+			return nil, nil
+		},
+		UsersetBatchSize: 2,
+	}
+
+	exists, err := splitter.SplitAndCheckTuplesExist(
+		context.Background(),
+		NewSchemaQueryFilterer(testSchema, sq.Select("*").From(testSchema.TableTuple)),
+		candidates,
+	)
+	require.NoError(err)
+	require.Equal(map[string]bool{
+		"document:first#viewer@user:tom":  false,
+		"document:second#viewer@user:tom": true,
+		"document:third#viewer@user:tom":  false,
+	}, exists)
+	require.Len(queries, 2)
+}
+
+func TestSplitAndExecuteQueryTracing(t *testing.T) {
+	require := require.New(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	var usersets []*core.ObjectAndRelation
+	for i := 0; i < 5; i++ {
+		usersets = append(usersets, tuple.ParseONR(fmt.Sprintf("user:user%d#...", i)))
+	}
+
+	executed := 0
+	splitter := TupleQuerySplitter{
+		Executor: func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error) {
+			executed++
+			return nil, nil
+		},
+		UsersetBatchSize: 2,
+	}
+
+	iter, err := splitter.SplitAndExecuteQuery(
+		context.Background(),
+		NewSchemaQueryFilterer(testSchema, sq.Select("*").From(testSchema.TableTuple)).
+			FilterToResourceType("document"),
+		options.SetUsersets(usersets),
+	)
+	require.NoError(err)
+	iter.Close()
+	require.Equal(3, executed)
+
+	var attributes []attribute.KeyValue
+	for _, span := range recorder.Ended() {
+		if span.Name() == "SplitAndExecuteQuery" {
+			attributes = span.Attributes()
+		}
+	}
+	require.Contains(attributes, ObjNamespaceNameKey.String("document"))
+	require.Contains(attributes, filterValueCountKey.Int(5))
+	require.Contains(attributes, splitCountKey.Int(3))
+}
+
+func TestSplitAndExecuteQueryRevisions(t *testing.T) {
+	require := require.New(t)
+
+	var usersets []*core.ObjectAndRelation
+	for i := 0; i < 3; i++ {
+		usersets = append(usersets, tuple.ParseONR(fmt.Sprintf("user:user%d#...", i)))
+	}
+
+	// Each split query returns a tuple for each of its usersets
+	tuplesFor := func(args []any) []*core.RelationTuple {
+		var found []*core.RelationTuple
+		for _, arg := range args {
+			if userID, ok := arg.(string); ok && strings.HasPrefix(userID, "user") && userID != "user" {
+				found = append(found, tuple.MustParse("document:doc#viewer@user:"+userID))
+			}
+		}
+		return found
+	}
+
+	splitter := TupleQuerySplitter{
+		Executor: func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error) {
+			return tuplesFor(args), nil
+		},
+		RevisionsExecutor: func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, map[*core.RelationTuple]datastore.RevisionMetadata, error) {
+			// Each tuple was created at a revision derived from its userset's index
+			found := tuplesFor(args)
+			revisions := make(map[*core.RelationTuple]datastore.RevisionMetadata, len(found))
+			for _, tpl := range found {
+				index, err := strconv.Atoi(strings.TrimPrefix(tpl.Subject.ObjectId, "user"))
+				require.NoError(err)
+				revisions[tpl] = datastore.RevisionMetadata{CreatedRevision: decimal.NewFromInt(int64(index + 1))}
+			}
+			return found, revisions, nil
+		},
+		UsersetBatchSize: 2,
+	}
+
+	query := func(opts ...options.QueryOptionsOption) map[string]datastore.RevisionMetadata {
+		iter, err := splitter.SplitAndExecuteQuery(
+			context.Background(),
+			NewSchemaQueryFilterer(testSchema, sq.Select("*").From(testSchema.TableTuple)),
+			append(opts, options.SetUsersets(usersets), options.WithSorted(true))...,
+		)
+		require.NoError(err)
+		defer iter.Close()
+
+		reporter, ok := iter.(datastore.RevisionMetadataReporter)
+		require.True(ok)
+
+		found := make(map[string]datastore.RevisionMetadata)
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			if metadata, ok := reporter.RevisionMetadata(); ok {
+				found[tuple.String(tpl)] = metadata
+			}
+		}
+		require.NoError(iter.Err())
+		return found
+	}
+
+	// Revision metadata is only reported when requested
+	require.Empty(query())
+
+	found := query(options.WithRevisionMetadata())
+	require.Len(found, 3)
+	for i := 0; i < 3; i++ {
+		metadata := found[fmt.Sprintf("document:doc#viewer@user:user%d", i)]
+		require.True(metadata.CreatedRevision.Equal(decimal.NewFromInt(int64(i + 1))))
+		require.True(metadata.DeletedRevision.Equal(datastore.NoRevision))
+	}
+}
+
+func TestSplitAndExecuteQueryStreaming(t *testing.T) {
+	var usersets []*core.ObjectAndRelation
+	for i := 0; i < 3; i++ {
+		usersets = append(usersets, tuple.ParseONR(fmt.Sprintf("user:user%d#...", i)))
+	}
+
+	testCases := []struct {
+		name           string
+		opts           []options.QueryOptionsOption
+		expectStreamed bool
+	}{
+		{"not requested", nil, false},
+		{"requested", []options.QueryOptionsOption{options.WithStreamResults(true)}, true},
+		{"requested and sorted", []options.QueryOptionsOption{options.WithStreamResults(true), options.WithSorted(true)}, true},
+		{"requested within a batch", []options.QueryOptionsOption{options.WithStreamResults(true), options.SetUsersets(usersets[:2])}, true},
+		{"requested but split", []options.QueryOptionsOption{options.WithStreamResults(true), options.SetUsersets(usersets)}, false},
+		{"requested with revisions", []options.QueryOptionsOption{options.WithStreamResults(true), options.WithRevisionMetadata()}, false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			executed := 0
+			streamed := 0
+			splitter := TupleQuerySplitter{
+				Executor: func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, error) {
+					executed++
+					return []*core.RelationTuple{tuple.MustParse("document:doc#viewer@user:tom")}, nil
+				},
+				RevisionsExecutor: func(ctx context.Context, sql string, args []any) ([]*core.RelationTuple, map[*core.RelationTuple]datastore.RevisionMetadata, error) {
+					executed++
+					return []*core.RelationTuple{tuple.MustParse("document:doc#viewer@user:tom")}, nil, nil
+				},
+				StreamingExecutor: func(ctx context.Context, sql string, args []any) (datastore.RelationshipIterator, error) {
+					streamed++
+					return datastore.NewSliceRelationshipIterator([]*core.RelationTuple{tuple.MustParse("document:doc#viewer@user:tom")}), nil
+				},
+				UsersetBatchSize: 2,
+			}
+
+			iter, err := splitter.SplitAndExecuteQuery(
+				context.Background(),
+				NewSchemaQueryFilterer(testSchema, sq.Select("*").From(testSchema.TableTuple)),
+				tc.opts...,
+			)
+			require.NoError(err)
+			defer iter.Close()
+
+			require.Equal("document:doc#viewer@user:tom", tuple.String(iter.Next()))
+			if tc.expectStreamed {
+				require.Equal(1, streamed)
+				require.Zero(executed)
+			} else {
+				require.Zero(streamed)
+				require.NotZero(executed)
+			}
+		})
+	}
+}
+
+// fakeTx is a pgx.Tx whose queries all return the same rows.
+type fakeTx struct {
+	pgx.Tx
+	rows *fakeRows
+}
+
+func (tx *fakeTx) Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error) {
+	tx.rows.ctx = ctx
+	return tx.rows, nil
+}
+
+// fakeRows are pgx.Rows of the six core tuple columns which, like the rows of a connection, stop
+// with an error once the context of their query is canceled.
+type fakeRows struct {
+	pgx.Rows
+	ctx    context.Context
+	total  int32
+	read   int32
+	closed int32
+	err    error
+}
+
+func (r *fakeRows) FieldDescriptions() []pgproto3.FieldDescription {
+	return make([]pgproto3.FieldDescription, 6)
+}
+
+func (r *fakeRows) Next() bool {
+	if err := r.ctx.Err(); err != nil {
+		r.err = err
+		return false
+	}
+
+	if atomic.LoadInt32(&r.read) >= r.total {
+		return false
+	}
+
+	atomic.AddInt32(&r.read, 1)
+	return true
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	subjectRelation := "member"
+	*dest[0].(*string) = "document"
+	*dest[1].(*string) = fmt.Sprintf("doc%d", atomic.LoadInt32(&r.read)-1)
+	*dest[2].(*string) = "viewer"
+	*dest[3].(*string) = "group"
+	*dest[4].(*string) = "eng"
+	*dest[5].(**string) = &subjectRelation
+	return nil
+}
+
+func (r *fakeRows) Err() error {
+	return r.err
+}
+
+func (r *fakeRows) Close() {
+	atomic.StoreInt32(&r.closed, 1)
+}
+
+func TestPGXStreamingExecutorCancel(t *testing.T) {
+	require := require.New(t)
+
+	const totalRows = 100_000
+	rows := &fakeRows{total: totalRows}
+	cleanedUp := make(chan struct{})
+	executor := NewPGXStreamingExecutor(func(ctx context.Context) (pgx.Tx, TxCleanupFunc, error) {
+		return &fakeTx{rows: rows}, func(context.Context) { close(cleanedUp) }, nil
+	}, NullSubjectRelationAsEllipsis)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	iter, err := executor(ctx, "SELECT * FROM relation_tuple", nil)
+	require.NoError(err)
+
+	// Rows are only read as the iterator is advanced
+	require.Zero(atomic.LoadInt32(&rows.read))
+
+	tuples, errs := datastore.StreamRelationships(ctx, iter)
+
+	const readBeforeCancel = 10
+	for i := 0; i < readBeforeCancel; i++ {
+		require.Equal(fmt.Sprintf("document:doc%d#viewer@group:eng#member", i), tuple.String(<-tuples))
+	}
+	cancel()
+
+	for range tuples {
+	}
+	require.ErrorIs(<-errs, context.Canceled)
+
+	// Canceling aborts the query and releases its transaction, without reading the other rows
+	select {
+	case <-cleanedUp:
+	case <-time.After(1 * time.Second):
+		require.Fail("timed out waiting for the transaction to be cleaned up")
+	}
+	require.Equal(int32(1), atomic.LoadInt32(&rows.closed))
+	require.ErrorIs(rows.ctx.Err(), context.Canceled)
+	require.Less(atomic.LoadInt32(&rows.read), int32(totalRows))
+}
+
+func TestFilterWith(t *testing.T) {
+	require := require.New(t)
+
+	sql, args, err := NewSchemaQueryFilterer(testSchema, sq.Select("*").From(testSchema.TableTuple)).
+		FilterToResourceType("document").
+		FilterWith(func(query sq.SelectBuilder) sq.SelectBuilder {
+			return query.Where(sq.Eq{"deleted_transaction": 5})
+		}).
+		ToSql()
+	require.NoError(err)
+	require.Equal("SELECT * FROM relation_tuple WHERE namespace = ? AND deleted_transaction = ?", sql)
+	require.Equal([]any{"document", 5}, args)
+}
+
+func TestIgnoringObjectIDCase(t *testing.T) {
+	require := require.New(t)
+
+	sql, args, err := NewSchemaQueryFilterer(testSchema, sq.Select("*").From(testSchema.TableTuple)).
+		FilterToResourceType("document").
+		IgnoringObjectIDCase().
+		FilterToResourceID("User123").
+		FilterToSubjectFilter(&v1.SubjectFilter{SubjectType: "user", OptionalSubjectId: "USER123"}).
+		ToSql()
+	require.NoError(err)
+	require.Equal("SELECT * FROM relation_tuple WHERE namespace = ? AND LOWER(object_id) = LOWER(?) AND userset_namespace = ? AND LOWER(userset_object_id) = LOWER(?)", sql)
+	require.Equal([]any{"document", "User123", "user", "USER123"}, args)
+}
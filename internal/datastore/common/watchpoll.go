@@ -0,0 +1,81 @@
+package common
+
+import (
+	"context"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+// WatchPollingConfig configures how long a polling watch waits between polls that do not
+// return any changes.
+type WatchPollingConfig struct {
+	// Interval is the time waited after a poll that returned no changes.
+	Interval time.Duration
+
+	// MaxInterval is the cap to which the interval grows after BackoffAfter consecutive polls
+	// without changes. If it is not greater than Interval, the interval never grows.
+	MaxInterval time.Duration
+
+	// BackoffAfter is the number of consecutive polls without changes after which the interval
+	// starts doubling.
+	BackoffAfter uint
+
+	// JitterFactor, if non-zero, randomly adjusts each wait by up to that fraction of it, so
+	// that watchers started together do not poll in lockstep.
+	JitterFactor float64
+}
+
+// WatchPoller paces the polls of a watch: it does not wait after a poll that returned changes,
+// and backs off exponentially after consecutive polls that did not.
+type WatchPoller struct {
+	config     WatchPollingConfig
+	clock      clock.Clock
+	emptyPolls uint
+	interval   time.Duration
+}
+
+// NewWatchPoller creates a new WatchPoller using the given config and clock.
+func NewWatchPoller(config WatchPollingConfig, clock clock.Clock) *WatchPoller {
+	return &WatchPoller{
+		config:   config,
+		clock:    clock,
+		interval: config.Interval,
+	}
+}
+
+// Interval returns the interval, before jitter, that will be waited after the next poll if it
+// returns no changes.
+func (wp *WatchPoller) Interval() time.Duration {
+	return wp.interval
+}
+
+// Wait records whether the last poll returned changes and, if it did not, waits before
+// returning. Returns the context's error if it is done before the wait completes.
+func (wp *WatchPoller) Wait(ctx context.Context, hadChanges bool) error {
+	if hadChanges {
+		wp.emptyPolls = 0
+		wp.interval = wp.config.Interval
+		return nil
+	}
+
+	wait := WithJitter(wp.config.JitterFactor, wp.interval)
+
+	wp.emptyPolls++
+	if wp.emptyPolls >= wp.config.BackoffAfter && wp.interval < wp.config.MaxInterval {
+		wp.interval *= 2
+		if wp.interval > wp.config.MaxInterval {
+			wp.interval = wp.config.MaxInterval
+		}
+	}
+
+	timer := wp.clock.Timer(wait)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,100 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/benbjohnson/clock"
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchPollerBackoff(t *testing.T) {
+	require := require.New(t)
+
+	mockTime := clock.NewMock()
+	poller := NewWatchPoller(WatchPollingConfig{
+		Interval:     100 * time.Millisecond,
+		MaxInterval:  time.Second,
+		BackoffAfter: 2,
+	}, mockTime)
+
+	expectedWaits := []time.Duration{
+		100 * time.Millisecond,
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	}
+	for _, expected := range expectedWaits {
+		require.Equal(expected, poller.Interval())
+		requireWaits(t, mockTime, poller, expected)
+	}
+
+	// A poll with changes does not wait, and resets the interval.
+	require.NoError(poller.Wait(context.Background(), true))
+	require.Equal(100*time.Millisecond, poller.Interval())
+	requireWaits(t, mockTime, poller, 100*time.Millisecond)
+	requireWaits(t, mockTime, poller, 100*time.Millisecond)
+	require.Equal(200*time.Millisecond, poller.Interval())
+}
+
+func TestWatchPollerNoBackoff(t *testing.T) {
+	mockTime := clock.NewMock()
+	poller := NewWatchPoller(WatchPollingConfig{Interval: 100 * time.Millisecond}, mockTime)
+
+	for i := 0; i < 5; i++ {
+		requireWaits(t, mockTime, poller, 100*time.Millisecond)
+	}
+}
+
+func TestWatchPollerJitter(t *testing.T) {
+	poller := NewWatchPoller(WatchPollingConfig{
+		Interval:     100 * time.Millisecond,
+		JitterFactor: 0.5,
+	}, clock.New())
+
+	start := time.Now()
+	require.NoError(t, poller.Wait(context.Background(), false))
+	waited := time.Since(start)
+
+	require.GreaterOrEqual(t, waited, 50*time.Millisecond)
+	require.Equal(t, 100*time.Millisecond, poller.Interval())
+}
+
+func TestWatchPollerCanceled(t *testing.T) {
+	poller := NewWatchPoller(WatchPollingConfig{Interval: time.Hour}, clock.NewMock())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	require.ErrorIs(t, poller.Wait(ctx, false), context.Canceled)
+}
+
+// requireWaits requires that a poll without changes waits for exactly the expected duration.
+func requireWaits(t *testing.T, mockTime *clock.Mock, poller *WatchPoller, expected time.Duration) {
+	done := make(chan error, 1)
+	go func() {
+		done <- poller.Wait(context.Background(), false)
+	}()
+
+	// Give the poller time to start its timer.
+	time.Sleep(10 * time.Millisecond)
+
+	mockTime.Add(expected - time.Nanosecond)
+	select {
+	case <-done:
+		require.Fail(t, "poller returned before the expected wait", "expected %s", expected)
+	default:
+	}
+
+	mockTime.Add(time.Nanosecond)
+	select {
+	case err := <-done:
+		require.NoError(t, err)
+	case <-time.After(time.Second):
+		require.Fail(t, "poller did not return after the expected wait", "expected %s", expected)
+	}
+}
@@ -0,0 +1,136 @@
+package common
+
+import (
+	"context"
+	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+// SendRevisionChanges sends the changes of a single revision to the updates channel of a watch.
+//
+// If the send timeout of the watch options is zero, the consumer has fallen behind as soon as the
+// channel is full. Otherwise, each send waits up to the send timeout for the consumer to make
+// room, and changes which do not fit in the channel's buffer are split into several
+// RevisionChanges for the same revision, so that a slow consumer can still make progress on them.
+// A consumer which has fallen behind is handled as configured by the overflow policy.
+func SendRevisionChanges(
+	ctx context.Context,
+	updates chan *datastore.RevisionChanges,
+	changes *datastore.RevisionChanges,
+	watchOpts *options.WatchOptions,
+) error {
+	if watchOpts.SendTimeout == 0 {
+		select {
+		case updates <- changes:
+			return nil
+		default:
+			return handleOverflow(updates, changes, watchOpts.OverflowPolicy)
+		}
+	}
+
+	for _, chunk := range chunkRevisionChanges(changes, cap(updates)) {
+		if err := sendWithTimeout(ctx, updates, chunk, watchOpts); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func sendWithTimeout(
+	ctx context.Context,
+	updates chan *datastore.RevisionChanges,
+	changes *datastore.RevisionChanges,
+	watchOpts *options.WatchOptions,
+) error {
+	timer := time.NewTimer(watchOpts.SendTimeout)
+	defer timer.Stop()
+
+	select {
+	case updates <- changes:
+		return nil
+	case <-timer.C:
+		return handleOverflow(updates, changes, watchOpts.OverflowPolicy)
+	case <-ctx.Done():
+		return datastore.NewWatchCanceledErr()
+	}
+}
+
+// handleOverflow handles changes which could not be sent because the consumer fell behind.
+//
+// With WatchOverflowDropAndSignal, the changes waiting in the channel are dropped and replaced by
+// a gap marker covering their revisions, followed by the changes. If the channel cannot hold
+// both, the changes are dropped too. The watch is only disconnected if the channel is unbuffered
+// and the consumer is not waiting on it.
+func handleOverflow(
+	updates chan *datastore.RevisionChanges,
+	changes *datastore.RevisionChanges,
+	policy options.WatchOverflowPolicy,
+) error {
+	if policy != options.WatchOverflowDropAndSignal {
+		return datastore.NewWatchDisconnectedErr()
+	}
+
+	var gap *datastore.RevisionChanges
+	addToGap := func(dropped *datastore.RevisionChanges) {
+		if gap == nil {
+			gap = &datastore.RevisionChanges{IsGap: true, GapStartRevision: dropped.Revision}
+			if dropped.IsGap {
+				gap.GapStartRevision = dropped.GapStartRevision
+			}
+		}
+		gap.Revision = dropped.Revision
+	}
+
+	// Only the watch sends to the channel, so once drained it has room for everything below.
+	for drained := false; !drained; {
+		select {
+		case dropped := <-updates:
+			addToGap(dropped)
+		default:
+			drained = true
+		}
+	}
+
+	if cap(updates) < 2 {
+		addToGap(changes)
+		changes = nil
+	}
+
+	for _, send := range []*datastore.RevisionChanges{gap, changes} {
+		if send == nil {
+			continue
+		}
+
+		select {
+		case updates <- send:
+		default:
+			return datastore.NewWatchDisconnectedErr()
+		}
+	}
+
+	return nil
+}
+
+func chunkRevisionChanges(changes *datastore.RevisionChanges, chunkSize int) []*datastore.RevisionChanges {
+	if chunkSize <= 0 || len(changes.Changes) <= chunkSize {
+		return []*datastore.RevisionChanges{changes}
+	}
+
+	chunks := make([]*datastore.RevisionChanges, 0, (len(changes.Changes)+chunkSize-1)/chunkSize)
+	for start := 0; start < len(changes.Changes); start += chunkSize {
+		end := start + chunkSize
+		if end > len(changes.Changes) {
+			end = len(changes.Changes)
+		}
+
+		chunks = append(chunks, &datastore.RevisionChanges{
+			Revision: changes.Revision,
+			Changes:  changes.Changes[start:end],
+		})
+	}
+
+	return chunks
+}
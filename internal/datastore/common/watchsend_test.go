@@ -0,0 +1,163 @@
+package common
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/require"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func makeRevisionChanges(numChanges int) *datastore.RevisionChanges {
+	changes := &datastore.RevisionChanges{Revision: rev1}
+	for i := 0; i < numChanges; i++ {
+		changes.Changes = append(changes.Changes, tuple.Touch(tuple.MustParse(fmt.Sprintf("docs:%d#reader@user:1", i))))
+	}
+	return changes
+}
+
+func TestSendRevisionChangesWithoutTimeout(t *testing.T) {
+	require := require.New(t)
+
+	updates := make(chan *datastore.RevisionChanges, 1)
+	large := makeRevisionChanges(5)
+
+	// Without a timeout, changes are never split
+	require.NoError(SendRevisionChanges(context.Background(), updates, large, &options.WatchOptions{}))
+	require.Same(large, <-updates)
+
+	updates <- makeRevisionChanges(1)
+	err := SendRevisionChanges(context.Background(), updates, makeRevisionChanges(1), &options.WatchOptions{})
+	require.True(errors.As(err, &datastore.ErrWatchDisconnected{}))
+}
+
+func TestSendRevisionChangesSlowReader(t *testing.T) {
+	require := require.New(t)
+
+	updates := make(chan *datastore.RevisionChanges, 2)
+	sent := makeRevisionChanges(5)
+
+	received := make(chan []*datastore.RevisionChanges, 1)
+	go func() {
+		var chunks []*datastore.RevisionChanges
+		for chunk := range updates {
+			time.Sleep(10 * time.Millisecond)
+			chunks = append(chunks, chunk)
+		}
+		received <- chunks
+	}()
+
+	for i := 0; i < 3; i++ {
+		require.NoError(SendRevisionChanges(context.Background(), updates, sent, &options.WatchOptions{SendTimeout: time.Second}))
+	}
+	close(updates)
+
+	chunks := <-received
+	require.Len(chunks, 9)
+
+	var changes []*core.RelationTupleUpdate
+	for i, chunk := range chunks {
+		require.Equal(rev1, chunk.Revision)
+		require.LessOrEqual(len(chunk.Changes), 2)
+
+		changes = append(changes, chunk.Changes...)
+		if i%3 == 2 {
+			require.Equal(sent.Changes, changes)
+			changes = nil
+		}
+	}
+}
+
+func TestSendRevisionChangesTimeout(t *testing.T) {
+	require := require.New(t)
+
+	updates := make(chan *datastore.RevisionChanges, 1)
+	updates <- makeRevisionChanges(1)
+
+	err := SendRevisionChanges(context.Background(), updates, makeRevisionChanges(1), &options.WatchOptions{SendTimeout: 10 * time.Millisecond})
+	require.True(errors.As(err, &datastore.ErrWatchDisconnected{}))
+}
+
+func TestSendRevisionChangesCanceled(t *testing.T) {
+	require := require.New(t)
+
+	updates := make(chan *datastore.RevisionChanges, 1)
+	updates <- makeRevisionChanges(1)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := SendRevisionChanges(ctx, updates, makeRevisionChanges(1), &options.WatchOptions{SendTimeout: time.Minute})
+	require.True(errors.As(err, &datastore.ErrWatchCanceled{}))
+}
+
+func TestSendRevisionChangesDropAndSignal(t *testing.T) {
+	require := require.New(t)
+
+	dropAndSignal := &options.WatchOptions{OverflowPolicy: options.WatchOverflowDropAndSignal}
+	withRevision := func(revision int64) *datastore.RevisionChanges {
+		changes := makeRevisionChanges(1)
+		changes.Revision = decimal.NewFromInt(revision)
+		return changes
+	}
+
+	updates := make(chan *datastore.RevisionChanges, 3)
+	for revision := int64(1); revision <= 3; revision++ {
+		require.NoError(SendRevisionChanges(context.Background(), updates, withRevision(revision), dropAndSignal))
+	}
+
+	// The waiting changes are replaced by a gap marker, followed by the new changes
+	require.NoError(SendRevisionChanges(context.Background(), updates, withRevision(4), dropAndSignal))
+	require.Len(updates, 2)
+
+	require.NoError(SendRevisionChanges(context.Background(), updates, withRevision(5), dropAndSignal))
+
+	// A gap marker which is dropped in turn is merged into the next one
+	require.NoError(SendRevisionChanges(context.Background(), updates, withRevision(6), dropAndSignal))
+	require.Len(updates, 2)
+
+	gap := <-updates
+	require.True(gap.IsGap)
+	require.Empty(gap.Changes)
+	require.True(decimal.NewFromInt(1).Equal(gap.GapStartRevision))
+	require.True(decimal.NewFromInt(5).Equal(gap.Revision))
+
+	next := <-updates
+	require.False(next.IsGap)
+	require.True(decimal.NewFromInt(6).Equal(next.Revision))
+}
+
+func TestSendRevisionChangesDropAndSignalSmallBuffer(t *testing.T) {
+	require := require.New(t)
+
+	dropAndSignal := &options.WatchOptions{
+		SendTimeout:    10 * time.Millisecond,
+		OverflowPolicy: options.WatchOverflowDropAndSignal,
+	}
+
+	// Without room for both, the gap marker also covers the new changes
+	updates := make(chan *datastore.RevisionChanges, 1)
+	updates <- makeRevisionChanges(1)
+
+	sent := makeRevisionChanges(1)
+	sent.Revision = rev2
+	require.NoError(SendRevisionChanges(context.Background(), updates, sent, dropAndSignal))
+
+	gap := <-updates
+	require.True(gap.IsGap)
+	require.True(rev1.Equal(gap.GapStartRevision))
+	require.True(rev2.Equal(gap.Revision))
+
+	// An unbuffered channel cannot hold a gap marker
+	err := SendRevisionChanges(context.Background(), make(chan *datastore.RevisionChanges), sent, dropAndSignal)
+	require.True(errors.As(err, &datastore.ErrWatchDisconnected{}))
+}
@@ -0,0 +1,60 @@
+package common
+
+import (
+	"context"
+	"sync"
+)
+
+// WatchShutdown stops the watches of a datastore when it is closed, so that each can deliver the
+// changes it has already loaded and end with ErrWatchClosing, rather than failing abruptly once
+// its connections are closed.
+type WatchShutdown struct {
+	closing   chan struct{}
+	closeOnce sync.Once
+	watches   sync.WaitGroup
+}
+
+// NewWatchShutdown creates a new WatchShutdown.
+func NewWatchShutdown() *WatchShutdown {
+	return &WatchShutdown{closing: make(chan struct{})}
+}
+
+// Start registers a watch, returning a context derived from ctx which is canceled once the
+// datastore starts closing, and a function which must be called when the watch has finished.
+// The watch should load and wait for changes with the returned context, but deliver them with
+// the original one, so that the changes it has loaded are still delivered while closing.
+func (ws *WatchShutdown) Start(ctx context.Context) (context.Context, func()) {
+	ws.watches.Add(1)
+
+	watchCtx, cancel := context.WithCancel(ctx)
+	go func() {
+		select {
+		case <-ws.closing:
+			cancel()
+		case <-watchCtx.Done():
+		}
+	}()
+
+	return watchCtx, func() {
+		cancel()
+		ws.watches.Done()
+	}
+}
+
+// Closing returns whether the datastore has started closing.
+func (ws *WatchShutdown) Closing() bool {
+	select {
+	case <-ws.closing:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close signals the watches to stop, and waits for them to finish.
+func (ws *WatchShutdown) Close() {
+	ws.closeOnce.Do(func() {
+		close(ws.closing)
+	})
+	ws.watches.Wait()
+}
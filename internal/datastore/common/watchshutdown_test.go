@@ -0,0 +1,65 @@
+package common
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestWatchShutdown(t *testing.T) {
+	require := require.New(t)
+
+	shutdown := NewWatchShutdown()
+	require.False(shutdown.Closing())
+
+	watchCtx, watchDone := shutdown.Start(context.Background())
+
+	closed := make(chan struct{})
+	go func() {
+		shutdown.Close()
+		close(closed)
+	}()
+
+	// Closing cancels the context of the watch, and waits for it to finish
+	select {
+	case <-watchCtx.Done():
+	case <-time.After(5 * time.Second):
+		require.Fail("watch context was not canceled")
+	}
+	require.True(shutdown.Closing())
+
+	select {
+	case <-closed:
+		require.Fail("closed before the watch finished")
+	case <-time.After(10 * time.Millisecond):
+	}
+
+	watchDone()
+	select {
+	case <-closed:
+	case <-time.After(5 * time.Second):
+		require.Fail("close did not complete after the watch finished")
+	}
+
+	// Closing again does not block
+	shutdown.Close()
+}
+
+func TestWatchShutdownCanceledWatch(t *testing.T) {
+	require := require.New(t)
+
+	shutdown := NewWatchShutdown()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	watchCtx, watchDone := shutdown.Start(ctx)
+	cancel()
+
+	<-watchCtx.Done()
+	require.False(shutdown.Closing())
+
+	watchDone()
+	shutdown.Close()
+	require.True(shutdown.Closing())
+}
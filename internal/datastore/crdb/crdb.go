@@ -188,8 +188,9 @@ func (cds *crdbDatastore) SnapshotReader(rev datastore.Revision) datastore.Reade
 	}
 
 	querySplitter := common.TupleQuerySplitter{
-		Executor:         common.NewPGXExecutor(createTxFunc),
-		UsersetBatchSize: cds.usersetBatchSize,
+		Executor:          common.NewPGXExecutor(createTxFunc, common.NullSubjectRelationAsEllipsis),
+		UsersetBatchSize:  cds.usersetBatchSize,
+		StreamingExecutor: common.NewPGXStreamingExecutor(createTxFunc, common.NullSubjectRelationAsEllipsis),
 	}
 
 	return &crdbReader{createTxFunc, querySplitter, noOverlapKeyer, nil, cds.execute}
@@ -209,7 +210,7 @@ func (cds *crdbDatastore) ReadWriteTx(
 			}
 
 			querySplitter := common.TupleQuerySplitter{
-				Executor:         common.NewPGXExecutor(longLivedTx),
+				Executor:         common.NewPGXExecutor(longLivedTx, common.NullSubjectRelationAsEllipsis),
 				UsersetBatchSize: cds.usersetBatchSize,
 			}
 
@@ -224,6 +225,7 @@ func (cds *crdbDatastore) ReadWriteTx(
 				ctx,
 				tx,
 				0,
+				0,
 			}
 
 			if err := f(ctx, rwt); err != nil {
@@ -117,8 +117,13 @@ func (cr *crdbReader) QueryRelationships(
 	filter *v1.RelationshipFilter,
 	opts ...options.QueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
+	queryOpts := options.NewQueryOptionsWithOptions(opts...)
 	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples).
-		FilterToResourceType(filter.ResourceType)
+		FilterToResourceTypes(append([]string{filter.ResourceType}, queryOpts.AdditionalResourceTypes...)...)
+
+	if queryOpts.IgnoreObjectIDCase {
+		qBuilder = qBuilder.IgnoringObjectIDCase()
+	}
 
 	if filter.OptionalResourceId != "" {
 		qBuilder = qBuilder.FilterToResourceID(filter.OptionalResourceId)
@@ -147,17 +152,20 @@ func (cr *crdbReader) ReverseQueryRelationships(
 	subjectFilter *v1.SubjectFilter,
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples).
-		FilterToSubjectFilter(subjectFilter)
-
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
 
+	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples).
+		FilterToReverseQuerySubject(subjectFilter, queryOpts)
+
 	if queryOpts.ResRelation != nil {
-		qBuilder = qBuilder.
-			FilterToResourceType(queryOpts.ResRelation.Namespace).
-			FilterToRelation(queryOpts.ResRelation.Relation)
+		qBuilder = qBuilder.FilterToResourceRelation(
+			queryOpts.ResRelation.Namespace,
+			queryOpts.ResRelation.Relation,
+		)
 	}
 
+	qBuilder = qBuilder.FilterToExcludedSubjects(queryOpts.ExcludedSubjects)
+
 	err = cr.execute(ctx, func(ctx context.Context) error {
 		iter, err = cr.querySplitter.SplitAndExecuteQuery(
 			ctx,
@@ -170,6 +178,21 @@ func (cr *crdbReader) ReverseQueryRelationships(
 	return
 }
 
+func (cr *crdbReader) CountRelationships(ctx context.Context, filter *v1.RelationshipFilter) (uint64, error) {
+	return common.CountRelationships(ctx, cr, filter)
+}
+
+func (cr *crdbReader) CheckRelationshipsExist(ctx context.Context, tuples []*core.RelationTuple) (exists map[string]bool, err error) {
+	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples)
+
+	err = cr.execute(ctx, func(ctx context.Context) error {
+		exists, err = cr.querySplitter.SplitAndCheckTuplesExist(ctx, qBuilder, tuples)
+		return err
+	})
+
+	return
+}
+
 func loadNamespace(ctx context.Context, tx pgx.Tx, nsName string) (*core.NamespaceDefinition, time.Time, error) {
 	query := queryReadNamespace.Where(sq.Eq{colNamespace: nsName})
 
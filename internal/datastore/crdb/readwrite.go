@@ -42,21 +42,29 @@ var (
 
 type crdbReadWriteTXN struct {
 	*crdbReader
-	ctx            context.Context
-	tx             pgx.Tx
-	relCountChange int64
+	ctx              context.Context
+	tx               pgx.Tx
+	relCountChange   int64
+	effectiveTouches uint64
+}
+
+// EffectiveTouches returns the number of touches written by the transaction which created a
+// relationship.
+func (rwt *crdbReadWriteTXN) EffectiveTouches() uint64 {
+	return rwt.effectiveTouches
 }
 
 var (
-	upsertTupleSuffix = fmt.Sprintf(
-		"ON CONFLICT (%s,%s,%s,%s,%s,%s) DO UPDATE SET %s = now()",
+	// Touches of relationships which are already living leave the row untouched, so that they
+	// are not emitted by the changefeed, and are not counted as effective.
+	touchTupleSuffix = fmt.Sprintf(
+		"ON CONFLICT (%s,%s,%s,%s,%s,%s) DO NOTHING",
 		colNamespace,
 		colObjectID,
 		colRelation,
 		colUsersetNamespace,
 		colUsersetObjectID,
 		colUsersetRelation,
-		colTimestamp,
 	)
 
 	queryWriteTuple = psql.Insert(tableTuple).Columns(
@@ -68,7 +76,7 @@ var (
 		colUsersetRelation,
 	)
 
-	queryTouchTuple = queryWriteTuple.Suffix(upsertTupleSuffix)
+	queryTouchTuple = queryWriteTuple.Suffix(touchTupleSuffix)
 
 	queryDeleteTuples = psql.Delete(tableTuple)
 
@@ -99,7 +107,6 @@ func (rwt *crdbReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpda
 
 		switch mutation.Operation {
 		case v1.RelationshipUpdate_OPERATION_TOUCH:
-			rwt.relCountChange++
 			bulkTouch = bulkTouch.Values(
 				rel.Resource.ObjectType,
 				rel.Resource.ObjectId,
@@ -136,23 +143,30 @@ func (rwt *crdbReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpda
 		}
 	}
 
-	bulkUpdateQueries := make([]sq.InsertBuilder, 0, 2)
 	if bulkWriteCount > 0 {
-		bulkUpdateQueries = append(bulkUpdateQueries, bulkWrite)
-	}
-	if bulkTouchCount > 0 {
-		bulkUpdateQueries = append(bulkUpdateQueries, bulkTouch)
+		sql, args, err := bulkWrite.ToSql()
+		if err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
+
+		if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
+			return fmt.Errorf(errUnableToWriteRelationships, err)
+		}
 	}
 
-	for _, updateQuery := range bulkUpdateQueries {
-		sql, args, err := updateQuery.ToSql()
+	if bulkTouchCount > 0 {
+		sql, args, err := bulkTouch.ToSql()
 		if err != nil {
 			return fmt.Errorf(errUnableToWriteRelationships, err)
 		}
 
-		if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
+		touched, err := rwt.tx.Exec(ctx, sql, args...)
+		if err != nil {
 			return fmt.Errorf(errUnableToWriteRelationships, err)
 		}
+
+		rwt.relCountChange += touched.RowsAffected()
+		rwt.effectiveTouches += uint64(touched.RowsAffected())
 	}
 
 	return nil
@@ -169,7 +183,7 @@ func exactRelationshipClause(r *v1.Relationship) sq.Eq {
 	}
 }
 
-func (rwt *crdbReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter) error {
+func (rwt *crdbReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter) (uint64, error) {
 	ctx, span := tracer.Start(datastore.SeparateContextWithTracing(rwt.ctx), "DeleteRelationships")
 	defer span.End()
 
@@ -203,17 +217,17 @@ func (rwt *crdbReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter)
 	span.SetAttributes(tracerAttributes...)
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+		return 0, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
 
 	modified, err := rwt.tx.Exec(ctx, sql, args...)
 	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+		return 0, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
 
 	rwt.relCountChange -= modified.RowsAffected()
 
-	return nil
+	return uint64(modified.RowsAffected()), nil
 }
 
 func (rwt *crdbReadWriteTXN) WriteNamespaces(newConfigs ...*core.NamespaceDefinition) error {
@@ -283,4 +297,12 @@ func (rwt *crdbReadWriteTXN) DeleteNamespace(nsName string) error {
 	return nil
 }
 
+func (rwt *crdbReadWriteTXN) RenameNamespace(oldName, newName string) error {
+	return common.RenameNamespace(datastore.SeparateContextWithTracing(rwt.ctx), rwt, oldName, newName)
+}
+
+func (rwt *crdbReadWriteTXN) CopyNamespace(sourceName, destName string, copyTuples bool) (uint64, error) {
+	return common.CopyNamespace(datastore.SeparateContextWithTracing(rwt.ctx), rwt, sourceName, destName, copyTuples)
+}
+
 var _ datastore.ReadWriteTransaction = &crdbReadWriteTXN{}
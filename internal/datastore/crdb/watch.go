@@ -11,12 +11,16 @@ import (
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
 )
 
 const queryChangefeed = "EXPERIMENTAL CHANGEFEED FOR %s WITH updated, cursor = '%s', resolved = '1s';"
 
-func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	watchOpts := options.NewWatchOptionsWithOptions(opts...)
+
 	updates := make(chan *datastore.RevisionChanges, cds.watchBufferLength)
 	errs := make(chan error, 1)
 
@@ -26,6 +30,20 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 		defer close(updates)
 		defer close(errs)
 
+		if watchOpts.EmitCheckpoint {
+			head, err := cds.HeadRevision(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			checkpoint := &datastore.RevisionChanges{Revision: head, IsCheckpoint: true}
+			if err := common.SendRevisionChanges(ctx, updates, checkpoint, watchOpts); err != nil {
+				errs <- err
+				return
+			}
+		}
+
 		pendingChanges := make(map[string]*datastore.RevisionChanges)
 
 		changes, err := cds.pool.Query(ctx, interpolated)
@@ -88,10 +106,8 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 				})
 
 				for _, change := range toEmit {
-					select {
-					case updates <- change:
-					default:
-						errs <- datastore.NewWatchDisconnectedErr()
+					if err := common.SendRevisionChanges(ctx, updates, change, watchOpts); err != nil {
+						errs <- err
 						return
 					}
 				}
@@ -111,6 +127,11 @@ func (cds *crdbDatastore) Watch(ctx context.Context, afterRevision datastore.Rev
 				return
 			}
 
+			// Changefeeds cannot be filtered, so skip any changes outside of the watched namespaces
+			if !watchOpts.IncludesNamespace(pkValues[0]) {
+				continue
+			}
+
 			oneChange := &core.RelationTupleUpdate{
 				Tuple: &core.RelationTuple{
 					ResourceAndRelation: &core.ObjectAndRelation{
@@ -0,0 +1,114 @@
+package memdb
+
+import (
+	"context"
+	"fmt"
+	"runtime"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/shopspring/decimal"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const errInvertedRevisionRange = "revision range from %s to %s is inverted"
+
+// QueryTuplesIncludingDeleted reconstructs the lifetimes of the relationships from the changelog,
+// since deleted relationships are removed from the relationship table. A relationship which is
+// touched while living keeps its lifetime.
+func (mdb *memdbDatastore) QueryTuplesIncludingDeleted(
+	ctx context.Context,
+	filter *v1.RelationshipFilter,
+	fromRevision, toRevision datastore.Revision,
+) (datastore.RelationshipIterator, error) {
+	if fromRevision.GreaterThan(toRevision) {
+		return nil, fmt.Errorf(errInvertedRevisionRange, fromRevision, toRevision)
+	}
+
+	for _, revision := range []datastore.Revision{fromRevision, toRevision} {
+		if err := mdb.checkRevisionLocal(revision); err != nil {
+			return nil, err
+		}
+	}
+
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	if mdb.db == nil {
+		return nil, fmt.Errorf("datastore has been closed")
+	}
+
+	tx := mdb.db.Txn(false)
+	defer tx.Abort()
+
+	it, err := tx.LowerBound(tableChangelog, indexRevision, int64(0))
+	if err != nil {
+		return nil, err
+	}
+
+	excluded := filterFuncForFilters(
+		filter.ResourceType,
+		filter.OptionalResourceId,
+		filter.OptionalRelation,
+		filter.OptionalSubjectFilter,
+		nil,
+	)
+
+	var tuples []*core.RelationTuple
+	var lifetimes []datastore.RevisionMetadata
+	living := make(map[string]int)
+	for changeRaw := it.Next(); changeRaw != nil; changeRaw = it.Next() {
+		change := changeRaw.(*changelog)
+		revision := decimal.NewFromInt(change.revisionNanos)
+
+		for _, update := range change.changes.Changes {
+			if excluded(relationshipFromTuple(update.Tuple)) {
+				continue
+			}
+
+			key := tuple.String(update.Tuple)
+			index, isLiving := living[key]
+			switch {
+			case update.Operation == core.RelationTupleUpdate_DELETE && isLiving:
+				lifetimes[index].DeletedRevision = revision
+				delete(living, key)
+			case update.Operation != core.RelationTupleUpdate_DELETE && !isLiving:
+				living[key] = len(tuples)
+				tuples = append(tuples, update.Tuple)
+				lifetimes = append(lifetimes, datastore.RevisionMetadata{CreatedRevision: revision})
+			}
+		}
+	}
+
+	var overlapping []*core.RelationTuple
+	revisions := make(map[*core.RelationTuple]datastore.RevisionMetadata)
+	for i, tpl := range tuples {
+		lifetime := lifetimes[i]
+		deleted := !lifetime.DeletedRevision.Equal(datastore.NoRevision)
+		if lifetime.CreatedRevision.GreaterThan(toRevision) || (deleted && lifetime.DeletedRevision.LessThanOrEqual(fromRevision)) {
+			continue
+		}
+
+		overlapping = append(overlapping, tpl)
+		revisions[tpl] = lifetime
+	}
+
+	iter := datastore.NewSliceRelationshipIteratorWithRevisions(overlapping, revisions)
+	runtime.SetFinalizer(iter, datastore.BuildFinalizerFunction())
+	return iter, nil
+}
+
+func relationshipFromTuple(tpl *core.RelationTuple) *relationship {
+	return &relationship{
+		tpl.ResourceAndRelation.Namespace,
+		tpl.ResourceAndRelation.ObjectId,
+		tpl.ResourceAndRelation.Relation,
+		tpl.Subject.Namespace,
+		tpl.Subject.ObjectId,
+		tpl.Subject.Relation,
+	}
+}
+
+var _ datastore.HistoricalQuerier = &memdbDatastore{}
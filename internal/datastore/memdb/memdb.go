@@ -9,10 +9,12 @@ import (
 	"sync"
 	"time"
 
+	"github.com/benbjohnson/clock"
 	"github.com/google/uuid"
 	"github.com/hashicorp/go-memdb"
 	"github.com/shopspring/decimal"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
@@ -35,6 +37,7 @@ func NewMemdbDatastore(
 	watchBufferLength uint16,
 	revisionQuantization,
 	gcWindow time.Duration,
+	opts ...Option,
 ) (datastore.Datastore, error) {
 	if revisionQuantization > gcWindow {
 		return nil, errors.New("gc window must be larger than quantization interval")
@@ -57,6 +60,11 @@ func NewMemdbDatastore(
 
 	negativeGCWindow := decimal.NewFromInt(gcWindow.Nanoseconds()).Mul(decimal.NewFromInt(-1))
 
+	config := memdbOptions{clock: clock.New()}
+	for _, opt := range opts {
+		opt(&config)
+	}
+
 	return &memdbDatastore{
 		db: db,
 		revisions: []snapshot{
@@ -69,6 +77,9 @@ func NewMemdbDatastore(
 		negativeGCWindow:   negativeGCWindow,
 		quantizationPeriod: decimal.NewFromInt(revisionQuantization.Nanoseconds()),
 		watchBufferLength:  watchBufferLength,
+		watchShutdown:      common.NewWatchShutdown(),
+		queryLatency:       simulatedLatency{config.clock, config.queryLatency},
+		watchLag:           simulatedLatency{config.clock, config.watchLag},
 		uniqueID:           uniqueID,
 	}, nil
 }
@@ -83,6 +94,9 @@ type memdbDatastore struct {
 	negativeGCWindow   datastore.Revision
 	quantizationPeriod datastore.Revision
 	watchBufferLength  uint16
+	watchShutdown      *common.WatchShutdown
+	queryLatency       simulatedLatency
+	watchLag           simulatedLatency
 	uniqueID           string
 }
 
@@ -96,7 +110,7 @@ func (mdb *memdbDatastore) SnapshotReader(revision datastore.Revision) datastore
 	defer mdb.RUnlock()
 
 	if err := mdb.checkRevisionLocal(revision); err != nil {
-		return &memdbReader{nil, nil, datastore.NoRevision, err}
+		return &memdbReader{nil, nil, datastore.NoRevision, err, simulatedLatency{}}
 	}
 
 	revIndex := sort.Search(len(mdb.revisions), func(i int) bool {
@@ -115,7 +129,7 @@ func (mdb *memdbDatastore) SnapshotReader(revision datastore.Revision) datastore
 		return roTxn, nil
 	}
 
-	return &memdbReader{noopTryLocker{}, txSrc, snapshotRevision, nil}
+	return &memdbReader{noopTryLocker{}, txSrc, snapshotRevision, nil, mdb.queryLatency}
 }
 
 func (mdb *memdbDatastore) ReadWriteTx(
@@ -151,7 +165,7 @@ func (mdb *memdbDatastore) ReadWriteTx(
 
 		newRevision := revisionFromTimestamp(time.Now().UTC())
 
-		rwt := &memdbReadWriteTx{memdbReader{&sync.Mutex{}, txSrc, datastore.NoRevision, nil}, newRevision}
+		rwt := &memdbReadWriteTx{memdbReader{&sync.Mutex{}, txSrc, datastore.NoRevision, nil, mdb.queryLatency}, newRevision, 0}
 		if err := f(ctx, rwt); err != nil {
 			mdb.Lock()
 			if tx != nil {
@@ -182,6 +196,7 @@ func (mdb *memdbDatastore) ReadWriteTx(
 			Revision: newRevision,
 			Changes:  nil,
 		}
+		var namespaceChanges []namespaceChange
 		if tx != nil {
 			for _, change := range tx.Changes() {
 				if change.Table == tableRelationship {
@@ -198,11 +213,21 @@ func (mdb *memdbDatastore) ReadWriteTx(
 						})
 					}
 				}
+				if change.Table == tableNamespace {
+					if change.After != nil {
+						written := change.After.(*namespace)
+						namespaceChanges = append(namespaceChanges, namespaceChange{written.name, written.configBytes})
+					}
+					if change.After == nil && change.Before != nil {
+						namespaceChanges = append(namespaceChanges, namespaceChange{change.Before.(*namespace).name, nil})
+					}
+				}
 			}
 
 			change := &changelog{
 				revisionNanos: newRevision.IntPart(),
 				changes:       newChanges,
+				namespaces:    namespaceChanges,
 			}
 			if err := tx.Insert(tableChangelog, change); err != nil {
 				return datastore.NoRevision, fmt.Errorf("error writing changelog: %w", err)
@@ -233,6 +258,9 @@ func (mdb *memdbDatastore) IsReady(ctx context.Context) (bool, error) {
 }
 
 func (mdb *memdbDatastore) Close() error {
+	// Stop the watches before the database they read from is released
+	mdb.watchShutdown.Close()
+
 	mdb.Lock()
 	defer mdb.Unlock()
 
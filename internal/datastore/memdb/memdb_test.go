@@ -8,13 +8,17 @@ import (
 	"testing"
 	"time"
 
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/benbjohnson/clock"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
 
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
 	test "github.com/authzed/spicedb/pkg/datastore/test"
 	ns "github.com/authzed/spicedb/pkg/namespace"
 	corev1 "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 type memDBTest struct{}
@@ -25,6 +29,8 @@ func (mdbt memDBTest) New(revisionQuantization, gcWindow time.Duration, watchBuf
 
 func TestMemdbDatastore(t *testing.T) {
 	test.All(t, memDBTest{})
+	t.Run("TestWatchCreateThenDelete", func(t *testing.T) { test.WatchCreateThenDeleteTest(t, memDBTest{}) })
+	t.Run("TestWatchSchemaOnlyWrite", func(t *testing.T) { test.WatchSchemaOnlyWriteTest(t, memDBTest{}) })
 }
 
 func TestConcurrentWritePanic(t *testing.T) {
@@ -77,3 +83,165 @@ func TestConcurrentWritePanic(t *testing.T) {
 	}, 1*time.Second, 10*time.Millisecond)
 	require.ErrorIs(err, recoverErr)
 }
+
+func TestWatchClosing(t *testing.T) {
+	require := require.New(t)
+
+	ds, err := NewMemdbDatastore(0, 0, DisableGC)
+	require.NoError(err)
+
+	ctx := context.Background()
+	startRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	changes, errchan := ds.Watch(ctx, startRevision)
+
+	writtenRevision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse("document:foo#viewer@user:tom"))),
+		})
+	})
+	require.NoError(err)
+
+	select {
+	case change := <-changes:
+		require.True(writtenRevision.Equal(change.Revision))
+	case <-time.After(5 * time.Second):
+		require.Fail("Timed out waiting for changes")
+	}
+
+	// Closing the datastore stops the watch with an error carrying the last delivered revision,
+	// rather than a cancellation
+	require.NoError(ds.Close())
+
+	select {
+	case err := <-errchan:
+		var closingErr datastore.ErrWatchClosing
+		require.ErrorAs(err, &closingErr)
+		require.True(writtenRevision.Equal(closingErr.LastRevision()))
+	case <-time.After(5 * time.Second):
+		require.Fail("Timed out waiting for the watch to stop")
+	}
+
+	_, ok := <-changes
+	require.False(ok)
+}
+
+func TestSimulatedWatchLagDisconnect(t *testing.T) {
+	require := require.New(t)
+
+	mockClock := clock.NewMock()
+	ds, err := NewMemdbDatastore(1, 0, DisableGC, WithSimulatedWatchLag(time.Second), WithClock(mockClock))
+	require.NoError(err)
+
+	ctx := context.Background()
+	startRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	changes, errchan := ds.Watch(ctx, startRevision)
+
+	for i := 0; i < 3; i++ {
+		_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+				tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse(fmt.Sprintf("document:doc%d#viewer@user:tom", i)))),
+			})
+		})
+		require.NoError(err)
+	}
+
+	// Nothing is delivered while the watch lags behind
+	select {
+	case <-changes:
+		require.Fail("changes delivered before the lag passed")
+	case err := <-errchan:
+		require.Fail("watch failed before the lag passed", "%s", err)
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	// Once the lag has passed, the watch loads all of the changes at once, which overflow the
+	// buffer of a consumer which has not read any
+	require.Eventually(func() bool {
+		mockClock.Add(time.Second)
+		select {
+		case err := <-errchan:
+			require.ErrorAs(err, &datastore.ErrWatchDisconnected{})
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestSimulatedQueryLatency(t *testing.T) {
+	require := require.New(t)
+
+	mockClock := clock.NewMock()
+	ds, err := NewMemdbDatastore(0, 0, DisableGC, WithSimulatedQueryLatency(time.Second), WithClock(mockClock))
+	require.NoError(err)
+
+	ctx := context.Background()
+	revision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	queried := make(chan error, 1)
+	go func() {
+		iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: "document"})
+		if err == nil {
+			iter.Close()
+		}
+		queried <- err
+	}()
+
+	select {
+	case <-queried:
+		require.Fail("query completed before the latency passed")
+	case <-time.After(50 * time.Millisecond):
+	}
+
+	require.Eventually(func() bool {
+		mockClock.Add(time.Second)
+		select {
+		case err := <-queried:
+			require.NoError(err)
+			return true
+		default:
+			return false
+		}
+	}, 5*time.Second, 10*time.Millisecond)
+}
+
+func TestObjectIDCase(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := NewMemdbDatastore(0, 0, DisableGC)
+	require.NoError(err)
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse("document:user123#viewer@user:user123"))),
+		})
+	})
+	require.NoError(err)
+
+	countMatching := func(filter *v1.RelationshipFilter, opts ...options.QueryOptionsOption) int {
+		iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, filter, opts...)
+		require.NoError(err)
+		defer iter.Close()
+
+		count := 0
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			count++
+		}
+		require.NoError(iter.Err())
+		return count
+	}
+
+	for _, filter := range []*v1.RelationshipFilter{
+		{ResourceType: "document", OptionalResourceId: "User123"},
+		{ResourceType: "document", OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: "user", OptionalSubjectId: "USER123"}},
+	} {
+		require.Zero(countMatching(filter))
+		require.Equal(1, countMatching(filter, options.WithCaseInsensitiveObjectID()))
+	}
+}
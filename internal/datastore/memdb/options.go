@@ -0,0 +1,65 @@
+package memdb
+
+import (
+	"context"
+	"time"
+
+	"github.com/benbjohnson/clock"
+)
+
+type memdbOptions struct {
+	queryLatency time.Duration
+	watchLag     time.Duration
+	clock        clock.Clock
+}
+
+// Option configures a memdb datastore.
+type Option func(*memdbOptions)
+
+// WithSimulatedQueryLatency delays each relationship query by the duration before it runs, as a
+// database would, so that tests can exercise slow queries.
+func WithSimulatedQueryLatency(latency time.Duration) Option {
+	return func(mo *memdbOptions) {
+		mo.queryLatency = latency
+	}
+}
+
+// WithSimulatedWatchLag delays each load of changes by watches by the duration, as the polling
+// watches of the SQL datastores would, so that changes accumulate in the meantime. This allows
+// tests to exercise consumers falling behind, and the disconnection of those which do.
+func WithSimulatedWatchLag(lag time.Duration) Option {
+	return func(mo *memdbOptions) {
+		mo.watchLag = lag
+	}
+}
+
+// WithClock sets the clock by which the simulated latencies are measured, which defaults to the
+// real clock. Tests can use a mock clock to control them deterministically.
+func WithClock(clock clock.Clock) Option {
+	return func(mo *memdbOptions) {
+		mo.clock = clock
+	}
+}
+
+// simulatedLatency delays operations by a duration, as measured by a clock.
+type simulatedLatency struct {
+	clock    clock.Clock
+	duration time.Duration
+}
+
+// wait waits out the latency, returning the context's error if it is done first.
+func (sl simulatedLatency) wait(ctx context.Context) error {
+	if sl.duration <= 0 {
+		return nil
+	}
+
+	timer := sl.clock.Timer(sl.duration)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -3,25 +3,31 @@ package memdb
 import (
 	"context"
 	"fmt"
+	"math"
 	"runtime"
+	"strings"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/hashicorp/go-memdb"
 	"github.com/jzelinskie/stringz"
+	"github.com/shopspring/decimal"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 type txFactory func() (*memdb.Txn, error)
 
 type memdbReader struct {
 	TryLocker
-	txSource txFactory
-	revision datastore.Revision
-	initErr  error
+	txSource     txFactory
+	revision     datastore.Revision
+	initErr      error
+	queryLatency simulatedLatency
 }
 
 // QueryRelationships reads relationships starting from the resource side.
@@ -34,6 +40,10 @@ func (r *memdbReader) QueryRelationships(
 		return nil, r.initErr
 	}
 
+	if err := r.queryLatency.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	r.lockOrPanic()
 	defer r.Unlock()
 
@@ -44,19 +54,44 @@ func (r *memdbReader) QueryRelationships(
 
 	queryOpts := options.NewQueryOptionsWithOptions(opts...)
 
-	bestIterator, err := iteratorForFilter(tx, filter)
+	// The indexes match object IDs exactly, so when ignoring their case the relationships are
+	// found without them, and the IDs are matched afterward.
+	indexFilter := filter
+	if queryOpts.IgnoreObjectIDCase {
+		indexFilter = withoutObjectIDs(filter)
+	}
+
+	resourceType := filter.ResourceType
+	var bestIterator memdb.ResultIterator
+	if len(queryOpts.AdditionalResourceTypes) > 0 {
+		// Each iterator is limited to a single resource type by its index, so the filter need not
+		// check it.
+		bestIterator, err = iteratorForResourceTypes(tx, indexFilter, queryOpts.AdditionalResourceTypes)
+		resourceType = ""
+	} else {
+		bestIterator, err = iteratorForFilter(tx, indexFilter)
+	}
 	if err != nil {
 		return nil, err
 	}
 
 	matchingRelationshipsFilterFunc := filterFuncForFilters(
-		filter.ResourceType,
-		filter.OptionalResourceId,
-		filter.OptionalRelation,
-		filter.OptionalSubjectFilter,
+		resourceType,
+		indexFilter.OptionalResourceId,
+		indexFilter.OptionalRelation,
+		indexFilter.OptionalSubjectFilter,
 		queryOpts.Usersets,
 	)
-	filteredIterator := memdb.NewFilterIterator(bestIterator, matchingRelationshipsFilterFunc)
+	var filteredIterator memdb.ResultIterator = memdb.NewFilterIterator(bestIterator, matchingRelationshipsFilterFunc)
+	if queryOpts.IgnoreObjectIDCase {
+		filteredIterator = memdb.NewFilterIterator(filteredIterator, objectIDsFoldFilterFunc(filter))
+	}
+
+	if queryOpts.Sorted {
+		iter := datastore.NewSliceRelationshipIterator(sortedTuples(filteredIterator, queryOpts.After, queryOpts.Limit))
+		runtime.SetFinalizer(iter, datastore.BuildFinalizerFunction())
+		return datastore.NewMaxResultsIterator(iter, queryOpts.MaxResults), nil
+	}
 
 	iter := &memdbTupleIterator{
 		it:    filteredIterator,
@@ -69,7 +104,7 @@ func (r *memdbReader) QueryRelationships(
 		}
 	})
 
-	return iter, nil
+	return datastore.NewMaxResultsIterator(iter, queryOpts.MaxResults), nil
 }
 
 // ReverseQueryRelationships reads relationships starting from the subject.
@@ -82,6 +117,10 @@ func (r *memdbReader) ReverseQueryRelationships(
 		return nil, r.initErr
 	}
 
+	if err := r.queryLatency.wait(ctx); err != nil {
+		return nil, err
+	}
+
 	r.lockOrPanic()
 	defer r.Unlock()
 
@@ -92,8 +131,16 @@ func (r *memdbReader) ReverseQueryRelationships(
 
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
 
+	// When the subject filter is broadened, the subject is matched separately.
+	var broadenedSubjectFilter memdb.FilterFunc
+	if (queryOpts.IncludeEllipsisRelation && subjectFilter.OptionalRelation != nil) ||
+		(queryOpts.IncludeWildcardSubject && subjectFilter.OptionalSubjectId != "") {
+		broadenedSubjectFilter = broadenedSubjectFilterFunc(subjectFilter, queryOpts)
+		subjectFilter = &v1.SubjectFilter{SubjectType: subjectFilter.SubjectType}
+	}
+
 	var bestIterator memdb.ResultIterator
-	if queryOpts.ResRelation != nil {
+	if queryOpts.ResRelation != nil && queryOpts.ResRelation.Namespace != "" && queryOpts.ResRelation.Relation != "" {
 		bestIterator, err = tx.Get(
 			tableRelationship,
 			indexSubjectAndResourceRelation,
@@ -135,6 +182,12 @@ func (r *memdbReader) ReverseQueryRelationships(
 		nil,
 	)
 	filteredIterator := memdb.NewFilterIterator(bestIterator, matchingRelationshipsFilterFunc)
+	if broadenedSubjectFilter != nil {
+		filteredIterator = memdb.NewFilterIterator(filteredIterator, broadenedSubjectFilter)
+	}
+	if len(queryOpts.ExcludedSubjects) > 0 {
+		filteredIterator = memdb.NewFilterIterator(filteredIterator, excludedSubjectsFilterFunc(queryOpts.ExcludedSubjects))
+	}
 
 	iter := &memdbTupleIterator{
 		it:    filteredIterator,
@@ -150,6 +203,78 @@ func (r *memdbReader) ReverseQueryRelationships(
 	return iter, nil
 }
 
+// CountRelationships counts the relationships matching the filter.
+func (r *memdbReader) CountRelationships(ctx context.Context, filter *v1.RelationshipFilter) (uint64, error) {
+	if r.initErr != nil {
+		return 0, r.initErr
+	}
+
+	r.lockOrPanic()
+	defer r.Unlock()
+
+	tx, err := r.txSource()
+	if err != nil {
+		return 0, err
+	}
+
+	bestIterator, err := iteratorForFilter(tx, filter)
+	if err != nil {
+		return 0, err
+	}
+
+	matchingRelationshipsFilterFunc := filterFuncForFilters(
+		filter.ResourceType,
+		filter.OptionalResourceId,
+		filter.OptionalRelation,
+		filter.OptionalSubjectFilter,
+		nil,
+	)
+	filteredIterator := memdb.NewFilterIterator(bestIterator, matchingRelationshipsFilterFunc)
+
+	var count uint64
+	for found := filteredIterator.Next(); found != nil; found = filteredIterator.Next() {
+		count++
+	}
+
+	return count, nil
+}
+
+// CheckRelationshipsExist returns whether each of the given tuples exists, keyed by tuple.String.
+func (r *memdbReader) CheckRelationshipsExist(ctx context.Context, tuples []*core.RelationTuple) (map[string]bool, error) {
+	if r.initErr != nil {
+		return nil, r.initErr
+	}
+
+	r.lockOrPanic()
+	defer r.Unlock()
+
+	tx, err := r.txSource()
+	if err != nil {
+		return nil, err
+	}
+
+	exists := make(map[string]bool, len(tuples))
+	for _, tpl := range tuples {
+		found, err := tx.First(
+			tableRelationship,
+			indexID,
+			tpl.ResourceAndRelation.Namespace,
+			tpl.ResourceAndRelation.ObjectId,
+			tpl.ResourceAndRelation.Relation,
+			tpl.Subject.Namespace,
+			tpl.Subject.ObjectId,
+			tpl.Subject.Relation,
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		exists[tuple.String(tpl)] = found != nil
+	}
+
+	return exists, nil
+}
+
 // ReadNamespace reads a namespace definition and version and returns it, and the revision at
 // which it was created or last written, if found.
 func (r *memdbReader) ReadNamespace(ctx context.Context, nsName string) (ns *core.NamespaceDefinition, lastWritten datastore.Revision, err error) {
@@ -218,6 +343,37 @@ func (r *memdbReader) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefi
 	return nsDefs, nil
 }
 
+// LastNamespaceChange returns the revision of the latest transaction which changed the
+// relationships or definition of the namespace.
+func (r *memdbReader) LastNamespaceChange(ctx context.Context, nsName string) (datastore.Revision, error) {
+	if r.initErr != nil {
+		return datastore.NoRevision, r.initErr
+	}
+
+	r.lockOrPanic()
+	defer r.Unlock()
+
+	tx, err := r.txSource()
+	if err != nil {
+		return datastore.NoRevision, err
+	}
+
+	// The changelog of a snapshot holds only the transactions committed at or before it.
+	it, err := tx.ReverseLowerBound(tableChangelog, indexRevision, int64(math.MaxInt64))
+	if err != nil {
+		return datastore.NoRevision, err
+	}
+
+	for changeRaw := it.Next(); changeRaw != nil; changeRaw = it.Next() {
+		change := changeRaw.(*changelog)
+		if change.changesNamespace(nsName) {
+			return decimal.NewFromInt(change.revisionNanos), nil
+		}
+	}
+
+	return datastore.NoRevision, nil
+}
+
 func (r *memdbReader) lockOrPanic() {
 	if !r.TryLock() {
 		panic("detected concurrent use of ReadWriteTransaction")
@@ -258,6 +414,48 @@ func iteratorForFilter(txn *memdb.Txn, filter *v1.RelationshipFilter) (memdb.Res
 	return iter, err
 }
 
+// iteratorForResourceTypes returns an iterator over the relationships matching the filter with
+// its own resource type or any of the additional resource types, one type after the other.
+func iteratorForResourceTypes(txn *memdb.Txn, filter *v1.RelationshipFilter, additionalResourceTypes []string) (memdb.ResultIterator, error) {
+	seen := make(map[string]struct{}, len(additionalResourceTypes)+1)
+	iterators := make([]memdb.ResultIterator, 0, len(additionalResourceTypes)+1)
+	for _, resourceType := range append([]string{filter.ResourceType}, additionalResourceTypes...) {
+		if _, ok := seen[resourceType]; ok {
+			continue
+		}
+		seen[resourceType] = struct{}{}
+
+		typeFilter := proto.Clone(filter).(*v1.RelationshipFilter)
+		typeFilter.ResourceType = resourceType
+		iter, err := iteratorForFilter(txn, typeFilter)
+		if err != nil {
+			return nil, err
+		}
+		iterators = append(iterators, iter)
+	}
+
+	return &concatIterator{iterators: iterators}, nil
+}
+
+// concatIterator returns the results of each of its iterators in turn.
+type concatIterator struct {
+	iterators []memdb.ResultIterator
+}
+
+func (ci *concatIterator) WatchCh() <-chan struct{} {
+	panic("concatIterator does not support watching")
+}
+
+func (ci *concatIterator) Next() interface{} {
+	for len(ci.iterators) > 0 {
+		if next := ci.iterators[0].Next(); next != nil {
+			return next
+		}
+		ci.iterators = ci.iterators[1:]
+	}
+	return nil
+}
+
 func filterFuncForFilters(optionalObjectType, optionalObjectID, optionalRelation string,
 	optionalSubjectFilter *v1.SubjectFilter, usersets []*core.ObjectAndRelation,
 ) memdb.FilterFunc {
@@ -301,11 +499,100 @@ func filterFuncForFilters(optionalObjectType, optionalObjectID, optionalRelation
 	}
 }
 
+// withoutObjectIDs returns a copy of the filter which does not filter on resource or subject ID.
+func withoutObjectIDs(filter *v1.RelationshipFilter) *v1.RelationshipFilter {
+	stripped := proto.Clone(filter).(*v1.RelationshipFilter)
+	stripped.OptionalResourceId = ""
+	if stripped.OptionalSubjectFilter != nil {
+		stripped.OptionalSubjectFilter.OptionalSubjectId = ""
+	}
+	return stripped
+}
+
+// objectIDsFoldFilterFunc returns a filter which removes the tuples whose resource or subject ID
+// does not match that of the filter, regardless of case.
+func objectIDsFoldFilterFunc(filter *v1.RelationshipFilter) memdb.FilterFunc {
+	return func(tupleRaw interface{}) bool {
+		rel := tupleRaw.(*relationship)
+
+		if filter.OptionalResourceId != "" && !strings.EqualFold(filter.OptionalResourceId, rel.resourceID) {
+			return true
+		}
+
+		subjectID := filter.GetOptionalSubjectFilter().GetOptionalSubjectId()
+		return subjectID != "" && !strings.EqualFold(subjectID, rel.subjectObjectID)
+	}
+}
+
+// broadenedSubjectFilterFunc returns a filter which removes the tuples whose subject does not
+// match the object ID and relation of the subject filter, broadened as requested by the
+// IncludeEllipsisRelation and IncludeWildcardSubject reverse query options.
+func broadenedSubjectFilterFunc(filter *v1.SubjectFilter, queryOpts *options.ReverseQueryOptions) memdb.FilterFunc {
+	return func(tupleRaw interface{}) bool {
+		rel := tupleRaw.(*relationship)
+
+		// The wildcard subject always has the ellipsis relation, so it is matched by object ID alone.
+		if queryOpts.IncludeWildcardSubject && rel.subjectObjectID == tuple.PublicWildcard {
+			return false
+		}
+
+		if filter.OptionalSubjectId != "" && rel.subjectObjectID != filter.OptionalSubjectId {
+			return true
+		}
+
+		if filter.OptionalRelation != nil {
+			relation := stringz.DefaultEmpty(filter.OptionalRelation.Relation, datastore.Ellipsis)
+			isEllipsis := queryOpts.IncludeEllipsisRelation && rel.subjectRelation == datastore.Ellipsis
+			if rel.subjectRelation != relation && !isEllipsis {
+				return true
+			}
+		}
+
+		return false
+	}
+}
+
+// excludedSubjectsFilterFunc returns a filter which removes the tuples whose subjects are any of
+// the given usersets.
+func excludedSubjectsFilterFunc(usersets []*core.ObjectAndRelation) memdb.FilterFunc {
+	return func(tupleRaw interface{}) bool {
+		tuple := tupleRaw.(*relationship)
+		for _, userset := range usersets {
+			if userset.Namespace == tuple.subjectNamespace &&
+				userset.ObjectId == tuple.subjectObjectID &&
+				userset.Relation == tuple.subjectRelation {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// sortedTuples loads all of the tuples from the iterator which sort after the cursor tuple, if
+// non-nil, and returns them sorted, up to the limit, if non-nil.
+func sortedTuples(it memdb.ResultIterator, after *core.RelationTuple, limit *uint64) []*core.RelationTuple {
+	var tuples []*core.RelationTuple
+	for foundRaw := it.Next(); foundRaw != nil; foundRaw = it.Next() {
+		tpl := foundRaw.(*relationship).RelationTuple()
+		if after == nil || common.TupleLess(after, tpl) {
+			tuples = append(tuples, tpl)
+		}
+	}
+
+	common.SortTuples(tuples)
+	if limit != nil && uint64(len(tuples)) > *limit {
+		tuples = tuples[:*limit]
+	}
+
+	return tuples
+}
+
 type memdbTupleIterator struct {
 	closed bool
 	it     memdb.ResultIterator
 	limit  *uint64
 	count  uint64
+	last   *core.RelationTuple
 }
 
 func (mti *memdbTupleIterator) Next() *core.RelationTuple {
@@ -319,18 +606,26 @@ func (mti *memdbTupleIterator) Next() *core.RelationTuple {
 	}
 	mti.count++
 
-	return foundRaw.(*relationship).RelationTuple()
+	mti.last = foundRaw.(*relationship).RelationTuple()
+	return mti.last
 }
 
 func (mti *memdbTupleIterator) Err() error {
 	return nil
 }
 
+func (mti *memdbTupleIterator) Cursor() *core.RelationTuple {
+	return mti.last
+}
+
 func (mti *memdbTupleIterator) Close() {
 	mti.closed = true
 }
 
-var _ datastore.Reader = &memdbReader{}
+var (
+	_ datastore.Reader                  = &memdbReader{}
+	_ datastore.NamespaceChangeReporter = &memdbReader{}
+)
 
 type TryLocker interface {
 	TryLock() bool
@@ -1,6 +1,7 @@
 package memdb
 
 import (
+	"context"
 	"fmt"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
@@ -8,6 +9,7 @@ import (
 	"github.com/jzelinskie/stringz"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
@@ -15,6 +17,14 @@ import (
 type memdbReadWriteTx struct {
 	memdbReader
 	newRevision datastore.Revision
+
+	effectiveTouches uint64
+}
+
+// EffectiveTouches returns the number of touches written by the transaction which created a
+// relationship.
+func (rwt *memdbReadWriteTx) EffectiveTouches() uint64 {
+	return rwt.effectiveTouches
 }
 
 func (rwt *memdbReadWriteTx) WriteRelationships(mutations []*v1.RelationshipUpdate) error {
@@ -66,8 +76,17 @@ func (rwt *memdbReadWriteTx) write(tx *memdb.Txn, mutations []*v1.RelationshipUp
 			if existing != nil {
 				return fmt.Errorf("duplicate relationship found for create operation")
 			}
-			fallthrough
+			if err := tx.Insert(tableRelationship, rel); err != nil {
+				return fmt.Errorf("error inserting relationship: %w", err)
+			}
 		case v1.RelationshipUpdate_OPERATION_TOUCH:
+			// Touches of relationships which are already living are skipped, so that they do not
+			// show up as changes in watch. A relationship deleted earlier in the same batch is no
+			// longer found, and is therefore written again.
+			if existing != nil {
+				continue
+			}
+			rwt.effectiveTouches++
 			if err := tx.Insert(tableRelationship, rel); err != nil {
 				return fmt.Errorf("error inserting relationship: %w", err)
 			}
@@ -85,24 +104,24 @@ func (rwt *memdbReadWriteTx) write(tx *memdb.Txn, mutations []*v1.RelationshipUp
 	return nil
 }
 
-func (rwt *memdbReadWriteTx) DeleteRelationships(filter *v1.RelationshipFilter) error {
+func (rwt *memdbReadWriteTx) DeleteRelationships(filter *v1.RelationshipFilter) (uint64, error) {
 	rwt.lockOrPanic()
 	defer rwt.Unlock()
 
 	tx, err := rwt.txSource()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	return rwt.deleteWithLock(tx, filter)
 }
 
 // caller must already hold the concurrent access lock
-func (rwt *memdbReadWriteTx) deleteWithLock(tx *memdb.Txn, filter *v1.RelationshipFilter) error {
+func (rwt *memdbReadWriteTx) deleteWithLock(tx *memdb.Txn, filter *v1.RelationshipFilter) (uint64, error) {
 	// Create an iterator to find the relevant tuples
 	bestIter, err := iteratorForFilter(tx, filter)
 	if err != nil {
-		return err
+		return 0, err
 	}
 	filteredIter := memdb.NewFilterIterator(bestIter, relationshipFilterFilterFunc(filter))
 
@@ -115,7 +134,11 @@ func (rwt *memdbReadWriteTx) deleteWithLock(tx *memdb.Txn, filter *v1.Relationsh
 		})
 	}
 
-	return rwt.write(tx, mutations)
+	if err := rwt.write(tx, mutations); err != nil {
+		return 0, err
+	}
+
+	return uint64(len(mutations)), nil
 }
 
 func (rwt *memdbReadWriteTx) WriteNamespaces(newConfigs ...*core.NamespaceDefinition) error {
@@ -167,7 +190,7 @@ func (rwt *memdbReadWriteTx) DeleteNamespace(nsName string) error {
 	}
 
 	// Delete the relationships from the namespace
-	if err := rwt.deleteWithLock(tx, &v1.RelationshipFilter{
+	if _, err := rwt.deleteWithLock(tx, &v1.RelationshipFilter{
 		ResourceType: nsName,
 	}); err != nil {
 		return fmt.Errorf("unable to delete relationships from deleted namespace: %w", err)
@@ -176,6 +199,14 @@ func (rwt *memdbReadWriteTx) DeleteNamespace(nsName string) error {
 	return nil
 }
 
+func (rwt *memdbReadWriteTx) RenameNamespace(oldName, newName string) error {
+	return common.RenameNamespace(context.Background(), rwt, oldName, newName)
+}
+
+func (rwt *memdbReadWriteTx) CopyNamespace(sourceName, destName string, copyTuples bool) (uint64, error) {
+	return common.CopyNamespace(context.Background(), rwt, sourceName, destName, copyTuples)
+}
+
 func relationshipFilterFilterFunc(filter *v1.RelationshipFilter) func(interface{}) bool {
 	return func(tupleRaw interface{}) bool {
 		tuple := tupleRaw.(*relationship)
@@ -26,6 +26,11 @@ func (mdb *memdbDatastore) HeadRevision(ctx context.Context) (datastore.Revision
 	return revisionFromTimestamp(time.Now().UTC()), nil
 }
 
+// MinWatchRevision returns the start of the garbage collection window, as with CheckRevision.
+func (mdb *memdbDatastore) MinWatchRevision(ctx context.Context) (datastore.Revision, error) {
+	return revisionFromTimestamp(time.Now().UTC()).Add(mdb.negativeGCWindow), nil
+}
+
 func (mdb *memdbDatastore) CheckRevision(ctx context.Context, revision datastore.Revision) error {
 	return mdb.checkRevisionLocal(revision)
 }
@@ -44,3 +49,5 @@ func (mdb *memdbDatastore) checkRevisionLocal(revision datastore.Revision) error
 
 	return nil
 }
+
+var _ datastore.WatchRevisionReporter = &memdbDatastore{}
@@ -96,6 +96,29 @@ func (r relationship) RelationTuple() *core.RelationTuple {
 type changelog struct {
 	revisionNanos int64
 	changes       datastore.RevisionChanges
+	namespaces    []namespaceChange
+}
+
+func (cl *changelog) changesNamespace(nsName string) bool {
+	for _, nsChange := range cl.namespaces {
+		if nsChange.name == nsName {
+			return true
+		}
+	}
+
+	for _, change := range cl.changes.Changes {
+		if change.Tuple.ResourceAndRelation.Namespace == nsName {
+			return true
+		}
+	}
+
+	return false
+}
+
+// namespaceChange records a namespace written in a transaction, or deleted if configBytes is nil.
+type namespaceChange struct {
+	name        string
+	configBytes []byte
 }
 
 var schema = &memdb.DBSchema{
@@ -6,27 +6,68 @@ import (
 	"fmt"
 
 	"github.com/hashicorp/go-memdb"
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/proto"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
 const errWatchError = "watch error: %w"
 
-func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	watchOpts := options.NewWatchOptionsWithOptions(opts...)
+
 	updates := make(chan *datastore.RevisionChanges, mdb.watchBufferLength)
 	errs := make(chan error, 1)
 
+	// Changes are waited for with a context canceled when the datastore starts closing, but
+	// delivered with the caller's, so that those already loaded are delivered.
+	watchCtx, watchDone := mdb.watchShutdown.Start(ctx)
+
 	go func() {
 		defer close(updates)
 		defer close(errs)
+		defer watchDone()
+
+		minRevision, err := mdb.MinWatchRevision(ctx)
+		if err != nil {
+			errs <- err
+			return
+		}
+		if afterRevision.LessThan(minRevision) {
+			errs <- datastore.NewWatchRevisionTooOldErr(afterRevision, minRevision)
+			return
+		}
+
+		if watchOpts.EmitCheckpoint {
+			head, err := mdb.HeadRevision(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			checkpoint := &datastore.RevisionChanges{Revision: head, IsCheckpoint: true}
+			if err := common.SendRevisionChanges(ctx, updates, checkpoint, watchOpts); err != nil {
+				errs <- err
+				return
+			}
+		}
 
 		currentTxn := afterRevision.IntPart()
 
 		for {
+			if err := mdb.watchLag.wait(watchCtx); err != nil {
+				errs <- mdb.watchStoppedErr(err, currentTxn)
+				return
+			}
+
 			var stagedUpdates []*datastore.RevisionChanges
 			var watchChan <-chan struct{}
 			var err error
-			stagedUpdates, currentTxn, watchChan, err = mdb.loadChanges(ctx, currentTxn)
+			stagedUpdates, currentTxn, watchChan, err = mdb.loadChanges(ctx, currentTxn, watchOpts)
 			if err != nil {
 				errs <- err
 				return
@@ -34,10 +75,8 @@ func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 
 			// Write the staged updates to the channel
 			for _, changeToWrite := range stagedUpdates {
-				select {
-				case updates <- changeToWrite:
-				default:
-					errs <- datastore.NewWatchDisconnectedErr()
+				if err := common.SendRevisionChanges(ctx, updates, changeToWrite, watchOpts); err != nil {
+					errs <- err
 					return
 				}
 			}
@@ -46,14 +85,9 @@ func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 			ws := memdb.NewWatchSet()
 			ws.Add(watchChan)
 
-			err = ws.WatchCtx(ctx)
+			err = ws.WatchCtx(watchCtx)
 			if err != nil {
-				switch {
-				case errors.Is(err, context.Canceled):
-					errs <- datastore.NewWatchCanceledErr()
-				default:
-					errs <- fmt.Errorf(errWatchError, err)
-				}
+				errs <- mdb.watchStoppedErr(err, currentTxn)
 				return
 			}
 		}
@@ -62,7 +96,21 @@ func (mdb *memdbDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 	return updates, errs
 }
 
-func (mdb *memdbDatastore) loadChanges(ctx context.Context, currentTxn int64) ([]*datastore.RevisionChanges, int64, <-chan struct{}, error) {
+// watchStoppedErr returns the error with which a watch, which has delivered the changes through
+// the revision, stops once waiting for changes failed with err: ErrWatchClosing if the datastore
+// is closing, and ErrWatchCanceled if the watch was canceled.
+func (mdb *memdbDatastore) watchStoppedErr(err error, currentTxn int64) error {
+	switch {
+	case mdb.watchShutdown.Closing():
+		return datastore.NewWatchClosingErr(decimal.NewFromInt(currentTxn))
+	case errors.Is(err, context.Canceled):
+		return datastore.NewWatchCanceledErr()
+	default:
+		return fmt.Errorf(errWatchError, err)
+	}
+}
+
+func (mdb *memdbDatastore) loadChanges(ctx context.Context, currentTxn int64, watchOpts *options.WatchOptions) ([]*datastore.RevisionChanges, int64, <-chan struct{}, error) {
 	mdb.RLock()
 	defer mdb.RUnlock()
 
@@ -78,8 +126,29 @@ func (mdb *memdbDatastore) loadChanges(ctx context.Context, currentTxn int64) ([
 	lastRevision := currentTxn
 	for changeRaw := it.Next(); changeRaw != nil; changeRaw = it.Next() {
 		change := changeRaw.(*changelog)
-		changes = append(changes, &change.changes)
 		lastRevision = change.revisionNanos
+
+		// Transactions which did not change any relationships are not reported, matching the
+		// SQL datastores, whose changes are read from the relationships themselves.
+		if len(change.changes.Changes) == 0 {
+			continue
+		}
+
+		if len(watchOpts.Namespaces) == 0 {
+			changes = append(changes, &change.changes)
+			continue
+		}
+
+		filtered := &datastore.RevisionChanges{Revision: change.changes.Revision}
+		for _, update := range change.changes.Changes {
+			if watchOpts.IncludesNamespace(update.Tuple.ResourceAndRelation.Namespace) {
+				filtered.Changes = append(filtered.Changes, update)
+			}
+		}
+
+		if len(filtered.Changes) > 0 {
+			changes = append(changes, filtered)
+		}
 	}
 
 	watchChan, _, err := loadNewTxn.LastWatch(tableChangelog, indexRevision)
@@ -89,3 +158,112 @@ func (mdb *memdbDatastore) loadChanges(ctx context.Context, currentTxn int64) ([
 
 	return changes, lastRevision, watchChan, nil
 }
+
+func (mdb *memdbDatastore) WatchNamespaces(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.NamespaceChange, <-chan error) {
+	updates := make(chan *datastore.NamespaceChange, mdb.watchBufferLength)
+	errs := make(chan error, 1)
+
+	watchCtx, watchDone := mdb.watchShutdown.Start(ctx)
+
+	go func() {
+		defer close(updates)
+		defer close(errs)
+		defer watchDone()
+
+		minRevision, err := mdb.MinWatchRevision(ctx)
+		if err != nil {
+			errs <- err
+			return
+		}
+		if afterRevision.LessThan(minRevision) {
+			errs <- datastore.NewWatchRevisionTooOldErr(afterRevision, minRevision)
+			return
+		}
+
+		currentTxn := afterRevision.IntPart()
+
+		for {
+			if err := mdb.watchLag.wait(watchCtx); err != nil {
+				errs <- mdb.watchStoppedErr(err, currentTxn)
+				return
+			}
+
+			var stagedChanges []*datastore.NamespaceChange
+			var watchChan <-chan struct{}
+			var err error
+			stagedChanges, currentTxn, watchChan, err = mdb.loadNamespaceChanges(currentTxn)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			// A consumer which stops reading must not hold up closing the datastore. The changes
+			// of a revision interrupted while closing may have been partially delivered, so the
+			// watch must then be resumed before it.
+			for _, changeToWrite := range stagedChanges {
+				select {
+				case updates <- changeToWrite:
+				case <-watchCtx.Done():
+					errs <- mdb.watchStoppedErr(watchCtx.Err(), changeToWrite.Revision.IntPart()-1)
+					return
+				}
+			}
+
+			ws := memdb.NewWatchSet()
+			ws.Add(watchChan)
+
+			err = ws.WatchCtx(watchCtx)
+			if err != nil {
+				errs <- mdb.watchStoppedErr(err, currentTxn)
+				return
+			}
+		}
+	}()
+
+	return updates, errs
+}
+
+func (mdb *memdbDatastore) loadNamespaceChanges(currentTxn int64) ([]*datastore.NamespaceChange, int64, <-chan struct{}, error) {
+	mdb.RLock()
+	defer mdb.RUnlock()
+
+	loadNewTxn := mdb.db.Txn(false)
+	defer loadNewTxn.Abort()
+
+	it, err := loadNewTxn.LowerBound(tableChangelog, indexRevision, currentTxn+1)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf(errWatchError, err)
+	}
+
+	var changes []*datastore.NamespaceChange
+	lastRevision := currentTxn
+	for changeRaw := it.Next(); changeRaw != nil; changeRaw = it.Next() {
+		change := changeRaw.(*changelog)
+		lastRevision = change.revisionNanos
+
+		for _, nsChange := range change.namespaces {
+			loaded := &datastore.NamespaceChange{
+				Revision: change.changes.Revision,
+				Name:     nsChange.name,
+			}
+
+			if nsChange.configBytes != nil {
+				loaded.Definition = &core.NamespaceDefinition{}
+				if err := proto.Unmarshal(nsChange.configBytes, loaded.Definition); err != nil {
+					return nil, 0, nil, fmt.Errorf(errWatchError, err)
+				}
+			}
+
+			changes = append(changes, loaded)
+		}
+	}
+
+	watchChan, _, err := loadNewTxn.LastWatch(tableChangelog, indexRevision)
+	if err != nil {
+		return nil, 0, nil, fmt.Errorf(errWatchError, err)
+	}
+
+	return changes, lastRevision, watchChan, nil
+}
+
+var _ datastore.NamespaceWatcher = &memdbDatastore{}
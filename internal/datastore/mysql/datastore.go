@@ -186,9 +186,16 @@ func NewMySQLDatastore(uri string, options ...Option) (*Datastore, error) {
 		readTxOptions:          &sql.TxOptions{Isolation: sql.LevelSerializable, ReadOnly: true},
 		maxRetries:             config.maxRetries,
 		analyzeBeforeStats:     config.analyzeBeforeStats,
+		nullSubjectRelation:    config.nullSubjectRelation,
 		CachedOptimizedRevisions: revisions.NewCachedOptimizedRevisions(
 			maxRevisionStaleness,
 		),
+		watchPolling: common.WatchPollingConfig{
+			Interval:     config.watchPollInterval,
+			MaxInterval:  config.watchPollMaxInterval,
+			BackoffAfter: config.watchPollBackoffAfter,
+			JitterFactor: config.watchPollJitterFactor,
+		},
 	}
 
 	store.SetOptimizedRevisionFunc(store.optimizedRevisionFunc)
@@ -231,7 +238,7 @@ func (mds *Datastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
 	}
 
 	querySplitter := common.TupleQuerySplitter{
-		Executor:         newMySQLExecutor(mds.db),
+		Executor:         newMySQLExecutor(mds.db, mds.nullSubjectRelation),
 		UsersetBatchSize: mds.usersetBatchSize,
 	}
 
@@ -266,7 +273,7 @@ func (mds *Datastore) ReadWriteTx(
 			}
 
 			querySplitter := common.TupleQuerySplitter{
-				Executor:         newMySQLExecutor(tx),
+				Executor:         newMySQLExecutor(tx, mds.nullSubjectRelation),
 				UsersetBatchSize: mds.usersetBatchSize,
 			}
 
@@ -280,6 +287,7 @@ func (mds *Datastore) ReadWriteTx(
 				ctx,
 				tx,
 				newTxnID,
+				0,
 			}
 
 			if err := fn(ctx, rwt); err != nil {
@@ -314,7 +322,7 @@ type querier interface {
 	QueryContext(context.Context, string, ...interface{}) (*sql.Rows, error)
 }
 
-func newMySQLExecutor(tx querier) common.ExecuteQueryFunc {
+func newMySQLExecutor(tx querier, nullSubjectRelation common.NullSubjectRelation) common.ExecuteQueryFunc {
 	// This implementation does not create a transaction because it's redundant for single statements, and it avoids
 	// the network overhead and reduce contention on the connection pool. From MySQL docs:
 	//
@@ -350,18 +358,24 @@ func newMySQLExecutor(tx querier) common.ExecuteQueryFunc {
 				Subject:             &core.ObjectAndRelation{},
 			}
 
+			var subjectRelation *string
 			err := rows.Scan(
 				&nextTuple.ResourceAndRelation.Namespace,
 				&nextTuple.ResourceAndRelation.ObjectId,
 				&nextTuple.ResourceAndRelation.Relation,
 				&nextTuple.Subject.Namespace,
 				&nextTuple.Subject.ObjectId,
-				&nextTuple.Subject.Relation,
+				&subjectRelation,
 			)
 			if err != nil {
 				return nil, fmt.Errorf(errUnableToQueryTuples, err)
 			}
 
+			nextTuple.Subject.Relation, err = nullSubjectRelation.SubjectRelationFromColumn(ctx, nextTuple, subjectRelation)
+			if err != nil {
+				return nil, fmt.Errorf(errUnableToQueryTuples, err)
+			}
+
 			tuples = append(tuples, nextTuple)
 		}
 		if err := rows.Err(); err != nil {
@@ -385,8 +399,10 @@ type Datastore struct {
 	gcInterval           time.Duration
 	gcTimeout            time.Duration
 	watchBufferLength    uint16
+	watchPolling         common.WatchPollingConfig
 	usersetBatchSize     uint16
 	maxRetries           uint8
+	nullSubjectRelation  common.NullSubjectRelation
 
 	optimizedRevisionQuery string
 	validTransactionQuery  string
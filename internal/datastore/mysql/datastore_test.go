@@ -85,6 +85,12 @@ func TestMySQLDatastore(t *testing.T) {
 	b := testdatastore.RunMySQLForTesting(t, "")
 	dst := datastoreTester{b: b, t: t}
 	test.All(t, test.DatastoreTesterFunc(dst.createDatastore))
+	t.Run("TestWatchCreateThenDelete", func(t *testing.T) {
+		test.WatchCreateThenDeleteTest(t, test.DatastoreTesterFunc(dst.createDatastore))
+	})
+	t.Run("TestWatchSchemaOnlyWrite", func(t *testing.T) {
+		test.WatchSchemaOnlyWriteTest(t, test.DatastoreTesterFunc(dst.createDatastore))
+	})
 
 	t.Run("DatabaseSeeding", createDatastoreTest(b, DatabaseSeedingTest))
 	t.Run("PrometheusCollector", createDatastoreTest(
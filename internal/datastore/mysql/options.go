@@ -3,6 +3,8 @@ package mysql
 import (
 	"fmt"
 	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
 )
 
 const (
@@ -15,6 +17,8 @@ const (
 	defaultConnMaxIdleTime                   = 30 * time.Minute
 	defaultConnMaxLifetime                   = 30 * time.Minute
 	defaultWatchBufferLength                 = 128
+	defaultWatchPollInterval                 = 100 * time.Millisecond
+	defaultWatchPollBackoffAfter             = 10
 	defaultUsersetBatchSize                  = 1024
 	defaultQuantization                      = 5 * time.Second
 	defaultMaxRevisionStalenessPercent       = 0.1
@@ -29,6 +33,10 @@ type mysqlOptions struct {
 	gcMaxOperationTime          time.Duration
 	maxRevisionStalenessPercent float64
 	watchBufferLength           uint16
+	watchPollInterval           time.Duration
+	watchPollMaxInterval        time.Duration
+	watchPollBackoffAfter       uint
+	watchPollJitterFactor       float64
 	tablePrefix                 string
 	enablePrometheusStats       bool
 	maxOpenConns                int
@@ -38,6 +46,7 @@ type mysqlOptions struct {
 	analyzeBeforeStats          bool
 	maxRetries                  uint8
 	lockWaitTimeoutSeconds      *uint8
+	nullSubjectRelation         common.NullSubjectRelation
 }
 
 // Option provides the facility to configure how clients within the
@@ -50,6 +59,8 @@ func generateConfig(options []Option) (mysqlOptions, error) {
 		gcInterval:                  defaultGarbageCollectionInterval,
 		gcMaxOperationTime:          defaultGarbageCollectionMaxOperationTime,
 		watchBufferLength:           defaultWatchBufferLength,
+		watchPollInterval:           defaultWatchPollInterval,
+		watchPollBackoffAfter:       defaultWatchPollBackoffAfter,
 		maxOpenConns:                defaultMaxOpenConns,
 		connMaxIdleTime:             defaultConnMaxIdleTime,
 		connMaxLifetime:             defaultConnMaxLifetime,
@@ -86,6 +97,47 @@ func WatchBufferLength(watchBufferLength uint16) Option {
 	}
 }
 
+// WatchPollInterval is the time Watch waits before polling again for new
+// transactions after a poll that found none.
+//
+// This value defaults to 100 milliseconds.
+func WatchPollInterval(interval time.Duration) Option {
+	return func(mo *mysqlOptions) {
+		mo.watchPollInterval = interval
+	}
+}
+
+// WatchPollMaxInterval is the maximum to which the watch poll interval doubles
+// after WatchPollBackoffAfter consecutive polls found no new transactions. The
+// interval is reset as soon as a poll finds new transactions.
+//
+// This value defaults to the poll interval, which disables backoff.
+func WatchPollMaxInterval(interval time.Duration) Option {
+	return func(mo *mysqlOptions) {
+		mo.watchPollMaxInterval = interval
+	}
+}
+
+// WatchPollBackoffAfter is the number of consecutive polls without new
+// transactions after which the watch poll interval starts to grow.
+//
+// This value defaults to 10.
+func WatchPollBackoffAfter(polls uint) Option {
+	return func(mo *mysqlOptions) {
+		mo.watchPollBackoffAfter = polls
+	}
+}
+
+// WatchPollJitterFactor is the fraction of the watch poll interval by which
+// each wait is randomly adjusted.
+//
+// This value defaults to zero.
+func WatchPollJitterFactor(factor float64) Option {
+	return func(mo *mysqlOptions) {
+		mo.watchPollJitterFactor = factor
+	}
+}
+
 // RevisionQuantization is the time bucket size to which advertised
 // revisions will be rounded.
 //
@@ -207,3 +259,13 @@ func OverrideLockWaitTimeout(seconds uint8) Option {
 		po.lockWaitTimeoutSeconds = &seconds
 	}
 }
+
+// NullSubjectRelation determines how relationships whose subject relation is NULL, which SpiceDB
+// never writes but a migration or another writer of the table may leave behind, are read.
+//
+// NULL subject relations are read as the ellipsis by default.
+func NullSubjectRelation(handling common.NullSubjectRelation) Option {
+	return func(mo *mysqlOptions) {
+		mo.nullSubjectRelation = handling
+	}
+}
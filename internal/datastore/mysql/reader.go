@@ -55,8 +55,13 @@ func (mr *mysqlReader) QueryRelationships(
 	opts ...options.QueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
 	// TODO (@vroldanbet) dupe from postgres datastore - need to refactor
+	queryOpts := options.NewQueryOptionsWithOptions(opts...)
 	qBuilder := common.NewSchemaQueryFilterer(schema, mr.filterer(mr.QueryTuplesQuery)).
-		FilterToResourceType(filter.ResourceType)
+		FilterToResourceTypes(append([]string{filter.ResourceType}, queryOpts.AdditionalResourceTypes...)...)
+
+	if queryOpts.IgnoreObjectIDCase {
+		qBuilder = qBuilder.IgnoringObjectIDCase()
+	}
 
 	if filter.OptionalResourceId != "" {
 		qBuilder = qBuilder.FilterToResourceID(filter.OptionalResourceId)
@@ -79,17 +84,20 @@ func (mr *mysqlReader) ReverseQueryRelationships(
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
 	// TODO (@vroldanbet) dupe from postgres datastore - need to refactor
-	qBuilder := common.NewSchemaQueryFilterer(schema, mr.filterer(mr.QueryTuplesQuery)).
-		FilterToSubjectFilter(subjectFilter)
-
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
 
+	qBuilder := common.NewSchemaQueryFilterer(schema, mr.filterer(mr.QueryTuplesQuery)).
+		FilterToReverseQuerySubject(subjectFilter, queryOpts)
+
 	if queryOpts.ResRelation != nil {
-		qBuilder = qBuilder.
-			FilterToResourceType(queryOpts.ResRelation.Namespace).
-			FilterToRelation(queryOpts.ResRelation.Relation)
+		qBuilder = qBuilder.FilterToResourceRelation(
+			queryOpts.ResRelation.Namespace,
+			queryOpts.ResRelation.Relation,
+		)
 	}
 
+	qBuilder = qBuilder.FilterToExcludedSubjects(queryOpts.ExcludedSubjects)
+
 	return mr.querySplitter.SplitAndExecuteQuery(
 		ctx,
 		qBuilder,
@@ -97,6 +105,15 @@ func (mr *mysqlReader) ReverseQueryRelationships(
 	)
 }
 
+func (mr *mysqlReader) CountRelationships(ctx context.Context, filter *v1.RelationshipFilter) (uint64, error) {
+	return common.CountRelationships(ctx, mr, filter)
+}
+
+func (mr *mysqlReader) CheckRelationshipsExist(ctx context.Context, tuples []*core.RelationTuple) (map[string]bool, error) {
+	qBuilder := common.NewSchemaQueryFilterer(schema, mr.filterer(mr.QueryTuplesQuery))
+	return mr.querySplitter.SplitAndCheckTuplesExist(ctx, qBuilder, tuples)
+}
+
 func (mr *mysqlReader) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
 	// TODO (@vroldanbet) dupe from postgres datastore - need to refactor
 	ctx, span := tracer.Start(ctx, "ReadNamespace", trace.WithAttributes(
@@ -9,6 +9,7 @@ import (
 	sq "github.com/Masterminds/squirrel"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/jzelinskie/stringz"
+	"github.com/scylladb/go-set/strset"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
@@ -17,6 +18,7 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/mysql/migrations"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 const (
@@ -32,6 +34,14 @@ type mysqlReadWriteTXN struct {
 	ctx      context.Context
 	tx       *sql.Tx
 	newTxnID uint64
+
+	effectiveTouches uint64
+}
+
+// EffectiveTouches returns the number of touches written by the transaction which created a
+// relationship.
+func (rwt *mysqlReadWriteTXN) EffectiveTouches() uint64 {
+	return rwt.effectiveTouches
 }
 
 // WriteRelationships takes a list of existing relationships that must exist, and a list of
@@ -42,37 +52,29 @@ func (rwt *mysqlReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpd
 	ctx, span := tracer.Start(datastore.SeparateContextWithTracing(rwt.ctx), "WriteTuples")
 	defer span.End()
 
-	bulkWrite := rwt.WriteTupleQuery
-	bulkWriteHasValues := false
-
-	selectForUpdateQuery := rwt.QueryTupleIdsQuery
-
+	// Touches of relationships which are already living are skipped, so that they neither
+	// create a new row nor show up as changes in watch, unless the same batch deletes them.
+	touched := strset.New()
+	deleted := strset.New()
 	clauses := sq.Or{}
-
-	// Process the actual updates
 	for _, mut := range mutations {
-		rel := mut.Relationship
-
 		// Implementation for TOUCH deviates from PostgreSQL datastore to prevent a deadlock in MySQL
-		if mut.Operation == v1.RelationshipUpdate_OPERATION_TOUCH || mut.Operation == v1.RelationshipUpdate_OPERATION_DELETE {
-			clauses = append(clauses, exactRelationshipClause(rel))
-		}
-
-		if mut.Operation == v1.RelationshipUpdate_OPERATION_TOUCH || mut.Operation == v1.RelationshipUpdate_OPERATION_CREATE {
-			bulkWrite = bulkWrite.Values(
-				rel.Resource.ObjectType,
-				rel.Resource.ObjectId,
-				rel.Relation,
-				rel.Subject.Object.ObjectType,
-				rel.Subject.Object.ObjectId,
-				stringz.DefaultEmpty(rel.Subject.OptionalRelation, datastore.Ellipsis),
-				rwt.newTxnID,
-			)
-			bulkWriteHasValues = true
+		switch mut.Operation {
+		case v1.RelationshipUpdate_OPERATION_TOUCH:
+			touched.Add(tuple.String(tuple.FromRelationship(mut.Relationship)))
+			clauses = append(clauses, exactRelationshipClause(mut.Relationship))
+		case v1.RelationshipUpdate_OPERATION_DELETE:
+			deleted.Add(tuple.String(tuple.FromRelationship(mut.Relationship)))
+			clauses = append(clauses, exactRelationshipClause(mut.Relationship))
 		}
 	}
 
+	living := strset.New()
 	if len(clauses) > 0 {
+		selectForUpdateQuery := rwt.QueryTupleIdsQuery.
+			Columns(colNamespace, colObjectID, colRelation, colUsersetNamespace, colUsersetObjectID, colUsersetRelation).
+			Where(sq.Eq{colDeletedTxn: liveDeletedTxnID})
+
 		query, args, err := selectForUpdateQuery.Where(clauses).ToSql()
 		if err != nil {
 			return fmt.Errorf(errUnableToWriteRelationships, err)
@@ -87,10 +89,27 @@ func (rwt *mysqlReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpd
 		tupleIds := make([]int64, 0, len(clauses))
 		for rows.Next() {
 			var tupleID int64
-			if err := rows.Scan(&tupleID); err != nil {
+			tpl := &core.RelationTuple{
+				ResourceAndRelation: &core.ObjectAndRelation{},
+				Subject:             &core.ObjectAndRelation{},
+			}
+			if err := rows.Scan(
+				&tupleID,
+				&tpl.ResourceAndRelation.Namespace,
+				&tpl.ResourceAndRelation.ObjectId,
+				&tpl.ResourceAndRelation.Relation,
+				&tpl.Subject.Namespace,
+				&tpl.Subject.ObjectId,
+				&tpl.Subject.Relation,
+			); err != nil {
 				return fmt.Errorf(errUnableToWriteRelationships, err)
 			}
 
+			if key := tuple.String(tpl); touched.Has(key) && !deleted.Has(key) {
+				living.Add(key)
+				continue
+			}
+
 			tupleIds = append(tupleIds, tupleID)
 		}
 
@@ -113,6 +132,34 @@ func (rwt *mysqlReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpd
 		}
 	}
 
+	bulkWrite := rwt.WriteTupleQuery
+	bulkWriteHasValues := false
+
+	// Process the actual updates
+	for _, mut := range mutations {
+		rel := mut.Relationship
+
+		if mut.Operation == v1.RelationshipUpdate_OPERATION_TOUCH {
+			if living.Has(tuple.String(tuple.FromRelationship(rel))) {
+				continue
+			}
+			rwt.effectiveTouches++
+		}
+
+		if mut.Operation == v1.RelationshipUpdate_OPERATION_TOUCH || mut.Operation == v1.RelationshipUpdate_OPERATION_CREATE {
+			bulkWrite = bulkWrite.Values(
+				rel.Resource.ObjectType,
+				rel.Resource.ObjectId,
+				rel.Relation,
+				rel.Subject.Object.ObjectType,
+				rel.Subject.Object.ObjectId,
+				stringz.DefaultEmpty(rel.Subject.OptionalRelation, datastore.Ellipsis),
+				rwt.newTxnID,
+			)
+			bulkWriteHasValues = true
+		}
+	}
+
 	if bulkWriteHasValues {
 		query, args, err := bulkWrite.ToSql()
 		if err != nil {
@@ -140,7 +187,7 @@ func exactRelationshipClause(r *v1.Relationship) sq.Eq {
 	}
 }
 
-func (rwt *mysqlReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter) error {
+func (rwt *mysqlReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter) (uint64, error) {
 	// TODO (@vroldanbet) dupe from postgres datastore - need to refactor
 	ctx, span := tracer.Start(datastore.SeparateContextWithTracing(rwt.ctx), "DeleteRelationships")
 	defer span.End()
@@ -177,14 +224,20 @@ func (rwt *mysqlReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter)
 
 	querySQL, args, err := query.ToSql()
 	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+		return 0, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
 
-	if _, err := rwt.tx.ExecContext(ctx, querySQL, args...); err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+	result, err := rwt.tx.ExecContext(ctx, querySQL, args...)
+	if err != nil {
+		return 0, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
 
-	return nil
+	deleted, err := result.RowsAffected()
+	if err != nil {
+		return 0, fmt.Errorf(errUnableToDeleteRelationships, err)
+	}
+
+	return uint64(deleted), nil
 }
 
 func (rwt *mysqlReadWriteTXN) WriteNamespaces(newNamespaces ...*core.NamespaceDefinition) error {
@@ -287,4 +340,25 @@ func (rwt *mysqlReadWriteTXN) DeleteNamespace(nsName string) error {
 	return nil
 }
 
+func (rwt *mysqlReadWriteTXN) RenameNamespace(oldName, newName string) error {
+	ctx, span := tracer.Start(rwt.ctx, "RenameNamespace", trace.WithAttributes(
+		attribute.String("oldName", oldName),
+		attribute.String("newName", newName),
+	))
+	defer span.End()
+
+	return common.RenameNamespace(datastore.SeparateContextWithTracing(ctx), rwt, oldName, newName)
+}
+
+func (rwt *mysqlReadWriteTXN) CopyNamespace(sourceName, destName string, copyTuples bool) (uint64, error) {
+	ctx, span := tracer.Start(rwt.ctx, "CopyNamespace", trace.WithAttributes(
+		attribute.String("sourceName", sourceName),
+		attribute.String("destName", destName),
+		attribute.Bool("copyTuples", copyTuples),
+	))
+	defer span.End()
+
+	return common.CopyNamespace(datastore.SeparateContextWithTracing(ctx), rwt, sourceName, destName, copyTuples)
+}
+
 var _ datastore.ReadWriteTransaction = &mysqlReadWriteTXN{}
@@ -3,18 +3,15 @@ package mysql
 import (
 	"context"
 	"errors"
-	"time"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/internal/datastore/mysql/migrations"
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
 	sq "github.com/Masterminds/squirrel"
-)
-
-const (
-	watchSleep = 100 * time.Millisecond
+	"github.com/benbjohnson/clock"
 )
 
 // Watch notifies the caller about all changes to tuples.
@@ -22,7 +19,8 @@ const (
 // All events following afterRevision will be sent to the caller.
 //
 // TODO (@vroldanbet) dupe from postgres datastore - need to refactor
-func (mds *Datastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (mds *Datastore) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	watchOpts := options.NewWatchOptionsWithOptions(opts...)
 	updates := make(chan *datastore.RevisionChanges, mds.watchBufferLength)
 	errs := make(chan error, 1)
 
@@ -30,12 +28,27 @@ func (mds *Datastore) Watch(ctx context.Context, afterRevision datastore.Revisio
 		defer close(updates)
 		defer close(errs)
 
+		if watchOpts.EmitCheckpoint {
+			head, err := mds.HeadRevision(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			checkpoint := &datastore.RevisionChanges{Revision: head, IsCheckpoint: true}
+			if err := common.SendRevisionChanges(ctx, updates, checkpoint, watchOpts); err != nil {
+				errs <- err
+				return
+			}
+		}
+
+		poller := common.NewWatchPoller(mds.watchPolling, clock.New())
 		currentTxn := transactionFromRevision(afterRevision)
 
 		for {
 			var stagedUpdates []*datastore.RevisionChanges
 			var err error
-			stagedUpdates, currentTxn, err = mds.loadChanges(ctx, currentTxn)
+			stagedUpdates, currentTxn, err = mds.loadChanges(ctx, currentTxn, watchOpts)
 			if err != nil {
 				if errors.Is(ctx.Err(), context.Canceled) {
 					errs <- datastore.NewWatchCanceledErr()
@@ -47,25 +60,16 @@ func (mds *Datastore) Watch(ctx context.Context, afterRevision datastore.Revisio
 
 			// Write the staged updates to the channel
 			for _, changeToWrite := range stagedUpdates {
-				select {
-				case updates <- changeToWrite:
-				default:
-					errs <- datastore.NewWatchDisconnectedErr()
+				if err := common.SendRevisionChanges(ctx, updates, changeToWrite, watchOpts); err != nil {
+					errs <- err
 					return
 				}
 			}
 
-			// If there were no changes, sleep a bit
-			if len(stagedUpdates) == 0 {
-				sleep := time.NewTimer(watchSleep)
-
-				select {
-				case <-sleep.C:
-					break
-				case <-ctx.Done():
-					errs <- datastore.NewWatchCanceledErr()
-					return
-				}
+			// If there were no changes, wait before polling again
+			if err := poller.Wait(ctx, len(stagedUpdates) > 0); err != nil {
+				errs <- datastore.NewWatchCanceledErr()
+				return
 			}
 		}
 	}()
@@ -77,6 +81,7 @@ func (mds *Datastore) Watch(ctx context.Context, afterRevision datastore.Revisio
 func (mds *Datastore) loadChanges(
 	ctx context.Context,
 	afterRevision uint64,
+	watchOpts *options.WatchOptions,
 ) (changes []*datastore.RevisionChanges, newRevision uint64, err error) {
 	newRevision, err = mds.loadRevision(ctx)
 	if err != nil {
@@ -87,7 +92,7 @@ func (mds *Datastore) loadChanges(
 		return
 	}
 
-	sql, args, err := mds.QueryChangedQuery.Where(sq.Or{
+	query := mds.QueryChangedQuery.Where(sq.Or{
 		sq.And{
 			sq.Gt{colCreatedTxn: afterRevision},
 			sq.LtOrEq{colCreatedTxn: newRevision},
@@ -96,7 +101,12 @@ func (mds *Datastore) loadChanges(
 			sq.Gt{colDeletedTxn: afterRevision},
 			sq.LtOrEq{colDeletedTxn: newRevision},
 		},
-	}).ToSql()
+	})
+	if len(watchOpts.Namespaces) > 0 {
+		query = query.Where(sq.Eq{colNamespace: watchOpts.Namespaces})
+	}
+
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return
 	}
@@ -118,6 +128,7 @@ func (mds *Datastore) loadChanges(
 			Subject:             &core.ObjectAndRelation{},
 		}
 
+		var subjectRelation *string
 		var createdTxn uint64
 		var deletedTxn uint64
 		err = rows.Scan(
@@ -126,7 +137,7 @@ func (mds *Datastore) loadChanges(
 			&nextTuple.ResourceAndRelation.Relation,
 			&nextTuple.Subject.Namespace,
 			&nextTuple.Subject.ObjectId,
-			&nextTuple.Subject.Relation,
+			&subjectRelation,
 			&createdTxn,
 			&deletedTxn,
 		)
@@ -134,6 +145,17 @@ func (mds *Datastore) loadChanges(
 			return
 		}
 
+		nextTuple.Subject.Relation, err = mds.nullSubjectRelation.SubjectRelationFromColumn(ctx, nextTuple, subjectRelation)
+		if err != nil {
+			return
+		}
+
+		// A tuple that was created and deleted within the same transaction never
+		// existed outside of it, so it does not contribute a change.
+		if createdTxn == deletedTxn {
+			continue
+		}
+
 		if createdTxn > afterRevision && createdTxn <= newRevision {
 			stagedChanges.AddChange(ctx, revisionFromTransaction(createdTxn), nextTuple, core.RelationTupleUpdate_TOUCH)
 		}
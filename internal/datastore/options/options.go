@@ -1,21 +1,144 @@
 package options
 
 import (
+	"time"
+
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
-//go:generate go run github.com/ecordell/optgen -output zz_generated.query_options.go . QueryOptions ReverseQueryOptions
+//go:generate go run github.com/ecordell/optgen -output zz_generated.query_options.go . QueryOptions ReverseQueryOptions WatchOptions
 
 // QueryOptions are the options that can affect the results of a normal forward query.
 type QueryOptions struct {
 	Limit    *uint64
 	Usersets []*core.ObjectAndRelation
+
+	// Sorted, if set, returns tuples in a stable order, sorted by resource and then subject,
+	// which allows paging through them.
+	Sorted bool
+
+	// After, if set on a sorted query, returns only the tuples sorting after it.
+	After *core.RelationTuple
+
+	// AdditionalResourceTypes, if set, broadens the query to also return the tuples with
+	// resources of any of the given types, in addition to those of the filter's resource type.
+	AdditionalResourceTypes []string
+
+	// IncludeRevisions, if set, makes the iterators of datastores which record the revisions at
+	// which tuples are created and deleted report them, as a datastore.RevisionMetadataReporter.
+	// Other datastores ignore it.
+	IncludeRevisions bool
+
+	// MaxResults, if non-zero, makes the iterator fail with datastore.ErrResultSetTooLarge once
+	// the query matches more than this many tuples, rather than returning them all or silently
+	// truncating them. Unlike Limit, it guards against unexpectedly large results.
+	MaxResults uint64
+
+	// IgnoreObjectIDCase, if set, matches the resource ID and subject ID of the filter against
+	// those of the tuples regardless of case. The SQL datastores then compare the lowercased
+	// columns, which cannot use the indexes on the columns themselves: postgres has functional
+	// indexes for them, but the other SQL datastores scan every tuple of the resource type. Note
+	// that MySQL's default collation already compares IDs regardless of case.
+	IgnoreObjectIDCase bool
+
+	// StreamResults, if set, makes datastores which support it read the tuples lazily, as the
+	// iterator is advanced, instead of loading all of them before returning the iterator. The
+	// iterator then holds a database connection until it is closed, so it must not be held open
+	// while making other queries which could wait for a connection from the same pool. Canceling
+	// the context of the query aborts it, while closing the iterator early reads the remaining
+	// rows. Queries which are split into several, or which include revisions, are always loaded.
+	StreamResults bool
 }
 
 // ReverseQueryOptions are the options that can affect the results of a reverse query.
 type ReverseQueryOptions struct {
 	ReverseLimit *uint64
 	ResRelation  *ResourceRelation
+
+	// ExcludedSubjects, if set, excludes the relationships with any of the given usersets as
+	// their subject from the results.
+	ExcludedSubjects []*core.ObjectAndRelation
+
+	// IncludeEllipsisRelation, if set, broadens a subject filter with a relation to also match
+	// subjects with the ellipsis relation, which refer to the subject object itself.
+	IncludeEllipsisRelation bool
+
+	// IncludeWildcardSubject, if set, broadens a subject filter with an object ID to also match
+	// the wildcard subject of its type, which grants access to every object of the type.
+	IncludeWildcardSubject bool
+}
+
+// WatchOptions are the options that can affect the changes returned by a watch.
+type WatchOptions struct {
+	Namespaces []string
+
+	// EmitCheckpoint, if set, makes the watch first emit a checkpoint carrying the head
+	// revision of the datastore at the time the watch was started, before any changes.
+	EmitCheckpoint bool
+
+	// SendTimeout is how long the watch waits for the consumer to make room for more changes
+	// before disconnecting. If zero, the watch disconnects as soon as its buffer is full. If
+	// set, the changes of a revision which do not fit in the buffer are split across several
+	// RevisionChanges with the same revision.
+	SendTimeout time.Duration
+
+	// OverflowPolicy is what the watch does when the consumer does not make room for more
+	// changes in time.
+	OverflowPolicy WatchOverflowPolicy
+}
+
+// WatchOverflowPolicy is what a watch does when its consumer falls behind by more than the
+// watch buffer.
+type WatchOverflowPolicy int
+
+const (
+	// WatchOverflowDisconnect disconnects the watch with ErrWatchDisconnected.
+	WatchOverflowDisconnect WatchOverflowPolicy = iota
+
+	// WatchOverflowDropAndSignal drops the oldest changes waiting in the watch buffer, replacing
+	// them with a single gap marker carrying the range of revisions that were dropped, and keeps
+	// the watch going.
+	WatchOverflowDropAndSignal
+)
+
+// IncludesNamespace returns whether changes to tuples with resources in the given namespace
+// should be returned by a watch with these options.
+func (w *WatchOptions) IncludesNamespace(namespace string) bool {
+	if len(w.Namespaces) == 0 {
+		return true
+	}
+
+	for _, included := range w.Namespaces {
+		if included == namespace {
+			return true
+		}
+	}
+
+	return false
+}
+
+// WithCursor returns an option that pages through the results of a query: tuples are returned
+// sorted, starting after lastSeen if it is non-nil, up to limit tuples. The cursor for the next
+// page is returned by the Cursor method of the resulting iterator.
+func WithCursor(lastSeen *core.RelationTuple, limit uint64) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.Sorted = true
+		q.After = lastSeen
+		q.Limit = &limit
+	}
+}
+
+// WithRevisionMetadata returns an option that includes the revisions at which each tuple was
+// created and deleted in the results of a query, for datastores which record them.
+func WithRevisionMetadata() QueryOptionsOption {
+	return WithIncludeRevisions(true)
+}
+
+// WithCaseInsensitiveObjectID returns an option that matches the object IDs of a query's filter
+// regardless of case, such as for subject IDs which are email addresses. See
+// QueryOptions.IgnoreObjectIDCase for its effect on index usage.
+func WithCaseInsensitiveObjectID() QueryOptionsOption {
+	return WithIgnoreObjectIDCase(true)
 }
 
 // ResourceRelation combines a resource object type and relation.
@@ -24,6 +147,15 @@ type ResourceRelation struct {
 	Relation  string
 }
 
+// WithResourceRelation returns an option that limits a reverse query to resources of the given
+// type and relation. Either may be left empty to not filter on it.
+func WithResourceRelation(namespace, relation string) ReverseQueryOptionsOption {
+	return WithResRelation(&ResourceRelation{
+		Namespace: namespace,
+		Relation:  relation,
+	})
+}
+
 var (
 	one = uint64(1)
 
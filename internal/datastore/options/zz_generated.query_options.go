@@ -1,7 +1,11 @@
 // Code generated by github.com/ecordell/optgen. DO NOT EDIT.
 package options
 
-import v1 "github.com/authzed/spicedb/pkg/proto/core/v1"
+import (
+	"time"
+
+	v1 "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
 
 type QueryOptionsOption func(q *QueryOptions)
 
@@ -19,6 +23,13 @@ func (q *QueryOptions) ToOption() QueryOptionsOption {
 	return func(to *QueryOptions) {
 		to.Limit = q.Limit
 		to.Usersets = q.Usersets
+		to.Sorted = q.Sorted
+		to.After = q.After
+		to.AdditionalResourceTypes = q.AdditionalResourceTypes
+		to.IncludeRevisions = q.IncludeRevisions
+		to.MaxResults = q.MaxResults
+		to.IgnoreObjectIDCase = q.IgnoreObjectIDCase
+		to.StreamResults = q.StreamResults
 	}
 }
 
@@ -51,6 +62,62 @@ func SetUsersets(usersets []*v1.ObjectAndRelation) QueryOptionsOption {
 	}
 }
 
+// WithSorted returns an option that can set Sorted on a QueryOptions
+func WithSorted(sorted bool) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.Sorted = sorted
+	}
+}
+
+// WithAfter returns an option that can set After on a QueryOptions
+func WithAfter(after *v1.RelationTuple) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.After = after
+	}
+}
+
+// WithAdditionalResourceTypes returns an option that can append AdditionalResourceTypess to QueryOptions.AdditionalResourceTypes
+func WithAdditionalResourceTypes(additionalResourceTypes string) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.AdditionalResourceTypes = append(q.AdditionalResourceTypes, additionalResourceTypes)
+	}
+}
+
+// SetAdditionalResourceTypes returns an option that can set AdditionalResourceTypes on a QueryOptions
+func SetAdditionalResourceTypes(additionalResourceTypes []string) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.AdditionalResourceTypes = additionalResourceTypes
+	}
+}
+
+// WithIncludeRevisions returns an option that can set IncludeRevisions on a QueryOptions
+func WithIncludeRevisions(includeRevisions bool) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.IncludeRevisions = includeRevisions
+	}
+}
+
+// WithMaxResults returns an option that can set MaxResults on a QueryOptions
+func WithMaxResults(maxResults uint64) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.MaxResults = maxResults
+	}
+}
+
+// WithIgnoreObjectIDCase returns an option that can set IgnoreObjectIDCase on a QueryOptions
+func WithIgnoreObjectIDCase(ignoreObjectIDCase bool) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.IgnoreObjectIDCase = ignoreObjectIDCase
+	}
+}
+
+// WithStreamResults returns an option that can set StreamResults on a QueryOptions
+func WithStreamResults(streamResults bool) QueryOptionsOption {
+	return func(q *QueryOptions) {
+		q.StreamResults = streamResults
+	}
+}
+
 type ReverseQueryOptionsOption func(r *ReverseQueryOptions)
 
 // NewReverseQueryOptionsWithOptions creates a new ReverseQueryOptions with the passed in options set
@@ -67,6 +134,9 @@ func (r *ReverseQueryOptions) ToOption() ReverseQueryOptionsOption {
 	return func(to *ReverseQueryOptions) {
 		to.ReverseLimit = r.ReverseLimit
 		to.ResRelation = r.ResRelation
+		to.ExcludedSubjects = r.ExcludedSubjects
+		to.IncludeEllipsisRelation = r.IncludeEllipsisRelation
+		to.IncludeWildcardSubject = r.IncludeWildcardSubject
 	}
 }
 
@@ -91,3 +161,95 @@ func WithResRelation(resRelation *ResourceRelation) ReverseQueryOptionsOption {
 		r.ResRelation = resRelation
 	}
 }
+
+// WithExcludedSubjects returns an option that can append ExcludedSubjectss to ReverseQueryOptions.ExcludedSubjects
+func WithExcludedSubjects(excludedSubjects *v1.ObjectAndRelation) ReverseQueryOptionsOption {
+	return func(r *ReverseQueryOptions) {
+		r.ExcludedSubjects = append(r.ExcludedSubjects, excludedSubjects)
+	}
+}
+
+// SetExcludedSubjects returns an option that can set ExcludedSubjects on a ReverseQueryOptions
+func SetExcludedSubjects(excludedSubjects []*v1.ObjectAndRelation) ReverseQueryOptionsOption {
+	return func(r *ReverseQueryOptions) {
+		r.ExcludedSubjects = excludedSubjects
+	}
+}
+
+// WithIncludeEllipsisRelation returns an option that can set IncludeEllipsisRelation on a ReverseQueryOptions
+func WithIncludeEllipsisRelation(includeEllipsisRelation bool) ReverseQueryOptionsOption {
+	return func(r *ReverseQueryOptions) {
+		r.IncludeEllipsisRelation = includeEllipsisRelation
+	}
+}
+
+// WithIncludeWildcardSubject returns an option that can set IncludeWildcardSubject on a ReverseQueryOptions
+func WithIncludeWildcardSubject(includeWildcardSubject bool) ReverseQueryOptionsOption {
+	return func(r *ReverseQueryOptions) {
+		r.IncludeWildcardSubject = includeWildcardSubject
+	}
+}
+
+type WatchOptionsOption func(w *WatchOptions)
+
+// NewWatchOptionsWithOptions creates a new WatchOptions with the passed in options set
+func NewWatchOptionsWithOptions(opts ...WatchOptionsOption) *WatchOptions {
+	w := &WatchOptions{}
+	for _, o := range opts {
+		o(w)
+	}
+	return w
+}
+
+// ToOption returns a new WatchOptionsOption that sets the values from the passed in WatchOptions
+func (w *WatchOptions) ToOption() WatchOptionsOption {
+	return func(to *WatchOptions) {
+		to.Namespaces = w.Namespaces
+		to.EmitCheckpoint = w.EmitCheckpoint
+		to.SendTimeout = w.SendTimeout
+		to.OverflowPolicy = w.OverflowPolicy
+	}
+}
+
+// WatchOptionsWithOptions configures an existing WatchOptions with the passed in options set
+func WatchOptionsWithOptions(w *WatchOptions, opts ...WatchOptionsOption) *WatchOptions {
+	for _, o := range opts {
+		o(w)
+	}
+	return w
+}
+
+// WithNamespaces returns an option that can append Namespacess to WatchOptions.Namespaces
+func WithNamespaces(namespaces string) WatchOptionsOption {
+	return func(w *WatchOptions) {
+		w.Namespaces = append(w.Namespaces, namespaces)
+	}
+}
+
+// SetNamespaces returns an option that can set Namespaces on a WatchOptions
+func SetNamespaces(namespaces []string) WatchOptionsOption {
+	return func(w *WatchOptions) {
+		w.Namespaces = namespaces
+	}
+}
+
+// WithEmitCheckpoint returns an option that can set EmitCheckpoint on a WatchOptions
+func WithEmitCheckpoint(emitCheckpoint bool) WatchOptionsOption {
+	return func(w *WatchOptions) {
+		w.EmitCheckpoint = emitCheckpoint
+	}
+}
+
+// WithSendTimeout returns an option that can set SendTimeout on a WatchOptions
+func WithSendTimeout(sendTimeout time.Duration) WatchOptionsOption {
+	return func(w *WatchOptions) {
+		w.SendTimeout = sendTimeout
+	}
+}
+
+// WithOverflowPolicy returns an option that can set OverflowPolicy on a WatchOptions
+func WithOverflowPolicy(overflowPolicy WatchOverflowPolicy) WatchOptionsOption {
+	return func(w *WatchOptions) {
+		w.OverflowPolicy = overflowPolicy
+	}
+}
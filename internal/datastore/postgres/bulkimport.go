@@ -0,0 +1,162 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgconn"
+	"github.com/jackc/pgx/v4"
+	"go.opentelemetry.io/otel/attribute"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+const (
+	errUnableToBulkImport = "unable to bulk import relationships: %w"
+
+	// bulkImportBatchSize is the number of relationships sent to the database by each COPY.
+	bulkImportBatchSize = 10_000
+
+	tableBulkImport = "bulk_import_tuple"
+)
+
+var (
+	bulkImportColumns = []string{
+		colNamespace,
+		colObjectID,
+		colRelation,
+		colUsersetNamespace,
+		colUsersetObjectID,
+		colUsersetRelation,
+		colCaveatName,
+		colCaveatContext,
+	}
+
+	bulkImportColumnsWithTxn = append(append([]string{}, bulkImportColumns...), colCreatedTxn)
+
+	createBulkImportTable = fmt.Sprintf(
+		"CREATE TEMPORARY TABLE %s (%s VARCHAR, %s VARCHAR, %s VARCHAR, %s VARCHAR, %s VARCHAR, %s VARCHAR, %s VARCHAR, %s JSONB) ON COMMIT DROP",
+		tableBulkImport,
+		colNamespace,
+		colObjectID,
+		colRelation,
+		colUsersetNamespace,
+		colUsersetObjectID,
+		colUsersetRelation,
+		colCaveatName,
+		colCaveatContext,
+	)
+
+	// insertBulkImported moves the relationships copied into the temporary table into the tuple
+	// table, skipping those which are duplicated or already living.
+	insertBulkImported = fmt.Sprintf(
+		"INSERT INTO %[1]s (%[3]s, %[4]s, %[5]s, %[6]s, %[7]s, %[8]s, %[10]s, %[11]s, %[9]s) SELECT DISTINCT %[3]s, %[4]s, %[5]s, %[6]s, %[7]s, %[8]s, %[10]s, %[11]s, $1::bigint FROM %[2]s ON CONFLICT DO NOTHING",
+		tableTuple,
+		tableBulkImport,
+		colNamespace,
+		colObjectID,
+		colRelation,
+		colUsersetNamespace,
+		colUsersetObjectID,
+		colUsersetRelation,
+		colCreatedTxn,
+		colCaveatName,
+		colCaveatContext,
+	)
+)
+
+// BulkImportRelationships writes the relationships by copying them into the database in batches,
+// all within a single transaction.
+func (pgd *pgDatastore) BulkImportRelationships(
+	ctx context.Context,
+	tuples []*core.RelationTuple,
+	onConflict datastore.ConflictMode,
+) (uint64, datastore.Revision, error) {
+	ctx, span := tracer.Start(ctx, "BulkImportRelationships")
+	defer span.End()
+
+	var imported uint64
+	revision, err := pgd.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		pgrwt := rwt.(*pgReadWriteTXN)
+		tx, newTxnID := pgrwt.tx, pgrwt.newTxnID
+
+		var err error
+		switch onConflict {
+		case datastore.ConflictError:
+			imported, err = copyTuples(ctx, tx, tableTuple, bulkImportColumnsWithTxn, tuples, newTxnID)
+			var pgerr *pgconn.PgError
+			if errors.As(err, &pgerr) && pgerr.SQLState() == pgUniqueConstraintViolation {
+				return datastore.NewRelationshipsExistErr()
+			}
+			return err
+
+		case datastore.ConflictSkip:
+			if _, err := tx.Exec(ctx, createBulkImportTable); err != nil {
+				return fmt.Errorf(errUnableToBulkImport, err)
+			}
+
+			if _, err := copyTuples(ctx, tx, tableBulkImport, bulkImportColumns, tuples); err != nil {
+				return err
+			}
+
+			result, err := tx.Exec(ctx, insertBulkImported, newTxnID)
+			if err != nil {
+				return fmt.Errorf(errUnableToBulkImport, err)
+			}
+			imported = uint64(result.RowsAffected())
+			return nil
+
+		default:
+			return fmt.Errorf(errUnableToBulkImport, fmt.Errorf("unknown conflict mode: %d", onConflict))
+		}
+	})
+	if err != nil {
+		return 0, datastore.NoRevision, err
+	}
+
+	span.SetAttributes(attribute.Int64("imported", int64(imported)))
+	return imported, revision, nil
+}
+
+// copyTuples copies the tuples into the columns of the table in batches, appending the extra
+// values to each row, and returns the number of rows copied.
+func copyTuples(ctx context.Context, tx pgx.Tx, table string, columns []string, tuples []*core.RelationTuple, extra ...interface{}) (uint64, error) {
+	var copied uint64
+	for start := 0; start < len(tuples); start += bulkImportBatchSize {
+		end := start + bulkImportBatchSize
+		if end > len(tuples) {
+			end = len(tuples)
+		}
+
+		batch := tuples[start:end]
+		count, err := tx.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromSlice(len(batch), func(i int) ([]interface{}, error) {
+			tpl := batch[i]
+			caveatName, caveatContext, err := common.CaveatColumnValues(tpl.Caveat)
+			if err != nil {
+				return nil, err
+			}
+
+			return append([]interface{}{
+				tpl.ResourceAndRelation.Namespace,
+				tpl.ResourceAndRelation.ObjectId,
+				tpl.ResourceAndRelation.Relation,
+				tpl.Subject.Namespace,
+				tpl.Subject.ObjectId,
+				tpl.Subject.Relation,
+				caveatName,
+				caveatContext,
+			}, extra...), nil
+		}))
+		if err != nil {
+			return copied, fmt.Errorf(errUnableToBulkImport, err)
+		}
+		copied += uint64(count)
+	}
+
+	return copied, nil
+}
+
+var _ datastore.BulkImporter = &pgDatastore{}
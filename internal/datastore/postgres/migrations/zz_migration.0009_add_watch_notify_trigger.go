@@ -0,0 +1,40 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	createNotifyTransactionFunction = `CREATE OR REPLACE FUNCTION notify_relation_tuple_transaction() RETURNS trigger AS $$
+BEGIN
+	PERFORM pg_notify('relation_tuple_transaction', NEW.id::text);
+	RETURN NEW;
+END;
+$$ LANGUAGE plpgsql`
+
+	createNotifyTransactionTrigger = `CREATE TRIGGER relation_tuple_transaction_notify
+	AFTER INSERT ON relation_tuple_transaction
+	FOR EACH ROW EXECUTE PROCEDURE notify_relation_tuple_transaction()`
+)
+
+func init() {
+	if err := DatabaseMigrations.Register(
+		"add-watch-notify-trigger",
+		"add-ns-config-id",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, createNotifyTransactionFunction); err != nil {
+				return err
+			}
+
+			if _, err := tx.Exec(ctx, createNotifyTransactionTrigger); err != nil {
+				return err
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
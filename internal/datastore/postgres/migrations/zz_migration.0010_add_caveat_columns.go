@@ -0,0 +1,27 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const addCaveatColumns = `ALTER TABLE relation_tuple
+	ADD COLUMN caveat_name VARCHAR,
+	ADD COLUMN caveat_context JSONB`
+
+func init() {
+	if err := DatabaseMigrations.Register(
+		"add-caveat-columns",
+		"add-watch-notify-trigger",
+		noNonatomicMigration,
+		func(ctx context.Context, tx pgx.Tx) error {
+			if _, err := tx.Exec(ctx, addCaveatColumns); err != nil {
+				return err
+			}
+
+			return nil
+		}); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// createReverseLivingIndex matches the predicates of reverse queries, in order: the subject
+	// columns, the resource type and relation, and then the window in which the tuple is living.
+	createReverseLivingIndex = `CREATE INDEX CONCURRENTLY ix_relation_tuple_by_subject_living ON relation_tuple (userset_namespace, userset_object_id, userset_relation, namespace, relation, created_transaction, deleted_transaction)`
+
+	// dropReverseQueryIndex drops the index superseded by ix_relation_tuple_by_subject_living,
+	// which serves every query it did.
+	dropReverseQueryIndex = `DROP INDEX CONCURRENTLY IF EXISTS ix_relation_tuple_by_subject`
+)
+
+func init() {
+	if err := DatabaseMigrations.Register("add-reverse-living-index", "add-caveat-columns",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			// CREATE INDEX CONCURRENTLY cannot run inside a transaction block (SQLSTATE 25001)
+			for _, stmt := range []string{
+				createReverseLivingIndex,
+				dropReverseQueryIndex,
+			} {
+				if _, err := conn.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, noTxMigration,
+	); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
@@ -0,0 +1,36 @@
+package migrations
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v4"
+)
+
+const (
+	// createLowerObjectIDIndex serves forward queries made with options.WithCaseInsensitiveObjectID,
+	// which match the lowercased resource ID within a resource type.
+	createLowerObjectIDIndex = `CREATE INDEX CONCURRENTLY ix_relation_tuple_by_lower_object_id ON relation_tuple (namespace, LOWER(object_id), relation, created_transaction, deleted_transaction)`
+
+	// createLowerUsersetObjectIDIndex serves the same queries when they match the lowercased
+	// subject ID within a subject type.
+	createLowerUsersetObjectIDIndex = `CREATE INDEX CONCURRENTLY ix_relation_tuple_by_lower_userset_object_id ON relation_tuple (userset_namespace, LOWER(userset_object_id), userset_relation, created_transaction, deleted_transaction)`
+)
+
+func init() {
+	if err := DatabaseMigrations.Register("add-lower-object-id-indexes", "add-reverse-living-index",
+		func(ctx context.Context, conn *pgx.Conn) error {
+			// CREATE INDEX CONCURRENTLY cannot run inside a transaction block (SQLSTATE 25001)
+			for _, stmt := range []string{
+				createLowerObjectIDIndex,
+				createLowerUsersetObjectIDIndex,
+			} {
+				if _, err := conn.Exec(ctx, stmt); err != nil {
+					return err
+				}
+			}
+			return nil
+		}, noTxMigration,
+	); err != nil {
+		panic("failed to register migration: " + err.Error())
+	}
+}
@@ -3,6 +3,8 @@ package postgres
 import (
 	"fmt"
 	"time"
+
+	"github.com/authzed/spicedb/internal/datastore/common"
 )
 
 type postgresOptions struct {
@@ -11,18 +13,33 @@ type postgresOptions struct {
 	healthCheckPeriod           *time.Duration
 	maxOpenConns                *int
 	minOpenConns                *int
+	statementCacheCapacity      *int
 	maxRevisionStalenessPercent float64
 
-	watchBufferLength    uint16
-	revisionQuantization time.Duration
-	gcWindow             time.Duration
-	gcInterval           time.Duration
-	gcMaxOperationTime   time.Duration
-	splitAtUsersetCount  uint16
-	maxRetries           uint8
+	watchBufferLength     uint16
+	watchPollInterval     time.Duration
+	watchPollMaxInterval  time.Duration
+	watchPollBackoffAfter uint
+	watchPollJitterFactor float64
+	watchQueryTimeout     time.Duration
+	watchChunkSize        uint64
+	watchChunkConcurrency uint16
+	queryStatementTimeout time.Duration
+	revisionQuantization  time.Duration
+	gcWindow              time.Duration
+	gcInterval            time.Duration
+	gcMaxOperationTime    time.Duration
+	splitAtUsersetCount   uint16
+	maxRetries            uint8
+
+	maxQueryRetries          uint8
+	queryRetryInitialBackoff time.Duration
+	queryRetryMaxBackoff     time.Duration
 
 	enablePrometheusStats   bool
 	analyzeBeforeStatistics bool
+	watchNotifications      bool
+	nullSubjectRelation     common.NullSubjectRelation
 
 	logger *tracingLogger
 }
@@ -31,6 +48,10 @@ const (
 	errQuantizationTooLarge = "revision quantization interval (%s) must be less than GC window (%s)"
 
 	defaultWatchBufferLength                 = 128
+	defaultWatchPollInterval                 = 100 * time.Millisecond
+	defaultWatchPollBackoffAfter             = 10
+	defaultWatchChunkSize                    = 10_000
+	defaultWatchChunkConcurrency             = 4
 	defaultGarbageCollectionWindow           = 24 * time.Hour
 	defaultGarbageCollectionInterval         = time.Minute * 3
 	defaultGarbageCollectionMaxOperationTime = time.Minute
@@ -39,6 +60,9 @@ const (
 	defaultMaxRevisionStalenessPercent       = 0.1
 	defaultEnablePrometheusStats             = false
 	defaultMaxRetries                        = 10
+	defaultMaxQueryRetries                   = 3
+	defaultQueryRetryInitialBackoff          = 20 * time.Millisecond
+	defaultQueryRetryMaxBackoff              = time.Second
 )
 
 // Option provides the facility to configure how clients within the
@@ -51,11 +75,18 @@ func generateConfig(options []Option) (postgresOptions, error) {
 		gcInterval:                  defaultGarbageCollectionInterval,
 		gcMaxOperationTime:          defaultGarbageCollectionMaxOperationTime,
 		watchBufferLength:           defaultWatchBufferLength,
+		watchPollInterval:           defaultWatchPollInterval,
+		watchPollBackoffAfter:       defaultWatchPollBackoffAfter,
+		watchChunkSize:              defaultWatchChunkSize,
+		watchChunkConcurrency:       defaultWatchChunkConcurrency,
 		splitAtUsersetCount:         defaultUsersetBatchSize,
 		revisionQuantization:        defaultQuantization,
 		maxRevisionStalenessPercent: defaultMaxRevisionStalenessPercent,
 		enablePrometheusStats:       defaultEnablePrometheusStats,
 		maxRetries:                  defaultMaxRetries,
+		maxQueryRetries:             defaultMaxQueryRetries,
+		queryRetryInitialBackoff:    defaultQueryRetryInitialBackoff,
+		queryRetryMaxBackoff:        defaultQueryRetryMaxBackoff,
 	}
 
 	for _, option := range options {
@@ -121,6 +152,18 @@ func MaxOpenConns(conns int) Option {
 	}
 }
 
+// StatementCacheCapacity is the number of prepared statements cached by each connection in the
+// pool. Queries are prepared and cached by their SQL, which for relationship queries depends only
+// on which filter fields are set, so repeated queries of the same shape are not parsed again by
+// the server. A capacity of zero disables the cache, and every query is parsed.
+//
+// This value defaults to the capacity set in the connection URL, or to 512.
+func StatementCacheCapacity(capacity int) Option {
+	return func(po *postgresOptions) {
+		po.statementCacheCapacity = &capacity
+	}
+}
+
 // MinOpenConns is the minimum size of the connection pool.
 // The health check will increase the number of connections to this amount if
 // it had dropped below.
@@ -142,6 +185,114 @@ func WatchBufferLength(watchBufferLength uint16) Option {
 	}
 }
 
+// WatchPollInterval is the time Watch waits before polling again for new
+// transactions after a poll that found none.
+//
+// This value defaults to 100 milliseconds.
+func WatchPollInterval(interval time.Duration) Option {
+	return func(po *postgresOptions) {
+		po.watchPollInterval = interval
+	}
+}
+
+// WatchPollMaxInterval is the maximum to which the watch poll interval doubles
+// after WatchPollBackoffAfter consecutive polls found no new transactions. The
+// interval is reset as soon as a poll finds new transactions.
+//
+// This value defaults to the poll interval, which disables backoff.
+func WatchPollMaxInterval(interval time.Duration) Option {
+	return func(po *postgresOptions) {
+		po.watchPollMaxInterval = interval
+	}
+}
+
+// WatchPollBackoffAfter is the number of consecutive polls without new
+// transactions after which the watch poll interval starts to grow.
+//
+// This value defaults to 10.
+func WatchPollBackoffAfter(polls uint) Option {
+	return func(po *postgresOptions) {
+		po.watchPollBackoffAfter = polls
+	}
+}
+
+// WatchPollJitterFactor is the fraction of the watch poll interval by which
+// each wait is randomly adjusted.
+//
+// This value defaults to zero.
+func WatchPollJitterFactor(factor float64) Option {
+	return func(po *postgresOptions) {
+		po.watchPollJitterFactor = factor
+	}
+}
+
+// WatchQueryTimeout is the maximum time Watch waits for the changes of each
+// cycle to load before failing with a timeout error, so that a stuck query
+// cannot wedge the watch.
+//
+// This value defaults to having no timeout.
+func WatchQueryTimeout(timeout time.Duration) Option {
+	return func(po *postgresOptions) {
+		po.watchQueryTimeout = timeout
+	}
+}
+
+// WatchChunkSize is the maximum number of transactions whose changes Watch
+// loads with a single query. Wider ranges of transactions, such as when a
+// watch resumes far behind the head revision, are split into chunks of this
+// size, which are loaded in parallel. Zero disables chunking.
+//
+// This value defaults to 10,000 transactions.
+func WatchChunkSize(transactions uint64) Option {
+	return func(po *postgresOptions) {
+		po.watchChunkSize = transactions
+	}
+}
+
+// WatchChunkConcurrency is the maximum number of chunks of transactions whose
+// changes Watch loads in parallel. It also bounds the number of chunks whose
+// changes are held in memory at once.
+//
+// This value defaults to 4.
+func WatchChunkConcurrency(concurrency uint16) Option {
+	return func(po *postgresOptions) {
+		po.watchChunkConcurrency = concurrency
+	}
+}
+
+// QueryStatementTimeout is the maximum time a relationship query made at a
+// snapshot revision may run before Postgres cancels it, so that one expensive
+// query cannot tie up a connection indefinitely. Queries which time out fail
+// with datastore.ErrQueryTimedOut.
+//
+// This value defaults to having no timeout.
+func QueryStatementTimeout(timeout time.Duration) Option {
+	return func(po *postgresOptions) {
+		po.queryStatementTimeout = timeout
+	}
+}
+
+// WithWatchNotifications marks whether Watch should wait for notifications of new
+// transactions, sent via Postgres LISTEN/NOTIFY, rather than polling for them. If the
+// connection used to listen for notifications is lost, Watch falls back to polling.
+//
+// Notifications are disabled by default.
+func WithWatchNotifications(enabled bool) Option {
+	return func(po *postgresOptions) {
+		po.watchNotifications = enabled
+	}
+}
+
+// NullSubjectRelation determines how relationships whose subject relation is NULL, which SpiceDB
+// never writes but a migration or another writer of the table may leave behind, are read.
+//
+// NULL subject relations are read as the ellipsis by default.
+func NullSubjectRelation(handling common.NullSubjectRelation) Option {
+	return func(po *postgresOptions) {
+		po.nullSubjectRelation = handling
+	}
+}
+
 // RevisionQuantization is the time bucket size to which advertised
 // revisions will be rounded.
 //
@@ -201,6 +352,33 @@ func MaxRetries(maxRetries uint8) Option {
 	}
 }
 
+// MaxQueryRetries is the maximum number of times a read query which failed with a transient
+// error, such as a lost connection or a serialization failure, will be client-side retried.
+// Setting it to zero disables retries.
+// Default: 3
+func MaxQueryRetries(maxRetries uint8) Option {
+	return func(po *postgresOptions) {
+		po.maxQueryRetries = maxRetries
+	}
+}
+
+// QueryRetryInitialBackoff is the delay before the first retry of a read query, which doubles
+// with each further retry up to QueryRetryMaxBackoff.
+// Default: 20ms
+func QueryRetryInitialBackoff(backoff time.Duration) Option {
+	return func(po *postgresOptions) {
+		po.queryRetryInitialBackoff = backoff
+	}
+}
+
+// QueryRetryMaxBackoff is the maximum delay between retries of a read query.
+// Default: 1s
+func QueryRetryMaxBackoff(backoff time.Duration) Option {
+	return func(po *postgresOptions) {
+		po.queryRetryMaxBackoff = backoff
+	}
+}
+
 // WithEnablePrometheusStats marks whether Prometheus metrics provided by the Postgres
 // clients being used by the datastore are enabled.
 //
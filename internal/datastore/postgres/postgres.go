@@ -5,12 +5,18 @@ import (
 	dbsql "database/sql"
 	"errors"
 	"fmt"
+	"io"
+	"net"
+	"strings"
+	"syscall"
 	"time"
 
 	"golang.org/x/sync/errgroup"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/benbjohnson/clock"
 	"github.com/jackc/pgconn"
+	"github.com/jackc/pgconn/stmtcache"
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/log/zerologadapter"
 	"github.com/jackc/pgx/v4/pgxpool"
@@ -47,6 +53,8 @@ const (
 	colUsersetNamespace = "userset_namespace"
 	colUsersetObjectID  = "userset_object_id"
 	colUsersetRelation  = "userset_relation"
+	colCaveatName       = "caveat_name"
+	colCaveatContext    = "caveat_context"
 
 	errUnableToInstantiate = "unable to instantiate datastore: %w"
 
@@ -61,6 +69,14 @@ const (
 
 	pgSerializationFailure      = "40001"
 	pgUniqueConstraintViolation = "23505"
+	pgQueryCanceled             = "57014"
+	pgDeadlockDetected          = "40P01"
+	pgAdminShutdown             = "57P01"
+	pgCrashShutdown             = "57P02"
+	pgCannotConnectNow          = "57P03"
+
+	// pgConnectionExceptionClass is the class of the SQLSTATE codes of connection exceptions.
+	pgConnectionExceptionClass = "08"
 )
 
 func init() {
@@ -115,6 +131,9 @@ func NewPostgresDatastore(
 	if config.healthCheckPeriod != nil {
 		pgxConfig.HealthCheckPeriod = *config.healthCheckPeriod
 	}
+	if config.statementCacheCapacity != nil {
+		pgxConfig.ConnConfig.BuildStatementCache = buildStatementCache(*config.statementCacheCapacity)
+	}
 
 	pgxConfig.ConnConfig.Logger = zerologadapter.NewLogger(log.Logger)
 
@@ -165,6 +184,11 @@ func NewPostgresDatastore(
 		dburl:                   url,
 		dbpool:                  dbpool,
 		watchBufferLength:       config.watchBufferLength,
+		watchQueryTimeout:       config.watchQueryTimeout,
+		watchChunkSize:          config.watchChunkSize,
+		watchChunkConcurrency:   config.watchChunkConcurrency,
+		queryStatementTimeout:   config.queryStatementTimeout,
+		watchNotifications:      config.watchNotifications,
 		optimizedRevisionQuery:  revisionQuery,
 		validTransactionQuery:   validTransactionQuery,
 		gcWindow:                config.gcWindow,
@@ -176,10 +200,28 @@ func NewPostgresDatastore(
 		cancelGc:                cancelGc,
 		readTxOptions:           pgx.TxOptions{IsoLevel: pgx.RepeatableRead, AccessMode: pgx.ReadOnly},
 		maxRetries:              config.maxRetries,
+		queryRetries: common.RetryPolicy{
+			MaxRetries:     config.maxQueryRetries,
+			InitialBackoff: config.queryRetryInitialBackoff,
+			MaxBackoff:     config.queryRetryMaxBackoff,
+			IsRetryable:    queryErrorRetryable,
+		},
+		watchPolling: common.WatchPollingConfig{
+			Interval:     config.watchPollInterval,
+			MaxInterval:  config.watchPollMaxInterval,
+			BackoffAfter: config.watchPollBackoffAfter,
+			JitterFactor: config.watchPollJitterFactor,
+		},
+		nullSubjectRelation: config.nullSubjectRelation,
+		watchShutdown:       common.NewWatchShutdown(),
 	}
 
 	datastore.SetOptimizedRevisionFunc(datastore.optimizedRevisionFunc)
 
+	// The watches of the datastore share their loads of the head revision, reusing it for up to
+	// one poll interval.
+	datastore.headRevisions = common.NewHeadRevisionCache(datastore.loadRevision, config.watchPollInterval, clock.New())
+
 	// Start a goroutine for garbage collection.
 	if datastore.gcInterval > 0*time.Minute {
 		datastore.gcGroup, datastore.gcCtx = errgroup.WithContext(datastore.gcCtx)
@@ -199,12 +241,31 @@ func NewPostgresDatastore(
 	return datastore, nil
 }
 
+// buildStatementCache returns a function which creates the prepared statement cache of each
+// connection, or nil if the capacity is zero and statements should not be cached.
+func buildStatementCache(capacity int) pgx.BuildStatementCacheFunc {
+	if capacity <= 0 {
+		return nil
+	}
+
+	return func(conn *pgconn.PgConn) stmtcache.Cache {
+		return stmtcache.New(conn, stmtcache.ModePrepare, capacity)
+	}
+}
+
 type pgDatastore struct {
 	*revisions.CachedOptimizedRevisions
 
 	dburl                   string
 	dbpool                  *pgxpool.Pool
 	watchBufferLength       uint16
+	watchQueryTimeout       time.Duration
+	watchChunkSize          uint64
+	watchChunkConcurrency   uint16
+	queryStatementTimeout   time.Duration
+	watchNotifications      bool
+	watchPolling            common.WatchPollingConfig
+	headRevisions           *common.HeadRevisionCache
 	optimizedRevisionQuery  string
 	validTransactionQuery   string
 	gcWindow                time.Duration
@@ -214,6 +275,9 @@ type pgDatastore struct {
 	analyzeBeforeStatistics bool
 	readTxOptions           pgx.TxOptions
 	maxRetries              uint8
+	queryRetries            common.RetryPolicy
+	nullSubjectRelation     common.NullSubjectRelation
+	watchShutdown           *common.WatchShutdown
 
 	gcGroup  *errgroup.Group
 	gcCtx    context.Context
@@ -221,6 +285,11 @@ type pgDatastore struct {
 }
 
 func (pgd *pgDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return pgd.reader(buildLivingObjectFilterForRevision(rev))
+}
+
+// reader returns a reader of the relationships and namespaces selected by the filterer.
+func (pgd *pgDatastore) reader(filterer queryFilterer) *pgReader {
 	createTxFunc := func(ctx context.Context) (pgx.Tx, common.TxCleanupFunc, error) {
 		tx, err := pgd.dbpool.BeginTx(ctx, pgd.readTxOptions)
 		if err != nil {
@@ -233,18 +302,35 @@ func (pgd *pgDatastore) SnapshotReader(rev datastore.Revision) datastore.Reader
 			}
 		}
 
+		if pgd.queryStatementTimeout > 0 {
+			setTimeout := fmt.Sprintf("SET LOCAL statement_timeout = %d", pgd.queryStatementTimeout.Milliseconds())
+			if _, err := tx.Exec(ctx, setTimeout); err != nil {
+				cleanup(ctx)
+				return nil, nil, err
+			}
+		}
+
 		return tx, cleanup, nil
 	}
 
 	querySplitter := common.TupleQuerySplitter{
-		Executor:         common.NewPGXExecutor(createTxFunc),
+		Executor:         common.NewRetryingExecutor(common.NewPGXExecutor(createTxFunc, pgd.nullSubjectRelation), pgd.queryRetries),
 		UsersetBatchSize: pgd.usersetBatchSize,
+		RevisionsExecutor: common.NewRetryingRevisionsExecutor(
+			common.NewPGXRevisionsExecutor(createTxFunc, pgd.nullSubjectRelation, revisionFromTupleTransaction),
+			pgd.queryRetries,
+		),
+		StreamingExecutor: common.NewRetryingStreamingExecutor(
+			common.NewPGXStreamingExecutor(createTxFunc, pgd.nullSubjectRelation),
+			pgd.queryRetries,
+		),
 	}
 
 	return &pgReader{
 		createTxFunc,
 		querySplitter,
-		buildLivingObjectFilterForRevision(rev),
+		filterer,
+		pgd.queryStatementTimeout,
 	}
 }
 
@@ -271,8 +357,9 @@ func (pgd *pgDatastore) ReadWriteTx(
 			}
 
 			querySplitter := common.TupleQuerySplitter{
-				Executor:         common.NewPGXExecutor(longLivedTx),
-				UsersetBatchSize: pgd.usersetBatchSize,
+				Executor:          common.NewPGXExecutor(longLivedTx, pgd.nullSubjectRelation),
+				UsersetBatchSize:  pgd.usersetBatchSize,
+				RevisionsExecutor: common.NewPGXRevisionsExecutor(longLivedTx, pgd.nullSubjectRelation, revisionFromTupleTransaction),
 			}
 
 			rwt := &pgReadWriteTXN{
@@ -280,10 +367,13 @@ func (pgd *pgDatastore) ReadWriteTx(
 					longLivedTx,
 					querySplitter,
 					currentlyLivingObjects,
+					0,
 				},
 				ctx,
 				tx,
 				newTxnID,
+				pgd.nullSubjectRelation,
+				0,
 			}
 
 			return fn(ctx, rwt)
@@ -300,6 +390,9 @@ func (pgd *pgDatastore) ReadWriteTx(
 }
 
 func (pgd *pgDatastore) Close() error {
+	// Stop the watches before closing the connections they use
+	pgd.watchShutdown.Close()
+
 	pgd.cancelGc()
 
 	if pgd.gcGroup != nil {
@@ -324,6 +417,36 @@ func errorRetryable(err error) bool {
 	return pgerr.SQLState() == pgSerializationFailure || pgerr.SQLState() == pgUniqueConstraintViolation
 }
 
+// queryErrorRetryable returns whether a read query which failed with the error is worth retrying:
+// serialization failures and deadlocks, and the errors of connections which were lost, such as
+// during a failover of the database. Errors caused by the context are never retried.
+func queryErrorRetryable(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var pgerr *pgconn.PgError
+	if errors.As(err, &pgerr) {
+		switch pgerr.SQLState() {
+		case pgSerializationFailure, pgDeadlockDetected, pgAdminShutdown, pgCrashShutdown, pgCannotConnectNow:
+			return true
+		default:
+			return strings.HasPrefix(pgerr.SQLState(), pgConnectionExceptionClass)
+		}
+	}
+
+	if pgconn.SafeToRetry(err) {
+		return true
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, syscall.ECONNRESET) ||
+		errors.Is(err, syscall.ECONNREFUSED)
+}
+
 func (pgd *pgDatastore) IsReady(ctx context.Context) (bool, error) {
 	headMigration, err := migrations.DatabaseMigrations.HeadRevision()
 	if err != nil {
@@ -344,6 +467,43 @@ func (pgd *pgDatastore) IsReady(ctx context.Context) (bool, error) {
 	return version == headMigration, nil
 }
 
+// ReadyState reports the stats of the connection pool, and whether the database can be reached
+// and is fully migrated. An exhausted pool is reported without pinging the database, since the
+// ping would have to wait for a connection to be released.
+func (pgd *pgDatastore) ReadyState(ctx context.Context) (datastore.ReadyState, error) {
+	stat := pgd.dbpool.Stat()
+	state := datastore.ReadyState{
+		Status:        datastore.ReadyStatusReady,
+		AcquiredConns: uint32(stat.AcquiredConns()),
+		IdleConns:     uint32(stat.IdleConns()),
+		MaxConns:      uint32(stat.MaxConns()),
+	}
+
+	if stat.AcquiredConns() >= stat.MaxConns() {
+		state.Status = datastore.ReadyStatusPoolExhausted
+		state.Message = fmt.Sprintf("all %d connections of the pool are in use", stat.MaxConns())
+		return state, nil
+	}
+
+	if err := pgd.dbpool.Ping(ctx); err != nil {
+		state.Status = datastore.ReadyStatusUnavailable
+		state.Message = fmt.Sprintf("unable to reach the database: %s", err)
+		return state, nil
+	}
+
+	ready, err := pgd.IsReady(ctx)
+	if err != nil {
+		return state, err
+	}
+
+	if !ready {
+		state.Status = datastore.ReadyStatusMigrationRequired
+		state.Message = "the database is not migrated to the head migration"
+	}
+
+	return state, nil
+}
+
 func buildLivingObjectFilterForRevision(revision datastore.Revision) queryFilterer {
 	return func(original sq.SelectBuilder) sq.SelectBuilder {
 		return original.Where(sq.LtOrEq{colCreatedTxn: transactionFromRevision(revision)}).
@@ -354,8 +514,21 @@ func buildLivingObjectFilterForRevision(revision datastore.Revision) queryFilter
 	}
 }
 
+// buildLifetimeFilterForRevisions filters to the objects living at any revision from fromRevision
+// through toRevision. Living objects have the maximum deleted transaction, so need no special case.
+func buildLifetimeFilterForRevisions(fromRevision, toRevision datastore.Revision) queryFilterer {
+	return func(original sq.SelectBuilder) sq.SelectBuilder {
+		return original.Where(sq.LtOrEq{colCreatedTxn: transactionFromRevision(toRevision)}).
+			Where(sq.Gt{colDeletedTxn: transactionFromRevision(fromRevision)})
+	}
+}
+
 func currentlyLivingObjects(original sq.SelectBuilder) sq.SelectBuilder {
 	return original.Where(sq.Eq{colDeletedTxn: liveDeletedTxnID})
 }
 
-var _ datastore.Datastore = &pgDatastore{}
+var (
+	_ datastore.Datastore          = &pgDatastore{}
+	_ datastore.ReadyStateReporter = &pgDatastore{}
+	_ datastore.HistoricalQuerier  = &pgDatastore{}
+)
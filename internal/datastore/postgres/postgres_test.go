@@ -6,17 +6,29 @@ package postgres
 import (
 	"context"
 	"fmt"
+	"io"
 	"math/rand"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"syscall"
 	"testing"
 	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/benbjohnson/clock"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/types/known/structpb"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	testdatastore "github.com/authzed/spicedb/internal/testserver/datastore"
 	"github.com/authzed/spicedb/pkg/datastore"
@@ -28,7 +40,7 @@ import (
 func TestPostgresDatastore(t *testing.T) {
 	b := testdatastore.RunPostgresForTesting(t, "")
 
-	test.All(t, test.DatastoreTesterFunc(func(revisionQuantization, gcWindow time.Duration, watchBufferLength uint16) (datastore.Datastore, error) {
+	tester := test.DatastoreTesterFunc(func(revisionQuantization, gcWindow time.Duration, watchBufferLength uint16) (datastore.Datastore, error) {
 		ds := b.NewDatastore(t, func(engine, uri string) datastore.Datastore {
 			ds, err := NewPostgresDatastore(uri,
 				RevisionQuantization(revisionQuantization),
@@ -40,7 +52,11 @@ func TestPostgresDatastore(t *testing.T) {
 			return ds
 		})
 		return ds, nil
-	}))
+	})
+
+	test.All(t, tester)
+	t.Run("TestWatchCreateThenDelete", func(t *testing.T) { test.WatchCreateThenDeleteTest(t, tester) })
+	t.Run("TestWatchSchemaOnlyWrite", func(t *testing.T) { test.WatchSchemaOnlyWriteTest(t, tester) })
 
 	t.Run("WithSplit", func(t *testing.T) {
 		// Set the split at a VERY small size, to ensure any WithUsersets queries are split.
@@ -85,6 +101,14 @@ func TestPostgresDatastore(t *testing.T) {
 		WatchBufferLength(1),
 	))
 
+	t.Run("GarbageCollectionRetention", createDatastoreTest(
+		b,
+		GarbageCollectionRetentionTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Millisecond),
+		WatchBufferLength(1),
+	))
+
 	t.Run("ChunkedGarbageCollection", createDatastoreTest(
 		b,
 		ChunkedGarbageCollectionTest,
@@ -96,6 +120,100 @@ func TestPostgresDatastore(t *testing.T) {
 	t.Run("QuantizedRevisions", func(t *testing.T) {
 		QuantizedRevisionTest(t, b)
 	})
+
+	t.Run("WatchNotifications", createDatastoreTest(
+		b,
+		WatchNotificationsTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Millisecond),
+		WithWatchNotifications(true),
+	))
+
+	t.Run("WatchQueryTimeout", func(t *testing.T) {
+		WatchQueryTimeoutTest(t, b)
+	})
+
+	t.Run("StatementCache", func(t *testing.T) {
+		StatementCacheTest(t, b)
+	})
+
+	t.Run("QueryStatementTimeout", func(t *testing.T) {
+		QueryStatementTimeoutTest(t, b)
+	})
+
+	t.Run("NullSubjectRelation", func(t *testing.T) {
+		NullSubjectRelationTest(t, b)
+	})
+
+	t.Run("BulkImport", createDatastoreTest(
+		b,
+		BulkImportTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Millisecond),
+		WatchBufferLength(1),
+	))
+
+	t.Run("ChunkedWatchChanges", createDatastoreTest(
+		b,
+		ChunkedWatchChangesTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Millisecond),
+		WatchBufferLength(50),
+	))
+
+	t.Run("CaveatedTuples", createDatastoreTest(
+		b,
+		CaveatedTuplesTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Millisecond),
+		WatchBufferLength(50),
+	))
+
+	t.Run("SortedQueryMatchesMemdb", createDatastoreTest(
+		b,
+		SortedQueryMatchesMemdbTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Millisecond),
+		WatchBufferLength(1),
+	))
+
+	t.Run("ReadyState", createDatastoreTest(
+		b,
+		ReadyStateTest,
+		MaxOpenConns(1),
+		WithWatchNotifications(false),
+	))
+
+	t.Run("RevisionMetadata", createDatastoreTest(
+		b,
+		RevisionMetadataTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Hour),
+	))
+
+	t.Run("WatchClosing", createDatastoreTest(
+		b,
+		WatchClosingTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Hour),
+	))
+
+	t.Run("ReverseQueryIndex", createDatastoreTest(
+		b,
+		ReverseQueryIndexTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Hour),
+	))
+
+	t.Run("SharedHeadRevision", createDatastoreTest(
+		b,
+		SharedHeadRevisionTest,
+		RevisionQuantization(0),
+		GCWindow(1*time.Millisecond),
+		WithWatchNotifications(false),
+		WatchPollInterval(50*time.Millisecond),
+		WatchPollBackoffAfter(1000),
+	))
 }
 
 type datastoreTestFunc func(t *testing.T, ds datastore.Datastore)
@@ -407,6 +525,75 @@ func GarbageCollectionByTimeTest(t *testing.T, ds datastore.Datastore) {
 	tRequire.NoTupleExists(ctx, tpl, relDeletedAt)
 }
 
+func GarbageCollectionRetentionTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"))
+	})
+	require.NoError(err)
+
+	pds := ds.(*pgDatastore)
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+
+	oldTombstone := tuple.Parse("resource:old#reader@user:someuser#...")
+	recentTombstone := tuple.Parse("resource:recent#reader@user:someuser#...")
+	living := tuple.Parse("resource:living#reader@user:someuser#...")
+
+	writtenAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(oldTombstone)),
+			tuple.UpdateToRelationshipUpdate(tuple.Create(recentTombstone)),
+			tuple.UpdateToRelationshipUpdate(tuple.Create(living)),
+		})
+	})
+	require.NoError(err)
+
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Delete(oldTombstone)),
+		})
+	})
+	require.NoError(err)
+
+	// Sleep 1ms to ensure the retention window ends after the first deletion.
+	time.Sleep(1 * time.Millisecond)
+	retainAfter, err := pds.Now(ctx)
+	require.NoError(err)
+	time.Sleep(1 * time.Millisecond)
+
+	recentDeletedAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Delete(recentTombstone)),
+		})
+	})
+	require.NoError(err)
+
+	// Run GC for the transactions before the retention window: only the old tombstone is removed.
+	retainAfterTx, err := pds.TxIDBefore(ctx, retainAfter)
+	require.NoError(err)
+
+	removed, err := pds.DeleteBeforeTx(ctx, retainAfterTx)
+	require.NoError(err)
+	require.Equal(int64(1), removed.Relationships)
+
+	// The recent tombstone is still visible at revisions within the window, and the living
+	// relationship at all of them.
+	recentDeletedTx := transactionFromRevision(recentDeletedAt)
+	beforeRecentDelete := revisionFromTransaction(recentDeletedTx - 1)
+	tRequire.TupleExists(ctx, recentTombstone, beforeRecentDelete)
+	tRequire.NoTupleExists(ctx, recentTombstone, recentDeletedAt)
+	tRequire.TupleExists(ctx, living, recentDeletedAt)
+	tRequire.NoTupleExists(ctx, oldTombstone, recentDeletedAt)
+
+	// The old tombstone is gone even at the revision it was written at.
+	tRequire.NoTupleExists(ctx, oldTombstone, writtenAt)
+}
+
 const chunkRelationshipCount = 2000
 
 func ChunkedGarbageCollectionTest(t *testing.T, ds datastore.Datastore) {
@@ -629,9 +816,679 @@ func QuantizedRevisionTest(t *testing.T, b testdatastore.RunningEngineForTest) {
 }
 
 func BenchmarkPostgresQuery(b *testing.B) {
-	req := require.New(b)
+	engine := testdatastore.RunPostgresForTesting(b, "")
+
+	for _, tc := range []struct {
+		name                   string
+		statementCacheCapacity int
+	}{
+		{"uncached statements", 0},
+		{"cached statements", 512},
+	} {
+		b.Run(tc.name, func(b *testing.B) {
+			req := require.New(b)
+
+			ds := engine.NewDatastore(b, func(engine, uri string) datastore.Datastore {
+				ds, err := NewPostgresDatastore(uri,
+					RevisionQuantization(0),
+					GCWindow(time.Millisecond*1),
+					WatchBufferLength(1),
+					StatementCacheCapacity(tc.statementCacheCapacity),
+				)
+				require.NoError(b, err)
+				return ds
+			})
+			defer ds.Close()
+			ds, revision := testfixtures.StandardDatastoreWithData(ds, req)
+
+			documentIDs := []string{"companyplan", "healthplan", "masterplan"}
+
+			b.Run("benchmark checks", func(b *testing.B) {
+				require := require.New(b)
+
+				for i := 0; i < b.N; i++ {
+					iter, err := ds.SnapshotReader(revision).QueryRelationships(context.Background(), &v1.RelationshipFilter{
+						ResourceType:       testfixtures.DocumentNS.Name,
+						OptionalResourceId: documentIDs[i%len(documentIDs)],
+						OptionalRelation:   "parent",
+					})
+					require.NoError(err)
+
+					for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+						require.Equal(testfixtures.DocumentNS.Name, tpl.ResourceAndRelation.Namespace)
+					}
+					require.NoError(iter.Err())
+					iter.Close()
+				}
+			})
+		})
+	}
+}
+
+func StatementCacheTest(t *testing.T, b testdatastore.RunningEngineForTest) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	newDatastore := func(capacity int) (datastore.Datastore, datastore.Revision) {
+		ds := b.NewDatastore(t, func(engine, uri string) datastore.Datastore {
+			ds, err := NewPostgresDatastore(uri,
+				RevisionQuantization(0),
+				GCWindow(time.Millisecond*1),
+				WatchBufferLength(1),
+				StatementCacheCapacity(capacity),
+			)
+			require.NoError(err)
+			return ds
+		})
+		return testfixtures.StandardDatastoreWithData(ds, require)
+	}
+
+	uncached, uncachedRevision := newDatastore(0)
+	defer uncached.Close()
+
+	cached, cachedRevision := newDatastore(512)
+	defer cached.Close()
 
-	ds := testdatastore.RunPostgresForTesting(b, "").NewDatastore(b, func(engine, uri string) datastore.Datastore {
+	filters := []*v1.RelationshipFilter{
+		{ResourceType: testfixtures.DocumentNS.Name},
+		{ResourceType: testfixtures.DocumentNS.Name, OptionalResourceId: "masterplan"},
+		{ResourceType: testfixtures.DocumentNS.Name, OptionalRelation: "viewer"},
+		{ResourceType: testfixtures.DocumentNS.Name, OptionalResourceId: "masterplan", OptionalRelation: "owner"},
+		{ResourceType: testfixtures.FolderNS.Name, OptionalResourceId: "company"},
+	}
+
+	readAll := func(ds datastore.Datastore, revision datastore.Revision, filter *v1.RelationshipFilter) []string {
+		iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, filter)
+		require.NoError(err)
+		defer iter.Close()
+
+		var found []string
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			found = append(found, tuple.String(tpl))
+		}
+		require.NoError(iter.Err())
+
+		sort.Strings(found)
+		return found
+	}
+
+	// Run each filter several times, so that the cached datastore reuses its prepared statements.
+	for i := 0; i < 3; i++ {
+		for _, filter := range filters {
+			expected := readAll(uncached, uncachedRevision, filter)
+			require.NotEmpty(expected)
+			require.Equal(expected, readAll(cached, cachedRevision, filter), "mismatched results for filter %v", filter)
+		}
+	}
+}
+
+func WatchNotificationsTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startRevision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"))
+	})
+	require.NoError(err)
+
+	changes, errchan := ds.Watch(ctx, startRevision)
+	require.Zero(len(errchan))
+
+	// Wait for the watch to have loaded the (empty) initial changes and be waiting for a
+	// notification, so that the write below is not picked up by the initial load.
+	time.Sleep(2 * defaultWatchPollInterval)
+
+	written := tuple.Parse("resource:foo#reader@user:tom")
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(written)),
+		})
+	})
+	require.NoError(err)
+	committedAt := time.Now()
+
+	select {
+	case change := <-changes:
+		delivered := time.Since(committedAt)
+		require.Len(change.Changes, 1)
+		require.Equal(tuple.String(written), tuple.String(change.Changes[0].Tuple))
+		require.Less(delivered, defaultWatchPollInterval/4, "change was not delivered via notification")
+	case err := <-errchan:
+		require.Fail("unexpected watch error", "%s", err)
+	case <-time.After(5 * time.Second):
+		require.Fail("timed out waiting for change")
+	}
+}
+
+func WatchQueryTimeoutTest(t *testing.T, b testdatastore.RunningEngineForTest) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var conn *pgx.Conn
+	ds := b.NewDatastore(t, func(engine, uri string) datastore.Datastore {
+		var err error
+		conn, err = pgx.Connect(ctx, uri)
+		require.NoError(err)
+
+		ds, err := NewPostgresDatastore(
+			uri,
+			RevisionQuantization(0),
+			GCWindow(1*time.Millisecond),
+			WatchQueryTimeout(500*time.Millisecond),
+		)
+		require.NoError(err)
+
+		return ds
+	})
+	defer ds.Close()
+
+	startRevision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"))
+	})
+	require.NoError(err)
+
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse("resource:foo#reader@user:tom"))),
+		})
+	})
+	require.NoError(err)
+
+	// Hold a lock on the relationships table, so that the watch's query for changes blocks.
+	tx, err := conn.Begin(ctx)
+	require.NoError(err)
+	defer func() {
+		require.NoError(tx.Rollback(ctx))
+	}()
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("LOCK TABLE %s IN ACCESS EXCLUSIVE MODE", tableTuple))
+	require.NoError(err)
+
+	t.Run("timeout", func(t *testing.T) {
+		_, errchan := ds.Watch(ctx, startRevision)
+
+		select {
+		case err := <-errchan:
+			require.ErrorAs(t, err, &datastore.ErrWatchTimedOut{})
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for the watch to time out")
+		}
+	})
+
+	t.Run("canceled mid-query", func(t *testing.T) {
+		watchCtx, cancelWatch := context.WithCancel(ctx)
+		_, errchan := ds.Watch(watchCtx, startRevision)
+
+		// Cancel the watch while its query is blocked, before the query timeout.
+		time.Sleep(100 * time.Millisecond)
+		cancelWatch()
+
+		select {
+		case err := <-errchan:
+			require.ErrorAs(t, err, &datastore.ErrWatchCanceled{})
+		case <-time.After(5 * time.Second):
+			require.Fail(t, "timed out waiting for the watch to be canceled")
+		}
+	})
+}
+
+func QueryStatementTimeoutTest(t *testing.T, b testdatastore.RunningEngineForTest) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var conn *pgx.Conn
+	ds := b.NewDatastore(t, func(engine, uri string) datastore.Datastore {
+		var err error
+		conn, err = pgx.Connect(ctx, uri)
+		require.NoError(err)
+
+		ds, err := NewPostgresDatastore(
+			uri,
+			RevisionQuantization(0),
+			GCWindow(1*time.Millisecond),
+			QueryStatementTimeout(500*time.Millisecond),
+		)
+		require.NoError(err)
+
+		return ds
+	})
+	defer ds.Close()
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.WriteNamespaces(namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user")); err != nil {
+			return err
+		}
+
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse("resource:foo#reader@user:tom"))),
+		})
+	})
+	require.NoError(err)
+
+	reader := ds.SnapshotReader(revision)
+
+	iter, err := reader.QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: "resource"})
+	require.NoError(err)
+	require.NotNil(iter.Next())
+	iter.Close()
+
+	// Hold a lock on the relationships table, so that queries block until they time out.
+	tx, err := conn.Begin(ctx)
+	require.NoError(err)
+	defer func() {
+		require.NoError(tx.Rollback(ctx))
+	}()
+
+	_, err = tx.Exec(ctx, fmt.Sprintf("LOCK TABLE %s IN ACCESS EXCLUSIVE MODE", tableTuple))
+	require.NoError(err)
+
+	start := time.Now()
+	_, err = reader.QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: "resource"})
+	require.ErrorAs(err, &datastore.ErrQueryTimedOut{})
+	require.Less(time.Since(start), 5*time.Second)
+
+	_, err = reader.ReverseQueryRelationships(ctx, &v1.SubjectFilter{SubjectType: "user"})
+	require.ErrorAs(err, &datastore.ErrQueryTimedOut{})
+}
+
+func NullSubjectRelationTest(t *testing.T, b testdatastore.RunningEngineForTest) {
+	testCases := []struct {
+		name             string
+		handling         common.NullSubjectRelation
+		expectedRelation string
+		expectError      bool
+	}{
+		{"as ellipsis", common.NullSubjectRelationAsEllipsis, tuple.Ellipsis, false},
+		{"as empty", common.NullSubjectRelationAsEmpty, "", false},
+		{"as error", common.NullSubjectRelationAsError, "", true},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+			defer cancel()
+
+			var conn *pgx.Conn
+			ds := b.NewDatastore(t, func(engine, uri string) datastore.Datastore {
+				var err error
+				conn, err = pgx.Connect(ctx, uri)
+				require.NoError(err)
+
+				ds, err := NewPostgresDatastore(
+					uri,
+					RevisionQuantization(0),
+					GCWindow(1*time.Millisecond),
+					NullSubjectRelation(tc.handling),
+				)
+				require.NoError(err)
+
+				return ds
+			})
+			defer ds.Close()
+
+			startRevision, err := ds.HeadRevision(ctx)
+			require.NoError(err)
+
+			revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				if err := rwt.WriteNamespaces(namespace.Namespace(
+					"resource",
+					namespace.Relation("reader", nil),
+				), namespace.Namespace("user")); err != nil {
+					return err
+				}
+
+				return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+					tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse("resource:foo#reader@user:tom"))),
+				})
+			})
+			require.NoError(err)
+
+			// Simulate another writer of the table leaving a NULL subject relation behind.
+			_, err = conn.Exec(ctx, fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s DROP NOT NULL", tableTuple, colUsersetRelation))
+			require.NoError(err)
+			_, err = conn.Exec(ctx, fmt.Sprintf("UPDATE %s SET %s = NULL", tableTuple, colUsersetRelation))
+			require.NoError(err)
+
+			iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: "resource"})
+			if tc.expectError {
+				require.ErrorContains(err, "is NULL")
+			} else {
+				require.NoError(err)
+				found := iter.Next()
+				require.NotNil(found)
+				require.Equal(tc.expectedRelation, found.Subject.Relation)
+				require.Nil(iter.Next())
+				iter.Close()
+			}
+
+			// Touching the relationship reads the row with the NULL subject relation as well.
+			touchRevision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+					tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.Parse("resource:foo#reader@user:tom"))),
+				})
+			})
+			if tc.expectError {
+				require.ErrorContains(err, "is NULL")
+			} else {
+				require.NoError(err)
+
+				// A NULL read as the ellipsis is the touched relationship, so the touch is skipped.
+				iter, err := ds.SnapshotReader(touchRevision).QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: "resource"})
+				require.NoError(err)
+				var relations []string
+				for found := iter.Next(); found != nil; found = iter.Next() {
+					relations = append(relations, found.Subject.Relation)
+				}
+				iter.Close()
+
+				if tc.expectedRelation == tuple.Ellipsis {
+					require.Equal([]string{tuple.Ellipsis}, relations)
+				} else {
+					require.ElementsMatch([]string{tc.expectedRelation, tuple.Ellipsis}, relations)
+				}
+			}
+
+			changes, errchan := ds.Watch(ctx, startRevision)
+			for {
+				select {
+				case change := <-changes:
+					if change.IsCheckpoint {
+						continue
+					}
+					require.False(tc.expectError)
+					require.Len(change.Changes, 1)
+					require.Equal("tom", change.Changes[0].Tuple.Subject.ObjectId)
+					require.Equal(tc.expectedRelation, change.Changes[0].Tuple.Subject.Relation)
+				case err := <-errchan:
+					require.True(tc.expectError)
+					require.ErrorContains(err, "is NULL")
+				case <-time.After(5 * time.Second):
+					require.Fail("timed out waiting for the watch")
+				}
+				return
+			}
+		})
+	}
+}
+
+func makeBulkImportTuples(prefix string, start, end int) []*core.RelationTuple {
+	tuples := make([]*core.RelationTuple, 0, end-start)
+	for i := start; i < end; i++ {
+		tuples = append(tuples, tuple.Parse(fmt.Sprintf("document:%s%d#viewer@user:user%d#...", prefix, i, i)))
+	}
+	return tuples
+}
+
+func BulkImportTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	importer, ok := ds.(datastore.BulkImporter)
+	require.True(ok)
+
+	countDocuments := func(revision datastore.Revision) uint64 {
+		count, err := ds.SnapshotReader(revision).CountRelationships(ctx, &v1.RelationshipFilter{ResourceType: "document"})
+		require.NoError(err)
+		return count
+	}
+
+	// Import more than a single batch, to ensure all batches are written in the same transaction.
+	beforeImport, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	initial := makeBulkImportTuples("doc", 0, bulkImportBatchSize+5)
+	imported, importedAt, err := importer.BulkImportRelationships(ctx, initial, datastore.ConflictError)
+	require.NoError(err)
+	require.Equal(uint64(len(initial)), imported)
+	require.Equal(uint64(len(initial)), countDocuments(importedAt))
+	require.Equal(uint64(0), countDocuments(beforeImport))
+
+	// Skipping conflicts imports only the relationships which do not yet exist, once each.
+	additional := makeBulkImportTuples("doc", bulkImportBatchSize, bulkImportBatchSize+10)
+	additional = append(additional, additional[len(additional)-1])
+	imported, skippedAt, err := importer.BulkImportRelationships(ctx, additional, datastore.ConflictSkip)
+	require.NoError(err)
+	require.Equal(uint64(5), imported)
+	require.Equal(uint64(len(initial)+5), countDocuments(skippedAt))
+
+	// Failing on conflicts writes nothing if any relationship already exists.
+	conflicting := makeBulkImportTuples("other", 0, 10)
+	conflicting = append(conflicting, initial[0])
+	_, _, err = importer.BulkImportRelationships(ctx, conflicting, datastore.ConflictError)
+	require.ErrorAs(err, &datastore.ErrRelationshipsExist{})
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	require.Equal(uint64(len(initial)+5), countDocuments(headRevision))
+
+	// Failing on conflicts also rejects relationships imported more than once.
+	duplicated := makeBulkImportTuples("other", 0, 10)
+	duplicated = append(duplicated, duplicated[0])
+	_, _, err = importer.BulkImportRelationships(ctx, duplicated, datastore.ConflictError)
+	require.ErrorAs(err, &datastore.ErrRelationshipsExist{})
+}
+
+func ChunkedWatchChangesTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	startRevision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(namespace.Namespace(
+			"resource",
+			namespace.Relation("reader", nil),
+		), namespace.Namespace("user"))
+	})
+	require.NoError(err)
+
+	write := func(updates ...*v1.RelationshipUpdate) {
+		_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			for _, update := range updates {
+				if err := rwt.WriteRelationships([]*v1.RelationshipUpdate{update}); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		require.NoError(err)
+	}
+
+	update := func(op v1.RelationshipUpdate_Operation, rel string) *v1.RelationshipUpdate {
+		return &v1.RelationshipUpdate{Operation: op, Relationship: tuple.MustToRelationship(tuple.Parse(rel))}
+	}
+
+	// Write transactions whose changes span chunk boundaries, including relationships touched
+	// and deleted in different transactions, and one created and deleted within a transaction.
+	for i := 0; i < 10; i++ {
+		write(update(v1.RelationshipUpdate_OPERATION_TOUCH, fmt.Sprintf("resource:doc%d#reader@user:tom", i)))
+		if i%3 == 0 {
+			write(update(v1.RelationshipUpdate_OPERATION_DELETE, fmt.Sprintf("resource:doc%d#reader@user:tom", i)))
+		}
+		if i%4 == 0 {
+			write(
+				update(v1.RelationshipUpdate_OPERATION_CREATE, fmt.Sprintf("resource:ephemeral%d#reader@user:tom", i)),
+				update(v1.RelationshipUpdate_OPERATION_DELETE, fmt.Sprintf("resource:ephemeral%d#reader@user:tom", i)),
+			)
+		}
+		write(
+			update(v1.RelationshipUpdate_OPERATION_TOUCH, fmt.Sprintf("resource:shared#reader@user:user%d", i)),
+			update(v1.RelationshipUpdate_OPERATION_TOUCH, fmt.Sprintf("resource:doc%d#reader@user:jerry", i)),
+		)
+	}
+
+	// Empty transactions ensure some chunks, and whole batches of them, have no changes
+	for i := 0; i < 5; i++ {
+		write()
+	}
+	write(update(v1.RelationshipUpdate_OPERATION_DELETE, "resource:shared#reader@user:user0"))
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	pgd := ds.(*pgDatastore)
+	loadAll := func(chunkSize uint64, concurrency uint16) []string {
+		pgd.watchChunkSize = chunkSize
+		pgd.watchChunkConcurrency = concurrency
+
+		var loaded []string
+		currentTxn := transactionFromRevision(startRevision)
+		for currentTxn < transactionFromRevision(headRevision) {
+			changes, newTxn, err := pgd.loadChanges(ctx, currentTxn, true, &options.WatchOptions{})
+			require.NoError(err)
+			require.Greater(newTxn, currentTxn)
+
+			for _, change := range changes {
+				require.True(change.Revision.GreaterThan(revisionFromTransaction(currentTxn)))
+				require.True(change.Revision.LessThanOrEqual(revisionFromTransaction(newTxn)))
+
+				var updates []string
+				for _, update := range change.Changes {
+					updates = append(updates, fmt.Sprintf("%s %s", update.Operation, tuple.String(update.Tuple)))
+				}
+				sort.Strings(updates)
+				loaded = append(loaded, fmt.Sprintf("%s: %s", change.Revision, strings.Join(updates, ", ")))
+			}
+			currentTxn = newTxn
+		}
+		return loaded
+	}
+
+	expected := loadAll(0, 0)
+	require.NotEmpty(expected)
+
+	for _, chunkSize := range []uint64{1, 2, 3, 7} {
+		for _, concurrency := range []uint16{1, 2, 4} {
+			require.Equal(expected, loadAll(chunkSize, concurrency), "chunk size %d, concurrency %d", chunkSize, concurrency)
+		}
+	}
+}
+
+func CaveatedTuplesTest(t *testing.T, ds datastore.Datastore) {
+	require := require.New(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	caveatContext, err := structpb.NewStruct(map[string]any{
+		"ip":      "10.0.0.1",
+		"allowed": []any{"read", "write"},
+		"limit":   42.0,
+	})
+	require.NoError(err)
+
+	caveated := tuple.Parse("document:caveated#viewer@user:tom#...")
+	caveated.Caveat = &core.ContextualizedCaveat{CaveatName: "ip_allowed", Context: caveatContext}
+
+	withoutContext := tuple.Parse("document:nocontext#viewer@user:tom#...")
+	withoutContext.Caveat = &core.ContextualizedCaveat{CaveatName: "ip_allowed"}
+
+	plain := tuple.Parse("document:plain#viewer@user:tom#...")
+	expected := []*core.RelationTuple{caveated, withoutContext, plain}
+
+	startRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	changes, errchan := ds.Watch(ctx, startRevision)
+	require.Zero(len(errchan))
+
+	importedAt := func() datastore.Revision {
+		importer, ok := ds.(datastore.BulkImporter)
+		require.True(ok)
+
+		_, revision, err := importer.BulkImportRelationships(ctx, expected, datastore.ConflictError)
+		require.NoError(err)
+		return revision
+	}()
+
+	requireMatches := func(found []*core.RelationTuple) {
+		foundByKey := make(map[string]*core.RelationTuple, len(found))
+		for _, tpl := range found {
+			foundByKey[tuple.String(tpl)] = tpl
+		}
+		require.Len(foundByKey, len(expected))
+
+		for _, tpl := range expected {
+			foundTpl, ok := foundByKey[tuple.String(tpl)]
+			require.True(ok, "missing %s", tuple.String(tpl))
+			require.True(proto.Equal(tpl.Caveat, foundTpl.Caveat), "caveat of %s did not round-trip: %v", tuple.String(tpl), foundTpl.Caveat)
+		}
+	}
+
+	readAll := func(iter datastore.RelationshipIterator, err error) []*core.RelationTuple {
+		require.NoError(err)
+		defer iter.Close()
+
+		var found []*core.RelationTuple
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			found = append(found, tpl)
+		}
+		require.NoError(iter.Err())
+		return found
+	}
+
+	reader := ds.SnapshotReader(importedAt)
+	requireMatches(readAll(reader.QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: "document"})))
+	requireMatches(readAll(reader.ReverseQueryRelationships(ctx, &v1.SubjectFilter{SubjectType: "user"})))
+
+	select {
+	case change, ok := <-changes:
+		require.True(ok)
+
+		var found []*core.RelationTuple
+		for _, update := range change.Changes {
+			require.Equal(core.RelationTupleUpdate_TOUCH, update.Operation)
+			found = append(found, update.Tuple)
+		}
+		requireMatches(found)
+	case <-time.After(5 * time.Second):
+		require.Fail("Timed out waiting for changes")
+	}
+
+	// A v1 touch writes no caveat, so it replaces the caveat of a living caveated relationship.
+	touchedAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.Parse(tuple.String(caveated)))),
+			tuple.UpdateToRelationshipUpdate(tuple.Touch(tuple.Parse(tuple.String(plain)))),
+		})
+	})
+	require.NoError(err)
+
+	expected = []*core.RelationTuple{tuple.Parse(tuple.String(caveated)), withoutContext, plain}
+	requireMatches(readAll(ds.SnapshotReader(touchedAt).QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: "document"})))
+
+	select {
+	case change, ok := <-changes:
+		require.True(ok)
+		require.Len(change.Changes, 1)
+		require.Equal(tuple.String(caveated), tuple.String(change.Changes[0].Tuple))
+		require.Nil(change.Changes[0].Tuple.Caveat)
+	case <-time.After(5 * time.Second):
+		require.Fail("Timed out waiting for changes")
+	}
+}
+
+func BenchmarkPostgresBulkImport(b *testing.B) {
+	const tuplesPerImport = 1000
+
+	engine := testdatastore.RunPostgresForTesting(b, "")
+	ds := engine.NewDatastore(b, func(engine, uri string) datastore.Datastore {
 		ds, err := NewPostgresDatastore(uri,
 			RevisionQuantization(0),
 			GCWindow(time.Millisecond*1),
@@ -641,23 +1498,31 @@ func BenchmarkPostgresQuery(b *testing.B) {
 		return ds
 	})
 	defer ds.Close()
-	ds, revision := testfixtures.StandardDatastoreWithData(ds, req)
 
-	b.Run("benchmark checks", func(b *testing.B) {
-		require := require.New(b)
+	ctx := context.Background()
 
+	b.Run("bulk import", func(b *testing.B) {
 		for i := 0; i < b.N; i++ {
-			iter, err := ds.SnapshotReader(revision).QueryRelationships(context.Background(), &v1.RelationshipFilter{
-				ResourceType: testfixtures.DocumentNS.Name,
-			})
-			require.NoError(err)
-
-			defer iter.Close()
+			tuples := makeBulkImportTuples(fmt.Sprintf("bulk%d-", i), 0, tuplesPerImport)
+			_, _, err := ds.(datastore.BulkImporter).BulkImportRelationships(ctx, tuples, datastore.ConflictError)
+			require.NoError(b, err)
+		}
+	})
 
-			for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
-				require.Equal(testfixtures.DocumentNS.Name, tpl.ResourceAndRelation.Namespace)
-			}
-			require.NoError(iter.Err())
+	b.Run("row by row", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			tuples := makeBulkImportTuples(fmt.Sprintf("rows%d-", i), 0, tuplesPerImport)
+			_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				for _, tpl := range tuples {
+					if err := rwt.WriteRelationships([]*v1.RelationshipUpdate{
+						tuple.UpdateToRelationshipUpdate(tuple.Create(tpl)),
+					}); err != nil {
+						return err
+					}
+				}
+				return nil
+			})
+			require.NoError(b, err)
 		}
 	})
 }
@@ -4,9 +4,11 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"time"
 
 	sq "github.com/Masterminds/squirrel"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/jackc/pgconn"
 	"github.com/jackc/pgx/v4"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
@@ -18,10 +20,23 @@ import (
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
+// pgReader reads the relationships and namespaces selected by its filterer, which are those living
+// at a revision for snapshot readers. Its query paths expect the following indexes of
+// relation_tuple:
+//   - QueryRelationships, CountRelationships and CheckRelationshipsExist filter on the resource
+//     columns first, which uq_relation_tuple_living leads with.
+//   - QueryRelationships filtered to usersets, and ReverseQueryRelationships, filter on the
+//     subject columns first, matching ix_relation_tuple_by_subject_living.
+//
+// Reverse queries filter on the living window, on created_transaction and deleted_transaction,
+// last, so that their predicates are in the order of the columns of their index.
 type pgReader struct {
 	txSource      common.TxFactory
 	querySplitter common.TupleQuerySplitter
 	filterer      queryFilterer
+
+	// statementTimeout is the statement timeout set on the reader's transactions, if any.
+	statementTimeout time.Duration
 }
 
 type queryFilterer func(original sq.SelectBuilder) sq.SelectBuilder
@@ -34,8 +49,16 @@ var (
 		colUsersetNamespace,
 		colUsersetObjectID,
 		colUsersetRelation,
+		colCaveatName,
+		colCaveatContext,
 	).From(tableTuple)
 
+	// queryTuplesWithRevisions also selects the transactions which created and deleted each tuple,
+	// for queries made with options.WithRevisionMetadata.
+	queryTuplesWithRevisions = queryTuples.Columns(colCreatedTxn, colDeletedTxn)
+
+	countTuples = psql.Select("COUNT(*)").From(tableTuple)
+
 	schema = common.SchemaInformation{
 		ColNamespace:        colNamespace,
 		ColObjectID:         colObjectID,
@@ -51,15 +74,111 @@ var (
 const (
 	errUnableToReadConfig     = "unable to read namespace config: %w"
 	errUnableToListNamespaces = "unable to list namespaces: %w"
+	errUnableToCountTuples    = "unable to count tuples: %w"
+	errInvertedRevisionRange  = "revision range from %s to %s is inverted"
 )
 
+// QueryTuplesIncludingDeleted queries the relationships whose lifetime, from the transaction which
+// created them to the one which deleted them, overlaps the range of revisions, rather than those
+// living at a single revision.
+func (pgd *pgDatastore) QueryTuplesIncludingDeleted(
+	ctx context.Context,
+	filter *v1.RelationshipFilter,
+	fromRevision, toRevision datastore.Revision,
+) (datastore.RelationshipIterator, error) {
+	if fromRevision.GreaterThan(toRevision) {
+		return nil, fmt.Errorf(errInvertedRevisionRange, fromRevision, toRevision)
+	}
+
+	// Deleted relationships are garbage collected once they fall out of the GC window.
+	for _, revision := range []datastore.Revision{fromRevision, toRevision} {
+		if err := pgd.CheckRevision(ctx, revision); err != nil {
+			return nil, err
+		}
+	}
+
+	reader := pgd.reader(buildLifetimeFilterForRevisions(fromRevision, toRevision))
+	return reader.QueryRelationships(ctx, filter, options.WithRevisionMetadata())
+}
+
 func (r *pgReader) QueryRelationships(
 	ctx context.Context,
 	filter *v1.RelationshipFilter,
 	opts ...options.QueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder := common.NewSchemaQueryFilterer(schema, r.filterer(queryTuples)).
-		FilterToResourceType(filter.ResourceType)
+	queryOpts := options.NewQueryOptionsWithOptions(opts...)
+	baseQuery := queryTuples
+	if queryOpts.IncludeRevisions {
+		baseQuery = queryTuplesWithRevisions
+	}
+
+	qBuilder := filterToRelationships(r.filterer(baseQuery), filter, queryOpts)
+	iter, err = r.querySplitter.SplitAndExecuteQuery(ctx, qBuilder, opts...)
+	return iter, r.rewriteQueryError(err)
+}
+
+// rewriteQueryError returns ErrQueryTimedOut if the error was caused by the query being canceled
+// by the statement timeout, and the error itself otherwise.
+func (r *pgReader) rewriteQueryError(err error) error {
+	var pgerr *pgconn.PgError
+	if r.statementTimeout > 0 && errors.As(err, &pgerr) && pgerr.SQLState() == pgQueryCanceled {
+		return datastore.NewQueryTimedOutErr(r.statementTimeout)
+	}
+	return err
+}
+
+func (r *pgReader) CountRelationships(ctx context.Context, filter *v1.RelationshipFilter) (uint64, error) {
+	ctx, span := tracer.Start(ctx, "CountRelationships")
+	defer span.End()
+
+	sql, args, err := filterToRelationships(r.filterer(countTuples), filter, &options.QueryOptions{}).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf(errUnableToCountTuples, err)
+	}
+
+	tx, txCleanup, err := r.txSource(ctx)
+	if err != nil {
+		return 0, fmt.Errorf(errUnableToCountTuples, err)
+	}
+	defer txCleanup(ctx)
+
+	var count int64
+	if err := tx.QueryRow(ctx, sql, args...).Scan(&count); err != nil {
+		return 0, fmt.Errorf(errUnableToCountTuples, err)
+	}
+
+	return uint64(count), nil
+}
+
+func (r *pgReader) CheckRelationshipsExist(ctx context.Context, tuples []*core.RelationTuple) (map[string]bool, error) {
+	qBuilder := common.NewSchemaQueryFilterer(schema, r.filterer(queryTuples))
+	return r.querySplitter.SplitAndCheckTuplesExist(ctx, qBuilder, tuples)
+}
+
+// reverseQueryFilterer filters a reverse query on the subject, and then on the resource type and
+// relation, so that along with the living window filtered on last, its predicates are in the
+// order of the columns of ix_relation_tuple_by_subject_living.
+func reverseQueryFilterer(subjectFilter *v1.SubjectFilter, queryOpts *options.ReverseQueryOptions) common.SchemaQueryFilterer {
+	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples).
+		FilterToReverseQuerySubject(subjectFilter, queryOpts)
+
+	if queryOpts.ResRelation != nil {
+		qBuilder = qBuilder.FilterToResourceRelation(
+			queryOpts.ResRelation.Namespace,
+			queryOpts.ResRelation.Relation,
+		)
+	}
+
+	return qBuilder.FilterToExcludedSubjects(queryOpts.ExcludedSubjects)
+}
+
+func filterToRelationships(baseQuery sq.SelectBuilder, filter *v1.RelationshipFilter, queryOpts *options.QueryOptions) common.SchemaQueryFilterer {
+	qBuilder := common.NewSchemaQueryFilterer(schema, baseQuery).
+		FilterToResourceTypes(append([]string{filter.ResourceType}, queryOpts.AdditionalResourceTypes...)...)
+
+	if queryOpts.IgnoreObjectIDCase {
+		qBuilder = qBuilder.IgnoringObjectIDCase()
+	}
 
 	if filter.OptionalResourceId != "" {
 		qBuilder = qBuilder.FilterToResourceID(filter.OptionalResourceId)
@@ -73,7 +192,7 @@ func (r *pgReader) QueryRelationships(
 		qBuilder = qBuilder.FilterToSubjectFilter(filter.OptionalSubjectFilter)
 	}
 
-	return r.querySplitter.SplitAndExecuteQuery(ctx, qBuilder, opts...)
+	return qBuilder
 }
 
 func (r *pgReader) ReverseQueryRelationships(
@@ -81,21 +200,14 @@ func (r *pgReader) ReverseQueryRelationships(
 	subjectFilter *v1.SubjectFilter,
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder := common.NewSchemaQueryFilterer(schema, r.filterer(queryTuples)).
-		FilterToSubjectFilter(subjectFilter)
-
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
+	qBuilder := reverseQueryFilterer(subjectFilter, queryOpts).FilterWith(r.filterer)
 
-	if queryOpts.ResRelation != nil {
-		qBuilder = qBuilder.
-			FilterToResourceType(queryOpts.ResRelation.Namespace).
-			FilterToRelation(queryOpts.ResRelation.Relation)
-	}
-
-	return r.querySplitter.SplitAndExecuteQuery(ctx,
+	iter, err = r.querySplitter.SplitAndExecuteQuery(ctx,
 		qBuilder,
 		options.WithLimit(queryOpts.ReverseLimit),
 	)
+	return iter, r.rewriteQueryError(err)
 }
 
 func (r *pgReader) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
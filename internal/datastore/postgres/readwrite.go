@@ -9,17 +9,23 @@ import (
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/jackc/pgx/v4"
 	"github.com/jzelinskie/stringz"
+	"github.com/scylladb/go-set/strset"
 	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/namespace"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 const (
 	errUnableToWriteConfig         = "unable to write namespace config: %w"
 	errUnableToDeleteConfig        = "unable to delete namespace config: %w"
+	errUnableToRenameConfig        = "unable to rename namespace config: %w"
+	errUnableToCopyConfig          = "unable to copy namespace config: %w"
 	errUnableToWriteRelationships  = "unable to write relationships: %w"
 	errUnableToDeleteRelationships = "unable to delete relationships: %w"
 )
@@ -43,6 +49,8 @@ var (
 		colUsersetObjectID,
 		colUsersetRelation,
 		colCreatedTxn,
+		colCaveatName,
+		colCaveatContext,
 	)
 
 	deleteTuple = psql.Update(tableTuple).Where(sq.Eq{colDeletedTxn: liveDeletedTxnID})
@@ -53,6 +61,16 @@ type pgReadWriteTXN struct {
 	ctx      context.Context
 	tx       pgx.Tx
 	newTxnID uint64
+
+	nullSubjectRelation common.NullSubjectRelation
+
+	effectiveTouches uint64
+}
+
+// EffectiveTouches returns the number of touches written by the transaction which created a
+// relationship.
+func (rwt *pgReadWriteTXN) EffectiveTouches() uint64 {
+	return rwt.effectiveTouches
 }
 
 func (rwt *pgReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpdate) error {
@@ -64,10 +82,24 @@ func (rwt *pgReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpdate
 
 	deleteClauses := sq.Or{}
 
+	// Touches of relationships which are already living are skipped, so that they neither
+	// create a new row nor show up as changes in watch, unless the same batch deletes them.
+	living, err := rwt.livingTouchedRelationships(ctx, mutations)
+	if err != nil {
+		return fmt.Errorf(errUnableToWriteRelationships, err)
+	}
+
 	// Process the actual updates
 	for _, mut := range mutations {
 		rel := mut.Relationship
 
+		if mut.Operation == v1.RelationshipUpdate_OPERATION_TOUCH {
+			if living.Has(tuple.String(tuple.FromRelationship(rel))) {
+				continue
+			}
+			rwt.effectiveTouches++
+		}
+
 		if mut.Operation == v1.RelationshipUpdate_OPERATION_TOUCH || mut.Operation == v1.RelationshipUpdate_OPERATION_DELETE {
 			deleteClauses = append(deleteClauses, exactRelationshipClause(rel))
 		}
@@ -81,6 +113,8 @@ func (rwt *pgReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpdate
 				rel.Subject.Object.ObjectId,
 				stringz.DefaultEmpty(rel.Subject.OptionalRelation, datastore.Ellipsis),
 				rwt.newTxnID,
+				nil,
+				nil,
 			)
 			bulkWriteHasValues = true
 		}
@@ -111,7 +145,7 @@ func (rwt *pgReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpdate
 	return nil
 }
 
-func (rwt *pgReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter) error {
+func (rwt *pgReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter) (uint64, error) {
 	ctx, span := tracer.Start(datastore.SeparateContextWithTracing(rwt.ctx), "DeleteRelationships")
 	defer span.End()
 
@@ -147,14 +181,15 @@ func (rwt *pgReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter) er
 
 	sql, args, err := query.ToSql()
 	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+		return 0, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
 
-	if _, err := rwt.tx.Exec(ctx, sql, args...); err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+	result, err := rwt.tx.Exec(ctx, sql, args...)
+	if err != nil {
+		return 0, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
 
-	return nil
+	return uint64(result.RowsAffected()), nil
 }
 
 func (rwt *pgReadWriteTXN) WriteNamespaces(newConfigs ...*core.NamespaceDefinition) error {
@@ -250,6 +285,274 @@ func (rwt *pgReadWriteTXN) DeleteNamespace(nsName string) error {
 	return nil
 }
 
+func (rwt *pgReadWriteTXN) RenameNamespace(oldName, newName string) error {
+	ctx, span := tracer.Start(datastore.SeparateContextWithTracing(rwt.ctx), "RenameNamespace", trace.WithAttributes(
+		attribute.String("oldName", oldName),
+		attribute.String("newName", newName),
+	))
+	defer span.End()
+
+	baseQuery := readNamespace.Where(sq.Eq{colDeletedTxn: liveDeletedTxnID})
+	existing, createdAt, err := loadNamespace(ctx, oldName, rwt.tx, baseQuery)
+	switch {
+	case errors.As(err, &datastore.ErrNamespaceNotFound{}):
+		return err
+	case err == nil:
+		break
+	default:
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	if err := ensureNamespaceMissing(ctx, newName, rwt.tx, baseQuery); err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	existing.Name = newName
+	namespace.RenameTypeReferences(existing, oldName, newName)
+	namespace.ClearDefinitionSource(existing)
+	serialized, err := proto.Marshal(existing)
+	if err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	delSQL, delArgs, err := deleteNamespace.
+		Set(colDeletedTxn, rwt.newTxnID).
+		Where(sq.Eq{colNamespace: oldName, colCreatedTxn: createdAt}).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	if _, err := rwt.tx.Exec(ctx, delSQL, delArgs...); err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	writeSQL, writeArgs, err := writeNamespace.Values(newName, serialized, rwt.newTxnID).ToSql()
+	if err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	if _, err := rwt.tx.Exec(ctx, writeSQL, writeArgs...); err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	if err := common.RenameNamespaceReferences(ctx, rwt, oldName, newName); err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	// Copy every live tuple which references the old namespace under the new name, and then
+	// retire the originals, so that watchers observe the rename as deletes and touches.
+	referencesOld := sq.Or{
+		sq.Eq{colNamespace: oldName},
+		sq.Eq{colUsersetNamespace: oldName},
+	}
+
+	copyTuples := sq.Select().
+		Column(renamedColumn(colNamespace, oldName, newName)).
+		Columns(colObjectID, colRelation).
+		Column(renamedColumn(colUsersetNamespace, oldName, newName)).
+		Columns(colUsersetObjectID, colUsersetRelation).
+		Column(sq.Expr("?::bigint", rwt.newTxnID)).
+		Columns(colCaveatName, colCaveatContext).
+		From(tableTuple).
+		Where(sq.Eq{colDeletedTxn: liveDeletedTxnID}).
+		Where(referencesOld)
+
+	copySQL, copyArgs, err := writeTuple.Select(copyTuples).ToSql()
+	if err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	if _, err := rwt.tx.Exec(ctx, copySQL, copyArgs...); err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	retireSQL, retireArgs, err := deleteNamespaceTuples.
+		Set(colDeletedTxn, rwt.newTxnID).
+		Where(referencesOld).
+		ToSql()
+	if err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	if _, err := rwt.tx.Exec(ctx, retireSQL, retireArgs...); err != nil {
+		return fmt.Errorf(errUnableToRenameConfig, err)
+	}
+
+	return nil
+}
+
+func (rwt *pgReadWriteTXN) CopyNamespace(sourceName, destName string, copyTuples bool) (uint64, error) {
+	ctx, span := tracer.Start(datastore.SeparateContextWithTracing(rwt.ctx), "CopyNamespace", trace.WithAttributes(
+		attribute.String("sourceName", sourceName),
+		attribute.String("destName", destName),
+		attribute.Bool("copyTuples", copyTuples),
+	))
+	defer span.End()
+
+	baseQuery := readNamespace.Where(sq.Eq{colDeletedTxn: liveDeletedTxnID})
+	existing, _, err := loadNamespace(ctx, sourceName, rwt.tx, baseQuery)
+	switch {
+	case errors.As(err, &datastore.ErrNamespaceNotFound{}):
+		return 0, err
+	case err == nil:
+		break
+	default:
+		return 0, fmt.Errorf(errUnableToCopyConfig, err)
+	}
+
+	if err := ensureNamespaceMissing(ctx, destName, rwt.tx, baseQuery); err != nil {
+		return 0, fmt.Errorf(errUnableToCopyConfig, err)
+	}
+
+	existing.Name = destName
+	namespace.ClearDefinitionSource(existing)
+	serialized, err := proto.Marshal(existing)
+	if err != nil {
+		return 0, fmt.Errorf(errUnableToCopyConfig, err)
+	}
+
+	writeSQL, writeArgs, err := writeNamespace.Values(destName, serialized, rwt.newTxnID).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf(errUnableToCopyConfig, err)
+	}
+
+	if _, err := rwt.tx.Exec(ctx, writeSQL, writeArgs...); err != nil {
+		return 0, fmt.Errorf(errUnableToCopyConfig, err)
+	}
+
+	if !copyTuples {
+		return 0, nil
+	}
+
+	copyTuplesQuery := sq.Select().
+		Column(sq.Expr("?", destName)).
+		Columns(colObjectID, colRelation, colUsersetNamespace, colUsersetObjectID, colUsersetRelation).
+		Column(sq.Expr("?::bigint", rwt.newTxnID)).
+		Columns(colCaveatName, colCaveatContext).
+		From(tableTuple).
+		Where(sq.Eq{colDeletedTxn: liveDeletedTxnID}).
+		Where(sq.Eq{colNamespace: sourceName})
+
+	copySQL, copyArgs, err := writeTuple.Select(copyTuplesQuery).ToSql()
+	if err != nil {
+		return 0, fmt.Errorf(errUnableToCopyConfig, err)
+	}
+
+	copied, err := rwt.tx.Exec(ctx, copySQL, copyArgs...)
+	if err != nil {
+		return 0, fmt.Errorf(errUnableToCopyConfig, err)
+	}
+
+	numCopied := copied.RowsAffected()
+	span.SetAttributes(attribute.Int64("copiedTuples", numCopied))
+
+	return uint64(numCopied), nil
+}
+
+func ensureNamespaceMissing(ctx context.Context, nsName string, tx pgx.Tx, baseQuery sq.SelectBuilder) error {
+	_, _, err := loadNamespace(ctx, nsName, tx, baseQuery)
+	switch {
+	case errors.As(err, &datastore.ErrNamespaceNotFound{}):
+		return nil
+	case err == nil:
+		return datastore.NewNamespaceAlreadyExistsErr(nsName)
+	default:
+		return err
+	}
+}
+
+func renamedColumn(column, oldName, newName string) sq.Sqlizer {
+	return sq.Expr(fmt.Sprintf("CASE WHEN %s = ? THEN ? ELSE %s END", column, column), oldName, newName)
+}
+
+// livingTouchedRelationships returns the keys of the relationships touched by the mutations which
+// are already living without a caveat and which are not deleted by the mutations.
+func (rwt *pgReadWriteTXN) livingTouchedRelationships(ctx context.Context, mutations []*v1.RelationshipUpdate) (*strset.Set, error) {
+	living := strset.New()
+	deleted := strset.New()
+	touchClauses := sq.Or{}
+	for _, mut := range mutations {
+		switch mut.Operation {
+		case v1.RelationshipUpdate_OPERATION_TOUCH:
+			touchClauses = append(touchClauses, touchedRelationshipClause(mut.Relationship))
+		case v1.RelationshipUpdate_OPERATION_DELETE:
+			deleted.Add(tuple.String(tuple.FromRelationship(mut.Relationship)))
+		}
+	}
+
+	if len(touchClauses) == 0 {
+		return living, nil
+	}
+
+	sql, args, err := queryTuples.Where(sq.Eq{colDeletedTxn: liveDeletedTxnID}).Where(touchClauses).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := rwt.tx.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		tpl := &core.RelationTuple{
+			ResourceAndRelation: &core.ObjectAndRelation{},
+			Subject:             &core.ObjectAndRelation{},
+		}
+		var subjectRelation *string
+		var caveatName *string
+		var caveatContext []byte
+		if err := rows.Scan(
+			&tpl.ResourceAndRelation.Namespace,
+			&tpl.ResourceAndRelation.ObjectId,
+			&tpl.ResourceAndRelation.Relation,
+			&tpl.Subject.Namespace,
+			&tpl.Subject.ObjectId,
+			&subjectRelation,
+			&caveatName,
+			&caveatContext,
+		); err != nil {
+			return nil, err
+		}
+
+		tpl.Subject.Relation, err = rwt.nullSubjectRelation.SubjectRelationFromColumn(ctx, tpl, subjectRelation)
+		if err != nil {
+			return nil, err
+		}
+
+		// Touches write no caveat, so a touch of a caveated relationship must still replace it.
+		if caveatName != nil {
+			continue
+		}
+
+		if key := tuple.String(tpl); !deleted.Has(key) {
+			living.Add(key)
+		}
+	}
+
+	return living, rows.Err()
+}
+
+// touchedRelationshipClause matches the rows which may hold the given relationship, including
+// those whose subject relation is NULL when the relationship's subject is an object.
+func touchedRelationshipClause(r *v1.Relationship) sq.Sqlizer {
+	subjectRelation := stringz.DefaultEmpty(r.Subject.OptionalRelation, datastore.Ellipsis)
+
+	clause := sq.And{sq.Eq{
+		colNamespace:        r.Resource.ObjectType,
+		colObjectID:         r.Resource.ObjectId,
+		colRelation:         r.Relation,
+		colUsersetNamespace: r.Subject.Object.ObjectType,
+		colUsersetObjectID:  r.Subject.Object.ObjectId,
+	}}
+	if subjectRelation == datastore.Ellipsis {
+		return append(clause, sq.Or{sq.Eq{colUsersetRelation: subjectRelation}, sq.Eq{colUsersetRelation: nil}})
+	}
+	return append(clause, sq.Eq{colUsersetRelation: subjectRelation})
+}
+
 func exactRelationshipClause(r *v1.Relationship) sq.Eq {
 	return sq.Eq{
 		colNamespace:        r.Resource.ObjectType,
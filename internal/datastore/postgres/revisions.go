@@ -97,6 +97,25 @@ func (pgd *pgDatastore) CheckRevision(ctx context.Context, revision datastore.Re
 	return nil
 }
 
+// MinWatchRevision returns the latest transaction committed before the garbage collection window,
+// which is the newest transaction whose deleted relationships may have been garbage collected.
+func (pgd *pgDatastore) MinWatchRevision(ctx context.Context) (datastore.Revision, error) {
+	ctx, span := tracer.Start(ctx, "MinWatchRevision")
+	defer span.End()
+
+	now, err := pgd.Now(ctx)
+	if err != nil {
+		return datastore.NoRevision, fmt.Errorf(errRevision, err)
+	}
+
+	watermark, err := pgd.TxIDBefore(ctx, now.Add(-pgd.gcWindow))
+	if err != nil {
+		return datastore.NoRevision, fmt.Errorf(errRevision, err)
+	}
+
+	return revisionFromTransaction(watermark), nil
+}
+
 func (pgd *pgDatastore) loadRevision(ctx context.Context) (uint64, error) {
 	ctx, span := tracer.Start(ctx, "loadRevision")
 	defer span.End()
@@ -122,6 +141,15 @@ func revisionFromTransaction(txID uint64) datastore.Revision {
 	return decimal.NewFromInt(int64(txID))
 }
 
+// revisionFromTupleTransaction converts the transaction which created or deleted a tuple to a
+// revision, which is NoRevision for the deleted transaction of a living tuple.
+func revisionFromTupleTransaction(txID uint64) datastore.Revision {
+	if txID == liveDeletedTxnID {
+		return datastore.NoRevision
+	}
+	return revisionFromTransaction(txID)
+}
+
 func transactionFromRevision(revision datastore.Revision) uint64 {
 	return uint64(revision.IntPart())
 }
@@ -133,3 +161,5 @@ func createNewTransaction(ctx context.Context, tx pgx.Tx) (newTxnID uint64, err
 	err = tx.QueryRow(ctx, createTxn).Scan(&newTxnID)
 	return
 }
+
+var _ datastore.WatchRevisionReporter = &pgDatastore{}
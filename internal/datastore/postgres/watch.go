@@ -3,19 +3,25 @@ package postgres
 import (
 	"context"
 	"errors"
-	"time"
+	"sort"
 
 	sq "github.com/Masterminds/squirrel"
+	"github.com/benbjohnson/clock"
+	"github.com/jackc/pgx/v4"
+	"github.com/rs/zerolog/log"
+	"golang.org/x/sync/errgroup"
+	"google.golang.org/protobuf/proto"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
 )
 
-const (
-	watchSleep = 100 * time.Millisecond
-)
+// watchNotifyChannel is the channel on which the relation_tuple_transaction_notify trigger
+// notifies of each new transaction.
+const watchNotifyChannel = "relation_tuple_transaction"
 
 var queryChanged = psql.Select(
 	colNamespace,
@@ -26,26 +32,79 @@ var queryChanged = psql.Select(
 	colUsersetRelation,
 	colCreatedTxn,
 	colDeletedTxn,
+	colCaveatName,
+	colCaveatContext,
 ).From(tableTuple)
 
-func (pgd *pgDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (pgd *pgDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	watchOpts := options.NewWatchOptionsWithOptions(opts...)
 	updates := make(chan *datastore.RevisionChanges, pgd.watchBufferLength)
 	errs := make(chan error, 1)
 
+	// Changes are loaded and waited for with a context canceled when the datastore starts
+	// closing, but delivered with the caller's, so that those already loaded are delivered.
+	watchCtx, watchDone := pgd.watchShutdown.Start(ctx)
+
 	go func() {
 		defer close(updates)
 		defer close(errs)
+		defer watchDone()
 
+		// Start listening before loading any changes, so that no transaction committed after
+		// the first load can be missed.
+		var listener *pgx.Conn
+		if pgd.watchNotifications {
+			listener = pgd.listenForTransactions(watchCtx)
+		}
+		defer func() {
+			if listener != nil {
+				closeListener(listener)
+			}
+		}()
+
+		minRevision, err := pgd.MinWatchRevision(ctx)
+		if err != nil {
+			errs <- err
+			return
+		}
+		if afterRevision.LessThan(minRevision) {
+			errs <- datastore.NewWatchRevisionTooOldErr(afterRevision, minRevision)
+			return
+		}
+
+		if watchOpts.EmitCheckpoint {
+			head, err := pgd.HeadRevision(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			checkpoint := &datastore.RevisionChanges{Revision: head, IsCheckpoint: true}
+			if err := common.SendRevisionChanges(ctx, updates, checkpoint, watchOpts); err != nil {
+				errs <- err
+				return
+			}
+		}
+
+		poller := common.NewWatchPoller(pgd.watchPolling, clock.New())
 		currentTxn := transactionFromRevision(afterRevision)
 
+		// The shared head revision may predate a transaction committed before listening started,
+		// or one that has since been notified of, so those loads must not reuse it. Transactions
+		// committed in between are notified of once the watch next waits.
+		requireFresh := listener != nil
+
 		for {
 			var stagedUpdates []*datastore.RevisionChanges
 			var err error
-			stagedUpdates, currentTxn, err = pgd.loadChanges(ctx, currentTxn)
+			stagedUpdates, currentTxn, err = pgd.loadChangesWithTimeout(watchCtx, currentTxn, requireFresh, watchOpts)
 			if err != nil {
-				if errors.Is(ctx.Err(), context.Canceled) {
+				switch {
+				case pgd.watchShutdown.Closing():
+					errs <- datastore.NewWatchClosingErr(revisionFromTransaction(currentTxn))
+				case errors.Is(ctx.Err(), context.Canceled):
 					errs <- datastore.NewWatchCanceledErr()
-				} else {
+				default:
 					errs <- err
 				}
 				return
@@ -53,23 +112,35 @@ func (pgd *pgDatastore) Watch(ctx context.Context, afterRevision datastore.Revis
 
 			// Write the staged updates to the channel
 			for _, changeToWrite := range stagedUpdates {
-				select {
-				case updates <- changeToWrite:
-				default:
-					errs <- datastore.NewWatchDisconnectedErr()
+				if err := common.SendRevisionChanges(ctx, updates, changeToWrite, watchOpts); err != nil {
+					errs <- err
 					return
 				}
 			}
 
-			// If there were no changes, sleep a bit
-			if len(stagedUpdates) == 0 {
-				sleep := time.NewTimer(watchSleep)
+			// If there were no changes, wait for a notification of a new transaction, or for the
+			// next poll if notifications are unavailable
+			if len(stagedUpdates) == 0 && listener != nil {
+				_, err := listener.WaitForNotification(watchCtx)
+				if err == nil {
+					requireFresh = true
+					continue
+				}
 
-				select {
-				case <-sleep.C:
-					break
-				case <-ctx.Done():
-					errs <- datastore.NewWatchCanceledErr()
+				if watchCtx.Err() != nil {
+					errs <- pgd.watchStoppedErr(currentTxn)
+					return
+				}
+
+				log.Ctx(ctx).Warn().Err(err).Msg("lost watch notification connection, falling back to polling")
+				closeListener(listener)
+				listener = nil
+			}
+
+			requireFresh = false
+			if listener == nil {
+				if err := poller.Wait(watchCtx, len(stagedUpdates) > 0); err != nil {
+					errs <- pgd.watchStoppedErr(currentTxn)
 					return
 				}
 			}
@@ -79,20 +150,179 @@ func (pgd *pgDatastore) Watch(ctx context.Context, afterRevision datastore.Revis
 	return updates, errs
 }
 
+// watchStoppedErr returns the error with which a watch, which has delivered the changes through
+// the transaction, stops once its context is done: ErrWatchClosing if the datastore is closing,
+// and ErrWatchCanceled otherwise.
+func (pgd *pgDatastore) watchStoppedErr(currentTxn uint64) error {
+	if pgd.watchShutdown.Closing() {
+		return datastore.NewWatchClosingErr(revisionFromTransaction(currentTxn))
+	}
+	return datastore.NewWatchCanceledErr()
+}
+
+// listenForTransactions opens a dedicated connection, outside of the pool, listening for
+// notifications of new transactions, returning nil if one could not be established.
+func (pgd *pgDatastore) listenForTransactions(ctx context.Context) *pgx.Conn {
+	conn, err := pgx.Connect(ctx, pgd.dburl)
+	if err != nil {
+		log.Ctx(ctx).Warn().Err(err).Msg("unable to open watch notification connection, falling back to polling")
+		return nil
+	}
+
+	if _, err := conn.Exec(ctx, "LISTEN "+watchNotifyChannel); err != nil {
+		closeListener(conn)
+		log.Ctx(ctx).Warn().Err(err).Msg("unable to listen for watch notifications, falling back to polling")
+		return nil
+	}
+
+	return conn
+}
+
+func closeListener(conn *pgx.Conn) {
+	if err := conn.Close(context.Background()); err != nil {
+		log.Warn().Err(err).Msg("error closing watch notification connection")
+	}
+}
+
+// loadChangesWithTimeout loads the changes after the revision, failing with ErrWatchTimedOut if
+// they could not be loaded within the configured watch query timeout.
+func (pgd *pgDatastore) loadChangesWithTimeout(
+	ctx context.Context,
+	afterRevision uint64,
+	requireFresh bool,
+	watchOpts *options.WatchOptions,
+) ([]*datastore.RevisionChanges, uint64, error) {
+	if pgd.watchQueryTimeout == 0 {
+		return pgd.loadChanges(ctx, afterRevision, requireFresh, watchOpts)
+	}
+
+	queryCtx, cancel := context.WithTimeout(ctx, pgd.watchQueryTimeout)
+	defer cancel()
+
+	changes, newRevision, err := pgd.loadChanges(queryCtx, afterRevision, requireFresh, watchOpts)
+	if err != nil && ctx.Err() == nil && errors.Is(queryCtx.Err(), context.DeadlineExceeded) {
+		return nil, afterRevision, datastore.NewWatchTimedOutErr(pgd.watchQueryTimeout)
+	}
+
+	return changes, newRevision, err
+}
+
+// loadChanges loads the changes after the revision, up to the head revision shared between the
+// watches of the datastore, or a freshly loaded one if requireFresh is set. Ranges wider than the
+// watch chunk size are loaded in chunks, in which case the returned revision, through which the
+// changes were loaded, may precede the head revision.
 func (pgd *pgDatastore) loadChanges(
 	ctx context.Context,
 	afterRevision uint64,
-) (changes []*datastore.RevisionChanges, newRevision uint64, err error) {
-	newRevision, err = pgd.loadRevision(ctx)
+	requireFresh bool,
+	watchOpts *options.WatchOptions,
+) ([]*datastore.RevisionChanges, uint64, error) {
+	newRevision, err := pgd.headRevisions.HeadRevision(ctx, requireFresh)
 	if err != nil {
-		return
+		return nil, afterRevision, err
 	}
 
-	if newRevision == afterRevision {
-		return
+	// The shared head revision can predate the revision a watch started after
+	if newRevision <= afterRevision {
+		return nil, afterRevision, nil
+	}
+
+	if pgd.watchChunkSize == 0 || newRevision-afterRevision <= pgd.watchChunkSize {
+		changes, err := pgd.loadChangesInRange(ctx, afterRevision, newRevision, watchOpts)
+		if err != nil {
+			return nil, afterRevision, err
+		}
+		return changes, newRevision, nil
+	}
+
+	return pgd.loadChangesInChunks(ctx, afterRevision, newRevision, watchOpts)
+}
+
+// loadChangesInChunks loads the changes after the revision, through newRevision, in chunks of
+// the watch chunk size, loading up to the watch chunk concurrency of them in parallel. To bound
+// the changes held in memory, it returns after the first batch of chunks with any changes, along
+// with the revision through which the changes were loaded.
+func (pgd *pgDatastore) loadChangesInChunks(
+	ctx context.Context,
+	afterRevision uint64,
+	newRevision uint64,
+	watchOpts *options.WatchOptions,
+) ([]*datastore.RevisionChanges, uint64, error) {
+	concurrency := int(pgd.watchChunkConcurrency)
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	for afterRevision < newRevision {
+		// Each chunk loads the changes after its start, through its end
+		var chunkStarts []uint64
+		batchEnd := afterRevision
+		for len(chunkStarts) < concurrency && batchEnd < newRevision {
+			chunkStarts = append(chunkStarts, batchEnd)
+			batchEnd += pgd.watchChunkSize
+			if batchEnd > newRevision {
+				batchEnd = newRevision
+			}
+		}
+
+		chunkChanges := make([][]*datastore.RevisionChanges, len(chunkStarts))
+		g, chunkCtx := errgroup.WithContext(ctx)
+		for i, chunkStart := range chunkStarts {
+			i, chunkStart := i, chunkStart
+			chunkEnd := batchEnd
+			if i+1 < len(chunkStarts) {
+				chunkEnd = chunkStarts[i+1]
+			}
+
+			g.Go(func() error {
+				changes, err := pgd.loadChangesInRange(chunkCtx, chunkStart, chunkEnd, watchOpts)
+				chunkChanges[i] = changes
+				return err
+			})
+		}
+		if err := g.Wait(); err != nil {
+			return nil, afterRevision, err
+		}
+
+		// The chunks partition the revisions, so concatenating them in order keeps the changes
+		// ordered by revision.
+		var changes []*datastore.RevisionChanges
+		for _, chunk := range chunkChanges {
+			changes = append(changes, chunk...)
+		}
+
+		afterRevision = batchEnd
+		if len(changes) > 0 {
+			return changes, afterRevision, nil
+		}
 	}
 
-	sql, args, err := queryChanged.Where(sq.Or{
+	return nil, afterRevision, nil
+}
+
+// loadChangesInRange loads the changes after the revision, through newRevision, with a single
+// query, which is retried if it fails with a transient error.
+func (pgd *pgDatastore) loadChangesInRange(
+	ctx context.Context,
+	afterRevision uint64,
+	newRevision uint64,
+	watchOpts *options.WatchOptions,
+) (changes []*datastore.RevisionChanges, err error) {
+	err = pgd.queryRetries.Retry(ctx, func(ctx context.Context) error {
+		var err error
+		changes, err = pgd.queryChangesInRange(ctx, afterRevision, newRevision, watchOpts)
+		return err
+	})
+	return
+}
+
+func (pgd *pgDatastore) queryChangesInRange(
+	ctx context.Context,
+	afterRevision uint64,
+	newRevision uint64,
+	watchOpts *options.WatchOptions,
+) (changes []*datastore.RevisionChanges, err error) {
+	query := queryChanged.Where(sq.Or{
 		sq.And{
 			sq.Gt{colCreatedTxn: afterRevision},
 			sq.LtOrEq{colCreatedTxn: newRevision},
@@ -101,7 +331,12 @@ func (pgd *pgDatastore) loadChanges(
 			sq.Gt{colDeletedTxn: afterRevision},
 			sq.LtOrEq{colDeletedTxn: newRevision},
 		},
-	}).ToSql()
+	})
+	if len(watchOpts.Namespaces) > 0 {
+		query = query.Where(sq.Eq{colNamespace: watchOpts.Namespaces})
+	}
+
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return
 	}
@@ -123,22 +358,43 @@ func (pgd *pgDatastore) loadChanges(
 			Subject:             &core.ObjectAndRelation{},
 		}
 
+		var subjectRelation *string
 		var createdTxn uint64
 		var deletedTxn uint64
+		var caveatName *string
+		var caveatContext []byte
 		err = rows.Scan(
 			&nextTuple.ResourceAndRelation.Namespace,
 			&nextTuple.ResourceAndRelation.ObjectId,
 			&nextTuple.ResourceAndRelation.Relation,
 			&nextTuple.Subject.Namespace,
 			&nextTuple.Subject.ObjectId,
-			&nextTuple.Subject.Relation,
+			&subjectRelation,
 			&createdTxn,
 			&deletedTxn,
+			&caveatName,
+			&caveatContext,
 		)
 		if err != nil {
 			return
 		}
 
+		nextTuple.Subject.Relation, err = pgd.nullSubjectRelation.SubjectRelationFromColumn(ctx, nextTuple, subjectRelation)
+		if err != nil {
+			return
+		}
+
+		nextTuple.Caveat, err = common.CaveatFromColumns(caveatName, caveatContext)
+		if err != nil {
+			return
+		}
+
+		// A tuple that was created and deleted within the same transaction never
+		// existed outside of it, so it does not contribute a change.
+		if createdTxn == deletedTxn {
+			continue
+		}
+
 		if createdTxn > afterRevision && createdTxn <= newRevision {
 			stagedChanges.AddChange(ctx, revisionFromTransaction(createdTxn), nextTuple, core.RelationTupleUpdate_TOUCH)
 		}
@@ -155,3 +411,192 @@ func (pgd *pgDatastore) loadChanges(
 
 	return
 }
+
+var queryNamespacesChanged = psql.Select(
+	colNamespace,
+	colConfig,
+	colCreatedTxn,
+	colDeletedTxn,
+).From(tableNamespace)
+
+func (pgd *pgDatastore) WatchNamespaces(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.NamespaceChange, <-chan error) {
+	updates := make(chan *datastore.NamespaceChange, pgd.watchBufferLength)
+	errs := make(chan error, 1)
+
+	watchCtx, watchDone := pgd.watchShutdown.Start(ctx)
+
+	go func() {
+		defer close(updates)
+		defer close(errs)
+		defer watchDone()
+
+		minRevision, err := pgd.MinWatchRevision(ctx)
+		if err != nil {
+			errs <- err
+			return
+		}
+		if afterRevision.LessThan(minRevision) {
+			errs <- datastore.NewWatchRevisionTooOldErr(afterRevision, minRevision)
+			return
+		}
+
+		poller := common.NewWatchPoller(pgd.watchPolling, clock.New())
+		currentTxn := transactionFromRevision(afterRevision)
+
+		for {
+			newTxn, err := pgd.headRevisions.HeadRevision(watchCtx, false)
+			if err != nil {
+				if watchCtx.Err() != nil {
+					errs <- pgd.watchStoppedErr(currentTxn)
+				} else {
+					errs <- err
+				}
+				return
+			}
+
+			var stagedChanges []*datastore.NamespaceChange
+			if newTxn > currentTxn {
+				stagedChanges, err = pgd.loadNamespaceChangesInRange(watchCtx, currentTxn, newTxn)
+				if err != nil {
+					if watchCtx.Err() != nil {
+						errs <- pgd.watchStoppedErr(currentTxn)
+					} else {
+						errs <- err
+					}
+					return
+				}
+				currentTxn = newTxn
+			}
+
+			// A consumer which stops reading must not hold up closing the datastore. The changes
+			// of a revision interrupted while closing may have been partially delivered, so the
+			// watch must then be resumed before it.
+			for _, changeToWrite := range stagedChanges {
+				select {
+				case updates <- changeToWrite:
+				case <-watchCtx.Done():
+					errs <- pgd.watchStoppedErr(transactionFromRevision(changeToWrite.Revision) - 1)
+					return
+				}
+			}
+
+			if err := poller.Wait(watchCtx, len(stagedChanges) > 0); err != nil {
+				errs <- pgd.watchStoppedErr(currentTxn)
+				return
+			}
+		}
+	}()
+
+	return updates, errs
+}
+
+// loadNamespaceChangesInRange loads the namespaces written or deleted after the revision, through
+// newRevision, ordered by revision and then by name. The query is retried if it fails with a
+// transient error.
+func (pgd *pgDatastore) loadNamespaceChangesInRange(
+	ctx context.Context,
+	afterRevision uint64,
+	newRevision uint64,
+) (changes []*datastore.NamespaceChange, err error) {
+	err = pgd.queryRetries.Retry(ctx, func(ctx context.Context) error {
+		var err error
+		changes, err = pgd.queryNamespaceChangesInRange(ctx, afterRevision, newRevision)
+		return err
+	})
+	return
+}
+
+func (pgd *pgDatastore) queryNamespaceChangesInRange(
+	ctx context.Context,
+	afterRevision uint64,
+	newRevision uint64,
+) ([]*datastore.NamespaceChange, error) {
+	sql, args, err := queryNamespacesChanged.Where(sq.Or{
+		sq.And{
+			sq.Gt{colCreatedTxn: afterRevision},
+			sq.LtOrEq{colCreatedTxn: newRevision},
+		},
+		sq.And{
+			sq.Gt{colDeletedTxn: afterRevision},
+			sq.LtOrEq{colDeletedTxn: newRevision},
+		},
+	}).ToSql()
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := pgd.dbpool.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type namespaceTxn struct {
+		name string
+		txn  uint64
+	}
+
+	written := make(map[namespaceTxn]*core.NamespaceDefinition)
+	var deleted []namespaceTxn
+	for rows.Next() {
+		var name string
+		var config []byte
+		var createdTxn uint64
+		var deletedTxn uint64
+		if err := rows.Scan(&name, &config, &createdTxn, &deletedTxn); err != nil {
+			return nil, err
+		}
+
+		// A namespace written more than once within the same transaction is only
+		// reported with its final definition.
+		if createdTxn == deletedTxn {
+			continue
+		}
+
+		if createdTxn > afterRevision && createdTxn <= newRevision {
+			loaded := &core.NamespaceDefinition{}
+			if err := proto.Unmarshal(config, loaded); err != nil {
+				return nil, err
+			}
+			written[namespaceTxn{name, createdTxn}] = loaded
+		}
+
+		if deletedTxn > afterRevision && deletedTxn <= newRevision {
+			deleted = append(deleted, namespaceTxn{name, deletedTxn})
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	changes := make([]*datastore.NamespaceChange, 0, len(written)+len(deleted))
+	for key, definition := range written {
+		changes = append(changes, &datastore.NamespaceChange{
+			Revision:   revisionFromTransaction(key.txn),
+			Name:       key.name,
+			Definition: definition,
+		})
+	}
+
+	// Writing a namespace replaces its previous row, which is therefore not a deletion
+	for _, key := range deleted {
+		if _, ok := written[key]; ok {
+			continue
+		}
+		changes = append(changes, &datastore.NamespaceChange{
+			Revision: revisionFromTransaction(key.txn),
+			Name:     key.name,
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if !changes[i].Revision.Equal(changes[j].Revision) {
+			return changes[i].Revision.LessThan(changes[j].Revision)
+		}
+		return changes[i].Name < changes[j].Name
+	})
+
+	return changes, nil
+}
+
+var _ datastore.NamespaceWatcher = &pgDatastore{}
@@ -7,6 +7,7 @@ import (
 	"sync"
 
 	"github.com/dustin/go-humanize"
+	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
 	"github.com/shopspring/decimal"
 	"golang.org/x/sync/singleflight"
@@ -17,20 +18,42 @@ import (
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
+// DefaultNamespaceCacheConfig is the configuration of the namespace cache used when none is
+// given to NewCachingDatastoreProxy.
+var DefaultNamespaceCacheConfig = cache.Config{
+	NumCounters: 1e4,     // number of keys to track frequency of (10k).
+	MaxCost:     1 << 24, // maximum cost of cache (16MB).
+	BufferItems: 64,      // number of keys per Get buffer.
+}
+
+const (
+	prometheusNamespace = "spicedb"
+	prometheusSubsystem = "namespace_cache"
+)
+
 // NewCachingDatastoreProxy creates a new datastore proxy which caches namespace definitions that
 // are loaded at specific datastore revisions.
+//
+// If metrics are enabled in the cache config, the hits, misses and costs of the cache are
+// exported to prometheus until the proxy is closed.
 func NewCachingDatastoreProxy(
 	delegate datastore.Datastore,
 	cacheConfig *cache.Config,
 ) (datastore.Datastore, error) {
 	if cacheConfig == nil {
-		cacheConfig = &cache.Config{
-			NumCounters: 1e4,     // number of keys to track frequency of (10k).
-			MaxCost:     1 << 24, // maximum cost of cache (16MB).
-			BufferItems: 64,      // number of keys per Get buffer.
-		}
+		defaultConfig := DefaultNamespaceCacheConfig
+		cacheConfig = &defaultConfig
 	} else {
-		log.Info().Int64("numCounters", cacheConfig.NumCounters).Str("maxCost", humanize.Bytes(uint64(cacheConfig.MaxCost))).Msg("configured caching namespace manager")
+		log.Info().
+			Int64("numCounters", cacheConfig.NumCounters).
+			Str("maxCost", humanize.Bytes(uint64(cacheConfig.MaxCost))).
+			Int64("bufferItems", cacheConfig.BufferItems).
+			Bool("metrics", cacheConfig.Metrics).
+			Msg("configured caching namespace manager")
+	}
+
+	if err := cacheConfig.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid namespace cache config: %w", err)
 	}
 
 	cache, err := cache.NewCache(cacheConfig)
@@ -38,18 +61,64 @@ func NewCachingDatastoreProxy(
 		return nil, fmt.Errorf("unable to create cache: %w", err)
 	}
 
-	return &nsCachingProxy{
+	proxy := &nsCachingProxy{
 		Datastore: delegate,
 		c:         cache,
-	}, nil
+	}
+
+	if cacheConfig.Metrics {
+		proxy.collectors = newCacheCollectors(cache)
+		for _, collector := range proxy.collectors {
+			if err := prometheus.Register(collector); err != nil {
+				proxy.unregisterCollectors()
+				cache.Close()
+				return nil, fmt.Errorf("unable to register namespace cache metrics: %w", err)
+			}
+		}
+	}
+
+	return proxy, nil
+}
+
+func newCacheCollectors(c cache.Cache) []prometheus.Collector {
+	newCounterFunc := func(name string, value func(cache.Metrics) uint64) prometheus.Collector {
+		return prometheus.NewCounterFunc(prometheus.CounterOpts{
+			Namespace: prometheusNamespace,
+			Subsystem: prometheusSubsystem,
+			Name:      name,
+		}, func() float64 {
+			return float64(value(c.GetMetrics()))
+		})
+	}
+
+	return []prometheus.Collector{
+		newCounterFunc("hits_total", cache.Metrics.Hits),
+		newCounterFunc("misses_total", cache.Metrics.Misses),
+		newCounterFunc("cost_added_bytes", cache.Metrics.CostAdded),
+		newCounterFunc("cost_evicted_bytes", cache.Metrics.CostEvicted),
+	}
 }
 
 type nsCachingProxy struct {
 	datastore.Datastore
 	c           cache.Cache
+	collectors  []prometheus.Collector
 	readNsGroup singleflight.Group
 }
 
+func (p *nsCachingProxy) unregisterCollectors() {
+	for _, collector := range p.collectors {
+		prometheus.Unregister(collector)
+	}
+	p.collectors = nil
+}
+
+func (p *nsCachingProxy) Close() error {
+	p.unregisterCollectors()
+	p.c.Close()
+	return p.Datastore.Close()
+}
+
 func (p *nsCachingProxy) SnapshotReader(rev datastore.Revision) datastore.Reader {
 	delegateReader := p.Datastore.SnapshotReader(rev)
 	return &nsCachingReader{delegateReader, sync.Mutex{}, rev, p}
@@ -2,6 +2,7 @@ package proxy
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
@@ -10,7 +11,9 @@ import (
 	"golang.org/x/sync/errgroup"
 
 	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
+	"github.com/authzed/spicedb/pkg/cache"
 	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
 
 var (
@@ -147,3 +150,83 @@ func TestSingleFlight(t *testing.T) {
 	dsMock.AssertExpectations(t)
 	oneReader.AssertExpectations(t)
 }
+
+func TestInvalidCacheConfig(t *testing.T) {
+	_, err := NewCachingDatastoreProxy(&proxy_test.MockDatastore{}, &cache.Config{
+		NumCounters: 1e4,
+		MaxCost:     0,
+		BufferItems: 64,
+	})
+	require.Error(t, err)
+}
+
+func TestCacheSizing(t *testing.T) {
+	const numNamespaces = 50
+
+	testCases := []struct {
+		name            string
+		maxCost         int64
+		expectEvictions bool
+	}{
+		{"tiny cache evicts", 1 << 10, true},
+		{"large cache retains", 1 << 24, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+
+			dsMock := &proxy_test.MockDatastore{}
+			dsMock.On("Close").Return(nil).Once()
+
+			oneReader := &proxy_test.MockReader{}
+			dsMock.On("SnapshotReader", one).Return(oneReader)
+
+			names := make([]string, 0, numNamespaces)
+			for i := 0; i < numNamespaces; i++ {
+				name := fmt.Sprintf("namespace_%d", i)
+				names = append(names, name)
+				oneReader.On("ReadNamespace", name).Return(&core.NamespaceDefinition{
+					Name: name,
+					Relation: []*core.Relation{
+						{Name: "viewer"},
+						{Name: "editor"},
+						{Name: "owner"},
+					},
+				}, zero, nil)
+			}
+
+			ds, err := NewCachingDatastoreProxy(dsMock, &cache.Config{
+				NumCounters: 1e4,
+				MaxCost:     tc.maxCost,
+				BufferItems: 64,
+				Metrics:     true,
+			})
+			require.NoError(err)
+
+			for _, name := range names {
+				_, _, err := ds.SnapshotReader(one).ReadNamespace(ctx, name)
+				require.NoError(err)
+			}
+
+			metrics := ds.(*nsCachingProxy).c.GetMetrics()
+			require.Equal(uint64(numNamespaces), metrics.Misses())
+
+			if tc.expectEvictions {
+				require.Greater(metrics.CostEvicted(), uint64(0))
+			} else {
+				require.Zero(metrics.CostEvicted())
+
+				for _, name := range names {
+					_, _, err := ds.SnapshotReader(one).ReadNamespace(ctx, name)
+					require.NoError(err)
+				}
+				require.Equal(uint64(numNamespaces), metrics.Hits())
+			}
+
+			require.NoError(ds.Close())
+			dsMock.AssertExpectations(t)
+		})
+	}
+}
@@ -49,7 +49,7 @@ func (dm *MockDatastore) CheckRevision(ctx context.Context, revision datastore.R
 	return args.Error(0)
 }
 
-func (dm *MockDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (dm *MockDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
 	args := dm.Called(afterRevision)
 	return args.Get(0).(<-chan *datastore.RevisionChanges), args.Get(1).(<-chan error)
 }
@@ -127,6 +127,21 @@ func (dm *MockReader) ReverseQueryRelationships(
 	return results, args.Error(1)
 }
 
+func (dm *MockReader) CountRelationships(ctx context.Context, filter *v1.RelationshipFilter) (uint64, error) {
+	args := dm.Called(filter)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (dm *MockReader) CheckRelationshipsExist(ctx context.Context, tuples []*core.RelationTuple) (map[string]bool, error) {
+	args := dm.Called(tuples)
+	var results map[string]bool
+	if args.Get(0) != nil {
+		results = args.Get(0).(map[string]bool)
+	}
+
+	return results, args.Error(1)
+}
+
 func (dm *MockReader) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
 	args := dm.Called()
 	return args.Get(0).([]*core.NamespaceDefinition), args.Error(1)
@@ -190,6 +205,21 @@ func (dm *MockReadWriteTransaction) ReverseQueryRelationships(
 	return results, args.Error(1)
 }
 
+func (dm *MockReadWriteTransaction) CountRelationships(ctx context.Context, filter *v1.RelationshipFilter) (uint64, error) {
+	args := dm.Called(filter)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
+func (dm *MockReadWriteTransaction) CheckRelationshipsExist(ctx context.Context, tuples []*core.RelationTuple) (map[string]bool, error) {
+	args := dm.Called(tuples)
+	var results map[string]bool
+	if args.Get(0) != nil {
+		results = args.Get(0).(map[string]bool)
+	}
+
+	return results, args.Error(1)
+}
+
 func (dm *MockReadWriteTransaction) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
 	args := dm.Called()
 	return args.Get(0).([]*core.NamespaceDefinition), args.Error(1)
@@ -200,9 +230,14 @@ func (dm *MockReadWriteTransaction) WriteRelationships(mutations []*v1.Relations
 	return args.Error(0)
 }
 
-func (dm *MockReadWriteTransaction) DeleteRelationships(filter *v1.RelationshipFilter) error {
+func (dm *MockReadWriteTransaction) EffectiveTouches() uint64 {
+	args := dm.Called()
+	return args.Get(0).(uint64)
+}
+
+func (dm *MockReadWriteTransaction) DeleteRelationships(filter *v1.RelationshipFilter) (uint64, error) {
 	args := dm.Called(filter)
-	return args.Error(0)
+	return args.Get(0).(uint64), args.Error(1)
 }
 
 func (dm *MockReadWriteTransaction) WriteNamespaces(newConfigs ...*core.NamespaceDefinition) error {
@@ -215,6 +250,16 @@ func (dm *MockReadWriteTransaction) DeleteNamespace(nsName string) error {
 	return args.Error(0)
 }
 
+func (dm *MockReadWriteTransaction) RenameNamespace(oldName, newName string) error {
+	args := dm.Called(oldName, newName)
+	return args.Error(0)
+}
+
+func (dm *MockReadWriteTransaction) CopyNamespace(sourceName, destName string, copyTuples bool) (uint64, error) {
+	args := dm.Called(sourceName, destName, copyTuples)
+	return args.Get(0).(uint64), args.Error(1)
+}
+
 var (
 	_ datastore.Datastore            = &MockDatastore{}
 	_ datastore.Reader               = &MockReader{}
@@ -3,6 +3,7 @@ package proxy
 import (
 	"context"
 
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
 )
 
@@ -46,8 +47,8 @@ func (rd roDatastore) CheckRevision(ctx context.Context, revision datastore.Revi
 	return rd.delegate.CheckRevision(ctx, revision)
 }
 
-func (rd roDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
-	return rd.delegate.Watch(ctx, afterRevision)
+func (rd roDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	return rd.delegate.Watch(ctx, afterRevision, opts...)
 }
 
 func (rd roDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
@@ -0,0 +1,590 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"strconv"
+	"sync"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+var shadowReadDivergenceCount = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "datastore",
+	Name:      "shadow_read_divergences_total",
+	Help:      "total number of shadow reads of the secondary datastore whose results differed from the primary",
+})
+
+var secondaryWriteFailureCount = promauto.NewCounter(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "datastore",
+	Name:      "secondary_write_failures_total",
+	Help:      "total number of writes committed to the primary datastore which failed to be replayed to the secondary",
+})
+
+// maxRevisionPairs is the number of most recent writes for which the revision of the secondary
+// datastore is remembered. Reads at revisions older than all of them are not shadowed.
+const maxRevisionPairs = 1024
+
+// Divergence describes a read whose results differed between the primary and secondary datastores
+// of a shadowing proxy.
+type Divergence struct {
+	// Operation is the name of the reader method whose results diverged.
+	Operation string
+
+	// PrimaryRevision is the revision read from the primary, and SecondaryRevision is the
+	// revision of the secondary written along with it.
+	PrimaryRevision   datastore.Revision
+	SecondaryRevision datastore.Revision
+
+	// OnlyInPrimary and OnlyInSecondary are the results returned by only one of the datastores.
+	OnlyInPrimary   []string
+	OnlyInSecondary []string
+}
+
+// ShadowingOption configures a shadowing proxy.
+type ShadowingOption func(*shadowingProxy)
+
+// WithShadowReads makes each read of the proxy also be made against the secondary datastore, at
+// the revision written along with the primary revision being read, and any difference between
+// their results be reported. Shadow reads are made synchronously, once the primary results have
+// been read in full, so they add to the latency of every read.
+func WithShadowReads() ShadowingOption {
+	return func(sp *shadowingProxy) {
+		sp.shadowReads = true
+	}
+}
+
+// WithDivergenceHandler sets the function to which divergent shadow reads are reported. By
+// default, they are logged.
+func WithDivergenceHandler(handler func(context.Context, Divergence)) ShadowingOption {
+	return func(sp *shadowingProxy) {
+		sp.onDivergence = handler
+	}
+}
+
+type revisionPair struct {
+	primary   datastore.Revision
+	secondary datastore.Revision
+}
+
+type shadowingProxy struct {
+	primary   datastore.Datastore
+	secondary datastore.Datastore
+
+	shadowReads  bool
+	onDivergence func(context.Context, Divergence)
+
+	// replayLock serializes the replay of writes to the secondary, and the recording of the
+	// revisions they were written at.
+	replayLock sync.Mutex
+
+	// revisionPairs are the revisions of the most recent writes, sorted by primary revision.
+	revisionPairs []revisionPair
+	revisionsLock sync.RWMutex
+}
+
+// NewShadowingProxy creates a proxy which serves reads, revisions and watches from the primary
+// datastore, and replays each write committed to the primary to the secondary datastore. This
+// allows a secondary datastore to be compared against the primary, or kept up to date while
+// migrating between them.
+//
+// The writes of a transaction are replayed in a single transaction of the secondary, after the
+// primary has committed. As the datastores cannot share a transaction, a write which fails to be
+// replayed is logged rather than returned, and concurrent writes may be replayed in a different
+// order than they were committed to the primary.
+func NewShadowingProxy(primary, secondary datastore.Datastore, opts ...ShadowingOption) datastore.Datastore {
+	sp := &shadowingProxy{
+		primary:      primary,
+		secondary:    secondary,
+		onDivergence: logDivergence,
+	}
+	for _, opt := range opts {
+		opt(sp)
+	}
+	return sp
+}
+
+func logDivergence(ctx context.Context, divergence Divergence) {
+	log.Ctx(ctx).Warn().
+		Str("operation", divergence.Operation).
+		Stringer("primaryRevision", divergence.PrimaryRevision).
+		Stringer("secondaryRevision", divergence.SecondaryRevision).
+		Strs("onlyInPrimary", divergence.OnlyInPrimary).
+		Strs("onlyInSecondary", divergence.OnlyInSecondary).
+		Msg("shadow read of secondary datastore diverged from primary")
+}
+
+func (sp *shadowingProxy) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	primary := sp.primary.SnapshotReader(rev)
+	if !sp.shadowReads {
+		return primary
+	}
+
+	secondaryRevision, ok := sp.secondaryRevision(rev)
+	if !ok {
+		return primary
+	}
+
+	return &shadowingReader{
+		primary:           primary,
+		secondary:         sp.secondary.SnapshotReader(secondaryRevision),
+		primaryRevision:   rev,
+		secondaryRevision: secondaryRevision,
+		onDivergence:      sp.onDivergence,
+	}
+}
+
+// secondaryRevision returns the revision of the secondary written along with the most recent
+// write to the primary at or before the given revision.
+func (sp *shadowingProxy) secondaryRevision(primaryRevision datastore.Revision) (datastore.Revision, bool) {
+	sp.revisionsLock.RLock()
+	defer sp.revisionsLock.RUnlock()
+
+	index := sort.Search(len(sp.revisionPairs), func(i int) bool {
+		return sp.revisionPairs[i].primary.GreaterThan(primaryRevision)
+	})
+	if index == 0 {
+		return datastore.NoRevision, false
+	}
+	return sp.revisionPairs[index-1].secondary, true
+}
+
+func (sp *shadowingProxy) recordRevisions(primaryRevision, secondaryRevision datastore.Revision) {
+	sp.revisionsLock.Lock()
+	defer sp.revisionsLock.Unlock()
+
+	index := sort.Search(len(sp.revisionPairs), func(i int) bool {
+		return sp.revisionPairs[i].primary.GreaterThan(primaryRevision)
+	})
+	sp.revisionPairs = append(sp.revisionPairs, revisionPair{})
+	copy(sp.revisionPairs[index+1:], sp.revisionPairs[index:])
+	sp.revisionPairs[index] = revisionPair{primaryRevision, secondaryRevision}
+
+	if len(sp.revisionPairs) > maxRevisionPairs {
+		sp.revisionPairs = sp.revisionPairs[len(sp.revisionPairs)-maxRevisionPairs:]
+	}
+}
+
+func (sp *shadowingProxy) ReadWriteTx(ctx context.Context, f datastore.TxUserFunc) (datastore.Revision, error) {
+	var recorded *recordingTransaction
+	primaryRevision, err := sp.primary.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		// The function is invoked again if the transaction is retried, in which case only the
+		// mutations of the attempt which committed are replayed.
+		recorded = &recordingTransaction{ReadWriteTransaction: rwt}
+		return f(ctx, recorded)
+	})
+	if err != nil {
+		return primaryRevision, err
+	}
+
+	sp.replayLock.Lock()
+	defer sp.replayLock.Unlock()
+
+	secondaryRevision, err := sp.secondary.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		for _, mutation := range recorded.mutations {
+			if err := mutation(rwt); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		secondaryWriteFailureCount.Inc()
+		log.Ctx(ctx).Error().Err(err).Stringer("revision", primaryRevision).Msg("failed to replay write to secondary datastore")
+		return primaryRevision, nil
+	}
+
+	sp.recordRevisions(primaryRevision, secondaryRevision)
+	return primaryRevision, nil
+}
+
+func (sp *shadowingProxy) OptimizedRevision(ctx context.Context) (datastore.Revision, error) {
+	return sp.primary.OptimizedRevision(ctx)
+}
+
+func (sp *shadowingProxy) HeadRevision(ctx context.Context) (datastore.Revision, error) {
+	return sp.primary.HeadRevision(ctx)
+}
+
+func (sp *shadowingProxy) CheckRevision(ctx context.Context, revision datastore.Revision) error {
+	return sp.primary.CheckRevision(ctx, revision)
+}
+
+func (sp *shadowingProxy) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	return sp.primary.Watch(ctx, afterRevision, opts...)
+}
+
+// IsReady returns whether both datastores are ready.
+func (sp *shadowingProxy) IsReady(ctx context.Context) (bool, error) {
+	ready, err := sp.primary.IsReady(ctx)
+	if err != nil || !ready {
+		return ready, err
+	}
+	return sp.secondary.IsReady(ctx)
+}
+
+func (sp *shadowingProxy) Statistics(ctx context.Context) (datastore.Stats, error) {
+	return sp.primary.Statistics(ctx)
+}
+
+func (sp *shadowingProxy) Close() error {
+	primaryErr := sp.primary.Close()
+	secondaryErr := sp.secondary.Close()
+	if primaryErr != nil {
+		return primaryErr
+	}
+	return secondaryErr
+}
+
+// recordingTransaction records the mutations successfully applied through a transaction of the
+// primary, so that they can be replayed to the secondary.
+type recordingTransaction struct {
+	datastore.ReadWriteTransaction
+
+	mutations []func(datastore.ReadWriteTransaction) error
+}
+
+func (rt *recordingTransaction) record(mutation func(datastore.ReadWriteTransaction) error) error {
+	if err := mutation(rt.ReadWriteTransaction); err != nil {
+		return err
+	}
+	rt.mutations = append(rt.mutations, mutation)
+	return nil
+}
+
+func (rt *recordingTransaction) WriteRelationships(mutations []*v1.RelationshipUpdate) error {
+	return rt.record(func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(mutations)
+	})
+}
+
+func (rt *recordingTransaction) DeleteRelationships(filter *v1.RelationshipFilter) (uint64, error) {
+	var deleted uint64
+	err := rt.record(func(rwt datastore.ReadWriteTransaction) error {
+		var err error
+		deleted, err = rwt.DeleteRelationships(filter)
+		return err
+	})
+	return deleted, err
+}
+
+func (rt *recordingTransaction) WriteNamespaces(newConfigs ...*core.NamespaceDefinition) error {
+	return rt.record(func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(newConfigs...)
+	})
+}
+
+func (rt *recordingTransaction) DeleteNamespace(nsName string) error {
+	return rt.record(func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.DeleteNamespace(nsName)
+	})
+}
+
+func (rt *recordingTransaction) RenameNamespace(oldName, newName string) error {
+	return rt.record(func(rwt datastore.ReadWriteTransaction) error {
+		return rwt.RenameNamespace(oldName, newName)
+	})
+}
+
+func (rt *recordingTransaction) CopyNamespace(sourceName, destName string, copyTuples bool) (uint64, error) {
+	var copied uint64
+	err := rt.record(func(rwt datastore.ReadWriteTransaction) error {
+		var err error
+		copied, err = rwt.CopyNamespace(sourceName, destName, copyTuples)
+		return err
+	})
+	return copied, err
+}
+
+// shadowingReader serves reads from the primary, and compares their results with those of the
+// same reads of the secondary.
+type shadowingReader struct {
+	primary   datastore.Reader
+	secondary datastore.Reader
+
+	primaryRevision   datastore.Revision
+	secondaryRevision datastore.Revision
+	onDivergence      func(context.Context, Divergence)
+}
+
+func (sr *shadowingReader) QueryRelationships(
+	ctx context.Context,
+	filter *v1.RelationshipFilter,
+	opts ...options.QueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	iter, err := sr.primary.QueryRelationships(ctx, filter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sr.shadowIterator(ctx, "QueryRelationships", iter, func() (datastore.RelationshipIterator, error) {
+		return sr.secondary.QueryRelationships(ctx, filter, opts...)
+	}), nil
+}
+
+func (sr *shadowingReader) ReverseQueryRelationships(
+	ctx context.Context,
+	subjectFilter *v1.SubjectFilter,
+	opts ...options.ReverseQueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	iter, err := sr.primary.ReverseQueryRelationships(ctx, subjectFilter, opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	return sr.shadowIterator(ctx, "ReverseQueryRelationships", iter, func() (datastore.RelationshipIterator, error) {
+		return sr.secondary.ReverseQueryRelationships(ctx, subjectFilter, opts...)
+	}), nil
+}
+
+func (sr *shadowingReader) CountRelationships(ctx context.Context, filter *v1.RelationshipFilter) (uint64, error) {
+	count, err := sr.primary.CountRelationships(ctx, filter)
+	if err != nil {
+		return count, err
+	}
+
+	secondaryCount, err := sr.secondary.CountRelationships(ctx, filter)
+	if err != nil {
+		sr.shadowReadFailed(ctx, "CountRelationships", err)
+		return count, nil
+	}
+
+	if count != secondaryCount {
+		sr.diverged(ctx, "CountRelationships", []string{formatUint(count)}, []string{formatUint(secondaryCount)})
+	}
+	return count, nil
+}
+
+func (sr *shadowingReader) CheckRelationshipsExist(ctx context.Context, tuples []*core.RelationTuple) (map[string]bool, error) {
+	exists, err := sr.primary.CheckRelationshipsExist(ctx, tuples)
+	if err != nil {
+		return exists, err
+	}
+
+	secondaryExists, err := sr.secondary.CheckRelationshipsExist(ctx, tuples)
+	if err != nil {
+		sr.shadowReadFailed(ctx, "CheckRelationshipsExist", err)
+		return exists, nil
+	}
+
+	sr.compare(ctx, "CheckRelationshipsExist", existingKeys(exists), existingKeys(secondaryExists))
+	return exists, nil
+}
+
+func (sr *shadowingReader) ReadNamespace(ctx context.Context, nsName string) (*core.NamespaceDefinition, datastore.Revision, error) {
+	ns, lastWritten, err := sr.primary.ReadNamespace(ctx, nsName)
+	if err != nil && !errors.As(err, &datastore.ErrNamespaceNotFound{}) {
+		return ns, lastWritten, err
+	}
+
+	secondaryNs, _, secondaryErr := sr.secondary.ReadNamespace(ctx, nsName)
+	if secondaryErr != nil && !errors.As(secondaryErr, &datastore.ErrNamespaceNotFound{}) {
+		sr.shadowReadFailed(ctx, "ReadNamespace", secondaryErr)
+		return ns, lastWritten, err
+	}
+
+	var primaryNamespaces, secondaryNamespaces []*core.NamespaceDefinition
+	if err == nil {
+		primaryNamespaces = append(primaryNamespaces, ns)
+	}
+	if secondaryErr == nil {
+		secondaryNamespaces = append(secondaryNamespaces, secondaryNs)
+	}
+	sr.compareNamespaces(ctx, "ReadNamespace", primaryNamespaces, secondaryNamespaces)
+
+	return ns, lastWritten, err
+}
+
+func (sr *shadowingReader) ListNamespaces(ctx context.Context) ([]*core.NamespaceDefinition, error) {
+	namespaces, err := sr.primary.ListNamespaces(ctx)
+	if err != nil {
+		return namespaces, err
+	}
+
+	secondaryNamespaces, err := sr.secondary.ListNamespaces(ctx)
+	if err != nil {
+		sr.shadowReadFailed(ctx, "ListNamespaces", err)
+		return namespaces, nil
+	}
+
+	sr.compareNamespaces(ctx, "ListNamespaces", namespaces, secondaryNamespaces)
+	return namespaces, nil
+}
+
+// shadowIterator returns an iterator over the primary results which, once they have been read in
+// full and it is closed, compares them with the results of the secondary query.
+func (sr *shadowingReader) shadowIterator(
+	ctx context.Context,
+	operation string,
+	iter datastore.RelationshipIterator,
+	secondaryQuery func() (datastore.RelationshipIterator, error),
+) datastore.RelationshipIterator {
+	return &shadowedIterator{
+		RelationshipIterator: iter,
+		onExhausted: func(found []string) {
+			secondaryIter, err := secondaryQuery()
+			if err != nil {
+				sr.shadowReadFailed(ctx, operation, err)
+				return
+			}
+			defer secondaryIter.Close()
+
+			var secondaryFound []string
+			for tpl := secondaryIter.Next(); tpl != nil; tpl = secondaryIter.Next() {
+				secondaryFound = append(secondaryFound, tuple.String(tpl))
+			}
+			if secondaryIter.Err() != nil {
+				sr.shadowReadFailed(ctx, operation, secondaryIter.Err())
+				return
+			}
+
+			sr.compare(ctx, operation, found, secondaryFound)
+		},
+	}
+}
+
+// compareNamespaces compares namespace definitions by name, reporting a namespace whose
+// definitions differ as being only in each datastore.
+func (sr *shadowingReader) compareNamespaces(ctx context.Context, operation string, primary, secondary []*core.NamespaceDefinition) {
+	secondaryByName := make(map[string]*core.NamespaceDefinition, len(secondary))
+	for _, ns := range secondary {
+		secondaryByName[ns.Name] = ns
+	}
+
+	var onlyInPrimary, onlyInSecondary []string
+	for _, ns := range primary {
+		secondaryNs, ok := secondaryByName[ns.Name]
+		delete(secondaryByName, ns.Name)
+
+		if !ok {
+			onlyInPrimary = append(onlyInPrimary, ns.Name)
+		} else if !proto.Equal(ns, secondaryNs) {
+			onlyInPrimary = append(onlyInPrimary, ns.Name)
+			onlyInSecondary = append(onlyInSecondary, ns.Name)
+		}
+	}
+	for name := range secondaryByName {
+		onlyInSecondary = append(onlyInSecondary, name)
+	}
+
+	sort.Strings(onlyInPrimary)
+	sort.Strings(onlyInSecondary)
+	sr.diverged(ctx, operation, onlyInPrimary, onlyInSecondary)
+}
+
+// compare reports the results found by only one of the datastores, if any.
+func (sr *shadowingReader) compare(ctx context.Context, operation string, primary, secondary []string) {
+	secondarySet := make(map[string]struct{}, len(secondary))
+	for _, result := range secondary {
+		secondarySet[result] = struct{}{}
+	}
+
+	var onlyInPrimary []string
+	for _, result := range primary {
+		if _, ok := secondarySet[result]; ok {
+			delete(secondarySet, result)
+			continue
+		}
+		onlyInPrimary = append(onlyInPrimary, result)
+	}
+
+	onlyInSecondary := make([]string, 0, len(secondarySet))
+	for result := range secondarySet {
+		onlyInSecondary = append(onlyInSecondary, result)
+	}
+
+	sort.Strings(onlyInPrimary)
+	sort.Strings(onlyInSecondary)
+	sr.diverged(ctx, operation, onlyInPrimary, onlyInSecondary)
+}
+
+func (sr *shadowingReader) diverged(ctx context.Context, operation string, onlyInPrimary, onlyInSecondary []string) {
+	if len(onlyInPrimary) == 0 && len(onlyInSecondary) == 0 {
+		return
+	}
+
+	shadowReadDivergenceCount.Inc()
+	sr.onDivergence(ctx, Divergence{
+		Operation:         operation,
+		PrimaryRevision:   sr.primaryRevision,
+		SecondaryRevision: sr.secondaryRevision,
+		OnlyInPrimary:     onlyInPrimary,
+		OnlyInSecondary:   onlyInSecondary,
+	})
+}
+
+func (sr *shadowingReader) shadowReadFailed(ctx context.Context, operation string, err error) {
+	log.Ctx(ctx).Warn().Err(err).Str("operation", operation).Stringer("revision", sr.secondaryRevision).Msg("shadow read of secondary datastore failed")
+}
+
+func existingKeys(exists map[string]bool) []string {
+	keys := make([]string, 0, len(exists))
+	for key, found := range exists {
+		if found {
+			keys = append(keys, key)
+		}
+	}
+	return keys
+}
+
+func formatUint(value uint64) string {
+	return strconv.FormatUint(value, 10)
+}
+
+// shadowedIterator records the tuples returned by the primary iterator, and invokes onExhausted
+// with them when closed, if they were read in full without error.
+type shadowedIterator struct {
+	datastore.RelationshipIterator
+
+	found       []string
+	exhausted   bool
+	closed      bool
+	onExhausted func(found []string)
+}
+
+func (si *shadowedIterator) Next() *core.RelationTuple {
+	tpl := si.RelationshipIterator.Next()
+	if tpl == nil {
+		si.exhausted = si.RelationshipIterator.Err() == nil
+		return nil
+	}
+
+	si.found = append(si.found, tuple.String(tpl))
+	return tpl
+}
+
+func (si *shadowedIterator) Close() {
+	si.RelationshipIterator.Close()
+	if si.exhausted && !si.closed {
+		si.onExhausted(si.found)
+	}
+	si.closed = true
+}
+
+// RevisionMetadata reports the revision metadata of the primary iterator, if it reports any.
+func (si *shadowedIterator) RevisionMetadata() (datastore.RevisionMetadata, bool) {
+	if reporter, ok := si.RelationshipIterator.(datastore.RevisionMetadataReporter); ok {
+		return reporter.RevisionMetadata()
+	}
+	return datastore.RevisionMetadata{}, false
+}
+
+var (
+	_ datastore.Datastore                = &shadowingProxy{}
+	_ datastore.Reader                   = &shadowingReader{}
+	_ datastore.ReadWriteTransaction     = &recordingTransaction{}
+	_ datastore.RevisionMetadataReporter = &shadowedIterator{}
+)
@@ -0,0 +1,169 @@
+package proxy
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	test "github.com/authzed/spicedb/pkg/datastore/test"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+type shadowingTest struct {
+	divergences *uint64
+}
+
+func (st shadowingTest) New(revisionQuantization, gcWindow time.Duration, watchBufferLength uint16) (datastore.Datastore, error) {
+	primary, err := memdb.NewMemdbDatastore(watchBufferLength, revisionQuantization, gcWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	secondary, err := memdb.NewMemdbDatastore(watchBufferLength, revisionQuantization, gcWindow)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewShadowingProxy(primary, secondary, WithShadowReads(), WithDivergenceHandler(func(context.Context, Divergence) {
+		atomic.AddUint64(st.divergences, 1)
+	})), nil
+}
+
+func TestShadowingProxyDatastore(t *testing.T) {
+	var divergences uint64
+	test.All(t, shadowingTest{&divergences})
+	require.Zero(t, atomic.LoadUint64(&divergences), "memdb datastores diverged")
+}
+
+type divergenceRecorder struct {
+	divergences []Divergence
+	mu          sync.Mutex
+}
+
+func (dr *divergenceRecorder) record(_ context.Context, divergence Divergence) {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+	dr.divergences = append(dr.divergences, divergence)
+}
+
+func (dr *divergenceRecorder) operations() []string {
+	dr.mu.Lock()
+	defer dr.mu.Unlock()
+
+	operations := make([]string, 0, len(dr.divergences))
+	for _, divergence := range dr.divergences {
+		operations = append(operations, divergence.Operation)
+	}
+	return operations
+}
+
+func TestShadowingProxyDivergence(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	primary, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	secondary, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	recorder := &divergenceRecorder{}
+	ds := NewShadowingProxy(primary, secondary, WithShadowReads(), WithDivergenceHandler(recorder.record))
+	ds, revision := testfixtures.StandardDatastoreWithData(ds, require)
+
+	// Writes are replayed to the secondary.
+	tchecker := testfixtures.TupleChecker{Require: require, DS: secondary}
+	secondaryRevision, err := secondary.HeadRevision(ctx)
+	require.NoError(err)
+	tchecker.VerifyIteratorCount(tchecker.ExactRelationshipIterator(ctx, tuple.MustParse("document:masterplan#viewer@user:eng_lead"), secondaryRevision), 1)
+
+	filter := &v1.RelationshipFilter{ResourceType: "document", OptionalResourceId: "masterplan"}
+	readAll := func(rev datastore.Revision) {
+		reader := ds.SnapshotReader(rev)
+
+		iter, err := reader.QueryRelationships(ctx, filter)
+		require.NoError(err)
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		}
+		require.NoError(iter.Err())
+		iter.Close()
+
+		_, err = reader.CountRelationships(ctx, filter)
+		require.NoError(err)
+
+		_, err = reader.CheckRelationshipsExist(ctx, []*core.RelationTuple{tuple.MustParse("document:masterplan#viewer@user:sneaky")})
+		require.NoError(err)
+
+		_, err = reader.ListNamespaces(ctx)
+		require.NoError(err)
+	}
+
+	// The datastores are consistent, so no divergence is reported.
+	readAll(revision)
+	require.Empty(recorder.operations())
+
+	// Write to the primary alone, making the pair inconsistent.
+	inconsistentRevision, err := primary.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.WriteNamespaces(ns.Namespace("extra")); err != nil {
+			return err
+		}
+
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{{
+			Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+			Relationship: tuple.MustToRelationship(tuple.MustParse("document:masterplan#viewer@user:sneaky")),
+		}})
+	})
+	require.NoError(err)
+
+	// Reads at the revision before the inconsistent write still agree.
+	readAll(revision)
+	require.Empty(recorder.operations())
+
+	readAll(inconsistentRevision)
+	require.Equal([]string{"QueryRelationships", "CountRelationships", "CheckRelationshipsExist", "ListNamespaces"}, recorder.operations())
+
+	divergences := recorder.divergences
+	require.Equal([]string{"document:masterplan#viewer@user:sneaky"}, divergences[0].OnlyInPrimary)
+	require.Empty(divergences[0].OnlyInSecondary)
+	require.Equal(inconsistentRevision, divergences[0].PrimaryRevision)
+	require.True(divergences[0].SecondaryRevision.LessThanOrEqual(secondaryRevision))
+	require.Equal([]string{"5"}, divergences[1].OnlyInPrimary)
+	require.Equal([]string{"4"}, divergences[1].OnlyInSecondary)
+	require.Equal([]string{"document:masterplan#viewer@user:sneaky"}, divergences[2].OnlyInPrimary)
+	require.Equal([]string{"extra"}, divergences[3].OnlyInPrimary)
+}
+
+func TestShadowingProxyWithoutShadowReads(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	primary, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	secondary, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds := NewShadowingProxy(primary, secondary, WithDivergenceHandler(func(context.Context, Divergence) {
+		require.Fail("divergence reported without shadow reads")
+	}))
+	ds, _ = testfixtures.StandardDatastoreWithData(ds, require)
+
+	_, err = secondary.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.DeleteNamespace("document")
+	})
+	require.NoError(err)
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	_, _, err = ds.SnapshotReader(headRevision).ReadNamespace(ctx, "document")
+	require.NoError(err)
+}
@@ -0,0 +1,135 @@
+package proxy
+
+import (
+	"context"
+	"fmt"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// ValidatingOption configures a schema validating proxy.
+type ValidatingOption func(*validatingProxy)
+
+// WithBulkImportValidation sets whether relationships bulk imported through the proxy are
+// validated. Validating reads the schema for every imported relationship type, so it can be
+// disabled for imports of relationships already known to be valid. Defaults to true.
+func WithBulkImportValidation(enabled bool) ValidatingOption {
+	return func(vp *validatingProxy) {
+		vp.validateBulkImports = enabled
+	}
+}
+
+type validatingProxy struct {
+	delegate            datastore.Datastore
+	validateBulkImports bool
+}
+
+// NewSchemaValidatingProxy creates a proxy which rejects writes of relationships that the schema
+// does not permit, such as those whose subject type or relation is not allowed on their relation,
+// with the typed errors of namespace.RelationshipValidator. Relationships are validated against
+// the schema as read within the writing transaction.
+//
+// The proxy implements datastore.BulkImporter, importing through the delegate if it does.
+func NewSchemaValidatingProxy(delegate datastore.Datastore, opts ...ValidatingOption) datastore.Datastore {
+	vp := &validatingProxy{delegate: delegate, validateBulkImports: true}
+	for _, opt := range opts {
+		opt(vp)
+	}
+	return vp
+}
+
+func (vp *validatingProxy) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return vp.delegate.SnapshotReader(rev)
+}
+
+func (vp *validatingProxy) ReadWriteTx(ctx context.Context, f datastore.TxUserFunc) (datastore.Revision, error) {
+	return vp.delegate.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return f(ctx, &validatingTransaction{ctx, rwt})
+	})
+}
+
+func (vp *validatingProxy) BulkImportRelationships(ctx context.Context, tuples []*core.RelationTuple, onConflict datastore.ConflictMode) (uint64, datastore.Revision, error) {
+	importer, ok := vp.delegate.(datastore.BulkImporter)
+	if !ok {
+		return 0, datastore.NoRevision, fmt.Errorf("datastore does not support bulk import")
+	}
+
+	if vp.validateBulkImports {
+		headRevision, err := vp.delegate.HeadRevision(ctx)
+		if err != nil {
+			return 0, datastore.NoRevision, err
+		}
+
+		validator := namespace.NewRelationshipValidator(vp.delegate.SnapshotReader(headRevision))
+		for _, tpl := range tuples {
+			if err := validator.ValidateRelationship(ctx, tpl); err != nil {
+				return 0, datastore.NoRevision, err
+			}
+		}
+	}
+
+	return importer.BulkImportRelationships(ctx, tuples, onConflict)
+}
+
+func (vp *validatingProxy) OptimizedRevision(ctx context.Context) (datastore.Revision, error) {
+	return vp.delegate.OptimizedRevision(ctx)
+}
+
+func (vp *validatingProxy) HeadRevision(ctx context.Context) (datastore.Revision, error) {
+	return vp.delegate.HeadRevision(ctx)
+}
+
+func (vp *validatingProxy) CheckRevision(ctx context.Context, revision datastore.Revision) error {
+	return vp.delegate.CheckRevision(ctx, revision)
+}
+
+func (vp *validatingProxy) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	return vp.delegate.Watch(ctx, afterRevision, opts...)
+}
+
+func (vp *validatingProxy) IsReady(ctx context.Context) (bool, error) {
+	return vp.delegate.IsReady(ctx)
+}
+
+func (vp *validatingProxy) Statistics(ctx context.Context) (datastore.Stats, error) {
+	return vp.delegate.Statistics(ctx)
+}
+
+func (vp *validatingProxy) Close() error {
+	return vp.delegate.Close()
+}
+
+// validatingTransaction validates the relationships created or touched through a transaction.
+type validatingTransaction struct {
+	ctx context.Context
+	datastore.ReadWriteTransaction
+}
+
+func (vt *validatingTransaction) WriteRelationships(mutations []*v1.RelationshipUpdate) error {
+	// The schema may have been written earlier in the transaction, so it is read anew for each
+	// write.
+	validator := namespace.NewRelationshipValidator(vt.ReadWriteTransaction)
+	for _, mutation := range mutations {
+		if mutation.Operation == v1.RelationshipUpdate_OPERATION_DELETE {
+			continue
+		}
+
+		if err := validator.ValidateRelationship(vt.ctx, tuple.FromRelationship(mutation.Relationship)); err != nil {
+			return err
+		}
+	}
+
+	return vt.ReadWriteTransaction.WriteRelationships(mutations)
+}
+
+var (
+	_ datastore.Datastore    = &validatingProxy{}
+	_ datastore.BulkImporter = &validatingProxy{}
+)
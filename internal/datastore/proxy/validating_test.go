@@ -0,0 +1,125 @@
+package proxy
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+var validatingTestNamespaces = []*core.NamespaceDefinition{
+	ns.Namespace("user"),
+	ns.Namespace("group",
+		ns.Relation("member", nil, ns.AllowedRelation("user", "...")),
+		ns.Relation("manager", nil, ns.AllowedRelation("user", "...")),
+	),
+	ns.Namespace("document",
+		ns.Relation("viewer", nil,
+			ns.AllowedRelation("user", "..."),
+			ns.AllowedRelation("group", "member"),
+		),
+	),
+}
+
+func TestSchemaValidatingProxyWrites(t *testing.T) {
+	testCases := []struct {
+		name             string
+		operation        v1.RelationshipUpdate_Operation
+		relationship     string
+		expectNotAllowed bool
+	}{
+		{"allowed subject", v1.RelationshipUpdate_OPERATION_CREATE, "document:foo#viewer@user:tom", false},
+		{"allowed subject relation", v1.RelationshipUpdate_OPERATION_TOUCH, "document:foo#viewer@group:eng#member", false},
+		{"disallowed subject type", v1.RelationshipUpdate_OPERATION_CREATE, "document:foo#viewer@document:bar", true},
+		{"disallowed subject relation", v1.RelationshipUpdate_OPERATION_TOUCH, "document:foo#viewer@group:eng#manager", true},
+		{"delete is not validated", v1.RelationshipUpdate_OPERATION_DELETE, "document:foo#viewer@group:eng#manager", false},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+
+			delegate, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+
+			ds := NewSchemaValidatingProxy(delegate)
+			_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				// The schema written earlier in the transaction is validated against.
+				if err := rwt.WriteNamespaces(validatingTestNamespaces...); err != nil {
+					return err
+				}
+
+				return rwt.WriteRelationships([]*v1.RelationshipUpdate{{
+					Operation:    tc.operation,
+					Relationship: tuple.ParseRel(tc.relationship),
+				}})
+			})
+			if !tc.expectNotAllowed {
+				require.NoError(err)
+				return
+			}
+
+			var notAllowed namespace.ErrSubjectNotAllowed
+			require.ErrorAs(err, &notAllowed)
+			require.Equal("document", notAllowed.NamespaceName())
+			require.Equal("viewer", notAllowed.RelationName())
+		})
+	}
+}
+
+type recordingImporter struct {
+	datastore.Datastore
+	imported []*core.RelationTuple
+}
+
+func (ri *recordingImporter) BulkImportRelationships(_ context.Context, tuples []*core.RelationTuple, _ datastore.ConflictMode) (uint64, datastore.Revision, error) {
+	ri.imported = append(ri.imported, tuples...)
+	return uint64(len(tuples)), datastore.NoRevision, nil
+}
+
+func TestSchemaValidatingProxyBulkImport(t *testing.T) {
+	tuples := []*core.RelationTuple{
+		tuple.MustParse("document:foo#viewer@user:tom"),
+		tuple.MustParse("document:foo#viewer@group:eng#manager"),
+	}
+
+	for _, validate := range []bool{true, false} {
+		validate := validate
+		t.Run(map[bool]string{true: "validated", false: "not validated"}[validate], func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+
+			delegate, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+
+			_, err = delegate.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				return rwt.WriteNamespaces(validatingTestNamespaces...)
+			})
+			require.NoError(err)
+
+			importer := &recordingImporter{Datastore: delegate}
+			ds := NewSchemaValidatingProxy(importer, WithBulkImportValidation(validate))
+
+			imported, _, err := ds.(datastore.BulkImporter).BulkImportRelationships(ctx, tuples, datastore.ConflictError)
+			if validate {
+				require.ErrorAs(err, &namespace.ErrSubjectNotAllowed{})
+				require.Empty(importer.imported)
+				return
+			}
+
+			require.NoError(err)
+			require.Equal(uint64(2), imported)
+			require.Len(importer.imported, 2)
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+var watchLagHistogram = promauto.NewHistogram(prometheus.HistogramOpts{
+	Namespace: "spicedb",
+	Subsystem: "datastore",
+	Name:      "watch_lag_revisions",
+	Help:      "difference between the head revision and the last revision delivered to a watcher, sampled for each watcher, in the revision units of the datastore.",
+	Buckets:   prometheus.ExponentialBuckets(1, 10, 12),
+})
+
+var watchEndedCount = promauto.NewCounterVec(prometheus.CounterOpts{
+	Namespace: "spicedb",
+	Subsystem: "datastore",
+	Name:      "watch_ended_total",
+	Help:      "number of watches ended because the watcher was disconnected or canceled.",
+}, []string{"reason"})
+
+// DefaultWatchLagSampleInterval is the default interval at which the lag of each watcher is
+// sampled.
+const DefaultWatchLagSampleInterval = 5 * time.Second
+
+// WatchMetricsOption configures a watch metrics proxy.
+type WatchMetricsOption func(*watchMetricsProxy)
+
+// WithWatchLagSampleInterval sets the interval at which the lag of each watcher is sampled.
+// Defaults to DefaultWatchLagSampleInterval.
+func WithWatchLagSampleInterval(interval time.Duration) WatchMetricsOption {
+	return func(wp *watchMetricsProxy) {
+		wp.sampleInterval = interval
+	}
+}
+
+type watchMetricsProxy struct {
+	delegate       datastore.Datastore
+	sampleInterval time.Duration
+}
+
+// NewWatchMetricsProxy creates a proxy which records prometheus metrics for the watches of the
+// delegate datastore, so that slow consumers can be found before they are disconnected.
+//
+// The lag of each watcher is the difference between the head revision and the last revision
+// delivered to it, and is sampled into a histogram shared by all watchers; it is zero while no
+// changes are waiting to be delivered.
+// Changes are delivered through an unbuffered channel, so a watch started through the proxy
+// holds at most one more change than the delegate's watch buffer. Watches ended by a
+// disconnection or cancellation are counted by reason.
+//
+// The proxy implements the optional interfaces of the datastore package which are implemented
+// by datastores, such as datastore.BulkImporter, calling through to the delegate if it does and
+// failing otherwise.
+func NewWatchMetricsProxy(delegate datastore.Datastore, opts ...WatchMetricsOption) datastore.Datastore {
+	wp := &watchMetricsProxy{
+		delegate:       delegate,
+		sampleInterval: DefaultWatchLagSampleInterval,
+	}
+	for _, opt := range opts {
+		opt(wp)
+	}
+	return wp
+}
+
+func (wp *watchMetricsProxy) SnapshotReader(rev datastore.Revision) datastore.Reader {
+	return wp.delegate.SnapshotReader(rev)
+}
+
+func (wp *watchMetricsProxy) ReadWriteTx(ctx context.Context, f datastore.TxUserFunc) (datastore.Revision, error) {
+	return wp.delegate.ReadWriteTx(ctx, f)
+}
+
+func (wp *watchMetricsProxy) OptimizedRevision(ctx context.Context) (datastore.Revision, error) {
+	return wp.delegate.OptimizedRevision(ctx)
+}
+
+func (wp *watchMetricsProxy) HeadRevision(ctx context.Context) (datastore.Revision, error) {
+	return wp.delegate.HeadRevision(ctx)
+}
+
+func (wp *watchMetricsProxy) CheckRevision(ctx context.Context, revision datastore.Revision) error {
+	return wp.delegate.CheckRevision(ctx, revision)
+}
+
+func (wp *watchMetricsProxy) IsReady(ctx context.Context) (bool, error) {
+	return wp.delegate.IsReady(ctx)
+}
+
+func (wp *watchMetricsProxy) Statistics(ctx context.Context) (datastore.Stats, error) {
+	return wp.delegate.Statistics(ctx)
+}
+
+func (wp *watchMetricsProxy) Close() error {
+	return wp.delegate.Close()
+}
+
+func (wp *watchMetricsProxy) BulkImportRelationships(ctx context.Context, tuples []*core.RelationTuple, onConflict datastore.ConflictMode) (uint64, datastore.Revision, error) {
+	importer, ok := wp.delegate.(datastore.BulkImporter)
+	if !ok {
+		return 0, datastore.NoRevision, fmt.Errorf("datastore does not support bulk import")
+	}
+	return importer.BulkImportRelationships(ctx, tuples, onConflict)
+}
+
+func (wp *watchMetricsProxy) QueryTuplesIncludingDeleted(ctx context.Context, filter *v1.RelationshipFilter, fromRevision, toRevision datastore.Revision) (datastore.RelationshipIterator, error) {
+	querier, ok := wp.delegate.(datastore.HistoricalQuerier)
+	if !ok {
+		return nil, fmt.Errorf("datastore does not support querying deleted relationships")
+	}
+	return querier.QueryTuplesIncludingDeleted(ctx, filter, fromRevision, toRevision)
+}
+
+func (wp *watchMetricsProxy) ReadyState(ctx context.Context) (datastore.ReadyState, error) {
+	reporter, ok := wp.delegate.(datastore.ReadyStateReporter)
+	if !ok {
+		return datastore.ReadyState{}, fmt.Errorf("datastore does not support reporting its ready state")
+	}
+	return reporter.ReadyState(ctx)
+}
+
+func (wp *watchMetricsProxy) MinWatchRevision(ctx context.Context) (datastore.Revision, error) {
+	reporter, ok := wp.delegate.(datastore.WatchRevisionReporter)
+	if !ok {
+		return datastore.NoRevision, fmt.Errorf("datastore does not support reporting its minimum watch revision")
+	}
+	return reporter.MinWatchRevision(ctx)
+}
+
+func (wp *watchMetricsProxy) WatchNamespaces(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.NamespaceChange, <-chan error) {
+	watcher, ok := wp.delegate.(datastore.NamespaceWatcher)
+	if !ok {
+		errs := make(chan error, 1)
+		errs <- fmt.Errorf("datastore does not support watching namespaces")
+		close(errs)
+		return nil, errs
+	}
+	return watcher.WatchNamespaces(ctx, afterRevision)
+}
+
+func (wp *watchMetricsProxy) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	changes, errs := wp.delegate.Watch(ctx, afterRevision, opts...)
+
+	delivered := make(chan *datastore.RevisionChanges)
+	deliveredErrs := make(chan error, 1)
+
+	go func() {
+		defer close(delivered)
+		defer close(deliveredErrs)
+
+		ticker := time.NewTicker(wp.sampleInterval)
+		defer ticker.Stop()
+
+		lastDelivered := afterRevision
+		var pending *datastore.RevisionChanges
+
+		endWatch := func(err error) {
+			switch {
+			case errors.As(err, &datastore.ErrWatchDisconnected{}):
+				watchEndedCount.WithLabelValues("disconnected").Inc()
+			case errors.As(err, &datastore.ErrWatchCanceled{}):
+				watchEndedCount.WithLabelValues("canceled").Inc()
+			}
+			deliveredErrs <- err
+		}
+
+		for {
+			// Only receive the next change once the last has been delivered, so that the
+			// delegate still sees the consumer falling behind.
+			var receive <-chan *datastore.RevisionChanges
+			var send chan<- *datastore.RevisionChanges
+			if pending == nil {
+				receive = changes
+			} else {
+				send = delivered
+			}
+
+			select {
+			case change, ok := <-receive:
+				if !ok {
+					if errs != nil {
+						if err, ok := <-errs; ok {
+							endWatch(err)
+						}
+					}
+					return
+				}
+				pending = change
+
+			case send <- pending:
+				lastDelivered = pending.Revision
+				pending = nil
+
+			case err, ok := <-errs:
+				if !ok {
+					// Keep delivering the changes still buffered by the delegate.
+					errs = nil
+					continue
+				}
+				endWatch(err)
+				return
+
+			case <-ctx.Done():
+				endWatch(datastore.NewWatchCanceledErr())
+				return
+
+			case <-ticker.C:
+				if pending == nil && len(changes) == 0 {
+					watchLagHistogram.Observe(0)
+					continue
+				}
+
+				head, err := wp.delegate.HeadRevision(ctx)
+				if err != nil {
+					continue
+				}
+
+				behind, _ := head.Sub(lastDelivered).Float64()
+				if behind < 0 {
+					behind = 0
+				}
+				watchLagHistogram.Observe(behind)
+			}
+		}
+	}()
+
+	return delivered, deliveredErrs
+}
+
+var (
+	_ datastore.BulkImporter          = &watchMetricsProxy{}
+	_ datastore.HistoricalQuerier     = &watchMetricsProxy{}
+	_ datastore.ReadyStateReporter    = &watchMetricsProxy{}
+	_ datastore.WatchRevisionReporter = &watchMetricsProxy{}
+	_ datastore.NamespaceWatcher      = &watchMetricsProxy{}
+)
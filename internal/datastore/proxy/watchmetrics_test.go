@@ -0,0 +1,137 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func newWatchMetricsTestDatastore(require *require.Assertions, watchBufferLength uint16) datastore.Datastore {
+	delegate, err := memdb.NewMemdbDatastore(watchBufferLength, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	return NewWatchMetricsProxy(delegate, WithWatchLagSampleInterval(5*time.Millisecond))
+}
+
+func watchLagSamples(require *require.Assertions) (uint64, float64) {
+	var metric dto.Metric
+	require.NoError(watchLagHistogram.Write(&metric))
+	return metric.GetHistogram().GetSampleCount(), metric.GetHistogram().GetSampleSum()
+}
+
+func writeWatchMetricsTestRelationships(ctx context.Context, require *require.Assertions, ds datastore.Datastore, count int) {
+	for i := 0; i < count; i++ {
+		_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			tpl := tuple.Parse(fmt.Sprintf("document:doc%d#viewer@user:tom", i))
+			return rwt.WriteRelationships([]*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(tuple.Create(tpl))})
+		})
+		require.NoError(err)
+	}
+}
+
+func TestWatchMetricsProxyLag(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds := newWatchMetricsTestDatastore(require, 0)
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	canceledBefore := testutil.ToFloat64(watchEndedCount.WithLabelValues("canceled"))
+	_, lagBefore := watchLagSamples(require)
+
+	changes, errs := ds.Watch(ctx, head)
+
+	// The consumer does not read any of the changes, so it falls behind the head revision.
+	writeWatchMetricsTestRelationships(ctx, require, ds, 3)
+
+	require.Eventually(func() bool {
+		_, lag := watchLagSamples(require)
+		return lag > lagBefore
+	}, 5*time.Second, 5*time.Millisecond)
+
+	// Once the consumer has caught up, it is no longer lagging.
+	for i := 0; i < 3; i++ {
+		select {
+		case <-changes:
+		case err := <-errs:
+			require.Failf("watch failed", "unexpected error: %s", err)
+		case <-time.After(5 * time.Second):
+			require.Fail("timed out waiting for changes")
+		}
+	}
+
+	// Samples taken after catching up are zero, so they add to the count but not the sum.
+	caughtUpCount, caughtUpLag := watchLagSamples(require)
+	require.Eventually(func() bool {
+		count, lag := watchLagSamples(require)
+		if lag != caughtUpLag {
+			caughtUpCount, caughtUpLag = count, lag
+			return false
+		}
+		return count >= caughtUpCount+3
+	}, 5*time.Second, 5*time.Millisecond)
+
+	cancel()
+	err = <-errs
+	require.True(errors.As(err, &datastore.ErrWatchCanceled{}))
+	require.Equal(canceledBefore+1, testutil.ToFloat64(watchEndedCount.WithLabelValues("canceled")))
+}
+
+func TestWatchMetricsProxyDisconnect(t *testing.T) {
+	require := require.New(t)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	ds := newWatchMetricsTestDatastore(require, 1)
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	disconnectedBefore := testutil.ToFloat64(watchEndedCount.WithLabelValues("disconnected"))
+
+	_, errs := ds.Watch(ctx, head)
+
+	// One change is held by the proxy and one by the delegate's buffer, so the third overflows.
+	writeWatchMetricsTestRelationships(ctx, require, ds, 3)
+
+	select {
+	case err := <-errs:
+		require.True(errors.As(err, &datastore.ErrWatchDisconnected{}))
+	case <-time.After(5 * time.Second):
+		require.Fail("timed out waiting for the watch to be disconnected")
+	}
+	require.Equal(disconnectedBefore+1, testutil.ToFloat64(watchEndedCount.WithLabelValues("disconnected")))
+}
+
+func TestWatchMetricsProxyOptionalInterfaces(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds := newWatchMetricsTestDatastore(require, 0)
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	// The memdb datastore reports its minimum watch revision through the proxy.
+	minRevision, err := ds.(datastore.WatchRevisionReporter).MinWatchRevision(ctx)
+	require.NoError(err)
+	require.False(minRevision.GreaterThan(head))
+
+	// It does not report a ready state, which the proxy reports as an error.
+	_, err = ds.(datastore.ReadyStateReporter).ReadyState(ctx)
+	require.Error(err)
+}
@@ -39,8 +39,13 @@ func (sr spannerReader) QueryRelationships(
 	filter *v1.RelationshipFilter,
 	opts ...options.QueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
+	queryOpts := options.NewQueryOptionsWithOptions(opts...)
 	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples).
-		FilterToResourceType(filter.ResourceType)
+		FilterToResourceTypes(append([]string{filter.ResourceType}, queryOpts.AdditionalResourceTypes...)...)
+
+	if queryOpts.IgnoreObjectIDCase {
+		qBuilder = qBuilder.IgnoringObjectIDCase()
+	}
 
 	if filter.OptionalResourceId != "" {
 		qBuilder = qBuilder.FilterToResourceID(filter.OptionalResourceId)
@@ -62,23 +67,35 @@ func (sr spannerReader) ReverseQueryRelationships(
 	subjectFilter *v1.SubjectFilter,
 	opts ...options.ReverseQueryOptionsOption,
 ) (iter datastore.RelationshipIterator, err error) {
-	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples).
-		FilterToSubjectFilter(subjectFilter)
-
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
 
+	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples).
+		FilterToReverseQuerySubject(subjectFilter, queryOpts)
+
 	if queryOpts.ResRelation != nil {
-		qBuilder = qBuilder.
-			FilterToResourceType(queryOpts.ResRelation.Namespace).
-			FilterToRelation(queryOpts.ResRelation.Relation)
+		qBuilder = qBuilder.FilterToResourceRelation(
+			queryOpts.ResRelation.Namespace,
+			queryOpts.ResRelation.Relation,
+		)
 	}
 
+	qBuilder = qBuilder.FilterToExcludedSubjects(queryOpts.ExcludedSubjects)
+
 	return sr.querySplitter.SplitAndExecuteQuery(ctx,
 		qBuilder,
 		options.WithLimit(queryOpts.ReverseLimit),
 	)
 }
 
+func (sr spannerReader) CountRelationships(ctx context.Context, filter *v1.RelationshipFilter) (uint64, error) {
+	return common.CountRelationships(ctx, sr, filter)
+}
+
+func (sr spannerReader) CheckRelationshipsExist(ctx context.Context, tuples []*core.RelationTuple) (map[string]bool, error) {
+	qBuilder := common.NewSchemaQueryFilterer(schema, queryTuples)
+	return sr.querySplitter.SplitAndCheckTuplesExist(ctx, qBuilder, tuples)
+}
+
 func queryExecutor(txSource txFactory) common.ExecuteQueryFunc {
 	return func(
 		ctx context.Context,
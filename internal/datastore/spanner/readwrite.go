@@ -10,24 +10,41 @@ import (
 	"github.com/google/uuid"
 	"github.com/jzelinskie/stringz"
 	"github.com/rs/zerolog/log"
+	"github.com/scylladb/go-set/strset"
 	"google.golang.org/protobuf/proto"
 
+	"github.com/authzed/spicedb/internal/datastore/common"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 type spannerReadWriteTXN struct {
 	spannerReader
-	ctx        context.Context
-	spannerRWT *spanner.ReadWriteTransaction
+	ctx              context.Context
+	spannerRWT       *spanner.ReadWriteTransaction
+	effectiveTouches uint64
 }
 
-func (rwt spannerReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpdate) error {
+// EffectiveTouches returns the number of touches written by the transaction which created a
+// relationship.
+func (rwt *spannerReadWriteTXN) EffectiveTouches() uint64 {
+	return rwt.effectiveTouches
+}
+
+func (rwt *spannerReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUpdate) error {
 	ctx, span := tracer.Start(rwt.ctx, "WriteTuples")
 	defer span.End()
 
 	changeUUID := uuid.New().String()
 
+	// Touches of relationships which are already living are skipped, so that they neither
+	// rewrite the row nor add a changelog entry, unless the same batch deletes them.
+	living, err := livingTouchedRelationships(ctx, rwt.spannerRWT, mutations)
+	if err != nil {
+		return fmt.Errorf(errUnableToWriteRelationships, err)
+	}
+
 	var rowCountChange int64
 
 	for _, mutation := range mutations {
@@ -35,6 +52,10 @@ func (rwt spannerReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUp
 		var op int
 		switch mutation.Operation {
 		case v1.RelationshipUpdate_OPERATION_TOUCH:
+			if living.Has(tuple.String(tuple.FromRelationship(mutation.Relationship))) {
+				continue
+			}
+			rwt.effectiveTouches++
 			rowCountChange++
 			txnMut = spanner.InsertOrUpdate(tableRelationship, allRelationshipCols, upsertVals(mutation.Relationship))
 			op = colChangeOpTouch
@@ -67,15 +88,15 @@ func (rwt spannerReadWriteTXN) WriteRelationships(mutations []*v1.RelationshipUp
 	return nil
 }
 
-func (rwt spannerReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter) error {
+func (rwt *spannerReadWriteTXN) DeleteRelationships(filter *v1.RelationshipFilter) (uint64, error) {
 	ctx, span := tracer.Start(rwt.ctx, "DeleteRelationships")
 	defer span.End()
 
-	err := deleteWithFilter(ctx, rwt.spannerRWT, filter)
+	numDeleted, err := deleteWithFilter(ctx, rwt.spannerRWT, filter)
 	if err != nil {
-		return fmt.Errorf(errUnableToDeleteRelationships, err)
+		return 0, fmt.Errorf(errUnableToDeleteRelationships, err)
 	}
-	return nil
+	return uint64(numDeleted), nil
 }
 
 type selectAndDelete struct {
@@ -89,7 +110,7 @@ func (snd selectAndDelete) Where(pred interface{}, args ...interface{}) selectAn
 	return snd
 }
 
-func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, filter *v1.RelationshipFilter) error {
+func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, filter *v1.RelationshipFilter) (int64, error) {
 	queries := selectAndDelete{queryTuples, sql.Delete(tableRelationship)}
 
 	// Add clauses for the ResourceFilter
@@ -114,7 +135,7 @@ func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, fi
 
 	ssql, sargs, err := queries.sel.ToSql()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	toDelete := rwt.Query(ctx, statementFromSQL(ssql, sargs))
@@ -150,28 +171,28 @@ func deleteWithFilter(ctx context.Context, rwt *spanner.ReadWriteTransaction, fi
 		))
 		return nil
 	}); err != nil {
-		return err
+		return 0, err
 	}
 
 	if err := rwt.BufferWrite(changelogMutations); err != nil {
-		return err
+		return 0, err
 	}
 
 	sql, args, err := queries.del.ToSql()
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	numDeleted, err := rwt.Update(ctx, statementFromSQL(sql, args))
 	if err != nil {
-		return err
+		return 0, err
 	}
 
 	if err := updateCounter(ctx, rwt, -1*numDeleted); err != nil {
-		return err
+		return 0, err
 	}
 
-	return nil
+	return numDeleted, nil
 }
 
 func upsertVals(r *v1.Relationship) []interface{} {
@@ -179,6 +200,62 @@ func upsertVals(r *v1.Relationship) []interface{} {
 	return append(key, spanner.CommitTimestamp)
 }
 
+// livingTouchedRelationships returns the keys of the relationships touched by the mutations which
+// are already living and which are not deleted by the mutations.
+func livingTouchedRelationships(ctx context.Context, rwt *spanner.ReadWriteTransaction, mutations []*v1.RelationshipUpdate) (*strset.Set, error) {
+	living := strset.New()
+	deleted := strset.New()
+	var touchedKeys []spanner.KeySet
+	for _, mutation := range mutations {
+		switch mutation.Operation {
+		case v1.RelationshipUpdate_OPERATION_TOUCH:
+			touchedKeys = append(touchedKeys, keyFromRelationship(mutation.Relationship))
+		case v1.RelationshipUpdate_OPERATION_DELETE:
+			deleted.Add(tuple.String(tuple.FromRelationship(mutation.Relationship)))
+		}
+	}
+
+	if len(touchedKeys) == 0 {
+		return living, nil
+	}
+
+	// Buffered writes are not visible to reads in the same transaction, so relationships deleted
+	// earlier in the batch are still found here, and are excluded afterwards.
+	rows := rwt.Read(ctx, tableRelationship, spanner.KeySets(touchedKeys...), []string{
+		colNamespace,
+		colObjectID,
+		colRelation,
+		colUsersetNamespace,
+		colUsersetObjectID,
+		colUsersetRelation,
+	})
+	if err := rows.Do(func(row *spanner.Row) error {
+		tpl := &core.RelationTuple{
+			ResourceAndRelation: &core.ObjectAndRelation{},
+			Subject:             &core.ObjectAndRelation{},
+		}
+		if err := row.Columns(
+			&tpl.ResourceAndRelation.Namespace,
+			&tpl.ResourceAndRelation.ObjectId,
+			&tpl.ResourceAndRelation.Relation,
+			&tpl.Subject.Namespace,
+			&tpl.Subject.ObjectId,
+			&tpl.Subject.Relation,
+		); err != nil {
+			return err
+		}
+
+		if key := tuple.String(tpl); !deleted.Has(key) {
+			living.Add(key)
+		}
+		return nil
+	}); err != nil {
+		return nil, err
+	}
+
+	return living, nil
+}
+
 func keyFromRelationship(r *v1.Relationship) spanner.Key {
 	return spanner.Key{
 		r.Resource.ObjectType,
@@ -204,7 +281,7 @@ func changeVals(changeUUID string, op int, r *v1.Relationship) []interface{} {
 	}
 }
 
-func (rwt spannerReadWriteTXN) WriteNamespaces(newConfigs ...*core.NamespaceDefinition) error {
+func (rwt *spannerReadWriteTXN) WriteNamespaces(newConfigs ...*core.NamespaceDefinition) error {
 	_, span := tracer.Start(rwt.ctx, "WriteNamespace")
 	defer span.End()
 
@@ -225,11 +302,11 @@ func (rwt spannerReadWriteTXN) WriteNamespaces(newConfigs ...*core.NamespaceDefi
 	return rwt.spannerRWT.BufferWrite(mutations)
 }
 
-func (rwt spannerReadWriteTXN) DeleteNamespace(nsName string) error {
+func (rwt *spannerReadWriteTXN) DeleteNamespace(nsName string) error {
 	ctx, span := tracer.Start(rwt.ctx, "DeleteNamespace")
 	defer span.End()
 
-	if err := deleteWithFilter(ctx, rwt.spannerRWT, &v1.RelationshipFilter{
+	if _, err := deleteWithFilter(ctx, rwt.spannerRWT, &v1.RelationshipFilter{
 		ResourceType: nsName,
 	}); err != nil {
 		return fmt.Errorf(errUnableToDeleteConfig, err)
@@ -245,4 +322,18 @@ func (rwt spannerReadWriteTXN) DeleteNamespace(nsName string) error {
 	return err
 }
 
-var _ datastore.ReadWriteTransaction = spannerReadWriteTXN{}
+func (rwt *spannerReadWriteTXN) RenameNamespace(oldName, newName string) error {
+	ctx, span := tracer.Start(rwt.ctx, "RenameNamespace")
+	defer span.End()
+
+	return common.RenameNamespace(ctx, rwt, oldName, newName)
+}
+
+func (rwt *spannerReadWriteTXN) CopyNamespace(sourceName, destName string, copyTuples bool) (uint64, error) {
+	ctx, span := tracer.Start(rwt.ctx, "CopyNamespace")
+	defer span.End()
+
+	return common.CopyNamespace(ctx, rwt, sourceName, destName, copyTuples)
+}
+
+var _ datastore.ReadWriteTransaction = &spannerReadWriteTXN{}
@@ -126,7 +126,7 @@ func (sd spannerDatastore) ReadWriteTx(
 			Executor:         queryExecutor(txSource),
 			UsersetBatchSize: usersetBatchsize,
 		}
-		rwt := spannerReadWriteTXN{spannerReader{querySplitter, txSource}, ctx, spannerRWT}
+		rwt := &spannerReadWriteTXN{spannerReader{querySplitter, txSource}, ctx, spannerRWT, 0}
 		return fn(ctx, rwt)
 	})
 	if err != nil {
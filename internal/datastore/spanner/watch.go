@@ -9,6 +9,7 @@ import (
 	sq "github.com/Masterminds/squirrel"
 
 	"github.com/authzed/spicedb/internal/datastore/common"
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 )
@@ -19,7 +20,8 @@ const (
 
 var queryChanged = sql.Select(allChangelogCols...).From(tableChangelog)
 
-func (sd spannerDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
+func (sd spannerDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	watchOpts := options.NewWatchOptionsWithOptions(opts...)
 	updates := make(chan *datastore.RevisionChanges, sd.config.watchBufferLength)
 	errs := make(chan error, 1)
 
@@ -27,12 +29,26 @@ func (sd spannerDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 		defer close(updates)
 		defer close(errs)
 
+		if watchOpts.EmitCheckpoint {
+			head, err := sd.HeadRevision(ctx)
+			if err != nil {
+				errs <- err
+				return
+			}
+
+			checkpoint := &datastore.RevisionChanges{Revision: head, IsCheckpoint: true}
+			if err := common.SendRevisionChanges(ctx, updates, checkpoint, watchOpts); err != nil {
+				errs <- err
+				return
+			}
+		}
+
 		currentTxn := timestampFromRevision(afterRevision)
 
 		for {
 			var stagedUpdates []*datastore.RevisionChanges
 			var err error
-			stagedUpdates, currentTxn, err = sd.loadChanges(ctx, currentTxn)
+			stagedUpdates, currentTxn, err = sd.loadChanges(ctx, currentTxn, watchOpts)
 			if err != nil {
 				if errors.Is(ctx.Err(), context.Canceled) {
 					errs <- datastore.NewWatchCanceledErr()
@@ -44,10 +60,8 @@ func (sd spannerDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 
 			// Write the staged updates to the channel
 			for _, changeToWrite := range stagedUpdates {
-				select {
-				case updates <- changeToWrite:
-				default:
-					errs <- datastore.NewWatchDisconnectedErr()
+				if err := common.SendRevisionChanges(ctx, updates, changeToWrite, watchOpts); err != nil {
+					errs <- err
 					return
 				}
 			}
@@ -73,8 +87,14 @@ func (sd spannerDatastore) Watch(ctx context.Context, afterRevision datastore.Re
 func (sd spannerDatastore) loadChanges(
 	ctx context.Context,
 	afterTimestamp time.Time,
+	watchOpts *options.WatchOptions,
 ) ([]*datastore.RevisionChanges, time.Time, error) {
-	sql, args, err := queryChanged.Where(sq.Gt{colChangeTS: afterTimestamp}).ToSql()
+	query := queryChanged.Where(sq.Gt{colChangeTS: afterTimestamp})
+	if len(watchOpts.Namespaces) > 0 {
+		query = query.Where(sq.Eq{colChangeNamespace: watchOpts.Namespaces})
+	}
+
+	sql, args, err := query.ToSql()
 	if err != nil {
 		return nil, afterTimestamp, err
 	}
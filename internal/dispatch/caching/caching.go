@@ -9,11 +9,17 @@ import (
 	"github.com/dustin/go-humanize"
 	"github.com/prometheus/client_golang/prometheus"
 	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"google.golang.org/protobuf/proto"
 
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/dispatch/keys"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/pkg/cache"
+	"github.com/authzed/spicedb/pkg/datastore"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
@@ -23,18 +29,32 @@ const (
 	prometheusNamespace = "spicedb"
 )
 
+var tracer = otel.Tracer("spicedb/internal/dispatch/caching")
+
 // Dispatcher is a dispatcher with built-in caching.
 type Dispatcher struct {
 	d          dispatch.Dispatcher
 	c          cache.Cache
 	keyHandler keys.Handler
 
+	// revisionQuantization, if non-zero, is the size of the windows of revisions which share
+	// cache entries.
+	revisionQuantization decimal.Decimal
+
+	// revisions resolves the revisions of requests which accept a bounded staleness, before their
+	// cache keys are computed.
+	revisions *dispatch.RecentRevisionCache
+
 	checkTotalCounter                  prometheus.Counter
 	checkFromCacheCounter              prometheus.Counter
+	expandTotalCounter                 prometheus.Counter
+	expandFromCacheCounter             prometheus.Counter
 	lookupTotalCounter                 prometheus.Counter
 	lookupFromCacheCounter             prometheus.Counter
 	reachableResourcesTotalCounter     prometheus.Counter
 	reachableResourcesFromCacheCounter prometheus.Counter
+	lookupSubjectsTotalCounter         prometheus.Counter
+	lookupSubjectsFromCacheCounter     prometheus.Counter
 
 	cacheHits        prometheus.CounterFunc
 	cacheMisses      prometheus.CounterFunc
@@ -46,6 +66,17 @@ type checkResultEntry struct {
 	response *v1.DispatchCheckResponse
 }
 
+// latestCheckResultEntry is the result of the latest computation of a check, along with the
+// revision at which it was computed, kept so that it can be reused at later revisions.
+type latestCheckResultEntry struct {
+	revision decimal.Decimal
+	response *v1.DispatchCheckResponse
+}
+
+type expandResultEntry struct {
+	response *v1.DispatchExpandResponse
+}
+
 type lookupResultEntry struct {
 	response *v1.DispatchLookupResponse
 }
@@ -54,10 +85,17 @@ type reachableResourcesResultEntry struct {
 	responses []*v1.DispatchReachableResourcesResponse
 }
 
+type lookupSubjectsResultEntry struct {
+	response *v1.DispatchLookupSubjectsResponse
+}
+
 var (
 	checkResultEntryCost            = int64(unsafe.Sizeof(checkResultEntry{}))
+	latestCheckResultEntryCost      = int64(unsafe.Sizeof(latestCheckResultEntry{}))
+	expandResultEntryEmptyCost      = int64(unsafe.Sizeof(expandResultEntry{}))
 	lookupResultEntryEmptyCost      = int64(unsafe.Sizeof(lookupResultEntry{}))
 	reachbleResourcesEntryEmptyCost = int64(unsafe.Sizeof(reachableResourcesResultEntry{}))
+	lookupSubjectsEntryEmptyCost    = int64(unsafe.Sizeof(lookupSubjectsResultEntry{}))
 )
 
 // NewCachingDispatcher creates a new dispatch.Dispatcher which delegates dispatch requests
@@ -94,6 +132,17 @@ func NewCachingDispatcher(
 		Name:      "check_from_cache_total",
 	})
 
+	expandTotalCounter := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: prometheusSubsystem,
+		Name:      "expand_total",
+	})
+	expandFromCacheCounter := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: prometheusSubsystem,
+		Name:      "expand_from_cache_total",
+	})
+
 	lookupTotalCounter := prometheus.NewCounter(prometheus.CounterOpts{
 		Namespace: prometheusNamespace,
 		Subsystem: prometheusSubsystem,
@@ -116,6 +165,17 @@ func NewCachingDispatcher(
 		Name:      "reachable_resources_from_cache_total",
 	})
 
+	lookupSubjectsTotalCounter := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: prometheusSubsystem,
+		Name:      "lookup_subjects_total",
+	})
+	lookupSubjectsFromCacheCounter := prometheus.NewCounter(prometheus.CounterOpts{
+		Namespace: prometheusNamespace,
+		Subsystem: prometheusSubsystem,
+		Name:      "lookup_subjects_from_cache_total",
+	})
+
 	cacheHitsTotal := prometheus.NewCounterFunc(prometheus.CounterOpts{
 		Namespace: prometheusNamespace,
 		Subsystem: prometheusSubsystem,
@@ -156,6 +216,14 @@ func NewCachingDispatcher(
 		if err != nil {
 			return nil, fmt.Errorf(errCachingInitialization, err)
 		}
+		err = prometheus.Register(expandTotalCounter)
+		if err != nil {
+			return nil, fmt.Errorf(errCachingInitialization, err)
+		}
+		err = prometheus.Register(expandFromCacheCounter)
+		if err != nil {
+			return nil, fmt.Errorf(errCachingInitialization, err)
+		}
 		err = prometheus.Register(lookupTotalCounter)
 		if err != nil {
 			return nil, fmt.Errorf(errCachingInitialization, err)
@@ -172,6 +240,14 @@ func NewCachingDispatcher(
 		if err != nil {
 			return nil, fmt.Errorf(errCachingInitialization, err)
 		}
+		err = prometheus.Register(lookupSubjectsTotalCounter)
+		if err != nil {
+			return nil, fmt.Errorf(errCachingInitialization, err)
+		}
+		err = prometheus.Register(lookupSubjectsFromCacheCounter)
+		if err != nil {
+			return nil, fmt.Errorf(errCachingInitialization, err)
+		}
 
 		// Export some ristretto metrics
 		err = prometheus.Register(cacheHitsTotal)
@@ -200,12 +276,17 @@ func NewCachingDispatcher(
 		d:                                  fakeDelegate{},
 		c:                                  cache,
 		keyHandler:                         keyHandler,
+		revisions:                          dispatch.NewRecentRevisionCache(),
 		checkTotalCounter:                  checkTotalCounter,
 		checkFromCacheCounter:              checkFromCacheCounter,
+		expandTotalCounter:                 expandTotalCounter,
+		expandFromCacheCounter:             expandFromCacheCounter,
 		lookupTotalCounter:                 lookupTotalCounter,
 		lookupFromCacheCounter:             lookupFromCacheCounter,
 		reachableResourcesTotalCounter:     reachableResourcesTotalCounter,
 		reachableResourcesFromCacheCounter: reachableResourcesFromCacheCounter,
+		lookupSubjectsTotalCounter:         lookupSubjectsTotalCounter,
+		lookupSubjectsFromCacheCounter:     lookupSubjectsFromCacheCounter,
 		cacheHits:                          cacheHitsTotal,
 		cacheMisses:                        cacheMissesTotal,
 		costAddedBytes:                     costAddedBytes,
@@ -218,10 +299,126 @@ func (cd *Dispatcher) SetDelegate(delegate dispatch.Dispatcher) {
 	cd.d = delegate
 }
 
+// SetRevisionQuantization sets the size of the windows of revisions which share cache entries,
+// in the units of the datastore's revisions. Every request is dispatched at the revision starting
+// its window, so that all requests in a window share the same results, trading bounded staleness
+// for a higher cache hit rate. A request never observes writes made after its own revision. A
+// window of zero disables quantization.
+func (cd *Dispatcher) SetRevisionQuantization(window uint64) {
+	cd.revisionQuantization = decimal.NewFromInt(int64(window))
+}
+
+type requestWithMetadata interface {
+	proto.Message
+	GetMetadata() *v1.ResolverMeta
+}
+
+// quantizedRequest returns the request to both key and dispatch: the request itself, or if
+// revision quantization is enabled, a copy whose revision has been rounded down to the start of
+// its window.
+func quantizedRequest[T requestWithMetadata](cd *Dispatcher, req T) T {
+	if cd.revisionQuantization.IsZero() {
+		return req
+	}
+
+	revision, err := decimal.NewFromString(req.GetMetadata().AtRevision)
+	if err != nil {
+		// Leave the request as is, so that it fails when dispatched.
+		return req
+	}
+
+	quantized := proto.Clone(req).(T)
+	quantized.GetMetadata().AtRevision = revision.Sub(revision.Mod(cd.revisionQuantization)).String()
+	return quantized
+}
+
+// latestCheckKeyPrefix prefixes the cache keys of the latest results of checks, to keep them apart
+// from the keys of results at a single revision.
+const latestCheckKeyPrefix = "latest//"
+
+// latestCheckKey returns the key under which the latest result of the check is cached, whatever
+// the revision at which it was computed, from the key of the check at its own revision.
+func latestCheckKey(req *v1.DispatchCheckRequest, requestKey string) string {
+	return latestCheckKeyPrefix + dispatch.CheckKeyWithoutRevision(req, requestKey)
+}
+
+// namespaceChangeReporter returns the reader of the datastore at the revision of the request, if
+// it can report changes to namespaces. Check results are only reused across revisions for
+// datastores whose readers are datastore.NamespaceChangeReporters, as only their latest results
+// can be invalidated.
+func namespaceChangeReporter(ctx context.Context, req *v1.DispatchCheckRequest) (datastore.NamespaceChangeReporter, decimal.Decimal, bool) {
+	revision, err := decimal.NewFromString(req.Metadata.AtRevision)
+	if err != nil {
+		return nil, decimal.Zero, false
+	}
+
+	ds := datastoremw.FromContext(ctx)
+	if ds == nil {
+		return nil, decimal.Zero, false
+	}
+
+	reporter, ok := ds.SnapshotReader(revision).(datastore.NamespaceChangeReporter)
+	return reporter, revision, ok
+}
+
+// reusableCheckResult returns the latest cached result of the check if it can answer the request:
+// it must have been computed at or before the requested revision, and none of the namespaces read
+// to compute it may have changed since.
+func (cd *Dispatcher) reusableCheckResult(
+	ctx context.Context,
+	req *v1.DispatchCheckRequest,
+	reporter datastore.NamespaceChangeReporter,
+	revision decimal.Decimal,
+	latestKey string,
+) (*v1.DispatchCheckResponse, bool) {
+	cachedResultRaw, found := cd.c.Get(latestKey)
+	if !found {
+		return nil, false
+	}
+
+	cachedResult := cachedResultRaw.(latestCheckResultEntry)
+	if req.Metadata.DepthRemaining < cachedResult.response.Metadata.DepthRequired {
+		return nil, false
+	}
+
+	if revision.LessThan(cachedResult.revision) {
+		return nil, false
+	}
+
+	for _, nsName := range cachedResult.response.Metadata.NamespacesRead {
+		changed, err := reporter.LastNamespaceChange(ctx, nsName)
+		if err != nil {
+			log.Ctx(ctx).Warn().Err(err).Str("namespace", nsName).Msg("unable to determine the last change to namespace")
+			return nil, false
+		}
+
+		if changed.GreaterThan(cachedResult.revision) {
+			return nil, false
+		}
+	}
+
+	return cachedResult.response, true
+}
+
+func checkResultEntrySize(response *v1.DispatchCheckResponse) int64 {
+	estimatedSize := checkResultEntryCost
+	if response.Explanation != nil {
+		estimatedSize += int64(proto.Size(response.Explanation))
+	}
+	return estimatedSize
+}
+
 // DispatchCheck implements dispatch.Check interface
 func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
 	cd.checkTotalCounter.Inc()
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
 
+	req, err := dispatch.ResolveRevision(ctx, req, cd.revisions)
+	if err != nil {
+		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+
+	req = quantizedRequest(cd, req)
 	requestKey, err := cd.keyHandler.ComputeCheckKey(ctx, req)
 	if err != nil {
 		return &v1.DispatchCheckResponse{Metadata: &v1.ResponseMeta{}}, err
@@ -231,10 +428,22 @@ func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRe
 		cachedResult := cachedResultRaw.(checkResultEntry)
 		if req.Metadata.DepthRemaining >= cachedResult.response.Metadata.DepthRequired {
 			cd.checkFromCacheCounter.Inc()
+			traceCacheHit(ctx, "DispatchCheck", dispatch.CheckSpanAttributes(req, true))
 			return cachedResult.response, nil
 		}
 	}
 
+	latestKey := latestCheckKey(req, requestKey)
+	reporter, revision, reusable := namespaceChangeReporter(ctx, req)
+	if reusable {
+		if reused, ok := cd.reusableCheckResult(ctx, req, reporter, revision, latestKey); ok {
+			cd.checkFromCacheCounter.Inc()
+			traceCacheHit(ctx, "DispatchCheck", dispatch.CheckSpanAttributes(req, true))
+			cd.c.Set(requestKey, checkResultEntry{reused}, checkResultEntrySize(reused))
+			return reused, nil
+		}
+	}
+
 	computed, err := cd.d.DispatchCheck(ctx, req)
 
 	// We only want to cache the result if there was no error
@@ -244,7 +453,12 @@ func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRe
 		adjustedComputed.Metadata.DispatchCount = 0
 
 		toCache := checkResultEntry{adjustedComputed}
-		cd.c.Set(requestKey, toCache, checkResultEntryCost)
+		estimatedSize := checkResultEntrySize(adjustedComputed)
+		cd.c.Set(requestKey, toCache, estimatedSize)
+
+		if reusable && len(adjustedComputed.Metadata.NamespacesRead) > 0 {
+			cd.c.Set(latestKey, latestCheckResultEntry{revision, adjustedComputed}, estimatedSize-checkResultEntryCost+latestCheckResultEntryCost)
+		}
 	}
 
 	// Return both the computed and err in ALL cases: computed contains resolved metadata even
@@ -252,22 +466,71 @@ func (cd *Dispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRe
 	return computed, err
 }
 
-// DispatchExpand implements dispatch.Expand interface and does not do any caching yet.
+// DispatchExpand implements dispatch.Expand interface. Cached trees are shared between requests,
+// so each request is returned its own copy.
 func (cd *Dispatcher) DispatchExpand(ctx context.Context, req *v1.DispatchExpandRequest) (*v1.DispatchExpandResponse, error) {
-	resp, err := cd.d.DispatchExpand(ctx, req)
-	return resp, err
+	cd.expandTotalCounter.Inc()
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+
+	req, err := dispatch.ResolveRevision(ctx, req, cd.revisions)
+	if err != nil {
+		return &v1.DispatchExpandResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+
+	req = quantizedRequest(cd, req)
+	requestKey := dispatch.ExpandRequestToKey(req)
+	if cachedResultRaw, found := cd.c.Get(requestKey); found {
+		cachedResult := cachedResultRaw.(expandResultEntry)
+		if req.Metadata.DepthRemaining >= cachedResult.response.Metadata.DepthRequired {
+			cd.expandFromCacheCounter.Inc()
+			traceCacheHit(ctx, "DispatchExpand", dispatch.ExpandSpanAttributes(req, true))
+			return proto.Clone(cachedResult.response).(*v1.DispatchExpandResponse), nil
+		}
+	}
+
+	computed, err := cd.d.DispatchExpand(ctx, req)
+
+	// We only want to cache the result if there was no error
+	if err == nil {
+		adjustedComputed := proto.Clone(computed).(*v1.DispatchExpandResponse)
+		adjustedComputed.Metadata.CachedDispatchCount = adjustedComputed.Metadata.DispatchCount
+		adjustedComputed.Metadata.DispatchCount = 0
+
+		toCache := expandResultEntry{adjustedComputed}
+		estimatedSize := expandResultEntryEmptyCost + int64(proto.Size(adjustedComputed))
+		cd.c.Set(requestKey, toCache, estimatedSize)
+	}
+
+	// Return both the computed and err in ALL cases: computed contains resolved metadata even
+	// if there was an error.
+	return computed, err
 }
 
 // DispatchLookup implements dispatch.Lookup interface and does not do any caching yet.
 func (cd *Dispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchLookupRequest) (*v1.DispatchLookupResponse, error) {
 	cd.lookupTotalCounter.Inc()
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+
+	req, err := dispatch.ResolveRevision(ctx, req, cd.revisions)
+	if err != nil {
+		return &v1.DispatchLookupResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
 
+	req = quantizedRequest(cd, req)
 	requestKey := dispatch.LookupRequestToKey(req)
 	if cachedResultRaw, found := cd.c.Get(requestKey); found {
 		cachedResult := cachedResultRaw.(lookupResultEntry)
 		if req.Metadata.DepthRemaining >= cachedResult.response.Metadata.DepthRequired {
-			log.Trace().Object("cachedLookup", req).Int("resultCount", len(cachedResult.response.ResolvedOnrs)).Send()
+			log.Ctx(ctx).Trace().Object("cachedLookup", req).Int("resultCount", len(cachedResult.response.ResolvedOnrs)).Send()
 			cd.lookupFromCacheCounter.Inc()
+			traceCacheHit(ctx, "DispatchLookup", dispatch.LookupSpanAttributes(req, true))
+
+			if cachedResult.response.DebugTrace != nil {
+				traced := proto.Clone(cachedResult.response).(*v1.DispatchLookupResponse)
+				traced.DebugTrace.IsCachedResult = true
+				return traced, nil
+			}
+
 			return cachedResult.response, nil
 		}
 	}
@@ -276,7 +539,7 @@ func (cd *Dispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchLookup
 
 	// We only want to cache the result if there was no error and nothing was excluded.
 	if err == nil && len(computed.Metadata.LookupExcludedDirect) == 0 && len(computed.Metadata.LookupExcludedTtu) == 0 {
-		log.Trace().Object("cachingLookup", req).Int("resultCount", len(computed.ResolvedOnrs)).Send()
+		log.Ctx(ctx).Trace().Object("cachingLookup", req).Int("resultCount", len(computed.ResolvedOnrs)).Send()
 
 		adjustedComputed := proto.Clone(computed).(*v1.DispatchLookupResponse)
 		adjustedComputed.Metadata.CachedDispatchCount = adjustedComputed.Metadata.DispatchCount
@@ -284,7 +547,6 @@ func (cd *Dispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchLookup
 		adjustedComputed.Metadata.LookupExcludedDirect = nil
 		adjustedComputed.Metadata.LookupExcludedTtu = nil
 
-		requestKey := dispatch.LookupRequestToKey(req)
 		toCache := lookupResultEntry{adjustedComputed}
 
 		estimatedSize := lookupResultEntryEmptyCost
@@ -303,11 +565,19 @@ func (cd *Dispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchLookup
 // DispatchReachableResources implements dispatch.ReachableResources interface and does not do any caching yet.
 func (cd *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResourcesRequest, stream dispatch.ReachableResourcesStream) error {
 	cd.reachableResourcesTotalCounter.Inc()
+	ctx, req := dispatch.WithCorrelationID(stream.Context(), req)
 
+	req, err := dispatch.ResolveRevision(ctx, req, cd.revisions)
+	if err != nil {
+		return err
+	}
+
+	req = quantizedRequest(cd, req)
 	requestKey := dispatch.ReachableResourcesRequestToKey(req)
 	if cachedResultRaw, found := cd.c.Get(requestKey); found {
 		cachedResult := cachedResultRaw.(reachableResourcesResultEntry)
 		cd.reachableResourcesFromCacheCounter.Inc()
+		traceCacheHit(ctx, "DispatchReachableResources", dispatch.ReachableResourcesSpanAttributes(req, true))
 		for _, result := range cachedResult.responses {
 			err := stream.Publish(result)
 			if err != nil {
@@ -323,7 +593,7 @@ func (cd *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResour
 	toCacheResults := []*v1.DispatchReachableResourcesResponse{}
 	wrapped := &dispatch.WrappedDispatchStream[*v1.DispatchReachableResourcesResponse]{
 		Stream: stream,
-		Ctx:    stream.Context(),
+		Ctx:    ctx,
 		Processor: func(result *v1.DispatchReachableResourcesResponse) (*v1.DispatchReachableResourcesResponse, error) {
 			mu.Lock()
 			defer mu.Unlock()
@@ -338,7 +608,7 @@ func (cd *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResour
 		},
 	}
 
-	err := cd.d.DispatchReachableResources(req, wrapped)
+	err = cd.d.DispatchReachableResources(req, wrapped)
 
 	// We only want to cache the result if there was no error
 	if err == nil {
@@ -349,13 +619,97 @@ func (cd *Dispatcher) DispatchReachableResources(req *v1.DispatchReachableResour
 	return err
 }
 
+// DispatchLookupSubjects implements dispatch.LookupSubjects interface.
+func (cd *Dispatcher) DispatchLookupSubjects(ctx context.Context, req *v1.DispatchLookupSubjectsRequest) (*v1.DispatchLookupSubjectsResponse, error) {
+	cd.lookupSubjectsTotalCounter.Inc()
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+
+	req, err := dispatch.ResolveRevision(ctx, req, cd.revisions)
+	if err != nil {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+
+	req = quantizedRequest(cd, req)
+	requestKey := dispatch.LookupSubjectsRequestToKey(req)
+	if cachedResultRaw, found := cd.c.Get(requestKey); found {
+		cachedResult := cachedResultRaw.(lookupSubjectsResultEntry)
+		if req.Metadata.DepthRemaining >= cachedResult.response.Metadata.DepthRequired {
+			log.Ctx(ctx).Trace().Object("cachedLookupSubjects", req).Int("resultCount", len(cachedResult.response.FoundSubjects)).Send()
+			cd.lookupSubjectsFromCacheCounter.Inc()
+			traceCacheHit(ctx, "DispatchLookupSubjects", dispatch.LookupSubjectsSpanAttributes(req, true))
+			return cachedResult.response, nil
+		}
+	}
+
+	computed, err := cd.d.DispatchLookupSubjects(ctx, req)
+
+	// We only want to cache the result if there was no error
+	if err == nil {
+		adjustedComputed := proto.Clone(computed).(*v1.DispatchLookupSubjectsResponse)
+		adjustedComputed.Metadata.CachedDispatchCount = adjustedComputed.Metadata.DispatchCount
+		adjustedComputed.Metadata.DispatchCount = 0
+
+		toCache := lookupSubjectsResultEntry{adjustedComputed}
+
+		estimatedSize := lookupSubjectsEntryEmptyCost
+		for _, onr := range toCache.response.FoundSubjects {
+			estimatedSize += int64(len(onr.Namespace) + len(onr.ObjectId) + len(onr.Relation))
+		}
+
+		cd.c.Set(requestKey, toCache, estimatedSize)
+	}
+
+	// Return both the computed and err in ALL cases: computed contains resolved metadata even
+	// if there was an error.
+	return computed, err
+}
+
+// DispatchLookupForSubjectType implements dispatch.LookupForSubjectType interface and does not do
+// any caching itself; the lookups of the individual subjects are cached when they are dispatched
+// through this dispatcher.
+func (cd *Dispatcher) DispatchLookupForSubjectType(ctx context.Context, req *v1.DispatchLookupForSubjectTypeRequest) (*v1.DispatchLookupForSubjectTypeResponse, error) {
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+
+	req, err := dispatch.ResolveRevision(ctx, req, cd.revisions)
+	if err != nil {
+		return &v1.DispatchLookupForSubjectTypeResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+
+	return cd.d.DispatchLookupForSubjectType(ctx, quantizedRequest(cd, req))
+}
+
+// DispatchCheckBulk implements dispatch.CheckBulk interface and does not do any caching itself;
+// the checks of the individual resource relations are cached when they are dispatched through
+// this dispatcher.
+func (cd *Dispatcher) DispatchCheckBulk(ctx context.Context, req *v1.DispatchCheckBulkRequest) (*v1.DispatchCheckBulkResponse, error) {
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+
+	req, err := dispatch.ResolveRevision(ctx, req, cd.revisions)
+	if err != nil {
+		return &v1.DispatchCheckBulkResponse{Metadata: &v1.ResponseMeta{}}, err
+	}
+
+	return cd.d.DispatchCheckBulk(ctx, req)
+}
+
+// traceCacheHit records the span of a request answered from the cache, which has the same name
+// as the span the delegate would have recorded had it computed the response.
+func traceCacheHit(ctx context.Context, name string, attrs []attribute.KeyValue) {
+	_, span := tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+	span.End()
+}
+
 func (cd *Dispatcher) Close() error {
 	prometheus.Unregister(cd.checkTotalCounter)
+	prometheus.Unregister(cd.expandTotalCounter)
+	prometheus.Unregister(cd.expandFromCacheCounter)
 	prometheus.Unregister(cd.lookupTotalCounter)
 	prometheus.Unregister(cd.reachableResourcesTotalCounter)
 	prometheus.Unregister(cd.lookupFromCacheCounter)
 	prometheus.Unregister(cd.checkFromCacheCounter)
 	prometheus.Unregister(cd.reachableResourcesFromCacheCounter)
+	prometheus.Unregister(cd.lookupSubjectsTotalCounter)
+	prometheus.Unregister(cd.lookupSubjectsFromCacheCounter)
 	prometheus.Unregister(cd.cacheHits)
 	prometheus.Unregister(cd.cacheMisses)
 	prometheus.Unregister(cd.costAddedBytes)
@@ -2,18 +2,25 @@ package caching
 
 import (
 	"context"
+	"fmt"
 	"testing"
 	"time"
 
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
 
 	"github.com/authzed/spicedb/internal/dispatch"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
+// testCorrelationID is set on the requests of tests which expect them to be passed through to
+// the delegate unchanged, since a request without one is dispatched with a generated ID.
+const testCorrelationID = "test-correlation-id"
+
 type checkRequest struct {
 	start             string
 	goal              string
@@ -91,6 +98,7 @@ func TestMaxDepthCaching(t *testing.T) {
 						Metadata: &v1.ResolverMeta{
 							AtRevision:     step.atRevision.String(),
 							DepthRemaining: step.depthRemaining,
+							CorrelationId:  testCorrelationID,
 						},
 					}).Return(&v1.DispatchCheckResponse{
 						Membership: v1.DispatchCheckResponse_MEMBER,
@@ -114,6 +122,7 @@ func TestMaxDepthCaching(t *testing.T) {
 					Metadata: &v1.ResolverMeta{
 						AtRevision:     step.atRevision.String(),
 						DepthRemaining: step.depthRemaining,
+						CorrelationId:  testCorrelationID,
 					},
 				})
 				require.NoError(err)
@@ -129,6 +138,197 @@ func TestMaxDepthCaching(t *testing.T) {
 	}
 }
 
+func TestEmptyLookupCaching(t *testing.T) {
+	require := require.New(t)
+
+	lookupRequest := func(atRevision decimal.Decimal) *v1.DispatchLookupRequest {
+		return &v1.DispatchLookupRequest{
+			ObjectRelation: &core.RelationReference{Namespace: "document", Relation: "view"},
+			Subject:        tuple.ParseSubjectONR("user:unknown#..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     atRevision.String(),
+				DepthRemaining: 50,
+				CorrelationId:  testCorrelationID,
+			},
+			Limit: 10,
+		}
+	}
+
+	delegate := delegateDispatchMock{&mock.Mock{}}
+	for _, revision := range []decimal.Decimal{decimal.Zero, decimal.NewFromInt(50)} {
+		delegate.On("DispatchLookup", lookupRequest(revision)).Return(&v1.DispatchLookupResponse{
+			Metadata: &v1.ResponseMeta{
+				DispatchCount: 1,
+				DepthRequired: 1,
+			},
+		}, nil).Times(1)
+	}
+
+	dispatch, err := NewCachingDispatcher(nil, "", nil)
+	dispatch.SetDelegate(delegate)
+	require.NoError(err)
+	defer dispatch.Close()
+
+	resp, err := dispatch.DispatchLookup(context.Background(), lookupRequest(decimal.Zero))
+	require.NoError(err)
+	require.Empty(resp.ResolvedOnrs)
+	require.Equal(uint32(1), resp.Metadata.DispatchCount)
+
+	// We have to sleep a while to let the cache converge:
+	// https://github.com/dgraph-io/ristretto/blob/01b9f37dd0fd453225e042d6f3a27cd14f252cd0/cache_test.go#L17
+	time.Sleep(10 * time.Millisecond)
+
+	// The identical empty lookup is served from the cache.
+	resp, err = dispatch.DispatchLookup(context.Background(), lookupRequest(decimal.Zero))
+	require.NoError(err)
+	require.Empty(resp.ResolvedOnrs)
+	require.Equal(uint32(0), resp.Metadata.DispatchCount)
+	require.Equal(uint32(1), resp.Metadata.CachedDispatchCount)
+
+	// The same lookup at another revision is not.
+	resp, err = dispatch.DispatchLookup(context.Background(), lookupRequest(decimal.NewFromInt(50)))
+	require.NoError(err)
+	require.Empty(resp.ResolvedOnrs)
+	require.Equal(uint32(1), resp.Metadata.DispatchCount)
+
+	delegate.AssertExpectations(t)
+}
+
+func TestRevisionQuantizedLookupCaching(t *testing.T) {
+	lookupRequest := func(atRevision int64) *v1.DispatchLookupRequest {
+		return &v1.DispatchLookupRequest{
+			ObjectRelation: &core.RelationReference{Namespace: "document", Relation: "view"},
+			Subject:        tuple.ParseSubjectONR("user:user1#..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     decimal.NewFromInt(atRevision).String(),
+				DepthRemaining: 50,
+			},
+			Limit: 10,
+		}
+	}
+
+	// Whichever request of a window comes first, the result shared by the window is computed at
+	// its start, so that no request is answered with writes made after its own revision.
+	for _, revisions := range [][]int64{{110, 150}, {150, 110}} {
+		revisions := revisions
+		t.Run(fmt.Sprintf("%d then %d", revisions[0], revisions[1]), func(t *testing.T) {
+			require := require.New(t)
+
+			delegate := delegateDispatchMock{&mock.Mock{}}
+			for _, revision := range []int64{100, 200} {
+				atRevision := decimal.NewFromInt(revision).String()
+				dispatchedAt := mock.MatchedBy(func(req *v1.DispatchLookupRequest) bool {
+					return req.Metadata.AtRevision == atRevision
+				})
+				delegate.On("DispatchLookup", dispatchedAt).Return(&v1.DispatchLookupResponse{
+					ResolvedOnrs: []*core.ObjectAndRelation{tuple.ParseONR("document:doc1#view")},
+					Metadata: &v1.ResponseMeta{
+						DispatchCount: 1,
+						DepthRequired: 1,
+					},
+				}, nil).Times(1)
+			}
+
+			dispatch, err := NewCachingDispatcher(nil, "", nil)
+			dispatch.SetDelegate(delegate)
+			dispatch.SetRevisionQuantization(100)
+			require.NoError(err)
+			defer dispatch.Close()
+
+			resp, err := dispatch.DispatchLookup(context.Background(), lookupRequest(revisions[0]))
+			require.NoError(err)
+			require.Equal(uint32(1), resp.Metadata.DispatchCount)
+
+			// We have to sleep a while to let the cache converge:
+			// https://github.com/dgraph-io/ristretto/blob/01b9f37dd0fd453225e042d6f3a27cd14f252cd0/cache_test.go#L17
+			time.Sleep(10 * time.Millisecond)
+
+			// A lookup at another revision in the same window shares the cache entry.
+			resp, err = dispatch.DispatchLookup(context.Background(), lookupRequest(revisions[1]))
+			require.NoError(err)
+			require.Len(resp.ResolvedOnrs, 1)
+			require.Equal(uint32(0), resp.Metadata.DispatchCount)
+			require.Equal(uint32(1), resp.Metadata.CachedDispatchCount)
+
+			// A lookup at a revision in the next window does not.
+			resp, err = dispatch.DispatchLookup(context.Background(), lookupRequest(210))
+			require.NoError(err)
+			require.Equal(uint32(1), resp.Metadata.DispatchCount)
+
+			delegate.AssertExpectations(t)
+		})
+	}
+}
+
+func TestExpandCaching(t *testing.T) {
+	require := require.New(t)
+
+	expandRequest := func(mode v1.DispatchExpandRequest_ExpansionMode) *v1.DispatchExpandRequest {
+		return &v1.DispatchExpandRequest{
+			ResourceAndRelation: tuple.ParseONR("document:doc1#view"),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     decimal.NewFromInt(10).String(),
+				DepthRemaining: 50,
+				CorrelationId:  testCorrelationID,
+			},
+			ExpansionMode: mode,
+		}
+	}
+
+	tree := &core.RelationTupleTreeNode{
+		NodeType: &core.RelationTupleTreeNode_LeafNode{
+			LeafNode: &core.DirectSubjects{
+				Subjects: []*core.ObjectAndRelation{tuple.ParseSubjectONR("user:user1#...")},
+			},
+		},
+		Expanded: tuple.ParseONR("document:doc1#view"),
+	}
+
+	delegate := delegateDispatchMock{&mock.Mock{}}
+	for _, mode := range []v1.DispatchExpandRequest_ExpansionMode{v1.DispatchExpandRequest_SHALLOW, v1.DispatchExpandRequest_RECURSIVE} {
+		delegate.On("DispatchExpand", expandRequest(mode)).Return(&v1.DispatchExpandResponse{
+			TreeNode: tree,
+			Metadata: &v1.ResponseMeta{
+				DispatchCount: 1,
+				DepthRequired: 1,
+			},
+		}, nil).Times(1)
+	}
+
+	dispatch, err := NewCachingDispatcher(nil, "", nil)
+	dispatch.SetDelegate(delegate)
+	require.NoError(err)
+	defer dispatch.Close()
+
+	resp, err := dispatch.DispatchExpand(context.Background(), expandRequest(v1.DispatchExpandRequest_SHALLOW))
+	require.NoError(err)
+	require.Equal(uint32(1), resp.Metadata.DispatchCount)
+
+	// We have to sleep a while to let the cache converge:
+	// https://github.com/dgraph-io/ristretto/blob/01b9f37dd0fd453225e042d6f3a27cd14f252cd0/cache_test.go#L17
+	time.Sleep(10 * time.Millisecond)
+
+	// The identical expand is served from the cache, with an identical tree.
+	cached, err := dispatch.DispatchExpand(context.Background(), expandRequest(v1.DispatchExpandRequest_SHALLOW))
+	require.NoError(err)
+	require.Equal(uint32(0), cached.Metadata.DispatchCount)
+	require.Equal(uint32(1), cached.Metadata.CachedDispatchCount)
+	require.True(proto.Equal(tree, cached.TreeNode))
+
+	// Changes made by a caller to its tree are not seen by later callers.
+	cached.TreeNode.Expanded.ObjectId = "changed"
+	cachedAgain, err := dispatch.DispatchExpand(context.Background(), expandRequest(v1.DispatchExpandRequest_SHALLOW))
+	require.NoError(err)
+	require.True(proto.Equal(tree, cachedAgain.TreeNode))
+
+	// An expand in another mode is not served from the cache.
+	resp, err = dispatch.DispatchExpand(context.Background(), expandRequest(v1.DispatchExpandRequest_RECURSIVE))
+	require.NoError(err)
+	require.Equal(uint32(1), resp.Metadata.DispatchCount)
+
+	delegate.AssertExpectations(t)
+}
+
 type delegateDispatchMock struct {
 	*mock.Mock
 }
@@ -139,17 +339,31 @@ func (ddm delegateDispatchMock) DispatchCheck(ctx context.Context, req *v1.Dispa
 }
 
 func (ddm delegateDispatchMock) DispatchExpand(ctx context.Context, req *v1.DispatchExpandRequest) (*v1.DispatchExpandResponse, error) {
-	return &v1.DispatchExpandResponse{}, nil
+	args := ddm.Called(req)
+	return args.Get(0).(*v1.DispatchExpandResponse), args.Error(1)
 }
 
 func (ddm delegateDispatchMock) DispatchLookup(ctx context.Context, req *v1.DispatchLookupRequest) (*v1.DispatchLookupResponse, error) {
-	return &v1.DispatchLookupResponse{}, nil
+	args := ddm.Called(req)
+	return args.Get(0).(*v1.DispatchLookupResponse), args.Error(1)
 }
 
 func (ddm delegateDispatchMock) DispatchReachableResources(req *v1.DispatchReachableResourcesRequest, stream dispatch.ReachableResourcesStream) error {
 	return nil
 }
 
+func (ddm delegateDispatchMock) DispatchLookupSubjects(ctx context.Context, req *v1.DispatchLookupSubjectsRequest) (*v1.DispatchLookupSubjectsResponse, error) {
+	return &v1.DispatchLookupSubjectsResponse{}, nil
+}
+
+func (ddm delegateDispatchMock) DispatchLookupForSubjectType(ctx context.Context, req *v1.DispatchLookupForSubjectTypeRequest) (*v1.DispatchLookupForSubjectTypeResponse, error) {
+	return &v1.DispatchLookupForSubjectTypeResponse{}, nil
+}
+
+func (ddm delegateDispatchMock) DispatchCheckBulk(ctx context.Context, req *v1.DispatchCheckBulkRequest) (*v1.DispatchCheckBulkResponse, error) {
+	return &v1.DispatchCheckBulkResponse{}, nil
+}
+
 func (ddm delegateDispatchMock) Close() error {
 	return nil
 }
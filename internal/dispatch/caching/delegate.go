@@ -35,4 +35,16 @@ func (fd fakeDelegate) DispatchReachableResources(req *v1.DispatchReachableResou
 	panic(errMessage)
 }
 
+func (fd fakeDelegate) DispatchLookupSubjects(ctx context.Context, req *v1.DispatchLookupSubjectsRequest) (*v1.DispatchLookupSubjectsResponse, error) {
+	panic(errMessage)
+}
+
+func (fd fakeDelegate) DispatchLookupForSubjectType(ctx context.Context, req *v1.DispatchLookupForSubjectTypeRequest) (*v1.DispatchLookupForSubjectTypeResponse, error) {
+	panic(errMessage)
+}
+
+func (fd fakeDelegate) DispatchCheckBulk(ctx context.Context, req *v1.DispatchCheckBulkRequest) (*v1.DispatchCheckBulkResponse, error) {
+	panic(errMessage)
+}
+
 var _ dispatch.Dispatcher = fakeDelegate{}
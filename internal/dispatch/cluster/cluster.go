@@ -12,8 +12,9 @@ import (
 type Option func(*optionState)
 
 type optionState struct {
-	prometheusSubsystem string
-	cacheConfig         *cache.Config
+	prometheusSubsystem  string
+	cacheConfig          *cache.Config
+	revisionQuantization uint64
 }
 
 // PrometheusSubsystem sets the subsystem name for the prometheus metrics
@@ -30,6 +31,14 @@ func CacheConfig(config *cache.Config) Option {
 	}
 }
 
+// RevisionQuantization sets the size of the windows of revisions which share entries in the
+// dispatcher's cache. See caching.Dispatcher.SetRevisionQuantization.
+func RevisionQuantization(window uint64) Option {
+	return func(state *optionState) {
+		state.revisionQuantization = window
+	}
+}
+
 // NewClusterDispatcher takes a dispatcher (such as one created by
 // combined.NewDispatcher) and returns a cluster dispatcher suitable for use as
 // the dispatcher for the dispatch grpc server.
@@ -49,5 +58,6 @@ func NewClusterDispatcher(dispatch dispatch.Dispatcher, options ...Option) (disp
 		return nil, err
 	}
 	cachingClusterDispatch.SetDelegate(clusterDispatch)
+	cachingClusterDispatch.SetRevisionQuantization(opts.revisionQuantization)
 	return cachingClusterDispatch, nil
 }
@@ -23,12 +23,13 @@ import (
 type Option func(*optionState)
 
 type optionState struct {
-	prometheusSubsystem string
-	upstreamAddr        string
-	upstreamCAPath      string
-	grpcPresharedKey    string
-	grpcDialOpts        []grpc.DialOption
-	cacheConfig         *cache.Config
+	prometheusSubsystem  string
+	upstreamAddr         string
+	upstreamCAPath       string
+	grpcPresharedKey     string
+	grpcDialOpts         []grpc.DialOption
+	cacheConfig          *cache.Config
+	revisionQuantization uint64
 }
 
 // PrometheusSubsystem sets the subsystem name for the prometheus metrics
@@ -76,6 +77,14 @@ func CacheConfig(config *cache.Config) Option {
 	}
 }
 
+// RevisionQuantization sets the size of the windows of revisions which share entries in the
+// dispatcher's cache. See caching.Dispatcher.SetRevisionQuantization.
+func RevisionQuantization(window uint64) Option {
+	return func(state *optionState) {
+		state.revisionQuantization = window
+	}
+}
+
 // NewDispatcher initializes a Dispatcher that caches and redispatches
 // optionally to the provided upstream.
 func NewDispatcher(options ...Option) (dispatch.Dispatcher, error) {
@@ -93,6 +102,7 @@ func NewDispatcher(options ...Option) (dispatch.Dispatcher, error) {
 	if err != nil {
 		return nil, err
 	}
+	cachingRedispatch.SetRevisionQuantization(opts.revisionQuantization)
 
 	redispatch := graph.NewDispatcher(cachingRedispatch)
 
@@ -0,0 +1,44 @@
+package dispatch
+
+import (
+	"context"
+
+	"github.com/google/uuid"
+	"github.com/rs/zerolog"
+	"github.com/rs/zerolog/log"
+	"google.golang.org/protobuf/proto"
+)
+
+// CorrelationIDKey is the key of the correlation ID in log statements.
+const CorrelationIDKey = "correlationID"
+
+type correlationIDContextKey struct{}
+
+// WithCorrelationID returns the request, with a newly generated correlation ID if it has none,
+// along with a context whose logger tags every statement with the request's correlation ID.
+func WithCorrelationID[T requestWithMetadata](ctx context.Context, req T) (context.Context, T) {
+	metadata := req.GetMetadata()
+	if metadata == nil {
+		return ctx, req
+	}
+
+	if metadata.CorrelationId == "" {
+		req = proto.Clone(req).(T)
+		req.GetMetadata().CorrelationId = uuid.NewString()
+	}
+
+	correlationID := req.GetMetadata().CorrelationId
+	if existing, ok := ctx.Value(correlationIDContextKey{}).(string); ok && existing == correlationID {
+		// The logger of the context is already tagged by a dispatcher higher in the traversal.
+		return ctx, req
+	}
+
+	logger := zerolog.Ctx(ctx)
+	if logger.GetLevel() == zerolog.Disabled {
+		// The context has no logger of its own.
+		logger = &log.Logger
+	}
+
+	ctx = logger.With().Str(CorrelationIDKey, correlationID).Logger().WithContext(ctx)
+	return context.WithValue(ctx, correlationIDContextKey{}, correlationID), req
+}
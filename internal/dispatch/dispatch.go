@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"strings"
 
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
@@ -21,6 +22,9 @@ type Dispatcher interface {
 	Expand
 	Lookup
 	ReachableResources
+	LookupSubjects
+	LookupForSubjectType
+	CheckBulk
 
 	// Close closes the dispatcher.
 	Close() error
@@ -59,6 +63,27 @@ type ReachableResources interface {
 	) error
 }
 
+// LookupSubjects interface describes just the methods required to dispatch lookup subjects requests.
+type LookupSubjects interface {
+	// DispatchLookupSubjects submits a single lookup subjects request and returns its result.
+	DispatchLookupSubjects(ctx context.Context, req *v1.DispatchLookupSubjectsRequest) (*v1.DispatchLookupSubjectsResponse, error)
+}
+
+// LookupForSubjectType interface describes just the methods required to dispatch lookups of the
+// resources accessible by any subject of a type.
+type LookupForSubjectType interface {
+	// DispatchLookupForSubjectType submits a single lookup for a subject type and returns its
+	// result.
+	DispatchLookupForSubjectType(ctx context.Context, req *v1.DispatchLookupForSubjectTypeRequest) (*v1.DispatchLookupForSubjectTypeResponse, error)
+}
+
+// CheckBulk interface describes just the methods required to dispatch bulk checks.
+type CheckBulk interface {
+	// DispatchCheckBulk submits a single bulk check, of one subject against many resource
+	// relations, and returns its result.
+	DispatchCheckBulk(ctx context.Context, req *v1.DispatchCheckBulkRequest) (*v1.DispatchCheckBulkResponse, error)
+}
+
 // HasMetadata is an interface for requests containing resolver metadata.
 type HasMetadata interface {
 	zerolog.LogObjectMarshaler
@@ -89,13 +114,16 @@ const (
 	lookupPrefix             cachePrefix = "l"
 	expandPrefix             cachePrefix = "e"
 	reachableResourcesPrefix cachePrefix = "rr"
+	lookupSubjectsPrefix     cachePrefix = "ls"
+	lookupSubjectTypePrefix  cachePrefix = "lt"
+	checkBulkPrefix          cachePrefix = "cb"
 )
 
-var cachePrefixes = []cachePrefix{checkViaRelationPrefix, checkViaCanonicalPrefix, lookupPrefix, expandPrefix, reachableResourcesPrefix}
+var cachePrefixes = []cachePrefix{checkViaRelationPrefix, checkViaCanonicalPrefix, lookupPrefix, expandPrefix, reachableResourcesPrefix, lookupSubjectsPrefix, lookupSubjectTypePrefix, checkBulkPrefix}
 
 // CheckRequestToKey converts a check request into a cache key based on the relation
 func CheckRequestToKey(req *v1.DispatchCheckRequest) string {
-	return fmt.Sprintf("%s//%s@%s@%s", checkViaRelationPrefix, tuple.StringONR(req.ResourceAndRelation), tuple.StringONR(req.Subject), req.Metadata.AtRevision)
+	return checkKeyWithExplain(req, fmt.Sprintf("%s//%s@%s@%s", checkViaRelationPrefix, tuple.StringONR(req.ResourceAndRelation), tuple.StringONR(req.Subject), req.Metadata.AtRevision))
 }
 
 // CheckRequestToKeyWithCanonical converts a check request into a cache key based
@@ -106,20 +134,66 @@ func CheckRequestToKeyWithCanonical(req *v1.DispatchCheckRequest, canonicalKey s
 	}
 
 	// NOTE: canonical cache keys are only unique *within* a version of a namespace.
-	return fmt.Sprintf("%s//%s:%s#%s@%s@%s", checkViaCanonicalPrefix, req.ResourceAndRelation.Namespace, req.ResourceAndRelation.ObjectId, canonicalKey, tuple.StringONR(req.Subject), req.Metadata.AtRevision)
+	return checkKeyWithExplain(req, fmt.Sprintf("%s//%s:%s#%s@%s@%s", checkViaCanonicalPrefix, req.ResourceAndRelation.Namespace, req.ResourceAndRelation.ObjectId, canonicalKey, tuple.StringONR(req.Subject), req.Metadata.AtRevision))
+}
+
+// CheckKeyWithoutRevision removes the revision of the request from a key computed for it by
+// CheckRequestToKey or CheckRequestToKeyWithCanonical.
+func CheckKeyWithoutRevision(req *v1.DispatchCheckRequest, key string) string {
+	return strings.TrimSuffix(key, checkKeyWithExplain(req, "@"+req.Metadata.AtRevision)) + checkKeyWithExplain(req, "")
+}
+
+// checkKeyWithExplain keeps the responses to requests for an explanation cached separately, as
+// only they contain one.
+func checkKeyWithExplain(req *v1.DispatchCheckRequest, key string) string {
+	if req.Explain {
+		return key + "[explain]"
+	}
+	return key
 }
 
 // LookupRequestToKey converts a lookup request into a cache key
 func LookupRequestToKey(req *v1.DispatchLookupRequest) string {
-	return fmt.Sprintf("%s//%s#%s@%s@%s", lookupPrefix, req.ObjectRelation.Namespace, req.ObjectRelation.Relation, tuple.StringONR(req.Subject), req.Metadata.AtRevision)
+	key := fmt.Sprintf("%s//%s#%s@%s@%s[%d:%s]", lookupPrefix, req.ObjectRelation.Namespace, req.ObjectRelation.Relation, tuple.StringONR(req.Subject), req.Metadata.AtRevision, req.Limit, req.PageToken)
+
+	// Responses to requests for a debug trace are cached separately, as only they contain one.
+	if req.DebugTrace {
+		key += "[debug]"
+	}
+	return key
 }
 
 // ExpandRequestToKey converts an expand request into a cache key
 func ExpandRequestToKey(req *v1.DispatchExpandRequest) string {
-	return fmt.Sprintf("%s//%s@%s", expandPrefix, tuple.StringONR(req.ResourceAndRelation), req.Metadata.AtRevision)
+	return fmt.Sprintf("%s//%s@%s[%s]", expandPrefix, tuple.StringONR(req.ResourceAndRelation), req.Metadata.AtRevision, req.ExpansionMode)
 }
 
 // ReachableResourcesRequestToKey converts a reachable resources request into a cache key
 func ReachableResourcesRequestToKey(req *v1.DispatchReachableResourcesRequest) string {
-	return fmt.Sprintf("%s//%s#%s@%s@%s", reachableResourcesPrefix, req.ObjectRelation.Namespace, req.ObjectRelation.Relation, tuple.StringONR(req.Subject), req.Metadata.AtRevision)
+	key := fmt.Sprintf("%s//%s#%s@%s@%s", reachableResourcesPrefix, req.ObjectRelation.Namespace, req.ObjectRelation.Relation, tuple.StringONR(req.Subject), req.Metadata.AtRevision)
+
+	// The responses to a limited request may be missing resources, so they are cached separately.
+	if req.Limit > 0 {
+		key += fmt.Sprintf("[%d]", req.Limit)
+	}
+	return key
+}
+
+// LookupSubjectsRequestToKey converts a lookup subjects request into a cache key
+func LookupSubjectsRequestToKey(req *v1.DispatchLookupSubjectsRequest) string {
+	return fmt.Sprintf("%s//%s@%s#%s@%s[%d]", lookupSubjectsPrefix, tuple.StringONR(req.ResourceAndRelation), req.SubjectRelation.Namespace, req.SubjectRelation.Relation, req.Metadata.AtRevision, req.Limit)
+}
+
+// LookupForSubjectTypeRequestToKey converts a lookup for a subject type into a cache key
+func LookupForSubjectTypeRequestToKey(req *v1.DispatchLookupForSubjectTypeRequest) string {
+	return fmt.Sprintf("%s//%s#%s@%s#%s@%s[%d]", lookupSubjectTypePrefix, req.ObjectRelation.Namespace, req.ObjectRelation.Relation, req.SubjectType.Namespace, req.SubjectType.Relation, req.Metadata.AtRevision, req.Limit)
+}
+
+// CheckBulkRequestToKey converts a bulk check into a cache key
+func CheckBulkRequestToKey(req *v1.DispatchCheckBulkRequest) string {
+	resources := make([]string, 0, len(req.ResourcesAndRelations))
+	for _, resource := range req.ResourcesAndRelations {
+		resources = append(resources, tuple.StringONR(resource))
+	}
+	return fmt.Sprintf("%s//%s@%s@%s", checkBulkPrefix, strings.Join(resources, ","), tuple.StringONR(req.Subject), req.Metadata.AtRevision)
 }
@@ -4,6 +4,9 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/require"
+
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 func TestCacheKeyPrefixOverlap(t *testing.T) {
@@ -14,3 +17,33 @@ func TestCacheKeyPrefixOverlap(t *testing.T) {
 		encountered[string(prefix)] = struct{}{}
 	}
 }
+
+func TestCheckKeyWithoutRevision(t *testing.T) {
+	request := func(revision string, explain bool) *v1.DispatchCheckRequest {
+		return &v1.DispatchCheckRequest{
+			ResourceAndRelation: tuple.ParseONR("document:foo#view"),
+			Subject:             tuple.ParseSubjectONR("user:tom"),
+			Metadata:            &v1.ResolverMeta{AtRevision: revision},
+			Explain:             explain,
+		}
+	}
+
+	for _, explain := range []bool{false, true} {
+		first, second := request("1", explain), request("2", explain)
+
+		require.Equal(t,
+			CheckKeyWithoutRevision(first, CheckRequestToKey(first)),
+			CheckKeyWithoutRevision(second, CheckRequestToKey(second)),
+		)
+		require.Equal(t,
+			CheckKeyWithoutRevision(first, CheckRequestToKeyWithCanonical(first, "canonical")),
+			CheckKeyWithoutRevision(second, CheckRequestToKeyWithCanonical(second, "canonical")),
+		)
+		require.NotContains(t, CheckKeyWithoutRevision(first, CheckRequestToKey(first)), "@1")
+	}
+
+	require.NotEqual(t,
+		CheckKeyWithoutRevision(request("1", false), CheckRequestToKey(request("1", false))),
+		CheckKeyWithoutRevision(request("1", true), CheckRequestToKey(request("1", true))),
+	)
+}
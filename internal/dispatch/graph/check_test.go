@@ -4,18 +4,31 @@ import (
 	"context"
 	"fmt"
 	"os"
+	"sort"
+	"strings"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	v1_api "github.com/authzed/authzed-go/proto/authzed/api/v1"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
+	"github.com/google/go-cmp/cmp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
+	"google.golang.org/protobuf/types/known/durationpb"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/proxy"
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/dispatch/caching"
 	"github.com/authzed/spicedb/internal/dispatch/keys"
@@ -287,13 +300,668 @@ func TestCheckMetadata(t *testing.T) {
 	}
 }
 
+// concurrencyCountingDispatcher records the maximum number of checks it was dispatched at once.
+type concurrencyCountingDispatcher struct {
+	dispatch.Dispatcher
+
+	current       int32
+	maxConcurrent int32
+}
+
+func (ccd *concurrencyCountingDispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	current := atomic.AddInt32(&ccd.current, 1)
+	defer atomic.AddInt32(&ccd.current, -1)
+
+	for {
+		maxConcurrent := atomic.LoadInt32(&ccd.maxConcurrent)
+		if current <= maxConcurrent || atomic.CompareAndSwapInt32(&ccd.maxConcurrent, maxConcurrent, current) {
+			break
+		}
+	}
+
+	// Hold the dispatch open long enough for concurrent dispatches to overlap.
+	time.Sleep(5 * time.Millisecond)
+	return ccd.Dispatcher.DispatchCheck(ctx, req)
+}
+
+func TestCheckConcurrencyLimit(t *testing.T) {
+	const fanOut = 20
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	ds, _ := testfixtures.StandardDatastoreWithData(rawDS, require.New(t))
+
+	// folder:wide is viewable through many folders, of which only the last has a viewer.
+	var updates []*v1_api.RelationshipUpdate
+	for i := 0; i < fanOut; i++ {
+		updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(
+			tuple.MustParse(fmt.Sprintf("folder:wide#viewer@folder:wide%d#viewer", i)),
+		)))
+	}
+	updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(
+		tuple.MustParse(fmt.Sprintf("folder:wide%d#viewer@user:wideguy#...", fanOut-1)),
+	)))
+
+	revision, err := ds.ReadWriteTx(context.Background(), func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(t, err)
+
+	for _, limit := range []int{1, 2, 5} {
+		for _, tc := range []struct {
+			subject  *core.ObjectAndRelation
+			isMember bool
+		}{
+			{ONR("user", "wideguy", graph.Ellipsis), true},
+			{ONR("user", "unknown", graph.Ellipsis), false},
+		} {
+			t.Run(fmt.Sprintf("%d/%s", limit, tuple.StringONR(tc.subject)), func(t *testing.T) {
+				require := require.New(t)
+
+				counting := &concurrencyCountingDispatcher{}
+				dispatcher := NewDispatcher(counting, WithDispatchConcurrencyLimit(limit))
+				counting.Dispatcher = dispatcher
+
+				ctx := datastoremw.ContextWithHandle(context.Background())
+				require.NoError(datastoremw.SetInContext(ctx, ds))
+
+				checkResult, err := dispatcher.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+					ResourceAndRelation: ONR("folder", "wide", "viewer"),
+					Subject:             tc.subject,
+					Metadata: &v1.ResolverMeta{
+						AtRevision:     revision.String(),
+						DepthRemaining: 50,
+					},
+				})
+				require.NoError(err)
+
+				expected := v1.DispatchCheckResponse_NOT_MEMBER
+				if tc.isMember {
+					expected = v1.DispatchCheckResponse_MEMBER
+				}
+				require.Equal(expected, checkResult.Membership)
+				require.LessOrEqual(int(atomic.LoadInt32(&counting.maxConcurrent)), limit)
+				require.Positive(atomic.LoadInt32(&counting.maxConcurrent))
+			})
+		}
+	}
+}
+
+var directCheckFastPathCases = []struct {
+	resource *core.ObjectAndRelation
+	subject  *core.ObjectAndRelation
+}{
+	{ONR("document", "masterplan", "owner"), ONR("user", "product_manager", graph.Ellipsis)},
+	{ONR("document", "masterplan", "owner"), ONR("user", "eng_lead", graph.Ellipsis)},
+	{ONR("document", "masterplan", "viewer"), ONR("user", "eng_lead", graph.Ellipsis)},
+	{ONR("document", "masterplan", "parent"), ONR("folder", "strategy", graph.Ellipsis)},
+	{ONR("document", "masterplan", "parent"), ONR("folder", "company", graph.Ellipsis)},
+	{ONR("document", "masterplan", "view"), ONR("user", "auditor", graph.Ellipsis)},
+	{ONR("document", "specialplan", "viewer_and_editor"), ONR("user", "multiroleguy", graph.Ellipsis)},
+	{ONR("folder", "company", "viewer"), ONR("user", "legal", graph.Ellipsis)},
+	{ONR("folder", "company", "viewer"), ONR("user", "auditor", graph.Ellipsis)},
+	{ONR("folder", "company", "viewer"), ONR("folder", "auditors", "viewer")},
+	{ONR("folder", "company", "viewer"), ONR("user", "villain", graph.Ellipsis)},
+	{ONR("folder", "isolated", "viewer"), ONR("user", "villain", graph.Ellipsis)},
+}
+
+func TestDirectCheckFastPath(t *testing.T) {
+	for _, tc := range directCheckFastPathCases {
+		t.Run(fmt.Sprintf("%s@%s", tuple.StringONR(tc.resource), tuple.StringONR(tc.subject)), func(t *testing.T) {
+			require := require.New(t)
+
+			var results []v1.DispatchCheckResponse_Membership
+			for _, enabled := range []bool{true, false} {
+				ctx, dispatch, revision := newLocalDispatcherWithOptions(require, WithDirectCheckFastPath(enabled))
+
+				checkResult, err := dispatch.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+					ResourceAndRelation: tc.resource,
+					Subject:             tc.subject,
+					Metadata: &v1.ResolverMeta{
+						AtRevision:     revision.String(),
+						DepthRemaining: 50,
+					},
+				})
+				require.NoError(err)
+				results = append(results, checkResult.Membership)
+			}
+
+			require.Equal(results[0], results[1], "fast path result differs from full traversal")
+		})
+	}
+}
+
+func TestCheckMinimizeLatency(t *testing.T) {
+	require := require.New(t)
+
+	ctx, dispatch, _ := newLocalDispatcher(require)
+	ds := datastoremw.MustFromContext(ctx)
+
+	check := func(maxStaleness time.Duration) v1.DispatchCheckResponse_Membership {
+		checkResult, err := dispatch.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+			ResourceAndRelation: ONR("document", "masterplan", "viewer"),
+			Subject:             ONR("user", "newviewer", graph.Ellipsis),
+			Metadata: &v1.ResolverMeta{
+				DepthRemaining:       50,
+				MaxRevisionStaleness: durationpb.New(maxStaleness),
+			},
+		})
+		require.NoError(err)
+		return checkResult.Membership
+	}
+
+	require.Equal(v1.DispatchCheckResponse_NOT_MEMBER, check(time.Hour))
+
+	_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1_api.RelationshipUpdate{{
+			Operation:    v1_api.RelationshipUpdate_OPERATION_CREATE,
+			Relationship: tuple.MustToRelationship(tuple.Parse("document:masterplan#viewer@user:newviewer")),
+		}})
+	})
+	require.NoError(err)
+
+	// The recently read revision is reused while it is within the staleness bound.
+	require.Equal(v1.DispatchCheckResponse_NOT_MEMBER, check(time.Hour))
+
+	// Once it is older than the bound, the head revision is read again.
+	time.Sleep(10 * time.Millisecond)
+	require.Equal(v1.DispatchCheckResponse_MEMBER, check(time.Millisecond))
+}
+
+func TestMinimizeLatencyForEveryRequest(t *testing.T) {
+	require := require.New(t)
+
+	ctx, cachingDispatcher, revision := newLocalDispatcher(require)
+
+	atRevision := func() *v1.ResolverMeta {
+		return &v1.ResolverMeta{AtRevision: revision.String(), DepthRemaining: 50}
+	}
+	minimizeLatency := func() *v1.ResolverMeta {
+		return &v1.ResolverMeta{DepthRemaining: 50, MaxRevisionStaleness: durationpb.New(time.Hour)}
+	}
+
+	for name, dispatcher := range map[string]dispatch.Dispatcher{
+		"caching": cachingDispatcher,
+		"local":   NewLocalOnlyDispatcher(),
+	} {
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			expand := func(metadata *v1.ResolverMeta) *core.RelationTupleTreeNode {
+				resp, err := dispatcher.DispatchExpand(ctx, &v1.DispatchExpandRequest{
+					ResourceAndRelation: ONR("document", "masterplan", "view"),
+					Metadata:            metadata,
+					ExpansionMode:       v1.DispatchExpandRequest_SHALLOW,
+				})
+				require.NoError(err)
+				return resp.TreeNode
+			}
+			require.True(proto.Equal(expand(atRevision()), expand(minimizeLatency())))
+
+			lookup := func(metadata *v1.ResolverMeta) []*core.ObjectAndRelation {
+				resp, err := dispatcher.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+					ObjectRelation: RR("document", "view"),
+					Subject:        ONR("user", "legal", "..."),
+					Metadata:       metadata,
+					Limit:          10,
+				})
+				require.NoError(err)
+				return resp.ResolvedOnrs
+			}
+			require.ElementsMatch(lookup(atRevision()), lookup(minimizeLatency()))
+
+			reachableResources := func(metadata *v1.ResolverMeta) []string {
+				stream := dispatch.NewCollectingDispatchStream[*v1.DispatchReachableResourcesResponse](ctx)
+				err := dispatcher.DispatchReachableResources(&v1.DispatchReachableResourcesRequest{
+					ObjectRelation: RR("document", "view"),
+					Subject:        ONR("user", "legal", "..."),
+					Metadata:       metadata,
+				}, stream)
+				require.NoError(err)
+
+				var found []string
+				for _, result := range stream.Results() {
+					found = append(found, tuple.StringONR(result.Resource.Resource))
+				}
+				return found
+			}
+			require.ElementsMatch(reachableResources(atRevision()), reachableResources(minimizeLatency()))
+
+			lookupSubjects := func(metadata *v1.ResolverMeta) []*core.ObjectAndRelation {
+				resp, err := dispatcher.DispatchLookupSubjects(ctx, &v1.DispatchLookupSubjectsRequest{
+					ResourceAndRelation: ONR("document", "masterplan", "view"),
+					SubjectRelation:     RR("user", "..."),
+					Metadata:            metadata,
+					Limit:               10,
+				})
+				require.NoError(err)
+				return resp.FoundSubjects
+			}
+			require.ElementsMatch(lookupSubjects(atRevision()), lookupSubjects(minimizeLatency()))
+
+			lookupForSubjectType := func(metadata *v1.ResolverMeta) int {
+				resp, err := dispatcher.DispatchLookupForSubjectType(ctx, &v1.DispatchLookupForSubjectTypeRequest{
+					ObjectRelation: RR("document", "view"),
+					SubjectType:    RR("user", "..."),
+					Metadata:       metadata,
+					Limit:          100,
+				})
+				require.NoError(err)
+				return len(resp.Results)
+			}
+			require.Equal(lookupForSubjectType(atRevision()), lookupForSubjectType(minimizeLatency()))
+
+			checkBulk := func(metadata *v1.ResolverMeta) []v1.DispatchCheckResponse_Membership {
+				resp, err := dispatcher.DispatchCheckBulk(ctx, &v1.DispatchCheckBulkRequest{
+					ResourcesAndRelations: []*core.ObjectAndRelation{
+						ONR("document", "masterplan", "view"),
+						ONR("document", "healthplan", "view"),
+					},
+					Subject:  ONR("user", "legal", "..."),
+					Metadata: metadata,
+				})
+				require.NoError(err)
+
+				var memberships []v1.DispatchCheckResponse_Membership
+				for _, result := range resp.Results {
+					memberships = append(memberships, result.Membership)
+				}
+				return memberships
+			}
+			require.Equal(checkBulk(atRevision()), checkBulk(minimizeLatency()))
+		})
+	}
+}
+
+func relationshipStep(tpl string) *v1.CheckExplanationStep {
+	return &v1.CheckExplanationStep{
+		Step: &v1.CheckExplanationStep_Relationship{Relationship: tuple.MustParse(tpl)},
+	}
+}
+
+func rewriteStep(from, to *core.ObjectAndRelation) *v1.CheckExplanationStep {
+	return &v1.CheckExplanationStep{
+		Step: &v1.CheckExplanationStep_Rewrite{Rewrite: &v1.CheckExplanationRewrite{From: from, To: to}},
+	}
+}
+
+func TestCheckExplanation(t *testing.T) {
+	testCases := []struct {
+		name              string
+		resource          *core.ObjectAndRelation
+		subject           *core.ObjectAndRelation
+		expectedGrantPath []*v1.CheckExplanationStep
+		expectedExplored  []*core.ObjectAndRelation
+	}{
+		{
+			"viewer grant",
+			ONR("document", "masterplan", "view"),
+			ONR("user", "eng_lead", graph.Ellipsis),
+			[]*v1.CheckExplanationStep{
+				rewriteStep(ONR("document", "masterplan", "view"), ONR("document", "masterplan", "viewer")),
+				relationshipStep("document:masterplan#viewer@user:eng_lead"),
+			},
+			nil,
+		},
+		{
+			"grant through a userset",
+			ONR("folder", "company", "view"),
+			ONR("user", "auditor", graph.Ellipsis),
+			[]*v1.CheckExplanationStep{
+				rewriteStep(ONR("folder", "company", "view"), ONR("folder", "company", "viewer")),
+				relationshipStep("folder:company#viewer@folder:auditors#viewer"),
+				relationshipStep("folder:auditors#viewer@user:auditor"),
+			},
+			nil,
+		},
+		{
+			"grant through a parent",
+			ONR("document", "healthplan", "view"),
+			ONR("user", "chief_financial_officer", graph.Ellipsis),
+			[]*v1.CheckExplanationStep{
+				relationshipStep("document:healthplan#parent@folder:plans"),
+				rewriteStep(ONR("document", "healthplan", "view"), ONR("folder", "plans", "view")),
+				rewriteStep(ONR("folder", "plans", "view"), ONR("folder", "plans", "viewer")),
+				relationshipStep("folder:plans#viewer@user:chief_financial_officer"),
+			},
+			nil,
+		},
+		{
+			"deny",
+			ONR("document", "healthplan", "view"),
+			ONR("user", "villain", graph.Ellipsis),
+			nil,
+			[]*core.ObjectAndRelation{
+				ONR("document", "healthplan", "view"),
+				ONR("document", "healthplan", "viewer"),
+				ONR("folder", "plans", "view"),
+				ONR("folder", "plans", "viewer"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		for _, fastPath := range []bool{true, false} {
+			t.Run(fmt.Sprintf("%s/fastpath=%t", tc.name, fastPath), func(t *testing.T) {
+				require := require.New(t)
+
+				ctx, dispatch, revision := newLocalDispatcherWithOptions(require, WithDirectCheckFastPath(fastPath))
+
+				req := &v1.DispatchCheckRequest{
+					ResourceAndRelation: tc.resource,
+					Subject:             tc.subject,
+					Metadata: &v1.ResolverMeta{
+						AtRevision:     revision.String(),
+						DepthRemaining: 50,
+					},
+					Explain: true,
+				}
+
+				checkResult, err := dispatch.DispatchCheck(ctx, req)
+				require.NoError(err)
+				require.NotNil(checkResult.Explanation)
+
+				if diff := cmp.Diff(tc.expectedGrantPath, checkResult.Explanation.GrantPath, protocmp.Transform()); diff != "" {
+					t.Fatalf("unexpected grant path (-want +got):\n%s", diff)
+				}
+
+				if tc.expectedGrantPath != nil {
+					require.Equal(v1.DispatchCheckResponse_MEMBER, checkResult.Membership)
+					require.Empty(checkResult.Explanation.Explored)
+				} else {
+					require.Equal(v1.DispatchCheckResponse_NOT_MEMBER, checkResult.Membership)
+
+					explored := make(map[string]struct{}, len(checkResult.Explanation.Explored))
+					for _, onr := range checkResult.Explanation.Explored {
+						explored[tuple.StringONR(onr)] = struct{}{}
+					}
+					for _, onr := range tc.expectedExplored {
+						require.Contains(explored, tuple.StringONR(onr))
+					}
+				}
+
+				// The explanation is only included if requested, and explained results are not
+				// returned from the cache for unexplained requests.
+				unexplainedReq := proto.Clone(req).(*v1.DispatchCheckRequest)
+				unexplainedReq.Explain = false
+				unexplainedResult, err := dispatch.DispatchCheck(ctx, unexplainedReq)
+				require.NoError(err)
+				require.Equal(checkResult.Membership, unexplainedResult.Membership)
+				require.Nil(unexplainedResult.Explanation)
+			})
+		}
+	}
+}
+
+func BenchmarkDirectCheck(b *testing.B) {
+	for _, enabled := range []bool{true, false} {
+		b.Run(fmt.Sprintf("fastpath=%t", enabled), func(b *testing.B) {
+			require := require.New(b)
+
+			rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+			ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+			// Dispatch directly to the local dispatcher, so that results are not cached.
+			dispatch := NewLocalOnlyDispatcher(WithDirectCheckFastPath(enabled))
+			ctx := datastoremw.ContextWithHandle(context.Background())
+			require.NoError(datastoremw.SetInContext(ctx, ds))
+
+			b.ResetTimer()
+			for i := 0; i < b.N; i++ {
+				tc := directCheckFastPathCases[i%len(directCheckFastPathCases)]
+				_, err := dispatch.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+					ResourceAndRelation: tc.resource,
+					Subject:             tc.subject,
+					Metadata: &v1.ResolverMeta{
+						AtRevision:     revision.String(),
+						DepthRemaining: 50,
+					},
+				})
+				require.NoError(err)
+			}
+		})
+	}
+}
+
+func TestCheckDispatchSpans(t *testing.T) {
+	require := require.New(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	ctx, dispatch, revision := newLocalDispatcher(require)
+
+	req := &v1.DispatchCheckRequest{
+		ResourceAndRelation: ONR("folder", "company", "view"),
+		Subject:             ONR("user", "villain", graph.Ellipsis),
+		Metadata: &v1.ResolverMeta{
+			AtRevision:     revision.String(),
+			DepthRemaining: 50,
+		},
+	}
+
+	checkResult, err := dispatch.DispatchCheck(ctx, req)
+	require.NoError(err)
+	require.Equal(v1.DispatchCheckResponse_NOT_MEMBER, checkResult.Membership)
+
+	// The view relation is the union of viewer, which contains the auditors folder's viewers, edit,
+	// which is in turn the union of editor and owner, and the view relation of the parent folder,
+	// of which there is none.
+	spans := checkSpans(recorder)
+	require.Len(spans, 6)
+	require.Equal(
+		"folder:company#view(folder:company#edit(folder:company#editor,folder:company#owner),folder:company#viewer(folder:auditors#viewer))",
+		spanTree(spans, spans[len(spans)-1], 50, require),
+	)
+
+	// The repeated check is answered from the cache, without recursing.
+	_, err = dispatch.DispatchCheck(ctx, req)
+	require.NoError(err)
+
+	spans = checkSpans(recorder)[6:]
+	require.Len(spans, 1)
+	require.Equal("folder:company#view", spanAttribute(spans[0], "start").AsString())
+	require.True(spanAttribute(spans[0], "cached").AsBool())
+}
+
+func TestCheckCachedAcrossRevisions(t *testing.T) {
+	require := require.New(t)
+
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	// The checks read from the memdb datastore directly, since it reports when namespaces change.
+	_, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+	ds := rawDS
+
+	// Redispatch through the cache, so that the results of subproblems are cached too.
+	cachingDispatcher, err := caching.NewCachingDispatcher(nil, "", &keys.CanonicalKeyHandler{})
+	require.NoError(err)
+	cachingDispatcher.SetDelegate(NewDispatcher(cachingDispatcher))
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	check := func(revision decimal.Decimal) []sdktrace.ReadOnlySpan {
+		before := len(checkSpans(recorder))
+
+		checkResult, err := cachingDispatcher.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+			ResourceAndRelation: ONR("document", "companyplan", "view"),
+			Subject:             ONR("user", "legal", graph.Ellipsis),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+		})
+		require.NoError(err)
+		require.Equal(v1.DispatchCheckResponse_MEMBER, checkResult.Membership)
+		require.Equal([]string{"document", "folder"}, checkResult.Metadata.NamespacesRead)
+
+		// Wait for the results to be cached.
+		time.Sleep(10 * time.Millisecond)
+		return checkSpans(recorder)[before:]
+	}
+
+	cachedSpan := func(spans []sdktrace.ReadOnlySpan, start string) bool {
+		for _, span := range spans {
+			if spanAttribute(span, "start").AsString() == start {
+				return spanAttribute(span, "cached").AsBool()
+			}
+		}
+		require.Fail("missing span", start)
+		return false
+	}
+
+	spans := check(revision)
+	require.False(cachedSpan(spans, "document:companyplan#view"))
+	require.False(cachedSpan(spans, "folder:company#view"))
+
+	// A write to the document namespace invalidates the check of the document, but the check
+	// of its parent folder reads only the folder namespace, so it is still answered from the
+	// cache.
+	unrelatedRevision, err := datastore.WriteTuples(ctx, ds, []*core.RelationTupleUpdate{
+		tuple.Touch(tuple.MustParse("document:masterplan#viewer@user:unrelated#...")),
+	})
+	require.NoError(err)
+
+	spans = check(unrelatedRevision)
+	require.False(cachedSpan(spans, "document:companyplan#view"))
+	require.True(cachedSpan(spans, "folder:company#view"))
+
+	// A write to the folder namespace invalidates the check of the folder.
+	relatedRevision, err := datastore.WriteTuples(ctx, ds, []*core.RelationTupleUpdate{
+		tuple.Touch(tuple.MustParse("folder:company#viewer@user:unrelated#...")),
+	})
+	require.NoError(err)
+
+	spans = check(relatedRevision)
+	require.False(cachedSpan(spans, "document:companyplan#view"))
+	require.False(cachedSpan(spans, "folder:company#view"))
+}
+
+func TestCheckNotCachedAcrossRevisionsWithoutNamespaceChanges(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	_, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	// The readers of the caching proxy do not report when namespaces change, so results can
+	// only be reused at the revision at which they were computed.
+	ds, err := proxy.NewCachingDatastoreProxy(rawDS, nil)
+	require.NoError(err)
+
+	cachingDispatcher, err := caching.NewCachingDispatcher(nil, "", &keys.CanonicalKeyHandler{})
+	require.NoError(err)
+
+	var dispatchCount int64
+	cachingDispatcher.SetDelegate(countingCheckDispatcher{NewDispatcher(cachingDispatcher), &dispatchCount})
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	check := func(revision decimal.Decimal) {
+		checkResult, err := cachingDispatcher.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+			ResourceAndRelation: ONR("document", "companyplan", "view"),
+			Subject:             ONR("user", "legal", graph.Ellipsis),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+		})
+		require.NoError(err)
+		require.Equal(v1.DispatchCheckResponse_MEMBER, checkResult.Membership)
+
+		// Wait for the results to be cached.
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	check(revision)
+	require.NotZero(atomic.LoadInt64(&dispatchCount))
+
+	atomic.StoreInt64(&dispatchCount, 0)
+	check(revision)
+	require.Zero(atomic.LoadInt64(&dispatchCount))
+
+	unrelatedRevision, err := datastore.WriteTuples(ctx, ds, []*core.RelationTupleUpdate{
+		tuple.Touch(tuple.MustParse("document:masterplan#viewer@user:unrelated#...")),
+	})
+	require.NoError(err)
+
+	check(unrelatedRevision)
+	require.NotZero(atomic.LoadInt64(&dispatchCount))
+}
+
+// countingCheckDispatcher counts the checks dispatched to its delegate.
+type countingCheckDispatcher struct {
+	dispatch.Dispatcher
+	count *int64
+}
+
+func (d countingCheckDispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	atomic.AddInt64(d.count, 1)
+	return d.Dispatcher.DispatchCheck(ctx, req)
+}
+
+func checkSpans(recorder *tracetest.SpanRecorder) []sdktrace.ReadOnlySpan {
+	var spans []sdktrace.ReadOnlySpan
+	for _, span := range recorder.Ended() {
+		if span.Name() == "DispatchCheck" {
+			spans = append(spans, span)
+		}
+	}
+	return spans
+}
+
+// spanTree formats the tree of spans rooted at root, asserting that the depth remaining is
+// decremented at each level of the recursion.
+func spanTree(spans []sdktrace.ReadOnlySpan, root sdktrace.ReadOnlySpan, depthRemaining int64, require *require.Assertions) string {
+	require.Equal(depthRemaining, spanAttribute(root, "depthRemaining").AsInt64())
+	require.False(spanAttribute(root, "cached").AsBool())
+
+	var children []string
+	for _, span := range spans {
+		if span.Parent().SpanID() == root.SpanContext().SpanID() {
+			children = append(children, spanTree(spans, span, depthRemaining-1, require))
+		}
+	}
+
+	start := spanAttribute(root, "start").AsString()
+	if len(children) == 0 {
+		return start
+	}
+
+	sort.Strings(children)
+	return fmt.Sprintf("%s(%s)", start, strings.Join(children, ","))
+}
+
+func spanAttribute(span sdktrace.ReadOnlySpan, key string) attribute.Value {
+	for _, attr := range span.Attributes() {
+		if string(attr.Key) == key {
+			return attr.Value
+		}
+	}
+	return attribute.Value{}
+}
+
 func newLocalDispatcher(require *require.Assertions) (context.Context, dispatch.Dispatcher, decimal.Decimal) {
+	return newLocalDispatcherWithOptions(require)
+}
+
+func newLocalDispatcherWithOptions(require *require.Assertions, options ...Option) (context.Context, dispatch.Dispatcher, decimal.Decimal) {
 	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
 	require.NoError(err)
 
 	ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
 
-	dispatch := NewLocalOnlyDispatcher()
+	dispatch := NewLocalOnlyDispatcher(options...)
 
 	cachingDispatcher, err := caching.NewCachingDispatcher(nil, "", &keys.CanonicalKeyHandler{})
 	cachingDispatcher.SetDelegate(dispatch)
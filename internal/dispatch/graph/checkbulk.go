@@ -0,0 +1,85 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+type checkMemoKey struct{}
+
+// checkMemo holds the results of the checks completed while evaluating a bulk check, so that
+// checks shared between its pairs are only evaluated once.
+type checkMemo struct {
+	results map[string]*v1.DispatchCheckResponse
+	mu      sync.Mutex
+}
+
+// DispatchCheckBulk implements dispatch.CheckBulk interface
+//
+// The resource relations are checked at the same revision, one after another, through the
+// dispatcher's redispatcher. Checks completed by this dispatcher while checking any of them,
+// including those of sub-problems, are reused for the rest instead of being evaluated again.
+// Sub-problems dispatched to other nodes are not shared.
+func (ld *localDispatcher) DispatchCheckBulk(ctx context.Context, req *v1.DispatchCheckBulkRequest) (*v1.DispatchCheckBulkResponse, error) {
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+	ctx, span := tracer.Start(ctx, "DispatchCheckBulk", trace.WithAttributes(dispatch.CheckBulkSpanAttributes(req, false)...))
+	defer span.End()
+
+	err := dispatch.CheckDepth(ctx, req)
+	if err != nil {
+		return &v1.DispatchCheckBulkResponse{Metadata: emptyMetadata}, err
+	}
+
+	req, err = dispatch.ResolveRevision(ctx, req, ld.revisions)
+	if err != nil {
+		return &v1.DispatchCheckBulkResponse{Metadata: emptyMetadata}, err
+	}
+
+	ctx = context.WithValue(ctx, checkMemoKey{}, &checkMemo{
+		results: make(map[string]*v1.DispatchCheckResponse),
+	})
+
+	return ld.bulkChecker.CheckBulk(ctx, req)
+}
+
+// memoizedCheck returns the result of the check from the bulk check memo in the context, if it
+// was completed with enough depth, and otherwise computes it and records it in the memo.
+func memoizedCheck(
+	ctx context.Context,
+	req *v1.DispatchCheckRequest,
+	compute func(context.Context, *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error),
+) (*v1.DispatchCheckResponse, error) {
+	memo, ok := ctx.Value(checkMemoKey{}).(*checkMemo)
+	if !ok {
+		return compute(ctx, req)
+	}
+
+	key := dispatch.CheckRequestToKey(req)
+
+	memo.mu.Lock()
+	found, ok := memo.results[key]
+	memo.mu.Unlock()
+
+	if ok && req.Metadata.DepthRemaining >= found.Metadata.DepthRequired {
+		return found, nil
+	}
+
+	computed, err := compute(ctx, req)
+	if err == nil {
+		adjustedComputed := proto.Clone(computed).(*v1.DispatchCheckResponse)
+		adjustedComputed.Metadata.CachedDispatchCount = adjustedComputed.Metadata.DispatchCount
+		adjustedComputed.Metadata.DispatchCount = 0
+
+		memo.mu.Lock()
+		memo.results[key] = adjustedComputed
+		memo.mu.Unlock()
+	}
+
+	return computed, err
+}
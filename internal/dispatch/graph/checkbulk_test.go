@@ -0,0 +1,147 @@
+package graph
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+
+	v1_api "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/dispatch"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+type queryCountingDatastore struct {
+	datastore.Datastore
+	queries *int64
+}
+
+func (qcd queryCountingDatastore) SnapshotReader(revision datastore.Revision) datastore.Reader {
+	return queryCountingReader{qcd.Datastore.SnapshotReader(revision), qcd.queries}
+}
+
+type queryCountingReader struct {
+	datastore.Reader
+	queries *int64
+}
+
+func (qcr queryCountingReader) QueryRelationships(ctx context.Context, filter *v1_api.RelationshipFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	atomic.AddInt64(qcr.queries, 1)
+	return qcr.Reader.QueryRelationships(ctx, filter, opts...)
+}
+
+func (qcr queryCountingReader) ReverseQueryRelationships(ctx context.Context, subjectFilter *v1_api.SubjectFilter, opts ...options.ReverseQueryOptionsOption) (datastore.RelationshipIterator, error) {
+	atomic.AddInt64(qcr.queries, 1)
+	return qcr.Reader.ReverseQueryRelationships(ctx, subjectFilter, opts...)
+}
+
+func TestDispatchCheckBulk(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	var queries int64
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, queryCountingDatastore{ds, &queries}))
+
+	dispatcher := NewLocalOnlyDispatcher()
+
+	// The documents share the folders they are in, which are also checked directly.
+	resources := []*core.ObjectAndRelation{
+		ONR("document", "masterplan", "view"),
+		ONR("document", "companyplan", "view"),
+		ONR("document", "healthplan", "view"),
+		ONR("folder", "strategy", "view"),
+		ONR("folder", "company", "view"),
+		ONR("folder", "plans", "view"),
+	}
+
+	for _, subject := range []*core.ObjectAndRelation{
+		ONR("user", "auditor", "..."),
+		ONR("user", "villain", "..."),
+	} {
+		metadata := &v1.ResolverMeta{
+			AtRevision:     revision.String(),
+			DepthRemaining: 50,
+		}
+
+		atomic.StoreInt64(&queries, 0)
+		var expected []v1.DispatchCheckResponse_Membership
+		for _, resource := range resources {
+			result, err := dispatcher.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+				ResourceAndRelation: resource,
+				Subject:             subject,
+				Metadata:            metadata,
+			})
+			require.NoError(err)
+			expected = append(expected, result.Membership)
+		}
+		individualQueries := atomic.LoadInt64(&queries)
+
+		atomic.StoreInt64(&queries, 0)
+		bulk, err := dispatcher.DispatchCheckBulk(ctx, &v1.DispatchCheckBulkRequest{
+			ResourcesAndRelations: resources,
+			Subject:               subject,
+			Metadata:              metadata,
+		})
+		require.NoError(err)
+		bulkQueries := atomic.LoadInt64(&queries)
+
+		require.Len(bulk.Results, len(resources))
+		for i, result := range bulk.Results {
+			require.Equal(expected[i], result.Membership, "mismatch for %s", resources[i])
+		}
+
+		if subject.ObjectId == "villain" {
+			// Every check is fully evaluated when the subject is not a member of any of the
+			// resources, so the number of queries is deterministic.
+			require.Less(bulkQueries, individualQueries)
+		}
+	}
+}
+
+func TestDispatchCheckBulkDepth(t *testing.T) {
+	require := require.New(t)
+
+	ctx, dispatcher, revision := newLocalDispatcher(require)
+
+	request := func(depth uint32) *v1.DispatchCheckBulkRequest {
+		return &v1.DispatchCheckBulkRequest{
+			ResourcesAndRelations: []*core.ObjectAndRelation{
+				ONR("document", "masterplan", "view"),
+				ONR("folder", "strategy", "view"),
+			},
+			Subject: ONR("user", "auditor", "..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: depth,
+			},
+		}
+	}
+
+	_, err := dispatcher.DispatchCheckBulk(ctx, request(0))
+	require.ErrorIs(err, dispatch.ErrMaxDepth)
+
+	// The bulk check requires one more level of depth than the deepest of its checks, which are
+	// each given the depth remaining after it.
+	bulk, err := dispatcher.DispatchCheckBulk(ctx, request(50))
+	require.NoError(err)
+
+	var deepest uint32
+	for _, result := range bulk.Results {
+		if result.Metadata.DepthRequired > deepest {
+			deepest = result.Metadata.DepthRequired
+		}
+	}
+	require.Equal(deepest+1, bulk.Metadata.DepthRequired)
+}
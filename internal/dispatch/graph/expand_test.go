@@ -312,3 +312,74 @@ func TestMaxDepthExpand(t *testing.T) {
 
 	require.Error(err)
 }
+
+func TestExpandNodeLimit(t *testing.T) {
+	// folder:groups#viewer holds three nested groups, each of which holds two users, for ten
+	// distinct nodes in all.
+	var relationships []string
+	for i := 0; i < 3; i++ {
+		relationships = append(relationships, fmt.Sprintf("folder:groups#viewer@folder:group%d#viewer", i))
+		for j := 0; j < 2; j++ {
+			relationships = append(relationships, fmt.Sprintf("folder:group%d#viewer@user:user%d_%d#...", i, i, j))
+		}
+	}
+
+	testCases := []struct {
+		nodeLimit            uint32
+		expectedDepthReached uint32
+		expectedTooLarge     bool
+	}{
+		{0, 0, false},
+		{11, 0, false},
+		{10, 0, false},
+		{9, 1, true},
+		{2, 1, true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("limit-%d", tc.nodeLimit), func(t *testing.T) {
+			require := require.New(t)
+
+			rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+
+			ds, _ := testfixtures.StandardDatastoreWithSchema(rawDS, require)
+
+			var mutations []*v1_api.RelationshipUpdate
+			for _, relationship := range relationships {
+				mutations = append(mutations, tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse(relationship))))
+			}
+
+			ctx := datastoremw.ContextWithHandle(context.Background())
+
+			revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				return rwt.WriteRelationships(mutations)
+			})
+			require.NoError(err)
+			require.NoError(datastoremw.SetInContext(ctx, ds))
+
+			dispatch := NewLocalOnlyDispatcher(WithExpandNodeLimit(tc.nodeLimit))
+
+			resp, err := dispatch.DispatchExpand(ctx, &v1.DispatchExpandRequest{
+				ResourceAndRelation: ONR("folder", "groups", "viewer"),
+				Metadata: &v1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: 50,
+				},
+				ExpansionMode: v1.DispatchExpandRequest_RECURSIVE,
+			})
+
+			if !tc.expectedTooLarge {
+				require.NoError(err)
+				require.NotNil(resp.TreeNode)
+				return
+			}
+
+			var tooLargeErr expand.ErrExpandTooLarge
+			require.ErrorAs(err, &tooLargeErr)
+			require.Equal(tc.nodeLimit, tooLargeErr.NodeLimit())
+			require.Equal(tc.expectedDepthReached, tooLargeErr.DepthReached())
+			require.Nil(resp.TreeNode)
+		})
+	}
+}
@@ -4,10 +4,10 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"runtime"
 
 	"github.com/shopspring/decimal"
 	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/trace"
 
 	"github.com/authzed/spicedb/internal/dispatch"
@@ -16,46 +16,112 @@ import (
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
-	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 const errDispatch = "error dispatching request: %w"
 
 var tracer = otel.Tracer("spicedb/internal/dispatch/local")
 
+// Option is a function-style option for configuring a graph dispatcher.
+type Option func(*optionState)
+
+type optionState struct {
+	concurrencyLimit    int
+	directCheckFastPath bool
+	expandNodeLimit     uint32
+}
+
+// WithDispatchConcurrencyLimit sets the maximum number of subproblems, such as the branches of a
+// union or intersection, that the dispatcher runs concurrently. Subproblems beyond the limit are
+// run serially. Defaults to GOMAXPROCS.
+func WithDispatchConcurrencyLimit(limit int) Option {
+	return func(state *optionState) {
+		state.concurrencyLimit = limit
+	}
+}
+
+// WithDirectCheckFastPath sets whether checks of relations without rewrites first look for the
+// subject being directly related to the resource, before loading all of the relationships of the
+// resource. Defaults to enabled.
+func WithDirectCheckFastPath(enabled bool) Option {
+	return func(state *optionState) {
+		state.directCheckFastPath = enabled
+	}
+}
+
+// WithExpandNodeLimit sets the maximum number of distinct nodes, being the usersets expanded and
+// the subjects found, that an expansion may reach before failing with graph.ErrExpandTooLarge.
+// Defaults to zero, which does not limit expansions.
+func WithExpandNodeLimit(limit uint32) Option {
+	return func(state *optionState) {
+		state.expandNodeLimit = limit
+	}
+}
+
+func newOptionState(options []Option) *optionState {
+	state := &optionState{
+		concurrencyLimit:    runtime.GOMAXPROCS(0),
+		directCheckFastPath: true,
+	}
+	for _, fn := range options {
+		fn(state)
+	}
+
+	return state
+}
+
 // NewLocalOnlyDispatcher creates a dispatcher that consults with the graph to formulate a response.
-func NewLocalOnlyDispatcher() dispatch.Dispatcher {
-	d := &localDispatcher{}
+func NewLocalOnlyDispatcher(options ...Option) dispatch.Dispatcher {
+	d := &localDispatcher{revisions: dispatch.NewRecentRevisionCache()}
+	state := newOptionState(options)
+	limiter := graph.NewConcurrencyLimiter(state.concurrencyLimit)
 
-	d.checker = graph.NewConcurrentChecker(d)
-	d.expander = graph.NewConcurrentExpander(d)
+	d.checker = graph.NewConcurrentChecker(d, limiter, state.directCheckFastPath)
+	d.expander = graph.NewConcurrentExpander(d, limiter, state.expandNodeLimit)
 	d.lookupHandler = graph.NewConcurrentLookup(d, d)
 	d.reachableResourcesHandler = graph.NewConcurrentReachableResources(d)
+	d.lookupSubjectsHandler = graph.NewConcurrentLookupSubjects(d, limiter)
+	d.lookupForSubjectTypeHandler = graph.NewConcurrentLookupForSubjectType(d)
+	d.bulkChecker = graph.NewBulkChecker(d)
 
 	return d
 }
 
 // NewDispatcher creates a dispatcher that consults with the graph and redispatches subproblems to
 // the provided redispatcher.
-func NewDispatcher(redispatcher dispatch.Dispatcher) dispatch.Dispatcher {
-	checker := graph.NewConcurrentChecker(redispatcher)
-	expander := graph.NewConcurrentExpander(redispatcher)
+func NewDispatcher(redispatcher dispatch.Dispatcher, options ...Option) dispatch.Dispatcher {
+	state := newOptionState(options)
+	limiter := graph.NewConcurrencyLimiter(state.concurrencyLimit)
+
+	checker := graph.NewConcurrentChecker(redispatcher, limiter, state.directCheckFastPath)
+	expander := graph.NewConcurrentExpander(redispatcher, limiter, state.expandNodeLimit)
 	lookupHandler := graph.NewConcurrentLookup(redispatcher, redispatcher)
 	reachableResourcesHandler := graph.NewConcurrentReachableResources(redispatcher)
+	lookupSubjectsHandler := graph.NewConcurrentLookupSubjects(redispatcher, limiter)
+	lookupForSubjectTypeHandler := graph.NewConcurrentLookupForSubjectType(redispatcher)
+	bulkChecker := graph.NewBulkChecker(redispatcher)
 
 	return &localDispatcher{
-		checker:                   checker,
-		expander:                  expander,
-		lookupHandler:             lookupHandler,
-		reachableResourcesHandler: reachableResourcesHandler,
+		checker:                     checker,
+		expander:                    expander,
+		lookupHandler:               lookupHandler,
+		reachableResourcesHandler:   reachableResourcesHandler,
+		lookupSubjectsHandler:       lookupSubjectsHandler,
+		lookupForSubjectTypeHandler: lookupForSubjectTypeHandler,
+		bulkChecker:                 bulkChecker,
+		revisions:                   dispatch.NewRecentRevisionCache(),
 	}
 }
 
 type localDispatcher struct {
-	checker                   *graph.ConcurrentChecker
-	expander                  *graph.ConcurrentExpander
-	lookupHandler             *graph.ConcurrentLookup
-	reachableResourcesHandler *graph.ConcurrentReachableResources
+	checker                     *graph.ConcurrentChecker
+	expander                    *graph.ConcurrentExpander
+	lookupHandler               *graph.ConcurrentLookup
+	reachableResourcesHandler   *graph.ConcurrentReachableResources
+	lookupSubjectsHandler       *graph.ConcurrentLookupSubjects
+	lookupForSubjectTypeHandler *graph.ConcurrentLookupForSubjectType
+	bulkChecker                 *graph.BulkChecker
+	revisions                   *dispatch.RecentRevisionCache
 }
 
 func (ld *localDispatcher) loadNamespace(ctx context.Context, nsName string, revision decimal.Decimal) (*core.NamespaceDefinition, error) {
@@ -86,28 +152,20 @@ func (ld *localDispatcher) lookupRelation(ctx context.Context, ns *core.Namespac
 	return relation, nil
 }
 
-type stringableOnr struct {
-	*core.ObjectAndRelation
-}
-
-func (onr stringableOnr) String() string {
-	return tuple.StringONR(onr.ObjectAndRelation)
-}
+// DispatchCheck implements dispatch.Check interface
+func (ld *localDispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
 
-type stringableRelRef struct {
-	*core.RelationReference
-}
+	req, err := dispatch.ResolveRevision(ctx, req, ld.revisions)
+	if err != nil {
+		return &v1.DispatchCheckResponse{Metadata: emptyMetadata}, err
+	}
 
-func (rr stringableRelRef) String() string {
-	return fmt.Sprintf("%s::%s", rr.Namespace, rr.Relation)
+	return memoizedCheck(ctx, req, ld.dispatchCheck)
 }
 
-// DispatchCheck implements dispatch.Check interface
-func (ld *localDispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
-	ctx, span := tracer.Start(ctx, "DispatchCheck", trace.WithAttributes(
-		attribute.Stringer("start", stringableOnr{req.ResourceAndRelation}),
-		attribute.Stringer("subject", stringableOnr{req.Subject}),
-	))
+func (ld *localDispatcher) dispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	ctx, span := tracer.Start(ctx, "DispatchCheck", trace.WithAttributes(dispatch.CheckSpanAttributes(req, false)...))
 	defer span.End()
 
 	err := dispatch.CheckDepth(ctx, req)
@@ -167,9 +225,8 @@ func (ld *localDispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCh
 
 // DispatchExpand implements dispatch.Expand interface
 func (ld *localDispatcher) DispatchExpand(ctx context.Context, req *v1.DispatchExpandRequest) (*v1.DispatchExpandResponse, error) {
-	ctx, span := tracer.Start(ctx, "DispatchExpand", trace.WithAttributes(
-		attribute.Stringer("start", stringableOnr{req.ResourceAndRelation}),
-	))
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+	ctx, span := tracer.Start(ctx, "DispatchExpand", trace.WithAttributes(dispatch.ExpandSpanAttributes(req, false)...))
 	defer span.End()
 
 	err := dispatch.CheckDepth(ctx, req)
@@ -177,6 +234,11 @@ func (ld *localDispatcher) DispatchExpand(ctx context.Context, req *v1.DispatchE
 		return &v1.DispatchExpandResponse{Metadata: emptyMetadata}, err
 	}
 
+	req, err = dispatch.ResolveRevision(ctx, req, ld.revisions)
+	if err != nil {
+		return &v1.DispatchExpandResponse{Metadata: emptyMetadata}, err
+	}
+
 	revision, err := decimal.NewFromString(req.Metadata.AtRevision)
 	if err != nil {
 		return &v1.DispatchExpandResponse{Metadata: emptyMetadata}, err
@@ -202,11 +264,8 @@ func (ld *localDispatcher) DispatchExpand(ctx context.Context, req *v1.DispatchE
 
 // DispatchLookup implements dispatch.Lookup interface
 func (ld *localDispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchLookupRequest) (*v1.DispatchLookupResponse, error) {
-	ctx, span := tracer.Start(ctx, "DispatchLookup", trace.WithAttributes(
-		attribute.Stringer("start", stringableRelRef{req.ObjectRelation}),
-		attribute.Stringer("subject", stringableOnr{req.Subject}),
-		attribute.Int64("limit", int64(req.Limit)),
-	))
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+	ctx, span := tracer.Start(ctx, "DispatchLookup", trace.WithAttributes(dispatch.LookupSpanAttributes(req, false)...))
 	defer span.End()
 
 	err := dispatch.CheckDepth(ctx, req)
@@ -214,6 +273,11 @@ func (ld *localDispatcher) DispatchLookup(ctx context.Context, req *v1.DispatchL
 		return &v1.DispatchLookupResponse{Metadata: emptyMetadata}, err
 	}
 
+	req, err = dispatch.ResolveRevision(ctx, req, ld.revisions)
+	if err != nil {
+		return &v1.DispatchLookupResponse{Metadata: emptyMetadata}, err
+	}
+
 	revision, err := decimal.NewFromString(req.Metadata.AtRevision)
 	if err != nil {
 		return &v1.DispatchLookupResponse{Metadata: emptyMetadata}, err
@@ -236,10 +300,8 @@ func (ld *localDispatcher) DispatchReachableResources(
 	req *v1.DispatchReachableResourcesRequest,
 	stream dispatch.ReachableResourcesStream,
 ) error {
-	ctx, span := tracer.Start(stream.Context(), "DispatchReachableResources", trace.WithAttributes(
-		attribute.Stringer("start", stringableRelRef{req.ObjectRelation}),
-		attribute.Stringer("subject", stringableOnr{req.Subject}),
-	))
+	ctx, req := dispatch.WithCorrelationID(stream.Context(), req)
+	ctx, span := tracer.Start(ctx, "DispatchReachableResources", trace.WithAttributes(dispatch.ReachableResourcesSpanAttributes(req, false)...))
 	defer span.End()
 
 	err := dispatch.CheckDepth(ctx, req)
@@ -247,6 +309,11 @@ func (ld *localDispatcher) DispatchReachableResources(
 		return err
 	}
 
+	req, err = dispatch.ResolveRevision(ctx, req, ld.revisions)
+	if err != nil {
+		return err
+	}
+
 	revision, err := decimal.NewFromString(req.Metadata.AtRevision)
 	if err != nil {
 		return err
@@ -261,6 +328,82 @@ func (ld *localDispatcher) DispatchReachableResources(
 	return ld.reachableResourcesHandler.ReachableResources(validatedReq, wrappedStream)
 }
 
+// DispatchLookupSubjects implements dispatch.LookupSubjects interface
+func (ld *localDispatcher) DispatchLookupSubjects(ctx context.Context, req *v1.DispatchLookupSubjectsRequest) (*v1.DispatchLookupSubjectsResponse, error) {
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+	ctx, span := tracer.Start(ctx, "DispatchLookupSubjects", trace.WithAttributes(dispatch.LookupSubjectsSpanAttributes(req, false)...))
+	defer span.End()
+
+	err := dispatch.CheckDepth(ctx, req)
+	if err != nil {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: emptyMetadata}, err
+	}
+
+	req, err = dispatch.ResolveRevision(ctx, req, ld.revisions)
+	if err != nil {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: emptyMetadata}, err
+	}
+
+	revision, err := decimal.NewFromString(req.Metadata.AtRevision)
+	if err != nil {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: emptyMetadata}, err
+	}
+
+	if req.Limit <= 0 {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: emptyMetadata, FoundSubjects: []*core.ObjectAndRelation{}}, nil
+	}
+
+	ns, err := ld.loadNamespace(ctx, req.ResourceAndRelation.Namespace, revision)
+	if err != nil {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: emptyMetadata}, err
+	}
+
+	relation, err := ld.lookupRelation(ctx, ns, req.ResourceAndRelation.Relation, revision)
+	if err != nil {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: emptyMetadata}, err
+	}
+
+	validatedReq := graph.ValidatedLookupSubjectsRequest{
+		DispatchLookupSubjectsRequest: req,
+		Revision:                      revision,
+	}
+
+	return ld.lookupSubjectsHandler.LookupSubjects(ctx, validatedReq, relation)
+}
+
+// DispatchLookupForSubjectType implements dispatch.LookupForSubjectType interface
+func (ld *localDispatcher) DispatchLookupForSubjectType(ctx context.Context, req *v1.DispatchLookupForSubjectTypeRequest) (*v1.DispatchLookupForSubjectTypeResponse, error) {
+	ctx, req = dispatch.WithCorrelationID(ctx, req)
+	ctx, span := tracer.Start(ctx, "DispatchLookupForSubjectType", trace.WithAttributes(dispatch.LookupForSubjectTypeSpanAttributes(req, false)...))
+	defer span.End()
+
+	err := dispatch.CheckDepth(ctx, req)
+	if err != nil {
+		return &v1.DispatchLookupForSubjectTypeResponse{Metadata: emptyMetadata}, err
+	}
+
+	req, err = dispatch.ResolveRevision(ctx, req, ld.revisions)
+	if err != nil {
+		return &v1.DispatchLookupForSubjectTypeResponse{Metadata: emptyMetadata}, err
+	}
+
+	revision, err := decimal.NewFromString(req.Metadata.AtRevision)
+	if err != nil {
+		return &v1.DispatchLookupForSubjectTypeResponse{Metadata: emptyMetadata}, err
+	}
+
+	if req.Limit <= 0 {
+		return &v1.DispatchLookupForSubjectTypeResponse{Metadata: emptyMetadata, Results: []*v1.SubjectLookupResult{}}, nil
+	}
+
+	validatedReq := graph.ValidatedLookupForSubjectTypeRequest{
+		DispatchLookupForSubjectTypeRequest: req,
+		Revision:                            revision,
+	}
+
+	return ld.lookupForSubjectTypeHandler.LookupForSubjectType(ctx, validatedReq)
+}
+
 func (ld *localDispatcher) Close() error {
 	return nil
 }
@@ -1,22 +1,34 @@
 package graph
 
 import (
+	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
 	"os"
 	"strings"
+	"sync/atomic"
 	"testing"
 	"time"
 
+	v1_api "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/google/go-cmp/cmp"
 	"github.com/rs/zerolog"
 	"github.com/rs/zerolog/log"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/testing/protocmp"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch"
+	"github.com/authzed/spicedb/internal/graph"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
@@ -158,6 +170,569 @@ func TestSimpleLookup(t *testing.T) {
 	}
 }
 
+func TestPagedLookup(t *testing.T) {
+	require := require.New(t)
+
+	ctx, dispatch, revision := newLocalDispatcher(require)
+
+	var found []*core.ObjectAndRelation
+	pageToken := ""
+	for pageCount := 1; ; pageCount++ {
+		require.LessOrEqual(pageCount, 3, "too many pages returned")
+
+		lookupResult, err := dispatch.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+			ObjectRelation: RR("folder", "view"),
+			Subject:        ONR("user", "auditor", "..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+			Limit:     2,
+			PageToken: pageToken,
+		})
+		require.NoError(err)
+		require.LessOrEqual(len(lookupResult.ResolvedOnrs), 2)
+
+		found = append(found, lookupResult.ResolvedOnrs...)
+		if !lookupResult.HasMore {
+			require.Empty(lookupResult.NextPageToken)
+			break
+		}
+
+		require.Len(lookupResult.ResolvedOnrs, 2)
+		require.NotEmpty(lookupResult.NextPageToken)
+		pageToken = lookupResult.NextPageToken
+	}
+
+	require.ElementsMatch([]*core.ObjectAndRelation{
+		ONR("folder", "auditors", "view"),
+		ONR("folder", "company", "view"),
+		ONR("folder", "strategy", "view"),
+	}, found)
+}
+
+func TestPagedLookupMatchesUnpaged(t *testing.T) {
+	testCases := []struct {
+		start  *core.RelationReference
+		target *core.ObjectAndRelation
+	}{
+		{RR("document", "view"), ONR("user", "owner", "...")},
+		{RR("document", "view"), ONR("user", "legal", "...")},
+		{RR("document", "view_and_edit"), ONR("user", "multiroleguy", "...")},
+		{RR("folder", "view"), ONR("user", "auditor", "...")},
+		{RR("folder", "view"), ONR("user", "legal", "...")},
+	}
+
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(fmt.Sprintf("%s#%s->%s", tc.start.Namespace, tc.start.Relation, tuple.StringONR(tc.target)), func(t *testing.T) {
+			require := require.New(t)
+
+			ctx, dispatch, revision := newLocalDispatcher(require)
+			metadata := &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			}
+
+			unpaged, err := dispatch.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+				ObjectRelation: tc.start,
+				Subject:        tc.target,
+				Metadata:       metadata,
+				Limit:          100,
+			})
+			require.NoError(err)
+			require.False(unpaged.HasMore)
+
+			var found []*core.ObjectAndRelation
+			seen := map[string]struct{}{}
+			pageToken := ""
+			for pageCount := 0; pageCount <= len(unpaged.ResolvedOnrs); pageCount++ {
+				page, err := dispatch.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+					ObjectRelation: tc.start,
+					Subject:        tc.target,
+					Metadata:       metadata,
+					Limit:          1,
+					PageToken:      pageToken,
+				})
+				require.NoError(err)
+
+				for _, onr := range page.ResolvedOnrs {
+					_, ok := seen[tuple.StringONR(onr)]
+					require.False(ok, "found %s in more than one page", tuple.StringONR(onr))
+					seen[tuple.StringONR(onr)] = struct{}{}
+				}
+				found = append(found, page.ResolvedOnrs...)
+
+				if !page.HasMore {
+					break
+				}
+				pageToken = page.NextPageToken
+			}
+
+			require.ElementsMatch(unpaged.ResolvedOnrs, found)
+		})
+	}
+}
+
+func TestLookupDiamondDeduplicated(t *testing.T) {
+	require := require.New(t)
+
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, _ := testfixtures.StandardDatastoreWithData(rawDS, require)
+
+	// The user can view document:diamond directly, as an editor, and through two parent folders.
+	diamondTuples := []string{
+		"document:diamond#viewer@user:diamondguy#...",
+		"document:diamond#editor@user:diamondguy#...",
+		"document:diamond#parent@folder:diamondleft#...",
+		"document:diamond#parent@folder:diamondright#...",
+		"folder:diamondleft#viewer@user:diamondguy#...",
+		"folder:diamondright#parent@folder:diamondtop#...",
+		"folder:diamondtop#viewer@user:diamondguy#...",
+		"document:other#parent@folder:diamondleft#...",
+	}
+
+	revision, err := ds.ReadWriteTx(context.Background(), func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		updates := make([]*v1_api.RelationshipUpdate, 0, len(diamondTuples))
+		for _, tpl := range diamondTuples {
+			updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse(tpl))))
+		}
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	dispatch := NewLocalOnlyDispatcher()
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	lookupResult, err := dispatch.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+		ObjectRelation: RR("document", "view"),
+		Subject:        ONR("user", "diamondguy", "..."),
+		Metadata: &v1.ResolverMeta{
+			AtRevision:     revision.String(),
+			DepthRemaining: 50,
+		},
+		Limit: 2,
+	})
+	require.NoError(err)
+	require.False(lookupResult.HasMore)
+	require.ElementsMatch([]*core.ObjectAndRelation{
+		ONR("document", "diamond", "view"),
+		ONR("document", "other", "view"),
+	}, lookupResult.ResolvedOnrs)
+}
+
+func TestLookupDebugTrace(t *testing.T) {
+	testCases := []struct {
+		subject       *core.ObjectAndRelation
+		expectedTrace *v1.LookupDebugTrace
+	}{
+		{
+			ONR("user", "multiroleguy", "..."),
+			&v1.LookupDebugTrace{
+				Relation:  RR("document", "view_and_edit"),
+				Operation: v1.LookupDebugTrace_INTERSECTION,
+				ContributedOnrs: []*core.ObjectAndRelation{
+					ONR("document", "specialplan", "view_and_edit"),
+				},
+				SubProblems: []*v1.LookupDebugTrace{
+					{
+						Relation:      RR("document", "view_and_edit"),
+						Resource:      ONR("document", "specialplan", "view_and_edit"),
+						Operation:     v1.LookupDebugTrace_INTERSECTION,
+						RequiredCheck: true,
+						ContributedOnrs: []*core.ObjectAndRelation{
+							ONR("document", "specialplan", "view_and_edit"),
+						},
+					},
+				},
+			},
+		},
+		{
+			ONR("user", "missingrolegal", "..."),
+			&v1.LookupDebugTrace{
+				Relation:        RR("document", "view_and_edit"),
+				Operation:       v1.LookupDebugTrace_INTERSECTION,
+				ContributedOnrs: []*core.ObjectAndRelation{},
+				SubProblems: []*v1.LookupDebugTrace{
+					{
+						Relation:      RR("document", "view_and_edit"),
+						Resource:      ONR("document", "specialplan", "view_and_edit"),
+						Operation:     v1.LookupDebugTrace_INTERSECTION,
+						RequiredCheck: true,
+					},
+				},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tuple.StringONR(tc.subject), func(t *testing.T) {
+			require := require.New(t)
+
+			ctx, dispatch, revision := newLocalDispatcher(require)
+
+			req := &v1.DispatchLookupRequest{
+				ObjectRelation: RR("document", "view_and_edit"),
+				Subject:        tc.subject,
+				Metadata: &v1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: 50,
+				},
+				Limit:      10,
+				DebugTrace: true,
+			}
+
+			lookupResult, err := dispatch.DispatchLookup(ctx, req)
+			require.NoError(err)
+			if diff := cmp.Diff(tc.expectedTrace, lookupResult.DebugTrace, protocmp.Transform()); diff != "" {
+				require.Fail("unexpected debug trace", diff)
+			}
+
+			// Without a debug trace requested, none is returned.
+			untracedReq := proto.Clone(req).(*v1.DispatchLookupRequest)
+			untracedReq.DebugTrace = false
+			untracedResult, err := dispatch.DispatchLookup(ctx, untracedReq)
+			require.NoError(err)
+			require.Nil(untracedResult.DebugTrace)
+
+			// We have to sleep a while to let the cache converge.
+			time.Sleep(10 * time.Millisecond)
+
+			// Run again with the cache available; the trace is returned as cached.
+			cachedResult, err := dispatch.DispatchLookup(ctx, req)
+			require.NoError(err)
+			require.True(cachedResult.DebugTrace.IsCachedResult)
+
+			cachedResult.DebugTrace.IsCachedResult = false
+			if diff := cmp.Diff(tc.expectedTrace, cachedResult.DebugTrace, protocmp.Transform()); diff != "" {
+				require.Fail("unexpected cached debug trace", diff)
+			}
+		})
+	}
+}
+
+var reportNS = ns.Namespace(
+	"report",
+	ns.Relation("viewer",
+		nil,
+		ns.AllowedRelation("user", "..."),
+		ns.AllowedRelation("group", "member"),
+	),
+	ns.Relation("banned",
+		nil,
+		ns.AllowedRelation("user", "..."),
+		ns.AllowedRelation("group", "member"),
+	),
+	ns.Relation("view",
+		ns.Exclusion(
+			ns.ComputedUserset("viewer"),
+			ns.ComputedUserset("banned"),
+		),
+	),
+)
+
+var reportTuples = []string{
+	"group:readers#member@user:alice",
+	"group:readers#member@user:bob",
+	"group:trolls#member@user:bob",
+	"report:public#viewer@group:readers#member",
+	"report:alicebanned#viewer@user:alice",
+	"report:alicebanned#banned@user:alice",
+	"report:onlybanned#banned@user:alice",
+	"report:onlybanned#banned@user:bob",
+	"report:trollsbanned#viewer@group:readers#member",
+	"report:trollsbanned#banned@group:trolls#member",
+	"report:bobonly#viewer@user:bob",
+}
+
+// reportDatastore returns a datastore with the schema of reports and groups, and the given
+// relationships.
+func reportDatastore(require *require.Assertions, tuples []string) (datastore.Datastore, datastore.Revision) {
+	rawDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	allDefs := []*core.NamespaceDefinition{ns.Namespace("user"), groupNS, reportNS}
+	revision, err := rawDS.ReadWriteTx(context.Background(), func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		for _, nsDef := range allDefs {
+			ts, err := namespace.BuildNamespaceTypeSystemWithFallback(nsDef, rwt, allDefs)
+			require.NoError(err)
+
+			vts, err := ts.Validate(ctx)
+			require.NoError(err)
+			require.NoError(namespace.AnnotateNamespace(vts))
+			require.NoError(rwt.WriteNamespaces(nsDef))
+		}
+
+		updates := make([]*v1_api.RelationshipUpdate, 0, len(tuples))
+		for _, tpl := range tuples {
+			updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse(tpl))))
+		}
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	return rawDS, revision
+}
+
+func TestLookupThroughExclusion(t *testing.T) {
+	testCases := []struct {
+		subject         *core.ObjectAndRelation
+		resolvedObjects []*core.ObjectAndRelation
+	}{
+		{
+			// alice is banned directly from alicebanned, which she can also view, and is only
+			// reachable from onlybanned through the excluded branch.
+			ONR("user", "alice", "..."),
+			[]*core.ObjectAndRelation{
+				ONR("report", "public", "view"),
+				ONR("report", "trollsbanned", "view"),
+			},
+		},
+		{
+			// bob reaches trollsbanned through both branches, by way of his groups.
+			ONR("user", "bob", "..."),
+			[]*core.ObjectAndRelation{
+				ONR("report", "bobonly", "view"),
+				ONR("report", "public", "view"),
+			},
+		},
+		{
+			ONR("user", "carol", "..."),
+			[]*core.ObjectAndRelation{},
+		},
+	}
+
+	rawDS, revision := reportDatastore(require.New(t), reportTuples)
+
+	for _, tc := range testCases {
+		t.Run(tuple.StringONR(tc.subject), func(t *testing.T) {
+			require := require.New(t)
+
+			dispatch := NewLocalOnlyDispatcher()
+			ctx := datastoremw.ContextWithHandle(context.Background())
+			require.NoError(datastoremw.SetInContext(ctx, rawDS))
+
+			lookupResult, err := dispatch.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+				ObjectRelation: RR("report", "view"),
+				Subject:        tc.subject,
+				Metadata: &v1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: 50,
+				},
+				Limit:      10,
+				DebugTrace: true,
+			})
+			require.NoError(err)
+			require.False(lookupResult.HasMore)
+			require.ElementsMatch(tc.resolvedObjects, lookupResult.ResolvedOnrs, "Found: %v, Expected: %v", lookupResult.ResolvedOnrs, tc.resolvedObjects)
+
+			// Every object reachable through the base branch must have been checked, so that
+			// those also reachable through the excluded branch are removed.
+			require.Equal(v1.LookupDebugTrace_EXCLUSION, lookupResult.DebugTrace.Operation)
+			for _, subProblem := range lookupResult.DebugTrace.SubProblems {
+				require.True(subProblem.RequiredCheck, "%s was not checked", tuple.StringONR(subProblem.Resource))
+				require.NotEqual("onlybanned", subProblem.Resource.ObjectId, "reached object only reachable through the excluded branch")
+			}
+		})
+	}
+}
+
+// checkCountingDispatcher counts the checks dispatched through it.
+type checkCountingDispatcher struct {
+	dispatch.Dispatcher
+
+	checks int32
+}
+
+func (ccd *checkCountingDispatcher) DispatchCheck(ctx context.Context, req *v1.DispatchCheckRequest) (*v1.DispatchCheckResponse, error) {
+	atomic.AddInt32(&ccd.checks, 1)
+	return ccd.Dispatcher.DispatchCheck(ctx, req)
+}
+
+func TestLookupLimitStopsChecksEarly(t *testing.T) {
+	const reportCount = 300
+
+	require := require.New(t)
+
+	// alice can view every report, but is banned from every seventh, so each requires a check.
+	var tuples []string
+	for i := 0; i < reportCount; i++ {
+		tuples = append(tuples, fmt.Sprintf("report:r%03d#viewer@user:alice", i))
+		if i%7 == 0 {
+			tuples = append(tuples, fmt.Sprintf("report:r%03d#banned@user:alice", i))
+		}
+	}
+	ds, revision := reportDatastore(require, tuples)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	lookup := func(limit uint32, pageToken string) (*v1.DispatchLookupResponse, int) {
+		counter := &checkCountingDispatcher{Dispatcher: NewLocalOnlyDispatcher()}
+		result, err := NewDispatcher(counter).DispatchLookup(ctx, &v1.DispatchLookupRequest{
+			ObjectRelation: RR("report", "view"),
+			Subject:        ONR("user", "alice", "..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+			Limit:     limit,
+			PageToken: pageToken,
+		})
+		require.NoError(err)
+		return result, int(atomic.LoadInt32(&counter.checks))
+	}
+
+	unlimited, unlimitedChecks := lookup(^uint32(0), "")
+	require.False(unlimited.HasMore)
+	require.Len(unlimited.ResolvedOnrs, reportCount-(reportCount+6)/7)
+	require.Equal(reportCount, unlimitedChecks)
+
+	limited, limitedChecks := lookup(5, "")
+	require.True(limited.HasMore)
+	require.Len(limited.ResolvedOnrs, 5)
+	require.Subset(unlimited.ResolvedOnrs, limited.ResolvedOnrs)
+	require.Less(limitedChecks, reportCount/10, "expected the limit to bound the checks performed")
+
+	// Paging through every result with a small limit returns each result exactly once.
+	var paged []*core.ObjectAndRelation
+	pageToken := ""
+	for pageCount := 0; pageCount <= len(unlimited.ResolvedOnrs); pageCount++ {
+		page, checks := lookup(5, pageToken)
+		require.Less(checks, reportCount/10)
+
+		paged = append(paged, page.ResolvedOnrs...)
+		if !page.HasMore {
+			break
+		}
+		pageToken = page.NextPageToken
+	}
+	require.ElementsMatch(unlimited.ResolvedOnrs, paged)
+}
+
+// reachableResourcesCountingDispatcher counts the reachable resources requests dispatched through
+// it.
+type reachableResourcesCountingDispatcher struct {
+	dispatch.Dispatcher
+
+	dispatches int32
+}
+
+func (rcd *reachableResourcesCountingDispatcher) DispatchReachableResources(req *v1.DispatchReachableResourcesRequest, stream dispatch.ReachableResourcesStream) error {
+	atomic.AddInt32(&rcd.dispatches, 1)
+	return rcd.Dispatcher.DispatchReachableResources(req, stream)
+}
+
+func TestLookupLimitStopsReachabilityEarly(t *testing.T) {
+	const groupCount = 300
+
+	require := require.New(t)
+
+	// alice can view each report through a separate group, so every report is only reached by a
+	// dispatch for its group.
+	var tuples []string
+	for i := 0; i < groupCount; i++ {
+		tuples = append(tuples,
+			fmt.Sprintf("group:g%03d#member@user:alice", i),
+			fmt.Sprintf("report:r%03d#viewer@group:g%03d#member", i, i),
+		)
+	}
+	ds, revision := reportDatastore(require, tuples)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	lookup := func(limit uint32, pageToken string) (*v1.DispatchLookupResponse, int) {
+		// Every subproblem is redispatched through the counter.
+		counter := &reachableResourcesCountingDispatcher{}
+		counter.Dispatcher = NewDispatcher(counter)
+
+		result, err := counter.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+			ObjectRelation: RR("report", "viewer"),
+			Subject:        ONR("user", "alice", "..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+			Limit:     limit,
+			PageToken: pageToken,
+		})
+		require.NoError(err)
+		return result, int(atomic.LoadInt32(&counter.dispatches))
+	}
+
+	unlimited, unlimitedDispatches := lookup(^uint32(0), "")
+	require.False(unlimited.HasMore)
+	require.Len(unlimited.ResolvedOnrs, groupCount)
+	require.Greater(unlimitedDispatches, groupCount)
+
+	limited, limitedDispatches := lookup(5, "")
+	require.True(limited.HasMore)
+	require.Len(limited.ResolvedOnrs, 5)
+	require.Subset(unlimited.ResolvedOnrs, limited.ResolvedOnrs)
+	require.Less(limitedDispatches, groupCount/10, "expected the limit to stop the traversal early")
+
+	// Continuing the lookup from the first page returns none of its results again.
+	next, _ := lookup(5, limited.NextPageToken)
+	require.Len(next.ResolvedOnrs, 5)
+	require.Subset(unlimited.ResolvedOnrs, next.ResolvedOnrs)
+	require.True(tuple.NewONRSet(limited.ResolvedOnrs...).Intersect(tuple.NewONRSet(next.ResolvedOnrs...)).IsEmpty())
+}
+
+func TestLookupLogsShareCorrelationID(t *testing.T) {
+	require := require.New(t)
+
+	zerolog.SetGlobalLevel(zerolog.TraceLevel)
+	defer zerolog.SetGlobalLevel(zerolog.DebugLevel)
+
+	ctx, dispatcher, revision := newLocalDispatcher(require)
+
+	var output bytes.Buffer
+	ctx = zerolog.New(zerolog.SyncWriter(&output)).WithContext(ctx)
+
+	lookupCorrelationIDs := func(correlationID string) []string {
+		output.Reset()
+
+		_, err := dispatcher.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+			ObjectRelation: RR("document", "view"),
+			Subject:        ONR("user", "chief_financial_officer", "..."),
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+				CorrelationId:  correlationID,
+			},
+			Limit: ^uint32(0),
+		})
+		require.NoError(err)
+
+		var correlationIDs []string
+		for _, line := range strings.Split(strings.TrimSpace(output.String()), "\n") {
+			// Each statement is tagged once, however deep in the traversal it was made.
+			require.Equal(1, strings.Count(line, dispatch.CorrelationIDKey), line)
+
+			var statement map[string]any
+			require.NoError(json.Unmarshal([]byte(line), &statement))
+			correlationIDs = append(correlationIDs, statement[dispatch.CorrelationIDKey].(string))
+		}
+		return correlationIDs
+	}
+
+	// A correlation ID is generated for a lookup without one.
+	correlationIDs := lookupCorrelationIDs("")
+	require.Greater(len(correlationIDs), 1)
+	require.NotEmpty(correlationIDs[0])
+	for _, correlationID := range correlationIDs {
+		require.Equal(correlationIDs[0], correlationID)
+	}
+
+	for _, correlationID := range lookupCorrelationIDs("some-correlation-id") {
+		require.Equal("some-correlation-id", correlationID)
+	}
+}
+
 func TestMaxDepthLookup(t *testing.T) {
 	require := require.New(t)
 
@@ -185,6 +760,36 @@ func TestMaxDepthLookup(t *testing.T) {
 	require.Error(err)
 }
 
+func TestLookupZeroLimit(t *testing.T) {
+	require := require.New(t)
+
+	ctx, _, revision := newLocalDispatcher(require)
+
+	// The local dispatcher answers a zero limit itself, so the lookup is invoked directly.
+	local := NewLocalOnlyDispatcher()
+	for _, limit := range []uint32{0, 1} {
+		limit := limit
+		t.Run(fmt.Sprintf("limit %d", limit), func(t *testing.T) {
+			result, err := graph.NewConcurrentLookup(local, local).LookupViaReachability(ctx, graph.ValidatedLookupRequest{
+				DispatchLookupRequest: &v1.DispatchLookupRequest{
+					ObjectRelation: RR("folder", "view"),
+					Subject:        ONR("user", "auditor", "..."),
+					Metadata: &v1.ResolverMeta{
+						AtRevision:     revision.String(),
+						DepthRemaining: 50,
+					},
+					Limit: limit,
+				},
+				Revision: revision,
+			})
+			require.NoError(err)
+			require.Len(result.ResolvedOnrs, int(limit))
+			require.Equal(limit > 0, result.HasMore)
+			require.Equal(limit > 0, result.NextPageToken != "")
+		})
+	}
+}
+
 type OrderedResolved []*core.ObjectAndRelation
 
 func (a OrderedResolved) Len() int { return len(a) }
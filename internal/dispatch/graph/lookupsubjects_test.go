@@ -0,0 +1,118 @@
+package graph
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestSimpleLookupSubjects(t *testing.T) {
+	testCases := []struct {
+		resource         *core.ObjectAndRelation
+		subjectRelation  *core.RelationReference
+		limit            uint32
+		expectedSubjects []*core.ObjectAndRelation
+	}{
+		{
+			ONR("document", "unknown", "view"),
+			RR("user", "..."),
+			10,
+			[]*core.ObjectAndRelation{},
+		},
+		{
+			ONR("document", "masterplan", "owner"),
+			RR("user", "..."),
+			10,
+			[]*core.ObjectAndRelation{
+				ONR("user", "product_manager", "..."),
+			},
+		},
+		{
+			ONR("document", "companyplan", "view"),
+			RR("user", "..."),
+			10,
+			[]*core.ObjectAndRelation{
+				ONR("user", "auditor", "..."),
+				ONR("user", "legal", "..."),
+				ONR("user", "owner", "..."),
+			},
+		},
+		{
+			ONR("document", "masterplan", "view"),
+			RR("user", "..."),
+			10,
+			[]*core.ObjectAndRelation{
+				ONR("user", "auditor", "..."),
+				ONR("user", "chief_financial_officer", "..."),
+				ONR("user", "eng_lead", "..."),
+				ONR("user", "legal", "..."),
+				ONR("user", "owner", "..."),
+				ONR("user", "product_manager", "..."),
+				ONR("user", "vp_product", "..."),
+			},
+		},
+		{
+			ONR("document", "masterplan", "view"),
+			RR("user", "..."),
+			3,
+			[]*core.ObjectAndRelation{
+				ONR("user", "auditor", "..."),
+				ONR("user", "chief_financial_officer", "..."),
+				ONR("user", "eng_lead", "..."),
+			},
+		},
+		{
+			ONR("document", "specialplan", "view_and_edit"),
+			RR("user", "..."),
+			10,
+			[]*core.ObjectAndRelation{
+				ONR("user", "multiroleguy", "..."),
+			},
+		},
+		{
+			ONR("folder", "company", "viewer"),
+			RR("folder", "viewer"),
+			10,
+			[]*core.ObjectAndRelation{
+				ONR("folder", "auditors", "viewer"),
+				ONR("folder", "company", "viewer"),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		name := fmt.Sprintf(
+			"%s->%s::%s[%d]",
+			tuple.StringONR(tc.resource),
+			tc.subjectRelation.Namespace,
+			tc.subjectRelation.Relation,
+			tc.limit,
+		)
+
+		t.Run(name, func(t *testing.T) {
+			require := require.New(t)
+
+			ctx, dispatch, revision := newLocalDispatcher(require)
+
+			lookupResult, err := dispatch.DispatchLookupSubjects(ctx, &v1.DispatchLookupSubjectsRequest{
+				ResourceAndRelation: tc.resource,
+				SubjectRelation:     tc.subjectRelation,
+				Metadata: &v1.ResolverMeta{
+					AtRevision:     revision.String(),
+					DepthRemaining: 50,
+				},
+				Limit: tc.limit,
+			})
+
+			require.NoError(err)
+			require.Equal(tc.expectedSubjects, lookupResult.FoundSubjects, "Found: %v, Expected: %v", lookupResult.FoundSubjects, tc.expectedSubjects)
+			require.GreaterOrEqual(lookupResult.Metadata.DepthRequired, uint32(1))
+		})
+	}
+}
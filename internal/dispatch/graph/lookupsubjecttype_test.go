@@ -0,0 +1,137 @@
+package graph
+
+import (
+	"context"
+	"testing"
+
+	v1_api "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+var groupNS = ns.Namespace(
+	"group",
+	ns.Relation("member",
+		nil,
+		ns.AllowedRelation("user", "..."),
+		ns.AllowedRelation("group", "member"),
+	),
+)
+
+var resourceNS = ns.Namespace(
+	"resource",
+	ns.Relation("viewer",
+		nil,
+		ns.AllowedRelation("user", "..."),
+		ns.AllowedRelation("group", "member"),
+	),
+	ns.Relation("view",
+		ns.Union(
+			ns.ComputedUserset("viewer"),
+		),
+	),
+)
+
+func TestDispatchLookupForSubjectType(t *testing.T) {
+	require := require.New(t)
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	allDefs := []*core.NamespaceDefinition{ns.Namespace("user"), groupNS, resourceNS}
+	revision, err := ds.ReadWriteTx(context.Background(), func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		for _, nsDef := range allDefs {
+			ts, err := namespace.BuildNamespaceTypeSystemWithFallback(nsDef, rwt, allDefs)
+			require.NoError(err)
+
+			vts, err := ts.Validate(ctx)
+			require.NoError(err)
+			require.NoError(namespace.AnnotateNamespace(vts))
+			require.NoError(rwt.WriteNamespaces(nsDef))
+		}
+
+		var updates []*v1_api.RelationshipUpdate
+		for _, tplString := range []string{
+			"group:eng#member@user:alice",
+			"group:eng#member@group:leads#member",
+			"group:leads#member@user:bob",
+			"group:sales#member@user:dan",
+			"group:empty#member@user:erin",
+			"resource:doc1#viewer@group:eng#member",
+			"resource:doc2#viewer@group:leads#member",
+			"resource:doc3#viewer@user:carol",
+			"resource:doc4#viewer@group:sales#member",
+		} {
+			updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse(tplString))))
+		}
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	ctx := datastoremw.ContextWithHandle(context.Background())
+	require.NoError(datastoremw.SetInContext(ctx, ds))
+
+	dispatcher := NewLocalOnlyDispatcher()
+	defer dispatcher.Close()
+
+	found, err := dispatcher.DispatchLookupForSubjectType(ctx, &v1.DispatchLookupForSubjectTypeRequest{
+		ObjectRelation: RR("resource", "view"),
+		SubjectType:    RR("group", "member"),
+		Metadata: &v1.ResolverMeta{
+			AtRevision:     revision.String(),
+			DepthRemaining: 50,
+		},
+		Limit: 100,
+	})
+	require.NoError(err)
+
+	// Members of leads are members of eng, and so can view its resources too. The empty group is
+	// never a subject, and carol is not a member of any group.
+	require.Len(found.Results, 3)
+
+	require.Equal("group:eng#member", tuple.StringONR(found.Results[0].Subject))
+	require.ElementsMatch([]*core.ObjectAndRelation{
+		ONR("resource", "doc1", "view"),
+	}, found.Results[0].ResolvedOnrs)
+
+	require.Equal("group:leads#member", tuple.StringONR(found.Results[1].Subject))
+	require.ElementsMatch([]*core.ObjectAndRelation{
+		ONR("resource", "doc1", "view"),
+		ONR("resource", "doc2", "view"),
+	}, found.Results[1].ResolvedOnrs)
+
+	require.Equal("group:sales#member", tuple.StringONR(found.Results[2].Subject))
+	require.ElementsMatch([]*core.ObjectAndRelation{
+		ONR("resource", "doc4", "view"),
+	}, found.Results[2].ResolvedOnrs)
+}
+
+func TestDispatchLookupForUndefinedSubjectType(t *testing.T) {
+	require := require.New(t)
+
+	ctx, dispatcher, revision := newLocalDispatcher(require)
+
+	for _, subjectType := range []*core.RelationReference{
+		RR("unknown", "member"),
+		RR("user", "unknown"),
+	} {
+		_, err := dispatcher.DispatchLookupForSubjectType(ctx, &v1.DispatchLookupForSubjectTypeRequest{
+			ObjectRelation: RR("document", "view"),
+			SubjectType:    subjectType,
+			Metadata: &v1.ResolverMeta{
+				AtRevision:     revision.String(),
+				DepthRemaining: 50,
+			},
+			Limit: 100,
+		})
+		require.Error(err, "expected an error for %s#%s", subjectType.Namespace, subjectType.Relation)
+	}
+}
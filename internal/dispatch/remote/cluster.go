@@ -20,6 +20,9 @@ type clusterClient interface {
 	DispatchExpand(ctx context.Context, req *v1.DispatchExpandRequest, opts ...grpc.CallOption) (*v1.DispatchExpandResponse, error)
 	DispatchLookup(ctx context.Context, req *v1.DispatchLookupRequest, opts ...grpc.CallOption) (*v1.DispatchLookupResponse, error)
 	DispatchReachableResources(ctx context.Context, in *v1.DispatchReachableResourcesRequest, opts ...grpc.CallOption) (v1.DispatchService_DispatchReachableResourcesClient, error)
+	DispatchLookupSubjects(ctx context.Context, req *v1.DispatchLookupSubjectsRequest, opts ...grpc.CallOption) (*v1.DispatchLookupSubjectsResponse, error)
+	DispatchLookupForSubjectType(ctx context.Context, req *v1.DispatchLookupForSubjectTypeRequest, opts ...grpc.CallOption) (*v1.DispatchLookupForSubjectTypeResponse, error)
+	DispatchCheckBulk(ctx context.Context, req *v1.DispatchCheckBulkRequest, opts ...grpc.CallOption) (*v1.DispatchCheckBulkResponse, error)
 }
 
 // NewClusterDispatcher creates a dispatcher implementation that uses the provided client
@@ -86,6 +89,48 @@ func (cr *clusterDispatcher) DispatchLookup(ctx context.Context, req *v1.Dispatc
 	return resp, nil
 }
 
+func (cr *clusterDispatcher) DispatchLookupSubjects(ctx context.Context, req *v1.DispatchLookupSubjectsRequest) (*v1.DispatchLookupSubjectsResponse, error) {
+	err := dispatch.CheckDepth(ctx, req)
+	if err != nil {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: emptyMetadata}, err
+	}
+	ctx = context.WithValue(ctx, balancer.CtxKey, []byte(dispatch.LookupSubjectsRequestToKey(req)))
+	resp, err := cr.clusterClient.DispatchLookupSubjects(ctx, req)
+	if err != nil {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: requestFailureMetadata}, err
+	}
+
+	return resp, nil
+}
+
+func (cr *clusterDispatcher) DispatchLookupForSubjectType(ctx context.Context, req *v1.DispatchLookupForSubjectTypeRequest) (*v1.DispatchLookupForSubjectTypeResponse, error) {
+	err := dispatch.CheckDepth(ctx, req)
+	if err != nil {
+		return &v1.DispatchLookupForSubjectTypeResponse{Metadata: emptyMetadata}, err
+	}
+	ctx = context.WithValue(ctx, balancer.CtxKey, []byte(dispatch.LookupForSubjectTypeRequestToKey(req)))
+	resp, err := cr.clusterClient.DispatchLookupForSubjectType(ctx, req)
+	if err != nil {
+		return &v1.DispatchLookupForSubjectTypeResponse{Metadata: requestFailureMetadata}, err
+	}
+
+	return resp, nil
+}
+
+func (cr *clusterDispatcher) DispatchCheckBulk(ctx context.Context, req *v1.DispatchCheckBulkRequest) (*v1.DispatchCheckBulkResponse, error) {
+	err := dispatch.CheckDepth(ctx, req)
+	if err != nil {
+		return &v1.DispatchCheckBulkResponse{Metadata: emptyMetadata}, err
+	}
+	ctx = context.WithValue(ctx, balancer.CtxKey, []byte(dispatch.CheckBulkRequestToKey(req)))
+	resp, err := cr.clusterClient.DispatchCheckBulk(ctx, req)
+	if err != nil {
+		return &v1.DispatchCheckBulkResponse{Metadata: requestFailureMetadata}, err
+	}
+
+	return resp, nil
+}
+
 func (cr *clusterDispatcher) DispatchReachableResources(
 	req *v1.DispatchReachableResourcesRequest,
 	stream dispatch.ReachableResourcesStream,
@@ -0,0 +1,78 @@
+package dispatch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"google.golang.org/protobuf/proto"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+// RecentRevisionCache caches a head revision recently served by the datastore, which is reused by
+// requests that trade a bounded amount of staleness for not reading the head revision themselves.
+type RecentRevisionCache struct {
+	sync.Mutex
+
+	revision decimal.Decimal
+	readAt   time.Time
+	now      func() time.Time
+}
+
+// NewRecentRevisionCache creates a new, empty RecentRevisionCache.
+func NewRecentRevisionCache() *RecentRevisionCache {
+	return &RecentRevisionCache{now: time.Now}
+}
+
+// Revision returns the cached revision if it was read no longer than maxStaleness ago, and
+// otherwise reads, caches and returns the current head revision of the datastore in the context.
+func (rc *RecentRevisionCache) Revision(ctx context.Context, maxStaleness time.Duration) (decimal.Decimal, error) {
+	rc.Lock()
+	defer rc.Unlock()
+
+	now := rc.now()
+	if !rc.readAt.IsZero() && now.Sub(rc.readAt) <= maxStaleness {
+		return rc.revision, nil
+	}
+
+	revision, err := datastoremw.MustFromContext(ctx).HeadRevision(ctx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	rc.revision = revision
+	rc.readAt = now
+	return revision, nil
+}
+
+type requestWithMetadata interface {
+	proto.Message
+	GetMetadata() *v1.ResolverMeta
+}
+
+// ResolveRevision returns the request as is if it has a revision, and otherwise a copy of it at a
+// revision from the cache which is no staler than the request's MaxRevisionStaleness.
+func ResolveRevision[T requestWithMetadata](ctx context.Context, req T, revisions *RecentRevisionCache) (T, error) {
+	metadata := req.GetMetadata()
+	if metadata == nil || metadata.AtRevision != "" {
+		return req, nil
+	}
+
+	if metadata.MaxRevisionStaleness == nil {
+		return req, fmt.Errorf("request must specify either a revision or a maximum revision staleness")
+	}
+
+	revision, err := revisions.Revision(ctx, metadata.MaxRevisionStaleness.AsDuration())
+	if err != nil {
+		return req, err
+	}
+
+	resolved := proto.Clone(req).(T)
+	resolved.GetMetadata().AtRevision = revision.String()
+	resolved.GetMetadata().MaxRevisionStaleness = nil
+	return resolved, nil
+}
@@ -0,0 +1,81 @@
+package dispatch
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/types/known/durationpb"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+func TestRecentRevisionCache(t *testing.T) {
+	require := require.New(t)
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+	now := time.Now()
+	revisions := NewRecentRevisionCache()
+	revisions.now = func() time.Time { return now }
+
+	before, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	first, err := revisions.Revision(ctx, time.Second)
+	require.NoError(err)
+	after, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	// The revision is one served by the datastore at the time it was read.
+	require.True(first.GreaterThanOrEqual(before))
+	require.True(first.LessThanOrEqual(after))
+
+	// Within the bound, the cached revision is reused even though the datastore has moved on.
+	time.Sleep(time.Millisecond)
+	now = now.Add(time.Second)
+	revision, err := revisions.Revision(ctx, time.Second)
+	require.NoError(err)
+	require.True(first.Equal(revision))
+
+	// Past the bound, the current head revision is read again.
+	now = now.Add(time.Nanosecond)
+	revision, err = revisions.Revision(ctx, time.Second)
+	require.NoError(err)
+	require.True(revision.GreaterThan(first))
+}
+
+func TestResolveRevision(t *testing.T) {
+	require := require.New(t)
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+	revisions := NewRecentRevisionCache()
+
+	explicit := &v1.DispatchCheckRequest{Metadata: &v1.ResolverMeta{AtRevision: "1", DepthRemaining: 1}}
+	resolved, err := ResolveRevision(ctx, explicit, revisions)
+	require.NoError(err)
+	require.Same(explicit, resolved)
+
+	_, err = ResolveRevision(ctx, &v1.DispatchCheckRequest{Metadata: &v1.ResolverMeta{DepthRemaining: 1}}, revisions)
+	require.Error(err)
+
+	stale := &v1.DispatchCheckRequest{Metadata: &v1.ResolverMeta{
+		DepthRemaining:       1,
+		MaxRevisionStaleness: durationpb.New(time.Minute),
+	}}
+	resolved, err = ResolveRevision(ctx, stale, revisions)
+	require.NoError(err)
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	require.Equal(head.String(), resolved.Metadata.AtRevision)
+	require.Nil(resolved.Metadata.MaxRevisionStaleness)
+	require.Empty(stale.Metadata.AtRevision)
+}
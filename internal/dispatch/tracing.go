@@ -0,0 +1,107 @@
+package dispatch
+
+import (
+	"fmt"
+
+	"go.opentelemetry.io/otel/attribute"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// Each dispatched request has a span, whether its response is computed, in which case the spans
+// of the requests it dispatches in turn are nested within it, or loaded from the dispatch cache.
+// The attributes below are set on all of them.
+var (
+	// StartKey is the resource, or resource type and relation, from which a request starts.
+	StartKey = attribute.Key("start")
+
+	// SubjectKey is the subject, or subject type and relation, of a request.
+	SubjectKey = attribute.Key("subject")
+
+	// LimitKey is the maximum number of results of a request.
+	LimitKey = attribute.Key("limit")
+
+	// RevisionKey is the revision at which a request is resolved.
+	RevisionKey = attribute.Key("revision")
+
+	// DepthRemainingKey is the remaining depth of a request.
+	DepthRemainingKey = attribute.Key("depthRemaining")
+
+	// CachedKey indicates whether the response to a request was loaded from the dispatch cache.
+	CachedKey = attribute.Key("cached")
+)
+
+// CheckSpanAttributes returns the attributes of the span of a dispatched check.
+func CheckSpanAttributes(req *v1.DispatchCheckRequest, cached bool) []attribute.KeyValue {
+	return append(metadataAttributes(req.Metadata, cached),
+		StartKey.String(tuple.StringONR(req.ResourceAndRelation)),
+		SubjectKey.String(tuple.StringONR(req.Subject)),
+	)
+}
+
+// CheckBulkSpanAttributes returns the attributes of the span of a dispatched bulk check.
+func CheckBulkSpanAttributes(req *v1.DispatchCheckBulkRequest, cached bool) []attribute.KeyValue {
+	return append(metadataAttributes(req.Metadata, cached),
+		SubjectKey.String(tuple.StringONR(req.Subject)),
+	)
+}
+
+// ExpandSpanAttributes returns the attributes of the span of a dispatched expand.
+func ExpandSpanAttributes(req *v1.DispatchExpandRequest, cached bool) []attribute.KeyValue {
+	return append(metadataAttributes(req.Metadata, cached),
+		StartKey.String(tuple.StringONR(req.ResourceAndRelation)),
+	)
+}
+
+// LookupSpanAttributes returns the attributes of the span of a dispatched lookup.
+func LookupSpanAttributes(req *v1.DispatchLookupRequest, cached bool) []attribute.KeyValue {
+	return append(metadataAttributes(req.Metadata, cached),
+		StartKey.String(relationString(req.ObjectRelation)),
+		SubjectKey.String(tuple.StringONR(req.Subject)),
+		LimitKey.Int64(int64(req.Limit)),
+	)
+}
+
+// ReachableResourcesSpanAttributes returns the attributes of the span of a dispatched reachable
+// resources request.
+func ReachableResourcesSpanAttributes(req *v1.DispatchReachableResourcesRequest, cached bool) []attribute.KeyValue {
+	return append(metadataAttributes(req.Metadata, cached),
+		StartKey.String(relationString(req.ObjectRelation)),
+		SubjectKey.String(tuple.StringONR(req.Subject)),
+		LimitKey.Int64(int64(req.Limit)),
+	)
+}
+
+// LookupSubjectsSpanAttributes returns the attributes of the span of a dispatched lookup subjects
+// request.
+func LookupSubjectsSpanAttributes(req *v1.DispatchLookupSubjectsRequest, cached bool) []attribute.KeyValue {
+	return append(metadataAttributes(req.Metadata, cached),
+		StartKey.String(tuple.StringONR(req.ResourceAndRelation)),
+		SubjectKey.String(relationString(req.SubjectRelation)),
+		LimitKey.Int64(int64(req.Limit)),
+	)
+}
+
+// LookupForSubjectTypeSpanAttributes returns the attributes of the span of a dispatched lookup
+// for a subject type.
+func LookupForSubjectTypeSpanAttributes(req *v1.DispatchLookupForSubjectTypeRequest, cached bool) []attribute.KeyValue {
+	return append(metadataAttributes(req.Metadata, cached),
+		StartKey.String(relationString(req.ObjectRelation)),
+		SubjectKey.String(relationString(req.SubjectType)),
+		LimitKey.Int64(int64(req.Limit)),
+	)
+}
+
+func metadataAttributes(metadata *v1.ResolverMeta, cached bool) []attribute.KeyValue {
+	return []attribute.KeyValue{
+		RevisionKey.String(metadata.GetAtRevision()),
+		DepthRemainingKey.Int64(int64(metadata.GetDepthRemaining())),
+		CachedKey.Bool(cached),
+	}
+}
+
+func relationString(rr *core.RelationReference) string {
+	return fmt.Sprintf("%s::%s", rr.Namespace, rr.Relation)
+}
@@ -14,19 +14,24 @@ import (
 	"github.com/authzed/spicedb/internal/dispatch"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
-// NewConcurrentChecker creates an instance of ConcurrentChecker.
-func NewConcurrentChecker(d dispatch.Check) *ConcurrentChecker {
-	return &ConcurrentChecker{d: d}
+// NewConcurrentChecker creates an instance of ConcurrentChecker, which runs its subproblems
+// concurrently within the bounds of the provided limiter. If directFastPath is set, checks of
+// relations without rewrites first look for the subject being directly related to the resource.
+func NewConcurrentChecker(d dispatch.Check, limiter *ConcurrencyLimiter, directFastPath bool) *ConcurrentChecker {
+	return &ConcurrentChecker{d: d, limiter: limiter, directFastPath: directFastPath}
 }
 
 // ConcurrentChecker exposes a method to perform Check requests, and delegates subproblems to the
 // provided dispatch.Check instance.
 type ConcurrentChecker struct {
-	d dispatch.Check
+	d              dispatch.Check
+	limiter        *ConcurrencyLimiter
+	directFastPath bool
 }
 
 func onrEqual(lhs, rhs *core.ObjectAndRelation) bool {
@@ -54,14 +59,20 @@ func (cc *ConcurrentChecker) Check(ctx context.Context, req ValidatedCheckReques
 			// If we have found the goal's ONR, then we know that the ONR is a member.
 			directFunc = alwaysMember()
 		} else if relation.UsersetRewrite == nil {
-			directFunc = cc.checkDirect(ctx, req)
+			directFunc = cc.checkDirect(ctx, req, relation)
 		} else {
 			directFunc = cc.checkUsersetRewrite(ctx, req, relation.UsersetRewrite)
 		}
 	}
 
-	resolved := union(ctx, []ReduceableCheckFunc{directFunc})
-	resolved.Resp.Metadata = addCallToResponseMetadata(resolved.Resp.Metadata)
+	resolved := union(ctx, cc.limiter, []ReduceableCheckFunc{directFunc})
+	resolved.Resp.Metadata = addNamespaceReadToResponseMetadata(
+		addCallToResponseMetadata(resolved.Resp.Metadata),
+		req.ResourceAndRelation.Namespace,
+	)
+	if req.Explain {
+		resolved.Resp.Explanation = explainResolved(req, resolved.Resp)
+	}
 	return resolved.Resp, resolved.Err
 }
 
@@ -77,11 +88,70 @@ func onrEqualOrWildcard(tpl, target *core.ObjectAndRelation) bool {
 	return onrEqual(tpl, target) || (tpl.Namespace == target.Namespace && tpl.ObjectId == tuple.PublicWildcard)
 }
 
-func (cc *ConcurrentChecker) checkDirect(ctx context.Context, req ValidatedCheckRequest) ReduceableCheckFunc {
+// allowsOnlyDirectSubjects returns whether the relation only allows objects and wildcards as
+// subjects, in which case checking it never requires dispatching.
+func allowsOnlyDirectSubjects(relation *core.Relation) bool {
+	for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		if allowed.GetPublicWildcard() == nil && allowed.GetRelation() != Ellipsis {
+			return false
+		}
+	}
+	return true
+}
+
+// checkDirectSubject returns the relationship by which the subject, or a wildcard of its type, is
+// directly related to the resource, or nil if there is none.
+func checkDirectSubject(ctx context.Context, ds datastore.Reader, req ValidatedCheckRequest) (*core.RelationTuple, error) {
+	candidates := []*core.RelationTuple{
+		{ResourceAndRelation: req.ResourceAndRelation, Subject: req.Subject},
+		{
+			ResourceAndRelation: req.ResourceAndRelation,
+			Subject: &core.ObjectAndRelation{
+				Namespace: req.Subject.Namespace,
+				ObjectId:  tuple.PublicWildcard,
+				Relation:  Ellipsis,
+			},
+		},
+	}
+
+	exists, err := ds.CheckRelationshipsExist(ctx, candidates)
+	if err != nil {
+		return nil, err
+	}
+
+	for i, found := range exists {
+		if found {
+			return candidates[i], nil
+		}
+	}
+	return nil, nil
+}
+
+func (cc *ConcurrentChecker) checkDirect(ctx context.Context, req ValidatedCheckRequest, relation *core.Relation) ReduceableCheckFunc {
 	return func(ctx context.Context, resultChan chan<- CheckResult) {
 		log.Ctx(ctx).Trace().Object("direct", req).Send()
 		ds := datastoremw.MustFromContext(ctx).SnapshotReader(req.Revision)
 
+		if cc.directFastPath {
+			found, err := checkDirectSubject(ctx, ds, req)
+			if err != nil {
+				resultChan <- checkResultError(NewCheckFailureErr(err), emptyMetadata)
+				return
+			}
+
+			if found != nil {
+				resultChan <- explainedMember(req, relationshipStep(found))
+				return
+			}
+
+			// If the relation does not allow usersets, the direct relationships are the only
+			// way for the subject to be a member.
+			if allowsOnlyDirectSubjects(relation) {
+				resultChan <- checkResult(v1.DispatchCheckResponse_NOT_MEMBER, emptyMetadata)
+				return
+			}
+		}
+
 		// TODO(jschorr): Use type information to further optimize this query.
 		it, err := ds.QueryRelationships(ctx, &v1_proto.RelationshipFilter{
 			ResourceType:       req.ResourceAndRelation.Namespace,
@@ -97,27 +167,28 @@ func (cc *ConcurrentChecker) checkDirect(ctx context.Context, req ValidatedCheck
 		var requestsToDispatch []ReduceableCheckFunc
 		for tpl := it.Next(); tpl != nil; tpl = it.Next() {
 			if onrEqualOrWildcard(tpl.Subject, req.Subject) {
-				resultChan <- checkResult(v1.DispatchCheckResponse_MEMBER, emptyMetadata)
+				resultChan <- explainedMember(req, relationshipStep(tpl))
 				return
 			}
 			if tpl.Subject.Relation != Ellipsis {
 				// We need to recursively call check here, potentially changing namespaces
-				requestsToDispatch = append(requestsToDispatch, cc.dispatch(ValidatedCheckRequest{
+				requestsToDispatch = append(requestsToDispatch, explainedVia(req, cc.dispatch(ValidatedCheckRequest{
 					&v1.DispatchCheckRequest{
 						ResourceAndRelation: tpl.Subject,
 						Subject:             req.Subject,
+						Explain:             req.Explain,
 
 						Metadata: decrementDepth(req.Metadata),
 					},
 					req.Revision,
-				}))
+				}), relationshipStep(tpl)))
 			}
 		}
 		if it.Err() != nil {
 			resultChan <- checkResultError(NewCheckFailureErr(it.Err()), emptyMetadata)
 			return
 		}
-		resultChan <- union(ctx, requestsToDispatch)
+		resultChan <- union(ctx, cc.limiter, requestsToDispatch)
 	}
 }
 
@@ -154,7 +225,7 @@ func (cc *ConcurrentChecker) checkSetOperation(ctx context.Context, req Validate
 	}
 	return func(ctx context.Context, resultChan chan<- CheckResult) {
 		log.Ctx(ctx).Trace().Object("setOperation", req).Stringer("operation", so).Send()
-		resultChan <- reducer(ctx, requests)
+		resultChan <- reducer(ctx, cc.limiter, requests)
 	}
 }
 
@@ -180,9 +251,19 @@ func (cc *ConcurrentChecker) checkComputedUserset(ctx context.Context, req Valid
 		Relation:  cu.Relation,
 	}
 
+	var steps []*v1.CheckExplanationStep
+	if req.Explain {
+		if tpl != nil {
+			steps = append(steps, relationshipStep(tpl))
+		}
+		steps = append(steps, rewriteStep(req.ResourceAndRelation, targetOnr))
+	}
+
 	// If we will be dispatching to the goal's ONR, then we know that the ONR is a member.
 	if onrEqual(req.Subject, targetOnr) {
-		return alwaysMember()
+		return func(ctx context.Context, resultChan chan<- CheckResult) {
+			resultChan <- explainedMember(req, steps...)
+		}
 	}
 
 	// Check if the target relation exists. If not, return nothing.
@@ -190,20 +271,25 @@ func (cc *ConcurrentChecker) checkComputedUserset(ctx context.Context, req Valid
 	err := namespace.CheckNamespaceAndRelation(ctx, start.Namespace, cu.Relation, true, ds)
 	if err != nil {
 		if errors.As(err, &namespace.ErrRelationNotFound{}) {
-			return notMember()
+			// The result depends upon the definition of the namespace, which may later gain
+			// the relation.
+			return func(ctx context.Context, resultChan chan<- CheckResult) {
+				resultChan <- checkResult(v1.DispatchCheckResponse_NOT_MEMBER, &v1.ResponseMeta{NamespacesRead: []string{start.Namespace}})
+			}
 		}
 
 		return checkError(err)
 	}
 
-	return cc.dispatch(ValidatedCheckRequest{
+	return explainedVia(req, cc.dispatch(ValidatedCheckRequest{
 		&v1.DispatchCheckRequest{
 			ResourceAndRelation: targetOnr,
 			Subject:             req.Subject,
 			Metadata:            decrementDepth(req.Metadata),
+			Explain:             req.Explain,
 		},
 		req.Revision,
-	})
+	}), steps...)
 }
 
 func (cc *ConcurrentChecker) checkTupleToUserset(ctx context.Context, req ValidatedCheckRequest, ttu *core.TupleToUserset) ReduceableCheckFunc {
@@ -230,12 +316,12 @@ func (cc *ConcurrentChecker) checkTupleToUserset(ctx context.Context, req Valida
 			return
 		}
 
-		resultChan <- union(ctx, requestsToDispatch)
+		resultChan <- union(ctx, cc.limiter, requestsToDispatch)
 	}
 }
 
 // all returns whether all of the lazy checks pass, and is used for intersection.
-func all(ctx context.Context, requests []ReduceableCheckFunc) CheckResult {
+func all(ctx context.Context, limiter *ConcurrencyLimiter, requests []ReduceableCheckFunc) CheckResult {
 	if len(requests) == 0 {
 		return checkResult(v1.DispatchCheckResponse_NOT_MEMBER, emptyMetadata)
 	}
@@ -246,9 +332,11 @@ func all(ctx context.Context, requests []ReduceableCheckFunc) CheckResult {
 	defer cancelFn()
 
 	for _, req := range requests {
-		go req(childCtx, resultChan)
+		req := req
+		limiter.run(func() { req(childCtx, resultChan) })
 	}
 
+	explanations := make([]*v1.CheckExplanation, 0, len(requests))
 	for i := 0; i < len(requests); i++ {
 		select {
 		case result := <-resultChan:
@@ -258,14 +346,15 @@ func all(ctx context.Context, requests []ReduceableCheckFunc) CheckResult {
 			}
 
 			if result.Resp.Membership != v1.DispatchCheckResponse_MEMBER {
-				return checkResult(v1.DispatchCheckResponse_NOT_MEMBER, responseMetadata)
+				return withExplanation(checkResult(v1.DispatchCheckResponse_NOT_MEMBER, responseMetadata), combineExplored(result.Resp.Explanation))
 			}
+			explanations = append(explanations, result.Resp.Explanation)
 		case <-ctx.Done():
 			return checkResultError(NewRequestCanceledErr(), responseMetadata)
 		}
 	}
 
-	return checkResult(v1.DispatchCheckResponse_MEMBER, responseMetadata)
+	return withExplanation(checkResult(v1.DispatchCheckResponse_MEMBER, responseMetadata), combineGrantPaths(explanations...))
 }
 
 // checkError returns the error.
@@ -290,7 +379,7 @@ func notMember() ReduceableCheckFunc {
 }
 
 // union returns whether any one of the lazy checks pass, and is used for union.
-func union(ctx context.Context, requests []ReduceableCheckFunc) CheckResult {
+func union(ctx context.Context, limiter *ConcurrencyLimiter, requests []ReduceableCheckFunc) CheckResult {
 	if len(requests) == 0 {
 		return checkResult(v1.DispatchCheckResponse_NOT_MEMBER, emptyMetadata)
 	}
@@ -300,10 +389,12 @@ func union(ctx context.Context, requests []ReduceableCheckFunc) CheckResult {
 	defer cancelFn()
 
 	for _, req := range requests {
-		go req(childCtx, resultChan)
+		req := req
+		limiter.run(func() { req(childCtx, resultChan) })
 	}
 
 	responseMetadata := emptyMetadata
+	explanations := make([]*v1.CheckExplanation, 0, len(requests))
 
 	for i := 0; i < len(requests); i++ {
 		select {
@@ -312,34 +403,37 @@ func union(ctx context.Context, requests []ReduceableCheckFunc) CheckResult {
 			responseMetadata = combineResponseMetadata(responseMetadata, result.Resp.Metadata)
 
 			if result.Err == nil && result.Resp.Membership == v1.DispatchCheckResponse_MEMBER {
-				return checkResult(v1.DispatchCheckResponse_MEMBER, result.Resp.Metadata)
+				return withExplanation(checkResult(v1.DispatchCheckResponse_MEMBER, result.Resp.Metadata), result.Resp.Explanation)
 			}
 			if result.Err != nil {
 				return checkResultError(result.Err, result.Resp.Metadata)
 			}
+			explanations = append(explanations, result.Resp.Explanation)
 		case <-ctx.Done():
 			log.Ctx(ctx).Trace().Msg("anyCanceled")
 			return checkResultError(NewRequestCanceledErr(), responseMetadata)
 		}
 	}
 
-	return checkResult(v1.DispatchCheckResponse_NOT_MEMBER, responseMetadata)
+	return withExplanation(checkResult(v1.DispatchCheckResponse_NOT_MEMBER, responseMetadata), combineExplored(explanations...))
 }
 
 // difference returns whether the first lazy check passes and none of the supsequent checks pass.
-func difference(ctx context.Context, requests []ReduceableCheckFunc) CheckResult {
+func difference(ctx context.Context, limiter *ConcurrencyLimiter, requests []ReduceableCheckFunc) CheckResult {
 	childCtx, cancelFn := context.WithCancel(ctx)
 	defer cancelFn()
 
 	baseChan := make(chan CheckResult, 1)
 	othersChan := make(chan CheckResult, len(requests)-1)
 
-	go requests[0](childCtx, baseChan)
+	limiter.run(func() { requests[0](childCtx, baseChan) })
 	for _, req := range requests[1:] {
-		go req(childCtx, othersChan)
+		req := req
+		limiter.run(func() { req(childCtx, othersChan) })
 	}
 
 	responseMetadata := emptyMetadata
+	var baseExplanation *v1.CheckExplanation
 
 	for i := 0; i < len(requests); i++ {
 		select {
@@ -351,8 +445,9 @@ func difference(ctx context.Context, requests []ReduceableCheckFunc) CheckResult
 			}
 
 			if base.Resp.Membership != v1.DispatchCheckResponse_MEMBER {
-				return checkResult(v1.DispatchCheckResponse_NOT_MEMBER, responseMetadata)
+				return withExplanation(checkResult(v1.DispatchCheckResponse_NOT_MEMBER, responseMetadata), combineExplored(base.Resp.Explanation))
 			}
+			baseExplanation = base.Resp.Explanation
 		case sub := <-othersChan:
 			responseMetadata = combineResponseMetadata(responseMetadata, sub.Resp.Metadata)
 
@@ -361,14 +456,19 @@ func difference(ctx context.Context, requests []ReduceableCheckFunc) CheckResult
 			}
 
 			if sub.Resp.Membership == v1.DispatchCheckResponse_MEMBER {
-				return checkResult(v1.DispatchCheckResponse_NOT_MEMBER, responseMetadata)
+				// The subject was excluded rather than not found, so nothing came up empty.
+				var explanation *v1.CheckExplanation
+				if sub.Resp.Explanation != nil {
+					explanation = &v1.CheckExplanation{}
+				}
+				return withExplanation(checkResult(v1.DispatchCheckResponse_NOT_MEMBER, responseMetadata), explanation)
 			}
 		case <-ctx.Done():
 			return checkResultError(NewRequestCanceledErr(), responseMetadata)
 		}
 	}
 
-	return checkResult(v1.DispatchCheckResponse_MEMBER, responseMetadata)
+	return withExplanation(checkResult(v1.DispatchCheckResponse_MEMBER, responseMetadata), combineGrantPaths(baseExplanation))
 }
 
 func checkResult(membership v1.DispatchCheckResponse_Membership, subProblemMetadata *v1.ResponseMeta) CheckResult {
@@ -381,6 +481,11 @@ func checkResult(membership v1.DispatchCheckResponse_Membership, subProblemMetad
 	}
 }
 
+func withExplanation(result CheckResult, explanation *v1.CheckExplanation) CheckResult {
+	result.Resp.Explanation = explanation
+	return result
+}
+
 func checkResultError(err error, subProblemMetadata *v1.ResponseMeta) CheckResult {
 	return CheckResult{
 		&v1.DispatchCheckResponse{
@@ -0,0 +1,54 @@
+package graph
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+)
+
+// NewBulkChecker creates an instance of BulkChecker.
+func NewBulkChecker(d dispatch.Check) *BulkChecker {
+	return &BulkChecker{d: d}
+}
+
+// BulkChecker exposes a method to perform bulk checks, and delegates the check of each resource
+// relation to the provided dispatch.Check instance.
+type BulkChecker struct {
+	d dispatch.Check
+}
+
+// CheckBulk performs a bulk check with the provided request and context, returning the result of
+// the check of each resource relation for the subject, in the requested order. The revision of
+// the request must already be resolved.
+//
+// The resource relations are checked one after another, each as a sub-problem of the bulk check
+// with the depth remaining after it, as the sub-problems of a single check are. Depth bounds how
+// deeply a check recurses rather than how much work it does, so every resource relation is
+// checked within the same budget rather than a share of it, and the response requires the
+// greatest depth required by any of them.
+func (bc *BulkChecker) CheckBulk(ctx context.Context, req *v1.DispatchCheckBulkRequest) (*v1.DispatchCheckBulkResponse, error) {
+	log.Ctx(ctx).Trace().Object("checkBulk", req).Send()
+
+	metadata := emptyMetadata
+	results := make([]*v1.DispatchCheckResponse, 0, len(req.ResourcesAndRelations))
+	for _, resource := range req.ResourcesAndRelations {
+		result, err := bc.d.DispatchCheck(ctx, &v1.DispatchCheckRequest{
+			ResourceAndRelation: resource,
+			Subject:             req.Subject,
+			Metadata:            decrementDepth(req.Metadata),
+		})
+		metadata = combineResponseMetadata(metadata, ensureMetadata(result.GetMetadata()))
+		if err != nil {
+			return &v1.DispatchCheckBulkResponse{Metadata: addCallToResponseMetadata(metadata)}, err
+		}
+		results = append(results, result)
+	}
+
+	return &v1.DispatchCheckBulkResponse{
+		Metadata: addCallToResponseMetadata(metadata),
+		Results:  results,
+	}, nil
+}
@@ -0,0 +1,133 @@
+package graph
+
+import (
+	"context"
+	"sort"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// Explanations are built bottom up as check results are reduced: the leaves of a check produce
+// the relationship which granted membership, each dispatch prefixes the relationship or rewrite
+// which led to it, and each reducer keeps only the branches which decided its result. Responses
+// may be shared with the dispatch cache, so explanations are never modified once built.
+
+func relationshipStep(tpl *core.RelationTuple) *v1.CheckExplanationStep {
+	return &v1.CheckExplanationStep{
+		Step: &v1.CheckExplanationStep_Relationship{Relationship: tpl},
+	}
+}
+
+func rewriteStep(from, to *core.ObjectAndRelation) *v1.CheckExplanationStep {
+	return &v1.CheckExplanationStep{
+		Step: &v1.CheckExplanationStep_Rewrite{Rewrite: &v1.CheckExplanationRewrite{From: from, To: to}},
+	}
+}
+
+// explainedMember returns a member result which, if the request asked for an explanation, was
+// granted by the given steps.
+func explainedMember(req ValidatedCheckRequest, steps ...*v1.CheckExplanationStep) CheckResult {
+	result := checkResult(v1.DispatchCheckResponse_MEMBER, emptyMetadata)
+	if req.Explain {
+		result.Resp.Explanation = &v1.CheckExplanation{GrantPath: steps}
+	}
+	return result
+}
+
+// explainedVia prefixes the given steps to the grant path of the result of the check, if the
+// request asked for an explanation and the subject was found to be a member.
+func explainedVia(req ValidatedCheckRequest, check ReduceableCheckFunc, steps ...*v1.CheckExplanationStep) ReduceableCheckFunc {
+	if !req.Explain {
+		return check
+	}
+
+	return func(ctx context.Context, resultChan chan<- CheckResult) {
+		checkResultChan := make(chan CheckResult, 1)
+		check(ctx, checkResultChan)
+		result := <-checkResultChan
+
+		if result.Err != nil || result.Resp.Membership != v1.DispatchCheckResponse_MEMBER {
+			resultChan <- result
+			return
+		}
+
+		grantPath := make([]*v1.CheckExplanationStep, 0, len(steps)+len(result.Resp.Explanation.GetGrantPath()))
+		grantPath = append(grantPath, steps...)
+		grantPath = append(grantPath, result.Resp.Explanation.GetGrantPath()...)
+
+		resultChan <- CheckResult{
+			&v1.DispatchCheckResponse{
+				Metadata:    result.Resp.Metadata,
+				Membership:  result.Resp.Membership,
+				Explanation: &v1.CheckExplanation{GrantPath: grantPath},
+			},
+			nil,
+		}
+	}
+}
+
+// combineGrantPaths returns the explanation of a member whose membership required all of the
+// given explanations, or nil if none were explained.
+func combineGrantPaths(explanations ...*v1.CheckExplanation) *v1.CheckExplanation {
+	var combined *v1.CheckExplanation
+	for _, explanation := range explanations {
+		if explanation == nil {
+			continue
+		}
+		if combined == nil {
+			combined = &v1.CheckExplanation{}
+		}
+		combined.GrantPath = append(combined.GrantPath, explanation.GrantPath...)
+	}
+	return combined
+}
+
+// combineExplored returns the explanation of a non member whose explored frontier is the union of
+// those of the given explanations, or nil if none were explained. The frontier is sorted, as the
+// order in which concurrent branches complete is not meaningful.
+func combineExplored(explanations ...*v1.CheckExplanation) *v1.CheckExplanation {
+	var combined *v1.CheckExplanation
+	seen := make(map[string]struct{})
+	for _, explanation := range explanations {
+		if explanation == nil {
+			continue
+		}
+		if combined == nil {
+			combined = &v1.CheckExplanation{}
+		}
+		for _, onr := range explanation.Explored {
+			key := tuple.StringONR(onr)
+			if _, ok := seen[key]; ok {
+				continue
+			}
+			seen[key] = struct{}{}
+			combined.Explored = append(combined.Explored, onr)
+		}
+	}
+
+	if combined != nil {
+		sort.Slice(combined.Explored, func(i, j int) bool {
+			return tuple.StringONR(combined.Explored[i]) < tuple.StringONR(combined.Explored[j])
+		})
+	}
+	return combined
+}
+
+// explainResolved returns the explanation of the resolved response of a check of the request:
+// a member keeps the grant path of the branch which granted it, while a non member adds the
+// checked relation to the explored frontier.
+func explainResolved(req ValidatedCheckRequest, resp *v1.DispatchCheckResponse) *v1.CheckExplanation {
+	switch resp.Membership {
+	case v1.DispatchCheckResponse_MEMBER:
+		return &v1.CheckExplanation{GrantPath: resp.Explanation.GetGrantPath()}
+	case v1.DispatchCheckResponse_NOT_MEMBER:
+		return combineExplored(resp.Explanation, &v1.CheckExplanation{
+			Explored: []*core.ObjectAndRelation{req.ResourceAndRelation},
+		})
+	default:
+		return nil
+	}
+}
@@ -47,6 +47,49 @@ func NewExpansionFailureErr(baseErr error) error {
 	}
 }
 
+// ErrExpandTooLarge occurs when an expansion reaches more distinct nodes than its node limit.
+type ErrExpandTooLarge struct {
+	error
+	nodeLimit    uint32
+	depthReached uint32
+}
+
+// NodeLimit returns the maximum number of distinct nodes the expansion was allowed to reach.
+func (err ErrExpandTooLarge) NodeLimit() uint32 {
+	return err.nodeLimit
+}
+
+// DepthReached returns the deepest level of dispatch the expansion had reached when it failed.
+func (err ErrExpandTooLarge) DepthReached() uint32 {
+	return err.depthReached
+}
+
+func (err ErrExpandTooLarge) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("error", err.Error()).Uint32("nodeLimit", err.nodeLimit).Uint32("depthReached", err.depthReached)
+}
+
+// NewExpandTooLargeErr constructs a new expansion too large error.
+func NewExpandTooLargeErr(nodeLimit uint32, depthReached uint32) error {
+	return ErrExpandTooLarge{
+		error:        fmt.Errorf("expansion reached more than %d distinct nodes, at a depth of %d", nodeLimit, depthReached),
+		nodeLimit:    nodeLimit,
+		depthReached: depthReached,
+	}
+}
+
+// ErrLookupSubjectsFailure occurs when a lookup of subjects failed in some manner. Note this should
+// not apply to namespaces and relations not being found.
+type ErrLookupSubjectsFailure struct {
+	error
+}
+
+// NewLookupSubjectsFailureErr constructs a new lookup subjects failed error.
+func NewLookupSubjectsFailureErr(baseErr error) error {
+	return ErrLookupSubjectsFailure{
+		error: fmt.Errorf("error performing lookup subjects: %w", baseErr),
+	}
+}
+
 // ErrAlwaysFail is returned when an internal error leads to an operation
 // guaranteed to fail.
 type ErrAlwaysFail struct {
@@ -17,15 +17,19 @@ import (
 	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
 
-// NewConcurrentExpander creates an instance of ConcurrentExpander
-func NewConcurrentExpander(d dispatch.Expand) *ConcurrentExpander {
-	return &ConcurrentExpander{d: d}
+// NewConcurrentExpander creates an instance of ConcurrentExpander, which runs its subproblems
+// concurrently within the bounds of the provided limiter. If nodeLimit is non-zero, expansions
+// fail with ErrExpandTooLarge once they reach more than that many distinct nodes.
+func NewConcurrentExpander(d dispatch.Expand, limiter *ConcurrencyLimiter, nodeLimit uint32) *ConcurrentExpander {
+	return &ConcurrentExpander{d: d, limiter: limiter, nodeLimit: nodeLimit}
 }
 
 // ConcurrentExpander exposes a method to perform Expand requests, and delegates subproblems to the
 // provided dispatch.Expand instance.
 type ConcurrentExpander struct {
-	d dispatch.Expand
+	d         dispatch.Expand
+	limiter   *ConcurrencyLimiter
+	nodeLimit uint32
 }
 
 // ValidatedExpandRequest represents a request after it has been validated and parsed for internal
@@ -39,6 +43,8 @@ type ValidatedExpandRequest struct {
 func (ce *ConcurrentExpander) Expand(ctx context.Context, req ValidatedExpandRequest, relation *core.Relation) (*v1.DispatchExpandResponse, error) {
 	log.Ctx(ctx).Trace().Object("expand", req).Send()
 
+	ctx, tracker := ce.nodeTracker(ctx, req)
+
 	var directFunc ReduceableExpandFunc
 	if relation.UsersetRewrite == nil {
 		directFunc = ce.expandDirect(ctx, req)
@@ -46,8 +52,14 @@ func (ce *ConcurrentExpander) Expand(ctx context.Context, req ValidatedExpandReq
 		directFunc = ce.expandUsersetRewrite(ctx, req, relation.UsersetRewrite)
 	}
 
-	resolved := expandOne(ctx, directFunc)
+	resolved := expandOne(ctx, ce.limiter, directFunc)
 	resolved.Resp.Metadata = addCallToResponseMetadata(resolved.Resp.Metadata)
+	if resolved.Err == nil && tracker != nil {
+		if err := tracker.add(resolved.Resp.TreeNode); err != nil {
+			resolved.Resp.TreeNode = nil
+			return resolved.Resp, err
+		}
+	}
 	return resolved.Resp, resolved.Err
 }
 
@@ -114,7 +126,7 @@ func (ce *ConcurrentExpander) expandDirect(
 			}))
 		}
 
-		result := expandAny(ctx, req.ResourceAndRelation, requestsToDispatch)
+		result := expandAny(ctx, ce.limiter, req.ResourceAndRelation, requestsToDispatch)
 		if result.Err != nil {
 			resultChan <- result
 			return
@@ -168,7 +180,7 @@ func (ce *ConcurrentExpander) expandSetOperation(ctx context.Context, req Valida
 		}
 	}
 	return func(ctx context.Context, resultChan chan<- ExpandResult) {
-		resultChan <- reducer(ctx, req.ResourceAndRelation, requests)
+		resultChan <- reducer(ctx, ce.limiter, req.ResourceAndRelation, requests)
 	}
 }
 
@@ -246,7 +258,7 @@ func (ce *ConcurrentExpander) expandTupleToUserset(ctx context.Context, req Vali
 			return
 		}
 
-		resultChan <- expandAny(ctx, req.ResourceAndRelation, requestsToDispatch)
+		resultChan <- expandAny(ctx, ce.limiter, req.ResourceAndRelation, requestsToDispatch)
 	}
 }
 
@@ -272,6 +284,7 @@ func setResult(
 
 func expandSetOperation(
 	ctx context.Context,
+	limiter *ConcurrencyLimiter,
 	start *core.ObjectAndRelation,
 	requests []ReduceableExpandFunc,
 	op core.SetOperationUserset_Operation,
@@ -287,9 +300,12 @@ func expandSetOperation(
 
 	resultChans := make([]chan ExpandResult, 0, len(requests))
 	for _, req := range requests {
-		resultChan := make(chan ExpandResult)
+		// Buffered so that requests run serially by the limiter do not block.
+		resultChan := make(chan ExpandResult, 1)
 		resultChans = append(resultChans, resultChan)
-		go req(childCtx, resultChan)
+
+		req := req
+		limiter.run(func() { req(childCtx, resultChan) })
 	}
 
 	responseMetadata := emptyMetadata
@@ -329,24 +345,24 @@ func expandError(err error) ReduceableExpandFunc {
 }
 
 // expandAll returns a tree with all of the children and an intersection node type.
-func expandAll(ctx context.Context, start *core.ObjectAndRelation, requests []ReduceableExpandFunc) ExpandResult {
-	return expandSetOperation(ctx, start, requests, core.SetOperationUserset_INTERSECTION)
+func expandAll(ctx context.Context, limiter *ConcurrencyLimiter, start *core.ObjectAndRelation, requests []ReduceableExpandFunc) ExpandResult {
+	return expandSetOperation(ctx, limiter, start, requests, core.SetOperationUserset_INTERSECTION)
 }
 
 // expandAny returns a tree with all of the children and a union node type.
-func expandAny(ctx context.Context, start *core.ObjectAndRelation, requests []ReduceableExpandFunc) ExpandResult {
-	return expandSetOperation(ctx, start, requests, core.SetOperationUserset_UNION)
+func expandAny(ctx context.Context, limiter *ConcurrencyLimiter, start *core.ObjectAndRelation, requests []ReduceableExpandFunc) ExpandResult {
+	return expandSetOperation(ctx, limiter, start, requests, core.SetOperationUserset_UNION)
 }
 
 // expandDifference returns a tree with all of the children and an exclusion node type.
-func expandDifference(ctx context.Context, start *core.ObjectAndRelation, requests []ReduceableExpandFunc) ExpandResult {
-	return expandSetOperation(ctx, start, requests, core.SetOperationUserset_EXCLUSION)
+func expandDifference(ctx context.Context, limiter *ConcurrencyLimiter, start *core.ObjectAndRelation, requests []ReduceableExpandFunc) ExpandResult {
+	return expandSetOperation(ctx, limiter, start, requests, core.SetOperationUserset_EXCLUSION)
 }
 
 // expandOne waits for exactly one response
-func expandOne(ctx context.Context, request ReduceableExpandFunc) ExpandResult {
+func expandOne(ctx context.Context, limiter *ConcurrencyLimiter, request ReduceableExpandFunc) ExpandResult {
 	resultChan := make(chan ExpandResult, 1)
-	go request(ctx, resultChan)
+	limiter.run(func() { request(ctx, resultChan) })
 
 	select {
 	case result := <-resultChan:
@@ -0,0 +1,90 @@
+package graph
+
+import (
+	"context"
+	"sync"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+type expandNodeTrackerKey struct{}
+
+// expandNodeTracker counts the distinct nodes reached by an expansion, shared by all of the
+// subproblems it dispatches within this process, so that the expansion can be stopped once it
+// exceeds the node limit. Subproblems dispatched to other nodes track their own nodes, and are
+// counted again here when their results are returned.
+type expandNodeTracker struct {
+	sync.Mutex
+
+	limit     uint32
+	rootDepth uint32
+	maxDepth  uint32
+	nodes     map[string]struct{}
+}
+
+// nodeTracker returns the node tracker of the expansion, recording the depth of the request in
+// it, and creating it if the request is the root of the expansion. It returns nil if the
+// expansion is not limited.
+func (ce *ConcurrentExpander) nodeTracker(ctx context.Context, req ValidatedExpandRequest) (context.Context, *expandNodeTracker) {
+	if ce.nodeLimit == 0 {
+		return ctx, nil
+	}
+
+	tracker, ok := ctx.Value(expandNodeTrackerKey{}).(*expandNodeTracker)
+	if !ok {
+		tracker = &expandNodeTracker{
+			limit:     ce.nodeLimit,
+			rootDepth: req.Metadata.DepthRemaining,
+			nodes:     make(map[string]struct{}),
+		}
+		ctx = context.WithValue(ctx, expandNodeTrackerKey{}, tracker)
+	}
+
+	tracker.Lock()
+	defer tracker.Unlock()
+
+	if req.Metadata.DepthRemaining < tracker.rootDepth {
+		depth := tracker.rootDepth - req.Metadata.DepthRemaining
+		if depth > tracker.maxDepth {
+			tracker.maxDepth = depth
+		}
+	}
+
+	return ctx, tracker
+}
+
+// add records the nodes of the tree, each of which is the userset expanded by a node or one of
+// the subjects it found, returning ErrExpandTooLarge if the expansion has now reached more
+// distinct nodes than its limit.
+func (t *expandNodeTracker) add(node *core.RelationTupleTreeNode) error {
+	t.Lock()
+	defer t.Unlock()
+
+	t.addNode(node)
+	if len(t.nodes) > int(t.limit) {
+		return NewExpandTooLargeErr(t.limit, t.maxDepth)
+	}
+	return nil
+}
+
+func (t *expandNodeTracker) addNode(node *core.RelationTupleTreeNode) {
+	if node == nil {
+		return
+	}
+
+	if node.Expanded != nil {
+		t.nodes[tuple.StringONR(node.Expanded)] = struct{}{}
+	}
+
+	switch typed := node.NodeType.(type) {
+	case *core.RelationTupleTreeNode_LeafNode:
+		for _, subject := range typed.LeafNode.Subjects {
+			t.nodes[tuple.StringONR(subject)] = struct{}{}
+		}
+	case *core.RelationTupleTreeNode_IntermediateNode:
+		for _, child := range typed.IntermediateNode.ChildNodes {
+			t.addNode(child)
+		}
+	}
+}
@@ -33,7 +33,7 @@ type LookupResult struct {
 type ReduceableCheckFunc func(ctx context.Context, resultChan chan<- CheckResult)
 
 // Reducer is a type for the functions Any and All which combine check results.
-type Reducer func(ctx context.Context, requests []ReduceableCheckFunc) CheckResult
+type Reducer func(ctx context.Context, limiter *ConcurrencyLimiter, requests []ReduceableCheckFunc) CheckResult
 
 // AlwaysFail is a ReduceableCheckFunc which will always fail when reduced.
 func AlwaysFail(ctx context.Context, resultChan chan<- CheckResult) {
@@ -51,6 +51,7 @@ func AlwaysFailExpand(ctx context.Context, resultChan chan<- ExpandResult) {
 // ExpandReducer is a type for the functions Any and All which combine check results.
 type ExpandReducer func(
 	ctx context.Context,
+	limiter *ConcurrencyLimiter,
 	start *core.ObjectAndRelation,
 	requests []ReduceableExpandFunc,
 ) ExpandResult
@@ -59,6 +60,7 @@ func decrementDepth(md *v1.ResolverMeta) *v1.ResolverMeta {
 	return &v1.ResolverMeta{
 		AtRevision:     md.AtRevision,
 		DepthRemaining: md.DepthRemaining - 1,
+		CorrelationId:  md.CorrelationId,
 	}
 }
 
@@ -76,6 +78,7 @@ func combineResponseMetadata(existing *v1.ResponseMeta, responseMetadata *v1.Res
 		DispatchCount:       existing.DispatchCount + responseMetadata.DispatchCount,
 		DepthRequired:       max(existing.DepthRequired, responseMetadata.DepthRequired),
 		CachedDispatchCount: existing.CachedDispatchCount + responseMetadata.CachedDispatchCount,
+		NamespacesRead:      mergeNamespacesRead(existing.NamespacesRead, responseMetadata.NamespacesRead),
 	}
 }
 
@@ -88,6 +91,7 @@ func ensureMetadata(subProblemMetadata *v1.ResponseMeta) *v1.ResponseMeta {
 		DispatchCount:       subProblemMetadata.DispatchCount,
 		DepthRequired:       subProblemMetadata.DepthRequired,
 		CachedDispatchCount: subProblemMetadata.CachedDispatchCount,
+		NamespacesRead:      subProblemMetadata.NamespacesRead,
 	}
 }
 
@@ -97,5 +101,43 @@ func addCallToResponseMetadata(metadata *v1.ResponseMeta) *v1.ResponseMeta {
 		DispatchCount:       metadata.DispatchCount + 1,
 		DepthRequired:       metadata.DepthRequired + 1,
 		CachedDispatchCount: metadata.CachedDispatchCount,
+		NamespacesRead:      metadata.NamespacesRead,
 	}
 }
+
+// addNamespaceReadToResponseMetadata returns the metadata with the namespace added to the
+// namespaces read to compute the response.
+func addNamespaceReadToResponseMetadata(metadata *v1.ResponseMeta, nsName string) *v1.ResponseMeta {
+	withNamespace := ensureMetadata(metadata)
+	withNamespace.NamespacesRead = mergeNamespacesRead(metadata.NamespacesRead, []string{nsName})
+	return withNamespace
+}
+
+// mergeNamespacesRead returns the sorted union of two sorted slices of namespace names.
+func mergeNamespacesRead(existing, additional []string) []string {
+	if len(additional) == 0 {
+		return existing
+	}
+	if len(existing) == 0 {
+		return additional
+	}
+
+	merged := make([]string, 0, len(existing)+len(additional))
+	i, j := 0, 0
+	for i < len(existing) && j < len(additional) {
+		switch {
+		case existing[i] < additional[j]:
+			merged = append(merged, existing[i])
+			i++
+		case existing[i] > additional[j]:
+			merged = append(merged, additional[j])
+			j++
+		default:
+			merged = append(merged, existing[i])
+			i++
+			j++
+		}
+	}
+	merged = append(merged, existing[i:]...)
+	return append(merged, additional[j:]...)
+}
@@ -0,0 +1,40 @@
+package graph
+
+// ConcurrencyLimiter bounds the number of goroutines used to run subproblems concurrently. Once
+// the limit is reached, further subproblems are run serially by their caller instead, which
+// ensures that a subproblem never waits on a slot held by one of its ancestors.
+//
+// A nil ConcurrencyLimiter does not limit concurrency.
+type ConcurrencyLimiter struct {
+	slots chan struct{}
+}
+
+// NewConcurrencyLimiter creates a ConcurrencyLimiter which runs at most limit subproblems at
+// once, including the one run by the goroutine serving the request. A limit of one runs all
+// subproblems serially.
+func NewConcurrencyLimiter(limit int) *ConcurrencyLimiter {
+	if limit < 1 {
+		limit = 1
+	}
+
+	return &ConcurrencyLimiter{slots: make(chan struct{}, limit-1)}
+}
+
+// run runs f in a new goroutine if the limit has not been reached, and in the calling goroutine
+// otherwise.
+func (cl *ConcurrencyLimiter) run(f func()) {
+	if cl == nil {
+		go f()
+		return
+	}
+
+	select {
+	case cl.slots <- struct{}{}:
+		go func() {
+			defer func() { <-cl.slots }()
+			f()
+		}()
+	default:
+		f()
+	}
+}
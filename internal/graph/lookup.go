@@ -4,8 +4,13 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"math"
+	"sort"
+	"strings"
 	"sync"
 
+	"github.com/rs/zerolog/log"
+	"github.com/scylladb/go-set/strset"
 	"github.com/shopspring/decimal"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -17,6 +22,12 @@ import (
 
 const MaxConcurrentSlowLookupChecks = 10
 
+// unlimitedLookup is the limit of lookups which return all of their results.
+const unlimitedLookup = ^uint32(0)
+
+// pageTokenSeparator separates the resources listed by a page token.
+const pageTokenSeparator = ","
+
 // NewConcurrentLookup creates and instance of ConcurrentLookup.
 func NewConcurrentLookup(c dispatch.Check, r dispatch.ReachableResources) *ConcurrentLookup {
 	return &ConcurrentLookup{c: c, r: r}
@@ -38,8 +49,17 @@ type ValidatedLookupRequest struct {
 
 type collectingStream struct {
 	checker *ParallelChecker
+
+	// previous holds the resources returned by the previous pages of the lookup, which are
+	// neither checked nor returned again.
+	previous *strset.Set
+
+	// page, if set, collects the allowed resources of a limited lookup.
+	page *pageCollector
+
 	req     ValidatedLookupRequest
 	context context.Context
+	tracer  *lookupTracer
 
 	dispatchCount       uint32
 	cachedDispatchCount uint32
@@ -66,29 +86,128 @@ func (ls *collectingStream) Publish(result *v1.DispatchReachableResourcesRespons
 		ls.depthRequired = max(result.Metadata.DepthRequired, ls.depthRequired)
 	}()
 
+	if ls.tracer != nil {
+		ls.tracer.reached(result)
+	}
+
+	if ls.previous.Has(tuple.StringONR(result.Resource.Resource)) {
+		return nil
+	}
+
+	if ls.page != nil && ls.page.complete() {
+		return nil
+	}
+
 	if result.Resource.ResultStatus == v1.ReachableResource_HAS_PERMISSION {
 		ls.checker.AddResult(result.Resource.Resource)
+		if ls.page != nil {
+			ls.page.allowed(result.Resource.Resource)
+		}
 		return nil
 	}
 
 	ls.checker.QueueCheck(result.Resource.Resource, &v1.ResolverMeta{
 		AtRevision:     ls.req.Revision.String(),
 		DepthRemaining: ls.req.Metadata.DepthRemaining,
+		CorrelationId:  ls.req.Metadata.CorrelationId,
 	})
 	return nil
 }
 
+// pageCollector collects the allowed resources found by a limited lookup, cancelling the lookup
+// once it has found one more than the limit, as that is enough to fill the page and to know
+// whether there are more.
+type pageCollector struct {
+	needed uint32
+	cancel context.CancelFunc
+	found  *tuple.ONRSet
+
+	mu sync.Mutex
+}
+
+func (pc *pageCollector) allowed(resource *core.ObjectAndRelation) {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+
+	pc.found.Add(resource)
+	if pc.found.Length() >= pc.needed {
+		pc.cancel()
+	}
+}
+
+func (pc *pageCollector) complete() bool {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.found.Length() >= pc.needed
+}
+
+func (pc *pageCollector) resources() []*core.ObjectAndRelation {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
+	return pc.found.AsSlice()
+}
+
 func (cl *ConcurrentLookup) LookupViaReachability(ctx context.Context, req ValidatedLookupRequest) (*v1.DispatchLookupResponse, error) {
+	log.Ctx(ctx).Trace().Object("lookup", req).Send()
+
 	if req.Subject.ObjectId == tuple.PublicWildcard {
 		resp := lookupResultError(NewErrInvalidArgument(errors.New("cannot perform lookup on wildcard")), emptyMetadata)
 		return resp.Resp, resp.Err
 	}
 
+	// The debug trace is only collected when requested, to avoid its cost otherwise.
+	var tracer *lookupTracer
+	if req.DebugTrace {
+		var err error
+		tracer, err = newLookupTracer(ctx, req)
+		if err != nil {
+			resp := lookupResultError(err, emptyMetadata)
+			return resp.Resp, resp.Err
+		}
+	}
+
 	cancelCtx, checkCancel := context.WithCancel(ctx)
 	defer checkCancel()
 
-	checker := NewParallelChecker(cancelCtx, cl.c, req.Subject, 10)
-	stream := &collectingStream{checker, req, cancelCtx, 0, 0, 0, sync.Mutex{}}
+	previous := pageTokenResources(req.PageToken)
+
+	// A limited lookup stops as soon as it has found enough allowed resources. The limit is
+	// passed into the reachability dispatch, which stops once it has reached enough resources
+	// known to have permission, and the resources requiring a check are checked as they are
+	// reached, until enough are confirmed.
+	var page *pageCollector
+	var reachabilityLimit uint32
+	if req.Limit != unlimitedLookup {
+		page = &pageCollector{needed: req.Limit + 1, cancel: checkCancel, found: tuple.NewONRSet()}
+
+		// The resources returned by the previous pages are reached again, so they are added to
+		// the limit. A limit too large to be represented is the same as none.
+		if limit := uint64(req.Limit) + 1 + uint64(previous.Size()); limit <= math.MaxUint32 {
+			reachabilityLimit = uint32(limit)
+		}
+	}
+	stoppedEarly := func() bool {
+		return page != nil && page.complete()
+	}
+
+	checker := NewParallelChecker(cancelCtx, cl.c, req.Subject, MaxConcurrentSlowLookupChecks)
+	checker.OnChecked(func(checkReq *v1.DispatchCheckRequest, res *v1.DispatchCheckResponse) {
+		if tracer != nil {
+			tracer.checked(checkReq, res)
+		}
+		if page != nil && res.Membership == v1.DispatchCheckResponse_MEMBER {
+			page.allowed(checkReq.ResourceAndRelation)
+		}
+	})
+
+	stream := &collectingStream{
+		checker:  checker,
+		previous: previous,
+		page:     page,
+		req:      req,
+		context:  cancelCtx,
+		tracer:   tracer,
+	}
 
 	// Start the checker.
 	checker.Start()
@@ -99,43 +218,88 @@ func (cl *ConcurrentLookup) LookupViaReachability(ctx context.Context, req Valid
 		ObjectRelation: req.ObjectRelation,
 		Subject:        req.Subject,
 		Metadata:       req.Metadata,
+		Limit:          reachabilityLimit,
 	}, stream)
-	if err != nil {
+	if err != nil && !stoppedEarly() {
 		resp := lookupResultError(NewErrInvalidArgument(fmt.Errorf("error in reachablility: %w", err)), emptyMetadata)
 		return resp.Resp, resp.Err
 	}
 
-	// Wait for the checker to finish.
+	// Wait for the checker to finish. Once a limited lookup has stopped, the checks still
+	// running are cancelled, and the resources it found are used instead.
 	allowed, err := checker.Wait()
-	if err != nil {
+	var found []*core.ObjectAndRelation
+	if stoppedEarly() {
+		found = page.resources()
+	} else if err != nil {
 		resp := lookupResultError(err, emptyMetadata)
 		return resp.Resp, resp.Err
+	} else {
+		found = allowed.AsSlice()
 	}
 
-	res := lookupResult(limitedSlice(allowed.AsSlice(), req.Limit), &v1.ResponseMeta{
+	resolved, hasMore := pagedSlice(found, req.Limit)
+	res := lookupResult(req.PageToken, resolved, hasMore, &v1.ResponseMeta{
 		DispatchCount:       stream.dispatchCount + checker.DispatchCount() + 1, // +1 for the lookup
 		CachedDispatchCount: stream.cachedDispatchCount + checker.CachedDispatchCount(),
 		DepthRequired:       max(stream.depthRequired, checker.DepthRequired()) + 1, // +1 for the lookup
 	})
+	if tracer != nil {
+		res.Resp.DebugTrace = tracer.trace(resolved)
+	}
 	return res.Resp, res.Err
 }
 
-func lookupResult(resolvedONRs []*core.ObjectAndRelation, subProblemMetadata *v1.ResponseMeta) LookupResult {
-	return LookupResult{
-		&v1.DispatchLookupResponse{
-			Metadata:     ensureMetadata(subProblemMetadata),
-			ResolvedOnrs: resolvedONRs,
-		},
-		nil,
+func lookupResult(pageToken string, resolvedONRs []*core.ObjectAndRelation, hasMore bool, subProblemMetadata *v1.ResponseMeta) LookupResult {
+	resp := &v1.DispatchLookupResponse{
+		Metadata:     ensureMetadata(subProblemMetadata),
+		ResolvedOnrs: resolvedONRs,
+	}
+
+	// A page can only be continued once it has returned a resource, so an empty page, as
+	// returned for a limit of zero, never has more.
+	if hasMore && len(resolvedONRs) > 0 {
+		resp.HasMore = true
+		resp.NextPageToken = nextPageToken(pageToken, resolvedONRs)
 	}
+
+	return LookupResult{resp, nil}
 }
 
-func limitedSlice(slice []*core.ObjectAndRelation, limit uint32) []*core.ObjectAndRelation {
+// nextPageToken returns the token continuing a lookup after the given page. As the resources
+// found first depend on the order in which the traversal completes, a page cannot be continued
+// from its last resource alone, so the token lists every resource returned so far.
+func nextPageToken(pageToken string, page []*core.ObjectAndRelation) string {
+	resources := make([]string, 0, len(page)+1)
+	if pageToken != "" {
+		resources = append(resources, pageToken)
+	}
+	for _, resource := range page {
+		resources = append(resources, tuple.StringONR(resource))
+	}
+	return strings.Join(resources, pageTokenSeparator)
+}
+
+// pageTokenResources returns the string forms of the resources listed by a page token.
+func pageTokenResources(pageToken string) *strset.Set {
+	if pageToken == "" {
+		return strset.New()
+	}
+	return strset.New(strings.Split(pageToken, pageTokenSeparator)...)
+}
+
+// pagedSlice orders the found ONRs by their string form and returns at most limit of them,
+// along with whether any further ONRs remain.
+func pagedSlice(slice []*core.ObjectAndRelation, limit uint32) ([]*core.ObjectAndRelation, bool) {
+	sort.Slice(slice, func(i, j int) bool {
+		return tuple.StringONR(slice[i]) < tuple.StringONR(slice[j])
+	})
+
 	if len(slice) > int(limit) {
-		return slice[0:limit]
+		return slice[0:limit], true
 	}
 
-	return slice
+	return slice, false
 }
 
 func lookupResultError(err error, subProblemMetadata *v1.ResponseMeta) LookupResult {
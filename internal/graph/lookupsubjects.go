@@ -0,0 +1,347 @@
+package graph
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"sync"
+
+	v1_proto "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// noLimit is the limit used for subproblems whose results must be complete, such as the branches
+// of an intersection or exclusion.
+const noLimit = math.MaxUint32
+
+// NewConcurrentLookupSubjects creates an instance of ConcurrentLookupSubjects, which runs its
+// subproblems concurrently within the bounds of the provided limiter.
+func NewConcurrentLookupSubjects(d dispatch.LookupSubjects, limiter *ConcurrencyLimiter) *ConcurrentLookupSubjects {
+	return &ConcurrentLookupSubjects{d: d, limiter: limiter}
+}
+
+// ConcurrentLookupSubjects exposes a method to perform LookupSubjects requests, and delegates
+// subproblems to the provided dispatch.LookupSubjects instance.
+type ConcurrentLookupSubjects struct {
+	d       dispatch.LookupSubjects
+	limiter *ConcurrencyLimiter
+}
+
+// ValidatedLookupSubjectsRequest represents a request after it has been validated and parsed for
+// internal consumption.
+type ValidatedLookupSubjectsRequest struct {
+	*v1.DispatchLookupSubjectsRequest
+	Revision decimal.Decimal
+}
+
+// subjectsResult is the set of subjects found by a single lookup or sub-lookup of subjects.
+type subjectsResult struct {
+	subjects *tuple.ONRSet
+	metadata *v1.ResponseMeta
+}
+
+// LookupSubjects performs a lookup subjects request with the provided request and context,
+// returning the subjects of the requested type which have the relation on the resource. Subjects
+// found via wildcard relationships are returned as the wildcard subject, and are not removed by
+// exclusions of specific subjects.
+func (cl *ConcurrentLookupSubjects) LookupSubjects(
+	ctx context.Context,
+	req ValidatedLookupSubjectsRequest,
+	relation *core.Relation,
+) (*v1.DispatchLookupSubjectsResponse, error) {
+	log.Ctx(ctx).Trace().Object("lookupSubjects", req).Send()
+
+	var result subjectsResult
+	var err error
+	if relation.UsersetRewrite == nil {
+		result, err = cl.lookupDirect(ctx, req, req.Limit)
+	} else {
+		result, err = cl.lookupUsersetRewrite(ctx, req, relation.UsersetRewrite, req.Limit)
+	}
+
+	metadata := addCallToResponseMetadata(ensureMetadata(result.metadata))
+	if err != nil {
+		return &v1.DispatchLookupSubjectsResponse{Metadata: metadata}, err
+	}
+
+	// The resource is itself a subject of the requested type, e.g. when looking up the members
+	// of a group which are themselves `group#member` usersets.
+	if req.ResourceAndRelation.Namespace == req.SubjectRelation.Namespace &&
+		req.ResourceAndRelation.Relation == req.SubjectRelation.Relation {
+		result.subjects.Add(req.ResourceAndRelation)
+	}
+
+	return &v1.DispatchLookupSubjectsResponse{
+		Metadata:      metadata,
+		FoundSubjects: limitedSubjects(result.subjects, req.Limit),
+	}, nil
+}
+
+func (cl *ConcurrentLookupSubjects) lookupDirect(
+	ctx context.Context,
+	req ValidatedLookupSubjectsRequest,
+	limit uint32,
+) (subjectsResult, error) {
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(req.Revision)
+	it, err := ds.QueryRelationships(ctx, &v1_proto.RelationshipFilter{
+		ResourceType:       req.ResourceAndRelation.Namespace,
+		OptionalResourceId: req.ResourceAndRelation.ObjectId,
+		OptionalRelation:   req.ResourceAndRelation.Relation,
+	})
+	if err != nil {
+		return emptySubjects(), NewLookupSubjectsFailureErr(err)
+	}
+	defer it.Close()
+
+	found := tuple.NewONRSet()
+	var toDispatch []*core.ObjectAndRelation
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		if tpl.Subject.Namespace == req.SubjectRelation.Namespace && tpl.Subject.Relation == req.SubjectRelation.Relation {
+			found.Add(tpl.Subject)
+			continue
+		}
+
+		if tpl.Subject.Relation != Ellipsis {
+			toDispatch = append(toDispatch, tpl.Subject)
+		}
+	}
+	if it.Err() != nil {
+		return emptySubjects(), NewLookupSubjectsFailureErr(it.Err())
+	}
+
+	result, err := cl.dispatchAll(ctx, req, toDispatch, limit)
+	result.subjects.UpdateFrom(found)
+	return result, err
+}
+
+func (cl *ConcurrentLookupSubjects) lookupUsersetRewrite(
+	ctx context.Context,
+	req ValidatedLookupSubjectsRequest,
+	usr *core.UsersetRewrite,
+	limit uint32,
+) (subjectsResult, error) {
+	switch rw := usr.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		return cl.lookupSetOperation(ctx, req, rw.Union, unionSubjects, limit)
+	case *core.UsersetRewrite_Intersection:
+		return cl.lookupSetOperation(ctx, req, rw.Intersection, intersectSubjects, noLimit)
+	case *core.UsersetRewrite_Exclusion:
+		return cl.lookupSetOperation(ctx, req, rw.Exclusion, subtractSubjects, noLimit)
+	default:
+		return emptySubjects(), NewAlwaysFailErr()
+	}
+}
+
+type subjectsReducer func(results []subjectsResult) *tuple.ONRSet
+
+func (cl *ConcurrentLookupSubjects) lookupSetOperation(
+	ctx context.Context,
+	req ValidatedLookupSubjectsRequest,
+	so *core.SetOperation,
+	reducer subjectsReducer,
+	limit uint32,
+) (subjectsResult, error) {
+	results := make([]subjectsResult, len(so.Child))
+	for i, childOneof := range so.Child {
+		var err error
+		switch child := childOneof.ChildType.(type) {
+		case *core.SetOperation_Child_XThis:
+			return emptySubjects(), errors.New("use of _this is unsupported; please rewrite your schema")
+		case *core.SetOperation_Child_ComputedUserset:
+			results[i], err = cl.lookupComputedUserset(ctx, req, req.ResourceAndRelation, child.ComputedUserset.Relation, limit)
+		case *core.SetOperation_Child_UsersetRewrite:
+			results[i], err = cl.lookupUsersetRewrite(ctx, req, child.UsersetRewrite, limit)
+		case *core.SetOperation_Child_TupleToUserset:
+			results[i], err = cl.lookupTupleToUserset(ctx, req, child.TupleToUserset, limit)
+		case *core.SetOperation_Child_XNil:
+			results[i] = emptySubjects()
+		default:
+			return emptySubjects(), fmt.Errorf("unknown set operation child `%T` in lookup subjects", child)
+		}
+
+		if err != nil {
+			return combineSubjectsMetadata(results[:i+1]), err
+		}
+	}
+
+	combined := combineSubjectsMetadata(results)
+	combined.subjects = reducer(results)
+	return combined, nil
+}
+
+func (cl *ConcurrentLookupSubjects) lookupComputedUserset(
+	ctx context.Context,
+	req ValidatedLookupSubjectsRequest,
+	start *core.ObjectAndRelation,
+	relation string,
+	limit uint32,
+) (subjectsResult, error) {
+	// Check if the target relation exists. If not, return nothing.
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(req.Revision)
+	err := namespace.CheckNamespaceAndRelation(ctx, start.Namespace, relation, true, ds)
+	if err != nil {
+		if errors.As(err, &namespace.ErrRelationNotFound{}) {
+			return emptySubjects(), nil
+		}
+
+		return emptySubjects(), err
+	}
+
+	return cl.dispatchAll(ctx, req, []*core.ObjectAndRelation{{
+		Namespace: start.Namespace,
+		ObjectId:  start.ObjectId,
+		Relation:  relation,
+	}}, limit)
+}
+
+func (cl *ConcurrentLookupSubjects) lookupTupleToUserset(
+	ctx context.Context,
+	req ValidatedLookupSubjectsRequest,
+	ttu *core.TupleToUserset,
+	limit uint32,
+) (subjectsResult, error) {
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(req.Revision)
+	it, err := ds.QueryRelationships(ctx, &v1_proto.RelationshipFilter{
+		ResourceType:       req.ResourceAndRelation.Namespace,
+		OptionalResourceId: req.ResourceAndRelation.ObjectId,
+		OptionalRelation:   ttu.Tupleset.Relation,
+	})
+	if err != nil {
+		return emptySubjects(), NewLookupSubjectsFailureErr(err)
+	}
+	defer it.Close()
+
+	var results []subjectsResult
+	for tpl := it.Next(); tpl != nil; tpl = it.Next() {
+		result, err := cl.lookupComputedUserset(ctx, req, tpl.Subject, ttu.ComputedUserset.Relation, limit)
+		results = append(results, result)
+		if err != nil {
+			return combineSubjectsMetadata(results), err
+		}
+	}
+	if it.Err() != nil {
+		return combineSubjectsMetadata(results), NewLookupSubjectsFailureErr(it.Err())
+	}
+
+	combined := combineSubjectsMetadata(results)
+	combined.subjects = unionSubjects(results)
+	return combined, nil
+}
+
+// dispatchAll concurrently dispatches a lookup of subjects for each of the given resources,
+// returning the union of the subjects found.
+func (cl *ConcurrentLookupSubjects) dispatchAll(
+	ctx context.Context,
+	req ValidatedLookupSubjectsRequest,
+	resources []*core.ObjectAndRelation,
+	limit uint32,
+) (subjectsResult, error) {
+	results := make([]subjectsResult, len(resources))
+	errs := make([]error, len(resources))
+
+	var wg sync.WaitGroup
+	for i, resource := range resources {
+		i, resource := i, resource
+		wg.Add(1)
+		cl.limiter.run(func() {
+			defer wg.Done()
+
+			resp, err := cl.d.DispatchLookupSubjects(ctx, &v1.DispatchLookupSubjectsRequest{
+				ResourceAndRelation: resource,
+				SubjectRelation:     req.SubjectRelation,
+				Metadata:            decrementDepth(req.Metadata),
+				Limit:               limit,
+			})
+
+			results[i] = emptySubjects()
+			if resp != nil {
+				results[i] = subjectsResult{tuple.NewONRSet(resp.FoundSubjects...), resp.Metadata}
+			}
+			errs[i] = err
+		})
+	}
+	wg.Wait()
+
+	combined := combineSubjectsMetadata(results)
+	for _, err := range errs {
+		if err != nil {
+			return combined, err
+		}
+	}
+
+	combined.subjects = unionSubjects(results)
+	return combined, nil
+}
+
+func emptySubjects() subjectsResult {
+	return subjectsResult{tuple.NewONRSet(), emptyMetadata}
+}
+
+// combineSubjectsMetadata returns an empty result with the combined metadata of all the results.
+func combineSubjectsMetadata(results []subjectsResult) subjectsResult {
+	metadata := emptyMetadata
+	for _, result := range results {
+		if result.metadata != nil {
+			metadata = combineResponseMetadata(metadata, result.metadata)
+		}
+	}
+	return subjectsResult{tuple.NewONRSet(), metadata}
+}
+
+func unionSubjects(results []subjectsResult) *tuple.ONRSet {
+	union := tuple.NewONRSet()
+	for _, result := range results {
+		if result.subjects != nil {
+			union.UpdateFrom(result.subjects)
+		}
+	}
+	return union
+}
+
+func intersectSubjects(results []subjectsResult) *tuple.ONRSet {
+	if len(results) == 0 {
+		return tuple.NewONRSet()
+	}
+
+	intersection := results[0].subjects
+	for _, result := range results[1:] {
+		intersection = intersection.Intersect(result.subjects)
+	}
+	return intersection
+}
+
+func subtractSubjects(results []subjectsResult) *tuple.ONRSet {
+	if len(results) == 0 {
+		return tuple.NewONRSet()
+	}
+
+	difference := results[0].subjects
+	for _, result := range results[1:] {
+		difference = difference.Subtract(result.subjects)
+	}
+	return difference
+}
+
+// limitedSubjects orders the subjects by their string form and returns at most limit of them.
+func limitedSubjects(subjects *tuple.ONRSet, limit uint32) []*core.ObjectAndRelation {
+	slice := subjects.AsSlice()
+	sort.Slice(slice, func(i, j int) bool {
+		return tuple.StringONR(slice[i]) < tuple.StringONR(slice[j])
+	})
+
+	if len(slice) > int(limit) {
+		return slice[0:limit]
+	}
+	return slice
+}
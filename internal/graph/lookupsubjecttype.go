@@ -0,0 +1,122 @@
+package graph
+
+import (
+	"context"
+	"sort"
+
+	v1_proto "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/rs/zerolog/log"
+	"github.com/shopspring/decimal"
+
+	"github.com/authzed/spicedb/internal/dispatch"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// NewConcurrentLookupForSubjectType creates an instance of ConcurrentLookupForSubjectType.
+func NewConcurrentLookupForSubjectType(d dispatch.Lookup) *ConcurrentLookupForSubjectType {
+	return &ConcurrentLookupForSubjectType{d: d}
+}
+
+// ConcurrentLookupForSubjectType exposes a method to perform lookups for any subject of a type,
+// and delegates the lookup for each subject to the provided dispatch.Lookup instance.
+type ConcurrentLookupForSubjectType struct {
+	d dispatch.Lookup
+}
+
+// ValidatedLookupForSubjectTypeRequest represents a request after it has been validated and
+// parsed for internal consumption.
+type ValidatedLookupForSubjectTypeRequest struct {
+	*v1.DispatchLookupForSubjectTypeRequest
+	Revision decimal.Decimal
+}
+
+// LookupForSubjectType performs a lookup for a subject type with the provided request and
+// context, returning the resources of the object relation accessible by each subject of the
+// type, at most the limit of the request per subject. Subjects which cannot access any resources
+// are omitted.
+//
+// The subjects are those of the type and relation which are the subject of any relationship, as
+// any other cannot have been granted access to a resource. The wildcard subject is not included.
+// Each subject is looked up by a separate dispatch, with the depth remaining after this one.
+func (cl *ConcurrentLookupForSubjectType) LookupForSubjectType(
+	ctx context.Context,
+	req ValidatedLookupForSubjectTypeRequest,
+) (*v1.DispatchLookupForSubjectTypeResponse, error) {
+	log.Ctx(ctx).Trace().Object("lookupForSubjectType", req).Send()
+
+	subjects, err := subjectsOfType(ctx, req)
+	if err != nil {
+		return &v1.DispatchLookupForSubjectTypeResponse{Metadata: addCallToResponseMetadata(emptyMetadata)}, err
+	}
+
+	metadata := emptyMetadata
+	results := make([]*v1.SubjectLookupResult, 0, len(subjects))
+	for _, subject := range subjects {
+		result, err := cl.d.DispatchLookup(ctx, &v1.DispatchLookupRequest{
+			ObjectRelation: req.ObjectRelation,
+			Subject:        subject,
+			Metadata:       decrementDepth(req.Metadata),
+			Limit:          req.Limit,
+		})
+		metadata = combineResponseMetadata(metadata, ensureMetadata(result.GetMetadata()))
+		if err != nil {
+			return &v1.DispatchLookupForSubjectTypeResponse{Metadata: addCallToResponseMetadata(metadata)}, err
+		}
+
+		if len(result.ResolvedOnrs) > 0 {
+			results = append(results, &v1.SubjectLookupResult{
+				Subject:      subject,
+				ResolvedOnrs: result.ResolvedOnrs,
+			})
+		}
+	}
+
+	return &v1.DispatchLookupForSubjectTypeResponse{
+		Metadata: addCallToResponseMetadata(metadata),
+		Results:  results,
+	}, nil
+}
+
+// subjectsOfType returns the distinct subjects of the subject type and relation found in the
+// relationships at the revision of the request, ordered by their string form. The subject type
+// and relation must be defined, rather than simply having no subjects.
+func subjectsOfType(ctx context.Context, req ValidatedLookupForSubjectTypeRequest) ([]*core.ObjectAndRelation, error) {
+	reader := namespace.NewValidatingReader(datastoremw.MustFromContext(ctx).SnapshotReader(req.Revision))
+	iter, err := reader.ReverseQueryRelationships(ctx, &v1_proto.SubjectFilter{
+		SubjectType: req.SubjectType.Namespace,
+		OptionalRelation: &v1_proto.SubjectFilter_RelationFilter{
+			Relation: req.SubjectType.Relation,
+		},
+	})
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	subjects := make(map[string]*core.ObjectAndRelation)
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		if tpl.Subject.ObjectId == tuple.PublicWildcard {
+			continue
+		}
+		subjects[tuple.StringONR(tpl.Subject)] = tpl.Subject
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(subjects))
+	for key := range subjects {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	ordered := make([]*core.ObjectAndRelation, 0, len(keys))
+	for _, key := range keys {
+		ordered = append(ordered, subjects[key])
+	}
+	return ordered, nil
+}
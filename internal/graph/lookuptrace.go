@@ -0,0 +1,131 @@
+package graph
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	v1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// lookupTracer collects the debug trace of a lookup which requested one. Each resource reached
+// while performing the lookup becomes a sub problem of the trace, whether it was found directly
+// by reachability or had to be checked.
+type lookupTracer struct {
+	relation  *core.RelationReference
+	operation v1.LookupDebugTrace_Operation
+
+	subProblems map[string]*v1.LookupDebugTrace
+	mu          sync.Mutex
+}
+
+func newLookupTracer(ctx context.Context, req ValidatedLookupRequest) (*lookupTracer, error) {
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(req.Revision)
+	_, relation, err := namespace.ReadNamespaceAndRelation(ctx, req.ObjectRelation.Namespace, req.ObjectRelation.Relation, ds)
+	if err != nil {
+		return nil, err
+	}
+
+	return &lookupTracer{
+		relation:    req.ObjectRelation,
+		operation:   rewriteOperation(relation),
+		subProblems: make(map[string]*v1.LookupDebugTrace),
+	}, nil
+}
+
+func rewriteOperation(relation *core.Relation) v1.LookupDebugTrace_Operation {
+	if relation.UsersetRewrite == nil {
+		return v1.LookupDebugTrace_DIRECT
+	}
+
+	switch relation.UsersetRewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Intersection:
+		return v1.LookupDebugTrace_INTERSECTION
+	case *core.UsersetRewrite_Exclusion:
+		return v1.LookupDebugTrace_EXCLUSION
+	default:
+		return v1.LookupDebugTrace_UNION
+	}
+}
+
+// isCachedResult returns whether the response with the given metadata was loaded from the
+// dispatch cache, which reports cached responses as having required no dispatches.
+func isCachedResult(metadata *v1.ResponseMeta) bool {
+	return metadata != nil && metadata.DispatchCount == 0 && metadata.CachedDispatchCount > 0
+}
+
+// reached records a resource found by reachability.
+func (lt *lookupTracer) reached(result *v1.DispatchReachableResourcesResponse) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	subProblem := lt.subProblemUnsafe(result.Resource.Resource, isCachedResult(result.Metadata))
+	if result.Resource.ResultStatus == v1.ReachableResource_HAS_PERMISSION {
+		subProblem.ContributedOnrs = []*core.ObjectAndRelation{result.Resource.Resource}
+	} else {
+		subProblem.RequiredCheck = true
+	}
+}
+
+// checked records the result of checking a resource found by reachability.
+func (lt *lookupTracer) checked(req *v1.DispatchCheckRequest, res *v1.DispatchCheckResponse) {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	subProblem := lt.subProblemUnsafe(req.ResourceAndRelation, isCachedResult(res.Metadata))
+	subProblem.RequiredCheck = true
+	if res.Membership == v1.DispatchCheckResponse_MEMBER {
+		subProblem.ContributedOnrs = []*core.ObjectAndRelation{req.ResourceAndRelation}
+	}
+}
+
+// subProblemUnsafe returns the sub problem for the resource, creating it if necessary. A resource
+// reached several times is only reported as cached if all of its results were.
+func (lt *lookupTracer) subProblemUnsafe(resource *core.ObjectAndRelation, cached bool) *v1.LookupDebugTrace {
+	key := tuple.StringONR(resource)
+	if subProblem, ok := lt.subProblems[key]; ok {
+		subProblem.IsCachedResult = subProblem.IsCachedResult && cached
+		return subProblem
+	}
+
+	subProblem := &v1.LookupDebugTrace{
+		Relation: &core.RelationReference{
+			Namespace: resource.Namespace,
+			Relation:  resource.Relation,
+		},
+		Resource:       resource,
+		Operation:      lt.operation,
+		IsCachedResult: cached,
+	}
+	lt.subProblems[key] = subProblem
+	return subProblem
+}
+
+// trace returns the debug trace of the lookup, which returned the found resources.
+func (lt *lookupTracer) trace(found []*core.ObjectAndRelation) *v1.LookupDebugTrace {
+	lt.mu.Lock()
+	defer lt.mu.Unlock()
+
+	keys := make([]string, 0, len(lt.subProblems))
+	for key := range lt.subProblems {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	subProblems := make([]*v1.LookupDebugTrace, 0, len(keys))
+	for _, key := range keys {
+		subProblems = append(subProblems, lt.subProblems[key])
+	}
+
+	return &v1.LookupDebugTrace{
+		Relation:        lt.relation,
+		Operation:       lt.operation,
+		ContributedOnrs: found,
+		SubProblems:     subProblems,
+	}
+}
@@ -31,6 +31,8 @@ type ParallelChecker struct {
 	cachedDispatchCount uint32
 	depthRequired       uint32
 
+	onChecked func(req *v1.DispatchCheckRequest, res *v1.DispatchCheckResponse)
+
 	mu sync.Mutex
 }
 
@@ -38,7 +40,13 @@ type ParallelChecker struct {
 func NewParallelChecker(ctx context.Context, c dispatch.Check, subject *core.ObjectAndRelation, maxConcurrent uint8) *ParallelChecker {
 	g, checkCtx := errgroup.WithContext(ctx)
 	toCheck := make(chan *v1.DispatchCheckRequest)
-	return &ParallelChecker{toCheck, tuple.NewONRSet(), c, g, checkCtx, subject, maxConcurrent, tuple.NewONRSet(), 0, 0, 0, sync.Mutex{}}
+	return &ParallelChecker{toCheck, tuple.NewONRSet(), c, g, checkCtx, subject, maxConcurrent, tuple.NewONRSet(), 0, 0, 0, nil, sync.Mutex{}}
+}
+
+// OnChecked registers a function to be invoked with each completed check. It must be called
+// before Start, and the function is invoked while the checker's lock is held.
+func (pc *ParallelChecker) OnChecked(onChecked func(req *v1.DispatchCheckRequest, res *v1.DispatchCheckResponse)) {
+	pc.onChecked = onChecked
 }
 
 // AddResult adds a result that has been already checked to the set.
@@ -73,7 +81,8 @@ func (pc *ParallelChecker) updateStatsUnsafe(metadata *v1.ResponseMeta) {
 	pc.depthRequired = max(pc.depthRequired, metadata.DepthRequired)
 }
 
-// QueueCheck queues a resource to be checked.
+// QueueCheck queues a resource to be checked. Once the checker's context is done, the resource
+// is dropped, as no further checks will be started.
 func (pc *ParallelChecker) QueueCheck(resource *core.ObjectAndRelation, meta *v1.ResolverMeta) {
 	queue := func() bool {
 		pc.mu.Lock()
@@ -84,10 +93,13 @@ func (pc *ParallelChecker) QueueCheck(resource *core.ObjectAndRelation, meta *v1
 		return
 	}
 
-	pc.toCheck <- &v1.DispatchCheckRequest{
+	select {
+	case pc.toCheck <- &v1.DispatchCheckRequest{
 		Metadata:            meta,
 		ResourceAndRelation: resource,
 		Subject:             pc.subject,
+	}:
+	case <-pc.checkCtx.Done():
 	}
 }
 
@@ -119,6 +131,9 @@ func (pc *ParallelChecker) Start() {
 						pc.addResultsUnsafe(req.ResourceAndRelation)
 					}
 					pc.updateStatsUnsafe(res.Metadata)
+					if pc.onChecked != nil {
+						pc.onChecked(req, res)
+					}
 				}()
 				return nil
 			})
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"sync"
 
+	"github.com/rs/zerolog/log"
 	"github.com/scylladb/go-set/strset"
 	"github.com/shopspring/decimal"
 	"golang.org/x/sync/errgroup"
@@ -19,15 +20,23 @@ import (
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
+// maxConcurrentLimitedDispatches is the maximum number of subproblems run concurrently by a
+// limited reachable resources request.
+const maxConcurrentLimitedDispatches = 10
+
 // NewConcurrentReachableResources creates an instance of ConcurrentReachableResources.
 func NewConcurrentReachableResources(d dispatch.ReachableResources) *ConcurrentReachableResources {
-	return &ConcurrentReachableResources{d: d}
+	return &ConcurrentReachableResources{d: d, typeSystems: namespace.NewDefaultTypeSystemCache()}
 }
 
 // ConcurrentReachableResources exposes a method to perform ReachableResources requests, and
 // delegates subproblems to the provided dispatch.ReachableResources instance.
 type ConcurrentReachableResources struct {
 	d dispatch.ReachableResources
+
+	// typeSystems is shared by all requests, since every dispatched subproblem at a revision
+	// reads the type systems of the same namespaces.
+	typeSystems *namespace.TypeSystemCache
 }
 
 // ValidatedReachableResourcesRequest represents a request after it has been validated and parsed for internal
@@ -50,8 +59,38 @@ func (s *syncONRSet) Add(onr *core.ObjectAndRelation) bool {
 func (crr *ConcurrentReachableResources) ReachableResources(
 	req ValidatedReachableResourcesRequest,
 	stream dispatch.ReachableResourcesStream,
+) error {
+	if req.Limit == 0 {
+		return crr.reachableResources(req, stream)
+	}
+
+	// A limited request stops its traversal, including that of every subproblem dispatched, by
+	// cancelling it once enough resources have been reached.
+	ctx, cancel := context.WithCancel(stream.Context())
+	defer cancel()
+
+	limited := &limitedReachableResourcesStream{
+		stream:  stream,
+		ctx:     ctx,
+		cancel:  cancel,
+		limit:   req.Limit,
+		reached: tuple.NewONRSet(),
+	}
+
+	err := crr.reachableResources(req, limited)
+	if limited.limitReached() {
+		return nil
+	}
+	return err
+}
+
+func (crr *ConcurrentReachableResources) reachableResources(
+	req ValidatedReachableResourcesRequest,
+	stream dispatch.ReachableResourcesStream,
 ) error {
 	ctx := stream.Context()
+	log.Ctx(ctx).Trace().Object("reachableResources", req).Send()
+
 	dispatched := &syncONRSet{}
 
 	// If the resource type matches the subject type, yield directly.
@@ -72,7 +111,7 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 	// Load the type system and reachability graph to find the entrypoints for the reachability.
 	ds := datastoremw.MustFromContext(ctx)
 	reader := ds.SnapshotReader(req.Revision)
-	_, typeSystem, err := namespace.ReadNamespaceAndTypes(ctx, req.ObjectRelation.Namespace, reader)
+	_, typeSystem, err := crr.typeSystems.ReadNamespaceAndTypes(ctx, req.ObjectRelation.Namespace, req.Revision, reader)
 	if err != nil {
 		return err
 	}
@@ -90,6 +129,9 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 	defer checkCancel()
 
 	g, subCtx := errgroup.WithContext(cancelCtx)
+	if req.Limit > 0 {
+		g.SetLimit(maxConcurrentLimitedDispatches)
+	}
 
 	// For each entrypoint, load the necessary data and re-dispatch if a subproblem was found.
 	for _, entrypoint := range entrypoints {
@@ -116,7 +158,7 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 		case core.ReachabilityEntrypoint_TUPLESET_TO_USERSET_ENTRYPOINT:
 			containingRelation := entrypoint.ContainingRelationOrPermission()
 
-			_, ttuTypeSystem, err := namespace.ReadNamespaceAndTypes(ctx, containingRelation.Namespace, reader)
+			_, ttuTypeSystem, err := crr.typeSystems.ReadNamespaceAndTypes(ctx, containingRelation.Namespace, req.Revision, reader)
 			if err != nil {
 				return err
 			}
@@ -178,7 +220,13 @@ func (crr *ConcurrentReachableResources) ReachableResources(
 		}
 	}
 
-	return g.Wait()
+	if err := g.Wait(); err != nil {
+		return err
+	}
+
+	// A traversal cancelled before it completed may have skipped resources, so it must not be
+	// reported, and cached, as having succeeded.
+	return ctx.Err()
 }
 
 func (crr *ConcurrentReachableResources) lookupRelationEntrypoint(ctx context.Context,
@@ -191,7 +239,7 @@ func (crr *ConcurrentReachableResources) lookupRelationEntrypoint(ctx context.Co
 	dispatched *syncONRSet,
 ) error {
 	relationReference := entrypoint.DirectRelation()
-	_, relTypeSystem, err := namespace.ReadNamespaceAndTypes(ctx, relationReference.Namespace, reader)
+	_, relTypeSystem, err := crr.typeSystems.ReadNamespaceAndTypes(ctx, relationReference.Namespace, req.Revision, reader)
 	if err != nil {
 		return err
 	}
@@ -273,6 +321,12 @@ func (crr *ConcurrentReachableResources) redispatchOrReport(
 		return nil
 	}
 
+	// Stop once the traversal has been cancelled, such as when its limit has been reached. Any
+	// error which caused the cancellation is returned when waiting on the group.
+	if ctx.Err() != nil {
+		return nil
+	}
+
 	// Check for entrypoints for the new found resource type.
 	hasResourceEntrypoints, err := rg.HasOptimizedEntrypointsForSubjectToResource(ctx, &core.RelationReference{
 		Namespace: foundResource.Namespace,
@@ -306,7 +360,7 @@ func (crr *ConcurrentReachableResources) redispatchOrReport(
 	}
 
 	// Otherwise, redispatch.
-	g.Go(func() error {
+	redispatch := func() error {
 		stream := &dispatch.WrappedDispatchStream[*v1.DispatchReachableResourcesResponse]{
 			Stream: parentStream,
 			Ctx:    ctx,
@@ -328,14 +382,76 @@ func (crr *ConcurrentReachableResources) redispatchOrReport(
 			},
 		}
 
+		// The subproblem is given the whole limit, rather than what remains of it, as the
+		// resources it reaches may have already been reached by another. If its results all
+		// require a check, none of them count towards the limit, so it is not limited at all.
+		limit := parentRequest.Limit
+		if !entrypoint.IsDirectResult() {
+			limit = 0
+		}
+
 		return crr.d.DispatchReachableResources(&v1.DispatchReachableResourcesRequest{
 			ObjectRelation: parentRequest.ObjectRelation,
 			Subject:        foundResource,
 			Metadata: &v1.ResolverMeta{
 				AtRevision:     parentRequest.Revision.String(),
 				DepthRemaining: parentRequest.Metadata.DepthRemaining - 1,
+				CorrelationId:  parentRequest.Metadata.CorrelationId,
 			},
+			Limit: limit,
 		}, stream)
-	})
+	}
+
+	// A limited request bounds the subproblems run concurrently, running any beyond the bound
+	// in the calling goroutine, so that it does not dispatch far more than it needs before its
+	// limit is reached.
+	if !g.TryGo(redispatch) {
+		return redispatch()
+	}
 	return nil
 }
+
+// limitedReachableResourcesStream publishes to the stream of a limited request, cancelling the
+// request's traversal once the limit of distinct resources with permission have been published.
+// Anything published afterwards is dropped, as the caller needs no more.
+type limitedReachableResourcesStream struct {
+	stream dispatch.ReachableResourcesStream
+	ctx    context.Context
+	cancel context.CancelFunc
+
+	limit   uint32
+	reached *tuple.ONRSet
+
+	mu sync.Mutex
+}
+
+func (ls *limitedReachableResourcesStream) Context() context.Context {
+	return ls.ctx
+}
+
+func (ls *limitedReachableResourcesStream) Publish(result *v1.DispatchReachableResourcesResponse) error {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+
+	if ls.reached.Length() >= ls.limit {
+		return nil
+	}
+
+	if err := ls.stream.Publish(result); err != nil {
+		return err
+	}
+
+	if result.Resource.ResultStatus == v1.ReachableResource_HAS_PERMISSION {
+		ls.reached.Add(result.Resource.Resource)
+		if ls.reached.Length() >= ls.limit {
+			ls.cancel()
+		}
+	}
+	return nil
+}
+
+func (ls *limitedReachableResourcesStream) limitReached() bool {
+	ls.mu.Lock()
+	defer ls.mu.Unlock()
+	return ls.reached.Length() >= ls.limit
+}
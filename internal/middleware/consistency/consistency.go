@@ -228,8 +228,12 @@ func pickBestRevision(ctx context.Context, requested *v1.ZedToken, ds datastore.
 }
 
 func rewriteDatastoreError(ctx context.Context, err error) error {
+	var invalidRevision datastore.ErrInvalidRevision
 	switch {
-	case errors.As(err, &datastore.ErrInvalidRevision{}):
+	case errors.As(err, &invalidRevision) && invalidRevision.Reason() == datastore.RevisionInFuture:
+		return status.Errorf(codes.InvalidArgument, "invalid revision: %s", err)
+
+	case errors.As(err, &invalidRevision):
 		return status.Errorf(codes.OutOfRange, "invalid revision: %s", err)
 
 	case errors.As(err, &datastore.ErrReadOnly{}):
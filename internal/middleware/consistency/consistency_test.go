@@ -14,9 +14,12 @@ import (
 	"github.com/stretchr/testify/require"
 	"github.com/stretchr/testify/suite"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/authzed/spicedb/internal/datastore/proxy/proxy_test"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
@@ -134,6 +137,35 @@ func TestAddRevisionToContextAtInvalidExactSnapshot(t *testing.T) {
 	ds.AssertExpectations(t)
 }
 
+func TestAddRevisionToContextAtUnavailableExactSnapshot(t *testing.T) {
+	for _, tc := range []struct {
+		name         string
+		reason       datastore.InvalidRevisionReason
+		expectedCode codes.Code
+	}{
+		{"stale", datastore.RevisionStale, codes.OutOfRange},
+		{"future", datastore.RevisionInFuture, codes.InvalidArgument},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ds := &proxy_test.MockDatastore{}
+			ds.On("CheckRevision", exact).Return(datastore.NewInvalidRevisionErr(exact, tc.reason)).Times(1)
+
+			updated := ContextWithHandle(context.Background())
+			err := AddRevisionToContext(updated, &v1.ReadRelationshipsRequest{
+				Consistency: &v1.Consistency{
+					Requirement: &v1.Consistency_AtExactSnapshot{
+						AtExactSnapshot: zedtoken.NewFromRevision(exact),
+					},
+				},
+			}, ds)
+			require.Equal(tc.expectedCode, status.Code(err))
+			ds.AssertExpectations(t)
+		})
+	}
+}
+
 func TestAddRevisionToContextAPIAlwaysFullyConsistent(t *testing.T) {
 	require := require.New(t)
 
@@ -0,0 +1,69 @@
+package validation
+
+import (
+	"context"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// allValidator is implemented by generated messages which can report every violated
+// constraint, rather than only the first.
+type allValidator interface {
+	ValidateAll() error
+}
+
+type validator interface {
+	Validate() error
+}
+
+// NewValidationInterceptor returns a new unary server interceptor that validates the incoming
+// request against the constraints declared on its proto definition, rejecting it with
+// codes.InvalidArgument before the handler is invoked. Unlike the upstream validator middleware,
+// every violated constraint is reported, not only the first.
+func NewValidationInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if err := validate(req); err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// NewStreamValidationInterceptor returns a new stream server interceptor that validates each
+// incoming message against the constraints declared on its proto definition, like
+// NewValidationInterceptor.
+func NewStreamValidationInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return handler(srv, &recvWrapper{stream})
+	}
+}
+
+type recvWrapper struct {
+	grpc.ServerStream
+}
+
+func (s *recvWrapper) RecvMsg(m interface{}) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+
+	return validate(m)
+}
+
+func validate(m interface{}) error {
+	var err error
+	switch v := m.(type) {
+	case allValidator:
+		err = v.ValidateAll()
+	case validator:
+		err = v.Validate()
+	}
+
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,182 @@
+package validation
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/authzed-go/proto/authzed/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestValidationInterceptor(t *testing.T) {
+	validMeta := &dispatchv1.ResolverMeta{AtRevision: "1234", DepthRemaining: 50}
+
+	testCases := []struct {
+		name      string
+		req       interface{}
+		expectErr bool
+	}{
+		{
+			"valid check",
+			&dispatchv1.DispatchCheckRequest{
+				Metadata:            validMeta,
+				ResourceAndRelation: tuple.ParseONR("document:masterplan#view"),
+				Subject:             tuple.ParseSubjectONR("user:eng_lead"),
+			},
+			false,
+		},
+		{
+			"check missing subject",
+			&dispatchv1.DispatchCheckRequest{
+				Metadata:            validMeta,
+				ResourceAndRelation: tuple.ParseONR("document:masterplan#view"),
+			},
+			true,
+		},
+		{
+			"check missing metadata",
+			&dispatchv1.DispatchCheckRequest{
+				ResourceAndRelation: tuple.ParseONR("document:masterplan#view"),
+				Subject:             tuple.ParseSubjectONR("user:eng_lead"),
+			},
+			true,
+		},
+		{
+			"check with zero depth",
+			&dispatchv1.DispatchCheckRequest{
+				Metadata:            &dispatchv1.ResolverMeta{AtRevision: "1234"},
+				ResourceAndRelation: tuple.ParseONR("document:masterplan#view"),
+				Subject:             tuple.ParseSubjectONR("user:eng_lead"),
+			},
+			true,
+		},
+		{
+			"lookup with empty namespace",
+			&dispatchv1.DispatchLookupRequest{
+				Metadata:       validMeta,
+				ObjectRelation: &core.RelationReference{Relation: "view"},
+				Subject:        tuple.ParseSubjectONR("user:eng_lead"),
+				Limit:          10,
+			},
+			true,
+		},
+		{
+			"valid write schema",
+			&v1alpha1.WriteSchemaRequest{Schema: "definition user {}"},
+			false,
+		},
+		{
+			"write schema over the maximum size",
+			&v1alpha1.WriteSchemaRequest{Schema: strings.Repeat("a", 262145)},
+			true,
+		},
+		{
+			"valid write relationships",
+			&v1.WriteRelationshipsRequest{
+				Updates: []*v1.RelationshipUpdate{{
+					Operation: v1.RelationshipUpdate_OPERATION_TOUCH,
+					Relationship: &v1.Relationship{
+						Resource: &v1.ObjectReference{ObjectType: "document", ObjectId: "masterplan"},
+						Relation: "viewer",
+						Subject:  &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "user", ObjectId: "eng_lead"}},
+					},
+				}},
+			},
+			false,
+		},
+		{
+			"write relationships missing relationship",
+			&v1.WriteRelationshipsRequest{
+				Updates: []*v1.RelationshipUpdate{{Operation: v1.RelationshipUpdate_OPERATION_TOUCH}},
+			},
+			true,
+		},
+		{
+			"delete namespace with empty name",
+			&schemav1alpha1.DeleteNamespaceRequest{},
+			true,
+		},
+		{
+			"rename namespace with empty new name",
+			&schemav1alpha1.RenameNamespaceRequest{OldName: "example/document"},
+			true,
+		},
+		{
+			"non-validating message",
+			"not a proto",
+			false,
+		},
+	}
+
+	interceptor := NewValidationInterceptor()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			called := false
+			handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+				called = true
+				return req, nil
+			}
+
+			_, err := interceptor(context.Background(), tc.req, &grpc.UnaryServerInfo{}, handler)
+			if tc.expectErr {
+				require.Error(err)
+				require.Equal(codes.InvalidArgument, status.Code(err))
+				require.False(called)
+			} else {
+				require.NoError(err)
+				require.True(called)
+			}
+		})
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	msg proto.Message
+}
+
+func (s *fakeServerStream) RecvMsg(m interface{}) error {
+	proto.Merge(m.(proto.Message), s.msg)
+	return nil
+}
+
+func TestStreamValidationInterceptor(t *testing.T) {
+	testCases := []struct {
+		name      string
+		msg       proto.Message
+		expectErr bool
+	}{
+		{"valid message", &schemav1alpha1.DeleteNamespaceRequest{Name: "example/document"}, false},
+		{"invalid message", &schemav1alpha1.DeleteNamespaceRequest{}, true},
+	}
+
+	interceptor := NewStreamValidationInterceptor()
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			err := interceptor(nil, &fakeServerStream{msg: tc.msg}, &grpc.StreamServerInfo{}, func(srv interface{}, stream grpc.ServerStream) error {
+				return stream.RecvMsg(&schemav1alpha1.DeleteNamespaceRequest{})
+			})
+			if tc.expectErr {
+				require.Error(err)
+				require.Equal(codes.InvalidArgument, status.Code(err))
+			} else {
+				require.NoError(err)
+			}
+		})
+	}
+}
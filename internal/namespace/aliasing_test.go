@@ -201,9 +201,14 @@ func TestAliasing(t *testing.T) {
 			ts, err := BuildNamespaceTypeSystemForDatastore(tc.toCheck, ds.SnapshotReader(lastRevision))
 			require.NoError(err)
 
-			ctx := context.Background()
-			vts, terr := ts.Validate(ctx)
-			require.NoError(terr)
+			// Cyclic permissions are rejected by validation, so they are marked as validated
+			// directly, to test that computing aliases also detects the cycle.
+			vts := ts.AsValidated()
+			if tc.expectedError == "" {
+				var terr error
+				vts, terr = ts.Validate(context.Background())
+				require.NoError(terr)
+			}
 
 			computed, aerr := computePermissionAliases(vts)
 			if tc.expectedError != "" {
@@ -38,4 +38,120 @@ func NewRelationNotFoundErr(nsName string, relationName string) error {
 	}
 }
 
+// newRelationNotFoundErrf constructs a new relation not found error with a message describing
+// where the relation was referenced.
+func newRelationNotFoundErrf(nsName string, relationName string, format string, args ...interface{}) error {
+	return ErrRelationNotFound{
+		error:         fmt.Errorf(format, args...),
+		namespaceName: nsName,
+		relationName:  relationName,
+	}
+}
+
+// ErrPermissionNotWritable occurs when a relationship was written to a permission rather than to
+// a relation.
+type ErrPermissionNotWritable struct {
+	error
+	namespaceName  string
+	permissionName string
+}
+
+// NamespaceName returns the name of the namespace defining the permission.
+func (epw ErrPermissionNotWritable) NamespaceName() string {
+	return epw.namespaceName
+}
+
+// PermissionName returns the name of the permission.
+func (epw ErrPermissionNotWritable) PermissionName() string {
+	return epw.permissionName
+}
+
+func (epw ErrPermissionNotWritable) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("error", epw.Error()).Str("namespace", epw.namespaceName).Str("permission", epw.permissionName)
+}
+
+// NewPermissionNotWritableErr constructs a new permission not writable error.
+func NewPermissionNotWritableErr(nsName string, permissionName string) error {
+	return ErrPermissionNotWritable{
+		error:          fmt.Errorf("cannot write a relationship to permission `%s` under definition `%s`", permissionName, nsName),
+		namespaceName:  nsName,
+		permissionName: permissionName,
+	}
+}
+
+// ErrSubjectNotAllowed occurs when the subject of a relationship is not among the subject types
+// allowed on its relation.
+type ErrSubjectNotAllowed struct {
+	error
+	namespaceName   string
+	relationName    string
+	subjectType     string
+	subjectRelation string
+	wildcard        bool
+}
+
+// NamespaceName returns the name of the namespace defining the relation.
+func (esa ErrSubjectNotAllowed) NamespaceName() string {
+	return esa.namespaceName
+}
+
+// RelationName returns the name of the relation on which the subject is not allowed.
+func (esa ErrSubjectNotAllowed) RelationName() string {
+	return esa.relationName
+}
+
+// SubjectType returns the type of the subject.
+func (esa ErrSubjectNotAllowed) SubjectType() string {
+	return esa.subjectType
+}
+
+// SubjectRelation returns the relation of the subject, which is empty for a wildcard.
+func (esa ErrSubjectNotAllowed) SubjectRelation() string {
+	return esa.subjectRelation
+}
+
+// IsWildcard returns whether the subject is the wildcard of its type.
+func (esa ErrSubjectNotAllowed) IsWildcard() bool {
+	return esa.wildcard
+}
+
+func (esa ErrSubjectNotAllowed) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("error", esa.Error()).
+		Str("namespace", esa.namespaceName).
+		Str("relation", esa.relationName).
+		Str("subjectType", esa.subjectType).
+		Str("subjectRelation", esa.subjectRelation).
+		Bool("wildcard", esa.wildcard)
+}
+
+// NewSubjectNotAllowedErr constructs a new error for a subject type and relation not allowed on
+// a relation.
+func NewSubjectNotAllowedErr(nsName string, relationName string, subjectType string, subjectRelation string) error {
+	return ErrSubjectNotAllowed{
+		error: fmt.Errorf(
+			"subjects of type `%s#%s` are not allowed on relation `%s` under definition `%s`",
+			subjectType, subjectRelation, relationName, nsName,
+		),
+		namespaceName:   nsName,
+		relationName:    relationName,
+		subjectType:     subjectType,
+		subjectRelation: subjectRelation,
+	}
+}
+
+// NewWildcardNotAllowedErr constructs a new error for a wildcard subject not allowed on a
+// relation.
+func NewWildcardNotAllowedErr(nsName string, relationName string, subjectType string) error {
+	return ErrSubjectNotAllowed{
+		error: fmt.Errorf(
+			"wildcard subjects of type `%s` are not allowed on relation `%s` under definition `%s`",
+			subjectType, relationName, nsName,
+		),
+		namespaceName: nsName,
+		relationName:  relationName,
+		subjectType:   subjectType,
+		wildcard:      true,
+	}
+}
+
 var _ sharederrors.UnknownRelationError = ErrRelationNotFound{}
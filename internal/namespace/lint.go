@@ -0,0 +1,392 @@
+package namespace
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/authzed/spicedb/pkg/commonerrors"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// LintWarningKind is the kind of anti-pattern reported by a lint warning.
+type LintWarningKind string
+
+const (
+	// LintUnusedRelation warns of a relation which is not used by any permission, nor as the
+	// subject relation of any relation.
+	LintUnusedRelation LintWarningKind = "unused-relation"
+
+	// LintUnreachablePermission warns of a permission which can never have any subjects.
+	LintUnreachablePermission LintWarningKind = "unreachable-permission"
+
+	// LintWildcardPermission warns of a relation allowing a wildcard, through which permissions
+	// are granted to every subject of a type.
+	LintWildcardPermission LintWarningKind = "wildcard-permission"
+)
+
+// LintWarning is a warning about an anti-pattern in a schema, which does not prevent the schema
+// from being written. The warning is positioned at the offending element of the source schema,
+// if known.
+type LintWarning struct {
+	*commonerrors.ErrorWithSource
+
+	// Kind is the kind of anti-pattern reported.
+	Kind LintWarningKind
+
+	// Relation is the relation or permission the warning is about.
+	Relation *core.RelationReference
+}
+
+// LintNamespaces returns the warnings for anti-patterns in the namespace definitions, which must
+// have been validated. The other definitions are those of the rest of the schema, which the
+// linted definitions may reference or be referenced by.
+func LintNamespaces(nsdefs []*core.NamespaceDefinition, otherDefs []*core.NamespaceDefinition) []*LintWarning {
+	l := newLinter(nsdefs, otherDefs)
+
+	var warnings []*LintWarning
+	for _, nsdef := range nsdefs {
+		for _, relation := range nsdef.Relation {
+			warnings = append(warnings, l.lintRelation(nsdef, relation)...)
+		}
+	}
+	return warnings
+}
+
+type linter struct {
+	defs map[string]*core.NamespaceDefinition
+
+	// used holds the keys of the relations used by a permission or as a subject relation.
+	used map[string]struct{}
+
+	// resolvable holds the keys of the relations and permissions which can have subjects.
+	resolvable map[string]struct{}
+}
+
+func newLinter(nsdefs []*core.NamespaceDefinition, otherDefs []*core.NamespaceDefinition) *linter {
+	l := &linter{
+		defs:       make(map[string]*core.NamespaceDefinition, len(nsdefs)+len(otherDefs)),
+		used:       make(map[string]struct{}),
+		resolvable: make(map[string]struct{}),
+	}
+
+	for _, nsdef := range otherDefs {
+		l.defs[nsdef.Name] = nsdef
+	}
+	for _, nsdef := range nsdefs {
+		l.defs[nsdef.Name] = nsdef
+	}
+
+	for _, nsdef := range l.defs {
+		for _, relation := range nsdef.Relation {
+			l.markUsed(nsdef, relation)
+		}
+	}
+
+	l.computeResolvable()
+	return l
+}
+
+func (l *linter) lintRelation(nsdef *core.NamespaceDefinition, relation *core.Relation) []*LintWarning {
+	key := relationKey(nsdef.Name, relation.Name)
+
+	if relation.UsersetRewrite != nil {
+		if _, ok := l.resolvable[key]; !ok {
+			return []*LintWarning{newLintWarning(
+				LintUnreachablePermission,
+				nsdef.Name,
+				relation,
+				relation,
+				"permission `%s` under definition `%s` can never have any subjects",
+				relation.Name,
+				nsdef.Name,
+			)}
+		}
+		return nil
+	}
+
+	var warnings []*LintWarning
+	if _, ok := l.used[key]; !ok {
+		warnings = append(warnings, newLintWarning(
+			LintUnusedRelation,
+			nsdef.Name,
+			relation,
+			relation,
+			"relation `%s` under definition `%s` is not used by any permission",
+			relation.Name,
+			nsdef.Name,
+		))
+	}
+
+	for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		if allowed.GetPublicWildcard() == nil {
+			continue
+		}
+
+		permissions := l.permissionsGrantedBy(nsdef, relation.Name)
+		if len(permissions) == 0 {
+			continue
+		}
+
+		warnings = append(warnings, newLintWarning(
+			LintWildcardPermission,
+			nsdef.Name,
+			relation,
+			allowed,
+			"relation `%s` under definition `%s` allows `%s:*`, which grants every `%s` the permissions %s",
+			relation.Name,
+			nsdef.Name,
+			allowed.Namespace,
+			allowed.Namespace,
+			quoteAll(permissions),
+		))
+	}
+
+	return warnings
+}
+
+func newLintWarning(
+	kind LintWarningKind,
+	namespaceName string,
+	relation *core.Relation,
+	withSource nspkg.WithSourcePosition,
+	message string,
+	args ...interface{},
+) *LintWarning {
+	var line, column uint64
+	if sourcePosition := withSource.GetSourcePosition(); sourcePosition != nil {
+		line = sourcePosition.ZeroIndexedLineNumber + 1       // +1 to make 1-indexed
+		column = sourcePosition.ZeroIndexedColumnPosition + 1 // +1 to make 1-indexed
+	}
+
+	return &LintWarning{
+		ErrorWithSource: commonerrors.NewErrorWithSource(fmt.Errorf(message, args...), relation.Name, line, column),
+		Kind:            kind,
+		Relation: &core.RelationReference{
+			Namespace: namespaceName,
+			Relation:  relation.Name,
+		},
+	}
+}
+
+// markUsed marks the relations used by the relation, either as subject relations or by its
+// rewrite.
+func (l *linter) markUsed(nsdef *core.NamespaceDefinition, relation *core.Relation) {
+	for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+		if allowed.GetRelation() != "" && allowed.GetRelation() != tuple.Ellipsis {
+			l.used[relationKey(allowed.Namespace, allowed.GetRelation())] = struct{}{}
+		}
+	}
+
+	walkRewrite(relation.UsersetRewrite, func(child *core.SetOperation_Child) {
+		switch child := child.ChildType.(type) {
+		case *core.SetOperation_Child_ComputedUserset:
+			l.used[relationKey(nsdef.Name, child.ComputedUserset.Relation)] = struct{}{}
+
+		case *core.SetOperation_Child_TupleToUserset:
+			tuplesetRelation := child.TupleToUserset.Tupleset.Relation
+			l.used[relationKey(nsdef.Name, tuplesetRelation)] = struct{}{}
+			for _, subjectType := range l.allowedTypes(nsdef.Name, tuplesetRelation) {
+				l.used[relationKey(subjectType, child.TupleToUserset.ComputedUserset.Relation)] = struct{}{}
+			}
+		}
+	})
+}
+
+// computeResolvable computes the relations and permissions which can have subjects. Relations
+// which allow any subject type can always have subjects, and permissions can if their rewrite
+// can, which is iterated to a fixed point to handle permissions referencing one another.
+func (l *linter) computeResolvable() {
+	for _, nsdef := range l.defs {
+		for _, relation := range nsdef.Relation {
+			if relation.UsersetRewrite == nil && len(relation.GetTypeInformation().GetAllowedDirectRelations()) > 0 {
+				l.resolvable[relationKey(nsdef.Name, relation.Name)] = struct{}{}
+			}
+		}
+	}
+
+	for changed := true; changed; {
+		changed = false
+		for _, nsdef := range l.defs {
+			for _, relation := range nsdef.Relation {
+				key := relationKey(nsdef.Name, relation.Name)
+				if _, ok := l.resolvable[key]; ok || relation.UsersetRewrite == nil {
+					continue
+				}
+
+				if l.rewriteResolves(nsdef.Name, relation.UsersetRewrite) {
+					l.resolvable[key] = struct{}{}
+					changed = true
+				}
+			}
+		}
+	}
+}
+
+func (l *linter) rewriteResolves(namespaceName string, rewrite *core.UsersetRewrite) bool {
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		for _, child := range rw.Union.Child {
+			if l.childResolves(namespaceName, child) {
+				return true
+			}
+		}
+		return false
+
+	case *core.UsersetRewrite_Intersection:
+		for _, child := range rw.Intersection.Child {
+			if !l.childResolves(namespaceName, child) {
+				return false
+			}
+		}
+		return len(rw.Intersection.Child) > 0
+
+	case *core.UsersetRewrite_Exclusion:
+		return len(rw.Exclusion.Child) > 0 && l.childResolves(namespaceName, rw.Exclusion.Child[0])
+
+	default:
+		return false
+	}
+}
+
+func (l *linter) childResolves(namespaceName string, child *core.SetOperation_Child) bool {
+	switch child := child.ChildType.(type) {
+	case *core.SetOperation_Child_XThis:
+		return true
+
+	case *core.SetOperation_Child_ComputedUserset:
+		_, ok := l.resolvable[relationKey(namespaceName, child.ComputedUserset.Relation)]
+		return ok
+
+	case *core.SetOperation_Child_TupleToUserset:
+		tuplesetRelation := child.TupleToUserset.Tupleset.Relation
+		if _, ok := l.resolvable[relationKey(namespaceName, tuplesetRelation)]; !ok {
+			return false
+		}
+
+		for _, subjectType := range l.allowedTypes(namespaceName, tuplesetRelation) {
+			if _, ok := l.resolvable[relationKey(subjectType, child.TupleToUserset.ComputedUserset.Relation)]; ok {
+				return true
+			}
+		}
+		return false
+
+	case *core.SetOperation_Child_UsersetRewrite:
+		return l.rewriteResolves(namespaceName, child.UsersetRewrite)
+
+	default:
+		return false
+	}
+}
+
+// permissionsGrantedBy returns the names of the permissions of the definition which grant their
+// permission to the subjects of the relation, rather than only restricting it, sorted by name.
+func (l *linter) permissionsGrantedBy(nsdef *core.NamespaceDefinition, relationName string) []string {
+	var permissions []string
+	for _, relation := range nsdef.Relation {
+		if relation.UsersetRewrite != nil && l.grants(nsdef, relation.UsersetRewrite, relationName, map[string]struct{}{}) {
+			permissions = append(permissions, relation.Name)
+		}
+	}
+	sort.Strings(permissions)
+	return permissions
+}
+
+// grants returns whether the rewrite grants to the subjects of the relation, through unions and
+// the base of exclusions, either directly or via other permissions of the definition.
+func (l *linter) grants(nsdef *core.NamespaceDefinition, rewrite *core.UsersetRewrite, relationName string, encountered map[string]struct{}) bool {
+	var children []*core.SetOperation_Child
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		children = rw.Union.Child
+	case *core.UsersetRewrite_Exclusion:
+		if len(rw.Exclusion.Child) > 0 {
+			children = rw.Exclusion.Child[:1]
+		}
+	}
+
+	for _, child := range children {
+		switch child := child.ChildType.(type) {
+		case *core.SetOperation_Child_ComputedUserset:
+			computed := child.ComputedUserset.Relation
+			if computed == relationName {
+				return true
+			}
+
+			if _, ok := encountered[computed]; ok {
+				continue
+			}
+			encountered[computed] = struct{}{}
+
+			for _, relation := range nsdef.Relation {
+				if relation.Name == computed && relation.UsersetRewrite != nil && l.grants(nsdef, relation.UsersetRewrite, relationName, encountered) {
+					return true
+				}
+			}
+
+		case *core.SetOperation_Child_UsersetRewrite:
+			if l.grants(nsdef, child.UsersetRewrite, relationName, encountered) {
+				return true
+			}
+		}
+	}
+
+	return false
+}
+
+// allowedTypes returns the subject types allowed on the relation.
+func (l *linter) allowedTypes(namespaceName string, relationName string) []string {
+	nsdef, ok := l.defs[namespaceName]
+	if !ok {
+		return nil
+	}
+
+	for _, relation := range nsdef.Relation {
+		if relation.Name != relationName {
+			continue
+		}
+
+		var types []string
+		for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+			types = append(types, allowed.Namespace)
+		}
+		return types
+	}
+
+	return nil
+}
+
+// walkRewrite calls the visitor with every child of the rewrite, including those of nested
+// rewrites.
+func walkRewrite(rewrite *core.UsersetRewrite, visit func(child *core.SetOperation_Child)) {
+	if rewrite == nil {
+		return
+	}
+
+	var children []*core.SetOperation_Child
+	switch rw := rewrite.RewriteOperation.(type) {
+	case *core.UsersetRewrite_Union:
+		children = rw.Union.Child
+	case *core.UsersetRewrite_Intersection:
+		children = rw.Intersection.Child
+	case *core.UsersetRewrite_Exclusion:
+		children = rw.Exclusion.Child
+	}
+
+	for _, child := range children {
+		visit(child)
+		if nested, ok := child.ChildType.(*core.SetOperation_Child_UsersetRewrite); ok {
+			walkRewrite(nested.UsersetRewrite, visit)
+		}
+	}
+}
+
+func quoteAll(names []string) string {
+	quoted := make([]string, 0, len(names))
+	for _, name := range names {
+		quoted = append(quoted, "`"+name+"`")
+	}
+	return strings.Join(quoted, ", ")
+}
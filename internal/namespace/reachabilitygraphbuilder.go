@@ -53,7 +53,10 @@ func computeRewriteReachability(ctx context.Context, graph *core.ReachabilityGra
 		return computeRewriteOpReachability(ctx, rw.Intersection.Child, core.ReachabilityEntrypoint_REACHABLE_CONDITIONAL_RESULT, graph, targetRelation, ts, option)
 
 	case *core.UsersetRewrite_Exclusion:
-		// If optimized mode is set, only return the first child of the exclusion.
+		// If optimized mode is set, only return the first child of the exclusion: a resource
+		// reachable solely through an excluded child can never have the permission, while those
+		// reachable through the base are conditional, and have the excluded children subtracted
+		// by checking them.
 		if option == reachabilityOptimized {
 			return computeRewriteOpReachability(ctx, rw.Exclusion.Child[0:1], core.ReachabilityEntrypoint_REACHABLE_CONDITIONAL_RESULT, graph, targetRelation, ts, option)
 		}
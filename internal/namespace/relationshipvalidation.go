@@ -0,0 +1,89 @@
+package namespace
+
+import (
+	"context"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// RelationshipValidator checks relationships against the schema at a reader's revision, reusing
+// the type systems it builds across relationships. It is not safe for concurrent use.
+type RelationshipValidator struct {
+	reader      datastore.Reader
+	typeSystems map[string]*TypeSystem
+}
+
+// NewRelationshipValidator creates a validator of relationships against the schema read from the
+// reader.
+func NewRelationshipValidator(reader datastore.Reader) *RelationshipValidator {
+	return &RelationshipValidator{reader: reader, typeSystems: map[string]*TypeSystem{}}
+}
+
+// ValidateRelationship returns an error if the schema does not permit the relationship to be
+// written.
+//
+// Returns datastore.ErrNamespaceNotFound if its resource or subject type is not defined.
+// Returns ErrRelationNotFound if its relation or subject relation is not defined.
+// Returns ErrPermissionNotWritable if its relation is a permission.
+// Returns ErrSubjectNotAllowed if its subject type and relation, or its wildcard subject, is not
+// allowed on its relation.
+func (rv *RelationshipValidator) ValidateRelationship(ctx context.Context, tpl *core.RelationTuple) error {
+	resource := tpl.ResourceAndRelation
+	if err := CheckNamespaceAndRelation(ctx, resource.Namespace, resource.Relation, false, rv.reader); err != nil {
+		return err
+	}
+
+	subject := tpl.Subject
+	if err := CheckNamespaceAndRelation(ctx, subject.Namespace, subject.Relation, true, rv.reader); err != nil {
+		return err
+	}
+
+	ts, err := rv.typeSystem(ctx, resource.Namespace)
+	if err != nil {
+		return err
+	}
+
+	if ts.IsPermission(resource.Relation) {
+		return NewPermissionNotWritableErr(resource.Namespace, resource.Relation)
+	}
+
+	if subject.ObjectId == tuple.PublicWildcard {
+		isAllowed, err := ts.IsAllowedPublicNamespace(resource.Relation, subject.Namespace)
+		if err != nil {
+			return err
+		}
+
+		if isAllowed != PublicSubjectAllowed {
+			return NewWildcardNotAllowedErr(resource.Namespace, resource.Relation, subject.Namespace)
+		}
+
+		return nil
+	}
+
+	isAllowed, err := ts.IsAllowedDirectRelation(resource.Relation, subject.Namespace, subject.Relation)
+	if err != nil {
+		return err
+	}
+
+	if isAllowed == DirectRelationNotValid {
+		return NewSubjectNotAllowedErr(resource.Namespace, resource.Relation, subject.Namespace, subject.Relation)
+	}
+
+	return nil
+}
+
+func (rv *RelationshipValidator) typeSystem(ctx context.Context, nsName string) (*TypeSystem, error) {
+	if ts, ok := rv.typeSystems[nsName]; ok {
+		return ts, nil
+	}
+
+	_, ts, err := ReadNamespaceAndTypes(ctx, nsName, rv.reader)
+	if err != nil {
+		return nil, err
+	}
+
+	rv.typeSystems[nsName] = ts
+	return ts, nil
+}
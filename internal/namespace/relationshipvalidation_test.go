@@ -0,0 +1,111 @@
+package namespace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestValidateRelationship(t *testing.T) {
+	ctx := context.Background()
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(
+			ns.Namespace("user"),
+			ns.Namespace("group",
+				ns.Relation("member", nil, ns.AllowedRelation("user", "...")),
+				ns.Relation("manager", nil, ns.AllowedRelation("user", "...")),
+			),
+			ns.Namespace("document",
+				ns.Relation("viewer", nil,
+					ns.AllowedRelation("user", "..."),
+					ns.AllowedPublicNamespace("user"),
+					ns.AllowedRelation("group", "member"),
+				),
+				ns.Relation("editor", nil, ns.AllowedRelation("user", "...")),
+				ns.Relation("view", ns.Union(ns.ComputedUserset("viewer"))),
+			),
+		)
+	})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		name          string
+		relationship  string
+		expectedError error
+	}{
+		{"allowed subject", "document:foo#viewer@user:tom", nil},
+		{"allowed subject relation", "document:foo#viewer@group:eng#member", nil},
+		{"allowed wildcard", "document:foo#viewer@user:*", nil},
+		{
+			"disallowed subject type",
+			"document:foo#editor@group:eng",
+			NewSubjectNotAllowedErr("document", "editor", "group", "..."),
+		},
+		{
+			"disallowed subject relation",
+			"document:foo#viewer@group:eng#manager",
+			NewSubjectNotAllowedErr("document", "viewer", "group", "manager"),
+		},
+		{
+			"disallowed wildcard",
+			"document:foo#editor@user:*",
+			NewWildcardNotAllowedErr("document", "editor", "user"),
+		},
+		{
+			"write to permission",
+			"document:foo#view@user:tom",
+			NewPermissionNotWritableErr("document", "view"),
+		},
+		{
+			"undefined relation",
+			"document:foo#owner@user:tom",
+			NewRelationNotFoundErr("document", "owner"),
+		},
+		{
+			"undefined subject relation",
+			"document:foo#viewer@group:eng#admin",
+			NewRelationNotFoundErr("group", "admin"),
+		},
+		{
+			"undefined subject type",
+			"document:foo#viewer@team:eng",
+			datastore.NewNamespaceNotFoundErr("team"),
+		},
+	}
+
+	validator := NewRelationshipValidator(ds.SnapshotReader(revision))
+	for _, tc := range testCases {
+		tc := tc
+		t.Run(tc.name, func(t *testing.T) {
+			err := validator.ValidateRelationship(ctx, tuple.MustParse(tc.relationship))
+			if tc.expectedError == nil {
+				require.NoError(t, err)
+				return
+			}
+
+			require.IsType(t, tc.expectedError, err)
+			require.EqualError(t, err, tc.expectedError.Error())
+		})
+	}
+}
+
+func TestValidateRelationshipErrorDetails(t *testing.T) {
+	var notAllowed ErrSubjectNotAllowed
+	require.ErrorAs(t, NewSubjectNotAllowedErr("document", "viewer", "group", "manager"), &notAllowed)
+	require.Equal(t, "document", notAllowed.NamespaceName())
+	require.Equal(t, "viewer", notAllowed.RelationName())
+	require.Equal(t, "group", notAllowed.SubjectType())
+	require.Equal(t, "manager", notAllowed.SubjectRelation())
+	require.False(t, notAllowed.IsWildcard())
+	require.EqualError(t, notAllowed, "subjects of type `group#manager` are not allowed on relation `viewer` under definition `document`")
+}
@@ -3,6 +3,8 @@ package namespace
 import (
 	"context"
 	"fmt"
+	"strings"
+	"sync"
 
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -113,6 +115,20 @@ func newErrorWithSource(withSource nspkg.WithSourcePosition, sourceCodeString st
 	)
 }
 
+// newRelationNotFoundErrWithSource returns an error for an allowed relation of the relation with
+// the given name which references a relation that does not exist. The error is also a
+// sharederrors.UnknownRelationError.
+func newRelationNotFoundErrWithSource(allowedRelation *core.AllowedRelation, relationName string) error {
+	return newErrorWithSource(allowedRelation, allowedRelation.GetRelation(), "%w", newRelationNotFoundErrf(
+		allowedRelation.GetNamespace(),
+		allowedRelation.GetRelation(),
+		"for relation `%s`: relation/permission `%s` was not found under definition `%s`",
+		relationName,
+		allowedRelation.GetRelation(),
+		allowedRelation.GetNamespace(),
+	))
+}
+
 // BuildNamespaceTypeSystem constructs a type system view of a namespace definition.
 func BuildNamespaceTypeSystem(nsDef *core.NamespaceDefinition, lookupNamespace LookupNamespace) (*TypeSystem, error) {
 	relationMap := map[string]*core.Relation{}
@@ -135,10 +151,14 @@ func BuildNamespaceTypeSystem(nsDef *core.NamespaceDefinition, lookupNamespace L
 
 // TypeSystem represents typing information found in a namespace.
 type TypeSystem struct {
-	lookupNamespace    LookupNamespace
-	nsDef              *core.NamespaceDefinition
-	relationMap        map[string]*core.Relation
+	lookupNamespace LookupNamespace
+	nsDef           *core.NamespaceDefinition
+	relationMap     map[string]*core.Relation
+
+	// wildcardCheckCache is guarded by wildcardCheckLock, since type systems are shared by
+	// TypeSystemCache.
 	wildcardCheckCache map[string]*WildcardTypeReference
+	wildcardCheckLock  sync.Mutex
 }
 
 // Namespace is the namespace for which the type system was constructed.
@@ -174,7 +194,7 @@ func (nts *TypeSystem) IsPermission(relationName string) bool {
 func (nts *TypeSystem) IsAllowedPublicNamespace(sourceRelationName string, targetNamespaceName string) (AllowedPublicSubject, error) {
 	found, ok := nts.relationMap[sourceRelationName]
 	if !ok {
-		return UnknownIfPublicAllowed, fmt.Errorf("unknown relation/permission `%s` under permissions system `%s`", sourceRelationName, nts.nsDef.Name)
+		return UnknownIfPublicAllowed, newRelationNotFoundErrf(nts.nsDef.Name, sourceRelationName, "unknown relation/permission `%s` under permissions system `%s`", sourceRelationName, nts.nsDef.Name)
 	}
 
 	typeInfo := found.GetTypeInformation()
@@ -197,7 +217,7 @@ func (nts *TypeSystem) IsAllowedPublicNamespace(sourceRelationName string, targe
 func (nts *TypeSystem) IsAllowedDirectRelation(sourceRelationName string, targetNamespaceName string, targetRelationName string) (AllowedDirectRelation, error) {
 	found, ok := nts.relationMap[sourceRelationName]
 	if !ok {
-		return UnknownIfRelationAllowed, fmt.Errorf("unknown relation/permission `%s` under permissions system `%s`", sourceRelationName, nts.nsDef.Name)
+		return UnknownIfRelationAllowed, newRelationNotFoundErrf(nts.nsDef.Name, sourceRelationName, "unknown relation/permission `%s` under permissions system `%s`", sourceRelationName, nts.nsDef.Name)
 	}
 
 	typeInfo := found.GetTypeInformation()
@@ -220,7 +240,7 @@ func (nts *TypeSystem) IsAllowedDirectRelation(sourceRelationName string, target
 func (nts *TypeSystem) AllowedDirectRelationsAndWildcards(sourceRelationName string) ([]*core.AllowedRelation, error) {
 	found, ok := nts.relationMap[sourceRelationName]
 	if !ok {
-		return []*core.AllowedRelation{}, fmt.Errorf("unknown relation/permission `%s` under permissions system `%s`", sourceRelationName, nts.nsDef.Name)
+		return []*core.AllowedRelation{}, newRelationNotFoundErrf(nts.nsDef.Name, sourceRelationName, "unknown relation/permission `%s` under permissions system `%s`", sourceRelationName, nts.nsDef.Name)
 	}
 
 	typeInfo := found.GetTypeInformation()
@@ -273,17 +293,23 @@ func (nts *TypeSystem) ReferencesWildcardType(ctx context.Context, relationName
 }
 
 func (nts *TypeSystem) referencesWildcardType(ctx context.Context, relationName string, encountered map[string]bool) (*WildcardTypeReference, error) {
+	nts.wildcardCheckLock.Lock()
 	cached, isCached := nts.wildcardCheckCache[relationName]
+	nts.wildcardCheckLock.Unlock()
 	if isCached {
 		return cached, nil
 	}
 
+	// The lock is not held while computing, since the computation may recurse into this type
+	// system for other relations.
 	computed, err := nts.computeReferencesWildcardType(ctx, relationName, encountered)
 	if err != nil {
 		return nil, err
 	}
 
+	nts.wildcardCheckLock.Lock()
 	nts.wildcardCheckCache[relationName] = computed
+	nts.wildcardCheckLock.Unlock()
 	return computed, nil
 }
 
@@ -428,7 +454,7 @@ func (nts *TypeSystem) Validate(ctx context.Context) (*ValidatedNamespaceTypeSys
 				if allowedRelation.GetPublicWildcard() == nil && allowedRelation.GetRelation() != tuple.Ellipsis {
 					_, ok := nts.relationMap[allowedRelation.GetRelation()]
 					if !ok {
-						return nil, newErrorWithSource(allowedRelation, allowedRelation.GetRelation(), "for relation `%s`: relation/permission `%s` was not found under definition `%s`", relation.Name, allowedRelation.GetRelation(), allowedRelation.GetNamespace())
+						return nil, newRelationNotFoundErrWithSource(allowedRelation, relation.Name)
 					}
 				}
 			} else {
@@ -441,7 +467,7 @@ func (nts *TypeSystem) Validate(ctx context.Context) (*ValidatedNamespaceTypeSys
 					// Ensure the relation exists.
 					ok := subjectTS.HasRelation(allowedRelation.GetRelation())
 					if !ok {
-						return nil, newErrorWithSource(allowedRelation, allowedRelation.GetRelation(), "for relation `%s`: relation/permission `%s` was not found under definition `%s`", relation.Name, allowedRelation.GetRelation(), allowedRelation.GetNamespace())
+						return nil, newRelationNotFoundErrWithSource(allowedRelation, relation.Name)
 					}
 
 					// Ensure the relation doesn't itself import wildcard.
@@ -458,9 +484,82 @@ func (nts *TypeSystem) Validate(ctx context.Context) (*ValidatedNamespaceTypeSys
 		}
 	}
 
+	if err := nts.checkForRewriteCycles(); err != nil {
+		return nil, err
+	}
+
 	return &ValidatedNamespaceTypeSystem{nts}, nil
 }
 
+type rewriteReference struct {
+	relationName string
+	child        *core.SetOperation_Child
+}
+
+// checkForRewriteCycles returns an error if a relation or permission references itself through
+// the computed usersets of rewrites, which would recurse forever when checked. References through
+// arrows are followed by walking relationships, so cycles through them are allowed.
+func (nts *TypeSystem) checkForRewriteCycles() error {
+	references := make(map[string][]rewriteReference, len(nts.relationMap))
+	for _, relation := range nts.nsDef.GetRelation() {
+		relationName := relation.Name
+		graph.WalkRewrite(relation.GetUsersetRewrite(), func(childOneof *core.SetOperation_Child) interface{} {
+			if computed, ok := childOneof.ChildType.(*core.SetOperation_Child_ComputedUserset); ok {
+				references[relationName] = append(references[relationName], rewriteReference{
+					relationName: computed.ComputedUserset.GetRelation(),
+					child:        childOneof,
+				})
+			}
+			return nil
+		})
+	}
+
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(references))
+	var path []string
+
+	var visit func(relationName string) error
+	visit = func(relationName string) error {
+		state[relationName] = visiting
+		path = append(path, relationName)
+
+		for _, reference := range references[relationName] {
+			switch state[reference.relationName] {
+			case visiting:
+				cycleStart := 0
+				for path[cycleStart] != reference.relationName {
+					cycleStart++
+				}
+				cycle := append(append([]string{}, path[cycleStart:]...), reference.relationName)
+				return newErrorWithSource(reference.child, reference.relationName, "under permission `%s`: found cycle in permissions: %s", relationName, strings.Join(cycle, " -> "))
+
+			case unvisited:
+				if err := visit(reference.relationName); err != nil {
+					return err
+				}
+			}
+		}
+
+		path = path[:len(path)-1]
+		state[relationName] = visited
+		return nil
+	}
+
+	for _, relation := range nts.nsDef.GetRelation() {
+		if state[relation.Name] == unvisited {
+			if err := visit(relation.Name); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
 func (nts *TypeSystem) typeSystemForNamespace(ctx context.Context, namespaceName string) (*TypeSystem, error) {
 	if nts.nsDef.Name == namespaceName {
 		return nts, nil
@@ -9,6 +9,8 @@ import (
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/sharederrors"
+	"github.com/authzed/spicedb/pkg/commonerrors"
 	"github.com/authzed/spicedb/pkg/datastore"
 	ns "github.com/authzed/spicedb/pkg/namespace"
 )
@@ -203,6 +205,64 @@ func TestTypeSystem(t *testing.T) {
 			},
 			"for relation `viewer`: relation/permission `group#member` includes wildcard type `user` via relation `group#manager`: wildcard relations cannot be transitively included",
 		},
+		{
+			"self-referential permission",
+			ns.Namespace(
+				"document",
+				ns.Relation("viewer", nil, ns.AllowedRelation("user", "...")),
+				ns.Relation("view", ns.Union(
+					ns.ComputedUserset("viewer"),
+					ns.ComputedUserset("view"),
+				)),
+			),
+			[]*core.NamespaceDefinition{
+				ns.Namespace("user"),
+			},
+			"under permission `view`: found cycle in permissions: view -> view",
+		},
+		{
+			"multi-hop permission cycle",
+			ns.Namespace(
+				"document",
+				ns.Relation("owner", nil, ns.AllowedRelation("user", "...")),
+				ns.Relation("viewer", nil, ns.AllowedRelation("user", "...")),
+				ns.Relation("view", ns.Union(
+					ns.ComputedUserset("viewer"),
+					ns.ComputedUserset("edit"),
+				)),
+				ns.Relation("edit", ns.Union(
+					ns.ComputedUserset("owner"),
+					ns.Rewrite(ns.Intersection(
+						ns.ComputedUserset("admin"),
+						ns.ComputedUserset("owner"),
+					)),
+				)),
+				ns.Relation("admin", ns.Exclusion(
+					ns.ComputedUserset("view"),
+					ns.ComputedUserset("viewer"),
+				)),
+			),
+			[]*core.NamespaceDefinition{
+				ns.Namespace("user"),
+			},
+			"under permission `admin`: found cycle in permissions: view -> edit -> admin -> view",
+		},
+		{
+			"recursion through relationships",
+			ns.Namespace(
+				"folder",
+				ns.Relation("parent", nil, ns.AllowedRelation("folder", "...")),
+				ns.Relation("viewer", nil, ns.AllowedRelation("user", "...")),
+				ns.Relation("view", ns.Union(
+					ns.ComputedUserset("viewer"),
+					ns.TupleToUserset("parent", "view"),
+				)),
+			),
+			[]*core.NamespaceDefinition{
+				ns.Namespace("user"),
+			},
+			"",
+		},
 	}
 
 	for _, tc := range testCases {
@@ -237,3 +297,70 @@ func TestTypeSystem(t *testing.T) {
 		})
 	}
 }
+
+func TestTypeSystemUnknownRelationError(t *testing.T) {
+	testCases := []struct {
+		name              string
+		toCheck           *core.NamespaceDefinition
+		expectedNamespace string
+		expectedRelation  string
+	}{
+		{
+			"relation under another definition",
+			ns.Namespace(
+				"document",
+				ns.Relation("viewer", nil, ns.AllowedRelation("folder", "nonexistent")),
+			),
+			"folder",
+			"nonexistent",
+		},
+		{
+			"relation under the same definition",
+			ns.Namespace(
+				"folder",
+				ns.Relation("viewer", nil, ns.AllowedRelation("folder", "nonexistent")),
+			),
+			"folder",
+			"nonexistent",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+
+			ctx := context.Background()
+			lastRevision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				return rwt.WriteNamespaces(ns.Namespace("folder", ns.Relation("viewer", nil)))
+			})
+			require.NoError(err)
+
+			ts, err := BuildNamespaceTypeSystemForDatastore(tc.toCheck, ds.SnapshotReader(lastRevision))
+			require.NoError(err)
+
+			_, terr := ts.Validate(ctx)
+			require.Error(terr)
+
+			var relNotFoundError sharederrors.UnknownRelationError
+			require.ErrorAs(terr, &relNotFoundError)
+			require.Equal(tc.expectedNamespace, relNotFoundError.NamespaceName())
+			require.Equal(tc.expectedRelation, relNotFoundError.NotFoundRelationName())
+
+			errWithSource, ok := commonerrors.AsErrorWithSource(terr)
+			require.True(ok)
+			require.Equal(tc.expectedRelation, errWithSource.SourceCodeString)
+		})
+	}
+
+	// Looking up the types allowed on a relation that does not exist also returns the error
+	ts, err := BuildNamespaceTypeSystem(ns.Namespace("folder"), nil)
+	require.NoError(t, err)
+
+	_, err = ts.AllowedDirectRelationsAndWildcards("nonexistent")
+	var relNotFoundError sharederrors.UnknownRelationError
+	require.ErrorAs(t, err, &relNotFoundError)
+	require.Equal(t, "nonexistent", relNotFoundError.NotFoundRelationName())
+}
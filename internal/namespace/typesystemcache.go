@@ -0,0 +1,108 @@
+package namespace
+
+import (
+	"container/list"
+	"context"
+	"sync"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// DefaultTypeSystemCacheSize is the number of type systems kept by a TypeSystemCache created
+// with NewDefaultTypeSystemCache.
+const DefaultTypeSystemCacheSize = 1024
+
+type typeSystemCacheKey struct {
+	namespace string
+	revision  string
+}
+
+type typeSystemCacheEntry struct {
+	key   typeSystemCacheKey
+	nsDef *core.NamespaceDefinition
+	ts    *TypeSystem
+}
+
+// TypeSystemCache keeps the most recently used type systems of namespaces, keyed by the name of
+// the namespace and the revision at which it was read. A type system looks up the other
+// namespaces it references at the same revision, so it is never reused at another revision, and a
+// namespace changed after a revision is therefore always rebuilt.
+//
+// A TypeSystemCache is safe for concurrent use.
+type TypeSystemCache struct {
+	maxSize int
+
+	mu      sync.Mutex
+	entries map[typeSystemCacheKey]*list.Element
+	order   *list.List
+	builds  uint64
+}
+
+// NewTypeSystemCache creates a cache which keeps at most maxSize type systems.
+func NewTypeSystemCache(maxSize int) *TypeSystemCache {
+	return &TypeSystemCache{
+		maxSize: maxSize,
+		entries: make(map[typeSystemCacheKey]*list.Element, maxSize),
+		order:   list.New(),
+	}
+}
+
+// NewDefaultTypeSystemCache creates a cache which keeps DefaultTypeSystemCacheSize type systems.
+func NewDefaultTypeSystemCache() *TypeSystemCache {
+	return NewTypeSystemCache(DefaultTypeSystemCacheSize)
+}
+
+// ReadNamespaceAndTypes returns the namespace definition and type system of the namespace, as
+// ReadNamespaceAndTypes does, reusing those built for an earlier read of the namespace at the
+// same revision. The reader must read at the given revision.
+func (tsc *TypeSystemCache) ReadNamespaceAndTypes(
+	ctx context.Context,
+	nsName string,
+	revision datastore.Revision,
+	ds datastore.Reader,
+) (*core.NamespaceDefinition, *TypeSystem, error) {
+	key := typeSystemCacheKey{namespace: nsName, revision: revision.String()}
+
+	tsc.mu.Lock()
+	if element, ok := tsc.entries[key]; ok {
+		tsc.order.MoveToFront(element)
+		entry := element.Value.(*typeSystemCacheEntry)
+		tsc.mu.Unlock()
+		return entry.nsDef, entry.ts, nil
+	}
+	tsc.mu.Unlock()
+
+	// The type system is built without holding the lock, so concurrent misses for the same
+	// namespace may each build it; the first to finish is kept.
+	nsDef, ts, err := ReadNamespaceAndTypes(ctx, nsName, ds)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	tsc.mu.Lock()
+	defer tsc.mu.Unlock()
+
+	tsc.builds++
+	if element, ok := tsc.entries[key]; ok {
+		tsc.order.MoveToFront(element)
+		entry := element.Value.(*typeSystemCacheEntry)
+		return entry.nsDef, entry.ts, nil
+	}
+
+	tsc.entries[key] = tsc.order.PushFront(&typeSystemCacheEntry{key: key, nsDef: nsDef, ts: ts})
+	for tsc.order.Len() > tsc.maxSize {
+		oldest := tsc.order.Back()
+		tsc.order.Remove(oldest)
+		delete(tsc.entries, oldest.Value.(*typeSystemCacheEntry).key)
+	}
+
+	return nsDef, ts, nil
+}
+
+// Builds returns the number of type systems the cache has built.
+func (tsc *TypeSystemCache) Builds() uint64 {
+	tsc.mu.Lock()
+	defer tsc.mu.Unlock()
+	return tsc.builds
+}
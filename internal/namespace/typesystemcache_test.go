@@ -0,0 +1,76 @@
+package namespace
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+func TestTypeSystemCache(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	writeDocument := func(relations ...string) datastore.Revision {
+		revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			nsRelations := make([]*core.Relation, 0, len(relations))
+			for _, relation := range relations {
+				nsRelations = append(nsRelations, ns.Relation(relation, nil, ns.AllowedRelation("user", "...")))
+			}
+			return rwt.WriteNamespaces(ns.Namespace("user"), ns.Namespace("document", nsRelations...))
+		})
+		require.NoError(err)
+		return revision
+	}
+
+	validate := func(cache *TypeSystemCache, revision datastore.Revision) *TypeSystem {
+		_, ts, err := cache.ReadNamespaceAndTypes(ctx, "document", revision, ds.SnapshotReader(revision))
+		require.NoError(err)
+		_, err = ts.Validate(ctx)
+		require.NoError(err)
+		return ts
+	}
+
+	firstRevision := writeDocument("viewer")
+	cache := NewTypeSystemCache(2)
+
+	// Validating twice at the same revision builds the type system once.
+	first := validate(cache, firstRevision)
+	require.Same(first, validate(cache, firstRevision))
+	require.Equal(uint64(1), cache.Builds())
+
+	// Once the namespace is changed, it is rebuilt at the new revision, while the type system
+	// at the earlier revision is still reused.
+	secondRevision := writeDocument("viewer", "editor")
+	second := validate(cache, secondRevision)
+	require.True(second.HasRelation("editor"))
+	require.False(first.HasRelation("editor"))
+	require.Equal(uint64(2), cache.Builds())
+
+	require.Same(first, validate(cache, firstRevision))
+	require.Equal(uint64(2), cache.Builds())
+
+	// Namespaces which do not exist are not cached.
+	_, _, err = cache.ReadNamespaceAndTypes(ctx, "unknown", secondRevision, ds.SnapshotReader(secondRevision))
+	require.Error(err)
+	require.Equal(uint64(2), cache.Builds())
+
+	// The least recently used type system is evicted once the cache is full.
+	thirdRevision := writeDocument("viewer", "owner")
+	validate(cache, thirdRevision)
+	require.Equal(uint64(3), cache.Builds())
+
+	validate(cache, firstRevision)
+	require.Equal(uint64(3), cache.Builds())
+
+	validate(cache, secondRevision)
+	require.Equal(uint64(4), cache.Builds())
+}
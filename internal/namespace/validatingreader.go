@@ -0,0 +1,56 @@
+package namespace
+
+import (
+	"context"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/pkg/datastore"
+)
+
+type validatingReader struct {
+	datastore.Reader
+}
+
+// NewValidatingReader returns a reader which checks that the subject type and relation of each
+// reverse query, and its resource relation if any, are defined at the reader's revision before
+// running the query. Queries over undefined types or relations fail with an error implementing
+// sharederrors.UnknownNamespaceError or sharederrors.UnknownRelationError, instead of returning
+// no relationships.
+//
+// Validating reads the namespace definitions for every query, so callers making many queries
+// over types they have already checked should use the underlying reader.
+func NewValidatingReader(reader datastore.Reader) datastore.Reader {
+	return validatingReader{reader}
+}
+
+func (vr validatingReader) ReverseQueryRelationships(
+	ctx context.Context,
+	subjectFilter *v1.SubjectFilter,
+	opts ...options.ReverseQueryOptionsOption,
+) (datastore.RelationshipIterator, error) {
+	subjectRelation := datastore.Ellipsis
+	if subjectFilter.OptionalRelation != nil && subjectFilter.OptionalRelation.Relation != "" {
+		subjectRelation = subjectFilter.OptionalRelation.Relation
+	}
+
+	if err := CheckNamespaceAndRelation(ctx, subjectFilter.SubjectType, subjectRelation, true, vr.Reader); err != nil {
+		return nil, err
+	}
+
+	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
+	if queryOpts.ResRelation != nil {
+		if err := CheckNamespaceAndRelation(
+			ctx,
+			queryOpts.ResRelation.Namespace,
+			queryOpts.ResRelation.Relation,
+			false,
+			vr.Reader,
+		); err != nil {
+			return nil, err
+		}
+	}
+
+	return vr.Reader.ReverseQueryRelationships(ctx, subjectFilter, opts...)
+}
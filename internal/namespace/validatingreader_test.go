@@ -0,0 +1,127 @@
+package namespace
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	"github.com/authzed/spicedb/internal/sharederrors"
+	"github.com/authzed/spicedb/pkg/datastore"
+	ns "github.com/authzed/spicedb/pkg/namespace"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestValidatingReader(t *testing.T) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.WriteNamespaces(
+			ns.Namespace("user"),
+			ns.Namespace("group", ns.Relation("member", nil)),
+			ns.Namespace("document", ns.Relation("viewer", nil)),
+		); err != nil {
+			return err
+		}
+
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse("document:first#viewer@user:tom#..."))),
+			tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse("document:first#viewer@group:admins#member"))),
+		})
+	})
+	require.NoError(err)
+
+	reader := NewValidatingReader(ds.SnapshotReader(revision))
+
+	testCases := []struct {
+		name                   string
+		subjectFilter          *v1.SubjectFilter
+		resRelation            *options.ResourceRelation
+		expectedCount          int
+		expectedUnknownNS      string
+		expectedUnknownRelName string
+	}{
+		{
+			"defined subject type",
+			&v1.SubjectFilter{SubjectType: "user"},
+			nil,
+			1,
+			"",
+			"",
+		},
+		{
+			"defined subject relation",
+			&v1.SubjectFilter{SubjectType: "group", OptionalRelation: &v1.SubjectFilter_RelationFilter{Relation: "member"}},
+			&options.ResourceRelation{Namespace: "document", Relation: "viewer"},
+			1,
+			"",
+			"",
+		},
+		{
+			"undefined subject relation",
+			&v1.SubjectFilter{SubjectType: "group", OptionalRelation: &v1.SubjectFilter_RelationFilter{Relation: "manager"}},
+			nil,
+			0,
+			"",
+			"manager",
+		},
+		{
+			"undefined subject type",
+			&v1.SubjectFilter{SubjectType: "robot"},
+			nil,
+			0,
+			"robot",
+			"",
+		},
+		{
+			"undefined resource relation",
+			&v1.SubjectFilter{SubjectType: "user"},
+			&options.ResourceRelation{Namespace: "document", Relation: "editor"},
+			0,
+			"",
+			"editor",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			var opts []options.ReverseQueryOptionsOption
+			if tc.resRelation != nil {
+				opts = append(opts, options.WithResRelation(tc.resRelation))
+			}
+
+			iter, err := reader.ReverseQueryRelationships(ctx, tc.subjectFilter, opts...)
+			switch {
+			case tc.expectedUnknownNS != "":
+				var nsNotFound sharederrors.UnknownNamespaceError
+				require.ErrorAs(err, &nsNotFound)
+				require.Equal(tc.expectedUnknownNS, nsNotFound.NotFoundNamespaceName())
+
+			case tc.expectedUnknownRelName != "":
+				var relNotFound sharederrors.UnknownRelationError
+				require.ErrorAs(err, &relNotFound)
+				require.Equal(tc.expectedUnknownRelName, relNotFound.NotFoundRelationName())
+
+			default:
+				require.NoError(err)
+				defer iter.Close()
+
+				count := 0
+				for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+					count++
+				}
+				require.NoError(iter.Err())
+				require.Equal(tc.expectedCount, count)
+			}
+		})
+	}
+}
@@ -4,13 +4,13 @@ import (
 	"context"
 	"errors"
 
-	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/validator"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/graph"
+	"github.com/authzed/spicedb/internal/middleware/validation"
 	"github.com/authzed/spicedb/internal/services/shared"
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
 )
@@ -27,8 +27,8 @@ func NewDispatchServer(localDispatch dispatch.Dispatcher) dispatchv1.DispatchSer
 	return &dispatchServer{
 		localDispatch: localDispatch,
 		WithServiceSpecificInterceptors: shared.WithServiceSpecificInterceptors{
-			Unary:  grpcvalidate.UnaryServerInterceptor(),
-			Stream: grpcvalidate.StreamServerInterceptor(),
+			Unary:  validation.NewValidationInterceptor(),
+			Stream: validation.NewStreamValidationInterceptor(),
 		},
 	}
 }
@@ -56,6 +56,21 @@ func (ds *dispatchServer) DispatchReachableResources(
 		dispatch.WrapGRPCStream[*dispatchv1.DispatchReachableResourcesResponse](resp))
 }
 
+func (ds *dispatchServer) DispatchLookupSubjects(ctx context.Context, req *dispatchv1.DispatchLookupSubjectsRequest) (*dispatchv1.DispatchLookupSubjectsResponse, error) {
+	resp, err := ds.localDispatch.DispatchLookupSubjects(ctx, req)
+	return resp, rewriteGraphError(ctx, err)
+}
+
+func (ds *dispatchServer) DispatchLookupForSubjectType(ctx context.Context, req *dispatchv1.DispatchLookupForSubjectTypeRequest) (*dispatchv1.DispatchLookupForSubjectTypeResponse, error) {
+	resp, err := ds.localDispatch.DispatchLookupForSubjectType(ctx, req)
+	return resp, rewriteGraphError(ctx, err)
+}
+
+func (ds *dispatchServer) DispatchCheckBulk(ctx context.Context, req *dispatchv1.DispatchCheckBulkRequest) (*dispatchv1.DispatchCheckBulkResponse, error) {
+	resp, err := ds.localDispatch.DispatchCheckBulk(ctx, req)
+	return resp, rewriteGraphError(ctx, err)
+}
+
 func (ds *dispatchServer) Close() error {
 	return nil
 }
@@ -12,6 +12,7 @@ import (
 	"github.com/authzed/spicedb/internal/services/health"
 	v1svc "github.com/authzed/spicedb/internal/services/v1"
 	v1alpha1svc "github.com/authzed/spicedb/internal/services/v1alpha1"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
 )
 
 // SchemaServiceOption defines the options for enabled or disabled the V1 Schema service.
@@ -30,7 +31,8 @@ const (
 	OverallServerHealthCheckKey = ""
 )
 
-// RegisterGrpcServices registers all services to be exposed on the GRPC server.
+// RegisterGrpcServices registers all services to be exposed on the GRPC server. The schema server
+// options configure both v1alpha1 schema services.
 func RegisterGrpcServices(
 	srv *grpc.Server,
 	healthManager health.Manager,
@@ -38,12 +40,16 @@ func RegisterGrpcServices(
 	maxDepth uint32,
 	prefixRequired v1alpha1svc.PrefixRequiredOption,
 	schemaServiceOption SchemaServiceOption,
+	schemaServerOptions ...v1alpha1svc.SchemaServerOption,
 ) {
 	healthManager.RegisterReportedService(OverallServerHealthCheckKey)
 
-	v1alpha1.RegisterSchemaServiceServer(srv, v1alpha1svc.NewSchemaServer(prefixRequired))
+	v1alpha1.RegisterSchemaServiceServer(srv, v1alpha1svc.NewSchemaServer(prefixRequired, schemaServerOptions...))
 	healthManager.RegisterReportedService(v1alpha1.SchemaService_ServiceDesc.ServiceName)
 
+	schemav1alpha1.RegisterExperimentalSchemaServiceServer(srv, v1alpha1svc.NewExperimentalSchemaServer(prefixRequired, schemaServerOptions...))
+	healthManager.RegisterReportedService(schemav1alpha1.ExperimentalSchemaService_ServiceDesc.ServiceName)
+
 	v1.RegisterPermissionsServiceServer(srv, v1svc.NewPermissionsServer(dispatch, maxDepth))
 	healthManager.RegisterReportedService(v1.PermissionsService_ServiceDesc.ServiceName)
 
@@ -21,25 +21,18 @@ func CheckPreconditions(
 	preconditions []*v1.Precondition,
 ) error {
 	for _, precond := range preconditions {
-		iter, err := rwt.QueryRelationships(ctx, precond.Filter, options.WithLimit(&limitOne))
+		matched, err := anyRelationshipMatches(ctx, rwt, precond.Filter)
 		if err != nil {
-			return fmt.Errorf("error reading relationships: %w", err)
-		}
-		defer iter.Close()
-
-		first := iter.Next()
-
-		if first == nil && iter.Err() != nil {
-			return fmt.Errorf("error reading relationships from iterator: %w", err)
+			return err
 		}
 
 		switch precond.Operation {
 		case v1.Precondition_OPERATION_MUST_NOT_MATCH:
-			if first != nil {
+			if matched {
 				return NewPreconditionFailedErr(precond)
 			}
 		case v1.Precondition_OPERATION_MUST_MATCH:
-			if first == nil {
+			if !matched {
 				return NewPreconditionFailedErr(precond)
 			}
 		default:
@@ -50,6 +43,25 @@ func CheckPreconditions(
 	return nil
 }
 
+func anyRelationshipMatches(
+	ctx context.Context,
+	rwt datastore.ReadWriteTransaction,
+	filter *v1.RelationshipFilter,
+) (bool, error) {
+	iter, err := rwt.QueryRelationships(ctx, filter, options.WithLimit(&limitOne))
+	if err != nil {
+		return false, fmt.Errorf("error reading relationships: %w", err)
+	}
+	defer iter.Close()
+
+	first := iter.Next()
+	if first == nil && iter.Err() != nil {
+		return false, fmt.Errorf("error reading relationships from iterator: %w", iter.Err())
+	}
+
+	return first != nil, nil
+}
+
 // ErrPreconditionFailed occurs when the precondition to a write tuple call does not match.
 type ErrPreconditionFailed struct {
 	error
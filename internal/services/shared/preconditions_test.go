@@ -38,13 +38,62 @@ func TestPreconditions(t *testing.T) {
 				Filter:    companyPlanFolder,
 			},
 		}))
-		require.Error(CheckPreconditions(ctx, rwt, []*v1.Precondition{
+		err := CheckPreconditions(ctx, rwt, []*v1.Precondition{
 			{
 				Operation: v1.Precondition_OPERATION_MUST_NOT_MATCH,
 				Filter:    companyPlanFolder,
 			},
-		}))
+		})
+		require.Error(err)
+		require.ErrorAs(err, &ErrPreconditionFailed{})
 		return nil
 	})
 	require.NoError(err)
 }
+
+func TestFailedPreconditionAbortsWrite(t *testing.T) {
+	require := require.New(t)
+	uninitialized, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	ds, _ := testfixtures.StandardDatastoreWithData(uninitialized, require)
+
+	newRelationship := &v1.Relationship{
+		Resource: &v1.ObjectReference{ObjectType: "document", ObjectId: "newdoc"},
+		Relation: "parent",
+		Subject: &v1.SubjectReference{
+			Object: &v1.ObjectReference{ObjectType: "folder", ObjectId: "company"},
+		},
+	}
+
+	ctx := context.Background()
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.WriteRelationships([]*v1.RelationshipUpdate{{
+			Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+			Relationship: newRelationship,
+		}}); err != nil {
+			return err
+		}
+
+		return CheckPreconditions(ctx, rwt, []*v1.Precondition{
+			{
+				Operation: v1.Precondition_OPERATION_MUST_NOT_MATCH,
+				Filter:    companyPlanFolder,
+			},
+		})
+	})
+	require.ErrorAs(err, &ErrPreconditionFailed{})
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	iter, err := ds.SnapshotReader(headRevision).QueryRelationships(ctx, &v1.RelationshipFilter{
+		ResourceType:       "document",
+		OptionalResourceId: "newdoc",
+	})
+	require.NoError(err)
+	defer iter.Close()
+
+	require.Nil(iter.Next(), "the write should have been rolled back")
+	require.NoError(iter.Err())
+}
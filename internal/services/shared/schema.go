@@ -2,8 +2,14 @@ package shared
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"runtime"
+	"sort"
+	"strings"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"golang.org/x/sync/errgroup"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
@@ -15,44 +21,241 @@ import (
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
-// EnsureNoRelationshipsExist ensures that no relationships exist within the namespace with the given name.
-func EnsureNoRelationshipsExist(ctx context.Context, rwt datastore.ReadWriteTransaction, namespaceName string) error {
-	qy, qyErr := rwt.QueryRelationships(
-		ctx,
-		&v1.RelationshipFilter{ResourceType: namespaceName},
-		options.WithLimit(options.LimitOne),
-	)
-	if err := ErrorIfTupleIteratorReturnsTuples(
-		ctx,
-		qy,
-		qyErr,
-		"cannot delete Object Definition `%s`, as a Relationship exists under it",
-		namespaceName,
-	); err != nil {
+// ValidateAndAnnotateNamespaces builds and validates the type system of each of the given namespace
+// definitions against allDefs, and then annotates each of them. Validation runs concurrently, with
+// at most one validation per CPU; if more than one definition is invalid, the error for the
+// earliest of them in nsdefs is returned, matching the result of validating them in order.
+func ValidateAndAnnotateNamespaces(ctx context.Context, nsdefs []*core.NamespaceDefinition, allDefs []*core.NamespaceDefinition) error {
+	err := ValidateAndAnnotateAllNamespaces(ctx, nsdefs, allDefs)
+
+	var invalidDefs *InvalidDefinitionsError
+	if errors.As(err, &invalidDefs) {
+		return invalidDefs.Definitions[0].Err
+	}
+
+	return err
+}
+
+// ValidateAndAnnotateAllNamespaces is like ValidateAndAnnotateNamespaces, except that if more than
+// one definition is invalid, an InvalidDefinitionsError holding the error for each of them is
+// returned.
+func ValidateAndAnnotateAllNamespaces(ctx context.Context, nsdefs []*core.NamespaceDefinition, allDefs []*core.NamespaceDefinition) error {
+	validated := make([]*namespace.ValidatedNamespaceTypeSystem, len(nsdefs))
+	errs := make([]error, len(nsdefs))
+
+	var g errgroup.Group
+	g.SetLimit(runtime.NumCPU())
+	for i, nsdef := range nsdefs {
+		i, nsdef := i, nsdef
+		g.Go(func() error {
+			ts, err := namespace.BuildNamespaceTypeSystemForDefs(nsdef, allDefs)
+			if err != nil {
+				errs[i] = err
+				return nil
+			}
+
+			validated[i], errs[i] = ts.Validate(ctx)
+			return nil
+		})
+	}
+	_ = g.Wait()
+
+	// Errors found after the context was canceled may be caused by it rather than by the
+	// definitions, so they are not reported as invalid definitions.
+	if err := ctx.Err(); err != nil {
 		return err
 	}
 
-	qy, qyErr = rwt.ReverseQueryRelationships(ctx, &v1.SubjectFilter{
-		SubjectType: namespaceName,
-	}, options.WithReverseLimit(options.LimitOne))
-	if err := ErrorIfTupleIteratorReturnsTuples(
-		ctx,
-		qy,
-		qyErr,
-		"cannot delete Object Definition `%s`, as a Relationship references it",
-		namespaceName,
-	); err != nil {
+	if err := NewDefinitionsError(nsdefs, errs); err != nil {
 		return err
 	}
 
+	// Annotation mutates the definitions, so it is only done once every validation, each of which
+	// may read any of the definitions, has completed.
+	for _, vts := range validated {
+		if err := namespace.AnnotateNamespace(vts); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
+// DefinitionError is the error found for a single namespace definition.
+type DefinitionError struct {
+	DefinitionName string
+	Err            error
+}
+
+// InvalidDefinitionsError is returned when more than one of the namespace definitions being
+// written is invalid. The errors are in the order of the definitions.
+type InvalidDefinitionsError struct {
+	Definitions []DefinitionError
+}
+
+func (err *InvalidDefinitionsError) Error() string {
+	messages := make([]string, 0, len(err.Definitions))
+	for _, defErr := range err.Definitions {
+		messages = append(messages, defErr.Err.Error())
+	}
+	return fmt.Sprintf("%d object definitions are invalid: %s", len(err.Definitions), strings.Join(messages, "; "))
+}
+
+// NewDefinitionsError returns nil if none of the errors, each for the namespace definition at
+// the same index, are set, the error itself if exactly one is set, and otherwise an
+// InvalidDefinitionsError. The errors must all be validation errors of the definitions, since
+// an InvalidDefinitionsError is reported to clients as an invalid argument.
+func NewDefinitionsError(nsdefs []*core.NamespaceDefinition, errs []error) error {
+	var defErrs []DefinitionError
+	for i, err := range errs {
+		if err != nil {
+			defErrs = append(defErrs, DefinitionError{DefinitionName: nsdefs[i].Name, Err: err})
+		}
+	}
+
+	switch len(defErrs) {
+	case 0:
+		return nil
+	case 1:
+		return defErrs[0].Err
+	default:
+		return &InvalidDefinitionsError{Definitions: defErrs}
+	}
+}
+
+// NamespaceInUseError is returned when an object definition cannot be deleted because it is
+// still in use. Each of its fields describes one of the uses blocking the deletion.
+type NamespaceInUseError struct {
+	NamespaceName string
+
+	// ReferencingDefinitions are the names of the other object definitions with relations
+	// allowing subjects of the object definition.
+	ReferencingDefinitions []string
+
+	// HasRelationships is whether relationships exist with resources of the object definition.
+	HasRelationships bool
+
+	// HasReferencingRelationships is whether relationships exist with subjects of the object
+	// definition.
+	HasReferencingRelationships bool
+}
+
+func (err *NamespaceInUseError) Error() string {
+	var blockers []string
+	if len(err.ReferencingDefinitions) > 0 {
+		blockers = append(blockers, fmt.Sprintf("it is referenced by Object Definitions `%s`", strings.Join(err.ReferencingDefinitions, "`, `")))
+	}
+	if err.HasRelationships {
+		blockers = append(blockers, "a Relationship exists under it")
+	}
+	if err.HasReferencingRelationships {
+		blockers = append(blockers, "a Relationship references it")
+	}
+	return fmt.Sprintf("cannot delete Object Definition `%s`, as %s", err.NamespaceName, strings.Join(blockers, " and "))
+}
+
+// SafeDeleteNamespace deletes the object definition with the given name, but only if no other
+// object definition references it and no relationships exist with resources or subjects of it.
+// Otherwise, a NamespaceInUseError listing everything blocking the deletion is returned.
+func SafeDeleteNamespace(ctx context.Context, rwt datastore.ReadWriteTransaction, namespaceName string) error {
+	return SafeDeleteNamespaces(ctx, rwt, namespaceName)
+}
+
+// SafeDeleteNamespaces deletes the object definitions with the given names, but only if none of
+// them is in use, as for SafeDeleteNamespace. References between the object definitions being
+// deleted do not block their deletion, so that definitions referencing each other can be deleted
+// together. The definitions are checked in order of their names, and a NamespaceInUseError is
+// returned for the first found in use, in which case none of them are deleted.
+func SafeDeleteNamespaces(ctx context.Context, rwt datastore.ReadWriteTransaction, namespaceNames ...string) error {
+	sortedNames := make([]string, 0, len(namespaceNames))
+	deleted := make(map[string]struct{}, len(namespaceNames))
+	for _, namespaceName := range namespaceNames {
+		if _, ok := deleted[namespaceName]; ok {
+			continue
+		}
+		if _, _, err := rwt.ReadNamespace(ctx, namespaceName); err != nil {
+			return err
+		}
+		deleted[namespaceName] = struct{}{}
+		sortedNames = append(sortedNames, namespaceName)
+	}
+	sort.Strings(sortedNames)
+
+	nsDefs, err := rwt.ListNamespaces(ctx)
+	if err != nil {
+		return err
+	}
+
+	for _, namespaceName := range sortedNames {
+		inUse := &NamespaceInUseError{NamespaceName: namespaceName}
+
+		for _, nsDef := range nsDefs {
+			if _, ok := deleted[nsDef.Name]; !ok && referencesNamespace(nsDef, namespaceName) {
+				inUse.ReferencingDefinitions = append(inUse.ReferencingDefinitions, nsDef.Name)
+			}
+		}
+		sort.Strings(inUse.ReferencingDefinitions)
+
+		inUse.HasRelationships, err = anyRelationshipMatches(ctx, rwt, &v1.RelationshipFilter{ResourceType: namespaceName})
+		if err != nil {
+			return err
+		}
+
+		inUse.HasReferencingRelationships, err = anyRelationshipReferences(ctx, rwt, namespaceName)
+		if err != nil {
+			return err
+		}
+
+		if len(inUse.ReferencingDefinitions) > 0 || inUse.HasRelationships || inUse.HasReferencingRelationships {
+			return inUse
+		}
+	}
+
+	for _, namespaceName := range sortedNames {
+		if err := rwt.DeleteNamespace(namespaceName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// referencesNamespace returns whether any relation of the definition allows subjects of the
+// namespace.
+func referencesNamespace(nsDef *core.NamespaceDefinition, namespaceName string) bool {
+	for _, relation := range nsDef.Relation {
+		for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+			if allowed.Namespace == namespaceName {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func anyRelationshipReferences(ctx context.Context, rwt datastore.ReadWriteTransaction, namespaceName string) (bool, error) {
+	iter, err := rwt.ReverseQueryRelationships(
+		ctx,
+		&v1.SubjectFilter{SubjectType: namespaceName},
+		options.WithReverseLimit(options.LimitOne),
+	)
+	if err != nil {
+		return false, fmt.Errorf("error reading relationships: %w", err)
+	}
+	defer iter.Close()
+
+	first := iter.Next()
+	if first == nil && iter.Err() != nil {
+		return false, fmt.Errorf("error reading relationships from iterator: %w", iter.Err())
+	}
+
+	return first != nil, nil
+}
+
 // SanityCheckExistingRelationships ensures that a namespace definition being written does not result
 // in relationships without associated defined schema object definitions and relations.
 func SanityCheckExistingRelationships(
 	ctx context.Context,
-	rwt datastore.ReadWriteTransaction,
+	reader datastore.Reader,
 	nsdef *core.NamespaceDefinition,
 	existingDefs map[string]*core.NamespaceDefinition,
 ) error {
@@ -66,7 +269,7 @@ func SanityCheckExistingRelationships(
 	for _, delta := range diff.Deltas() {
 		switch delta.Type {
 		case namespace.RemovedRelation:
-			qy, qyErr := rwt.QueryRelationships(ctx, &v1.RelationshipFilter{
+			qy, qyErr := reader.QueryRelationships(ctx, &v1.RelationshipFilter{
 				ResourceType:     nsdef.Name,
 				OptionalRelation: delta.RelationName,
 			})
@@ -81,7 +284,7 @@ func SanityCheckExistingRelationships(
 			}
 
 			// Also check for right sides of tuples.
-			qy, qyErr = rwt.ReverseQueryRelationships(ctx, &v1.SubjectFilter{
+			qy, qyErr = reader.ReverseQueryRelationships(ctx, &v1.SubjectFilter{
 				SubjectType: nsdef.Name,
 				OptionalRelation: &v1.SubjectFilter_RelationFilter{
 					Relation: delta.RelationName,
@@ -97,7 +300,7 @@ func SanityCheckExistingRelationships(
 			}
 
 		case namespace.RelationDirectWildcardTypeRemoved:
-			qy, qyErr := rwt.ReverseQueryRelationships(
+			qy, qyErr := reader.ReverseQueryRelationships(
 				ctx,
 				&v1.SubjectFilter{
 					SubjectType:       delta.WildcardType,
@@ -120,7 +323,7 @@ func SanityCheckExistingRelationships(
 			}
 
 		case namespace.RelationDirectTypeRemoved:
-			qy, qyErr := rwt.ReverseQueryRelationships(
+			qy, qyErr := reader.ReverseQueryRelationships(
 				ctx,
 				&v1.SubjectFilter{
 					SubjectType: delta.DirectType.Namespace,
@@ -0,0 +1,306 @@
+package shared
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/commonerrors"
+	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const numGeneratedDefinitions = 50
+
+// generateSchema generates a schema with a chain of numGeneratedDefinitions resource definitions,
+// each with a parent pointing to the previous one. If brokenIndexes are given, the permissions of
+// the resources at those indexes reference a relation that does not exist.
+func generateSchema(brokenIndexes ...int) string {
+	broken := make(map[int]struct{}, len(brokenIndexes))
+	for _, index := range brokenIndexes {
+		broken[index] = struct{}{}
+	}
+
+	var sb strings.Builder
+	sb.WriteString("definition user {}\n\n")
+	for i := 0; i < numGeneratedDefinitions; i++ {
+		fmt.Fprintf(&sb, "definition resource%d {\n", i)
+		sb.WriteString("\trelation viewer: user\n")
+
+		view := "viewer"
+		if i > 0 {
+			fmt.Fprintf(&sb, "\trelation parent: resource%d\n", i-1)
+			view += " + parent->view"
+		}
+		if _, ok := broken[i]; ok {
+			view += " + missing"
+		}
+
+		fmt.Fprintf(&sb, "\tpermission view = %s\n}\n\n", view)
+	}
+
+	return sb.String()
+}
+
+func compileSchema(require *require.Assertions, schema string) []*core.NamespaceDefinition {
+	empty := ""
+	nsdefs, err := compiler.Compile([]compiler.InputSchema{{
+		Source:       input.Source("schema"),
+		SchemaString: schema,
+	}}, &empty)
+	require.NoError(err)
+	return nsdefs
+}
+
+func TestValidateAndAnnotateNamespaces(t *testing.T) {
+	require := require.New(t)
+
+	nsdefs := compileSchema(require, generateSchema())
+	require.Len(nsdefs, numGeneratedDefinitions+1)
+
+	require.NoError(ValidateAndAnnotateNamespaces(context.Background(), nsdefs, nsdefs))
+
+	for _, nsdef := range nsdefs {
+		for _, rel := range nsdef.Relation {
+			require.NotEmpty(rel.CanonicalCacheKey, "missing cache key for %s#%s", nsdef.Name, rel.Name)
+		}
+	}
+}
+
+func TestValidateAndAnnotateNamespacesReturnsFirstError(t *testing.T) {
+	require := require.New(t)
+
+	schema := generateSchema(12, 37)
+
+	// Find the error returned when validating the definitions one at a time, in order.
+	nsdefs := compileSchema(require, schema)
+	var expectedErr error
+	for _, nsdef := range nsdefs {
+		ts, err := namespace.BuildNamespaceTypeSystemForDefs(nsdef, nsdefs)
+		require.NoError(err)
+
+		if _, err := ts.Validate(context.Background()); err != nil {
+			expectedErr = err
+			break
+		}
+	}
+	require.Error(expectedErr)
+
+	expectedWithSource, ok := commonerrors.AsErrorWithSource(expectedErr)
+	require.True(ok)
+
+	// Validate repeatedly, to ensure the reported error does not depend on scheduling.
+	for i := 0; i < 10; i++ {
+		nsdefs := compileSchema(require, schema)
+
+		err := ValidateAndAnnotateNamespaces(context.Background(), nsdefs, nsdefs)
+		require.Error(err)
+		require.Equal(expectedErr.Error(), err.Error())
+
+		withSource, ok := commonerrors.AsErrorWithSource(err)
+		require.True(ok)
+		require.Equal(expectedWithSource.LineNumber, withSource.LineNumber)
+	}
+}
+
+func BenchmarkValidateAndAnnotateNamespaces(b *testing.B) {
+	require := require.New(b)
+	schema := generateSchema()
+
+	for i := 0; i < b.N; i++ {
+		b.StopTimer()
+		nsdefs := compileSchema(require, schema)
+		b.StartTimer()
+
+		require.NoError(ValidateAndAnnotateNamespaces(context.Background(), nsdefs, nsdefs))
+	}
+}
+
+func TestSafeDeleteNamespace(t *testing.T) {
+	testCases := []struct {
+		name          string
+		toDelete      string
+		relationships []string
+		expectedInUse *NamespaceInUseError
+	}{
+		{
+			"unused",
+			"orphan",
+			nil,
+			nil,
+		},
+		{
+			"referenced by definitions",
+			"user",
+			nil,
+			&NamespaceInUseError{NamespaceName: "user", ReferencingDefinitions: []string{"document", "group"}},
+		},
+		{
+			"relationships exist",
+			"group",
+			[]string{"group:admins#member@user:tom"},
+			&NamespaceInUseError{NamespaceName: "group", HasRelationships: true},
+		},
+		{
+			"relationships reference it",
+			"orphan",
+			[]string{"document:readme#viewer@orphan:someorphan"},
+			&NamespaceInUseError{NamespaceName: "orphan", HasReferencingRelationships: true},
+		},
+		{
+			"all blockers",
+			"user",
+			[]string{"user:tom#friend@user:fred"},
+			&NamespaceInUseError{
+				NamespaceName:               "user",
+				ReferencingDefinitions:      []string{"document", "group"},
+				HasRelationships:            true,
+				HasReferencingRelationships: true,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+
+			ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+
+			nsdefs := compileSchema(require, `
+				definition user {}
+				definition orphan {}
+				definition group { relation member: user }
+				definition document { relation viewer: user }
+			`)
+
+			_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				if err := rwt.WriteNamespaces(nsdefs...); err != nil {
+					return err
+				}
+
+				var updates []*v1.RelationshipUpdate
+				for _, rel := range tc.relationships {
+					updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse(rel))))
+				}
+				return rwt.WriteRelationships(updates)
+			})
+			require.NoError(err)
+
+			deletedAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				return SafeDeleteNamespace(ctx, rwt, tc.toDelete)
+			})
+
+			if tc.expectedInUse != nil {
+				var inUse *NamespaceInUseError
+				require.ErrorAs(err, &inUse)
+				require.Equal(tc.expectedInUse, inUse)
+
+				headRevision, err := ds.HeadRevision(ctx)
+				require.NoError(err)
+				_, _, err = ds.SnapshotReader(headRevision).ReadNamespace(ctx, tc.toDelete)
+				require.NoError(err)
+				return
+			}
+
+			require.NoError(err)
+			_, _, err = ds.SnapshotReader(deletedAt).ReadNamespace(ctx, tc.toDelete)
+			require.ErrorAs(err, &datastore.ErrNamespaceNotFound{})
+		})
+	}
+}
+
+func TestSafeDeleteNamespaces(t *testing.T) {
+	testCases := []struct {
+		name          string
+		toDelete      []string
+		expectedInUse *NamespaceInUseError
+	}{
+		{
+			"referencing pair",
+			[]string{"team", "project"},
+			nil,
+		},
+		{
+			"referencing pair in reverse order",
+			[]string{"project", "team"},
+			nil,
+		},
+		{
+			"referenced from outside",
+			[]string{"user", "group"},
+			&NamespaceInUseError{NamespaceName: "user", ReferencingDefinitions: []string{"document"}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			ctx := context.Background()
+
+			ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+
+			nsdefs := compileSchema(require, `
+				definition user {}
+				definition group { relation member: user }
+				definition document { relation viewer: user }
+				definition team {}
+				definition project { relation member: team }
+			`)
+
+			_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				return rwt.WriteNamespaces(nsdefs...)
+			})
+			require.NoError(err)
+
+			deletedAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				return SafeDeleteNamespaces(ctx, rwt, tc.toDelete...)
+			})
+
+			if tc.expectedInUse != nil {
+				var inUse *NamespaceInUseError
+				require.ErrorAs(err, &inUse)
+				require.Equal(tc.expectedInUse, inUse)
+
+				// Nothing is deleted if any of the definitions is in use.
+				headRevision, err := ds.HeadRevision(ctx)
+				require.NoError(err)
+				for _, nsName := range tc.toDelete {
+					_, _, err = ds.SnapshotReader(headRevision).ReadNamespace(ctx, nsName)
+					require.NoError(err)
+				}
+				return
+			}
+
+			require.NoError(err)
+			for _, nsName := range tc.toDelete {
+				_, _, err = ds.SnapshotReader(deletedAt).ReadNamespace(ctx, nsName)
+				require.ErrorAs(err, &datastore.ErrNamespaceNotFound{})
+			}
+		})
+	}
+}
+
+func TestSafeDeleteUnknownNamespace(t *testing.T) {
+	require := require.New(t)
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+
+	_, err = ds.ReadWriteTx(context.Background(), func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return SafeDeleteNamespace(ctx, rwt, "unknown")
+	})
+	require.ErrorAs(err, &datastore.ErrNamespaceNotFound{})
+}
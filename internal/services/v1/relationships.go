@@ -3,20 +3,23 @@ package v1
 import (
 	"context"
 	"errors"
+	"strconv"
 
+	"github.com/authzed/authzed-go/pkg/responsemeta"
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	grpcmw "github.com/grpc-ecosystem/go-grpc-middleware"
-	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/validator"
 	"github.com/jzelinskie/stringz"
 	"github.com/rs/zerolog/log"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/internal/dispatch"
 	"github.com/authzed/spicedb/internal/graph"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/handwrittenvalidation"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/middleware/validation"
 	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/internal/services/serviceerrors"
 	"github.com/authzed/spicedb/internal/services/shared"
@@ -28,35 +31,59 @@ import (
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
+// EffectiveTouches is the response header metadata key containing the number of touches in a
+// WriteRelationships request which created a relationship, rather than finding it already living.
+const EffectiveTouches responsemeta.ResponseMetadataHeaderKey = "io.spicedb.respmeta.effectivetouches"
+
 // NewPermissionsServer creates a PermissionsServiceServer instance.
 func NewPermissionsServer(
 	dispatch dispatch.Dispatcher,
 	defaultDepth uint32,
+	options ...PermissionsServerOption,
 ) v1.PermissionsServiceServer {
-	return &permissionServer{
-		dispatch:     dispatch,
-		defaultDepth: defaultDepth,
+	ps := &permissionServer{
+		dispatch:         dispatch,
+		defaultDepth:     defaultDepth,
+		identifierLimits: tuple.DefaultIdentifierLimits,
 		WithServiceSpecificInterceptors: shared.WithServiceSpecificInterceptors{
 			Unary: grpcmw.ChainUnaryServer(
-				grpcvalidate.UnaryServerInterceptor(),
+				validation.NewValidationInterceptor(),
 				handwrittenvalidation.UnaryServerInterceptor,
 				usagemetrics.UnaryServerInterceptor(),
 			),
 			Stream: grpcmw.ChainStreamServer(
-				grpcvalidate.StreamServerInterceptor(),
+				validation.NewStreamValidationInterceptor(),
 				handwrittenvalidation.StreamServerInterceptor,
 				usagemetrics.StreamServerInterceptor(),
 			),
 		},
 	}
+
+	for _, option := range options {
+		option(ps)
+	}
+
+	return ps
+}
+
+// PermissionsServerOption is an option for configuring the permissions server.
+type PermissionsServerOption func(ps *permissionServer)
+
+// WithIdentifierLimits sets the maximum lengths of the identifiers of written relationships.
+// Defaults to tuple.DefaultIdentifierLimits.
+func WithIdentifierLimits(limits tuple.IdentifierLimits) PermissionsServerOption {
+	return func(ps *permissionServer) {
+		ps.identifierLimits = limits
+	}
 }
 
 type permissionServer struct {
 	v1.UnimplementedPermissionsServiceServer
 	shared.WithServiceSpecificInterceptors
 
-	dispatch     dispatch.Dispatcher
-	defaultDepth uint32
+	dispatch         dispatch.Dispatcher
+	defaultDepth     uint32
+	identifierLimits tuple.IdentifierLimits
 }
 
 func (ps *permissionServer) checkFilterComponent(ctx context.Context, objectType, optionalRelation string, ds datastore.Reader) error {
@@ -96,7 +123,9 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 		DispatchCount: 1,
 	})
 
-	tupleIterator, err := ds.QueryRelationships(ctx, req.RelationshipFilter)
+	// Relationships are sent as they are read, without other queries made in between, so they
+	// can be streamed from the datastore rather than loaded all at once.
+	tupleIterator, err := ds.QueryRelationships(ctx, req.RelationshipFilter, options.WithStreamResults(true))
 	if err != nil {
 		return rewritePermissionsError(ctx, err)
 	}
@@ -132,7 +161,7 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 		}
 	}
 	if tupleIterator.Err() != nil {
-		return status.Errorf(codes.Internal, "error when reading tuples: %s", err)
+		return status.Errorf(codes.Internal, "error when reading tuples: %s", tupleIterator.Err())
 	}
 
 	return nil
@@ -141,6 +170,7 @@ func (ps *permissionServer) ReadRelationships(req *v1.ReadRelationshipsRequest,
 func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.WriteRelationshipsRequest) (*v1.WriteRelationshipsResponse, error) {
 	ds := datastoremw.MustFromContext(ctx)
 
+	var effectiveTouches uint64
 	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
 		for _, precond := range req.OptionalPreconditions {
 			if err := ps.checkFilterNamespaces(ctx, precond.Filter, rwt); err != nil {
@@ -148,11 +178,7 @@ func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.Writ
 			}
 		}
 		for _, update := range req.Updates {
-			if err := tuple.ValidateResourceID(update.Relationship.Resource.ObjectId); err != nil {
-				return err
-			}
-
-			if err := tuple.ValidateSubjectID(update.Relationship.Subject.Object.ObjectId); err != nil {
+			if err := ps.identifierLimits.ValidateRelationship(update.Relationship); err != nil {
 				return err
 			}
 
@@ -239,12 +265,24 @@ func (ps *permissionServer) WriteRelationships(ctx context.Context, req *v1.Writ
 			return err
 		}
 
-		return rwt.WriteRelationships(req.Updates)
+		if err := rwt.WriteRelationships(req.Updates); err != nil {
+			return err
+		}
+
+		effectiveTouches = rwt.EffectiveTouches()
+		return nil
 	})
 	if err != nil {
 		return nil, rewritePermissionsError(ctx, err)
 	}
 
+	err = responsemeta.SetResponseHeaderMetadata(ctx, map[responsemeta.ResponseMetadataHeaderKey]string{
+		EffectiveTouches: strconv.FormatUint(effectiveTouches, 10),
+	})
+	if err != nil {
+		log.Ctx(ctx).Err(err).Msg("could not report metadata")
+	}
+
 	return &v1.WriteRelationshipsResponse{
 		WrittenAt: zedtoken.NewFromRevision(revision),
 	}, nil
@@ -267,7 +305,8 @@ func (ps *permissionServer) DeleteRelationships(ctx context.Context, req *v1.Del
 			return err
 		}
 
-		return rwt.DeleteRelationships(req.RelationshipFilter)
+		_, err := rwt.DeleteRelationships(req.RelationshipFilter)
+		return err
 	})
 	if err != nil {
 		return nil, rewritePermissionsError(ctx, err)
@@ -293,9 +332,15 @@ func rewritePermissionsError(ctx context.Context, err error) error {
 	case errors.As(err, &graph.ErrInvalidArgument{}):
 		return status.Errorf(codes.InvalidArgument, "%s", err)
 
+	case errors.As(err, &tuple.ErrInvalidRelationshipField{}):
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+
 	case errors.As(err, &graph.ErrRequestCanceled{}):
 		return status.Errorf(codes.Canceled, "request canceled: %s", err)
 
+	case errors.As(err, &graph.ErrExpandTooLarge{}):
+		return status.Errorf(codes.ResourceExhausted, "%s", err)
+
 	case errors.As(err, &datastore.ErrInvalidRevision{}):
 		return status.Errorf(codes.OutOfRange, "invalid zedtoken: %s", err)
 
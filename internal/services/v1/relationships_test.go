@@ -12,12 +12,18 @@ import (
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/grpcutil"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/dispatch/graph"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	v1svc "github.com/authzed/spicedb/internal/services/v1"
 	tf "github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 	"github.com/authzed/spicedb/pkg/zedtoken"
 )
@@ -331,6 +337,41 @@ func TestWriteRelationships(t *testing.T) {
 	require.ErrorIs(err, io.EOF)
 }
 
+func TestWriteRelationshipsEffectiveTouches(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, tf.StandardDatastoreWithData)
+	client := v1.NewPermissionsServiceClient(conn)
+	t.Cleanup(cleanup)
+
+	existing := tuple.MustParse(tf.StandardTuples[0])
+	toWrite := tuple.MustParse("document:totallynew#parent@folder:plans")
+
+	touch := func(tpls ...*core.RelationTuple) []string {
+		updates := make([]*v1.RelationshipUpdate, 0, len(tpls))
+		for _, tpl := range tpls {
+			updates = append(updates, &v1.RelationshipUpdate{
+				Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+				Relationship: tuple.MustToRelationship(tpl),
+			})
+		}
+
+		var header metadata.MD
+		resp, err := client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+			Updates: updates,
+		}, grpc.Header(&header))
+		require.NoError(err)
+		require.NotNil(resp.WrittenAt)
+		return header.Get(string(v1svc.EffectiveTouches))
+	}
+
+	// Only the touch of the relationship which does not yet exist is effective.
+	require.Equal([]string{"1"}, touch(existing, toWrite))
+
+	// Both relationships are now living, so nothing is effective.
+	require.Equal([]string{"0"}, touch(existing, toWrite))
+}
+
 func precondFilter(resType, resID, relation, subType, subID string, subRel *string) *v1.RelationshipFilter {
 	var optionalRel *v1.SubjectFilter_RelationFilter
 	if subRel != nil {
@@ -845,3 +886,62 @@ func standardTuplesWithout(without map[string]struct{}) map[string]struct{} {
 	}
 	return out
 }
+
+func TestWriteRelationshipsIdentifierLimits(t *testing.T) {
+	testCases := []struct {
+		name          string
+		relationship  *v1.Relationship
+		expectedCode  codes.Code
+		errorContains string
+	}{
+		{
+			"within limits",
+			rel("document", "newdoc", "viewer", "user", "tom", ""),
+			codes.OK,
+			"",
+		},
+		{
+			"resource id too long",
+			rel("document", "averylongdocid", "viewer", "user", "tom", ""),
+			codes.InvalidArgument,
+			"invalid resource object id of relationship `document:averylongdocid#viewer@user:tom`",
+		},
+		{
+			"subject id too long",
+			rel("document", "newdoc", "viewer", "user", "averylonguserid", ""),
+			codes.InvalidArgument,
+			"invalid subject object id of relationship `document:newdoc#viewer@user:averylonguserid`",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			emptyDS, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+			ds, _ := tf.StandardDatastoreWithSchema(emptyDS, require)
+			ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+			srv := v1svc.NewPermissionsServer(graph.NewLocalOnlyDispatcher(), 50, v1svc.WithIdentifierLimits(tuple.IdentifierLimits{
+				MaxNamespaceLength: 64,
+				MaxObjectIDLength:  10,
+				MaxRelationLength:  32,
+			}))
+
+			_, err = srv.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+				Updates: []*v1.RelationshipUpdate{{
+					Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+					Relationship: tc.relationship,
+				}},
+			})
+			if tc.expectedCode == codes.OK {
+				require.NoError(err)
+				return
+			}
+
+			grpcutil.RequireStatus(t, tc.expectedCode, err)
+			require.Contains(err.Error(), tc.errorContains)
+		})
+	}
+}
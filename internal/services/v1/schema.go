@@ -6,7 +6,6 @@ import (
 	"strings"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
-	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/validator"
 	"github.com/rs/zerolog/log"
 	"github.com/scylladb/go-set/strset"
 	"google.golang.org/grpc/codes"
@@ -15,7 +14,7 @@ import (
 	"github.com/authzed/spicedb/internal/middleware/consistency"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
-	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/middleware/validation"
 	"github.com/authzed/spicedb/internal/services/serviceerrors"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/internal/sharederrors"
@@ -32,8 +31,8 @@ import (
 func NewSchemaServer() v1.SchemaServiceServer {
 	return &schemaServer{
 		WithServiceSpecificInterceptors: shared.WithServiceSpecificInterceptors{
-			Unary:  grpcvalidate.UnaryServerInterceptor(),
-			Stream: grpcvalidate.StreamServerInterceptor(),
+			Unary:  validation.NewValidationInterceptor(),
+			Stream: validation.NewStreamValidationInterceptor(),
 		},
 	}
 }
@@ -90,22 +89,12 @@ func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaReque
 	log.Ctx(ctx).Trace().Interface("namespaceDefinitions", nsdefs).Msg("compiled namespace definitions")
 
 	// Do as much validation as we can before talking to the datastore
+	if err := shared.ValidateAndAnnotateNamespaces(ctx, nsdefs, nsdefs); err != nil {
+		return nil, rewriteSchemaError(ctx, err)
+	}
+
 	newDefs := strset.NewWithSize(len(nsdefs))
 	for _, nsdef := range nsdefs {
-		ts, err := namespace.BuildNamespaceTypeSystemForDefs(nsdef, nsdefs)
-		if err != nil {
-			return nil, rewriteSchemaError(ctx, err)
-		}
-
-		vts, err := ts.Validate(ctx)
-		if err != nil {
-			return nil, rewriteSchemaError(ctx, err)
-		}
-
-		if err := namespace.AnnotateNamespace(vts); err != nil {
-			return nil, rewriteSchemaError(ctx, err)
-		}
-
 		newDefs.Add(nsdef.Name)
 	}
 
@@ -132,18 +121,6 @@ func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaReque
 		}
 		log.Ctx(ctx).Trace().Interface("namespaceDefinitions", nsdefs).Msg("validated namespace definitions")
 
-		// Ensure that deleting namespaces will not result in any relationships left without associated
-		// schema.
-		removed := strset.Difference(existing, newDefs)
-		var checkRelErr error
-		removed.Each(func(nsdefName string) bool {
-			checkRelErr = shared.EnsureNoRelationshipsExist(ctx, rwt, nsdefName)
-			return checkRelErr == nil
-		})
-		if checkRelErr != nil {
-			return checkRelErr
-		}
-
 		// Write the new namespaces.
 		if err := rwt.WriteNamespaces(nsdefs...); err != nil {
 			return err
@@ -153,14 +130,12 @@ func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaReque
 			DispatchCount: uint32(len(nsdefs)),
 		})
 
-		// Delete the removed namespaces.
-		var removeErr error
-		removed.Each(func(nsdefName string) bool {
-			removeErr = rwt.DeleteNamespace(nsdefName)
-			return removeErr == nil
-		})
-		if removeErr != nil {
-			return removeErr
+		// Delete the removed namespaces, ensuring that this will not result in any relationships
+		// or definitions left referencing them. The new namespaces have already been written, so
+		// they are the definitions checked for references.
+		removed := strset.Difference(existing, newDefs)
+		if err := shared.SafeDeleteNamespaces(ctx, rwt, removed.List()...); err != nil {
+			return err
 		}
 
 		usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
@@ -184,7 +159,9 @@ func (ss *schemaServer) WriteSchema(ctx context.Context, in *v1.WriteSchemaReque
 
 func rewriteSchemaError(ctx context.Context, err error) error {
 	var nsNotFoundError sharederrors.UnknownNamespaceError
+	var relNotFoundError sharederrors.UnknownRelationError
 	var errWithContext compiler.ErrorWithContext
+	var inUseError *shared.NamespaceInUseError
 
 	errWithSource, ok := commonerrors.AsErrorWithSource(err)
 	if ok {
@@ -194,8 +171,12 @@ func rewriteSchemaError(ctx context.Context, err error) error {
 	switch {
 	case errors.As(err, &nsNotFoundError):
 		return status.Errorf(codes.NotFound, "Object Definition `%s` not found", nsNotFoundError.NotFoundNamespaceName())
+	case errors.As(err, &relNotFoundError):
+		return status.Errorf(codes.InvalidArgument, "Relation/Permission `%s` not found under Object Definition `%s`", relNotFoundError.NotFoundRelationName(), relNotFoundError.NamespaceName())
 	case errors.As(err, &errWithContext):
 		return status.Errorf(codes.InvalidArgument, "%s", err)
+	case errors.As(err, &inUseError):
+		return status.Errorf(codes.InvalidArgument, "%s", inUseError)
 	case errors.As(err, &datastore.ErrReadOnly{}):
 		return serviceerrors.ErrServiceReadOnly
 	default:
@@ -3,6 +3,7 @@ package v1_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/grpcutil"
@@ -12,6 +13,7 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/memdb"
 	tf "github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
+	"github.com/authzed/spicedb/pkg/datastore"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
@@ -59,6 +61,42 @@ func TestSchemaWriteAndReadBack(t *testing.T) {
 	require.Equal(t, userSchema, readback.SchemaText)
 }
 
+func TestSchemaWriteNotifiesNamespaceWatchers(t *testing.T) {
+	conn, cleanup, ds, revision := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1.NewSchemaServiceClient(conn)
+
+	watcher, ok := ds.(datastore.NamespaceWatcher)
+	require.True(t, ok)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := watcher.WatchNamespaces(ctx, revision)
+
+	_, err := client.WriteSchema(ctx, &v1.WriteSchemaRequest{
+		Schema: `definition example/user {}`,
+	})
+	require.NoError(t, err)
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(t, err)
+	_, writtenRevision, err := ds.SnapshotReader(headRevision).ReadNamespace(ctx, "example/user")
+	require.NoError(t, err)
+
+	select {
+	case change, ok := <-changes:
+		if !ok {
+			require.Fail(t, "watch failed", "%s", <-errchan)
+		}
+		require.Equal(t, "example/user", change.Name)
+		require.True(t, writtenRevision.Equal(change.Revision))
+		require.Equal(t, "example/user", change.Definition.Name)
+	case <-time.After(5 * time.Second):
+		require.Fail(t, "Timed out waiting for namespace change")
+	}
+}
+
 func TestSchemaDeleteRelation(t *testing.T) {
 	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
 	t.Cleanup(cleanup)
@@ -151,6 +189,7 @@ func TestSchemaDeleteDefinition(t *testing.T) {
 		Schema: `definition example/user {}`,
 	})
 	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.Equal(t, "rpc error: code = InvalidArgument desc = cannot delete Object Definition `example/document`, as a Relationship exists under it", err.Error())
 
 	// Delete the relationship.
 	_, err = v1client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
@@ -172,6 +211,36 @@ func TestSchemaDeleteDefinition(t *testing.T) {
 	require.Equal(t, `definition example/user {}`, readback.SchemaText)
 }
 
+func TestSchemaDeleteReferencingDefinitions(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1.NewSchemaServiceClient(conn)
+
+	_, err := client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/group {
+			relation member: example/user
+		}
+
+		definition example/document {
+			relation viewer: example/user | example/group#member
+		}`,
+	})
+	require.NoError(t, err)
+
+	// Removing the document and group together succeeds, even though the document references
+	// the group, whatever the order in which they are deleted.
+	_, err = client.WriteSchema(context.Background(), &v1.WriteSchemaRequest{
+		Schema: `definition example/user {}`,
+	})
+	require.NoError(t, err)
+
+	readback, err := client.ReadSchema(context.Background(), &v1.ReadSchemaRequest{})
+	require.NoError(t, err)
+	require.Equal(t, `definition example/user {}`, readback.SchemaText)
+}
+
 func TestSchemaRemoveWildcard(t *testing.T) {
 	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, true, tf.EmptyDatastore)
 	t.Cleanup(cleanup)
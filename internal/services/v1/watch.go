@@ -4,13 +4,14 @@ import (
 	"errors"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
-	grpcvalidate "github.com/grpc-ecosystem/go-grpc-middleware/validator"
 	"github.com/shopspring/decimal"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
+	"github.com/authzed/spicedb/internal/datastore/options"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/middleware/validation"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/pkg/datastore"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -28,7 +29,7 @@ type watchServer struct {
 func NewWatchServer() v1.WatchServiceServer {
 	s := &watchServer{
 		WithStreamServiceSpecificInterceptor: shared.WithStreamServiceSpecificInterceptor{
-			Stream: grpcvalidate.StreamServerInterceptor(),
+			Stream: validation.NewStreamValidationInterceptor(),
 		},
 	}
 	return s
@@ -63,27 +64,54 @@ func (ws *watchServer) Watch(req *v1.WatchRequest, stream v1.WatchService_WatchS
 		DispatchCount: 1,
 	})
 
-	updates, errchan := ds.Watch(ctx, afterRevision)
+	sendUpdate := func(update *datastore.RevisionChanges) error {
+		filtered := filterUpdates(objectTypesMap, update.Changes)
+		if len(filtered) == 0 {
+			return nil
+		}
+
+		if err := stream.Send(&v1.WatchResponse{
+			Updates:        filtered,
+			ChangesThrough: zedtoken.NewFromRevision(update.Revision),
+		}); err != nil {
+			return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
+		}
+		return nil
+	}
+
+	updates, errchan := ds.Watch(ctx, afterRevision, options.SetNamespaces(req.GetOptionalObjectTypes()))
 	for {
 		select {
 		case update, ok := <-updates:
 			if ok {
-				filtered := filterUpdates(objectTypesMap, update.Changes)
-				if len(filtered) > 0 {
-					if err := stream.Send(&v1.WatchResponse{
-						Updates:        filtered,
-						ChangesThrough: zedtoken.NewFromRevision(update.Revision),
-					}); err != nil {
-						return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
-					}
+				if err := sendUpdate(update); err != nil {
+					return err
 				}
 			}
 		case err := <-errchan:
+			var closingErr datastore.ErrWatchClosing
 			switch {
+			case errors.As(err, &closingErr):
+				// The changes delivered before closing are still sent, after which the watch
+				// can be resumed from the last revision.
+				for update := range updates {
+					if err := sendUpdate(update); err != nil {
+						return err
+					}
+				}
+				return status.Errorf(
+					codes.Unavailable,
+					"watch closed as the server is shutting down, resume from %s",
+					zedtoken.NewFromRevision(closingErr.LastRevision()).Token,
+				)
 			case errors.As(err, &datastore.ErrWatchCanceled{}):
 				return status.Errorf(codes.Canceled, "watch canceled by user: %s", err)
 			case errors.As(err, &datastore.ErrWatchDisconnected{}):
 				return status.Errorf(codes.ResourceExhausted, "watch disconnected: %s", err)
+			case errors.As(err, &datastore.ErrWatchTimedOut{}):
+				return status.Errorf(codes.DeadlineExceeded, "watch timed out: %s", err)
+			case errors.As(err, &datastore.ErrWatchRevisionTooOld{}):
+				return status.Errorf(codes.FailedPrecondition, "watch start revision too old: %s", err)
 			default:
 				return status.Errorf(codes.Internal, "watch error: %s", err)
 			}
@@ -3,20 +3,23 @@ package v1alpha1
 import (
 	"context"
 	"errors"
+	"fmt"
+	"sort"
+	"strings"
 
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/authzed-go/proto/authzed/api/v1alpha1"
-	"github.com/authzed/grpcutil"
-	grpcmw "github.com/grpc-ecosystem/go-grpc-middleware"
 	"github.com/rs/zerolog/log"
 	"github.com/scylladb/go-set/strset"
 	"github.com/shopspring/decimal"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 
 	"github.com/authzed/spicedb/internal/middleware/consistency"
 	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
 	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
-	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/middleware/validation"
 	"github.com/authzed/spicedb/internal/services/serviceerrors"
 	"github.com/authzed/spicedb/internal/services/shared"
 	"github.com/authzed/spicedb/internal/sharederrors"
@@ -25,9 +28,11 @@ import (
 	nspkg "github.com/authzed/spicedb/pkg/namespace"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
 	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
 	"github.com/authzed/spicedb/pkg/schemadsl/generator"
 	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
 // PrefixRequiredOption is an option to the schema server indicating whether
@@ -38,6 +43,10 @@ type writeSchemaPreconditionFailure struct {
 	error
 }
 
+type duplicateDefinitionError struct {
+	error
+}
+
 const (
 	// PrefixNotRequired indicates that prefixes are not required.
 	PrefixNotRequired PrefixRequiredOption = iota
@@ -46,41 +55,124 @@ const (
 	PrefixRequired
 )
 
+// DefaultMaxSchemaBytes is the default maximum size, in bytes, of a schema that can be written.
+const DefaultMaxSchemaBytes = 1 << 20
+
+// MaxWriteSchemaRequestBytes is the largest schema accepted by the validation of WriteSchema
+// requests, which is defined by the authzed API rather than configurable here. Schemas larger
+// than it, up to the maximum schema size, can only be written by WriteSchemaMulti or
+// WriteSchemaStream.
+const MaxWriteSchemaRequestBytes = 256 << 10
+
 type schemaServiceServer struct {
 	v1alpha1.UnimplementedSchemaServiceServer
-	shared.WithUnaryServiceSpecificInterceptor
+	schemav1alpha1.UnimplementedExperimentalSchemaServiceServer
+	shared.WithServiceSpecificInterceptors
 
 	prefixRequired PrefixRequiredOption
+	maxSchemaBytes int
+}
+
+// SchemaServerOption is an option for configuring the schema server.
+type SchemaServerOption func(ss *schemaServiceServer)
+
+// WithMaxSchemaBytes sets the maximum size, in bytes, of a schema that can be written. Larger
+// schemas are rejected before being compiled. For WriteSchemaMulti and WriteSchemaStream, the
+// limit applies to the combined size of every schema written. Defaults to DefaultMaxSchemaBytes.
+// A single WriteSchema request is additionally limited to MaxWriteSchemaRequestBytes.
+func WithMaxSchemaBytes(n int) SchemaServerOption {
+	return func(ss *schemaServiceServer) {
+		ss.maxSchemaBytes = n
+	}
 }
 
 // NewSchemaServer returns an new instance of a server that implements
 // authzed.api.v1alpha1.SchemaService.
-func NewSchemaServer(prefixRequired PrefixRequiredOption) v1alpha1.SchemaServiceServer {
-	return &schemaServiceServer{
+func NewSchemaServer(prefixRequired PrefixRequiredOption, options ...SchemaServerOption) v1alpha1.SchemaServiceServer {
+	return newSchemaServer(prefixRequired, options...)
+}
+
+// NewExperimentalSchemaServer returns a new instance of a server that implements
+// schema.v1alpha1.ExperimentalSchemaService, which provides the schema operations that are not
+// part of authzed.api.v1alpha1.SchemaService. It takes the same options as NewSchemaServer, and
+// should be given the same options as the schema server it is served alongside.
+func NewExperimentalSchemaServer(prefixRequired PrefixRequiredOption, options ...SchemaServerOption) schemav1alpha1.ExperimentalSchemaServiceServer {
+	return newSchemaServer(prefixRequired, options...)
+}
+
+func newSchemaServer(prefixRequired PrefixRequiredOption, options ...SchemaServerOption) *schemaServiceServer {
+	ss := &schemaServiceServer{
 		prefixRequired: prefixRequired,
-		WithUnaryServiceSpecificInterceptor: shared.WithUnaryServiceSpecificInterceptor{
-			Unary: grpcmw.ChainUnaryServer(grpcutil.DefaultUnaryMiddleware...),
+		maxSchemaBytes: DefaultMaxSchemaBytes,
+		WithServiceSpecificInterceptors: shared.WithServiceSpecificInterceptors{
+			Unary:  validation.NewValidationInterceptor(),
+			Stream: validation.NewStreamValidationInterceptor(),
 		},
 	}
+
+	for _, option := range options {
+		option(ss)
+	}
+
+	return ss
 }
 
 func (ss *schemaServiceServer) ReadSchema(ctx context.Context, in *v1alpha1.ReadSchemaRequest) (*v1alpha1.ReadSchemaResponse, error) {
 	headRevision, _ := consistency.MustRevisionFromContext(ctx)
-	ds := datastoremw.MustFromContext(ctx).SnapshotReader(headRevision)
+	return ss.readSchema(ctx, in.GetObjectDefinitionsNames(), headRevision)
+}
+
+// ReadSchemaAtRevision reads the requested object definitions as they were at the revision,
+// which must still be within the datastore's garbage collection window.
+func (ss *schemaServiceServer) ReadSchemaAtRevision(ctx context.Context, in *schemav1alpha1.ReadSchemaAtRevisionRequest) (*schemav1alpha1.ReadSchemaAtRevisionResponse, error) {
+	revision, err := zedtoken.DecodeRevision(&v1.ZedToken{Token: in.GetAtRevision()})
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "failed to decode revision: %s", err)
+	}
 
-	numRequested := len(in.GetObjectDefinitionsNames())
+	if err := datastoremw.MustFromContext(ctx).CheckRevision(ctx, revision); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
 
-	objectDefs := make([]string, 0, numRequested)
+	resp, err := ss.readSchema(ctx, in.GetObjectDefinitionsNames(), revision)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schemav1alpha1.ReadSchemaAtRevisionResponse{
+		ObjectDefinitions:           resp.ObjectDefinitions,
+		ComputedDefinitionsRevision: resp.ComputedDefinitionsRevision,
+	}, nil
+}
+
+func (ss *schemaServiceServer) readSchema(ctx context.Context, objectDefNames []string, revision datastore.Revision) (*v1alpha1.ReadSchemaResponse, error) {
+	ds := datastoremw.MustFromContext(ctx).SnapshotReader(revision)
+
+	numRequested := len(objectDefNames)
+
+	nsdefs := make([]*core.NamespaceDefinition, 0, numRequested)
 	createdRevisions := make(map[string]datastore.Revision, numRequested)
-	for _, objectDefName := range in.GetObjectDefinitionsNames() {
+	for _, objectDefName := range objectDefNames {
 		found, createdAt, err := ds.ReadNamespace(ctx, objectDefName)
 		if err != nil {
 			return nil, rewriteError(ctx, err)
 		}
 
 		createdRevisions[objectDefName] = createdAt
+		nsdefs = append(nsdefs, found)
+	}
 
-		objectDef, _ := generator.GenerateSource(found)
+	ordered, err := orderByDependencies(nsdefs)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	objectDefs := make([]string, 0, numRequested)
+	for _, found := range ordered {
+		objectDef, ok := nspkg.GetDefinitionSource(found)
+		if !ok {
+			objectDef, _ = generator.GenerateSource(found)
+		}
 		objectDefs = append(objectDefs, objectDef)
 	}
 
@@ -100,74 +192,36 @@ func (ss *schemaServiceServer) ReadSchema(ctx context.Context, in *v1alpha1.Read
 }
 
 func (ss *schemaServiceServer) WriteSchema(ctx context.Context, in *v1alpha1.WriteSchemaRequest) (*v1alpha1.WriteSchemaResponse, error) {
-	log.Ctx(ctx).Trace().Str("schema", in.GetSchema()).Msg("requested Schema to be written")
-	ds := datastoremw.MustFromContext(ctx)
-
-	inputSchema := compiler.InputSchema{
-		Source:       input.Source("schema"),
-		SchemaString: in.GetSchema(),
+	if err := ss.checkSchemaSize(in.GetSchema()); err != nil {
+		return nil, err
 	}
 
-	var prefix *string
-	if ss.prefixRequired == PrefixNotRequired {
-		empty := ""
-		prefix = &empty
-	}
+	log.Ctx(ctx).Trace().Str("schema", in.GetSchema()).Msg("requested Schema to be written")
 
-	nsdefs, err := compiler.Compile([]compiler.InputSchema{inputSchema}, prefix)
+	nsdefs, err := ss.compileSchema(in.GetSchema())
 	if err != nil {
 		return nil, rewriteError(ctx, err)
 	}
 
-	liveDefs := append([]*core.NamespaceDefinition{}, nsdefs...)
-	liveDefNames := strset.New()
-	for _, nsdef := range nsdefs {
-		liveDefNames.Add(nsdef.Name)
-	}
-
 	log.Ctx(ctx).Trace().Interface("namespaceDefinitions", nsdefs).Msg("compiled namespace definitions")
 
+	return ss.writeDefinitions(ctx, nsdefs, in.OptionalDefinitionsRevisionPrecondition)
+}
+
+// writeDefinitions writes the compiled namespace definitions, after validating them and checking
+// the optional definitions revision precondition.
+func (ss *schemaServiceServer) writeDefinitions(ctx context.Context, nsdefs []*core.NamespaceDefinition, precondition string) (*v1alpha1.WriteSchemaResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
 	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
-		// Build a map of existing definitions
-		existingDefs, err := rwt.ListNamespaces(ctx)
-		if err != nil {
+		if err := validateNamespaces(ctx, rwt, nsdefs); err != nil {
 			return err
 		}
-
-		existingDefMap := make(map[string]*core.NamespaceDefinition, len(existingDefs))
-		for _, existingDef := range existingDefs {
-			existingDefMap[existingDef.Name] = existingDef
-			if !liveDefNames.Has(existingDef.Name) {
-				liveDefNames.Add(existingDef.Name)
-				liveDefs = append(liveDefs, existingDef)
-			}
-		}
-
-		for _, nsdef := range nsdefs {
-			ts, err := namespace.BuildNamespaceTypeSystemForDefs(nsdef, liveDefs)
-			if err != nil {
-				return err
-			}
-
-			vts, err := ts.Validate(ctx)
-			if err != nil {
-				return err
-			}
-
-			if err := namespace.AnnotateNamespace(vts); err != nil {
-				return err
-			}
-
-			if err := shared.SanityCheckExistingRelationships(ctx, rwt, nsdef, existingDefMap); err != nil {
-				return err
-			}
-		}
 		log.Ctx(ctx).Trace().Interface("namespaceDefinitions", nsdefs).Msg("validated namespace definitions")
 
 		// If a precondition was given, decode it, and verify that none of the namespaces specified
 		// have changed in any way.
-		if in.OptionalDefinitionsRevisionPrecondition != "" {
-			decoded, err := nspkg.DecodeV1Alpha1Revision(in.OptionalDefinitionsRevisionPrecondition)
+		if precondition != "" {
+			decoded, err := nspkg.DecodeV1Alpha1Revision(precondition)
 			if err != nil {
 				return err
 			}
@@ -229,10 +283,265 @@ func (ss *schemaServiceServer) WriteSchema(ctx context.Context, in *v1alpha1.Wri
 	}, nil
 }
 
+// orderByDependencies orders the definitions so that each definition comes after the definitions
+// it references as allowed subject types, and otherwise keeps their order. A cycle of references
+// between definitions has no such order, and is returned as a definitionCycleError. References of
+// a definition to itself are not cycles.
+func orderByDependencies(nsdefs []*core.NamespaceDefinition) ([]*core.NamespaceDefinition, error) {
+	byName := make(map[string]*core.NamespaceDefinition, len(nsdefs))
+	for _, nsdef := range nsdefs {
+		byName[nsdef.Name] = nsdef
+	}
+
+	const (
+		visiting = iota + 1
+		visited
+	)
+	states := make(map[string]int, len(nsdefs))
+	ordered := make([]*core.NamespaceDefinition, 0, len(nsdefs))
+
+	// path holds the names of the definitions being visited, in the order they were reached.
+	var path []string
+
+	var visit func(nsdef *core.NamespaceDefinition) error
+	visit = func(nsdef *core.NamespaceDefinition) error {
+		states[nsdef.Name] = visiting
+		path = append(path, nsdef.Name)
+		for _, relation := range nsdef.Relation {
+			for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+				referenced, ok := byName[allowed.Namespace]
+				if !ok || referenced.Name == nsdef.Name {
+					continue
+				}
+
+				switch states[referenced.Name] {
+				case visiting:
+					for index, name := range path {
+						if name == referenced.Name {
+							cycle := append(append([]string{}, path[index:]...), referenced.Name)
+							return definitionCycleError{cycle}
+						}
+					}
+				case visited:
+				default:
+					if err := visit(referenced); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		path = path[:len(path)-1]
+		states[nsdef.Name] = visited
+		ordered = append(ordered, nsdef)
+		return nil
+	}
+
+	for _, nsdef := range nsdefs {
+		if states[nsdef.Name] == 0 {
+			if err := visit(nsdef); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return ordered, nil
+}
+
+// definitionCycleError is returned when definitions reference each other in a cycle, and so have
+// no order in which each comes after the definitions it references.
+type definitionCycleError struct {
+	cycle []string
+}
+
+func (err definitionCycleError) Error() string {
+	return fmt.Sprintf("object definitions reference each other in a cycle: %s", strings.Join(err.cycle, " -> "))
+}
+
+// ValidateSchema runs all of the validation that WriteSchema does against the current schema and
+// relationships, without writing anything, returning the same errors WriteSchema would.
+func (ss *schemaServiceServer) ValidateSchema(ctx context.Context, in *schemav1alpha1.ValidateSchemaRequest) (*schemav1alpha1.ValidateSchemaResponse, error) {
+	if err := ss.checkSchemaSize(in.GetSchema()); err != nil {
+		return nil, err
+	}
+
+	nsdefs, err := ss.compileSchema(in.GetSchema())
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	reader, _, err := datastore.HeadSnapshotReader(ctx, datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	if err := validateNamespaces(ctx, reader, nsdefs); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	return &schemav1alpha1.ValidateSchemaResponse{}, nil
+}
+
+// ListObjectDefinitions returns a summary of every object definition in the schema at the head
+// revision, sorted by name.
+func (ss *schemaServiceServer) ListObjectDefinitions(ctx context.Context, _ *schemav1alpha1.ListObjectDefinitionsRequest) (*schemav1alpha1.ListObjectDefinitionsResponse, error) {
+	reader, _, err := datastore.HeadSnapshotReader(ctx, datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	nsdefs, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	summaries := make([]*schemav1alpha1.ObjectDefinitionSummary, 0, len(nsdefs))
+	for _, nsdef := range nsdefs {
+		relations := make([]string, 0, len(nsdef.Relation))
+		for _, relation := range nsdef.Relation {
+			relations = append(relations, relation.Name)
+		}
+
+		summaries = append(summaries, &schemav1alpha1.ObjectDefinitionSummary{
+			Name:      nsdef.Name,
+			Relations: relations,
+		})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool {
+		return summaries[i].Name < summaries[j].Name
+	})
+
+	return &schemav1alpha1.ListObjectDefinitionsResponse{ObjectDefinitions: summaries}, nil
+}
+
+// validateNamespaces validates and annotates the namespaces against the existing namespaces,
+// and ensures that writing them would not orphan any existing relationships. If more than one
+// namespace is invalid, a shared.InvalidDefinitionsError is returned. Any other error, such as
+// that of a failed datastore query, is returned as soon as it is encountered.
+func validateNamespaces(ctx context.Context, reader datastore.Reader, nsdefs []*core.NamespaceDefinition) error {
+	existingDefMap, err := loadExistingDefinitions(ctx, reader, nsdefs)
+	if err != nil {
+		return err
+	}
+
+	errs := make([]error, len(nsdefs))
+	for i, nsdef := range nsdefs {
+		err := shared.SanityCheckExistingRelationships(ctx, reader, nsdef, existingDefMap)
+		if err != nil && status.Code(err) != codes.InvalidArgument {
+			return err
+		}
+		errs[i] = err
+	}
+
+	return shared.NewDefinitionsError(nsdefs, errs)
+}
+
+// loadExistingDefinitions validates and annotates the namespaces against the existing
+// namespaces, returning the existing namespaces by name.
+func loadExistingDefinitions(
+	ctx context.Context,
+	reader datastore.Reader,
+	nsdefs []*core.NamespaceDefinition,
+) (map[string]*core.NamespaceDefinition, error) {
+	existingDefs, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	liveDefs := append([]*core.NamespaceDefinition{}, nsdefs...)
+	liveDefNames := strset.New()
+	for _, nsdef := range nsdefs {
+		liveDefNames.Add(nsdef.Name)
+	}
+
+	existingDefMap := make(map[string]*core.NamespaceDefinition, len(existingDefs))
+	for _, existingDef := range existingDefs {
+		existingDefMap[existingDef.Name] = existingDef
+		if !liveDefNames.Has(existingDef.Name) {
+			liveDefNames.Add(existingDef.Name)
+			liveDefs = append(liveDefs, existingDef)
+		}
+	}
+
+	if err := shared.ValidateAndAnnotateAllNamespaces(ctx, nsdefs, liveDefs); err != nil {
+		return nil, err
+	}
+
+	return existingDefMap, nil
+}
+
+// checkSchemaSize returns an error if the schema is larger than the configured maximum size.
+func (ss *schemaServiceServer) checkSchemaSize(schema string) error {
+	if len(schema) > ss.maxSchemaBytes {
+		return status.Errorf(codes.InvalidArgument, "schema of %d bytes exceeds the maximum allowed size of %d bytes", len(schema), ss.maxSchemaBytes)
+	}
+	return nil
+}
+
+// compileSchema compiles the schema into namespace definitions.
+func (ss *schemaServiceServer) compileSchema(schema string) ([]*core.NamespaceDefinition, error) {
+	return ss.compileSchemas([]compiler.InputSchema{{
+		Source:       input.Source("schema"),
+		SchemaString: schema,
+	}})
+}
+
+// compileSchemas compiles the schemas into namespace definitions, applying the same prefix rules
+// to each. It is an error for an object definition to be defined by more than one schema.
+func (ss *schemaServiceServer) compileSchemas(schemas []compiler.InputSchema) ([]*core.NamespaceDefinition, error) {
+	var prefix *string
+	if ss.prefixRequired == PrefixNotRequired {
+		empty := ""
+		prefix = &empty
+	}
+
+	var nsdefs []*core.NamespaceDefinition
+	definedIn := make(map[string]input.Source)
+	for _, inputSchema := range schemas {
+		compiled, err := compiler.Compile([]compiler.InputSchema{inputSchema}, prefix)
+		if err != nil {
+			return nil, err
+		}
+
+		for _, nsdef := range compiled {
+			if existing, ok := definedIn[nsdef.Name]; ok {
+				return nil, &duplicateDefinitionError{
+					fmt.Errorf("object definition `%s` is defined in both `%s` and `%s`", nsdef.Name, existing, inputSchema.Source),
+				}
+			}
+			definedIn[nsdef.Name] = inputSchema.Source
+		}
+
+		// Record the verbatim source of each definition, so that comments and formatting are
+		// preserved when the schema is read back.
+		sources := compiler.DefinitionSources(inputSchema.Source, inputSchema.SchemaString)
+		if len(sources) == len(compiled) {
+			for index, nsdef := range compiled {
+				if err := nspkg.SetDefinitionSource(nsdef, sources[index]); err != nil {
+					return nil, err
+				}
+			}
+		}
+
+		nsdefs = append(nsdefs, compiled...)
+	}
+
+	return nsdefs, nil
+}
+
 func rewriteError(ctx context.Context, err error) error {
 	var nsNotFoundError sharederrors.UnknownNamespaceError
+	var relNotFoundError sharederrors.UnknownRelationError
 	var errWithContext compiler.ErrorWithContext
 	var errPreconditionFailure *writeSchemaPreconditionFailure
+	var errInvalidDefinitions *shared.InvalidDefinitionsError
+	var errDuplicateDefinition *duplicateDefinitionError
+	var errInUse *shared.NamespaceInUseError
+	var errCycle definitionCycleError
+
+	if errors.As(err, &errInvalidDefinitions) {
+		return invalidDefinitionsStatus(errInvalidDefinitions)
+	}
 
 	errWithSource, ok := commonerrors.AsErrorWithSource(err)
 	if ok {
@@ -242,10 +551,22 @@ func rewriteError(ctx context.Context, err error) error {
 	switch {
 	case errors.As(err, &nsNotFoundError):
 		return status.Errorf(codes.NotFound, "Object Definition `%s` not found", nsNotFoundError.NotFoundNamespaceName())
+	case errors.As(err, &relNotFoundError):
+		return status.Errorf(codes.InvalidArgument, "Relation/Permission `%s` not found under Object Definition `%s`", relNotFoundError.NotFoundRelationName(), relNotFoundError.NamespaceName())
 	case errors.As(err, &errWithContext):
 		return status.Errorf(codes.InvalidArgument, "%s", err)
+	case errors.As(err, &errDuplicateDefinition):
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+	case errors.As(err, &errCycle):
+		return status.Errorf(codes.InvalidArgument, "%s", errCycle)
+	case errors.As(err, &errInUse):
+		return status.Errorf(codes.FailedPrecondition, "%s", errInUse)
+	case errors.As(err, &datastore.ErrNamespaceAlreadyExists{}):
+		return status.Errorf(codes.AlreadyExists, "%s", err)
 	case errors.As(err, &datastore.ErrReadOnly{}):
 		return serviceerrors.ErrServiceReadOnly
+	case errors.As(err, &datastore.ErrInvalidRevision{}):
+		return status.Errorf(codes.OutOfRange, "invalid revision: %s", err)
 	case errors.As(err, &errPreconditionFailure):
 		return status.Errorf(codes.FailedPrecondition, "%s", err)
 	default:
@@ -253,3 +574,28 @@ func rewriteError(ctx context.Context, err error) error {
 		return err
 	}
 }
+
+// invalidDefinitionsStatus returns an InvalidArgument status with a field violation for each
+// invalid definition, including the position of the error in the schema if it is known.
+func invalidDefinitionsStatus(err *shared.InvalidDefinitionsError) error {
+	violations := make([]*errdetails.BadRequest_FieldViolation, 0, len(err.Definitions))
+	for _, defErr := range err.Definitions {
+		description := defErr.Err.Error()
+		if errWithSource, ok := commonerrors.AsErrorWithSource(defErr.Err); ok && errWithSource.LineNumber > 0 {
+			description = fmt.Sprintf("line %d, column %d: %s", errWithSource.LineNumber, errWithSource.ColumnPosition, description)
+		}
+
+		violations = append(violations, &errdetails.BadRequest_FieldViolation{
+			Field:       defErr.DefinitionName,
+			Description: description,
+		})
+	}
+
+	st, detailsErr := status.New(codes.InvalidArgument, err.Error()).WithDetails(&errdetails.BadRequest{
+		FieldViolations: violations,
+	})
+	if detailsErr != nil {
+		return status.Errorf(codes.InvalidArgument, "%s", err)
+	}
+	return st.Err()
+}
@@ -2,20 +2,32 @@ package v1alpha1_test
 
 import (
 	"context"
+	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/authzed/authzed-go/proto/authzed/api/v1alpha1"
 	"github.com/authzed/grpcutil"
+	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 
 	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/datastore/options"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	v1alpha1svc "github.com/authzed/spicedb/internal/services/v1alpha1"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/internal/testserver"
 	"github.com/authzed/spicedb/pkg/datastore"
 	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
 	"github.com/authzed/spicedb/pkg/tuple"
+	"github.com/authzed/spicedb/pkg/zedtoken"
 )
 
 func TestSchemaReadNoPrefix(t *testing.T) {
@@ -55,6 +67,91 @@ func TestSchemaWriteNoPrefixNotRequired(t *testing.T) {
 	require.Len(t, rev, 1)
 }
 
+func TestSchemaWriteMaxSize(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+	experimentalClient := schemav1alpha1.NewExperimentalSchemaServiceClient(conn)
+
+	schema := `definition user {}`
+
+	// A single WriteSchema request is limited by its validation.
+	atRequestLimit := schema + strings.Repeat(" ", v1alpha1svc.MaxWriteSchemaRequestBytes-len(schema))
+	_, err := client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: atRequestLimit,
+	})
+	require.NoError(t, err)
+
+	_, err = client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: atRequestLimit + " ",
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// Larger schemas, up to the default maximum size, can be written as several files, whose
+	// combined size is limited.
+	otherSchema := `definition document {}`
+	atLimit := []*schemav1alpha1.SchemaFile{
+		{Name: "user.zed", Schema: schema + strings.Repeat(" ", v1alpha1svc.DefaultMaxSchemaBytes/2-len(schema))},
+		{Name: "document.zed", Schema: otherSchema + strings.Repeat(" ", v1alpha1svc.DefaultMaxSchemaBytes/2-len(otherSchema))},
+	}
+	_, err = experimentalClient.WriteSchemaMulti(context.Background(), &schemav1alpha1.WriteSchemaMultiRequest{
+		Schemas: atLimit,
+	})
+	require.NoError(t, err)
+
+	overLimit := []*schemav1alpha1.SchemaFile{
+		atLimit[0],
+		{Name: "document.zed", Schema: atLimit[1].Schema + " "},
+	}
+	_, err = experimentalClient.WriteSchemaMulti(context.Background(), &schemav1alpha1.WriteSchemaMultiRequest{
+		Schemas: overLimit,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.Contains(t, err.Error(), "exceeds the maximum allowed size")
+}
+
+func TestSchemaWriteConfiguredMaxSize(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+	schema := `definition user {}`
+	const maxSize = 1024
+	server := v1alpha1svc.NewSchemaServer(v1alpha1svc.PrefixNotRequired, v1alpha1svc.WithMaxSchemaBytes(maxSize))
+
+	atLimit := schema + strings.Repeat(" ", maxSize-len(schema))
+	_, err = server.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{Schema: atLimit})
+	require.NoError(t, err)
+
+	_, err = server.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{Schema: atLimit + " "})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.Contains(t, err.Error(), "exceeds the maximum allowed size")
+}
+
+func TestSchemaWriteUnknownRelation(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+
+	_, err := client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/folder {
+			relation viewer: example/user
+		}
+
+		definition example/document {
+			relation viewer: example/folder#nonexistent
+			permission view = viewer
+		}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.Equal(t,
+		"for relation `viewer`: relation/permission `nonexistent` was not found under definition `example/folder`",
+		status.Convert(err).Message(),
+	)
+}
+
 func TestSchemaReadInvalidName(t *testing.T) {
 	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
 	t.Cleanup(cleanup)
@@ -113,6 +210,63 @@ func TestSchemaWriteAndReadBack(t *testing.T) {
 	require.Equal(t, []string{userSchema}, readback.GetObjectDefinitions())
 }
 
+func TestSchemaReadInDependencyOrder(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+
+	userSchema := `definition example/user {}`
+	folderSchema := `definition example/folder {
+	relation viewer: example/user
+}`
+	documentSchema := `definition example/document {
+	relation parent: example/folder
+	relation viewer: example/user
+}`
+
+	_, err := client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: strings.Join([]string{documentSchema, folderSchema, userSchema}, "\n\n"),
+	})
+	require.NoError(t, err)
+
+	readback, err := client.ReadSchema(context.Background(), &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"example/document", "example/folder", "example/user"},
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{userSchema, folderSchema, documentSchema}, readback.GetObjectDefinitions())
+
+	// Definitions which reference each other have no dependency order, so reading them fails
+	// with an error naming the cycle.
+	_, err = client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+definition example/folder {
+	relation viewer: example/user
+	relation document: example/document
+}
+
+definition example/document {
+	relation parent: example/folder
+}`,
+	})
+	require.NoError(t, err)
+
+	_, err = client.ReadSchema(context.Background(), &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"example/document", "example/folder"},
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.Contains(t, err.Error(), "example/document -> example/folder -> example/document")
+
+	// A cycle is only an error among the requested definitions.
+	readback, err = client.ReadSchema(context.Background(), &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"example/document", "example/user"},
+	})
+	require.NoError(t, err)
+	require.Len(t, readback.GetObjectDefinitions(), 2)
+	require.True(t, strings.HasPrefix(readback.GetObjectDefinitions()[0], "definition example/document"))
+	require.True(t, strings.HasPrefix(readback.GetObjectDefinitions()[1], "definition example/user"))
+}
+
 func TestSchemaDeleteRelation(t *testing.T) {
 	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
 	t.Cleanup(cleanup)
@@ -284,3 +438,492 @@ func TestSchemaReadDeleteAndFailWrite(t *testing.T) {
 	})
 	grpcutil.RequireStatus(t, codes.NotFound, err)
 }
+
+func TestSchemaWriteFailureWritesNothing(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+	v1client := v1.NewPermissionsServiceClient(conn)
+
+	_, err := client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {
+			relation reader: example/user
+		}`,
+	})
+	require.NoError(t, err)
+
+	_, err = v1client.WriteRelationships(context.Background(), &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(
+				tuple.MustParse("example/document:somedoc#reader@example/user:someuser#..."),
+			)),
+		},
+	})
+	require.NoError(t, err)
+
+	// Add two new definitions and, in the last definition, remove a relation which has data,
+	// which must fail.
+	_, err = client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {
+			relation manager: example/user
+		}
+
+		definition example/folder {
+			relation viewer: example/user
+		}
+
+		definition example/team {
+			relation member: example/user
+		}
+
+		definition example/document {}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// Ensure none of the definitions before the failing one were written.
+	for _, objectDefName := range []string{"example/folder", "example/team"} {
+		_, err = client.ReadSchema(context.Background(), &v1alpha1.ReadSchemaRequest{
+			ObjectDefinitionsNames: []string{objectDefName},
+		})
+		grpcutil.RequireStatus(t, codes.NotFound, err)
+	}
+
+	readResp, err := client.ReadSchema(context.Background(), &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"example/user", "example/document"},
+	})
+	require.NoError(t, err)
+	require.NotContains(t, readResp.ObjectDefinitions[0], "manager")
+	require.Contains(t, readResp.ObjectDefinitions[1], "reader")
+}
+
+func TestSchemaWriteFailureRollsBackWrittenDefinitions(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+	server := v1alpha1svc.NewSchemaServer(v1alpha1svc.PrefixRequired)
+	_, err = server.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}`,
+	})
+	require.NoError(t, err)
+
+	// Every definition but the last is written within the transaction before the write fails.
+	writeErr := errors.New("write failed")
+	faulty := &faultyDatastore{Datastore: ds, writeErr: writeErr}
+	_, err = server.WriteSchema(datastoremw.ContextWithDatastore(context.Background(), faulty), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {
+			relation manager: example/user
+		}
+
+		definition example/folder {
+			relation viewer: example/user
+		}
+
+		definition example/document {
+			relation reader: example/user
+		}`,
+	})
+	require.ErrorIs(t, err, writeErr)
+
+	// Neither the new definitions nor the update of the existing one were persisted.
+	for _, objectDefName := range []string{"example/folder", "example/document"} {
+		_, err = server.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+			ObjectDefinitionsNames: []string{objectDefName},
+		})
+		grpcutil.RequireStatus(t, codes.NotFound, err)
+	}
+
+	readResp, err := server.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"example/user"},
+	})
+	require.NoError(t, err)
+	require.NotContains(t, readResp.ObjectDefinitions[0], "manager")
+}
+
+func TestSchemaWriteReturnsWrittenRevision(t *testing.T) {
+	conn, cleanup, ds, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+
+	writeResp, err := client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {
+			relation reader: example/user
+		}`,
+	})
+	require.NoError(t, err)
+
+	writtenAt, err := nspkg.V1Alpha1RevisionWrittenAt(writeResp.ComputedDefinitionsRevision)
+	require.NoError(t, err)
+
+	// Both definitions can be read at exactly the written revision, but not before it.
+	for _, objectDefName := range writeResp.ObjectDefinitionsNames {
+		_, _, err := ds.SnapshotReader(writtenAt).ReadNamespace(context.Background(), objectDefName)
+		require.NoError(t, err)
+
+		_, _, err = ds.SnapshotReader(writtenAt.Sub(decimal.NewFromInt(1))).ReadNamespace(context.Background(), objectDefName)
+		require.ErrorAs(t, err, &datastore.ErrNamespaceNotFound{})
+	}
+}
+
+func TestValidateSchema(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, ds, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := schemav1alpha1.NewExperimentalSchemaServiceClient(conn)
+	ctx := context.Background()
+
+	// A schema which compiles, but fails type system validation.
+	_, err := client.ValidateSchema(ctx, &schemav1alpha1.ValidateSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {
+			relation reader: example/user
+			permission view = reader + unknownrelation
+		}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// A schema which does not compile.
+	_, err = client.ValidateSchema(ctx, &schemav1alpha1.ValidateSchemaRequest{
+		Schema: `definition example/user {`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	// A valid schema.
+	_, err = client.ValidateSchema(ctx, &schemav1alpha1.ValidateSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {
+			relation reader: example/user
+			permission view = reader
+		}`,
+	})
+	require.NoError(err)
+
+	// Ensure nothing was written.
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	nsdefs, err := ds.SnapshotReader(headRevision).ListNamespaces(ctx)
+	require.NoError(err)
+	require.Empty(nsdefs)
+}
+
+func TestListObjectDefinitions(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+	experimentalClient := schemav1alpha1.NewExperimentalSchemaServiceClient(conn)
+	ctx := context.Background()
+
+	resp, err := experimentalClient.ListObjectDefinitions(ctx, &schemav1alpha1.ListObjectDefinitionsRequest{})
+	require.NoError(err)
+	require.Empty(resp.ObjectDefinitions)
+
+	_, err = client.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {
+			relation reader: example/user
+			relation writer: example/user
+			permission view = reader + writer
+		}`,
+	})
+	require.NoError(err)
+
+	resp, err = experimentalClient.ListObjectDefinitions(ctx, &schemav1alpha1.ListObjectDefinitionsRequest{})
+	require.NoError(err)
+	require.Len(resp.ObjectDefinitions, 2)
+	require.Equal("example/document", resp.ObjectDefinitions[0].Name)
+	require.Equal([]string{"reader", "writer", "view"}, resp.ObjectDefinitions[0].Relations)
+	require.Equal("example/user", resp.ObjectDefinitions[1].Name)
+	require.Empty(resp.ObjectDefinitions[1].Relations)
+
+	_, err = client.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/folder {
+			relation viewer: example/user
+		}`,
+	})
+	require.NoError(err)
+
+	resp, err = experimentalClient.ListObjectDefinitions(ctx, &schemav1alpha1.ListObjectDefinitionsRequest{})
+	require.NoError(err)
+	require.Len(resp.ObjectDefinitions, 3)
+	require.Equal("example/folder", resp.ObjectDefinitions[1].Name)
+	require.Equal([]string{"viewer"}, resp.ObjectDefinitions[1].Relations)
+}
+
+func TestReadSchemaAtRevision(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, ds, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+	experimentalClient := schemav1alpha1.NewExperimentalSchemaServiceClient(conn)
+	ctx := context.Background()
+
+	beforeWrite, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	_, err = client.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {
+			relation reader: example/user
+			permission view = reader
+		}`,
+	})
+	require.NoError(err)
+
+	firstRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	_, err = client.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {
+			relation reader: example/user
+			relation writer: example/user
+			permission view = reader + writer
+		}`,
+	})
+	require.NoError(err)
+
+	readAt := func(revision decimal.Decimal) (*schemav1alpha1.ReadSchemaAtRevisionResponse, error) {
+		return experimentalClient.ReadSchemaAtRevision(ctx, &schemav1alpha1.ReadSchemaAtRevisionRequest{
+			ObjectDefinitionsNames: []string{"example/document"},
+			AtRevision:             zedtoken.NewFromRevision(revision).Token,
+		})
+	}
+
+	// The earlier revision reads the schema as it was first written.
+	old, err := readAt(firstRevision)
+	require.NoError(err)
+	require.Len(old.ObjectDefinitions, 1)
+	require.Contains(old.ObjectDefinitions[0], "permission view = reader")
+	require.NotContains(old.ObjectDefinitions[0], "writer")
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	current, err := readAt(headRevision)
+	require.NoError(err)
+	require.Len(current.ObjectDefinitions, 1)
+	require.Contains(current.ObjectDefinitions[0], "relation writer: example/user")
+	require.NotEqual(old.ComputedDefinitionsRevision, current.ComputedDefinitionsRevision)
+
+	// Definitions which did not yet exist at the revision are not found.
+	_, err = readAt(beforeWrite)
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+
+	// Revisions in the future are rejected.
+	_, err = readAt(headRevision.Add(decimal.NewFromInt(time.Hour.Nanoseconds())))
+	grpcutil.RequireStatus(t, codes.OutOfRange, err)
+
+	_, err = experimentalClient.ReadSchemaAtRevision(ctx, &schemav1alpha1.ReadSchemaAtRevisionRequest{
+		ObjectDefinitionsNames: []string{"example/document"},
+		AtRevision:             "invalid",
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func TestSchemaWriteAndReadBackPreservesSource(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+
+	userSchema := `// A user of the system.
+definition example/user {}`
+
+	documentSchema := `/* Documents. */
+definition example/document {
+	// Readers of the document.
+	relation reader:   example/user
+
+	relation writer: example/user // writers may also read
+	permission view = reader + writer
+}`
+
+	writeResp, err := client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: userSchema + "\n\n" + documentSchema + "\n",
+	})
+	require.NoError(t, err)
+
+	readback, err := client.ReadSchema(context.Background(), &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: writeResp.GetObjectDefinitionsNames(),
+	})
+	require.NoError(t, err)
+	require.Equal(t, []string{userSchema, documentSchema}, readback.GetObjectDefinitions())
+}
+
+func TestSchemaReadAfterRename(t *testing.T) {
+	conn, cleanup, ds, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+
+	_, err := client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+definition example/document {
+	relation reader: example/user
+}`,
+	})
+	require.NoError(t, err)
+
+	_, err = ds.ReadWriteTx(context.Background(), func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.RenameNamespace("example/user", "example/person")
+	})
+	require.NoError(t, err)
+
+	// The source recorded when the schema was written names the old definition, so it must not
+	// be returned for either the renamed definition or the one referencing it.
+	readback, err := client.ReadSchema(context.Background(), &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"example/person", "example/document"},
+	})
+	require.NoError(t, err)
+	require.Len(t, readback.GetObjectDefinitions(), 2)
+	require.Contains(t, readback.GetObjectDefinitions()[0], "definition example/person")
+	require.Contains(t, readback.GetObjectDefinitions()[1], "relation reader: example/person")
+	for _, objectDef := range readback.GetObjectDefinitions() {
+		require.NotContains(t, objectDef, "example/user")
+	}
+}
+
+func TestSchemaWriteMultipleInvalidDefinitions(t *testing.T) {
+	conn, cleanup, _, _ := testserver.NewTestServer(require.New(t), 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+
+	_, err := client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/folder {
+			relation viewer: example/user
+			permission view = viewer + missingfolderrelation
+		}
+
+		definition example/document {
+			relation reader: example/user
+			permission view = reader + missingdocumentrelation
+		}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	st, ok := status.FromError(err)
+	require.True(t, ok)
+	require.Contains(t, st.Message(), "missingfolderrelation")
+	require.Contains(t, st.Message(), "missingdocumentrelation")
+
+	require.Len(t, st.Details(), 1)
+	badRequest, ok := st.Details()[0].(*errdetails.BadRequest)
+	require.True(t, ok)
+
+	violations := badRequest.GetFieldViolations()
+	require.Len(t, violations, 2)
+	require.Equal(t, "example/folder", violations[0].Field)
+	require.Contains(t, violations[0].Description, "missingfolderrelation")
+	require.Equal(t, "example/document", violations[1].Field)
+	require.Contains(t, violations[1].Description, "missingdocumentrelation")
+
+	// A single invalid definition returns its error alone.
+	_, err = client.WriteSchema(context.Background(), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/document {
+			relation reader: example/user
+			permission view = reader + missingdocumentrelation
+		}`,
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+
+	st, ok = status.FromError(err)
+	require.True(t, ok)
+	require.Empty(t, st.Details())
+}
+
+func TestSchemaWriteStopsOnDatastoreError(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+
+	server := v1alpha1svc.NewSchemaServer(v1alpha1svc.PrefixRequired)
+	_, err = server.WriteSchema(datastoremw.ContextWithDatastore(context.Background(), ds), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/folder {
+			relation viewer: example/user
+		}
+
+		definition example/document {
+			relation reader: example/user
+		}`,
+	})
+	require.NoError(t, err)
+
+	// Removing a relation of each definition requires a query per definition, which fails.
+	queryErr := errors.New("datastore unavailable")
+	faulty := &faultyDatastore{Datastore: ds, queryErr: queryErr}
+	_, err = server.WriteSchema(datastoremw.ContextWithDatastore(context.Background(), faulty), &v1alpha1.WriteSchemaRequest{
+		Schema: `definition example/user {}
+
+		definition example/folder {}
+
+		definition example/document {}`,
+	})
+	require.ErrorIs(t, err, queryErr)
+	require.NotEqual(t, codes.InvalidArgument, status.Code(err))
+	require.Equal(t, 1, faulty.queries, "expected the validation to stop at the first failed query")
+}
+
+// faultyDatastore wraps a datastore, injecting failures into its read-write transactions.
+type faultyDatastore struct {
+	datastore.Datastore
+
+	// queryErr, if set, is returned by every relationship query.
+	queryErr error
+	queries  int
+
+	// writeErr, if set, is returned by WriteNamespaces after every definition but the last was
+	// written.
+	writeErr error
+}
+
+func (fd *faultyDatastore) ReadWriteTx(ctx context.Context, fn datastore.TxUserFunc) (datastore.Revision, error) {
+	return fd.Datastore.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return fn(ctx, &faultyReadWriteTx{rwt, fd})
+	})
+}
+
+type faultyReadWriteTx struct {
+	datastore.ReadWriteTransaction
+	fd *faultyDatastore
+}
+
+func (ft *faultyReadWriteTx) QueryRelationships(ctx context.Context, filter *v1.RelationshipFilter, opts ...options.QueryOptionsOption) (datastore.RelationshipIterator, error) {
+	ft.fd.queries++
+	if ft.fd.queryErr != nil {
+		return nil, ft.fd.queryErr
+	}
+	return ft.ReadWriteTransaction.QueryRelationships(ctx, filter, opts...)
+}
+
+func (ft *faultyReadWriteTx) WriteNamespaces(newConfigs ...*core.NamespaceDefinition) error {
+	if ft.fd.writeErr == nil {
+		return ft.ReadWriteTransaction.WriteNamespaces(newConfigs...)
+	}
+
+	for _, nsdef := range newConfigs[:len(newConfigs)-1] {
+		if err := ft.ReadWriteTransaction.WriteNamespaces(nsdef); err != nil {
+			return err
+		}
+	}
+	return ft.fd.writeErr
+}
@@ -0,0 +1,124 @@
+package v1alpha1
+
+import (
+	"context"
+	"sort"
+
+	"github.com/scylladb/go-set/strset"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/datastore"
+	nspkg "github.com/authzed/spicedb/pkg/namespace"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	iv1 "github.com/authzed/spicedb/pkg/proto/impl/v1"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+	"github.com/authzed/spicedb/pkg/schemadsl/generator"
+)
+
+// DiffSchema compiles the proposed schema exactly as WriteSchema does and returns the changes
+// that writing it would make to the current object definitions.
+func (ss *schemaServiceServer) DiffSchema(ctx context.Context, in *schemav1alpha1.DiffSchemaRequest) (*schemav1alpha1.DiffSchemaResponse, error) {
+	if err := ss.checkSchemaSize(in.GetSchema()); err != nil {
+		return nil, err
+	}
+
+	nsdefs, err := ss.compileSchema(in.GetSchema())
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	reader, _, err := datastore.HeadSnapshotReader(ctx, datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	existingDefMap, err := loadExistingDefinitions(ctx, reader, nsdefs)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	schemaDiff := &schemav1alpha1.DiffSchemaResponse{ChangedDefinitions: make(map[string]*schemav1alpha1.DefinitionDiff)}
+	for _, nsdef := range nsdefs {
+		existing, ok := existingDefMap[nsdef.Name]
+		if !ok {
+			schemaDiff.AddedDefinitions = append(schemaDiff.AddedDefinitions, nsdef.Name)
+			continue
+		}
+
+		defDiff, err := diffDefinition(existing, nsdef)
+		if err != nil {
+			return nil, rewriteError(ctx, err)
+		}
+
+		if defDiff == nil {
+			continue
+		}
+
+		if err := shared.SanityCheckExistingRelationships(ctx, reader, nsdef, existingDefMap); err != nil {
+			defDiff.BreakingChange = err.Error()
+		}
+
+		schemaDiff.ChangedDefinitions[nsdef.Name] = defDiff
+	}
+
+	sort.Strings(schemaDiff.AddedDefinitions)
+	return schemaDiff, nil
+}
+
+// diffDefinition returns the changes between an existing and a proposed definition, or nil if
+// there are none.
+func diffDefinition(existing, proposed *core.NamespaceDefinition) (*schemav1alpha1.DefinitionDiff, error) {
+	diff, err := namespace.DiffNamespaces(existing, proposed)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(diff.Deltas()) == 0 {
+		return nil, nil
+	}
+
+	existingSource, _ := generator.GenerateSource(existing)
+	proposedSource, _ := generator.GenerateSource(proposed)
+	defDiff := &schemav1alpha1.DefinitionDiff{
+		ExistingSource: existingSource,
+		ProposedSource: proposedSource,
+	}
+
+	changed := strset.New()
+	for _, delta := range diff.Deltas() {
+		switch delta.Type {
+		case namespace.AddedRelation:
+			if isPermission(proposed, delta.RelationName) {
+				defDiff.AddedPermissions = append(defDiff.AddedPermissions, delta.RelationName)
+			} else {
+				defDiff.AddedRelations = append(defDiff.AddedRelations, delta.RelationName)
+			}
+
+		case namespace.RemovedRelation:
+			if isPermission(existing, delta.RelationName) {
+				defDiff.RemovedPermissions = append(defDiff.RemovedPermissions, delta.RelationName)
+			} else {
+				defDiff.RemovedRelations = append(defDiff.RemovedRelations, delta.RelationName)
+			}
+
+		default:
+			changed.Add(delta.RelationName)
+		}
+	}
+
+	defDiff.ChangedRelations = changed.List()
+	sort.Strings(defDiff.ChangedRelations)
+
+	return defDiff, nil
+}
+
+func isPermission(nsdef *core.NamespaceDefinition, relationName string) bool {
+	for _, relation := range nsdef.Relation {
+		if relation.Name == relationName {
+			return nspkg.GetRelationKind(relation) == iv1.RelationMetadata_PERMISSION
+		}
+	}
+	return false
+}
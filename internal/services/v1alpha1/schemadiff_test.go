@@ -0,0 +1,125 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/authzed-go/proto/authzed/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	v1alpha1svc "github.com/authzed/spicedb/internal/services/v1alpha1"
+	"github.com/authzed/spicedb/pkg/datastore"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+const baseDiffSchema = `definition example/user {}
+
+definition example/document {
+	relation reader: example/user
+	relation writer: example/user
+	permission view = reader + writer
+}`
+
+func setupSchemaDiff(t *testing.T, relationships ...string) (context.Context, schemav1alpha1.ExperimentalSchemaServiceServer) {
+	require := require.New(t)
+
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(err)
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+	server := v1alpha1svc.NewSchemaServer(v1alpha1svc.PrefixNotRequired)
+	_, err = server.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{Schema: baseDiffSchema})
+	require.NoError(err)
+
+	if len(relationships) > 0 {
+		_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			mutations := make([]*v1.RelationshipUpdate, 0, len(relationships))
+			for _, rel := range relationships {
+				mutations = append(mutations, tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse(rel))))
+			}
+			return rwt.WriteRelationships(mutations)
+		})
+		require.NoError(err)
+	}
+
+	return ctx, v1alpha1svc.NewExperimentalSchemaServer(v1alpha1svc.PrefixNotRequired)
+}
+
+func TestDiffSchemaNoChanges(t *testing.T) {
+	ctx, differ := setupSchemaDiff(t)
+
+	diff, err := differ.DiffSchema(ctx, &schemav1alpha1.DiffSchemaRequest{Schema: baseDiffSchema})
+	require.NoError(t, err)
+	require.Empty(t, diff.AddedDefinitions)
+	require.Empty(t, diff.ChangedDefinitions)
+}
+
+func TestDiffSchemaAdditions(t *testing.T) {
+	require := require.New(t)
+	ctx, differ := setupSchemaDiff(t, "example/document:doc1#reader@example/user:alice#...")
+
+	diff, err := differ.DiffSchema(ctx, &schemav1alpha1.DiffSchemaRequest{Schema: `definition example/user {}
+
+	definition example/group {
+		relation member: example/user
+	}
+
+	definition example/document {
+		relation reader: example/user
+		relation writer: example/user
+		relation owner: example/user
+		permission view = reader + writer + owner
+		permission edit = writer + owner
+	}`})
+	require.NoError(err)
+
+	require.Equal([]string{"example/group"}, diff.AddedDefinitions)
+	require.Len(diff.ChangedDefinitions, 1)
+
+	docDiff := diff.ChangedDefinitions["example/document"]
+	require.NotNil(docDiff)
+	require.Equal([]string{"owner"}, docDiff.AddedRelations)
+	require.Equal([]string{"edit"}, docDiff.AddedPermissions)
+	require.Equal([]string{"view"}, docDiff.ChangedRelations)
+	require.Empty(docDiff.RemovedRelations)
+	require.Empty(docDiff.RemovedPermissions)
+	require.Empty(docDiff.BreakingChange)
+	require.NotEqual(docDiff.ExistingSource, docDiff.ProposedSource)
+}
+
+func TestDiffSchemaRemovalWithData(t *testing.T) {
+	require := require.New(t)
+	ctx, differ := setupSchemaDiff(t, "example/document:doc1#reader@example/user:alice#...")
+
+	diff, err := differ.DiffSchema(ctx, &schemav1alpha1.DiffSchemaRequest{Schema: `definition example/user {}
+
+	definition example/document {
+		relation writer: example/user
+	}`})
+	require.NoError(err)
+
+	require.Empty(diff.AddedDefinitions)
+
+	docDiff := diff.ChangedDefinitions["example/document"]
+	require.NotNil(docDiff)
+	require.Equal([]string{"reader"}, docDiff.RemovedRelations)
+	require.Equal([]string{"view"}, docDiff.RemovedPermissions)
+	require.Contains(docDiff.BreakingChange, "reader")
+
+	// Removing a relation without relationships is not a breaking change.
+	diff, err = differ.DiffSchema(ctx, &schemav1alpha1.DiffSchemaRequest{Schema: `definition example/user {}
+
+	definition example/document {
+		relation reader: example/user
+	}`})
+	require.NoError(err)
+
+	docDiff = diff.ChangedDefinitions["example/document"]
+	require.NotNil(docDiff)
+	require.Equal([]string{"writer"}, docDiff.RemovedRelations)
+	require.Empty(docDiff.BreakingChange)
+}
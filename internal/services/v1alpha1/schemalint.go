@@ -0,0 +1,57 @@
+package v1alpha1
+
+import (
+	"context"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	"github.com/authzed/spicedb/pkg/datastore"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+)
+
+// LintSchema runs all of the validation that WriteSchema does, returning the same errors
+// WriteSchema would, and then returns the warnings for anti-patterns in the schema, such as
+// unused relations or permissions which can never have any subjects. Warnings do not prevent the
+// schema from being written.
+func (ss *schemaServiceServer) LintSchema(ctx context.Context, in *schemav1alpha1.LintSchemaRequest) (*schemav1alpha1.LintSchemaResponse, error) {
+	if err := ss.checkSchemaSize(in.GetSchema()); err != nil {
+		return nil, err
+	}
+
+	nsdefs, err := ss.compileSchema(in.GetSchema())
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	reader, _, err := datastore.HeadSnapshotReader(ctx, datastoremw.MustFromContext(ctx))
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	if err := validateNamespaces(ctx, reader, nsdefs); err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	existingDefs, err := reader.ListNamespaces(ctx)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	// Existing definitions which are not in the schema are left untouched by WriteSchema, so they
+	// remain part of the schema the linted definitions are used in.
+	lintWarnings := namespace.LintNamespaces(nsdefs, existingDefs)
+
+	warnings := make([]*schemav1alpha1.LintWarning, 0, len(lintWarnings))
+	for _, warning := range lintWarnings {
+		warnings = append(warnings, &schemav1alpha1.LintWarning{
+			Kind:           string(warning.Kind),
+			Message:        warning.Error(),
+			Relation:       warning.Relation,
+			LineNumber:     warning.LineNumber,
+			ColumnPosition: warning.ColumnPosition,
+			SourceCode:     warning.SourceCodeString,
+		})
+	}
+
+	return &schemav1alpha1.LintSchemaResponse{Warnings: warnings}, nil
+}
@@ -0,0 +1,196 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/authzed/authzed-go/proto/authzed/api/v1alpha1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/namespace"
+	v1alpha1svc "github.com/authzed/spicedb/internal/services/v1alpha1"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+)
+
+func TestLintSchema(t *testing.T) {
+	testCases := []struct {
+		name             string
+		schema           string
+		expectedWarnings []string
+		expectedLines    []uint64
+	}{
+		{
+			"no warnings",
+			`definition example/user {}
+
+			definition example/document {
+				relation reader: example/user
+				permission view = reader
+			}`,
+			nil,
+			nil,
+		},
+		{
+			"unused relation",
+			`definition example/user {}
+
+			definition example/document {
+				relation reader: example/user
+				relation auditor: example/user
+				permission view = reader
+			}`,
+			[]string{"relation `auditor` under definition `example/document` is not used by any permission"},
+			[]uint64{5},
+		},
+		{
+			"relation used as a subject relation",
+			`definition example/user {}
+
+			definition example/group {
+				relation member: example/user
+			}
+
+			definition example/document {
+				relation reader: example/group#member
+				permission view = reader
+			}`,
+			nil,
+			nil,
+		},
+		{
+			"relation used through an arrow",
+			`definition example/user {}
+
+			definition example/folder {
+				relation viewer: example/user
+			}
+
+			definition example/document {
+				relation parent: example/folder
+				permission view = parent->viewer
+			}`,
+			nil,
+			nil,
+		},
+		{
+			"unreachable permission",
+			`definition example/user {}
+
+			definition example/document {
+				relation reader: example/user
+				permission nobody = nil
+				permission view = reader & nobody
+				permission edit = reader + nobody
+			}`,
+			[]string{
+				"permission `nobody` under definition `example/document` can never have any subjects",
+				"permission `view` under definition `example/document` can never have any subjects",
+			},
+			[]uint64{5, 6},
+		},
+		{
+			"unreachable permission through an arrow",
+			`definition example/user {}
+
+			definition example/folder {
+				relation viewer: example/user
+				permission nobody = nil
+				permission view = viewer
+			}
+
+			definition example/document {
+				relation parent: example/folder
+				permission view = parent->view
+				permission never = parent->nobody
+			}`,
+			[]string{
+				"permission `nobody` under definition `example/folder` can never have any subjects",
+				"permission `never` under definition `example/document` can never have any subjects",
+			},
+			[]uint64{5, 12},
+		},
+		{
+			"wildcard granting permissions",
+			`definition example/user {}
+
+			definition example/document {
+				relation reader: example/user | example/user:*
+				relation banned: example/user:*
+				permission view = reader - banned
+				permission edit = view
+			}`,
+			[]string{"relation `reader` under definition `example/document` allows `example/user:*`, which grants every `example/user` the permissions `edit`, `view`"},
+			[]uint64{4},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+			require.NoError(err)
+			ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+			server := v1alpha1svc.NewSchemaServer(v1alpha1svc.PrefixNotRequired)
+			linter := v1alpha1svc.NewExperimentalSchemaServer(v1alpha1svc.PrefixNotRequired)
+			resp, err := linter.LintSchema(ctx, &schemav1alpha1.LintSchemaRequest{Schema: tc.schema})
+			require.NoError(err)
+
+			var messages []string
+			var lines []uint64
+			for _, warning := range resp.Warnings {
+				messages = append(messages, warning.Message)
+				lines = append(lines, warning.LineNumber)
+			}
+			require.Equal(tc.expectedWarnings, messages)
+			require.Equal(tc.expectedLines, lines)
+
+			// Warnings never block writing the schema
+			_, err = server.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{Schema: tc.schema})
+			require.NoError(err)
+		})
+	}
+}
+
+func TestLintSchemaWarningKinds(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+	linter := v1alpha1svc.NewExperimentalSchemaServer(v1alpha1svc.PrefixNotRequired)
+	resp, err := linter.LintSchema(ctx, &schemav1alpha1.LintSchemaRequest{Schema: `definition example/user {}
+
+	definition example/document {
+		relation reader: example/user:*
+		relation auditor: example/user
+		permission nobody = nil
+		permission view = reader
+	}`})
+	require.NoError(t, err)
+
+	warnings := resp.Warnings
+	require.Len(t, warnings, 3)
+
+	require.Equal(t, string(namespace.LintWildcardPermission), warnings[0].Kind)
+	require.Equal(t, "reader", warnings[0].Relation.Relation)
+	require.Equal(t, string(namespace.LintUnusedRelation), warnings[1].Kind)
+	require.Equal(t, "auditor", warnings[1].Relation.Relation)
+	require.Equal(t, string(namespace.LintUnreachablePermission), warnings[2].Kind)
+	require.Equal(t, "nobody", warnings[2].Relation.Relation)
+	require.Equal(t, "example/document", warnings[2].Relation.Namespace)
+}
+
+func TestLintSchemaRejectsInvalidSchema(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+	linter := v1alpha1svc.NewExperimentalSchemaServer(v1alpha1svc.PrefixNotRequired)
+	_, err = linter.LintSchema(ctx, &schemav1alpha1.LintSchemaRequest{Schema: `definition example/document {
+		permission view = missing
+	}`})
+	require.Error(t, err)
+}
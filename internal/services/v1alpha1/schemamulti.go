@@ -0,0 +1,63 @@
+package v1alpha1
+
+import (
+	"context"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+// WriteSchemaMulti compiles the schemas together and writes the resulting object definitions, as
+// WriteSchema does for a single schema. Definitions may reference those of any of the schemas, and
+// the prefix rules are applied to each schema in the same way. The combined size of the schemas
+// is limited as for a single schema, and it is an error for more than one schema to define the
+// same object definition.
+func (ss *schemaServiceServer) WriteSchemaMulti(ctx context.Context, in *schemav1alpha1.WriteSchemaMultiRequest) (*schemav1alpha1.WriteSchemaMultiResponse, error) {
+	schemas := make([]compiler.InputSchema, 0, len(in.GetSchemas()))
+	for _, schema := range in.GetSchemas() {
+		schemas = append(schemas, compiler.InputSchema{
+			Source:       input.Source(schema.GetName()),
+			SchemaString: schema.GetSchema(),
+		})
+	}
+
+	return ss.writeSchemas(ctx, schemas, in.GetOptionalDefinitionsRevisionPrecondition())
+}
+
+func (ss *schemaServiceServer) writeSchemas(ctx context.Context, schemas []compiler.InputSchema, precondition string) (*schemav1alpha1.WriteSchemaMultiResponse, error) {
+	if len(schemas) == 0 {
+		return nil, status.Errorf(codes.InvalidArgument, "at least one schema must be given")
+	}
+
+	size := 0
+	for _, schema := range schemas {
+		size += len(schema.SchemaString)
+	}
+	if size > ss.maxSchemaBytes {
+		return nil, status.Errorf(codes.InvalidArgument, "schema of %d bytes exceeds the maximum allowed size of %d bytes", size, ss.maxSchemaBytes)
+	}
+
+	log.Ctx(ctx).Trace().Int("schemaCount", len(schemas)).Msg("requested Schemas to be written")
+
+	nsdefs, err := ss.compileSchemas(schemas)
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	log.Ctx(ctx).Trace().Interface("namespaceDefinitions", nsdefs).Msg("compiled namespace definitions")
+
+	resp, err := ss.writeDefinitions(ctx, nsdefs, precondition)
+	if err != nil {
+		return nil, err
+	}
+
+	return &schemav1alpha1.WriteSchemaMultiResponse{
+		ObjectDefinitionsNames:      resp.ObjectDefinitionsNames,
+		ComputedDefinitionsRevision: resp.ComputedDefinitionsRevision,
+	}, nil
+}
@@ -0,0 +1,104 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/authzed/authzed-go/proto/authzed/api/v1alpha1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	v1alpha1svc "github.com/authzed/spicedb/internal/services/v1alpha1"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+)
+
+var (
+	folderSchema = &schemav1alpha1.SchemaFile{
+		Name: "folder.zed",
+		Schema: `definition example/user {}
+
+definition example/folder {
+	relation viewer: example/user
+}`,
+	}
+
+	documentSchema = &schemav1alpha1.SchemaFile{
+		Name: "document.zed",
+		Schema: `definition example/document {
+	relation parent: example/folder
+	relation viewer: example/user
+	permission view = viewer + parent->viewer
+}`,
+	}
+)
+
+func setupSchemaMulti(t *testing.T, prefixRequired v1alpha1svc.PrefixRequiredOption) (context.Context, v1alpha1.SchemaServiceServer, schemav1alpha1.ExperimentalSchemaServiceServer) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	return datastoremw.ContextWithDatastore(context.Background(), ds),
+		v1alpha1svc.NewSchemaServer(prefixRequired),
+		v1alpha1svc.NewExperimentalSchemaServer(prefixRequired)
+}
+
+func TestWriteSchemaMulti(t *testing.T) {
+	require := require.New(t)
+	ctx, server, experimental := setupSchemaMulti(t, v1alpha1svc.PrefixRequired)
+
+	resp, err := experimental.WriteSchemaMulti(ctx, &schemav1alpha1.WriteSchemaMultiRequest{
+		Schemas: []*schemav1alpha1.SchemaFile{documentSchema, folderSchema},
+	})
+	require.NoError(err)
+	require.ElementsMatch([]string{"example/user", "example/folder", "example/document"}, resp.ObjectDefinitionsNames)
+
+	read, err := server.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"example/document"},
+	})
+	require.NoError(err)
+	require.Equal([]string{documentSchema.Schema}, read.ObjectDefinitions)
+}
+
+func TestWriteSchemaMultiMissingReference(t *testing.T) {
+	ctx, _, experimental := setupSchemaMulti(t, v1alpha1svc.PrefixRequired)
+
+	_, err := experimental.WriteSchemaMulti(ctx, &schemav1alpha1.WriteSchemaMultiRequest{
+		Schemas: []*schemav1alpha1.SchemaFile{documentSchema},
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
+
+func TestWriteSchemaMultiPrefixRequired(t *testing.T) {
+	ctx, _, experimental := setupSchemaMulti(t, v1alpha1svc.PrefixRequired)
+
+	_, err := experimental.WriteSchemaMulti(ctx, &schemav1alpha1.WriteSchemaMultiRequest{
+		Schemas: []*schemav1alpha1.SchemaFile{
+			folderSchema,
+			{Name: "unprefixed.zed", Schema: `definition document {}`},
+		},
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.Contains(t, err.Error(), "unprefixed.zed")
+}
+
+func TestWriteSchemaMultiConflict(t *testing.T) {
+	ctx, _, experimental := setupSchemaMulti(t, v1alpha1svc.PrefixNotRequired)
+
+	_, err := experimental.WriteSchemaMulti(ctx, &schemav1alpha1.WriteSchemaMultiRequest{
+		Schemas: []*schemav1alpha1.SchemaFile{
+			folderSchema,
+			documentSchema,
+			{Name: "other.zed", Schema: `definition example/folder {}`},
+		},
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.Contains(t, err.Error(), "object definition `example/folder` is defined in both `folder.zed` and `other.zed`")
+}
+
+func TestWriteSchemaMultiEmpty(t *testing.T) {
+	ctx, _, experimental := setupSchemaMulti(t, v1alpha1svc.PrefixNotRequired)
+
+	_, err := experimental.WriteSchemaMulti(ctx, &schemav1alpha1.WriteSchemaMultiRequest{})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+}
@@ -0,0 +1,102 @@
+package v1alpha1
+
+import (
+	"context"
+	"strings"
+
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	"github.com/authzed/spicedb/internal/middleware/usagemetrics"
+	"github.com/authzed/spicedb/internal/services/shared"
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	dispatchv1 "github.com/authzed/spicedb/pkg/proto/dispatch/v1"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+)
+
+// DeleteNamespace deletes an object definition, but only if no other object definition references
+// it and no relationships exist with resources or subjects of it.
+func (ss *schemaServiceServer) DeleteNamespace(ctx context.Context, in *schemav1alpha1.DeleteNamespaceRequest) (*schemav1alpha1.DeleteNamespaceResponse, error) {
+	ds := datastoremw.MustFromContext(ctx)
+	_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return shared.SafeDeleteNamespace(ctx, rwt, in.GetName())
+	})
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		DispatchCount: 1,
+	})
+
+	return &schemav1alpha1.DeleteNamespaceResponse{}, nil
+}
+
+// RenameNamespace renames an object definition, along with every relationship and every allowed
+// subject type referencing it, in a single transaction. The new name must follow the same prefix
+// rules as the names of written object definitions.
+func (ss *schemaServiceServer) RenameNamespace(ctx context.Context, in *schemav1alpha1.RenameNamespaceRequest) (*schemav1alpha1.RenameNamespaceResponse, error) {
+	if in.GetOldName() == in.GetNewName() {
+		return nil, status.Errorf(codes.InvalidArgument, "cannot rename Object Definition `%s` to itself", in.GetOldName())
+	}
+
+	if err := ss.checkDefinitionName(in.GetNewName()); err != nil {
+		return nil, err
+	}
+
+	ds := datastoremw.MustFromContext(ctx)
+	_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.RenameNamespace(in.GetOldName(), in.GetNewName())
+	})
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		DispatchCount: 1,
+	})
+
+	return &schemav1alpha1.RenameNamespaceResponse{}, nil
+}
+
+// CopyNamespace writes a copy of an object definition under a new name and, if requested, copies
+// every relationship with a resource of it to the copy, in a single transaction. The new name must
+// follow the same prefix rules as the names of written object definitions.
+func (ss *schemaServiceServer) CopyNamespace(ctx context.Context, in *schemav1alpha1.CopyNamespaceRequest) (*schemav1alpha1.CopyNamespaceResponse, error) {
+	if err := ss.checkDefinitionName(in.GetDestName()); err != nil {
+		return nil, err
+	}
+
+	var copied uint64
+	ds := datastoremw.MustFromContext(ctx)
+	_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		var err error
+		copied, err = rwt.CopyNamespace(in.GetSourceName(), in.GetDestName(), in.GetCopyTuples())
+		return err
+	})
+	if err != nil {
+		return nil, rewriteError(ctx, err)
+	}
+
+	usagemetrics.SetInContext(ctx, &dispatchv1.ResponseMeta{
+		DispatchCount: 1,
+	})
+
+	return &schemav1alpha1.CopyNamespaceResponse{CopiedTuples: copied}, nil
+}
+
+// checkDefinitionName returns an error if the name is not valid for an object definition, or does
+// not have a prefix when prefixes are required.
+func (ss *schemaServiceServer) checkDefinitionName(name string) error {
+	if err := (&core.NamespaceDefinition{Name: name}).Validate(); err != nil {
+		return status.Errorf(codes.InvalidArgument, "invalid Object Definition name `%s`: %s", name, err)
+	}
+
+	if ss.prefixRequired == PrefixRequired && !strings.Contains(name, "/") {
+		return status.Errorf(codes.InvalidArgument, "Object Definition `%s` must have a prefix", name)
+	}
+
+	return nil
+}
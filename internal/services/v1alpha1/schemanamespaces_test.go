@@ -0,0 +1,267 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/authzed/authzed-go/proto/authzed/api/v1alpha1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+func TestDeleteNamespace(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+	experimentalClient := schemav1alpha1.NewExperimentalSchemaServiceClient(conn)
+	permissionsClient := v1.NewPermissionsServiceClient(conn)
+	ctx := context.Background()
+
+	_, err := client.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{
+		Schema: `definition user {}
+
+definition document {
+	relation viewer: user
+}`,
+	})
+	require.NoError(err)
+
+	_, err = experimentalClient.DeleteNamespace(ctx, &schemav1alpha1.DeleteNamespaceRequest{Name: "unknown"})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+
+	// The user definition is referenced by the document definition.
+	_, err = experimentalClient.DeleteNamespace(ctx, &schemav1alpha1.DeleteNamespaceRequest{Name: "user"})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	require.Contains(err.Error(), "it is referenced by Object Definitions `document`")
+
+	// The document definition has a relationship.
+	rel := tuple.MustParse("document:readme#viewer@user:tom")
+	_, err = permissionsClient.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(tuple.Create(rel))},
+	})
+	require.NoError(err)
+
+	_, err = experimentalClient.DeleteNamespace(ctx, &schemav1alpha1.DeleteNamespaceRequest{Name: "document"})
+	grpcutil.RequireStatus(t, codes.FailedPrecondition, err)
+	require.Contains(err.Error(), "a Relationship exists under it")
+
+	_, err = permissionsClient.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(tuple.Delete(rel))},
+	})
+	require.NoError(err)
+
+	// Once unused, both definitions can be deleted.
+	_, err = experimentalClient.DeleteNamespace(ctx, &schemav1alpha1.DeleteNamespaceRequest{Name: "document"})
+	require.NoError(err)
+
+	_, err = experimentalClient.DeleteNamespace(ctx, &schemav1alpha1.DeleteNamespaceRequest{Name: "user"})
+	require.NoError(err)
+
+	_, err = client.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"document"},
+	})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+}
+
+func TestRenameNamespace(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := v1alpha1.NewSchemaServiceClient(conn)
+	experimentalClient := schemav1alpha1.NewExperimentalSchemaServiceClient(conn)
+	permissionsClient := v1.NewPermissionsServiceClient(conn)
+	ctx := context.Background()
+
+	_, err := client.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{
+		Schema: `definition user {}
+
+definition document {
+	relation viewer: user
+	permission view = viewer
+}`,
+	})
+	require.NoError(err)
+
+	_, err = permissionsClient.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+		Updates: []*v1.RelationshipUpdate{tuple.UpdateToRelationshipUpdate(tuple.Create(
+			tuple.MustParse("document:readme#viewer@user:tom"),
+		))},
+	})
+	require.NoError(err)
+
+	for _, tc := range []struct {
+		oldName      string
+		newName      string
+		expectedCode codes.Code
+	}{
+		{"unknown", "person", codes.NotFound},
+		{"user", "document", codes.AlreadyExists},
+		{"user", "user", codes.InvalidArgument},
+		{"user", "Not-A-Name", codes.InvalidArgument},
+		{"user", "", codes.InvalidArgument},
+	} {
+		_, err = experimentalClient.RenameNamespace(ctx, &schemav1alpha1.RenameNamespaceRequest{
+			OldName: tc.oldName,
+			NewName: tc.newName,
+		})
+		grpcutil.RequireStatus(t, tc.expectedCode, err)
+	}
+
+	_, err = experimentalClient.RenameNamespace(ctx, &schemav1alpha1.RenameNamespaceRequest{
+		OldName: "user",
+		NewName: "person",
+	})
+	require.NoError(err)
+
+	_, err = client.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"user"},
+	})
+	grpcutil.RequireStatus(t, codes.NotFound, err)
+
+	// The document now allows subjects of the renamed definition.
+	read, err := client.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"person", "document"},
+	})
+	require.NoError(err)
+	require.Len(read.ObjectDefinitions, 2)
+	require.Contains(read.ObjectDefinitions[1], "relation viewer: person")
+
+	// The relationship was renamed along with the definition, and is still checked.
+	fullyConsistent := &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}}
+	checked, err := permissionsClient.CheckPermission(ctx, &v1.CheckPermissionRequest{
+		Consistency: fullyConsistent,
+		Resource:    &v1.ObjectReference{ObjectType: "document", ObjectId: "readme"},
+		Permission:  "view",
+		Subject:     &v1.SubjectReference{Object: &v1.ObjectReference{ObjectType: "person", ObjectId: "tom"}},
+	})
+	require.NoError(err)
+	require.Equal(v1.CheckPermissionResponse_PERMISSIONSHIP_HAS_PERMISSION, checked.Permissionship)
+
+	stream, err := permissionsClient.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+		Consistency:        fullyConsistent,
+		RelationshipFilter: &v1.RelationshipFilter{ResourceType: "document"},
+	})
+	require.NoError(err)
+
+	found, err := stream.Recv()
+	require.NoError(err)
+	require.Equal("document:readme#viewer@person:tom", tuple.MustRelString(found.Relationship))
+}
+
+func TestCopyNamespace(t *testing.T) {
+	for _, copyTuples := range []bool{false, true} {
+		copyTuples := copyTuples
+		t.Run(fmt.Sprintf("copy tuples %t", copyTuples), func(t *testing.T) {
+			require := require.New(t)
+
+			conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, false, testfixtures.EmptyDatastore)
+			t.Cleanup(cleanup)
+			client := v1alpha1.NewSchemaServiceClient(conn)
+			experimentalClient := schemav1alpha1.NewExperimentalSchemaServiceClient(conn)
+			permissionsClient := v1.NewPermissionsServiceClient(conn)
+			ctx := context.Background()
+
+			_, err := client.WriteSchema(ctx, &v1alpha1.WriteSchemaRequest{
+				Schema: `definition user {}
+
+definition document {
+	relation viewer: user
+	relation editor: user
+	permission view = viewer + editor
+}`,
+			})
+			require.NoError(err)
+
+			_, err = permissionsClient.WriteRelationships(ctx, &v1.WriteRelationshipsRequest{
+				Updates: []*v1.RelationshipUpdate{
+					tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse("document:readme#viewer@user:tom"))),
+					tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.MustParse("document:readme#editor@user:fred"))),
+				},
+			})
+			require.NoError(err)
+
+			_, err = experimentalClient.CopyNamespace(ctx, &schemav1alpha1.CopyNamespaceRequest{
+				SourceName: "document",
+				DestName:   "user",
+			})
+			grpcutil.RequireStatus(t, codes.AlreadyExists, err)
+
+			_, err = experimentalClient.CopyNamespace(ctx, &schemav1alpha1.CopyNamespaceRequest{
+				SourceName: "unknown",
+				DestName:   "staging",
+			})
+			grpcutil.RequireStatus(t, codes.NotFound, err)
+
+			resp, err := experimentalClient.CopyNamespace(ctx, &schemav1alpha1.CopyNamespaceRequest{
+				SourceName: "document",
+				DestName:   "staging",
+				CopyTuples: copyTuples,
+			})
+			require.NoError(err)
+
+			relationships := func(resourceType string) []string {
+				stream, err := permissionsClient.ReadRelationships(ctx, &v1.ReadRelationshipsRequest{
+					Consistency:        &v1.Consistency{Requirement: &v1.Consistency_FullyConsistent{FullyConsistent: true}},
+					RelationshipFilter: &v1.RelationshipFilter{ResourceType: resourceType},
+				})
+				require.NoError(err)
+
+				var found []string
+				for {
+					resp, err := stream.Recv()
+					if errors.Is(err, io.EOF) {
+						break
+					}
+					require.NoError(err)
+					found = append(found, tuple.MustRelString(resp.Relationship))
+				}
+				sort.Strings(found)
+				return found
+			}
+
+			// The original is unmodified.
+			original, err := client.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+				ObjectDefinitionsNames: []string{"document"},
+			})
+			require.NoError(err)
+			require.Equal([]string{`definition document {
+	relation viewer: user
+	relation editor: user
+	permission view = viewer + editor
+}`}, original.ObjectDefinitions)
+			require.Equal([]string{"document:readme#editor@user:fred", "document:readme#viewer@user:tom"}, relationships("document"))
+
+			// The copy has the same structure under the new name.
+			copied, err := client.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+				ObjectDefinitionsNames: []string{"staging"},
+			})
+			require.NoError(err)
+			require.Equal([]string{strings.Replace(original.ObjectDefinitions[0], "definition document", "definition staging", 1)}, copied.ObjectDefinitions)
+
+			if !copyTuples {
+				require.Zero(resp.CopiedTuples)
+				require.Empty(relationships("staging"))
+				return
+			}
+
+			require.Equal(uint64(2), resp.CopiedTuples)
+			require.Equal([]string{"staging:readme#editor@user:fred", "staging:readme#viewer@user:tom"}, relationships("staging"))
+		})
+	}
+}
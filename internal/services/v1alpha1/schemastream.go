@@ -0,0 +1,63 @@
+package v1alpha1
+
+import (
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/rs/zerolog/log"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+	"github.com/authzed/spicedb/pkg/schemadsl/compiler"
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+// WriteSchemaStream receives the chunks of a schema, each holding one or more complete object
+// definitions, and once all have been received compiles them together and writes the resulting
+// object definitions in a single transaction, as WriteSchemaMulti does. Definitions may reference
+// those of any chunk. The precondition, if any, is taken from the first chunk, and later chunks
+// must either omit it or repeat it. Nothing is written if any chunk fails to be received.
+func (ss *schemaServiceServer) WriteSchemaStream(stream schemav1alpha1.ExperimentalSchemaService_WriteSchemaStreamServer) error {
+	ctx := stream.Context()
+
+	var schemas []compiler.InputSchema
+	var precondition string
+	size := 0
+	for {
+		chunk, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		if len(schemas) == 0 {
+			precondition = chunk.OptionalDefinitionsRevisionPrecondition
+		} else if chunk.OptionalDefinitionsRevisionPrecondition != "" && chunk.OptionalDefinitionsRevisionPrecondition != precondition {
+			return status.Errorf(codes.InvalidArgument, "schema chunk %d has a different precondition than the first chunk", len(schemas))
+		}
+
+		// Stop receiving as soon as the schema is too large, rather than buffering the rest.
+		size += len(chunk.GetSchema())
+		if size > ss.maxSchemaBytes {
+			return status.Errorf(codes.InvalidArgument, "schema of more than %d bytes exceeds the maximum allowed size of %d bytes", size, ss.maxSchemaBytes)
+		}
+
+		schemas = append(schemas, compiler.InputSchema{
+			Source:       input.Source(fmt.Sprintf("chunk %d", len(schemas))),
+			SchemaString: chunk.GetSchema(),
+		})
+	}
+
+	log.Ctx(ctx).Trace().Int("chunkCount", len(schemas)).Msg("received schema chunks")
+
+	resp, err := ss.writeSchemas(ctx, schemas, precondition)
+	if err != nil {
+		return err
+	}
+
+	return stream.SendAndClose(resp)
+}
@@ -0,0 +1,165 @@
+package v1alpha1_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/authzed/authzed-go/proto/authzed/api/v1alpha1"
+	"github.com/authzed/grpcutil"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+
+	"github.com/authzed/spicedb/internal/datastore/memdb"
+	datastoremw "github.com/authzed/spicedb/internal/middleware/datastore"
+	v1alpha1svc "github.com/authzed/spicedb/internal/services/v1alpha1"
+	"github.com/authzed/spicedb/internal/testfixtures"
+	"github.com/authzed/spicedb/internal/testserver"
+	schemav1alpha1 "github.com/authzed/spicedb/pkg/proto/schema/v1alpha1"
+)
+
+type chunkStream struct {
+	grpc.ServerStream
+
+	ctx    context.Context
+	chunks []*schemav1alpha1.WriteSchemaChunk
+	err    error
+	resp   *schemav1alpha1.WriteSchemaMultiResponse
+}
+
+func (cs *chunkStream) Context() context.Context {
+	return cs.ctx
+}
+
+func (cs *chunkStream) Recv() (*schemav1alpha1.WriteSchemaChunk, error) {
+	if len(cs.chunks) == 0 {
+		if cs.err != nil {
+			return nil, cs.err
+		}
+		return nil, io.EOF
+	}
+
+	chunk := cs.chunks[0]
+	cs.chunks = cs.chunks[1:]
+	return chunk, nil
+}
+
+func (cs *chunkStream) SendAndClose(resp *schemav1alpha1.WriteSchemaMultiResponse) error {
+	cs.resp = resp
+	return nil
+}
+
+func TestWriteSchemaStream(t *testing.T) {
+	require := require.New(t)
+	ctx, server, experimental := setupSchemaMulti(t, v1alpha1svc.PrefixRequired)
+
+	stream := &chunkStream{
+		ctx: ctx,
+		chunks: []*schemav1alpha1.WriteSchemaChunk{
+			{Schema: folderSchema.Schema},
+			{Schema: documentSchema.Schema},
+		},
+	}
+	require.NoError(experimental.WriteSchemaStream(stream))
+	require.ElementsMatch([]string{"example/user", "example/folder", "example/document"}, stream.resp.ObjectDefinitionsNames)
+
+	read, err := server.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"example/document"},
+	})
+	require.NoError(err)
+	require.Equal([]string{documentSchema.Schema}, read.ObjectDefinitions)
+}
+
+func TestWriteSchemaStreamOverConnection(t *testing.T) {
+	require := require.New(t)
+
+	conn, cleanup, _, _ := testserver.NewTestServer(require, 0, memdb.DisableGC, true, testfixtures.EmptyDatastore)
+	t.Cleanup(cleanup)
+	client := schemav1alpha1.NewExperimentalSchemaServiceClient(conn)
+
+	stream, err := client.WriteSchemaStream(context.Background())
+	require.NoError(err)
+	require.NoError(stream.Send(&schemav1alpha1.WriteSchemaChunk{Schema: folderSchema.Schema}))
+	require.NoError(stream.Send(&schemav1alpha1.WriteSchemaChunk{Schema: documentSchema.Schema}))
+
+	resp, err := stream.CloseAndRecv()
+	require.NoError(err)
+	require.ElementsMatch([]string{"example/user", "example/folder", "example/document"}, resp.ObjectDefinitionsNames)
+
+	read, err := v1alpha1.NewSchemaServiceClient(conn).ReadSchema(context.Background(), &v1alpha1.ReadSchemaRequest{
+		ObjectDefinitionsNames: []string{"example/document"},
+	})
+	require.NoError(err)
+	require.Equal([]string{documentSchema.Schema}, read.ObjectDefinitions)
+}
+
+func TestWriteSchemaStreamAtomic(t *testing.T) {
+	testCases := []struct {
+		name         string
+		stream       *chunkStream
+		expectedCode codes.Code
+	}{
+		{
+			"invalid later chunk",
+			&chunkStream{chunks: []*schemav1alpha1.WriteSchemaChunk{
+				{Schema: folderSchema.Schema},
+				{Schema: `definition example/document {
+	relation owner: example/unknown
+}`},
+			}},
+			codes.InvalidArgument,
+		},
+		{
+			"failed receive",
+			&chunkStream{
+				chunks: []*schemav1alpha1.WriteSchemaChunk{{Schema: folderSchema.Schema}},
+				err:    errors.New("stream broken"),
+			},
+			codes.Unknown,
+		},
+		{
+			"mismatched precondition",
+			&chunkStream{chunks: []*schemav1alpha1.WriteSchemaChunk{
+				{Schema: folderSchema.Schema},
+				{Schema: documentSchema.Schema, OptionalDefinitionsRevisionPrecondition: "somerevision"},
+			}},
+			codes.InvalidArgument,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx, server, experimental := setupSchemaMulti(t, v1alpha1svc.PrefixRequired)
+			tc.stream.ctx = ctx
+
+			err := experimental.WriteSchemaStream(tc.stream)
+			grpcutil.RequireStatus(t, tc.expectedCode, err)
+
+			// None of the definitions of the earlier chunks were written.
+			_, err = server.ReadSchema(ctx, &v1alpha1.ReadSchemaRequest{
+				ObjectDefinitionsNames: []string{"example/folder"},
+			})
+			grpcutil.RequireStatus(t, codes.NotFound, err)
+		})
+	}
+}
+
+func TestWriteSchemaStreamTooLarge(t *testing.T) {
+	ds, err := memdb.NewMemdbDatastore(0, 0, memdb.DisableGC)
+	require.NoError(t, err)
+	ctx := datastoremw.ContextWithDatastore(context.Background(), ds)
+
+	server := v1alpha1svc.NewExperimentalSchemaServer(v1alpha1svc.PrefixRequired, v1alpha1svc.WithMaxSchemaBytes(len(folderSchema.Schema)))
+
+	err = server.WriteSchemaStream(&chunkStream{
+		ctx: ctx,
+		chunks: []*schemav1alpha1.WriteSchemaChunk{
+			{Schema: folderSchema.Schema},
+			{Schema: documentSchema.Schema},
+		},
+	})
+	grpcutil.RequireStatus(t, codes.InvalidArgument, err)
+	require.Contains(t, err.Error(), "exceeds the maximum allowed size")
+}
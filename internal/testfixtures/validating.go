@@ -61,8 +61,8 @@ func (vd validatingDatastore) CheckRevision(ctx context.Context, revision datast
 	return vd.delegate.CheckRevision(ctx, revision)
 }
 
-func (vd validatingDatastore) Watch(ctx context.Context, afterRevision datastore.Revision) (<-chan *datastore.RevisionChanges, <-chan error) {
-	return vd.delegate.Watch(ctx, afterRevision)
+func (vd validatingDatastore) Watch(ctx context.Context, afterRevision datastore.Revision, opts ...options.WatchOptionsOption) (<-chan *datastore.RevisionChanges, <-chan error) {
+	return vd.delegate.Watch(ctx, afterRevision, opts...)
 }
 
 func (vd validatingDatastore) Statistics(ctx context.Context) (datastore.Stats, error) {
@@ -109,6 +109,28 @@ func (vsr validatingSnapshotReader) QueryRelationships(ctx context.Context,
 	return vsr.delegate.QueryRelationships(ctx, filter, opts...)
 }
 
+func (vsr validatingSnapshotReader) CountRelationships(ctx context.Context,
+	filter *v1.RelationshipFilter,
+) (uint64, error) {
+	if err := filter.Validate(); err != nil {
+		return 0, err
+	}
+
+	return vsr.delegate.CountRelationships(ctx, filter)
+}
+
+func (vsr validatingSnapshotReader) CheckRelationshipsExist(ctx context.Context,
+	tuples []*core.RelationTuple,
+) (map[string]bool, error) {
+	for _, tpl := range tuples {
+		if err := tpl.Validate(); err != nil {
+			return nil, err
+		}
+	}
+
+	return vsr.delegate.CheckRelationshipsExist(ctx, tuples)
+}
+
 func (vsr validatingSnapshotReader) ReadNamespace(
 	ctx context.Context,
 	nsName string,
@@ -132,12 +154,9 @@ func (vsr validatingSnapshotReader) ReverseQueryRelationships(ctx context.Contex
 
 	queryOpts := options.NewReverseQueryOptionsWithOptions(opts...)
 	if queryOpts.ResRelation != nil {
-		if queryOpts.ResRelation.Namespace == "" {
+		if queryOpts.ResRelation.Namespace == "" && queryOpts.ResRelation.Relation != "" {
 			return nil, errors.New("resource relation on reverse query missing namespace")
 		}
-		if queryOpts.ResRelation.Relation == "" {
-			return nil, errors.New("resource relation on reverse query missing relation")
-		}
 	}
 
 	return vsr.delegate.ReverseQueryRelationships(ctx, subjectFilter, opts...)
@@ -161,6 +180,14 @@ func (vrwt validatingReadWriteTransaction) DeleteNamespace(nsName string) error
 	return vrwt.delegate.DeleteNamespace(nsName)
 }
 
+func (vrwt validatingReadWriteTransaction) RenameNamespace(oldName, newName string) error {
+	return vrwt.delegate.RenameNamespace(oldName, newName)
+}
+
+func (vrwt validatingReadWriteTransaction) CopyNamespace(sourceName, destName string, copyTuples bool) (uint64, error) {
+	return vrwt.delegate.CopyNamespace(sourceName, destName, copyTuples)
+}
+
 func (vrwt validatingReadWriteTransaction) WriteRelationships(mutations []*v1.RelationshipUpdate) error {
 	if err := common.ValidateUpdatesToWrite(mutations); err != nil {
 		return err
@@ -174,9 +201,13 @@ func (vrwt validatingReadWriteTransaction) WriteRelationships(mutations []*v1.Re
 	return vrwt.delegate.WriteRelationships(mutations)
 }
 
-func (vrwt validatingReadWriteTransaction) DeleteRelationships(filter *v1.RelationshipFilter) error {
+func (vrwt validatingReadWriteTransaction) EffectiveTouches() uint64 {
+	return vrwt.delegate.EffectiveTouches()
+}
+
+func (vrwt validatingReadWriteTransaction) DeleteRelationships(filter *v1.RelationshipFilter) (uint64, error) {
 	if err := filter.Validate(); err != nil {
-		return err
+		return 0, err
 	}
 
 	return vrwt.delegate.DeleteRelationships(filter)
@@ -1,5 +1,7 @@
 package cache
 
+import "fmt"
+
 // Config for caching.
 // See: https://github.com/dgraph-io/ristretto#Config
 type Config struct {
@@ -39,6 +41,20 @@ type Config struct {
 	Metrics bool
 }
 
+// Validate returns an error if the config cannot be used to create a cache.
+func (c *Config) Validate() error {
+	if c.NumCounters <= 0 {
+		return fmt.Errorf("cache num counters must be greater than zero, got %d", c.NumCounters)
+	}
+	if c.MaxCost <= 0 {
+		return fmt.Errorf("cache max cost must be greater than zero, got %d", c.MaxCost)
+	}
+	if c.BufferItems <= 0 {
+		return fmt.Errorf("cache buffer items must be greater than zero, got %d", c.BufferItems)
+	}
+	return nil
+}
+
 // Cache defines an interface for a generic cache.
 type Cache interface {
 	// Get returns the value for the given key in the cache, if it exists.
@@ -53,6 +53,7 @@ type Config struct {
 	SplitQueryCount        uint16
 	ReadOnly               bool
 	EnableDatastoreMetrics bool
+	ValidateWrites         bool
 
 	// Bootstrap
 	BootstrapFiles     []string
@@ -64,6 +65,11 @@ type Config struct {
 	RequestHedgingMaxRequests      uint64
 	RequestHedgingQuantile         float64
 
+	// Shadowing
+	ShadowEngine string
+	ShadowURI    string
+	ShadowReads  bool
+
 	// CRDB
 	FollowerReadDelay time.Duration
 	MaxRetries        int
@@ -74,6 +80,7 @@ type Config struct {
 	HealthCheckPeriod  time.Duration
 	GCInterval         time.Duration
 	GCMaxOperationTime time.Duration
+	WatchNotifications bool
 
 	// Spanner
 	SpannerCredentialsFile string
@@ -98,14 +105,19 @@ func RegisterDatastoreFlags(cmd *cobra.Command, opts *Config) {
 	cmd.Flags().DurationVar(&opts.GCWindow, "datastore-gc-window", 24*time.Hour, "amount of time before revisions are garbage collected")
 	cmd.Flags().DurationVar(&opts.GCInterval, "datastore-gc-interval", 3*time.Minute, "amount of time between passes of garbage collection (postgres driver only)")
 	cmd.Flags().DurationVar(&opts.GCMaxOperationTime, "datastore-gc-max-operation-time", 1*time.Minute, "maximum amount of time a garbage collection pass can operate before timing out (postgres driver only)")
+	cmd.Flags().BoolVar(&opts.WatchNotifications, "datastore-watch-notifications", false, "use LISTEN/NOTIFY to wait for new changes in watch rather than polling for them (postgres driver only)")
 	cmd.Flags().DurationVar(&opts.RevisionQuantization, "datastore-revision-quantization-interval", 5*time.Second, "boundary interval to which to round the quantized revision")
 	cmd.Flags().BoolVar(&opts.ReadOnly, "datastore-readonly", false, "set the service to read-only mode")
+	cmd.Flags().BoolVar(&opts.ValidateWrites, "datastore-validate-writes", false, "reject writes of relationships which are not permitted by the schema, including writes made outside of the API")
 	cmd.Flags().StringSliceVar(&opts.BootstrapFiles, "datastore-bootstrap-files", []string{}, "bootstrap data yaml files to load")
 	cmd.Flags().BoolVar(&opts.BootstrapOverwrite, "datastore-bootstrap-overwrite", false, "overwrite any existing data with bootstrap data")
 	cmd.Flags().BoolVar(&opts.RequestHedgingEnabled, "datastore-request-hedging", true, "enable request hedging")
 	cmd.Flags().DurationVar(&opts.RequestHedgingInitialSlowValue, "datastore-request-hedging-initial-slow-value", 10*time.Millisecond, "initial value to use for slow datastore requests, before statistics have been collected")
 	cmd.Flags().Uint64Var(&opts.RequestHedgingMaxRequests, "datastore-request-hedging-max-requests", 1_000_000, "maximum number of historical requests to consider")
 	cmd.Flags().Float64Var(&opts.RequestHedgingQuantile, "datastore-request-hedging-quantile", 0.95, "quantile of historical datastore request time over which a request will be considered slow")
+	cmd.Flags().StringVar(&opts.ShadowEngine, "datastore-shadow-engine", "", fmt.Sprintf(`type of a secondary datastore to which every write is replayed, e.g. to migrate between datastores (%s)`, datastore.EngineOptions()))
+	cmd.Flags().StringVar(&opts.ShadowURI, "datastore-shadow-conn-uri", "", "connection string used by the secondary datastore, if it is remote")
+	cmd.Flags().BoolVar(&opts.ShadowReads, "datastore-shadow-reads", false, "also make every read against the secondary datastore, reporting any results which differ from the primary")
 	cmd.Flags().BoolVar(&opts.EnableDatastoreMetrics, "datastore-prometheus-metrics", true, "set to false to disabled prometheus metrics from the datastore")
 	// See crdb doc for info about follower reads and how it is configured: https://www.cockroachlabs.com/docs/stable/follower-reads.html
 	cmd.Flags().DurationVar(&opts.FollowerReadDelay, "datastore-follower-read-delay-duration", 4_800*time.Millisecond, "amount of time to subtract from non-sync revision timestamps to ensure they are sufficiently in the past to enable follower reads (cockroach driver only)")
@@ -186,6 +198,22 @@ func NewDatastore(options ...ConfigOption) (datastore.Datastore, error) {
 		}
 	}
 
+	if opts.ShadowEngine != "" {
+		ds, err = newShadowingDatastore(ds, *opts)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if opts.ValidateWrites {
+		log.Info().Msg("validating written relationships against the schema")
+		ds = proxy.NewSchemaValidatingProxy(ds)
+	}
+
+	if opts.EnableDatastoreMetrics {
+		ds = proxy.NewWatchMetricsProxy(ds)
+	}
+
 	if opts.RequestHedgingEnabled {
 		log.Info().
 			Stringer("initialSlowRequest", opts.RequestHedgingInitialSlowValue).
@@ -208,6 +236,33 @@ func NewDatastore(options ...ConfigOption) (datastore.Datastore, error) {
 	return ds, nil
 }
 
+// newShadowingDatastore builds the secondary datastore of the options, with the same options as
+// the primary other than its engine and connection string, and returns a proxy replaying the
+// writes to the primary to it. Bootstrap data is written to the primary before it is proxied, so
+// it is not replayed to the secondary.
+func newShadowingDatastore(primary datastore.Datastore, opts Config) (datastore.Datastore, error) {
+	shadowBuilder, ok := BuilderForEngine[opts.ShadowEngine]
+	if !ok {
+		return nil, fmt.Errorf("unknown shadow datastore engine type: %s", opts.ShadowEngine)
+	}
+	log.Info().Bool("shadowReads", opts.ShadowReads).Msgf("shadowing writes to %s datastore engine", opts.ShadowEngine)
+
+	shadowOpts := opts
+	shadowOpts.Engine = opts.ShadowEngine
+	shadowOpts.URI = opts.ShadowURI
+
+	secondary, err := shadowBuilder(shadowOpts)
+	if err != nil {
+		return nil, fmt.Errorf("unable to initialize shadow datastore: %w", err)
+	}
+
+	var proxyOpts []proxy.ShadowingOption
+	if opts.ShadowReads {
+		proxyOpts = append(proxyOpts, proxy.WithShadowReads())
+	}
+	return proxy.NewShadowingProxy(primary, secondary, proxyOpts...), nil
+}
+
 func newCRDBDatastore(opts Config) (datastore.Datastore, error) {
 	return crdb.NewCRDBDatastore(
 		opts.URI,
@@ -242,6 +297,7 @@ func newPostgresDatastore(opts Config) (datastore.Datastore, error) {
 		postgres.WatchBufferLength(opts.WatchBufferLength),
 		postgres.WithEnablePrometheusStats(opts.EnableDatastoreMetrics),
 		postgres.MaxRetries(uint8(opts.MaxRetries)),
+		postgres.WithWatchNotifications(opts.WatchNotifications),
 	}
 	return postgres.NewPostgresDatastore(opts.URI, pgOpts...)
 }
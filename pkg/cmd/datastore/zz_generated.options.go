@@ -29,12 +29,16 @@ func (c *Config) ToOption() ConfigOption {
 		to.SplitQueryCount = c.SplitQueryCount
 		to.ReadOnly = c.ReadOnly
 		to.EnableDatastoreMetrics = c.EnableDatastoreMetrics
+		to.ValidateWrites = c.ValidateWrites
 		to.BootstrapFiles = c.BootstrapFiles
 		to.BootstrapOverwrite = c.BootstrapOverwrite
 		to.RequestHedgingEnabled = c.RequestHedgingEnabled
 		to.RequestHedgingInitialSlowValue = c.RequestHedgingInitialSlowValue
 		to.RequestHedgingMaxRequests = c.RequestHedgingMaxRequests
 		to.RequestHedgingQuantile = c.RequestHedgingQuantile
+		to.ShadowEngine = c.ShadowEngine
+		to.ShadowURI = c.ShadowURI
+		to.ShadowReads = c.ShadowReads
 		to.FollowerReadDelay = c.FollowerReadDelay
 		to.MaxRetries = c.MaxRetries
 		to.OverlapKey = c.OverlapKey
@@ -42,6 +46,7 @@ func (c *Config) ToOption() ConfigOption {
 		to.HealthCheckPeriod = c.HealthCheckPeriod
 		to.GCInterval = c.GCInterval
 		to.GCMaxOperationTime = c.GCMaxOperationTime
+		to.WatchNotifications = c.WatchNotifications
 		to.SpannerCredentialsFile = c.SpannerCredentialsFile
 		to.SpannerEmulatorHost = c.SpannerEmulatorHost
 		to.TablePrefix = c.TablePrefix
@@ -141,6 +146,13 @@ func WithEnableDatastoreMetrics(enableDatastoreMetrics bool) ConfigOption {
 	}
 }
 
+// WithValidateWrites returns an option that can set ValidateWrites on a Config
+func WithValidateWrites(validateWrites bool) ConfigOption {
+	return func(c *Config) {
+		c.ValidateWrites = validateWrites
+	}
+}
+
 // WithBootstrapFiles returns an option that can append BootstrapFiless to Config.BootstrapFiles
 func WithBootstrapFiles(bootstrapFiles string) ConfigOption {
 	return func(c *Config) {
@@ -190,6 +202,27 @@ func WithRequestHedgingQuantile(requestHedgingQuantile float64) ConfigOption {
 	}
 }
 
+// WithShadowEngine returns an option that can set ShadowEngine on a Config
+func WithShadowEngine(shadowEngine string) ConfigOption {
+	return func(c *Config) {
+		c.ShadowEngine = shadowEngine
+	}
+}
+
+// WithShadowURI returns an option that can set ShadowURI on a Config
+func WithShadowURI(shadowURI string) ConfigOption {
+	return func(c *Config) {
+		c.ShadowURI = shadowURI
+	}
+}
+
+// WithShadowReads returns an option that can set ShadowReads on a Config
+func WithShadowReads(shadowReads bool) ConfigOption {
+	return func(c *Config) {
+		c.ShadowReads = shadowReads
+	}
+}
+
 // WithFollowerReadDelay returns an option that can set FollowerReadDelay on a Config
 func WithFollowerReadDelay(followerReadDelay time.Duration) ConfigOption {
 	return func(c *Config) {
@@ -239,6 +272,13 @@ func WithGCMaxOperationTime(gCMaxOperationTime time.Duration) ConfigOption {
 	}
 }
 
+// WithWatchNotifications returns an option that can set WatchNotifications on a Config
+func WithWatchNotifications(watchNotifications bool) ConfigOption {
+	return func(c *Config) {
+		c.WatchNotifications = watchNotifications
+	}
+}
+
 // WithSpannerCredentialsFile returns an option that can set SpannerCredentialsFile on a Config
 func WithSpannerCredentialsFile(spannerCredentialsFile string) ConfigOption {
 	return func(c *Config) {
@@ -2,10 +2,12 @@ package cmd
 
 import (
 	"context"
+	"fmt"
 	"time"
 
 	"github.com/spf13/cobra"
 
+	v1alpha1svc "github.com/authzed/spicedb/internal/services/v1alpha1"
 	"github.com/authzed/spicedb/internal/telemetry"
 	"github.com/authzed/spicedb/pkg/cmd/datastore"
 	"github.com/authzed/spicedb/pkg/cmd/server"
@@ -35,6 +37,7 @@ func RegisterServeFlags(cmd *cobra.Command, config *server.Config) {
 
 	// Flags for parsing and validating schemas.
 	cmd.Flags().BoolVar(&config.SchemaPrefixesRequired, "schema-prefixes-required", false, "require prefixes on all object definitions in schemas")
+	cmd.Flags().IntVar(&config.SchemaMaxBytes, "schema-max-bytes", v1alpha1svc.DefaultMaxSchemaBytes, fmt.Sprintf("maximum size, in bytes, of a schema that can be written, including all of the schemas written together by a multi-file or streamed write; a single WriteSchema request is additionally limited to %d bytes by the API", v1alpha1svc.MaxWriteSchemaRequestBytes))
 
 	// Flags for HTTP gateway
 	util.RegisterHTTPServerFlags(cmd.Flags(), &config.HTTPGateway, "http", "http", ":8443", false)
@@ -59,6 +62,7 @@ func RegisterServeFlags(cmd *cobra.Command, config *server.Config) {
 	util.RegisterGRPCServerFlags(cmd.Flags(), &config.DispatchServer, "dispatch-cluster", "dispatch", ":50053", false)
 	server.RegisterCacheConfigFlags(cmd.Flags(), &config.DispatchCacheConfig, "dispatch-cache")
 	server.RegisterCacheConfigFlags(cmd.Flags(), &config.ClusterDispatchCacheConfig, "dispatch-cluster-cache")
+	cmd.Flags().Uint64Var(&config.DispatchCacheRevisionQuantization, "dispatch-cache-revision-quantization", 0, "size of the windows of datastore revisions which share dispatch cache entries, trading bounded staleness for cache hits (0 disables)")
 
 	// Flags for configuring dispatch requests
 	cmd.Flags().Uint32Var(&config.DispatchMaxDepth, "dispatch-max-depth", 50, "maximum recursion depth for nested calls")
@@ -15,6 +15,7 @@ import (
 type CacheConfig struct {
 	MaxCost     string
 	NumCounters int64
+	BufferItems int64
 	Metrics     bool
 }
 
@@ -35,12 +36,22 @@ func (cc *CacheConfig) Complete() (*cache.Config, error) {
 		return nil, fmt.Errorf("error parsing cache max cost `%s`: %w", cc.MaxCost, err)
 	}
 
-	return &cache.Config{
+	bufferItems := cc.BufferItems
+	if bufferItems == 0 {
+		bufferItems = defaultBufferItems
+	}
+
+	config := &cache.Config{
 		MaxCost:     int64(maxCost),
 		NumCounters: cc.NumCounters,
 		Metrics:     cc.Metrics,
-		BufferItems: defaultBufferItems,
-	}, nil
+		BufferItems: bufferItems,
+	}
+	if err := config.Validate(); err != nil {
+		return nil, fmt.Errorf("invalid cache config: %w", err)
+	}
+
+	return config, nil
 }
 
 // RegisterCacheConfigFlags registers flags for a ristretto-based cache.
@@ -48,5 +59,6 @@ func RegisterCacheConfigFlags(flags *pflag.FlagSet, config *CacheConfig, flagPre
 	flagPrefix = stringz.DefaultEmpty(flagPrefix, "cache")
 	flags.StringVar(&config.MaxCost, flagPrefix+"-max-cost", defaultMaxCost, "the maximum cost to be stored in the cache, in bytes")
 	flags.Int64Var(&config.NumCounters, flagPrefix+"-num-counters", defaultNumCounters, "the number of keys to track")
+	flags.Int64Var(&config.BufferItems, flagPrefix+"-buffer-items", defaultBufferItems, "the number of keys per Get buffer")
 	flags.BoolVar(&config.Metrics, flagPrefix+"-metrics", false, "whether metrics should be maintained for the cache. WARNING: Incurs a performance penality.")
 }
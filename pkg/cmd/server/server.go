@@ -60,6 +60,7 @@ type Config struct {
 
 	// Schema options
 	SchemaPrefixesRequired bool
+	SchemaMaxBytes         int
 
 	// Dispatch options
 	DispatchServer               util.GRPCServerConfig
@@ -70,8 +71,9 @@ type Config struct {
 	DispatchClusterMetricsPrefix string
 	Dispatcher                   dispatch.Dispatcher
 
-	DispatchCacheConfig        CacheConfig
-	ClusterDispatchCacheConfig CacheConfig
+	DispatchCacheConfig               CacheConfig
+	ClusterDispatchCacheConfig        CacheConfig
+	DispatchCacheRevisionQuantization uint64
 
 	// API Behavior
 	DisableV1SchemaAPI bool
@@ -162,6 +164,7 @@ func (c *Config) Complete() (RunnableServer, error) {
 			),
 			combineddispatch.PrometheusSubsystem(c.DispatchClientMetricsPrefix),
 			combineddispatch.CacheConfig(cc),
+			combineddispatch.RevisionQuantization(c.DispatchCacheRevisionQuantization),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to create dispatcher: %w", err)
@@ -188,6 +191,7 @@ func (c *Config) Complete() (RunnableServer, error) {
 			dispatcher,
 			clusterdispatch.PrometheusSubsystem(c.DispatchClusterMetricsPrefix),
 			clusterdispatch.CacheConfig(cdcc),
+			clusterdispatch.RevisionQuantization(c.DispatchCacheRevisionQuantization),
 		)
 		if err != nil {
 			return nil, fmt.Errorf("failed to configure cluster dispatch: %w", err)
@@ -210,6 +214,11 @@ func (c *Config) Complete() (RunnableServer, error) {
 		prefixRequiredOption = v1alpha1svc.PrefixNotRequired
 	}
 
+	var schemaServerOptions []v1alpha1svc.SchemaServerOption
+	if c.SchemaMaxBytes > 0 {
+		schemaServerOptions = append(schemaServerOptions, v1alpha1svc.WithMaxSchemaBytes(c.SchemaMaxBytes))
+	}
+
 	v1SchemaServiceOption := services.V1SchemaServiceEnabled
 	if c.DisableV1SchemaAPI {
 		v1SchemaServiceOption = services.V1SchemaServiceDisabled
@@ -229,6 +238,7 @@ func (c *Config) Complete() (RunnableServer, error) {
 				c.DispatchMaxDepth,
 				prefixRequiredOption,
 				v1SchemaServiceOption,
+				schemaServerOptions...,
 			)
 		},
 	)
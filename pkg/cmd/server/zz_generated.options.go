@@ -39,6 +39,7 @@ func (c *Config) ToOption() ConfigOption {
 		to.Datastore = c.Datastore
 		to.NamespaceCacheConfig = c.NamespaceCacheConfig
 		to.SchemaPrefixesRequired = c.SchemaPrefixesRequired
+		to.SchemaMaxBytes = c.SchemaMaxBytes
 		to.DispatchServer = c.DispatchServer
 		to.DispatchMaxDepth = c.DispatchMaxDepth
 		to.DispatchUpstreamAddr = c.DispatchUpstreamAddr
@@ -48,6 +49,7 @@ func (c *Config) ToOption() ConfigOption {
 		to.Dispatcher = c.Dispatcher
 		to.DispatchCacheConfig = c.DispatchCacheConfig
 		to.ClusterDispatchCacheConfig = c.ClusterDispatchCacheConfig
+		to.DispatchCacheRevisionQuantization = c.DispatchCacheRevisionQuantization
 		to.DisableV1SchemaAPI = c.DisableV1SchemaAPI
 		to.DashboardAPI = c.DashboardAPI
 		to.MetricsAPI = c.MetricsAPI
@@ -182,6 +184,13 @@ func WithSchemaPrefixesRequired(schemaPrefixesRequired bool) ConfigOption {
 	}
 }
 
+// WithSchemaMaxBytes returns an option that can set SchemaMaxBytes on a Config
+func WithSchemaMaxBytes(schemaMaxBytes int) ConfigOption {
+	return func(c *Config) {
+		c.SchemaMaxBytes = schemaMaxBytes
+	}
+}
+
 // WithDispatchServer returns an option that can set DispatchServer on a Config
 func WithDispatchServer(dispatchServer util.GRPCServerConfig) ConfigOption {
 	return func(c *Config) {
@@ -245,6 +254,13 @@ func WithClusterDispatchCacheConfig(clusterDispatchCacheConfig CacheConfig) Conf
 	}
 }
 
+// WithDispatchCacheRevisionQuantization returns an option that can set DispatchCacheRevisionQuantization on a Config
+func WithDispatchCacheRevisionQuantization(dispatchCacheRevisionQuantization uint64) ConfigOption {
+	return func(c *Config) {
+		c.DispatchCacheRevisionQuantization = dispatchCacheRevisionQuantization
+	}
+}
+
 // WithDisableV1SchemaAPI returns an option that can set DisableV1SchemaAPI on a Config
 func WithDisableV1SchemaAPI(disableV1SchemaAPI bool) ConfigOption {
 	return func(c *Config) {
@@ -32,6 +32,11 @@ func (ews *ErrorWithSource) InnerError() error {
 	return ews.error
 }
 
+// Unwrap returns the inner, wrapped error.
+func (ews *ErrorWithSource) Unwrap() error {
+	return ews.error
+}
+
 // NewErrorWithSource creates and returns a new ErrorWithSource.
 func NewErrorWithSource(err error, sourceCodeString string, oneIndexedLineNumber uint64, oneIndexedColumnPosition uint64) *ErrorWithSource {
 	return &ErrorWithSource{err, sourceCodeString, oneIndexedLineNumber, oneIndexedColumnPosition}
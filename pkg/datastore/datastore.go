@@ -2,6 +2,7 @@ package datastore
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -11,6 +12,7 @@ import (
 
 	"github.com/authzed/spicedb/internal/datastore/options"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
 )
 
 var Engines = []string{}
@@ -40,6 +42,19 @@ const Ellipsis = "..."
 type RevisionChanges struct {
 	Revision Revision
 	Changes  []*core.RelationTupleUpdate
+
+	// IsCheckpoint indicates that this is not a transaction, but a checkpoint
+	// carrying the head revision of the datastore when the watch was started,
+	// and therefore has no changes.
+	IsCheckpoint bool
+
+	// IsGap indicates that this is not a transaction, but a marker that the changes of the
+	// revisions from GapStartRevision up to and including Revision were dropped because the
+	// consumer fell behind, and therefore has no changes. The changes of GapStartRevision may
+	// have been partially received before the gap. A consumer can resync by reading the
+	// relationships at Revision, after which the following changes apply as usual.
+	IsGap            bool
+	GapStartRevision Revision
 }
 
 type Reader interface {
@@ -57,6 +72,14 @@ type Reader interface {
 		options ...options.ReverseQueryOptionsOption,
 	) (RelationshipIterator, error)
 
+	// CountRelationships returns the number of relationships matching the filter, without
+	// reading them.
+	CountRelationships(ctx context.Context, filter *v1.RelationshipFilter) (uint64, error)
+
+	// CheckRelationshipsExist returns whether each of the given tuples exists, keyed by
+	// tuple.String.
+	CheckRelationshipsExist(ctx context.Context, tuples []*core.RelationTuple) (map[string]bool, error)
+
 	// ReadNamespace reads a namespace definition and the revision at which it was created or
 	// last written. It returns an instance of ErrNamespaceNotFound if not found.
 	ReadNamespace(ctx context.Context, nsName string) (ns *core.NamespaceDefinition, lastWritten Revision, err error)
@@ -69,16 +92,33 @@ type ReadWriteTransaction interface {
 	Reader
 
 	// WriteRelationships takes a list of tuple mutations and applies them to the datastore.
+	// Touches of relationships which are already living are skipped, so that they are neither
+	// rewritten nor reported as changed by watch.
 	WriteRelationships(mutations []*v1.RelationshipUpdate) error
 
-	// DeleteRelationships deletes all Relationships that match the provided filter.
-	DeleteRelationships(filter *v1.RelationshipFilter) error
+	// EffectiveTouches returns the number of touches written so far in the transaction which
+	// created a relationship, rather than finding it already living.
+	EffectiveTouches() uint64
+
+	// DeleteRelationships deletes all Relationships that match the provided filter, and returns
+	// the number of relationships deleted.
+	DeleteRelationships(filter *v1.RelationshipFilter) (uint64, error)
 
 	// WriteNamespaces takes proto namespace definitions and persists them.
 	WriteNamespaces(newConfigs ...*core.NamespaceDefinition) error
 
 	// DeleteNamespace deletes a namespace and any associated tuples.
 	DeleteNamespace(nsName string) error
+
+	// RenameNamespace renames a namespace and rewrites every tuple that references it,
+	// as either the resource or the subject, and every definition whose relations allow it
+	// as a subject type, to use the new name.
+	RenameNamespace(oldName, newName string) error
+
+	// CopyNamespace writes a copy of a namespace definition under a new name. If copyTuples
+	// is true, all tuples whose resource is in the source namespace are copied into the new
+	// namespace as well. Returns the number of tuples copied.
+	CopyNamespace(sourceName, destName string, copyTuples bool) (uint64, error)
 }
 
 // TxUserFunc is a type for the function that users supply when they invoke a read-write transaction.
@@ -108,8 +148,10 @@ type Datastore interface {
 
 	// Watch notifies the caller about all changes to tuples.
 	//
-	// All events following afterRevision will be sent to the caller.
-	Watch(ctx context.Context, afterRevision Revision) (<-chan *RevisionChanges, <-chan error)
+	// All events following afterRevision will be sent to the caller. If namespaces are
+	// specified via options.WithNamespaces, only changes to tuples with resources in those
+	// namespaces are sent, and revisions without any such changes are skipped.
+	Watch(ctx context.Context, afterRevision Revision, opts ...options.WatchOptionsOption) (<-chan *RevisionChanges, <-chan error)
 
 	// IsReady returns whether the datastore is ready to accept data. Datastores that require
 	// database schema creation will return false until the migrations have been run to create
@@ -123,6 +165,67 @@ type Datastore interface {
 	Close() error
 }
 
+// HeadSnapshotReader returns a read-only handle which reads the datastore at its current head
+// revision, along with that revision. All reads made through the handle are consistent with each
+// other, and do not observe any changes written after it was created.
+func HeadSnapshotReader(ctx context.Context, ds Datastore) (Reader, Revision, error) {
+	headRevision, err := ds.HeadRevision(ctx)
+	if err != nil {
+		return nil, NoRevision, err
+	}
+
+	return ds.SnapshotReader(headRevision), headRevision, nil
+}
+
+// WriteTuples applies all of the updates in a single read-write transaction, and returns the
+// revision at which they were written. As they share that revision, they are reported by Watch
+// as a single RevisionChanges, rather than creating a revision per update.
+func WriteTuples(ctx context.Context, ds Datastore, updates []*core.RelationTupleUpdate) (Revision, error) {
+	return ds.ReadWriteTx(ctx, func(ctx context.Context, rwt ReadWriteTransaction) error {
+		return rwt.WriteRelationships(tuple.UpdatesToRelationshipUpdates(updates))
+	})
+}
+
+// DeletePreview describes the relationships that deleting with a filter would delete.
+type DeletePreview struct {
+	// Count is the number of relationships that would be deleted.
+	Count uint64
+
+	// Sample is some of the relationships that would be deleted, at most the requested number.
+	Sample []*core.RelationTuple
+}
+
+// PreviewDeleteRelationships returns the relationships which DeleteRelationships would delete
+// with the filter at the reader's revision, without deleting them. Datastores match relationship
+// filters identically when querying and deleting, so the preview matches the deletion made at
+// the same revision.
+func PreviewDeleteRelationships(ctx context.Context, reader Reader, filter *v1.RelationshipFilter, sampleSize uint64) (*DeletePreview, error) {
+	count, err := reader.CountRelationships(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	preview := &DeletePreview{Count: count}
+	if sampleSize == 0 || count == 0 {
+		return preview, nil
+	}
+
+	iter, err := reader.QueryRelationships(ctx, filter, options.WithLimit(&sampleSize))
+	if err != nil {
+		return nil, err
+	}
+	defer iter.Close()
+
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		preview.Sample = append(preview.Sample, tpl)
+	}
+	if err := iter.Err(); err != nil {
+		return nil, err
+	}
+
+	return preview, nil
+}
+
 // ObjectTypeStat represents statistics for a single object type (namespace).
 type ObjectTypeStat struct {
 	// NumRelations is the number of relations defined in a single object type.
@@ -155,16 +258,174 @@ type RelationshipIterator interface {
 	// After receiving a nil response, the caller must check for an error.
 	Err() error
 
+	// Cursor returns the last tuple returned by Next, or nil if none was. When querying with
+	// options.WithCursor, it can be used to fetch the next page of results.
+	Cursor() *core.RelationTuple
+
 	// Close cancels the query and closes any open connections.
 	Close()
 }
 
+// RevisionMetadata holds the revisions at which a relationship was created and deleted.
+type RevisionMetadata struct {
+	// CreatedRevision is the revision at which the relationship was written.
+	CreatedRevision Revision
+
+	// DeletedRevision is the revision at which the relationship was deleted, which follows the
+	// revision at which it was read, or NoRevision if it has not been deleted.
+	DeletedRevision Revision
+}
+
+// RevisionMetadataReporter is implemented by relationship iterators which can report the revision
+// metadata of the relationships they return, when queried with options.WithRevisionMetadata.
+type RevisionMetadataReporter interface {
+	// RevisionMetadata returns the revision metadata of the last tuple returned by Next, and
+	// false if none was returned or the query did not include revision metadata.
+	RevisionMetadata() (RevisionMetadata, bool)
+}
+
+// HistoricalQuerier is implemented by datastores which keep the relationships deleted within their
+// garbage collection window, and can query them along with the living ones, such as to find who
+// had access to a resource and when.
+type HistoricalQuerier interface {
+	// QueryTuplesIncludingDeleted returns the relationships matching the filter which were living
+	// at any revision from fromRevision through toRevision: those created at or before
+	// toRevision, and not deleted at or before fromRevision. A relationship which was deleted and
+	// written again is returned once for each of its lifetimes. The iterator is a
+	// RevisionMetadataReporter reporting the lifetime of each relationship, whose DeletedRevision
+	// is NoRevision if it has not been deleted.
+	QueryTuplesIncludingDeleted(ctx context.Context, filter *v1.RelationshipFilter, fromRevision, toRevision Revision) (RelationshipIterator, error)
+}
+
 // Revision is a type alias to make changing the revision type a little bit
 // easier if we need to do it in the future. Implementations should code
 // directly against decimal.Decimal when creating or parsing.
+//
+// Revisions of a datastore are totally ordered, later revisions comparing as greater, so
+// consumers can order them with the Cmp, GreaterThan, LessThan and Equal methods of
+// decimal.Decimal, and convert them to strings and back with String and RevisionFromString.
 type Revision = decimal.Decimal
 
 // NoRevision is a zero type for the revision that will make changing the
 // revision type in the future a bit easier if necessary. Implementations
 // should use any time they want to signal an empty/error revision.
 var NoRevision Revision
+
+// RevisionFromString parses a revision from its String form. For every revision r returned by a
+// datastore, RevisionFromString(r.String()) is equal to r. It returns an ErrMalformedRevision if
+// the string is not a revision.
+func RevisionFromString(revisionString string) (Revision, error) {
+	revision, err := decimal.NewFromString(revisionString)
+	if err != nil {
+		return NoRevision, NewMalformedRevisionErr(revisionString, err)
+	}
+
+	if revision.IsNegative() {
+		return NoRevision, NewMalformedRevisionErr(revisionString, errors.New("revisions cannot be negative"))
+	}
+
+	return revision, nil
+}
+
+// ConflictMode determines how a bulk import handles relationships which already exist.
+type ConflictMode int
+
+const (
+	// ConflictError fails the whole import with ErrRelationshipsExist if any imported
+	// relationship already exists or is imported more than once.
+	ConflictError ConflictMode = iota
+
+	// ConflictSkip skips imported relationships which already exist or are imported more than
+	// once, importing the rest.
+	ConflictSkip
+)
+
+// BulkImporter is implemented by datastores which can import large numbers of relationships
+// more efficiently than by writing them in a read-write transaction.
+type BulkImporter interface {
+	// BulkImportRelationships writes the relationships in a single transaction, returning the
+	// number of relationships written and the revision at which they were written.
+	BulkImportRelationships(ctx context.Context, tuples []*core.RelationTuple, onConflict ConflictMode) (uint64, Revision, error)
+}
+
+// ReadyStatus is the readiness of a datastore, as reported by ReadyStateReporter.
+type ReadyStatus int
+
+const (
+	// ReadyStatusReady indicates that the datastore is ready to serve requests.
+	ReadyStatusReady ReadyStatus = iota
+
+	// ReadyStatusPoolExhausted indicates that the database is reachable, but that every
+	// connection of the pool is in use, so requests will wait for a connection.
+	ReadyStatusPoolExhausted
+
+	// ReadyStatusUnavailable indicates that the database could not be reached.
+	ReadyStatusUnavailable
+
+	// ReadyStatusMigrationRequired indicates that the database is reachable, but is not
+	// migrated to the version required by this version of SpiceDB.
+	ReadyStatusMigrationRequired
+)
+
+// ReadyState describes the readiness of a datastore in more detail than IsReady.
+type ReadyState struct {
+	Status ReadyStatus
+
+	// Message describes why the datastore is not ready, and is empty if it is.
+	Message string
+
+	// AcquiredConns, IdleConns and MaxConns are the number of connections of the pool which are
+	// in use, idle, and allowed.
+	AcquiredConns uint32
+	IdleConns     uint32
+	MaxConns      uint32
+}
+
+// ReadyStateReporter is implemented by datastores backed by a connection pool which can
+// distinguish between their database being unreachable and their pool being exhausted.
+type ReadyStateReporter interface {
+	// ReadyState returns the readiness of the datastore. An error is only returned if the
+	// readiness could not be determined, not if the datastore is not ready.
+	ReadyState(ctx context.Context) (ReadyState, error)
+}
+
+// WatchRevisionReporter is implemented by datastores which can report the oldest revision from
+// which a watch can be resumed. Their watches fail with ErrWatchRevisionTooOld when started after
+// an older revision, since some of the changes after it may have been garbage collected.
+type WatchRevisionReporter interface {
+	// MinWatchRevision returns the oldest revision after which a watch is guaranteed to
+	// receive every change.
+	MinWatchRevision(ctx context.Context) (Revision, error)
+}
+
+// NamespaceChangeReporter is implemented by readers which can report when the relationships or
+// definition of a namespace were last changed, as of the revision at which they read.
+type NamespaceChangeReporter interface {
+	// LastNamespaceChange returns the latest revision, no later than the revision of the reader,
+	// at which a relationship with a resource of the namespace was written or deleted, or at
+	// which the namespace itself was written or deleted. NoRevision is returned if no such
+	// change is known.
+	LastNamespaceChange(ctx context.Context, nsName string) (Revision, error)
+}
+
+// NamespaceChange represents a change to the definition of a single namespace.
+type NamespaceChange struct {
+	// Revision is the revision at which the namespace was written or deleted.
+	Revision Revision
+
+	// Name is the name of the changed namespace.
+	Name string
+
+	// Definition is the definition of the namespace as of Revision, or nil if the namespace
+	// was deleted.
+	Definition *core.NamespaceDefinition
+}
+
+// NamespaceWatcher is implemented by datastores which can notify of changes to namespace
+// definitions, such as those made by writing a schema.
+type NamespaceWatcher interface {
+	// WatchNamespaces notifies the caller of each namespace written or deleted after the
+	// specified revision, in revision order. A namespace written again with an unchanged
+	// definition is still reported.
+	WatchNamespaces(ctx context.Context, afterRevision Revision) (<-chan *NamespaceChange, <-chan error)
+}
@@ -2,6 +2,7 @@ package datastore
 
 import (
 	"fmt"
+	"time"
 
 	"github.com/rs/zerolog"
 )
@@ -22,6 +23,23 @@ func (enf ErrNamespaceNotFound) MarshalZerologObject(e *zerolog.Event) {
 	e.Str("error", enf.Error()).Str("namespace", enf.namespaceName)
 }
 
+// ErrNamespaceAlreadyExists occurs when a namespace would be written under the name of an existing
+// namespace, such as when renaming or copying another namespace to it.
+type ErrNamespaceAlreadyExists struct {
+	error
+	namespaceName string
+}
+
+// ExistingNamespaceName is the name of the namespace which already exists.
+func (eae ErrNamespaceAlreadyExists) ExistingNamespaceName() string {
+	return eae.namespaceName
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (eae ErrNamespaceAlreadyExists) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("error", eae.Error()).Str("namespace", eae.namespaceName)
+}
+
 // ErrWatchDisconnected occurs when a watch has fallen too far behind and was forcibly disconnected
 // as a result.
 type ErrWatchDisconnected struct{ error }
@@ -29,10 +47,94 @@ type ErrWatchDisconnected struct{ error }
 // ErrWatchCanceled occurs when a watch was canceled by the caller
 type ErrWatchCanceled struct{ error }
 
+// ErrWatchRevisionTooOld occurs when a watch is started after a revision older than the oldest
+// revision from which the datastore still has every change, so the consumer must resync instead.
+type ErrWatchRevisionTooOld struct {
+	error
+	revision    Revision
+	minRevision Revision
+}
+
+// Revision is the revision after which the watch was started.
+func (err ErrWatchRevisionTooOld) Revision() Revision {
+	return err.revision
+}
+
+// MinRevision is the oldest revision after which a watch could be started.
+func (err ErrWatchRevisionTooOld) MinRevision() Revision {
+	return err.minRevision
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrWatchRevisionTooOld) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("error", err.Error()).Str("revision", err.revision.String()).Str("min_revision", err.minRevision.String())
+}
+
+// ErrWatchTimedOut occurs when a watch could not load the next changes within the configured
+// timeout.
+type ErrWatchTimedOut struct{ error }
+
+// ErrWatchClosing occurs when a watch was stopped because the datastore is closing. Every change
+// through its last revision was delivered before it, so the watch can be resumed after it.
+type ErrWatchClosing struct {
+	error
+	lastRevision Revision
+}
+
+// LastRevision is the revision through which the changes were delivered.
+func (err ErrWatchClosing) LastRevision() Revision {
+	return err.lastRevision
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrWatchClosing) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("error", err.Error()).Str("last_revision", err.lastRevision.String())
+}
+
+// ErrQueryTimedOut occurs when a query did not complete within the configured timeout.
+type ErrQueryTimedOut struct{ error }
+
+// ErrResultSetTooLarge occurs when a query matches more relationships than the maximum number
+// of results it was allowed to return.
+type ErrResultSetTooLarge struct {
+	error
+	maxResults uint64
+}
+
+// MaxResults is the maximum number of results the query was allowed to return.
+func (err ErrResultSetTooLarge) MaxResults() uint64 {
+	return err.maxResults
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrResultSetTooLarge) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("error", err.Error()).Uint64("max_results", err.maxResults)
+}
+
+// ErrRelationshipsExist occurs when a bulk import fails because imported relationships already
+// exist.
+type ErrRelationshipsExist struct{ error }
+
 // ErrReadOnly is returned when the operation cannot be completed because the datastore is in
 // read-only mode.
 type ErrReadOnly struct{ error }
 
+// ErrMalformedRevision occurs when a string could not be parsed as a revision.
+type ErrMalformedRevision struct {
+	error
+	revisionString string
+}
+
+// RevisionString is the string which could not be parsed.
+func (err ErrMalformedRevision) RevisionString() string {
+	return err.revisionString
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (err ErrMalformedRevision) MarshalZerologObject(e *zerolog.Event) {
+	e.Str("error", err.Error()).Str("revision", err.revisionString)
+}
+
 // InvalidRevisionReason is the reason the revision could not be used.
 type InvalidRevisionReason int
 
@@ -89,6 +191,14 @@ func NewNamespaceNotFoundErr(nsName string) error {
 	}
 }
 
+// NewNamespaceAlreadyExistsErr constructs a new namespace already exists error.
+func NewNamespaceAlreadyExistsErr(nsName string) error {
+	return ErrNamespaceAlreadyExists{
+		error:         fmt.Errorf("object definition `%s` already exists", nsName),
+		namespaceName: nsName,
+	}
+}
+
 // NewWatchDisconnectedErr constructs a new watch was disconnected error.
 func NewWatchDisconnectedErr() error {
 	return ErrWatchDisconnected{
@@ -103,6 +213,52 @@ func NewWatchCanceledErr() error {
 	}
 }
 
+// NewWatchRevisionTooOldErr constructs a new watch revision too old error.
+func NewWatchRevisionTooOldErr(revision Revision, minRevision Revision) error {
+	return ErrWatchRevisionTooOld{
+		error:       fmt.Errorf("cannot watch after revision %s, as changes before revision %s may have been garbage collected", revision, minRevision),
+		revision:    revision,
+		minRevision: minRevision,
+	}
+}
+
+// NewWatchTimedOutErr constructs a new watch timed out error.
+func NewWatchTimedOutErr(timeout time.Duration) error {
+	return ErrWatchTimedOut{
+		error: fmt.Errorf("watch did not load changes within %s", timeout),
+	}
+}
+
+// NewWatchClosingErr constructs a new watch closing error.
+func NewWatchClosingErr(lastRevision Revision) error {
+	return ErrWatchClosing{
+		error:        fmt.Errorf("watch stopped as the datastore is closing, after delivering the changes through revision %s", lastRevision),
+		lastRevision: lastRevision,
+	}
+}
+
+// NewQueryTimedOutErr constructs a new query timed out error.
+func NewQueryTimedOutErr(timeout time.Duration) error {
+	return ErrQueryTimedOut{
+		error: fmt.Errorf("query did not complete within %s", timeout),
+	}
+}
+
+// NewResultSetTooLargeErr constructs a new result set too large error.
+func NewResultSetTooLargeErr(maxResults uint64) error {
+	return ErrResultSetTooLarge{
+		error:      fmt.Errorf("query matched more than the maximum of %d relationships", maxResults),
+		maxResults: maxResults,
+	}
+}
+
+// NewRelationshipsExistErr constructs a new relationships already exist error.
+func NewRelationshipsExistErr() error {
+	return ErrRelationshipsExist{
+		error: fmt.Errorf("one or more imported relationships already exist"),
+	}
+}
+
 // NewReadonlyErr constructs an error for when a request has failed because
 // the datastore has been configured to be read-only.
 func NewReadonlyErr() error {
@@ -111,6 +267,14 @@ func NewReadonlyErr() error {
 	}
 }
 
+// NewMalformedRevisionErr constructs a new malformed revision error.
+func NewMalformedRevisionErr(revisionString string, err error) error {
+	return ErrMalformedRevision{
+		error:          fmt.Errorf("malformed revision `%s`: %w", revisionString, err),
+		revisionString: revisionString,
+	}
+}
+
 // NewInvalidRevisionErr constructs a new invalid revision error.
 func NewInvalidRevisionErr(revision Revision, reason InvalidRevisionReason) error {
 	switch reason {
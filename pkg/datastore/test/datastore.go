@@ -35,20 +35,48 @@ func (f DatastoreTesterFunc) New(revisionQuantization, gcWindow time.Duration, w
 // All runs all generic datastore tests on a DatastoreTester.
 func All(t *testing.T, tester DatastoreTester) {
 	t.Run("TestNamespaceWrite", func(t *testing.T) { NamespaceWriteTest(t, tester) })
+	t.Run("TestNamespaceModifiedRevision", func(t *testing.T) { NamespaceModifiedRevisionTest(t, tester) })
 	t.Run("TestNamespaceDelete", func(t *testing.T) { NamespaceDeleteTest(t, tester) })
 	t.Run("TestEmptyNamespaceDelete", func(t *testing.T) { EmptyNamespaceDeleteTest(t, tester) })
+	t.Run("TestNamespaceRename", func(t *testing.T) { NamespaceRenameTest(t, tester) })
+	t.Run("TestNamespaceCopy", func(t *testing.T) { NamespaceCopyTest(t, tester) })
 
 	t.Run("TestSimple", func(t *testing.T) { SimpleTest(t, tester) })
 	t.Run("TestDeleteRelationships", func(t *testing.T) { DeleteRelationshipsTest(t, tester) })
+	t.Run("TestDeleteRelationshipsPreview", func(t *testing.T) { DeleteRelationshipsPreviewTest(t, tester) })
+	t.Run("TestCountRelationships", func(t *testing.T) { CountRelationshipsTest(t, tester) })
+	t.Run("TestReverseQueryExcludedSubjects", func(t *testing.T) { ReverseQueryExcludedSubjectsTest(t, tester) })
+	t.Run("TestReverseQueryEllipsisRelation", func(t *testing.T) { ReverseQueryEllipsisRelationTest(t, tester) })
+	t.Run("TestReverseQueryWildcardSubject", func(t *testing.T) { ReverseQueryWildcardSubjectTest(t, tester) })
+	t.Run("TestQueryCursor", func(t *testing.T) { QueryCursorTest(t, tester) })
+	t.Run("TestQueryMaxResults", func(t *testing.T) { QueryMaxResultsTest(t, tester) })
+	t.Run("TestCaseInsensitiveObjectID", func(t *testing.T) { CaseInsensitiveObjectIDTest(t, tester) })
+	t.Run("TestMultipleResourceTypesQuery", func(t *testing.T) { MultipleResourceTypesQueryTest(t, tester) })
+	t.Run("TestCheckRelationshipsExist", func(t *testing.T) { CheckRelationshipsExistTest(t, tester) })
 	t.Run("TestInvalidReads", func(t *testing.T) { InvalidReadsTest(t, tester) })
 	t.Run("TestUsersets", func(t *testing.T) { UsersetsTest(t, tester) })
+	t.Run("TestHeadSnapshotReader", func(t *testing.T) { HeadSnapshotReaderTest(t, tester) })
+	t.Run("TestStreamRelationshipsCancel", func(t *testing.T) { StreamRelationshipsCancelTest(t, tester) })
+	t.Run("TestExportSnapshotRoundTrip", func(t *testing.T) { ExportSnapshotRoundTripTest(t, tester) })
 	t.Run("TestMultipleReadsInRWT", func(t *testing.T) { MultipleReadsInRWTTest(t, tester) })
 	t.Run("TestConcurrentWriteSerialization", func(t *testing.T) { ConcurrentWriteSerializationTest(t, tester) })
+	t.Run("TestHistoricalQuery", func(t *testing.T) { HistoricalQueryTest(t, tester) })
 
 	t.Run("TestRevisionQuantization", func(t *testing.T) { RevisionQuantizationTest(t, tester) })
+	t.Run("TestRevisionValidity", func(t *testing.T) { RevisionValidityTest(t, tester) })
+	t.Run("TestRevisionOrdering", func(t *testing.T) { RevisionOrderingTest(t, tester) })
 
 	t.Run("TestWatch", func(t *testing.T) { WatchTest(t, tester) })
 	t.Run("TestWatchCancel", func(t *testing.T) { WatchCancelTest(t, tester) })
+	t.Run("TestTouchLivingRelationship", func(t *testing.T) { TouchLivingRelationshipTest(t, tester) })
+	t.Run("TestWatchNamespaceFilter", func(t *testing.T) { WatchNamespaceFilterTest(t, tester) })
+	t.Run("TestWatchCheckpoint", func(t *testing.T) { WatchCheckpointTest(t, tester) })
+	t.Run("TestWatchCheckpointAbandoned", func(t *testing.T) { WatchCheckpointAbandonedTest(t, tester) })
+	t.Run("TestWatchSlowReader", func(t *testing.T) { WatchSlowReaderTest(t, tester) })
+	t.Run("TestWatchOverflowDropAndSignal", func(t *testing.T) { WatchOverflowDropAndSignalTest(t, tester) })
+	t.Run("TestWatchRevisionTooOld", func(t *testing.T) { WatchRevisionTooOldTest(t, tester) })
+	t.Run("TestWatchNamespaces", func(t *testing.T) { WatchNamespacesTest(t, tester) })
+	t.Run("TestWriteTuplesBatch", func(t *testing.T) { WriteTuplesBatchTest(t, tester) })
 
 	t.Run("TestStats", func(t *testing.T) { StatsTest(t, tester) })
 }
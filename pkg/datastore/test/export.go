@@ -0,0 +1,106 @@
+package test
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// ExportSnapshotRoundTripTest tests that importing an export into an empty datastore reproduces
+// the exported datastore.
+func ExportSnapshotRoundTripTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	source, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer source.Close()
+
+	setupDatastore(source, require)
+
+	// Write more relationships than fit in a single page of the export.
+	var updates []*v1.RelationshipUpdate
+	for i := 0; i < 1200; i++ {
+		tpl := makeTestTuple(fmt.Sprintf("resource%d", i), fmt.Sprintf("user%d", i%7))
+		updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tpl)))
+	}
+	_, err = source.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	exportedAt, items, errs := datastore.ExportSnapshot(ctx, source)
+
+	// Writes made after the export started are not exported.
+	_, err = source.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(makeTestTuple("late", "user0"))),
+		})
+	})
+	require.NoError(err)
+
+	var nsDefs []*core.NamespaceDefinition
+	var exported []*v1.RelationshipUpdate
+	for item := range items {
+		if item.Namespace != nil {
+			require.Empty(exported, "namespaces must be exported before relationships")
+			nsDefs = append(nsDefs, item.Namespace)
+			continue
+		}
+		exported = append(exported, tuple.UpdateToRelationshipUpdate(tuple.Create(item.Relationship)))
+	}
+	require.NoError(<-errs)
+	require.Len(nsDefs, 2)
+	require.Len(exported, len(updates))
+
+	destination, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer destination.Close()
+
+	importedAt, err := destination.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		if err := rwt.WriteNamespaces(nsDefs...); err != nil {
+			return err
+		}
+		return rwt.WriteRelationships(exported)
+	})
+	require.NoError(err)
+
+	sourceReader := source.SnapshotReader(exportedAt)
+	destinationReader := destination.SnapshotReader(importedAt)
+
+	destinationNamespaces, err := destinationReader.ListNamespaces(ctx)
+	require.NoError(err)
+	require.Len(destinationNamespaces, len(nsDefs))
+
+	for _, filter := range []*v1.RelationshipFilter{
+		{ResourceType: testResourceNamespace},
+		{ResourceType: testResourceNamespace, OptionalResourceId: "resource42"},
+		{ResourceType: testResourceNamespace, OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: testUserNamespace, OptionalSubjectId: "user3"}},
+		{ResourceType: testUserNamespace},
+	} {
+		require.Equal(queryAll(ctx, require, sourceReader, filter), queryAll(ctx, require, destinationReader, filter))
+	}
+}
+
+func queryAll(ctx context.Context, require *require.Assertions, reader datastore.Reader, filter *v1.RelationshipFilter) []string {
+	iter, err := reader.QueryRelationships(ctx, filter)
+	require.NoError(err)
+	defer iter.Close()
+
+	var found []string
+	for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+		found = append(found, tuple.String(tpl))
+	}
+	require.NoError(iter.Err())
+
+	sort.Strings(found)
+	return found
+}
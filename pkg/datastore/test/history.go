@@ -0,0 +1,101 @@
+package test
+
+import (
+	"context"
+	"testing"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/datastore"
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+	"github.com/authzed/spicedb/pkg/tuple"
+)
+
+// HistoricalQueryTest tests that relationships which were deleted are returned by queries of the
+// range of revisions over which they were living, along with their lifetimes.
+func HistoricalQueryTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+
+	setupRevision := setupDatastore(ds, require)
+
+	querier, ok := ds.(datastore.HistoricalQuerier)
+	if !ok {
+		t.Skip("datastore does not query deleted relationships")
+	}
+
+	deleted := makeTestTuple("deleted", "alice")
+	living := makeTestTuple("living", "alice")
+	later := makeTestTuple("later", "alice")
+
+	createdAt, err := datastore.WriteTuples(ctx, ds, []*core.RelationTupleUpdate{tuple.Touch(deleted), tuple.Touch(living)})
+	require.NoError(err)
+
+	deletedAt, err := datastore.WriteTuples(ctx, ds, []*core.RelationTupleUpdate{tuple.Delete(deleted)})
+	require.NoError(err)
+
+	laterAt, err := datastore.WriteTuples(ctx, ds, []*core.RelationTupleUpdate{tuple.Touch(later)})
+	require.NoError(err)
+
+	// The deleted relationship is no longer living at the head revision.
+	iter, err := ds.SnapshotReader(laterAt).QueryRelationships(ctx, &v1.RelationshipFilter{
+		ResourceType:       testResourceNamespace,
+		OptionalResourceId: "deleted",
+	})
+	require.NoError(err)
+	require.Nil(iter.Next())
+	iter.Close()
+
+	lifetimes := func(fromRevision, toRevision datastore.Revision) map[string]datastore.RevisionMetadata {
+		iter, err := querier.QueryTuplesIncludingDeleted(ctx, &v1.RelationshipFilter{ResourceType: testResourceNamespace}, fromRevision, toRevision)
+		require.NoError(err)
+		defer iter.Close()
+
+		reporter, ok := iter.(datastore.RevisionMetadataReporter)
+		require.True(ok, "iterator does not report the lifetimes of relationships")
+
+		found := make(map[string]datastore.RevisionMetadata)
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			lifetime, ok := reporter.RevisionMetadata()
+			require.True(ok)
+			found[tuple.String(tpl)] = lifetime
+		}
+		require.NoError(iter.Err())
+		return found
+	}
+
+	requireLifetime := func(found map[string]datastore.RevisionMetadata, tpl *core.RelationTuple, created, deleted datastore.Revision) {
+		lifetime, ok := found[tuple.String(tpl)]
+		require.True(ok, "missing relationship %s", tuple.String(tpl))
+		require.True(created.Equal(lifetime.CreatedRevision), "created at %s, expected %s", lifetime.CreatedRevision, created)
+		require.True(deleted.Equal(lifetime.DeletedRevision), "deleted at %s, expected %s", lifetime.DeletedRevision, deleted)
+	}
+
+	// Over every revision, each relationship is returned along with its lifetime.
+	found := lifetimes(setupRevision, laterAt)
+	require.Len(found, 3)
+	requireLifetime(found, deleted, createdAt, deletedAt)
+	requireLifetime(found, living, createdAt, datastore.NoRevision)
+	requireLifetime(found, later, laterAt, datastore.NoRevision)
+
+	// Deletions after the end of the range are still reported.
+	found = lifetimes(createdAt, createdAt)
+	require.Len(found, 2)
+	requireLifetime(found, deleted, createdAt, deletedAt)
+	requireLifetime(found, living, createdAt, datastore.NoRevision)
+
+	// A relationship is not living at the revision at which it was deleted.
+	found = lifetimes(deletedAt, laterAt)
+	require.Len(found, 2)
+	requireLifetime(found, living, createdAt, datastore.NoRevision)
+	requireLifetime(found, later, laterAt, datastore.NoRevision)
+
+	require.Empty(lifetimes(setupRevision, setupRevision))
+
+	_, err = querier.QueryTuplesIncludingDeleted(ctx, &v1.RelationshipFilter{ResourceType: testResourceNamespace}, laterAt, setupRevision)
+	require.Error(err)
+}
@@ -4,12 +4,14 @@ import (
 	"context"
 	"errors"
 	"testing"
+	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
 	"github.com/google/go-cmp/cmp"
 	"github.com/stretchr/testify/require"
 	"google.golang.org/protobuf/testing/protocmp"
 
+	"github.com/authzed/spicedb/internal/namespace"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/pkg/datastore"
 	ns "github.com/authzed/spicedb/pkg/namespace"
@@ -105,6 +107,40 @@ func NamespaceWriteTest(t *testing.T, tester DatastoreTester) {
 	require.Empty(cmp.Diff(testUserNS, checkOldList[0], protocmp.Transform()))
 }
 
+// NamespaceModifiedRevisionTest tests that the revision returned when reading a namespace is the
+// revision at which it was last written, and is unaffected by writes of other namespaces.
+func NamespaceModifiedRevisionTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	ctx := context.Background()
+
+	writtenRev, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(testUserNS, testNamespace)
+	})
+	require.NoError(err)
+
+	updatedRev, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(updatedNamespace)
+	})
+	require.NoError(err)
+	require.True(updatedRev.GreaterThan(writtenRev))
+
+	reader := ds.SnapshotReader(updatedRev)
+
+	_, updatedModified, err := reader.ReadNamespace(ctx, testNamespace.Name)
+	require.NoError(err)
+	require.True(updatedModified.GreaterThan(writtenRev))
+	require.True(updatedModified.LessThanOrEqual(updatedRev))
+
+	_, untouchedModified, err := reader.ReadNamespace(ctx, testUserNS.Name)
+	require.NoError(err)
+	require.True(untouchedModified.LessThanOrEqual(writtenRev))
+}
+
 // NamespaceDeleteTest tests whether or not the requirements for deleting
 // namespaces hold for a particular datastore.
 func NamespaceDeleteTest(t *testing.T, tester DatastoreTester) {
@@ -176,3 +212,195 @@ func EmptyNamespaceDeleteTest(t *testing.T, tester DatastoreTester) {
 	_, _, err = ds.SnapshotReader(deletedRev).ReadNamespace(ctx, testfixtures.UserNS.Name)
 	require.True(errors.As(err, &datastore.ErrNamespaceNotFound{}))
 }
+
+// NamespaceRenameTest tests renaming a namespace, along with all of the tuples which
+// reference it, in the datastore.
+func NamespaceRenameTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	rawDS, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+
+	ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, revision)
+	require.Zero(len(errchan))
+
+	const renamedNamespace = "directory"
+
+	renamedRev, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.RenameNamespace(testfixtures.FolderNS.Name, renamedNamespace)
+	})
+	require.NoError(err)
+	require.True(renamedRev.GreaterThan(revision))
+
+	_, _, err = ds.SnapshotReader(renamedRev).ReadNamespace(ctx, testfixtures.FolderNS.Name)
+	require.True(errors.As(err, &datastore.ErrNamespaceNotFound{}))
+
+	found, _, err := ds.SnapshotReader(renamedRev).ReadNamespace(ctx, renamedNamespace)
+	require.NoError(err)
+	require.Equal(renamedNamespace, found.Name)
+	require.Equal(len(testfixtures.FolderNS.Relation), len(found.Relation))
+
+	// Documents reference folders as their parents, and folders reference themselves, so the
+	// schema only remains valid if those references are renamed too.
+	renamedDefs, err := ds.SnapshotReader(renamedRev).ListNamespaces(ctx)
+	require.NoError(err)
+	for _, nsDef := range renamedDefs {
+		ts, err := namespace.BuildNamespaceTypeSystemForDatastore(nsDef, ds.SnapshotReader(renamedRev))
+		require.NoError(err)
+
+		_, err = ts.Validate(ctx)
+		require.NoError(err, "definition %s is invalid after the rename", nsDef.Name)
+
+		for _, relation := range nsDef.Relation {
+			for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+				require.NotEqual(testfixtures.FolderNS.Name, allowed.Namespace)
+			}
+		}
+	}
+
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+
+	expectedChanges := 0
+	for _, tplString := range testfixtures.StandardTuples {
+		tpl := tuple.Parse(tplString)
+		require.NotNil(tpl)
+
+		if tpl.ResourceAndRelation.Namespace != testfixtures.FolderNS.Name &&
+			tpl.Subject.Namespace != testfixtures.FolderNS.Name {
+			tRequire.TupleExists(ctx, tpl, renamedRev)
+			continue
+		}
+
+		tRequire.TupleExists(ctx, tpl, revision)
+		tRequire.NoTupleExists(ctx, tpl, renamedRev)
+
+		renamed := tuple.Parse(tplString)
+		if renamed.ResourceAndRelation.Namespace == testfixtures.FolderNS.Name {
+			renamed.ResourceAndRelation.Namespace = renamedNamespace
+		}
+		if renamed.Subject.Namespace == testfixtures.FolderNS.Name {
+			renamed.Subject.Namespace = renamedNamespace
+		}
+		tRequire.TupleExists(ctx, renamed, renamedRev)
+		tRequire.NoTupleExists(ctx, renamed, revision)
+
+		// One delete for the original tuple and one touch for the renamed tuple.
+		expectedChanges += 2
+	}
+
+	// The old name is gone from both sides of the relationships, and the new name is found.
+	reader := ds.SnapshotReader(renamedRev)
+	for _, nsName := range []string{testfixtures.FolderNS.Name, renamedNamespace} {
+		count, err := reader.CountRelationships(ctx, &v1.RelationshipFilter{ResourceType: nsName})
+		require.NoError(err)
+
+		iter, err := reader.ReverseQueryRelationships(ctx, &v1.SubjectFilter{SubjectType: nsName})
+		require.NoError(err)
+		first := iter.Next()
+		require.NoError(iter.Err())
+		iter.Close()
+
+		if nsName == renamedNamespace {
+			require.NotZero(count)
+			require.NotNil(first)
+		} else {
+			require.Zero(count)
+			require.Nil(first)
+		}
+	}
+
+	select {
+	case change, ok := <-changes:
+		require.True(ok)
+		require.True(change.Revision.Equal(renamedRev))
+		require.Equal(expectedChanges, len(change.Changes))
+	case err := <-errchan:
+		require.Failf("watch failed", "unexpected error: %s", err)
+	case <-time.NewTimer(5 * time.Second).C:
+		require.Fail("timed out waiting for rename changes")
+	}
+
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.RenameNamespace(testfixtures.DocumentNS.Name, renamedNamespace)
+	})
+	require.Error(err)
+}
+
+// NamespaceCopyTest tests copying a namespace, optionally along with its tuples, in the
+// datastore.
+func NamespaceCopyTest(t *testing.T, tester DatastoreTester) {
+	testCases := []struct {
+		name       string
+		copyTuples bool
+	}{
+		{"definition only", false},
+		{"with tuples", true},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			rawDS, err := tester.New(0, veryLargeGCWindow, 1)
+			require.NoError(err)
+
+			ds, revision := testfixtures.StandardDatastoreWithData(rawDS, require)
+			ctx := context.Background()
+
+			const copiedNamespace = "document_staging"
+
+			var numCopied uint64
+			copiedRev, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				var err error
+				numCopied, err = rwt.CopyNamespace(testfixtures.DocumentNS.Name, copiedNamespace, tc.copyTuples)
+				return err
+			})
+			require.NoError(err)
+			require.True(copiedRev.GreaterThan(revision))
+
+			original, _, err := ds.SnapshotReader(copiedRev).ReadNamespace(ctx, testfixtures.DocumentNS.Name)
+			require.NoError(err)
+			require.Empty(cmp.Diff(testfixtures.DocumentNS, original, protocmp.Transform()))
+
+			copied, _, err := ds.SnapshotReader(copiedRev).ReadNamespace(ctx, copiedNamespace)
+			require.NoError(err)
+			require.Equal(copiedNamespace, copied.Name)
+
+			copied.Name = testfixtures.DocumentNS.Name
+			require.Empty(cmp.Diff(testfixtures.DocumentNS, copied, protocmp.Transform()))
+
+			tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+
+			expectedCopies := uint64(0)
+			for _, tplString := range testfixtures.StandardTuples {
+				tpl := tuple.Parse(tplString)
+				require.NotNil(tpl)
+				tRequire.TupleExists(ctx, tpl, copiedRev)
+
+				if tpl.ResourceAndRelation.Namespace != testfixtures.DocumentNS.Name {
+					continue
+				}
+
+				tpl.ResourceAndRelation.Namespace = copiedNamespace
+				if tc.copyTuples {
+					tRequire.TupleExists(ctx, tpl, copiedRev)
+					expectedCopies++
+				} else {
+					tRequire.NoTupleExists(ctx, tpl, copiedRev)
+				}
+			}
+
+			require.Equal(expectedCopies, numCopied)
+
+			_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+				_, err := rwt.CopyNamespace(testfixtures.FolderNS.Name, copiedNamespace, tc.copyTuples)
+				return err
+			})
+			require.Error(err)
+		})
+	}
+}
@@ -71,3 +71,96 @@ func RevisionQuantizationTest(t *testing.T, tester DatastoreTester) {
 		})
 	}
 }
+
+// RevisionValidityTest tests that revisions are only accepted by CheckRevision while they are
+// within the GC window, and rejected as stale afterwards or as in the future before they exist.
+func RevisionValidityTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	gcWindow := 1 * time.Second
+	ds, err := tester.New(0, gcWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	head, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	require.NoError(ds.CheckRevision(ctx, head))
+
+	future := head.Add(decimal.NewFromInt(1_000_000_000_000_000))
+	requireInvalidRevision(require, ds.CheckRevision(ctx, future), datastore.RevisionInFuture)
+
+	// Let the revision leave the GC window, and write a newer revision within it.
+	time.Sleep(gcWindow + 100*time.Millisecond)
+
+	writtenAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(makeTestTuple("first", "owner"))),
+		})
+	})
+	require.NoError(err)
+
+	requireInvalidRevision(require, ds.CheckRevision(ctx, head), datastore.RevisionStale)
+	require.NoError(ds.CheckRevision(ctx, writtenAt))
+}
+
+// RevisionOrderingTest tests that the revisions of a datastore are ordered by when they were
+// written, and round-trip through their string form.
+func RevisionOrderingTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	var revisions []datastore.Revision
+	for i := 0; i < 3; i++ {
+		writtenAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+				tuple.UpdateToRelationshipUpdate(tuple.Touch(makeTestTuple(fmt.Sprintf("resource%d", i), "owner"))),
+			})
+		})
+		require.NoError(err)
+		revisions = append(revisions, writtenAt)
+	}
+
+	for i, revision := range revisions {
+		parsed, err := datastore.RevisionFromString(revision.String())
+		require.NoError(err)
+		require.True(parsed.Equal(revision))
+		require.Equal(revision.String(), parsed.String())
+
+		for j, other := range revisions {
+			switch {
+			case i < j:
+				require.Equal(-1, parsed.Cmp(other))
+				require.False(parsed.GreaterThan(other))
+			case i == j:
+				require.Equal(0, parsed.Cmp(other))
+				require.True(parsed.Equal(other))
+			default:
+				require.Equal(1, parsed.Cmp(other))
+				require.True(parsed.GreaterThan(other))
+			}
+		}
+	}
+
+	for _, malformed := range []string{"", "abc", "1.2.3", "-1", revisions[0].String() + "x"} {
+		_, err := datastore.RevisionFromString(malformed)
+
+		var malformedRevision datastore.ErrMalformedRevision
+		require.ErrorAs(err, &malformedRevision)
+		require.Equal(malformed, malformedRevision.RevisionString())
+	}
+}
+
+func requireInvalidRevision(require *require.Assertions, err error, reason datastore.InvalidRevisionReason) {
+	var invalidRevision datastore.ErrInvalidRevision
+	require.ErrorAs(err, &invalidRevision)
+	require.Equal(reason, invalidRevision.Reason())
+}
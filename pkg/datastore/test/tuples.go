@@ -10,6 +10,7 @@ import (
 	"time"
 
 	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/scylladb/go-set/strset"
 	"github.com/shopspring/decimal"
 	"github.com/stretchr/testify/require"
 	"golang.org/x/sync/errgroup"
@@ -17,6 +18,7 @@ import (
 	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/internal/testfixtures"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/namespace"
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
@@ -223,8 +225,35 @@ func SimpleTest(t *testing.T, tester DatastoreTester) {
 				tRequire.VerifyIteratorResults(iter)
 			}
 
+			// Check that an empty resource relation filter does not filter the reverse query, and
+			// that each half of the filter can be used on its own.
+			for _, resRelation := range []struct {
+				namespace string
+				relation  string
+			}{
+				{"", ""},
+				{testResourceNamespace, ""},
+				{testResourceNamespace, testReaderRelation},
+			} {
+				iter, err := dsReader.ReverseQueryRelationships(
+					ctx,
+					&v1.SubjectFilter{SubjectType: testUserNamespace},
+					options.WithResourceRelation(resRelation.namespace, resRelation.relation),
+				)
+				require.NoError(err)
+				tRequire.VerifyIteratorResults(iter, testTuples...)
+			}
+
+			iter, err := dsReader.ReverseQueryRelationships(
+				ctx,
+				&v1.SubjectFilter{SubjectType: testUserNamespace},
+				options.WithResourceRelation(testResourceNamespace, "fake"),
+			)
+			require.NoError(err)
+			tRequire.VerifyIteratorResults(iter)
+
 			// Check a query that returns a number of tuples
-			iter, err := dsReader.QueryRelationships(ctx, &v1.RelationshipFilter{
+			iter, err = dsReader.QueryRelationships(ctx, &v1.RelationshipFilter{
 				ResourceType: testResourceNamespace,
 			})
 			require.NoError(err)
@@ -346,7 +375,7 @@ func SimpleTest(t *testing.T, tester DatastoreTester) {
 
 			// Delete with DeleteRelationship
 			deletedAt, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
-				err := rwt.DeleteRelationships(&v1.RelationshipFilter{
+				_, err := rwt.DeleteRelationships(&v1.RelationshipFilter{
 					ResourceType: testResourceNamespace,
 				})
 				require.NoError(err)
@@ -454,8 +483,9 @@ func DeleteRelationshipsTest(t *testing.T, tester DatastoreTester) {
 			require.NoError(err)
 
 			deletedAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
-				err := rwt.DeleteRelationships(tt.filter)
+				deleted, err := rwt.DeleteRelationships(tt.filter)
 				require.NoError(err)
+				require.Equal(uint64(len(tt.expectedNonExistingTuples)), deleted)
 				return err
 			})
 			require.NoError(err)
@@ -467,10 +497,720 @@ func DeleteRelationshipsTest(t *testing.T, tester DatastoreTester) {
 			for _, tpl := range tt.expectedNonExistingTuples {
 				tRequire.NoTupleExists(ctx, tpl, deletedAt)
 			}
+
+			iter, err := ds.SnapshotReader(deletedAt).QueryRelationships(ctx, tt.filter)
+			require.NoError(err)
+			tRequire.VerifyIteratorResults(iter)
 		})
 	}
 }
 
+// CountRelationshipsTest tests whether or not the requirements for counting relationships
+// at different revisions hold for a particular datastore.
+// HeadSnapshotReaderTest tests that a reader at the head revision does not observe changes
+// written after it was created.
+func HeadSnapshotReaderTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	existing := makeTestTuple("resource0", "user0")
+	written, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(existing)),
+		})
+	})
+	require.NoError(err)
+
+	reader, snapshotRevision, err := datastore.HeadSnapshotReader(ctx, ds)
+	require.NoError(err)
+	require.True(snapshotRevision.GreaterThanOrEqual(written))
+
+	added := makeTestTuple("resource1", "user1")
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(added)),
+			tuple.UpdateToRelationshipUpdate(tuple.Delete(existing)),
+		})
+	})
+	require.NoError(err)
+
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(namespace.Namespace("new_namespace"))
+	})
+	require.NoError(err)
+
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+
+	iter, err := reader.QueryRelationships(ctx, &v1.RelationshipFilter{ResourceType: testResourceNamespace})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, existing)
+
+	iter, err = reader.ReverseQueryRelationships(ctx, &v1.SubjectFilter{SubjectType: testUserNamespace})
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, existing)
+
+	_, _, err = reader.ReadNamespace(ctx, "new_namespace")
+	require.ErrorAs(err, &datastore.ErrNamespaceNotFound{})
+}
+
+// ReverseQueryExcludedSubjectsTest tests that reverse queries never return relationships with
+// excluded subjects.
+func ReverseQueryExcludedSubjectsTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	var testTuples []*core.RelationTuple
+	for i := 0; i < 6; i++ {
+		testTuples = append(testTuples, makeTestTuple(fmt.Sprintf("resource%d", i), fmt.Sprintf("user%d", i%3)))
+	}
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		updates := make([]*v1.RelationshipUpdate, 0, len(testTuples))
+		for _, tpl := range testTuples {
+			updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tpl)))
+		}
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+	dsReader := ds.SnapshotReader(revision)
+	subjectFilter := &v1.SubjectFilter{SubjectType: testUserNamespace}
+
+	iter, err := dsReader.ReverseQueryRelationships(ctx, subjectFilter, options.SetExcludedSubjects(nil))
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, testTuples...)
+
+	iter, err = dsReader.ReverseQueryRelationships(
+		ctx,
+		subjectFilter,
+		options.WithExcludedSubjects(testTuples[0].Subject),
+		options.WithExcludedSubjects(testTuples[1].Subject),
+	)
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, testTuples[2], testTuples[5])
+
+	iter, err = dsReader.ReverseQueryRelationships(
+		ctx,
+		subjectFilter,
+		options.WithResourceRelation(testResourceNamespace, testReaderRelation),
+		options.SetExcludedSubjects([]*core.ObjectAndRelation{testTuples[0].Subject}),
+		options.WithReverseLimit(options.LimitOne),
+	)
+	require.NoError(err)
+	tRequire.VerifyIteratorCount(iter, 1)
+
+	// A subject is only excluded if its relation matches too.
+	iter, err = dsReader.ReverseQueryRelationships(
+		ctx,
+		subjectFilter,
+		options.WithExcludedSubjects(&core.ObjectAndRelation{
+			Namespace: testUserNamespace,
+			ObjectId:  "user0",
+			Relation:  "fake",
+		}),
+	)
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, testTuples...)
+}
+
+// ReverseQueryEllipsisRelationTest tests that reverse queries which include the ellipsis relation
+// also return relationships whose subjects have the ellipsis relation.
+func ReverseQueryEllipsisRelationTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	direct := makeTestTuple("resource0", "tom")
+	member := makeTestTuple("resource1", "tom")
+	member.Subject.Relation = "member"
+	otherMember := makeTestTuple("resource2", "fred")
+	otherMember.Subject.Relation = "member"
+	otherRelation := makeTestTuple("resource3", "tom")
+	otherRelation.Subject.Relation = "admin"
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		var updates []*v1.RelationshipUpdate
+		for _, tpl := range []*core.RelationTuple{direct, member, otherMember, otherRelation} {
+			updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tpl)))
+		}
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+	dsReader := ds.SnapshotReader(revision)
+
+	tomMember := &v1.SubjectFilter{
+		SubjectType:       testUserNamespace,
+		OptionalSubjectId: "tom",
+		OptionalRelation:  &v1.SubjectFilter_RelationFilter{Relation: "member"},
+	}
+
+	iter, err := dsReader.ReverseQueryRelationships(ctx, tomMember)
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, member)
+
+	iter, err = dsReader.ReverseQueryRelationships(ctx, tomMember, options.WithIncludeEllipsisRelation(true))
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, direct, member)
+
+	anyMember := &v1.SubjectFilter{
+		SubjectType:      testUserNamespace,
+		OptionalRelation: &v1.SubjectFilter_RelationFilter{Relation: "member"},
+	}
+	iter, err = dsReader.ReverseQueryRelationships(
+		ctx,
+		anyMember,
+		options.WithIncludeEllipsisRelation(true),
+		options.WithResourceRelation(testResourceNamespace, testReaderRelation),
+	)
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, direct, member, otherMember)
+
+	// Without a subject relation, the option has no effect.
+	iter, err = dsReader.ReverseQueryRelationships(
+		ctx,
+		&v1.SubjectFilter{SubjectType: testUserNamespace, OptionalSubjectId: "tom"},
+		options.WithIncludeEllipsisRelation(true),
+	)
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, direct, member, otherRelation)
+}
+
+// DeleteRelationshipsPreviewTest tests that previewing a deletion reports the relationships that
+// would be deleted, without deleting them.
+func DeleteRelationshipsPreviewTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	var testTuples []*core.RelationTuple
+	for i := 0; i < 10; i++ {
+		testTuples = append(testTuples, makeTestTuple(fmt.Sprintf("resource%d", i), fmt.Sprintf("user%d", i%2)))
+	}
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		var updates []*v1.RelationshipUpdate
+		for _, tpl := range testTuples {
+			updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tpl)))
+		}
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	filter := &v1.RelationshipFilter{
+		ResourceType:          testResourceNamespace,
+		OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: testUserNamespace, OptionalSubjectId: "user0"},
+	}
+
+	preview, err := datastore.PreviewDeleteRelationships(ctx, ds.SnapshotReader(revision), filter, 3)
+	require.NoError(err)
+	require.Equal(uint64(5), preview.Count)
+	require.Len(preview.Sample, 3)
+	for _, tpl := range preview.Sample {
+		require.Equal("user0", tpl.Subject.ObjectId)
+	}
+
+	// Nothing was deleted.
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+	for _, tpl := range testTuples {
+		tRequire.TupleExists(ctx, tpl, headRevision)
+	}
+
+	// The preview matches the deletion.
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		deleted, err := rwt.DeleteRelationships(filter)
+		require.Equal(preview.Count, deleted)
+		return err
+	})
+	require.NoError(err)
+}
+
+// ReverseQueryWildcardSubjectTest tests that reverse queries for a specific subject return
+// relationships with the wildcard subject of its type only when requested.
+func ReverseQueryWildcardSubjectTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	direct := makeTestTuple("resource0", "tom")
+	public := makeTestTuple("resource1", tuple.PublicWildcard)
+	other := makeTestTuple("resource2", "fred")
+	member := makeTestTuple("resource3", "tom")
+	member.Subject.Relation = "member"
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		var updates []*v1.RelationshipUpdate
+		for _, tpl := range []*core.RelationTuple{direct, public, other, member} {
+			updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tpl)))
+		}
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+	dsReader := ds.SnapshotReader(revision)
+
+	tom := &v1.SubjectFilter{
+		SubjectType:       testUserNamespace,
+		OptionalSubjectId: "tom",
+		OptionalRelation:  &v1.SubjectFilter_RelationFilter{Relation: ""},
+	}
+
+	iter, err := dsReader.ReverseQueryRelationships(ctx, tom)
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, direct)
+
+	iter, err = dsReader.ReverseQueryRelationships(ctx, tom, options.WithIncludeWildcardSubject(true))
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, direct, public)
+
+	// The wildcard subject has the ellipsis relation, but is returned for any subject relation.
+	iter, err = dsReader.ReverseQueryRelationships(
+		ctx,
+		&v1.SubjectFilter{
+			SubjectType:       testUserNamespace,
+			OptionalSubjectId: "tom",
+			OptionalRelation:  &v1.SubjectFilter_RelationFilter{Relation: "member"},
+		},
+		options.WithIncludeWildcardSubject(true),
+		options.WithResourceRelation(testResourceNamespace, testReaderRelation),
+	)
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, public, member)
+
+	iter, err = dsReader.ReverseQueryRelationships(
+		ctx,
+		&v1.SubjectFilter{SubjectType: testUserNamespace, OptionalSubjectId: "tom"},
+		options.WithIncludeWildcardSubject(true),
+	)
+	require.NoError(err)
+	tRequire.VerifyIteratorResults(iter, direct, public, member)
+}
+
+func CountRelationshipsTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	var testTuples []*core.RelationTuple
+	for i := 0; i < 10; i++ {
+		newTuple := makeTestTuple(fmt.Sprintf("resource%d", i), fmt.Sprintf("user%d", i%2))
+		testTuples = append(testTuples, newTuple)
+	}
+	testTuples[len(testTuples)-1].ResourceAndRelation.Relation = "writer"
+
+	touch := func(tuples []*core.RelationTuple) datastore.Revision {
+		revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			updates := make([]*v1.RelationshipUpdate, 0, len(tuples))
+			for _, tpl := range tuples {
+				updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Touch(tpl)))
+			}
+			return rwt.WriteRelationships(updates)
+		})
+		require.NoError(err)
+		return revision
+	}
+
+	allFilter := &v1.RelationshipFilter{ResourceType: testResourceNamespace}
+	user0Filter := &v1.RelationshipFilter{
+		ResourceType:          testResourceNamespace,
+		OptionalSubjectFilter: &v1.SubjectFilter{SubjectType: testUserNamespace, OptionalSubjectId: "user0"},
+	}
+	writerFilter := &v1.RelationshipFilter{
+		ResourceType:     testResourceNamespace,
+		OptionalRelation: "writer",
+	}
+	resourceFilter := &v1.RelationshipFilter{
+		ResourceType:       testResourceNamespace,
+		OptionalResourceId: "resource0",
+	}
+
+	requireCount := func(revision datastore.Revision, filter *v1.RelationshipFilter, expected uint64) {
+		count, err := ds.SnapshotReader(revision).CountRelationships(ctx, filter)
+		require.NoError(err)
+		require.Equal(expected, count, "unexpected count for filter %v at revision %s", filter, revision)
+	}
+
+	writtenAt := touch(testTuples)
+	requireCount(writtenAt, allFilter, 10)
+	requireCount(writtenAt, user0Filter, 5)
+	requireCount(writtenAt, writerFilter, 1)
+	requireCount(writtenAt, resourceFilter, 1)
+
+	deletedAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		deleted, err := rwt.DeleteRelationships(user0Filter)
+		require.Equal(uint64(5), deleted)
+		return err
+	})
+	require.NoError(err)
+	requireCount(deletedAt, allFilter, 5)
+	requireCount(deletedAt, user0Filter, 0)
+	requireCount(deletedAt, resourceFilter, 0)
+
+	rewrittenAt := touch([]*core.RelationTuple{testTuples[0], testTuples[2]})
+	requireCount(rewrittenAt, allFilter, 7)
+	requireCount(rewrittenAt, user0Filter, 2)
+	requireCount(rewrittenAt, resourceFilter, 1)
+
+	// Counts at earlier revisions must not be affected by later writes
+	requireCount(writtenAt, allFilter, 10)
+	requireCount(writtenAt, user0Filter, 5)
+	requireCount(deletedAt, allFilter, 5)
+	requireCount(deletedAt, user0Filter, 0)
+}
+
+// CheckRelationshipsExistTest tests whether or not the requirements for checking the existence
+// of many relationships at once hold for a particular datastore.
+func CheckRelationshipsExistTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	existing := makeTestTuple("existing", "user1")
+	deleted := makeTestTuple("deleted", "user1")
+	neverWritten := makeTestTuple("neverwritten", "user1")
+	otherSubject := makeTestTuple("existing", "user2")
+
+	writtenAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Touch(existing)),
+			tuple.UpdateToRelationshipUpdate(tuple.Touch(deleted)),
+		})
+	})
+	require.NoError(err)
+
+	deletedAt, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Delete(deleted)),
+		})
+	})
+	require.NoError(err)
+
+	candidates := []*core.RelationTuple{existing, deleted, neverWritten, otherSubject}
+
+	exists, err := ds.SnapshotReader(writtenAt).CheckRelationshipsExist(ctx, candidates)
+	require.NoError(err)
+	require.Equal(map[string]bool{
+		tuple.String(existing):     true,
+		tuple.String(deleted):      true,
+		tuple.String(neverWritten): false,
+		tuple.String(otherSubject): false,
+	}, exists)
+
+	exists, err = ds.SnapshotReader(deletedAt).CheckRelationshipsExist(ctx, candidates)
+	require.NoError(err)
+	require.Equal(map[string]bool{
+		tuple.String(existing):     true,
+		tuple.String(deleted):      false,
+		tuple.String(neverWritten): false,
+		tuple.String(otherSubject): false,
+	}, exists)
+
+	exists, err = ds.SnapshotReader(deletedAt).CheckRelationshipsExist(ctx, nil)
+	require.NoError(err)
+	require.Empty(exists)
+}
+
+// QueryCursorTest tests whether or not paging through relationships with a cursor returns
+// every relationship exactly once, in a stable order, for a particular datastore.
+func QueryCursorTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	expected := strset.New()
+	var updates []*v1.RelationshipUpdate
+	for i := 0; i < 7; i++ {
+		for _, userID := range []string{"user0", "user1"} {
+			tpl := makeTestTuple(fmt.Sprintf("resource%d", i%4), fmt.Sprintf("%s%d", userID, i))
+			expected.Add(tuple.String(tpl))
+			updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Touch(tpl)))
+		}
+	}
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	readPages := func() []string {
+		var found []string
+		var cursor *core.RelationTuple
+		for {
+			iter, err := ds.SnapshotReader(revision).QueryRelationships(
+				ctx,
+				&v1.RelationshipFilter{ResourceType: testResourceNamespace},
+				options.WithCursor(cursor, 2),
+			)
+			require.NoError(err)
+
+			pageSize := 0
+			for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+				found = append(found, tuple.String(tpl))
+				pageSize++
+			}
+			require.NoError(iter.Err())
+			require.LessOrEqual(pageSize, 2)
+
+			cursor = iter.Cursor()
+			iter.Close()
+
+			if pageSize < 2 {
+				return found
+			}
+		}
+	}
+
+	found := readPages()
+	require.Len(found, expected.Size(), "found overlapping or missing relationships")
+	require.True(strset.New(found...).IsEqual(expected))
+
+	// Paging again at the same revision must return the same order
+	require.Equal(found, readPages())
+}
+
+// QueryMaxResultsTest tests that a query fails with ErrResultSetTooLarge once it matches more
+// relationships than its maximum number of results, and that a maximum of zero is unbounded.
+func QueryMaxResultsTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	var updates []*v1.RelationshipUpdate
+	for i := 0; i < 5; i++ {
+		tpl := makeTestTuple(fmt.Sprintf("resource%d", i), "user0")
+		updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Touch(tpl)))
+	}
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	testCases := []struct {
+		name          string
+		opts          []options.QueryOptionsOption
+		expectedCount int
+		expectedErr   bool
+	}{
+		{"unlimited", []options.QueryOptionsOption{options.WithMaxResults(0)}, 5, false},
+		{"above results", []options.QueryOptionsOption{options.WithMaxResults(6)}, 5, false},
+		{"at results", []options.QueryOptionsOption{options.WithMaxResults(5)}, 5, false},
+		{"below results", []options.QueryOptionsOption{options.WithMaxResults(4)}, 4, true},
+		{"one", []options.QueryOptionsOption{options.WithMaxResults(1)}, 1, true},
+		{"sorted below results", []options.QueryOptionsOption{options.WithMaxResults(4), options.WithSorted(true)}, 4, true},
+		{"limited below max", []options.QueryOptionsOption{options.WithMaxResults(4), options.WithLimit(options.LimitOne)}, 1, false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			iter, err := ds.SnapshotReader(revision).QueryRelationships(
+				ctx,
+				&v1.RelationshipFilter{ResourceType: testResourceNamespace},
+				tc.opts...,
+			)
+			require.NoError(err)
+			defer iter.Close()
+
+			count := 0
+			for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+				count++
+			}
+			require.Equal(tc.expectedCount, count)
+
+			if tc.expectedErr {
+				var tooLargeErr datastore.ErrResultSetTooLarge
+				require.ErrorAs(iter.Err(), &tooLargeErr)
+				require.Less(tooLargeErr.MaxResults(), uint64(5))
+			} else {
+				require.NoError(iter.Err())
+			}
+		})
+	}
+}
+
+// CaseInsensitiveObjectIDTest tests that a query made with WithCaseInsensitiveObjectID matches
+// resource and subject IDs regardless of case. Whether other queries match them exactly depends
+// on the collation of the datastore, so it is not tested here.
+func CaseInsensitiveObjectIDTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	stored := makeTestTuple("user123", "user123")
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{
+			tuple.UpdateToRelationshipUpdate(tuple.Create(stored)),
+			tuple.UpdateToRelationshipUpdate(tuple.Create(makeTestTuple("user1234", "user1234"))),
+		})
+	})
+	require.NoError(err)
+
+	testCases := []struct {
+		name   string
+		filter *v1.RelationshipFilter
+	}{
+		{"resource ID", &v1.RelationshipFilter{
+			ResourceType:       testResourceNamespace,
+			OptionalResourceId: "User123",
+		}},
+		{"subject ID", &v1.RelationshipFilter{
+			ResourceType: testResourceNamespace,
+			OptionalSubjectFilter: &v1.SubjectFilter{
+				SubjectType:       testUserNamespace,
+				OptionalSubjectId: "USER123",
+			},
+		}},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+			tRequire := testfixtures.TupleChecker{Require: require, DS: ds}
+
+			iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, tc.filter, options.WithCaseInsensitiveObjectID())
+			require.NoError(err)
+			tRequire.VerifyIteratorResults(iter, stored)
+		})
+	}
+}
+
+// MultipleResourceTypesQueryTest tests that a query for several resource types at once returns
+// the union of the results of querying each type.
+func MultipleResourceTypesQueryTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	const testFolderNamespace = "test/folder"
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(
+			testResourceNS,
+			testUserNS,
+			namespace.Namespace(testFolderNamespace, namespace.Relation(testReaderRelation, nil)),
+			namespace.Namespace("test/other", namespace.Relation(testReaderRelation, nil)),
+		)
+	})
+	require.NoError(err)
+
+	var updates []*v1.RelationshipUpdate
+	for i := 0; i < 5; i++ {
+		resourceTpl := makeTestTuple(fmt.Sprintf("resource%d", i), fmt.Sprintf("user%d", i%2))
+		folderTpl := makeTestTuple(fmt.Sprintf("folder%d", i), fmt.Sprintf("user%d", i%3))
+		folderTpl.ResourceAndRelation.Namespace = testFolderNamespace
+		updates = append(updates,
+			tuple.UpdateToRelationshipUpdate(tuple.Create(resourceTpl)),
+			tuple.UpdateToRelationshipUpdate(tuple.Create(folderTpl)),
+		)
+	}
+	updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tuple.Parse("test/other:other#reader@test/user:user0"))))
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	query := func(filter *v1.RelationshipFilter, opts ...options.QueryOptionsOption) []string {
+		iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, filter, opts...)
+		require.NoError(err)
+		defer iter.Close()
+
+		var found []string
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			found = append(found, tuple.String(tpl))
+		}
+		require.NoError(iter.Err())
+		return found
+	}
+
+	for _, subjectFilter := range []*v1.SubjectFilter{
+		nil,
+		{SubjectType: testUserNamespace, OptionalSubjectId: "user0"},
+	} {
+		resourceFilter := &v1.RelationshipFilter{ResourceType: testResourceNamespace, OptionalSubjectFilter: subjectFilter}
+		folderFilter := &v1.RelationshipFilter{ResourceType: testFolderNamespace, OptionalSubjectFilter: subjectFilter}
+
+		expected := append(query(resourceFilter), query(folderFilter)...)
+		require.NotEmpty(expected)
+
+		found := query(resourceFilter, options.WithAdditionalResourceTypes(testFolderNamespace))
+		require.ElementsMatch(expected, found)
+
+		// Every found tuple carries the namespace of its resource.
+		for _, tplString := range found {
+			resourceType := tuple.Parse(tplString).ResourceAndRelation.Namespace
+			require.Contains([]string{testResourceNamespace, testFolderNamespace}, resourceType)
+		}
+
+		// Sorted queries return the union in a stable order.
+		sorted := query(resourceFilter, options.WithAdditionalResourceTypes(testFolderNamespace), options.WithSorted(true))
+		require.ElementsMatch(expected, sorted)
+		require.Equal(sorted, query(folderFilter, options.WithAdditionalResourceTypes(testResourceNamespace), options.WithSorted(true)))
+	}
+}
+
 // InvalidReadsTest tests whether or not the requirements for reading via
 // invalid revisions hold for a particular datastore.
 func InvalidReadsTest(t *testing.T, tester DatastoreTester) {
@@ -694,3 +1434,63 @@ func ConcurrentWriteSerializationTest(t *testing.T, tester DatastoreTester) {
 	require.NoError(g.Wait())
 	require.Less(time.Since(startTime), 10*time.Second)
 }
+
+// StreamRelationshipsCancelTest tests that canceling a stream of query results stops it promptly
+// and closes both of its channels.
+func StreamRelationshipsCancelTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+	ctx := context.Background()
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+	defer ds.Close()
+
+	setupDatastore(ds, require)
+
+	const numTuples = 1000
+	updates := make([]*v1.RelationshipUpdate, 0, numTuples)
+	for i := 0; i < numTuples; i++ {
+		tpl := makeTestTuple(fmt.Sprintf("resource%d", i), fmt.Sprintf("user%d", i))
+		updates = append(updates, tuple.UpdateToRelationshipUpdate(tuple.Create(tpl)))
+	}
+
+	revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships(updates)
+	})
+	require.NoError(err)
+
+	streamCtx, cancel := context.WithCancel(ctx)
+	iter, err := ds.SnapshotReader(revision).QueryRelationships(streamCtx, &v1.RelationshipFilter{
+		ResourceType: testResourceNamespace,
+	}, options.WithStreamResults(true))
+	require.NoError(err)
+
+	tuples, errs := datastore.StreamRelationships(streamCtx, iter)
+
+	const readBeforeCancel = 10
+	for i := 0; i < readBeforeCancel; i++ {
+		require.NotNil(<-tuples)
+	}
+	cancel()
+
+	received := readBeforeCancel
+	closed := time.After(1 * time.Second)
+	for tuples != nil {
+		select {
+		case tpl, ok := <-tuples:
+			if !ok {
+				tuples = nil
+				continue
+			}
+			require.NotNil(tpl)
+			received++
+		case <-closed:
+			require.Fail("timed out waiting for the stream to close")
+		}
+	}
+	require.Less(received, numTuples)
+
+	require.ErrorIs(<-errs, context.Canceled)
+	_, ok := <-errs
+	require.False(ok)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"errors"
 	"fmt"
+	"sort"
 	"strconv"
 	"testing"
 	"time"
@@ -16,7 +17,9 @@ import (
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
 
+	"github.com/authzed/spicedb/internal/datastore/options"
 	"github.com/authzed/spicedb/pkg/datastore"
+	"github.com/authzed/spicedb/pkg/namespace"
 	"github.com/authzed/spicedb/pkg/tuple"
 )
 
@@ -111,10 +114,11 @@ func WatchTest(t *testing.T, tester DatastoreTester) {
 			})
 			require.NoError(err)
 
-			testUpdates = append(testUpdates, batch, []*v1.RelationshipUpdate{deleteUpdate})
+			// relation0 is already living, so only the creation of another_relation is reported.
+			testUpdates = append(testUpdates, []*v1.RelationshipUpdate{createUpdate}, []*v1.RelationshipUpdate{deleteUpdate})
 
 			_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
-				err := rwt.DeleteRelationships(&v1.RelationshipFilter{
+				_, err := rwt.DeleteRelationships(&v1.RelationshipFilter{
 					ResourceType:     testResourceNamespace,
 					OptionalRelation: testReaderRelation,
 					OptionalSubjectFilter: &v1.SubjectFilter{
@@ -196,6 +200,569 @@ func setOfChanges(changes []*core.RelationTupleUpdate) *strset.Set {
 	return changeSet
 }
 
+// WatchNamespaceFilterTest tests that a watch filtered to a set of namespaces only returns changes
+// to tuples in those namespaces, and skips revisions without any such changes.
+func WatchNamespaceFilterTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	const otherNamespace = "test/other"
+	startWatchRevision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(namespace.Namespace(
+			otherNamespace,
+			namespace.Relation(testReaderRelation, nil),
+		))
+	})
+	require.NoError(err)
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, options.WithNamespaces(testResourceNamespace))
+	require.Zero(len(errchan))
+
+	otherUpdate := func(resourceID string) *v1.RelationshipUpdate {
+		return &v1.RelationshipUpdate{
+			Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+			Relationship: tuple.MustToRelationship(tuple.Parse(fmt.Sprintf("%s:%s#%s@%s:tom", otherNamespace, resourceID, testReaderRelation, testUserNamespace))),
+		}
+	}
+	resourceUpdate := &v1.RelationshipUpdate{
+		Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+		Relationship: makeTestRelationship("foo", "tom"),
+	}
+
+	for _, batch := range [][]*v1.RelationshipUpdate{
+		{otherUpdate("first")},
+		{otherUpdate("second"), resourceUpdate},
+	} {
+		_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships(batch)
+		})
+		require.NoError(err)
+	}
+
+	verifyUpdates(require, [][]*v1.RelationshipUpdate{{resourceUpdate}}, changes, errchan, false)
+}
+
+// WatchCheckpointTest tests that a watch started with a checkpoint first emits the head
+// revision at the time it was started, followed by the changes after the start revision.
+func WatchCheckpointTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	historical := &v1.RelationshipUpdate{
+		Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+		Relationship: makeTestRelationship("historical", "test_user"),
+	}
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{historical})
+	})
+	require.NoError(err)
+
+	headBefore, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, options.WithEmitCheckpoint(true))
+	require.Zero(len(errchan))
+
+	select {
+	case checkpoint, ok := <-changes:
+		require.True(ok)
+		require.True(checkpoint.IsCheckpoint)
+		require.Empty(checkpoint.Changes)
+
+		headAfter, err := ds.HeadRevision(ctx)
+		require.NoError(err)
+		require.True(checkpoint.Revision.GreaterThanOrEqual(headBefore), "checkpoint %s is before head %s", checkpoint.Revision, headBefore)
+		require.True(checkpoint.Revision.LessThanOrEqual(headAfter), "checkpoint %s is after head %s", checkpoint.Revision, headAfter)
+	case <-time.After(5 * time.Second):
+		require.Fail("Timed out waiting for checkpoint")
+	}
+
+	verifyUpdates(require, [][]*v1.RelationshipUpdate{{historical}}, changes, errchan, false)
+}
+
+// WatchCheckpointAbandonedTest tests that a watch emitting a checkpoint stops once canceled, even
+// if its consumer never reads from it.
+func WatchCheckpointAbandonedTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 1)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{{
+			Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+			Relationship: makeTestRelationship("abandoned", "test_user"),
+		}})
+	})
+	require.NoError(err)
+
+	// The checkpoint fills the buffer, so the watch then waits to send the change.
+	_, errchan := ds.Watch(ctx, startWatchRevision, options.WithEmitCheckpoint(true), options.WithSendTimeout(time.Hour))
+	time.Sleep(100 * time.Millisecond)
+	cancel()
+
+	// Nothing is read from the changes, so the watch can only stop if the blocked send observes
+	// the cancellation.
+	select {
+	case err := <-errchan:
+		require.Error(err)
+	case <-time.After(5 * time.Second):
+		require.Fail("watch did not stop after being canceled")
+	}
+}
+
+// WatchSlowReaderTest tests that a watch with a send timeout does not disconnect a consumer
+// which falls behind by more than the watch buffer, as long as it keeps making progress.
+func WatchSlowReaderTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 2)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, options.WithSendTimeout(5*time.Second))
+	require.Zero(len(errchan))
+
+	var testUpdates [][]*v1.RelationshipUpdate
+	for i := 0; i < 8; i++ {
+		batch := []*v1.RelationshipUpdate{{
+			Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+			Relationship: makeTestRelationship(fmt.Sprintf("slow%d", i), "test_user"),
+		}}
+		testUpdates = append(testUpdates, batch)
+
+		_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships(batch)
+		})
+		require.NoError(err)
+	}
+
+	for _, expected := range testUpdates {
+		time.Sleep(10 * time.Millisecond)
+		verifyUpdates(require, [][]*v1.RelationshipUpdate{expected}, changes, errchan, false)
+	}
+}
+
+// WatchOverflowDropAndSignalTest tests that a watch with the drop and signal overflow policy
+// replaces the changes a consumer fell behind on with a gap marker, from which the consumer can
+// resync, instead of disconnecting.
+func WatchOverflowDropAndSignalTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 2)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	writeUpdate := func(op v1.RelationshipUpdate_Operation, resourceID string) {
+		_, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships([]*v1.RelationshipUpdate{{
+				Operation:    op,
+				Relationship: makeTestRelationship(resourceID, "test_user"),
+			}})
+		})
+		require.NoError(err)
+	}
+
+	// Write more transactions than fit in the buffer before watching from before them, so that
+	// the watch falls behind as it catches up
+	for i := 0; i < 10; i++ {
+		writeUpdate(v1.RelationshipUpdate_OPERATION_TOUCH, fmt.Sprintf("overflow%d", i))
+	}
+	writeUpdate(v1.RelationshipUpdate_OPERATION_DELETE, "overflow0")
+	writeUpdate(v1.RelationshipUpdate_OPERATION_TOUCH, "overflowlast")
+
+	readRelationships := func(revision datastore.Revision) *strset.Set {
+		iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, &v1.RelationshipFilter{
+			ResourceType: testResourceNamespace,
+		})
+		require.NoError(err)
+		defer iter.Close()
+
+		found := strset.New()
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			found.Add(tuple.String(tpl))
+		}
+		require.NoError(iter.Err())
+		return found
+	}
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision, options.WithOverflowPolicy(options.WatchOverflowDropAndSignal))
+	require.Zero(len(errchan))
+	require.Eventually(func() bool { return len(changes) == cap(changes) }, 5*time.Second, 10*time.Millisecond)
+
+	// Resync the relationships from the last gap, applying the changes received after it
+	relationships := readRelationships(startWatchRevision)
+	gaps := 0
+	for sawLast := false; !sawLast; {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				require.Fail("watch disconnected", "%s", <-errchan)
+			}
+
+			if change.IsGap {
+				require.Empty(change.Changes)
+				require.True(change.GapStartRevision.LessThanOrEqual(change.Revision))
+				relationships = readRelationships(change.Revision)
+				gaps++
+				continue
+			}
+
+			for _, update := range change.Changes {
+				if update.Operation == core.RelationTupleUpdate_DELETE {
+					relationships.Remove(tuple.String(update.Tuple))
+				} else {
+					relationships.Add(tuple.String(update.Tuple))
+				}
+				sawLast = sawLast || update.Tuple.ResourceAndRelation.ObjectId == "overflowlast"
+			}
+		case <-time.After(5 * time.Second):
+			require.Fail("Timed out waiting for changes")
+		}
+	}
+
+	require.Positive(gaps)
+
+	headRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+	require.ElementsMatch(readRelationships(headRevision).List(), relationships.List())
+}
+
+// WatchRevisionTooOldTest tests that a watch can be resumed after a revision within the garbage
+// collection window, and fails with ErrWatchRevisionTooOld after one which has left it.
+func WatchRevisionTooOldTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	gcWindow := 2 * time.Second
+	ds, err := tester.New(0, gcWindow, 16)
+	require.NoError(err)
+
+	reporter, ok := ds.(datastore.WatchRevisionReporter)
+	if !ok {
+		t.Skip("datastore does not report the minimum watch revision")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	oldRevision := setupDatastore(ds, require)
+
+	writeUpdate := func(resourceID string) datastore.Revision {
+		revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			return rwt.WriteRelationships([]*v1.RelationshipUpdate{{
+				Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+				Relationship: makeTestRelationship(resourceID, "test_user"),
+			}})
+		})
+		require.NoError(err)
+		return revision
+	}
+
+	// A watch resumed from a recent checkpoint receives the changes after it
+	writeUpdate("before")
+	minRevision, err := reporter.MinWatchRevision(ctx)
+	require.NoError(err)
+	require.True(oldRevision.GreaterThanOrEqual(minRevision))
+
+	changes, errchan := ds.Watch(ctx, oldRevision)
+	select {
+	case change, ok := <-changes:
+		if !ok {
+			require.Fail("watch failed", "%s", <-errchan)
+		}
+		require.Len(change.Changes, 1)
+		require.Equal("before", change.Changes[0].Tuple.ResourceAndRelation.ObjectId)
+	case <-time.After(5 * time.Second):
+		require.Fail("Timed out waiting for changes")
+	}
+
+	// Once the checkpoint has left the garbage collection window, resuming from it fails
+	time.Sleep(gcWindow + 500*time.Millisecond)
+	recentRevision := writeUpdate("after")
+
+	minRevision, err = reporter.MinWatchRevision(ctx)
+	require.NoError(err)
+	require.True(oldRevision.LessThan(minRevision))
+
+	changes, errchan = ds.Watch(ctx, oldRevision)
+	select {
+	case err := <-errchan:
+		var tooOld datastore.ErrWatchRevisionTooOld
+		require.ErrorAs(err, &tooOld)
+		require.True(tooOld.Revision().Equal(oldRevision))
+		require.True(tooOld.MinRevision().GreaterThanOrEqual(minRevision))
+	case <-time.After(5 * time.Second):
+		require.Fail("Timed out waiting for the watch to fail")
+	}
+	_, ok = <-changes
+	require.False(ok)
+
+	// A watch resumed from a revision within the window still succeeds
+	changes, errchan = ds.Watch(ctx, recentRevision)
+	writeUpdate("later")
+	select {
+	case change, ok := <-changes:
+		if !ok {
+			require.Fail("watch failed", "%s", <-errchan)
+		}
+		require.Len(change.Changes, 1)
+		require.Equal("later", change.Changes[0].Tuple.ResourceAndRelation.ObjectId)
+	case <-time.After(5 * time.Second):
+		require.Fail("Timed out waiting for changes")
+	}
+}
+
+// WatchCreateThenDeleteTest tests that a relationship which is created and then deleted
+// within a single transaction does not produce a change in the watch stream.
+func WatchCreateThenDeleteTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision)
+	require.Zero(len(errchan))
+
+	ephemeral := makeTestRelationship("ephemeral", "test_user")
+	kept := &v1.RelationshipUpdate{
+		Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+		Relationship: makeTestRelationship("kept", "test_user"),
+	}
+
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		err := rwt.WriteRelationships([]*v1.RelationshipUpdate{{
+			Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+			Relationship: ephemeral,
+		}, kept})
+		require.NoError(err)
+
+		err = rwt.WriteRelationships([]*v1.RelationshipUpdate{{
+			Operation:    v1.RelationshipUpdate_OPERATION_DELETE,
+			Relationship: ephemeral,
+		}})
+		require.NoError(err)
+		return err
+	})
+	require.NoError(err)
+
+	// Creates are reported as touches by the watch API.
+	expected := []*v1.RelationshipUpdate{{
+		Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+		Relationship: kept.Relationship,
+	}}
+	verifyUpdates(require, [][]*v1.RelationshipUpdate{expected}, changes, errchan, false)
+}
+
+// WatchSchemaOnlyWriteTest tests that a transaction which does not change any relationships does
+// not produce a change in the watch stream.
+func WatchSchemaOnlyWriteTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision)
+	require.Zero(len(errchan))
+
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(namespace.Namespace("test/unrelated"))
+	})
+	require.NoError(err)
+
+	created := &v1.RelationshipUpdate{
+		Operation:    v1.RelationshipUpdate_OPERATION_CREATE,
+		Relationship: makeTestRelationship("afterschema", "test_user"),
+	}
+	_, err = ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteRelationships([]*v1.RelationshipUpdate{created})
+	})
+	require.NoError(err)
+
+	// The first change received must be the relationship write.
+	expected := []*v1.RelationshipUpdate{{
+		Operation:    v1.RelationshipUpdate_OPERATION_TOUCH,
+		Relationship: created.Relationship,
+	}}
+	verifyUpdates(require, [][]*v1.RelationshipUpdate{expected}, changes, errchan, false)
+}
+
+// WriteTuplesBatchTest tests that tuples written together by datastore.WriteTuples share a
+// single revision, and are reported by watch as a single change.
+func WriteTuplesBatchTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision)
+	require.Zero(len(errchan))
+
+	const batchSize = 1000
+	updates := make([]*core.RelationTupleUpdate, 0, batchSize)
+	for i := 0; i < batchSize; i++ {
+		updates = append(updates, tuple.Touch(makeTestTuple(fmt.Sprintf("resource%d", i), "test_user")))
+	}
+
+	revision, err := datastore.WriteTuples(ctx, ds, updates)
+	require.NoError(err)
+
+	changeWait := time.NewTimer(5 * time.Second)
+	select {
+	case change, ok := <-changes:
+		require.True(ok, "watch closed before the batch was received")
+		require.True(revision.Equal(change.Revision), "batch received at revision %s, written at %s", change.Revision, revision)
+
+		missingExpected := strset.Difference(setOfChanges(updates), setOfChanges(change.Changes))
+		require.True(missingExpected.IsEmpty(), "expected changes missing: %s", missingExpected)
+		require.Len(change.Changes, batchSize)
+	case err := <-errchan:
+		require.Failf("watch failed", "%s", err)
+	case <-changeWait.C:
+		require.Fail("Timed out", "waiting for the batch")
+	}
+
+	// The batch is either entirely visible or not at all.
+	countAt := func(revision datastore.Revision) uint64 {
+		count, err := ds.SnapshotReader(revision).CountRelationships(ctx, &v1.RelationshipFilter{
+			ResourceType: testResourceNamespace,
+		})
+		require.NoError(err)
+		return count
+	}
+	require.Zero(countAt(startWatchRevision))
+	require.Equal(uint64(batchSize), countAt(revision))
+}
+
+// TouchLivingRelationshipTest tests that touches of relationships which are already living are
+// skipped: they are not counted as effective and are not reported by watch, while the transaction
+// still commits a new revision.
+func TouchLivingRelationshipTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	startWatchRevision := setupDatastore(ds, require)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	changes, errchan := ds.Watch(ctx, startWatchRevision)
+	require.Zero(len(errchan))
+
+	write := func(updates ...*v1.RelationshipUpdate) (datastore.Revision, uint64) {
+		var effective uint64
+		revision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+			if err := rwt.WriteRelationships(updates); err != nil {
+				return err
+			}
+			effective = rwt.EffectiveTouches()
+			return nil
+		})
+		require.NoError(err)
+		return revision, effective
+	}
+
+	living := func(revision datastore.Revision) []string {
+		iter, err := ds.SnapshotReader(revision).QueryRelationships(ctx, &v1.RelationshipFilter{
+			ResourceType: testResourceNamespace,
+		})
+		require.NoError(err)
+		defer iter.Close()
+
+		var found []string
+		for tpl := iter.Next(); tpl != nil; tpl = iter.Next() {
+			found = append(found, tuple.String(tpl))
+		}
+		require.NoError(iter.Err())
+		sort.Strings(found)
+		return found
+	}
+
+	touch := func(rel *v1.Relationship) *v1.RelationshipUpdate {
+		return &v1.RelationshipUpdate{Operation: v1.RelationshipUpdate_OPERATION_TOUCH, Relationship: rel}
+	}
+	del := func(rel *v1.Relationship) *v1.RelationshipUpdate {
+		return &v1.RelationshipUpdate{Operation: v1.RelationshipUpdate_OPERATION_DELETE, Relationship: rel}
+	}
+
+	tom := makeTestRelationship("foo", "tom")
+	fred := makeTestRelationship("foo", "fred")
+	sarah := makeTestRelationship("foo", "sarah")
+
+	// Touching relationships which do not exist creates all of them.
+	allNewRevision, effective := write(touch(tom), touch(fred))
+	require.Equal(uint64(2), effective)
+
+	// Touching relationships which are all living changes nothing, but still commits a revision.
+	allNoopRevision, effective := write(touch(tom), touch(fred))
+	require.Equal(uint64(0), effective)
+	require.True(allNoopRevision.GreaterThan(allNewRevision))
+	require.Equal(living(allNewRevision), living(allNoopRevision))
+
+	// Only the touches of relationships which do not exist are effective in a mixed batch, which
+	// is still applied atomically with the rest of its mutations.
+	mixedRevision, effective := write(touch(tom), touch(sarah), del(fred))
+	require.Equal(uint64(1), effective)
+	require.Equal([]string{
+		tuple.MustRelString(sarah),
+		tuple.MustRelString(tom),
+	}, living(mixedRevision))
+
+	// Watch reports the effective touches and the delete, and nothing at all for the no-op batch.
+	verifyUpdates(require, [][]*v1.RelationshipUpdate{
+		{touch(tom), touch(fred)},
+		{touch(sarah), del(fred)},
+	}, changes, errchan, false)
+
+	// A touch of a relationship deleted earlier in the same batch recreates it, and is effective.
+	recreatedRevision, effective := write(del(tom), touch(tom))
+	require.Equal(uint64(1), effective)
+	require.Equal(living(mixedRevision), living(recreatedRevision))
+}
+
 // WatchCancelTest tests whether or not the requirements for cancelling watches
 // hold for a particular datastore.
 func WatchCancelTest(t *testing.T, tester DatastoreTester) {
@@ -251,3 +818,69 @@ func WatchCancelTest(t *testing.T, tester DatastoreTester) {
 		}
 	}
 }
+
+// WatchNamespacesTest tests that namespace watchers receive the namespaces written and deleted
+// after the revision from which they are started, with the revisions at which they changed.
+func WatchNamespacesTest(t *testing.T, tester DatastoreTester) {
+	require := require.New(t)
+
+	ds, err := tester.New(0, veryLargeGCWindow, 16)
+	require.NoError(err)
+
+	watcher, ok := ds.(datastore.NamespaceWatcher)
+	if !ok {
+		t.Skip("datastore does not watch namespaces")
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	startRevision, err := ds.HeadRevision(ctx)
+	require.NoError(err)
+
+	changes, errchan := watcher.WatchNamespaces(ctx, startRevision)
+
+	userNS := namespace.Namespace(testUserNamespace)
+	resourceNS := namespace.Namespace(testResourceNamespace, namespace.Relation(testReaderRelation, nil))
+	writtenRevision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.WriteNamespaces(userNS, resourceNS)
+	})
+	require.NoError(err)
+
+	deletedRevision, err := ds.ReadWriteTx(ctx, func(ctx context.Context, rwt datastore.ReadWriteTransaction) error {
+		return rwt.DeleteNamespace(testResourceNamespace)
+	})
+	require.NoError(err)
+
+	expected := []*datastore.NamespaceChange{
+		{Revision: writtenRevision, Name: testResourceNamespace, Definition: resourceNS},
+		{Revision: writtenRevision, Name: testUserNamespace, Definition: userNS},
+		{Revision: deletedRevision, Name: testResourceNamespace},
+	}
+
+	var received []*datastore.NamespaceChange
+	for len(received) < len(expected) {
+		select {
+		case change, ok := <-changes:
+			if !ok {
+				require.Fail("watch failed", "%s", <-errchan)
+			}
+			received = append(received, change)
+		case <-time.After(5 * time.Second):
+			require.Fail("Timed out waiting for namespace changes")
+		}
+	}
+
+	// Changes within the same revision may be received in any order
+	require.True(received[0].Revision.Equal(writtenRevision))
+	require.True(received[1].Revision.Equal(writtenRevision))
+	if received[0].Name != testResourceNamespace {
+		received[0], received[1] = received[1], received[0]
+	}
+
+	for i, change := range received {
+		require.True(expected[i].Revision.Equal(change.Revision), "unexpected revision for %s", change.Name)
+		require.Equal(expected[i].Name, change.Name)
+		require.Empty(cmp.Diff(expected[i].Definition, change.Definition, protocmp.Transform()))
+	}
+}
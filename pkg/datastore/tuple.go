@@ -1,6 +1,7 @@
 package datastore
 
 import (
+	"context"
 	"errors"
 
 	core "github.com/authzed/spicedb/pkg/proto/core/v1"
@@ -13,10 +14,18 @@ func NewSliceRelationshipIterator(tuples []*core.RelationTuple) RelationshipIter
 	return &sliceRelationshipIterator{tuples: tuples}
 }
 
+// NewSliceRelationshipIteratorWithRevisions creates a datastore.TupleIterator instance from a
+// materialized slice of tuples, which reports the revision metadata of each from the given map.
+func NewSliceRelationshipIteratorWithRevisions(tuples []*core.RelationTuple, revisions map[*core.RelationTuple]RevisionMetadata) RelationshipIterator {
+	return &sliceRelationshipIterator{tuples: tuples, revisions: revisions}
+}
+
 type sliceRelationshipIterator struct {
-	tuples []*core.RelationTuple
-	closed bool
-	err    error
+	tuples    []*core.RelationTuple
+	revisions map[*core.RelationTuple]RevisionMetadata
+	last      *core.RelationTuple
+	closed    bool
+	err       error
 }
 
 // Next implements TupleIterator
@@ -29,6 +38,7 @@ func (sti *sliceRelationshipIterator) Next() *core.RelationTuple {
 	if len(sti.tuples) > 0 {
 		first := sti.tuples[0]
 		sti.tuples = sti.tuples[1:]
+		sti.last = first
 		return first
 	}
 
@@ -40,6 +50,21 @@ func (sti *sliceRelationshipIterator) Err() error {
 	return sti.err
 }
 
+// Cursor implements TupleIterator
+func (sti *sliceRelationshipIterator) Cursor() *core.RelationTuple {
+	return sti.last
+}
+
+// RevisionMetadata implements RevisionMetadataReporter
+func (sti *sliceRelationshipIterator) RevisionMetadata() (RevisionMetadata, bool) {
+	if sti.last == nil || sti.revisions == nil {
+		return RevisionMetadata{}, false
+	}
+
+	metadata, ok := sti.revisions[sti.last]
+	return metadata, ok
+}
+
 // Close implements TupleIterator
 func (sti *sliceRelationshipIterator) Close() {
 	if sti.closed {
@@ -47,9 +72,75 @@ func (sti *sliceRelationshipIterator) Close() {
 	}
 
 	sti.tuples = nil
+	sti.revisions = nil
 	sti.closed = true
 }
 
+// NewMaxResultsIterator wraps an iterator so that it fails with ErrResultSetTooLarge, instead of
+// returning more than maxResults tuples. A maxResults of zero means the results are unbounded.
+func NewMaxResultsIterator(iter RelationshipIterator, maxResults uint64) RelationshipIterator {
+	if maxResults == 0 {
+		return iter
+	}
+	return &maxResultsIterator{delegate: iter, maxResults: maxResults}
+}
+
+type maxResultsIterator struct {
+	delegate   RelationshipIterator
+	maxResults uint64
+	count      uint64
+	last       *core.RelationTuple
+	err        error
+}
+
+// Next implements TupleIterator
+func (mri *maxResultsIterator) Next() *core.RelationTuple {
+	if mri.err != nil {
+		return nil
+	}
+
+	next := mri.delegate.Next()
+	if next == nil {
+		return nil
+	}
+
+	if mri.count >= mri.maxResults {
+		mri.err = NewResultSetTooLargeErr(mri.maxResults)
+		return nil
+	}
+
+	mri.count++
+	mri.last = next
+	return next
+}
+
+// Err implements TupleIterator
+func (mri *maxResultsIterator) Err() error {
+	if mri.err != nil {
+		return mri.err
+	}
+	return mri.delegate.Err()
+}
+
+// Cursor implements TupleIterator
+func (mri *maxResultsIterator) Cursor() *core.RelationTuple {
+	return mri.last
+}
+
+// RevisionMetadata implements RevisionMetadataReporter
+func (mri *maxResultsIterator) RevisionMetadata() (RevisionMetadata, bool) {
+	reporter, ok := mri.delegate.(RevisionMetadataReporter)
+	if !ok || mri.err != nil {
+		return RevisionMetadata{}, false
+	}
+	return reporter.RevisionMetadata()
+}
+
+// Close implements TupleIterator
+func (mri *maxResultsIterator) Close() {
+	mri.delegate.Close()
+}
+
 // BuildFinalizerFunction creates a function which can be used as a finalizer to make sure that
 // tuples are getting closed before they are garbage collected.
 func BuildFinalizerFunction() func(iter *sliceRelationshipIterator) {
@@ -59,3 +150,48 @@ func BuildFinalizerFunction() func(iter *sliceRelationshipIterator) {
 		}
 	}
 }
+
+// StreamRelationships reads the iterator in a goroutine and sends its tuples on the returned
+// channel, for consumers which prefer channels to iterators. Both channels are closed, and the
+// iterator with them, once the iterator is exhausted, fails, or the context is canceled; in the
+// latter two cases the error is sent on the error channel first.
+//
+// Canceling the stream only aborts reading from the database if the query was made with the same
+// context and options.WithStreamResults; otherwise the results were loaded before the iterator was
+// returned, and canceling only stops their delivery.
+func StreamRelationships(ctx context.Context, iter RelationshipIterator) (<-chan *core.RelationTuple, <-chan error) {
+	tuples := make(chan *core.RelationTuple)
+	errs := make(chan error, 1)
+
+	go func() {
+		defer close(errs)
+		defer close(tuples)
+		defer iter.Close()
+
+		for {
+			if err := ctx.Err(); err != nil {
+				errs <- err
+				return
+			}
+
+			tpl := iter.Next()
+			if tpl == nil {
+				if err := iter.Err(); err != nil {
+					errs <- err
+				}
+				return
+			}
+
+			select {
+			case tuples <- tpl:
+			case <-ctx.Done():
+				errs <- ctx.Err()
+				return
+			}
+		}
+	}()
+
+	return tuples, errs
+}
+
+var _ RevisionMetadataReporter = &sliceRelationshipIterator{}
@@ -17,7 +17,8 @@ type WithSourcePosition interface {
 //  in the namespace proto. If placed here, FilterUserDefinedMetadataInPlace will remove the
 // metadata when called on the namespace.
 var userDefinedMetadataTypeUrls = map[string]struct{}{
-	"type.googleapis.com/impl.v1.DocComment": {},
+	"type.googleapis.com/impl.v1.DocComment":       {},
+	"type.googleapis.com/impl.v1.DefinitionSource": {},
 }
 
 // FilterUserDefinedMetadataInPlace removes user-defined metadata (e.g. comments) from the given namespace
@@ -108,3 +109,61 @@ func SetRelationKind(relation *core.Relation, kind iv1.RelationMetadata_Relation
 	metadata.MetadataMessage = append(metadata.MetadataMessage, encoded)
 	return nil
 }
+
+// GetDefinitionSource returns the schema source from which the namespace was compiled, if it was
+// recorded.
+func GetDefinitionSource(nsdef *core.NamespaceDefinition) (string, bool) {
+	if nsdef.Metadata == nil {
+		return "", false
+	}
+
+	for _, msg := range nsdef.Metadata.MetadataMessage {
+		var ds iv1.DefinitionSource
+		if err := msg.UnmarshalTo(&ds); err == nil {
+			return ds.Source, true
+		}
+	}
+
+	return "", false
+}
+
+// SetDefinitionSource records the schema source from which the namespace was compiled, replacing
+// any previously recorded source.
+func SetDefinitionSource(nsdef *core.NamespaceDefinition, source string) error {
+	metadata := nsdef.Metadata
+	if metadata == nil {
+		metadata = &core.Metadata{}
+		nsdef.Metadata = metadata
+	}
+
+	encoded, err := anypb.New(&iv1.DefinitionSource{Source: source})
+	if err != nil {
+		return err
+	}
+
+	filtered := make([]*anypb.Any, 0, len(metadata.MetadataMessage)+1)
+	for _, msg := range metadata.MetadataMessage {
+		if msg.TypeUrl != encoded.TypeUrl {
+			filtered = append(filtered, msg)
+		}
+	}
+
+	metadata.MetadataMessage = append(filtered, encoded)
+	return nil
+}
+
+// ClearDefinitionSource removes any recorded schema source from the namespace, for use when the
+// namespace is changed in a way its recorded source does not reflect, such as being renamed.
+func ClearDefinitionSource(nsdef *core.NamespaceDefinition) {
+	if nsdef.Metadata == nil {
+		return
+	}
+
+	filtered := make([]*anypb.Any, 0, len(nsdef.Metadata.MetadataMessage))
+	for _, msg := range nsdef.Metadata.MetadataMessage {
+		if !msg.MessageIs(&iv1.DefinitionSource{}) {
+			filtered = append(filtered, msg)
+		}
+	}
+	nsdef.Metadata.MetadataMessage = filtered
+}
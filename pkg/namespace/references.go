@@ -0,0 +1,26 @@
+package namespace
+
+import (
+	core "github.com/authzed/spicedb/pkg/proto/core/v1"
+)
+
+// RenameTypeReferences rewrites, in place, every allowed subject type of the namespace's
+// relations which references oldName to reference newName instead, returning whether any
+// reference was rewritten. The recorded schema source of a rewritten namespace is cleared, since
+// it still names the old type.
+func RenameTypeReferences(nsdef *core.NamespaceDefinition, oldName, newName string) bool {
+	renamed := false
+	for _, relation := range nsdef.Relation {
+		for _, allowed := range relation.GetTypeInformation().GetAllowedDirectRelations() {
+			if allowed.Namespace == oldName {
+				allowed.Namespace = newName
+				renamed = true
+			}
+		}
+	}
+
+	if renamed {
+		ClearDefinitionSource(nsdef)
+	}
+	return renamed
+}
@@ -83,3 +83,22 @@ func TestEncodeInObject(t *testing.T) {
 	}
 	require.NoError(t, objRef.Validate())
 }
+
+func TestWrittenAt(t *testing.T) {
+	computed, err := ComputeV1Alpha1Revision(map[string]decimal.Decimal{
+		"foo": decimal.NewFromInt(3),
+		"bar": decimal.NewFromInt(7),
+		"baz": decimal.NewFromInt(5),
+	})
+	require.NoError(t, err)
+
+	writtenAt, err := V1Alpha1RevisionWrittenAt(computed)
+	require.NoError(t, err)
+	require.True(t, decimal.NewFromInt(7).Equal(writtenAt))
+
+	empty, err := ComputeV1Alpha1Revision(map[string]decimal.Decimal{})
+	require.NoError(t, err)
+
+	_, err = V1Alpha1RevisionWrittenAt(empty)
+	require.Error(t, err)
+}
@@ -2,6 +2,7 @@ package namespace
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"sort"
 
@@ -67,6 +68,28 @@ func DecodeV1Alpha1Revision(encoded string) (map[string]decimal.Decimal, error)
 	return revisions, nil
 }
 
+// V1Alpha1RevisionWrittenAt decodes an encoded revision and returns the latest revision at which
+// any of its namespaces was written. For a revision returned by WriteSchema, this is the revision
+// at which the whole schema was written.
+func V1Alpha1RevisionWrittenAt(encoded string) (decimal.Decimal, error) {
+	decoded, err := DecodeV1Alpha1Revision(encoded)
+	if err != nil {
+		return decimal.Zero, err
+	}
+
+	if len(decoded) == 0 {
+		return decimal.Zero, fmt.Errorf(errDecodeError, errors.New("revision contains no namespaces"))
+	}
+
+	writtenAt := decimal.Zero
+	for _, revision := range decoded {
+		if revision.GreaterThan(writtenAt) {
+			writtenAt = revision
+		}
+	}
+	return writtenAt, nil
+}
+
 type byName []*internal.NamespaceAndRevision
 
 func (n byName) Len() int           { return len(n) }
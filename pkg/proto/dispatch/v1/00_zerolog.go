@@ -17,6 +17,7 @@ func (cr *DispatchCheckRequest) MarshalZerologObject(e *zerolog.Event) {
 		ResourceAndRelation: cr.ResourceAndRelation,
 		Subject:             cr.Subject,
 	}))
+	e.Bool("explain", cr.Explain)
 }
 
 // MarshalZerologObject implements zerolog object marshalling.
@@ -45,6 +46,7 @@ func (lr *DispatchLookupRequest) MarshalZerologObject(e *zerolog.Event) {
 	e.Array("direct", onArray(lr.DirectStack))
 	e.Array("ttu", onArray(lr.TtuStack))
 	e.Uint32("limit", lr.Limit)
+	e.Bool("debug", lr.DebugTrace)
 }
 
 // MarshalZerologObject implements zerolog object marshalling.
@@ -54,6 +56,29 @@ func (lr *DispatchReachableResourcesRequest) MarshalZerologObject(e *zerolog.Eve
 	e.Str("subject", tuple.StringONR(lr.Subject))
 }
 
+// MarshalZerologObject implements zerolog object marshalling.
+func (lr *DispatchLookupSubjectsRequest) MarshalZerologObject(e *zerolog.Event) {
+	e.Object("metadata", lr.Metadata)
+	e.Str("resource", tuple.StringONR(lr.ResourceAndRelation))
+	e.Str("subject", fmt.Sprintf("%s#%s", lr.SubjectRelation.Namespace, lr.SubjectRelation.Relation))
+	e.Uint32("limit", lr.Limit)
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (cr *DispatchCheckBulkRequest) MarshalZerologObject(e *zerolog.Event) {
+	e.Object("metadata", cr.Metadata)
+	e.Int("resources", len(cr.ResourcesAndRelations))
+	e.Str("subject", tuple.StringONR(cr.Subject))
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (lr *DispatchLookupForSubjectTypeRequest) MarshalZerologObject(e *zerolog.Event) {
+	e.Object("metadata", lr.Metadata)
+	e.Str("object", fmt.Sprintf("%s#%s", lr.ObjectRelation.Namespace, lr.ObjectRelation.Relation))
+	e.Str("subject", fmt.Sprintf("%s#%s", lr.SubjectType.Namespace, lr.SubjectType.Relation))
+	e.Uint32("limit", lr.Limit)
+}
+
 type onArray []*core.RelationReference
 
 type zerologON core.RelationReference
@@ -76,6 +101,21 @@ func (cr *DispatchLookupResponse) MarshalZerologObject(e *zerolog.Event) {
 	e.Object("metadata", cr.Metadata)
 }
 
+// MarshalZerologObject implements zerolog object marshalling.
+func (cr *DispatchLookupSubjectsResponse) MarshalZerologObject(e *zerolog.Event) {
+	e.Object("metadata", cr.Metadata)
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (cr *DispatchCheckBulkResponse) MarshalZerologObject(e *zerolog.Event) {
+	e.Object("metadata", cr.Metadata)
+}
+
+// MarshalZerologObject implements zerolog object marshalling.
+func (cr *DispatchLookupForSubjectTypeResponse) MarshalZerologObject(e *zerolog.Event) {
+	e.Object("metadata", cr.Metadata)
+}
+
 // MarshalZerologObject implements zerolog object marshalling.
 func (cr *ResolverMeta) MarshalZerologObject(e *zerolog.Event) {
 	e.Str("revision", cr.AtRevision)
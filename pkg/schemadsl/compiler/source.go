@@ -0,0 +1,41 @@
+package compiler
+
+import (
+	"strings"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+	"github.com/authzed/spicedb/pkg/schemadsl/lexer"
+)
+
+// DefinitionSources splits a schema into the verbatim source of each of its top-level
+// definitions, in the order in which they are defined. The source of a definition includes any
+// comments between it and the previous definition.
+//
+// The schema is expected to have been successfully compiled, in which case the sources
+// correspond one-to-one with the compiled namespace definitions.
+func DefinitionSources(source input.Source, schema string) []string {
+	lx := lexer.NewPeekableLexer(lexer.Lex(source, schema))
+	defer lx.Close()
+
+	var sources []string
+	depth := 0
+	start := 0
+	for {
+		token := lx.NextToken()
+		switch token.Kind {
+		case lexer.TokenTypeEOF, lexer.TokenTypeError:
+			return sources
+
+		case lexer.TokenTypeLeftBrace:
+			depth++
+
+		case lexer.TokenTypeRightBrace:
+			depth--
+			if depth == 0 {
+				end := int(token.Position) + len(token.Value)
+				sources = append(sources, strings.TrimSpace(schema[start:end]))
+				start = end
+			}
+		}
+	}
+}
@@ -0,0 +1,53 @@
+package compiler
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/authzed/spicedb/pkg/schemadsl/input"
+)
+
+func TestDefinitionSources(t *testing.T) {
+	tests := []struct {
+		name     string
+		schema   string
+		expected []string
+	}{
+		{"empty", "", nil},
+		{"single definition", "definition user {}", []string{"definition user {}"}},
+		{
+			"comments and formatting",
+			`// The user.
+definition user {}
+
+/**
+ * A document, with a } in its comment.
+ */
+definition document {
+	relation   reader: user // trailing comment
+	permission view = reader   + nil
+}
+
+// A dangling comment.`,
+			[]string{
+				`// The user.
+definition user {}`,
+				`/**
+ * A document, with a } in its comment.
+ */
+definition document {
+	relation   reader: user // trailing comment
+	permission view = reader   + nil
+}`,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.expected, DefinitionSources(input.Source(tt.name), tt.schema))
+		})
+	}
+}
@@ -0,0 +1,123 @@
+package tuple
+
+import (
+	"fmt"
+	"regexp"
+
+	v1 "github.com/authzed/authzed-go/proto/authzed/api/v1"
+	"github.com/jzelinskie/stringz"
+)
+
+// RelationshipField is a field of a relationship, as named in validation errors.
+type RelationshipField string
+
+const (
+	ResourceTypeField    RelationshipField = "resource type"
+	ResourceIDField      RelationshipField = "resource object id"
+	RelationField        RelationshipField = "relation"
+	SubjectTypeField     RelationshipField = "subject type"
+	SubjectIDField       RelationshipField = "subject object id"
+	SubjectRelationField RelationshipField = "subject relation"
+)
+
+// IdentifierLimits are the maximum lengths, in bytes, of the identifiers of a relationship.
+type IdentifierLimits struct {
+	MaxNamespaceLength int
+	MaxObjectIDLength  int
+	MaxRelationLength  int
+}
+
+// DefaultIdentifierLimits are the documented maximum lengths of identifiers.
+var DefaultIdentifierLimits = IdentifierLimits{
+	MaxNamespaceLength: 128,
+	MaxObjectIDLength:  128,
+	MaxRelationLength:  64,
+}
+
+// The allowed character sets of identifiers, without any bound on their lengths, which are
+// checked separately against the configured limits.
+var (
+	namespaceCharsRegex = regexp.MustCompile("^([a-z][a-z0-9_]+[a-z0-9]/)?[a-z][a-z0-9_]+[a-z0-9]$")
+	objectIDCharsRegex  = regexp.MustCompile("^[a-zA-Z0-9_][a-zA-Z0-9/_|-]*$")
+	relationCharsRegex  = regexp.MustCompile("^[a-z][a-z0-9_]+[a-z0-9]$")
+)
+
+// ErrInvalidRelationshipField occurs when a field of a relationship is too long or contains
+// characters which are not allowed.
+type ErrInvalidRelationshipField struct {
+	error
+	field        RelationshipField
+	relationship string
+}
+
+// Field is the field of the relationship which is invalid.
+func (err ErrInvalidRelationshipField) Field() RelationshipField {
+	return err.field
+}
+
+// Relationship is the string form of the relationship with the invalid field.
+func (err ErrInvalidRelationshipField) Relationship() string {
+	return err.relationship
+}
+
+func newInvalidRelationshipFieldErr(rel *v1.Relationship, field RelationshipField, reason string, args ...interface{}) error {
+	relString := relationshipString(rel)
+	return ErrInvalidRelationshipField{
+		error:        fmt.Errorf("invalid %s of relationship `%s`: %s", field, relString, fmt.Sprintf(reason, args...)),
+		field:        field,
+		relationship: relString,
+	}
+}
+
+// relationshipString returns the string form of the relationship, even if it does not validate.
+func relationshipString(rel *v1.Relationship) string {
+	subject := fmt.Sprintf("%s:%s", rel.Subject.Object.ObjectType, rel.Subject.Object.ObjectId)
+	if rel.Subject.OptionalRelation != "" {
+		subject = fmt.Sprintf("%s#%s", subject, rel.Subject.OptionalRelation)
+	}
+
+	return fmt.Sprintf("%s:%s#%s@%s", rel.Resource.ObjectType, rel.Resource.ObjectId, rel.Relation, subject)
+}
+
+// ValidateRelationship returns an ErrInvalidRelationshipField if any identifier of the
+// relationship is longer than the limits or contains characters which are not allowed.
+func (limits IdentifierLimits) ValidateRelationship(rel *v1.Relationship) error {
+	checks := []struct {
+		field     RelationshipField
+		value     string
+		maxLength int
+		allowed   *regexp.Regexp
+	}{
+		{ResourceTypeField, rel.Resource.ObjectType, limits.MaxNamespaceLength, namespaceCharsRegex},
+		{ResourceIDField, rel.Resource.ObjectId, limits.MaxObjectIDLength, objectIDCharsRegex},
+		{RelationField, rel.Relation, limits.MaxRelationLength, relationCharsRegex},
+		{SubjectTypeField, rel.Subject.Object.ObjectType, limits.MaxNamespaceLength, namespaceCharsRegex},
+		{SubjectIDField, rel.Subject.Object.ObjectId, limits.MaxObjectIDLength, objectIDCharsRegex},
+		{SubjectRelationField, stringz.DefaultEmpty(rel.Subject.OptionalRelation, Ellipsis), limits.MaxRelationLength, relationCharsRegex},
+	}
+
+	allowedDescriptions := map[*regexp.Regexp]string{
+		namespaceCharsRegex: "must be lowercase alphanumeric or `_`, optionally prefixed with a `/`-separated prefix",
+		objectIDCharsRegex:  "must be alphanumeric or one of `_`, `/`, `|` and `-`",
+		relationCharsRegex:  "must be lowercase alphanumeric or `_`",
+	}
+
+	for _, check := range checks {
+		switch {
+		case check.field == SubjectIDField && check.value == PublicWildcard:
+			continue
+		case check.field == SubjectRelationField && check.value == Ellipsis:
+			continue
+		}
+
+		if len(check.value) > check.maxLength {
+			return newInvalidRelationshipFieldErr(rel, check.field, "length of %d bytes exceeds the maximum of %d bytes", len(check.value), check.maxLength)
+		}
+
+		if !check.allowed.MatchString(check.value) {
+			return newInvalidRelationshipFieldErr(rel, check.field, "`%s` is not allowed; %s", check.value, allowedDescriptions[check.allowed])
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,72 @@
+package tuple
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestValidateRelationship(t *testing.T) {
+	limits := IdentifierLimits{
+		MaxNamespaceLength: 16,
+		MaxObjectIDLength:  8,
+		MaxRelationLength:  10,
+	}
+
+	testCases := []struct {
+		name          string
+		limits        IdentifierLimits
+		resType       string
+		resID         string
+		relation      string
+		subType       string
+		subID         string
+		subRel        string
+		expectedField RelationshipField
+		errorContains string
+	}{
+		{"valid", limits, "document", "doc1", "viewer", "user", "tom", "", "", ""},
+		{"valid with subject relation", limits, "document", "doc1", "viewer", "group", "eng", "member", "", ""},
+		{"valid with ellipsis", limits, "document", "doc1", "viewer", "user", "tom", Ellipsis, "", ""},
+		{"valid wildcard subject", limits, "document", "doc1", "viewer", "user", PublicWildcard, "", "", ""},
+		{"valid prefixed namespace", limits, "org/document", "doc1", "viewer", "user", "tom", "", "", ""},
+		{"valid default limits", DefaultIdentifierLimits, "document", strings.Repeat("a", 128), "viewer", "user", "tom", "", "", ""},
+		{"resource type too long", limits, "averylongdocumenttype", "doc1", "viewer", "user", "tom", "", ResourceTypeField, "length of 21 bytes exceeds the maximum of 16 bytes"},
+		{"resource type invalid", limits, "Document", "doc1", "viewer", "user", "tom", "", ResourceTypeField, "`Document` is not allowed"},
+		{"resource id too long", limits, "document", "document1", "viewer", "user", "tom", "", ResourceIDField, "length of 9 bytes exceeds the maximum of 8 bytes"},
+		{"resource id invalid", limits, "document", "doc 1", "viewer", "user", "tom", "", ResourceIDField, "alphanumeric"},
+		{"resource id wildcard", limits, "document", PublicWildcard, "viewer", "user", "tom", "", ResourceIDField, "`*` is not allowed"},
+		{"relation too long", limits, "document", "doc1", "viewer_relation", "user", "tom", "", RelationField, "length of 15 bytes exceeds the maximum of 10 bytes"},
+		{"relation invalid", limits, "document", "doc1", "view-er", "user", "tom", "", RelationField, "`view-er` is not allowed"},
+		{"subject type too long", limits, "document", "doc1", "viewer", "averylongusertypename", "tom", "", SubjectTypeField, "exceeds the maximum"},
+		{"subject type invalid", limits, "document", "doc1", "viewer", "us.er", "tom", "", SubjectTypeField, "`us.er` is not allowed"},
+		{"subject id too long", limits, "document", "doc1", "viewer", "user", "tommytommy", "", SubjectIDField, "length of 10 bytes exceeds the maximum of 8 bytes"},
+		{"subject id invalid", limits, "document", "doc1", "viewer", "user", "tom!", "", SubjectIDField, "`tom!` is not allowed"},
+		{"subject relation too long", limits, "document", "doc1", "viewer", "group", "eng", "membersofgroup", SubjectRelationField, "exceeds the maximum"},
+		{"subject relation invalid", limits, "document", "doc1", "viewer", "group", "eng", "Member", SubjectRelationField, "`Member` is not allowed"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			require := require.New(t)
+
+			relationship := rel(tc.resType, tc.resID, tc.relation, tc.subType, tc.subID, tc.subRel)
+			err := tc.limits.ValidateRelationship(relationship)
+			if tc.expectedField == "" {
+				require.NoError(err)
+				return
+			}
+
+			require.Error(err)
+			require.Contains(err.Error(), tc.errorContains)
+			require.Contains(err.Error(), string(tc.expectedField))
+
+			var fieldErr ErrInvalidRelationshipField
+			require.True(errors.As(err, &fieldErr))
+			require.Equal(tc.expectedField, fieldErr.Field())
+			require.Equal(relationshipString(relationship), fieldErr.Relationship())
+		})
+	}
+}
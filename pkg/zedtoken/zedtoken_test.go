@@ -47,6 +47,24 @@ var decodeTests = []struct {
 		expectedRevision: decimal.Zero,
 		expectError:      true,
 	},
+	{
+		format:           "invalid proto",
+		token:            "//8=",
+		expectedRevision: decimal.Zero,
+		expectError:      true,
+	},
+	{
+		format:           "unknown version",
+		token:            "",
+		expectedRevision: decimal.Zero,
+		expectError:      true,
+	},
+	{
+		format:           "V1 ZedToken with invalid revision",
+		token:            "CAIaBQoDYWJj",
+		expectedRevision: decimal.Zero,
+		expectError:      true,
+	},
 	{
 		format:           "V1 Zookie",
 		token:            "CAESAA==",
@@ -132,3 +150,8 @@ func TestDecode(t *testing.T) {
 		})
 	}
 }
+
+func TestDecodeNil(t *testing.T) {
+	_, err := DecodeRevision(nil)
+	require.ErrorIs(t, err, ErrNilZedToken)
+}